@@ -18,14 +18,25 @@
 package adminapi
 
 import (
+	"time"
+
 	"xconfwebconfig/dataapi"
+	"xconfwebconfig/db"
 
 	xcommon "xconfadmin/common"
 	xwcommon "xconfwebconfig/common"
 
+	"xconfadmin/adminapi/auth"
+	"xconfadmin/adminapi/change"
+	"xconfadmin/adminapi/dcm"
+	"xconfadmin/adminapi/firmware"
 	queries "xconfadmin/adminapi/queries"
+	"xconfadmin/adminapi/webhook"
 	xhttp "xconfadmin/http"
 	xshared "xconfadmin/shared"
+	xchange "xconfadmin/shared/change"
+
+	log "github.com/sirupsen/logrus"
 )
 
 // Ws - webserver object
@@ -44,6 +55,18 @@ func WebServerInjection(ws *xhttp.WebconfigServer, xc *dataapi.XconfConfigs) {
 		xcommon.DefaultAuthProfiles = "prod"
 		xcommon.SatOn = false
 		xcommon.IpMacIsConditionLimit = 20
+		xcommon.AuditRetentionDays = 90
+		xcommon.PendingChangeTtlHours = map[string]int{}
+		xcommon.PendingChangeSweepIntervalMinutes = 60
+		xcommon.ScheduledChangeIntervalSeconds = 60
+		xcommon.WebhookMaxAttempts = 5
+		xcommon.WebhookRetryBackoffSeconds = 10
+		xcommon.WebhookDeliveryRetentionDays = 7
+		xcommon.WebhookAllowPrivateNetworks = false
+		xcommon.FirmwareSimulationMaxDevices = 50000
+		xcommon.FirmwareSimulationSyncLimit = 100
+		xcommon.FirmwareSimulationRetentionDays = 7
+		xcommon.PercentageRolloutIntervalSeconds = 60
 	} else {
 		xwcommon.CacheUpdateWindowSize = ws.XW_XconfServer.ServerConfig.GetInt64("xconfwebconfig.xconf.cache_update_window_size")
 		xcommon.AllowedNumberOfFeatures = int(ws.XW_XconfServer.ServerConfig.GetInt32("xconfwebconfig.xconf.allowedNumberOfFeatures", 100))
@@ -51,6 +74,23 @@ func WebServerInjection(ws *xhttp.WebconfigServer, xc *dataapi.XconfConfigs) {
 		xcommon.DefaultAuthProfiles = ws.XW_XconfServer.ServerConfig.GetString("xconfwebconfig.xconf.authProfilesDefault")
 		xcommon.SatOn = ws.XW_XconfServer.ServerConfig.GetBoolean("xconfwebconfig.sat.SAT_ON")
 		xcommon.IpMacIsConditionLimit = int(ws.XW_XconfServer.ServerConfig.GetInt32("xconfwebconfig.xconf.ipMacIsConditionLimit", 20))
+		xcommon.ConfiguredApplicationTypes = ws.XW_XconfServer.ServerConfig.GetStringList("xconfwebconfig.xconf.application_types")
+		xcommon.AuditRetentionDays = int(ws.XW_XconfServer.ServerConfig.GetInt32("xconfwebconfig.xconf.audit_retention_in_days", 90))
+		xcommon.PendingChangeTtlHours = map[string]int{
+			db.TABLE_XCONF_CHANGE:               int(ws.XW_XconfServer.ServerConfig.GetInt32("xconfwebconfig.xconf.telemetry_change_ttl_in_hours", 0)),
+			db.TABLE_XCONF_TELEMETRY_TWO_CHANGE: int(ws.XW_XconfServer.ServerConfig.GetInt32("xconfwebconfig.xconf.telemetry_two_change_ttl_in_hours", 0)),
+			xcommon.TABLE_ENTITY_CHANGES:        int(ws.XW_XconfServer.ServerConfig.GetInt32("xconfwebconfig.xconf.entity_change_ttl_in_hours", 0)),
+		}
+		xcommon.PendingChangeSweepIntervalMinutes = int(ws.XW_XconfServer.ServerConfig.GetInt32("xconfwebconfig.xconf.pending_change_sweep_interval_in_minutes", 60))
+		xcommon.ScheduledChangeIntervalSeconds = int(ws.XW_XconfServer.ServerConfig.GetInt32("xconfwebconfig.xconf.scheduled_change_interval_in_seconds", 60))
+		xcommon.WebhookMaxAttempts = int(ws.XW_XconfServer.ServerConfig.GetInt32("xconfwebconfig.xconf.webhook_max_attempts", 5))
+		xcommon.WebhookRetryBackoffSeconds = int(ws.XW_XconfServer.ServerConfig.GetInt32("xconfwebconfig.xconf.webhook_retry_backoff_in_seconds", 10))
+		xcommon.WebhookDeliveryRetentionDays = int(ws.XW_XconfServer.ServerConfig.GetInt32("xconfwebconfig.xconf.webhook_delivery_retention_in_days", 7))
+		xcommon.WebhookAllowPrivateNetworks = ws.XW_XconfServer.ServerConfig.GetBoolean("xconfwebconfig.xconf.webhook_allow_private_networks")
+		xcommon.FirmwareSimulationMaxDevices = int(ws.XW_XconfServer.ServerConfig.GetInt32("xconfwebconfig.xconf.firmware_simulation_max_devices", 50000))
+		xcommon.FirmwareSimulationSyncLimit = int(ws.XW_XconfServer.ServerConfig.GetInt32("xconfwebconfig.xconf.firmware_simulation_sync_limit", 100))
+		xcommon.FirmwareSimulationRetentionDays = int(ws.XW_XconfServer.ServerConfig.GetInt32("xconfwebconfig.xconf.firmware_simulation_retention_in_days", 7))
+		xcommon.PercentageRolloutIntervalSeconds = int(ws.XW_XconfServer.ServerConfig.GetInt32("xconfwebconfig.xconf.percentage_rollout_interval_in_seconds", 60))
 	}
 	if ws.TestOnly() {
 		xcommon.SatOn = false
@@ -58,9 +98,93 @@ func WebServerInjection(ws *xhttp.WebconfigServer, xc *dataapi.XconfConfigs) {
 	Xc = xc
 }
 
+// initLoginTokenVerification refuses to start outside the dev profile unless login tokens
+// can be verified against configured public keys
+func initLoginTokenVerification(ws *xhttp.WebconfigServer) {
+	if xhttp.IsLoginTokenKeyConfigured() {
+		if err := xhttp.ValidateLoginTokenConfig(); err != nil {
+			panic(err)
+		}
+		return
+	}
+	if auth.IsDevProfile() || ws.TestOnly() {
+		log.Warn("login token verification key is not configured, accepting dev login tokens")
+		xhttp.EnableLegacyLoginToken(true)
+		return
+	}
+	panic(xhttp.ValidateLoginTokenConfig())
+}
+
+// startPendingChangeSweeper cancels the expired pending changes when a change table has a TTL
+func startPendingChangeSweeper(ws *xhttp.WebconfigServer) {
+	if ws.TestOnly() || !xchange.HasPendingChangeTtl() {
+		return
+	}
+	interval := xcommon.PendingChangeSweepIntervalMinutes
+	if interval <= 0 {
+		interval = 60
+	}
+	change.StartPendingChangeSweeper(time.Duration(interval) * time.Minute)
+}
+
+// startScheduledChangeScheduler applies the scheduled changes once they are due
+func startScheduledChangeScheduler(ws *xhttp.WebconfigServer) {
+	if ws.TestOnly() {
+		return
+	}
+	interval := xcommon.ScheduledChangeIntervalSeconds
+	if interval <= 0 {
+		interval = 60
+	}
+	change.StartScheduledChangeScheduler(time.Duration(interval) * time.Second)
+}
+
+// failInterruptedFirmwareSimulations ends the simulation jobs which were running when an admin instance stopped
+func failInterruptedFirmwareSimulations(ws *xhttp.WebconfigServer) {
+	if ws.TestOnly() {
+		return
+	}
+	if failed := firmware.FailInterruptedFirmwareSimulations(time.Now()); failed > 0 {
+		log.Warnf("%d interrupted firmware simulations are failed", failed)
+	}
+}
+
+// startPercentageRolloutScheduler advances the rollout plans of the percentage beans once their steps are due
+func startPercentageRolloutScheduler(ws *xhttp.WebconfigServer) {
+	if ws.TestOnly() {
+		return
+	}
+	interval := xcommon.PercentageRolloutIntervalSeconds
+	if interval <= 0 {
+		interval = 60
+	}
+	queries.StartPercentageRolloutScheduler(time.Duration(interval) * time.Second)
+}
+
+// startWebhookDispatcher sends the events of the admin writes to the webhook subscriptions
+func startWebhookDispatcher(ws *xhttp.WebconfigServer) {
+	if ws.TestOnly() {
+		return
+	}
+	interval := xcommon.WebhookRetryBackoffSeconds
+	if interval <= 0 {
+		interval = 10
+	}
+	webhook.StartWebhookDispatcher(time.Duration(interval) * time.Second)
+}
+
+// registerEntityChangeAppliers registers the entity types which can be put into approval mode
+func registerEntityChangeAppliers() {
+	xchange.RegisterEntityChangeApplier(xchange.FIRMWARE_RULE, queries.NewFirmwareRuleChangeApplier())
+	xchange.RegisterEntityChangeApplier(xchange.PERCENTAGE_BEAN, queries.NewPercentageBeanChangeApplier())
+	xchange.RegisterEntityChangeApplier(xchange.FEATURE_RULE, queries.NewFeatureRuleChangeApplier())
+	xchange.RegisterEntityChangeApplier(xchange.DCM_FORMULA, dcm.NewDcmFormulaChangeApplier())
+}
+
 func initDB() {
-	queries.CreateFirmwareRuleTemplates() // Initialize FirmwareRule templates
-	initAppSettings()                     // Initialize Application settings
+	queries.CreateFirmwareRuleTemplates()    // Initialize FirmwareRule templates
+	initAppSettings()                        // Initialize Application settings
+	queries.SeedAllApplicationTypeDefaults() // Initialize ApplicationType default entities
 }
 
 func initAppSettings() {
@@ -0,0 +1,323 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package adminapi
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+
+	"xconfadmin/adminapi/auth"
+	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
+	xshared "xconfadmin/shared"
+	xwcommon "xconfwebconfig/common"
+	"xconfwebconfig/db"
+	xwhttp "xconfwebconfig/http"
+	"xconfwebconfig/util"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// auditedTables maps the route name to the table of the entity the route modifies
+var auditedTables = map[string]string{
+	"ApplicationTypes":            xcommon.TABLE_APPLICATION_TYPES,
+	"ApprovalPolicies":            xcommon.TABLE_APPROVAL_POLICIES,
+	"ApprovalSettings":            xcommon.TABLE_APPROVAL_SETTINGS,
+	"AppSettings":                 xcommon.TABLE_APP_SETTINGS,
+	"ChangeReviews":               xcommon.TABLE_CHANGE_REVIEWS,
+	"DCM-DeviceSettings":          db.TABLE_DEVICE_SETTINGS,
+	"DCM-Formulas":                db.TABLE_DCM_RULE,
+	"DCM-LogUploadSettings":       db.TABLE_LOG_UPLOAD_SETTINGS,
+	"DCM-UploadRepository":        db.TABLE_UPLOAD_REPOSITORY,
+	"DCM-VODSettings":             db.TABLE_VOD_SETTINGS,
+	"Environments":                db.TABLE_ENVIRONMENT,
+	"Firmware-ActivationVersion":  db.TABLE_FIRMWARE_RULE,
+	"Firmware-Configs":            db.TABLE_FIRMWARE_CONFIG,
+	"Firmware-PercentFilter":      db.TABLE_FIRMWARE_RULE,
+	"Firmware-PercentageRollouts": xcommon.TABLE_PERCENTAGE_ROLLOUTS,
+	"Firmware-ConfigLifecycle":    xcommon.TABLE_FIRMWARE_CONFIG_LIFECYCLES,
+	"Firmware-VersionPatterns":    xcommon.TABLE_FIRMWARE_VERSION_PATTERNS,
+	"Firmware-Rules":              db.TABLE_FIRMWARE_RULE,
+	"Firmware-Templates":          db.TABLE_FIRMWARE_RULE_TEMPLATE,
+	"Models":                      db.TABLE_MODEL,
+	"NameSpaced-Lists":            db.TABLE_GENERIC_NS_LIST,
+	"Ownership-Groups":            xcommon.TABLE_OWNERSHIP_GROUP_MAPPINGS,
+	"RFC-Feature":                 db.TABLE_XCONF_FEATURE,
+	"RFC-FeatureRules":            db.TABLE_FEATURE_CONTROL_RULE,
+	"ScheduledChanges":            xcommon.TABLE_SCHEDULED_CHANGES,
+	"ServiceAccounts":             xcommon.TABLE_SERVICE_ACCOUNTS,
+	"Settings-Profiles":           db.TABLE_SETTING_PROFILES,
+	"Settings-Rules":              db.TABLE_SETTING_RULES,
+	"Telemetry1-Changes":          db.TABLE_XCONF_CHANGE,
+	"Telemetry1-Profiles":         db.TABLE_PERMANENT_TELEMETRY,
+	"Telemetry1-Rules":            db.TABLE_TELEMETRY_RULES,
+	"Telemetry2-Changes":          db.TABLE_XCONF_TELEMETRY_TWO_CHANGE,
+	"Telemetry2-Profiles":         db.TABLE_TELEMETRY_TWO_PROFILES,
+	"Telemetry2-Rules":            db.TABLE_TELEMETRY_TWO_RULES,
+	"Webhooks":                    xcommon.TABLE_WEBHOOK_SUBSCRIPTIONS,
+}
+
+// revertedTables holds the approved changes a revert route works on
+var revertedTables = map[string]string{
+	"Telemetry1-Changes": db.TABLE_XCONF_APPROVED_CHANGE,
+	"Telemetry2-Changes": db.TABLE_XCONF_APPROVED_TELEMETRY_TWO_CHANGE,
+}
+
+// path segments of POST and PUT routes which don't modify data
+var readonlyPathSegments = []string{
+	"filtered",
+	"testpage",
+	"test",
+	"byIdList",
+	"bySupportedModels",
+	"getSortedFirmwareVersionsIfExistOrNot",
+	"settingsAvailability",
+	"formulasAvailability",
+	"reportpage",
+}
+
+// mux vars which identify the modified entity, in order of precedence
+var auditedEntityVars = []string{
+	xwcommon.ID,
+	xcommon.ROW_KEY,
+	xwcommon.LIST_ID,
+	xcommon.CHANGE_ID,
+	xcommon.APPROVE_ID,
+}
+
+// AuditMiddleware writes an audit entry for every successful admin write
+func AuditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := mux.CurrentRoute(r)
+		xw, ok := w.(*xwhttp.XResponseWriter)
+		if route == nil || !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		operation := getAuditOperation(r.Method, route)
+		if operation == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		vars := mux.Vars(r)
+		entityType := route.GetName()
+		tableName := auditedTables[entityType]
+		if operation == xshared.AUDIT_REVERT {
+			tableName = revertedTables[entityType]
+		}
+		if name, ok := vars[xwcommon.TABLE_NAME]; ok {
+			tableName = name
+		}
+		entityId := ""
+		for _, key := range auditedEntityVars {
+			if id, ok := vars[key]; ok {
+				entityId = id
+				break
+			}
+		}
+		entities := getAuditedEntities(xw.Body(), entityId)
+		for _, entity := range entities {
+			entity.before = getAuditSnapshot(tableName, entity.id)
+		}
+
+		next.ServeHTTP(w, r)
+
+		// a write diverted into a pending change is audited when the change is approved
+		if xw.Status() >= http.StatusBadRequest || xw.Status() == http.StatusAccepted {
+			return
+		}
+		// a honored dry run does not modify anything, so it is neither audited nor sent to the webhooks
+		if xhttp.IsDryRunHonored(w) {
+			return
+		}
+		if len(entities) == 1 && entities[0].id == "" {
+			entities[0].id = getJsonId(xw.Response())
+		}
+		for _, entity := range entities {
+			entityOperation := operation
+			after := getAuditSnapshot(tableName, entity.id)
+			if after == nil && entityOperation != xshared.AUDIT_DELETE && entity.body != nil {
+				after = entity.body
+			}
+			if entityOperation == xshared.AUDIT_CREATE && entity.before != nil {
+				entityOperation = xshared.AUDIT_UPDATE
+			}
+
+			entry := xshared.AuditEntry{
+				User:            auth.GetUserNameOrUnknown(r),
+				SourceIp:        getSourceIp(r),
+				RequestId:       xw.AuditId(),
+				Operation:       entityOperation,
+				Method:          r.Method,
+				Path:            r.URL.Path,
+				Status:          xw.Status(),
+				EntityType:      entityType,
+				EntityID:        entity.id,
+				TableName:       tableName,
+				ApplicationType: getAuditApplicationType(r, after, entity.before),
+				Before:          entity.before,
+				After:           after,
+			}
+			if err := xshared.SaveAuditEntry(&entry); err != nil {
+				log.Errorf("unable to save audit entry of %s for %s %s: %s", entity.id, r.Method, r.URL.Path, err.Error())
+			}
+		}
+	})
+}
+
+// getAuditOperation returns the audited operation of the route, empty if the route doesn't modify data
+func getAuditOperation(method string, route *mux.Route) string {
+	template, _ := route.GetPathTemplate()
+	segments := []string{}
+	for _, segment := range strings.Split(template, "/") {
+		if segment != "" && !strings.HasPrefix(segment, "{") {
+			segments = append(segments, segment)
+		}
+	}
+
+	if method == http.MethodGet {
+		for _, segment := range segments {
+			switch segment {
+			case "approve":
+				return xshared.AUDIT_APPROVE
+			case "revert":
+				return xshared.AUDIT_REVERT
+			case "cancel":
+				return xshared.AUDIT_CANCEL
+			}
+		}
+		return ""
+	}
+
+	for _, segment := range segments {
+		if util.Contains(readonlyPathSegments, segment) {
+			return ""
+		}
+	}
+	for _, segment := range segments {
+		switch {
+		case segment == "approveChanges":
+			return xshared.AUDIT_APPROVE
+		case segment == "revertChanges":
+			return xshared.AUDIT_REVERT
+		case segment == "reject":
+			return xshared.AUDIT_REJECT
+		case segment == "priority":
+			return xshared.AUDIT_PRIORITY_CHANGE
+		case strings.HasPrefix(segment, "import"):
+			return xshared.AUDIT_IMPORT
+		}
+	}
+	switch method {
+	case http.MethodPost:
+		return xshared.AUDIT_CREATE
+	case http.MethodPut:
+		return xshared.AUDIT_UPDATE
+	case http.MethodDelete:
+		return xshared.AUDIT_DELETE
+	}
+	return ""
+}
+
+func getAuditSnapshot(tableName string, id string) json.RawMessage {
+	if tableName == "" || id == "" {
+		return nil
+	}
+	inst, err := db.GetCachedSimpleDao().GetOne(tableName, id)
+	if err != nil {
+		// tables of the admin service are not cached
+		if inst, err = db.GetSimpleDao().GetOne(tableName, id); err != nil {
+			return nil
+		}
+	}
+	bytes, err := json.Marshal(inst)
+	if err != nil {
+		return nil
+	}
+	return bytes
+}
+
+// auditedEntity is an entity the request modifies, with its snapshot before the write
+type auditedEntity struct {
+	id     string
+	body   json.RawMessage
+	before json.RawMessage
+}
+
+// getAuditedEntities returns the entities of the request, a batch write with a JSON array body modifies one entity per element
+func getAuditedEntities(body string, entityId string) []*auditedEntity {
+	var bodyJson json.RawMessage
+	if json.Valid([]byte(body)) {
+		bodyJson = json.RawMessage(body)
+	}
+	if entityId != "" {
+		return []*auditedEntity{{id: entityId, body: bodyJson}}
+	}
+	var elements []json.RawMessage
+	if bodyJson != nil && json.Unmarshal(bodyJson, &elements) == nil {
+		entities := make([]*auditedEntity, 0, len(elements))
+		for _, element := range elements {
+			entities = append(entities, &auditedEntity{id: getJsonId(string(element)), body: element})
+		}
+		return entities
+	}
+	return []*auditedEntity{{id: getJsonId(body), body: bodyJson}}
+}
+
+// getJsonId returns the id field of a JSON object
+func getJsonId(body string) string {
+	entity := struct {
+		ID interface{} `json:"id"`
+	}{}
+	if err := json.Unmarshal([]byte(body), &entity); err != nil {
+		return ""
+	}
+	if id, ok := entity.ID.(string); ok {
+		return id
+	}
+	return ""
+}
+
+func getAuditApplicationType(r *http.Request, snapshots ...json.RawMessage) string {
+	for _, snapshot := range snapshots {
+		entity := struct {
+			ApplicationType string `json:"applicationType"`
+		}{}
+		if snapshot != nil && json.Unmarshal(snapshot, &entity) == nil && entity.ApplicationType != "" {
+			return entity.ApplicationType
+		}
+	}
+	if applicationType := r.URL.Query().Get(xwcommon.APPLICATION_TYPE); applicationType != "" {
+		return applicationType
+	}
+	return xshared.GetApplicationFromCookies(r)
+}
+
+func getSourceIp(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
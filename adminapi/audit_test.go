@@ -0,0 +1,79 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package adminapi
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	xshared "xconfadmin/shared"
+	"xconfadmin/testutil"
+	"xconfwebconfig/db"
+	xwhttp "xconfwebconfig/http"
+	coreef "xconfwebconfig/shared/estbfirmware"
+
+	"github.com/gorilla/mux"
+	"gotest.tools/assert"
+)
+
+func TestAuditBatchWriteAuditsEveryElement(t *testing.T) {
+	testutil.SetupTestDB()
+	existing := coreef.NewEmptyFirmwareConfig()
+	existing.ID = "audit-existing"
+	existing.Description = "before"
+	existing.ApplicationType = "stb"
+	assert.NilError(t, db.GetCachedSimpleDao().SetOne(db.TABLE_FIRMWARE_CONFIG, existing.ID, existing))
+
+	router := mux.NewRouter()
+	router.Use(AuditMiddleware)
+	router.HandleFunc("/xconfAdminService/firmwareconfig/entities", func(w http.ResponseWriter, r *http.Request) {
+		xwhttp.WriteXconfResponse(w, http.StatusOK, []byte(`{}`))
+	}).Methods("POST").Name("Firmware-Configs")
+
+	body := `[{"id":"audit-existing","description":"after","applicationType":"stb"},{"id":"audit-new","description":"new","applicationType":"stb"}]`
+	rr := httptest.NewRecorder()
+	xw := xwhttp.NewXResponseWriter(rr)
+	r := httptest.NewRequest(http.MethodPost, "/xconfAdminService/firmwareconfig/entities", strings.NewReader(body))
+	bytes, _ := ioutil.ReadAll(r.Body)
+	xw.SetBody(string(bytes))
+	router.ServeHTTP(xw, r)
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	entries := xshared.GetAuditEntries(&xshared.AuditFilter{EntityType: "Firmware-Configs"})
+	assert.Equal(t, len(entries), 2)
+	byId := map[string]*xshared.AuditEntry{}
+	for _, entry := range entries {
+		byId[entry.EntityID] = entry
+	}
+
+	updated := byId["audit-existing"]
+	assert.Assert(t, updated != nil)
+	assert.Equal(t, updated.Operation, xshared.AUDIT_UPDATE)
+	assert.Assert(t, strings.Contains(string(updated.Before), `"before"`), string(updated.Before))
+	assert.Equal(t, updated.ApplicationType, "stb")
+
+	created := byId["audit-new"]
+	assert.Assert(t, created != nil)
+	assert.Equal(t, created.Operation, xshared.AUDIT_CREATE)
+	assert.Assert(t, created.Before == nil)
+	assert.Assert(t, strings.Contains(string(created.After), `"new"`), string(created.After))
+	assert.Equal(t, created.RequestId, updated.RequestId)
+}
@@ -61,7 +61,12 @@ func GetAdminUIUrlFromCookies(r *http.Request) string {
 	return adminServiceUrl
 }
 
+// BasicAuthHandler issues dev login tokens, it is only available when login tokens are not verified against JWKS keys
 func BasicAuthHandler(w http.ResponseWriter, r *http.Request) {
+	if !xhttp.IsLegacyLoginTokenEnabled() {
+		http.Error(w, "Basic login is disabled, login tokens are verified against the configured JWKS keys", http.StatusNotFound)
+		return
+	}
 
 	type AuthRequest struct {
 		Username string `json:"login"`
@@ -107,7 +112,7 @@ func BasicAuthHandler(w http.ResponseWriter, r *http.Request) {
 					"exp": time.Now().Add(time.Hour * 24).Unix(),
 				}})
 
-		token, err := claims.SignedString([]byte("xconf"))
+		token, err := claims.SignedString([]byte(xhttp.LegacyLoginTokenSecret))
 		if err != nil {
 			log.Error("Authentication Error : ", err)
 			http.Error(w, "Authentication Error", http.StatusUnauthorized)
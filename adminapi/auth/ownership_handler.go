@@ -0,0 +1,226 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
+	xshared "xconfadmin/shared"
+	xrfc "xconfadmin/shared/rfc"
+	xwcommon "xconfwebconfig/common"
+	xwhttp "xconfwebconfig/http"
+	xwshared "xconfwebconfig/shared"
+	corefw "xconfwebconfig/shared/firmware"
+	"xconfwebconfig/shared/logupload"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	cOwnershipEntityType = "entityType"
+)
+
+// OwnershipInfo is the owner of an entity returned by the ownership api
+type OwnershipInfo struct {
+	EntityType string `json:"entityType"`
+	EntityID   string `json:"entityId"`
+	OwnerGroup string `json:"ownerGroup"`
+}
+
+// getOwnedEntityApplicationType returns the permission entity type and application type of an existing entity
+func getOwnedEntityApplicationType(entityType string, id string) (string, string, bool) {
+	switch entityType {
+	case xshared.OWNED_FIRMWARE_RULE:
+		if rule, err := corefw.GetFirmwareRuleOneDB(id); err == nil && rule != nil {
+			return FIRMWARE_ENTITY, rule.ApplicationType, true
+		}
+	case xshared.OWNED_FEATURE_RULE:
+		if rule := xrfc.GetFeatureRule(id); rule != nil {
+			return FIRMWARE_ENTITY, rule.ApplicationType, true
+		}
+	case xshared.OWNED_DCM_FORMULA:
+		if rule := logupload.GetOneDCMGenericRule(id); rule != nil {
+			return DCM_ENTITY, rule.ApplicationType, true
+		}
+	case xshared.OWNED_TELEMETRY_PROFILE:
+		if profile := logupload.GetOnePermanentTelemetryProfile(id); profile != nil {
+			return TELEMETRY_ENTITY, profile.ApplicationType, true
+		}
+	case xshared.OWNED_TELEMETRY_TWO_PROFILE:
+		if profile := logupload.GetOneTelemetryTwoProfile(id); profile != nil {
+			return TELEMETRY_ENTITY, profile.ApplicationType, true
+		}
+	case xshared.OWNED_NAMESPACED_LIST:
+		if list, err := xwshared.GetGenericNamedListOneDB(id); err == nil && list != nil {
+			return COMMON_ENTITY, "", true
+		}
+	}
+	return "", "", false
+}
+
+func GetEntityOwnerHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	entityType, id := vars[cOwnershipEntityType], vars[xwcommon.ID]
+	permissionEntity, appType, found := getOwnedEntityApplicationType(entityType, id)
+	if !found {
+		xhttp.WriteAdminErrorResponse(w, http.StatusNotFound, entityType+" "+id+" does not exist")
+		return
+	}
+	var err error
+	if appType == "" {
+		_, err = CanRead(r, permissionEntity)
+	} else {
+		err = ValidateRead(r, appType, permissionEntity)
+	}
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	info := OwnershipInfo{
+		EntityType: entityType,
+		EntityID:   id,
+		OwnerGroup: xshared.GetEntityOwner(entityType, id),
+	}
+	res, err := xhttp.ReturnJsonResponse(info, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
+// SetEntityOwnerHandler changes the owner to the ownerGroup query param, an empty group removes the owner
+func SetEntityOwnerHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	entityType, id := vars[cOwnershipEntityType], vars[xwcommon.ID]
+	permissionEntity, appType, found := getOwnedEntityApplicationType(entityType, id)
+	if !found {
+		xhttp.WriteAdminErrorResponse(w, http.StatusNotFound, entityType+" "+id+" does not exist")
+		return
+	}
+	var err error
+	if appType == "" {
+		_, err = CanWrite(r, permissionEntity)
+	} else {
+		err = ValidateWrite(r, appType, permissionEntity)
+	}
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	if err := ValidateOwnership(r, entityType, id); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	if err := ValidateOwnerGroupParam(r); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	group := strings.TrimSpace(r.URL.Query().Get(xcommon.OWNER_GROUP))
+	if group == "" {
+		RemoveOwnership(entityType, id)
+	} else if err := xshared.SetEntityOwner(entityType, id, group, GetUserNameOrUnknown(r)); err != nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	info := OwnershipInfo{
+		EntityType: entityType,
+		EntityID:   id,
+		OwnerGroup: group,
+	}
+	res, err := xhttp.ReturnJsonResponse(info, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
+func GetOwnershipGroupMappingsHandler(w http.ResponseWriter, r *http.Request) {
+	if !HasReadPermissionForTool(r) {
+		xhttp.WriteAdminErrorResponse(w, http.StatusForbidden, "No permission to view ownership groups")
+		return
+	}
+	mappings := xshared.GetOwnershipGroupMappings()
+	sort.Slice(mappings, func(i, j int) bool {
+		return strings.ToLower(mappings[i].ID) < strings.ToLower(mappings[j].ID)
+	})
+	res, err := xhttp.ReturnJsonResponse(mappings, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
+func SetOwnershipGroupMappingHandler(w http.ResponseWriter, r *http.Request) {
+	if !HasOwnershipAdminPermission(r) {
+		xhttp.WriteAdminErrorResponse(w, http.StatusForbidden, "No permission to modify ownership groups")
+		return
+	}
+
+	// r.Body is already drained in the middleware
+	xw, ok := w.(*xwhttp.XResponseWriter)
+	if !ok {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, "Unable to extract body")
+		return
+	}
+	mapping := xshared.OwnershipGroupMapping{}
+	if err := json.Unmarshal([]byte(xw.Body()), &mapping); err != nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// only an ownership admin may grant the admin flag or change the mapping of another admin
+	existing := xshared.GetOwnershipGroupMapping(mapping.ID)
+	if (mapping.Admin || (existing != nil && existing.Admin)) && !isOwnershipAdmin(r) {
+		xhttp.WriteAdminErrorResponse(w, http.StatusForbidden, "Only an ownership admin may modify an admin mapping")
+		return
+	}
+	if err := xshared.SetOwnershipGroupMapping(&mapping); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	res, err := xhttp.ReturnJsonResponse(mapping, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
+func DeleteOwnershipGroupMappingHandler(w http.ResponseWriter, r *http.Request) {
+	if !HasOwnershipAdminPermission(r) {
+		xhttp.WriteAdminErrorResponse(w, http.StatusForbidden, "No permission to modify ownership groups")
+		return
+	}
+	id := mux.Vars(r)[xwcommon.ID]
+	if existing := xshared.GetOwnershipGroupMapping(id); existing != nil && existing.Admin && !isOwnershipAdmin(r) {
+		xhttp.WriteAdminErrorResponse(w, http.StatusForbidden, "Only an ownership admin may modify an admin mapping")
+		return
+	}
+	if err := xshared.DeleteOwnershipGroupMapping(id); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusNoContent, nil)
+}
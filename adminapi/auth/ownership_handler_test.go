@@ -0,0 +1,88 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package auth
+
+import (
+	"net/http"
+	"testing"
+
+	xcommon "xconfadmin/common"
+	xshared "xconfadmin/shared"
+	"xconfadmin/testutil"
+
+	"gotest.tools/assert"
+)
+
+func setTestOwnershipGroupMapping(body string, permissions ...string) int {
+	r := testutil.NewRequest(http.MethodPost, "/xconfAdminService/ownership/groups", body, permissions...)
+	return testutil.Serve(SetOwnershipGroupMappingHandler, r).Code
+}
+
+func deleteTestOwnershipGroupMapping(id string, permissions ...string) int {
+	r := testutil.NewRequest(http.MethodDelete, "/xconfAdminService/ownership/groups/"+id, "", permissions...)
+	r = testutil.WithVars(r, map[string]string{"id": id})
+	return testutil.Serve(DeleteOwnershipGroupMappingHandler, r).Code
+}
+
+func TestOwnershipGroupMappingsRequireOwnershipPermission(t *testing.T) {
+	testutil.SetupTestDB()
+
+	assert.Equal(t, setTestOwnershipGroupMapping(`{"id":"alice","groups":["video"]}`, WRITE_COMMON), http.StatusForbidden)
+	assert.Assert(t, xshared.GetOwnershipGroupMapping("alice") == nil)
+
+	assert.Equal(t, setTestOwnershipGroupMapping(`{"id":"alice","groups":["video"]}`, WRITE_OWNERSHIP), http.StatusOK)
+	assert.DeepEqual(t, xshared.GetOwnershipGroupMapping("alice").Groups, []string{"video"})
+
+	assert.Equal(t, deleteTestOwnershipGroupMapping("alice", WRITE_COMMON), http.StatusForbidden)
+	assert.Equal(t, deleteTestOwnershipGroupMapping("alice", WRITE_OWNERSHIP), http.StatusNoContent)
+	assert.Assert(t, xshared.GetOwnershipGroupMapping("alice") == nil)
+}
+
+func TestOwnershipAdminFlagRequiresOwnershipAdmin(t *testing.T) {
+	testutil.SetupTestDB()
+
+	assert.Equal(t, setTestOwnershipGroupMapping(`{"id":"alice","groups":["video"],"admin":true}`, WRITE_OWNERSHIP), http.StatusForbidden)
+	assert.Assert(t, xshared.GetOwnershipGroupMapping("alice") == nil)
+
+	assert.NilError(t, xshared.SetOwnershipGroupMapping(&xshared.OwnershipGroupMapping{ID: "bob", Groups: []string{"video"}, Admin: true}))
+	assert.Equal(t, setTestOwnershipGroupMapping(`{"id":"bob","groups":["video"]}`, WRITE_OWNERSHIP), http.StatusForbidden)
+	assert.Equal(t, deleteTestOwnershipGroupMapping("bob", WRITE_OWNERSHIP), http.StatusForbidden)
+	assert.Assert(t, xshared.GetOwnershipGroupMapping("bob").Admin)
+
+	// the current user becomes an ownership admin
+	assert.NilError(t, xshared.SetOwnershipGroupMapping(&xshared.OwnershipGroupMapping{ID: testutil.TestUser, Admin: true}))
+	assert.Equal(t, setTestOwnershipGroupMapping(`{"id":"alice","groups":["video"],"admin":true}`, WRITE_OWNERSHIP), http.StatusOK)
+	assert.Assert(t, xshared.GetOwnershipGroupMapping("alice").Admin)
+	assert.Equal(t, deleteTestOwnershipGroupMapping("bob", WRITE_OWNERSHIP), http.StatusNoContent)
+}
+
+func TestNewOwnerFilter(t *testing.T) {
+	testutil.SetupTestDB()
+	assert.NilError(t, xshared.SetEntityOwner(xshared.OWNED_FIRMWARE_RULE, "rule1", "video", testutil.TestUser))
+	assert.NilError(t, xshared.SetEntityOwner(xshared.OWNED_FIRMWARE_RULE, "rule2", "voice", testutil.TestUser))
+	assert.NilError(t, xshared.SetEntityOwner(xshared.OWNED_FEATURE_RULE, "rule3", "video", testutil.TestUser))
+
+	honored := NewOwnerFilter(map[string]string{}, xshared.OWNED_FIRMWARE_RULE)
+	assert.Assert(t, honored("rule1") && honored("rule2") && honored("unowned"))
+
+	honored = NewOwnerFilter(map[string]string{xcommon.OWNER: "VIDEO"}, xshared.OWNED_FIRMWARE_RULE)
+	assert.Assert(t, honored("rule1"))
+	assert.Assert(t, !honored("rule2"))
+	assert.Assert(t, !honored("rule3"))
+	assert.Assert(t, !honored("unowned"))
+}
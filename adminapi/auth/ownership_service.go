@@ -0,0 +1,144 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
+	xshared "xconfadmin/shared"
+	xutil "xconfadmin/util"
+	"xconfwebconfig/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// isOwnershipCheckExempt returns true if ownership is not enforced for the request
+func isOwnershipCheckExempt(r *http.Request) bool {
+	if !isPermissionCheckEnabled(r) || len(xhttp.GetCapabilitiesFromContext(r)) > 0 {
+		return true
+	}
+	_, admin := xhttp.GetOwnershipGroups(r)
+	return admin
+}
+
+// isOwnershipAdmin returns true if the user may grant or revoke the ownership admin flag
+func isOwnershipAdmin(r *http.Request) bool {
+	if !isPermissionCheckEnabled(r) || util.Contains(xhttp.GetCapabilitiesFromContext(r), XCONF_ALL) {
+		return true
+	}
+	_, admin := xhttp.GetOwnershipGroups(r)
+	return admin
+}
+
+func isOwnershipGroupMember(r *http.Request, group string) bool {
+	groups, _ := xhttp.GetOwnershipGroups(r)
+	for _, g := range groups {
+		if strings.EqualFold(g, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateOwnership allows the modification of an unowned entity, or of an entity owned by one of the user's groups
+func ValidateOwnership(r *http.Request, entityType string, entityId string) error {
+	if util.IsBlank(entityId) || isOwnershipCheckExempt(r) {
+		return nil
+	}
+	owner := xshared.GetEntityOwner(entityType, entityId)
+	if owner == "" || isOwnershipGroupMember(r, owner) {
+		return nil
+	}
+	return xcommon.NewXconfError(http.StatusForbidden,
+		fmt.Sprintf("%s %s is owned by group %s, current user is not a member", entityType, entityId, owner))
+}
+
+// ValidateOwnerGroupParam checks that the user may assign the group given in the ownerGroup query param
+func ValidateOwnerGroupParam(r *http.Request) error {
+	group := strings.TrimSpace(r.URL.Query().Get(xcommon.OWNER_GROUP))
+	if group == "" || isOwnershipCheckExempt(r) || isOwnershipGroupMember(r, group) {
+		return nil
+	}
+	return xcommon.NewXconfError(http.StatusForbidden, fmt.Sprintf("Current user is not a member of ownership group %s", group))
+}
+
+// ValidateOwnershipForWrite validates the ownership of the entity and the ownerGroup query param of a write request
+func ValidateOwnershipForWrite(r *http.Request, entityType string, entityId string) error {
+	if err := ValidateOwnership(r, entityType, entityId); err != nil {
+		return err
+	}
+	return ValidateOwnerGroupParam(r)
+}
+
+// AssignOwnership sets the owner of a created entity to the ownerGroup query param,
+// or to the user's only ownership group when the param is not given
+func AssignOwnership(r *http.Request, entityType string, entityId string) {
+	group := strings.TrimSpace(r.URL.Query().Get(xcommon.OWNER_GROUP))
+	if group == "" {
+		if groups, _ := xhttp.GetOwnershipGroups(r); len(groups) == 1 {
+			group = groups[0]
+		}
+	}
+	setOwnership(r, entityType, entityId, group)
+}
+
+// UpdateOwnership changes the owner of an updated entity when the ownerGroup query param is given
+func UpdateOwnership(r *http.Request, entityType string, entityId string) {
+	setOwnership(r, entityType, entityId, strings.TrimSpace(r.URL.Query().Get(xcommon.OWNER_GROUP)))
+}
+
+func setOwnership(r *http.Request, entityType string, entityId string, group string) {
+	if util.IsBlank(entityId) || group == "" {
+		return
+	}
+	if err := xshared.SetEntityOwner(entityType, entityId, group, GetUserNameOrUnknown(r)); err != nil {
+		log.Errorf("unable to set owner of %s %s: %s", entityType, entityId, err.Error())
+	}
+}
+
+// MoveOwnership keeps the owner of a renamed entity
+func MoveOwnership(r *http.Request, entityType string, oldId string, newId string) {
+	owner := xshared.GetEntityOwner(entityType, oldId)
+	if owner == "" || oldId == newId {
+		return
+	}
+	RemoveOwnership(entityType, oldId)
+	setOwnership(r, entityType, newId, owner)
+}
+
+// RemoveOwnership removes the owner of a deleted entity
+func RemoveOwnership(entityType string, entityId string) {
+	xshared.DeleteEntityOwner(entityType, entityId)
+}
+
+// NewOwnerFilter returns a filter honoring the group in the OWNER search context entry,
+// the owners of the entity type are loaded once for the whole search
+func NewOwnerFilter(context map[string]string, entityType string) func(entityId string) bool {
+	owner, found := xutil.FindEntryInContext(context, xcommon.OWNER, false)
+	if !found {
+		return func(string) bool { return true }
+	}
+	owners := xshared.GetEntityOwnersByType(entityType)
+	return func(entityId string) bool {
+		return strings.EqualFold(owners[entityId], owner)
+	}
+}
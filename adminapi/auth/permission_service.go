@@ -28,7 +28,6 @@ import (
 
 	xshared "xconfadmin/shared"
 	xwcommon "xconfwebconfig/common"
-	xwshared "xconfwebconfig/shared"
 	"xconfwebconfig/util"
 )
 
@@ -39,6 +38,10 @@ const (
 	VIEW_TOOLS  string = "view-tools"
 	WRITE_TOOLS string = "write-tools"
 
+	WRITE_OWNERSHIP string = "write-ownership"
+
+	WRITE_APPROVAL_POLICIES string = "write-approval-policies"
+
 	READ_DCM_STB      string = "read-dcm-stb"
 	READ_DCM_RDLCLOUD string = "read-dcm-rdkcloud"
 	READ_DCM_ALL      string = "read-dcm-*"
@@ -170,7 +173,7 @@ func getEntityPermission(entityType string) *EntityPermission {
 }
 
 func HasReadPermissionForTool(r *http.Request) bool {
-	if !(xcommon.SatOn) {
+	if !isPermissionCheckEnabled(r) {
 		return true
 	}
 
@@ -190,7 +193,7 @@ func HasReadPermissionForTool(r *http.Request) bool {
 }
 
 func HasWritePermissionForTool(r *http.Request) bool {
-	if !(xcommon.SatOn) {
+	if !isPermissionCheckEnabled(r) {
 		return true
 	}
 
@@ -209,13 +212,53 @@ func HasWritePermissionForTool(r *http.Request) bool {
 	return false
 }
 
+// HasOwnershipAdminPermission returns true if the user may manage the ownership group mappings,
+// which requires the full xconf capability or the write-ownership permission
+func HasOwnershipAdminPermission(r *http.Request) bool {
+	if !isPermissionCheckEnabled(r) {
+		return true
+	}
+	if xhttp.IsServiceAccountRequest(r) {
+		return false
+	}
+	if capabilities := xhttp.GetCapabilitiesFromContext(r); len(capabilities) > 0 {
+		return util.Contains(capabilities, XCONF_ALL)
+	}
+	return util.Contains(GetPermissionsFunc(r), WRITE_OWNERSHIP)
+}
+
+// HasApprovalAdminPermission returns true if the user may change the approval settings and policies,
+// which requires the full xconf capability or the write-approval-policies permission
+func HasApprovalAdminPermission(r *http.Request) bool {
+	if !isPermissionCheckEnabled(r) {
+		return true
+	}
+	if xhttp.IsServiceAccountRequest(r) {
+		return false
+	}
+	if capabilities := xhttp.GetCapabilitiesFromContext(r); len(capabilities) > 0 {
+		return util.Contains(capabilities, XCONF_ALL)
+	}
+	return util.Contains(GetPermissionsFunc(r), WRITE_APPROVAL_POLICIES)
+}
+
+// HasPermission returns true if the user holds the permission or the wildcard permission covering it
+func HasPermission(r *http.Request, permission string) bool {
+	if !isPermissionCheckEnabled(r) {
+		return true
+	}
+
+	// checked capabilities from SAT token if available
+	if capabilities := xhttp.GetCapabilitiesFromContext(r); len(capabilities) > 0 {
+		return util.Contains(capabilities, XCONF_ALL) || util.Contains(capabilities, XCONF_WRITE)
+	}
+	permissions := GetPermissionsFunc(r)
+	return util.Contains(permissions, permission) || holdsWildcardOf(permissions, permission)
+}
+
 // CanWrite returns the applicationType the user has write permission for non-common entityType,
 // otherwise returns error if applicationType is not specified in query parameter, cookie, or vargs param
 func CanWrite(r *http.Request, entityType string, vargs ...string) (applicationType string, err error) {
-	if isReadonlyMode() {
-		return "", xcommon.NewXconfError(http.StatusForbidden, "Modification not allowed in read-only mode")
-	}
-
 	if entityType != COMMON_ENTITY && entityType != TOOL_ENTITY {
 		if values, ok := r.URL.Query()[xwcommon.APPLICATION_TYPE]; ok {
 			applicationType = values[0]
@@ -233,7 +276,7 @@ func CanWrite(r *http.Request, entityType string, vargs ...string) (applicationT
 		}
 	}
 
-	if !(xcommon.SatOn) {
+	if !isPermissionCheckEnabled(r) {
 		return applicationType, nil
 	}
 
@@ -243,15 +286,11 @@ func CanWrite(r *http.Request, entityType string, vargs ...string) (applicationT
 			return "", xcommon.NewXconfError(http.StatusForbidden, "No write capabilities")
 		}
 		return applicationType, nil
-	} else {
-		// checked permissions from Login token
-		permissions := GetPermissionsFunc(r)
-		if util.Contains(permissions, getEntityPermission(entityType).WriteAll) {
-			return applicationType, nil
-		}
-		if xwshared.STB == applicationType && util.Contains(permissions, getEntityPermission(entityType).WriteStb) {
-			return applicationType, nil
-		}
+	}
+
+	// checked permissions from Login token
+	if hasWritePermission(GetPermissionsFunc(r), entityType, applicationType) {
+		return applicationType, nil
 	}
 
 	if applicationType == "" {
@@ -261,6 +300,27 @@ func CanWrite(r *http.Request, entityType string, vargs ...string) (applicationT
 	}
 }
 
+// CanWriteApplicationType returns error if the user has no write permission for the entityType in the given applicationType,
+// it checks a write to another applicationType than the one of the request
+func CanWriteApplicationType(r *http.Request, entityType string, applicationType string) error {
+	if err := xshared.ValidateApplicationType(applicationType); err != nil {
+		return err
+	}
+	if !isPermissionCheckEnabled(r) {
+		return nil
+	}
+	if capabilities := xhttp.GetCapabilitiesFromContext(r); len(capabilities) > 0 {
+		if !(util.Contains(capabilities, XCONF_ALL) || util.Contains(capabilities, XCONF_WRITE)) {
+			return xcommon.NewXconfError(http.StatusForbidden, "No write capabilities")
+		}
+		return nil
+	}
+	if !hasWritePermission(GetPermissionsFunc(r), entityType, applicationType) {
+		return xcommon.NewXconfError(http.StatusForbidden, "No write permission for ApplicationType "+applicationType)
+	}
+	return nil
+}
+
 // CanRead returns the applicationType the user has read permission for non-common entityType,
 // otherwise returns error if applicationType is not specified in query parameter, cookie, or vargs param.
 func CanRead(r *http.Request, entityType string, vargs ...string) (applicationType string, err error) {
@@ -281,7 +341,7 @@ func CanRead(r *http.Request, entityType string, vargs ...string) (applicationTy
 		}
 	}
 
-	if !(xcommon.SatOn) {
+	if !isPermissionCheckEnabled(r) {
 		return applicationType, nil
 	}
 
@@ -291,15 +351,11 @@ func CanRead(r *http.Request, entityType string, vargs ...string) (applicationTy
 			return "", xcommon.NewXconfError(http.StatusForbidden, "No read capabilities")
 		}
 		return applicationType, nil
-	} else {
-		// checked permissions from Login token
-		permissions := GetPermissionsFunc(r)
-		if util.Contains(permissions, getEntityPermission(entityType).ReadAll) {
-			return applicationType, nil
-		}
-		if xwshared.STB == applicationType && util.Contains(permissions, getEntityPermission(entityType).ReadStb) {
-			return applicationType, nil
-		}
+	}
+
+	// checked permissions from Login token
+	if hasReadPermission(GetPermissionsFunc(r), entityType, applicationType) {
+		return applicationType, nil
 	}
 
 	if applicationType == "" {
@@ -309,22 +365,91 @@ func CanRead(r *http.Request, entityType string, vargs ...string) (applicationTy
 	}
 }
 
+// getScopedPermission derives the applicationType permission from the entity's wildcard permission,
+// e.g. read-firmware-* and suffix rdkcloud gives read-firmware-rdkcloud
+func getScopedPermission(allPermission string, applicationType string) string {
+	if !strings.HasSuffix(allPermission, "*") || applicationType == "" {
+		return ""
+	}
+	suffix := xshared.GetApplicationTypePermissionSuffix(applicationType)
+	if suffix == "" {
+		return ""
+	}
+	return strings.TrimSuffix(allPermission, "*") + suffix
+}
+
+// hasReadPermission checks the entity's read-all permission or the one scoped to the applicationType
+func hasReadPermission(permissions []string, entityType string, applicationType string) bool {
+	entityPermission := getEntityPermission(entityType)
+	if entityPermission == nil {
+		return false
+	}
+	if util.Contains(permissions, entityPermission.ReadAll) {
+		return true
+	}
+	if scoped := getScopedPermission(entityPermission.ReadAll, applicationType); scoped != "" {
+		return util.Contains(permissions, scoped)
+	}
+	return false
+}
+
+// hasWritePermission checks the entity's write-all permission or the one scoped to the applicationType
+func hasWritePermission(permissions []string, entityType string, applicationType string) bool {
+	entityPermission := getEntityPermission(entityType)
+	if entityPermission == nil {
+		return false
+	}
+	if util.Contains(permissions, entityPermission.WriteAll) {
+		return true
+	}
+	if scoped := getScopedPermission(entityPermission.WriteAll, applicationType); scoped != "" {
+		return util.Contains(permissions, scoped)
+	}
+	return false
+}
+
 var GetPermissionsFunc = getPermissions
 
 func getPermissions(r *http.Request) (permissions []string) {
+	if xhttp.IsServiceAccountRequest(r) {
+		// api keys are limited to their own permissions in every profile
+		return xhttp.GetPermissionsFromContext(r)
+	}
 	if IsDevProfile() {
 		permissions = []string{
 			WRITE_COMMON, READ_COMMON,
 			WRITE_FIRMWARE_ALL, READ_FIRMWARE_ALL,
 			WRITE_DCM_ALL, READ_DCM_ALL,
 			WRITE_TELEMETRY_ALL, READ_TELEMETRY_ALL,
-			READ_CHANGES_ALL, WRITE_CHANGES_ALL}
+			READ_CHANGES_ALL, WRITE_CHANGES_ALL,
+			WRITE_OWNERSHIP, WRITE_APPROVAL_POLICIES}
 	} else {
 		permissions = xhttp.GetPermissionsFromContext(r)
 	}
 	return permissions
 }
 
+// isPermissionCheckEnabled returns true if SAT is on or the request is authenticated with an api key
+func isPermissionCheckEnabled(r *http.Request) bool {
+	return xcommon.SatOn || xhttp.IsServiceAccountRequest(r)
+}
+
+// IsKnownPermission returns true if the permission is one of the entity permissions
+// or a wildcard permission scoped to a registered application type
+func IsKnownPermission(permission string) bool {
+	for _, entityPermission := range []*EntityPermission{&CommonPermissions, &ToolPermissions, &FirmwarePermissions, &ChangePermissions, &DcmPermissions, &TelemetryPermissions} {
+		if permission == entityPermission.ReadAll || permission == entityPermission.WriteAll {
+			return true
+		}
+		for _, appType := range xshared.GetApplicationTypes() {
+			if permission == getScopedPermission(entityPermission.ReadAll, appType.ID) || permission == getScopedPermission(entityPermission.WriteAll, appType.ID) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func IsDevProfile() bool {
 	activeProfiles := strings.Split(strings.TrimSpace(xcommon.ActiveAuthProfiles), ",")
 	if len(activeProfiles) > 0 {
@@ -342,6 +467,10 @@ func ValidateRead(r *http.Request, entityApplicationType string, entityType stri
 	if err != nil {
 		return err
 	}
+	if isPermissionCheckEnabled(r) && len(xhttp.GetCapabilitiesFromContext(r)) == 0 && !hasReadPermission(GetPermissionsFunc(r), entityType, entityApplicationType) {
+		return xcommon.NewXconfError(http.StatusForbidden,
+			fmt.Sprintf("No read permission for entity's ApplicationType %s", entityApplicationType))
+	}
 	if applicationType != entityApplicationType {
 		return xcommon.NewXconfError(http.StatusForbidden,
 			fmt.Sprintf("Current ApplicationType %s doesn't match with entity's ApplicationType: %s", applicationType, entityApplicationType))
@@ -357,6 +486,10 @@ func ValidateWrite(r *http.Request, entityApplicationType string, entityType str
 	if err != nil {
 		return err
 	}
+	if isPermissionCheckEnabled(r) && len(xhttp.GetCapabilitiesFromContext(r)) == 0 && !hasWritePermission(GetPermissionsFunc(r), entityType, entityApplicationType) {
+		return xcommon.NewXconfError(http.StatusForbidden,
+			fmt.Sprintf("No write permission for entity's ApplicationType %s", entityApplicationType))
+	}
 	if applicationType != entityApplicationType {
 		return xcommon.NewXconfError(http.StatusForbidden,
 			fmt.Sprintf("Current ApplicationType %s doesn't match with entity's ApplicationType: %s", applicationType, entityApplicationType))
@@ -364,10 +497,6 @@ func ValidateWrite(r *http.Request, entityApplicationType string, entityType str
 	return nil
 }
 
-func isReadonlyMode() bool {
-	return xcommon.GetBooleanAppSetting(xcommon.READONLY_MODE, false)
-}
-
 func GetUserNameOrUnknown(r *http.Request) string {
 	if userName := r.Header.Get(xhttp.AUTH_SUBJECT); userName == "" {
 		return xhttp.UNKNOWN_USER
@@ -0,0 +1,84 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package auth
+
+import (
+	"net/http"
+	"testing"
+
+	xcommon "xconfadmin/common"
+	"xconfadmin/testutil"
+
+	"gotest.tools/assert"
+)
+
+func getErrorStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	return xcommon.GetXconfErrorStatusCode(err)
+}
+
+func TestRdkcloudPermissionsAreHonoredForAllEntityTypes(t *testing.T) {
+	testutil.SetupTestDB()
+	permissions := map[string]EntityPermission{
+		FIRMWARE_ENTITY:  FirmwarePermissions,
+		DCM_ENTITY:       DcmPermissions,
+		TELEMETRY_ENTITY: TelemetryPermissions,
+		CHANGE_ENTITY:    ChangePermissions,
+	}
+	for entityType, entityPermission := range permissions {
+		r := testutil.NewRequest(http.MethodGet, "/xconfAdminService/test?applicationType=rdkcloud", "", entityPermission.ReadRdkcloud)
+		applicationType, err := CanRead(r, entityType)
+		assert.NilError(t, err, entityType)
+		assert.Equal(t, applicationType, "rdkcloud")
+		_, err = CanWrite(r, entityType)
+		assert.Equal(t, getErrorStatus(err), http.StatusForbidden, entityType)
+
+		r = testutil.NewRequest(http.MethodPost, "/xconfAdminService/test?applicationType=rdkcloud", "", entityPermission.WriteRdkcloud)
+		applicationType, err = CanWrite(r, entityType)
+		assert.NilError(t, err, entityType)
+		assert.Equal(t, applicationType, "rdkcloud")
+
+		r = testutil.NewRequest(http.MethodPost, "/xconfAdminService/test?applicationType=stb", "", entityPermission.ReadRdkcloud, entityPermission.WriteRdkcloud)
+		_, err = CanRead(r, entityType)
+		assert.Equal(t, getErrorStatus(err), http.StatusForbidden, entityType)
+		_, err = CanWrite(r, entityType)
+		assert.Equal(t, getErrorStatus(err), http.StatusForbidden, entityType)
+	}
+}
+
+func TestValidateRejectsEntitiesOfAnotherApplicationType(t *testing.T) {
+	testutil.SetupTestDB()
+	r := testutil.NewRequest(http.MethodGet, "/xconfAdminService/test?applicationType=rdkcloud", "", READ_FIRMWARE_RDKCLOUD, WRITE_FIRMWARE_RDKCLOUD)
+	assert.NilError(t, ValidateRead(r, "rdkcloud", FIRMWARE_ENTITY))
+	assert.NilError(t, ValidateWrite(r, "rdkcloud", FIRMWARE_ENTITY))
+
+	err := ValidateRead(r, "stb", FIRMWARE_ENTITY)
+	assert.Equal(t, getErrorStatus(err), http.StatusForbidden)
+	assert.ErrorContains(t, err, "No read permission for entity's ApplicationType stb")
+	err = ValidateWrite(r, "stb", FIRMWARE_ENTITY)
+	assert.Equal(t, getErrorStatus(err), http.StatusForbidden)
+	assert.ErrorContains(t, err, "No write permission for entity's ApplicationType stb")
+
+	// a user of both application types still can't touch the entity through the other application type
+	r = testutil.NewRequest(http.MethodGet, "/xconfAdminService/test?applicationType=rdkcloud", "", READ_FIRMWARE_ALL, WRITE_FIRMWARE_ALL)
+	err = ValidateRead(r, "stb", FIRMWARE_ENTITY)
+	assert.Equal(t, getErrorStatus(err), http.StatusForbidden)
+	assert.ErrorContains(t, err, "doesn't match with entity's ApplicationType: stb")
+}
@@ -0,0 +1,286 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
+	xshared "xconfadmin/shared"
+	xwcommon "xconfwebconfig/common"
+	xwhttp "xconfwebconfig/http"
+	"xconfwebconfig/util"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	cServiceAccountKeyId = "keyId"
+)
+
+// IssuedServiceAccountKey is returned once when the key is issued, ApiKey is not stored
+type IssuedServiceAccountKey struct {
+	*xshared.ServiceAccountKey
+	ApiKey string `json:"apiKey"`
+}
+
+// canManageServiceAccounts requires tools permission, api keys can't manage service accounts
+func canManageServiceAccounts(r *http.Request, write bool) error {
+	if xhttp.IsServiceAccountRequest(r) {
+		return xcommon.NewXconfError(http.StatusForbidden, "Service accounts can't be managed with an api key")
+	}
+	if write && !HasWritePermissionForTool(r) || !write && !HasReadPermissionForTool(r) {
+		return xcommon.NewXconfError(http.StatusForbidden, "No permission to manage service accounts")
+	}
+	return nil
+}
+
+func GetServiceAccountsHandler(w http.ResponseWriter, r *http.Request) {
+	if err := canManageServiceAccounts(r, false); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	accounts := xshared.GetServiceAccounts()
+	sort.Slice(accounts, func(i, j int) bool {
+		return strings.ToLower(accounts[i].Name) < strings.ToLower(accounts[j].Name)
+	})
+	res, err := xhttp.ReturnJsonResponse(accounts, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
+func GetServiceAccountByIdHandler(w http.ResponseWriter, r *http.Request) {
+	if err := canManageServiceAccounts(r, false); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	account := xshared.GetServiceAccount(mux.Vars(r)[xwcommon.ID])
+	if account == nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusNotFound, "ServiceAccount does not exist")
+		return
+	}
+	res, err := xhttp.ReturnJsonResponse(account, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
+func CreateServiceAccountHandler(w http.ResponseWriter, r *http.Request) {
+	saveServiceAccount(w, r, true)
+}
+
+func UpdateServiceAccountHandler(w http.ResponseWriter, r *http.Request) {
+	saveServiceAccount(w, r, false)
+}
+
+func saveServiceAccount(w http.ResponseWriter, r *http.Request, create bool) {
+	if err := canManageServiceAccounts(r, true); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	// r.Body is already drained in the middleware
+	xw, ok := w.(*xwhttp.XResponseWriter)
+	if !ok {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, "Unable to extract body")
+		return
+	}
+	account := xshared.ServiceAccount{}
+	if err := json.Unmarshal([]byte(xw.Body()), &account); err != nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	status := http.StatusOK
+	if create {
+		account.ID = ""
+		account.Created = 0
+		account.CreatedBy = GetUserNameOrUnknown(r)
+		status = http.StatusCreated
+	} else {
+		existing := xshared.GetServiceAccount(account.ID)
+		if util.IsBlank(account.ID) || existing == nil {
+			xhttp.WriteAdminErrorResponse(w, http.StatusNotFound, "ServiceAccount "+account.ID+" does not exist")
+			return
+		}
+		account.Created = existing.Created
+		account.CreatedBy = existing.CreatedBy
+	}
+	account.Name = strings.TrimSpace(account.Name)
+	if err := account.Validate(); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	if err := xshared.SetServiceAccount(&account); err != nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	res, err := xhttp.ReturnJsonResponse(account, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteResponseBytes(w, res, status, xhttp.ContextTypeHeader(r))
+}
+
+func DeleteServiceAccountHandler(w http.ResponseWriter, r *http.Request) {
+	if err := canManageServiceAccounts(r, true); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	id := mux.Vars(r)[xwcommon.ID]
+	if xshared.GetServiceAccount(id) == nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusNotFound, "ServiceAccount does not exist")
+		return
+	}
+	if err := xshared.DeleteServiceAccount(id); err != nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusNoContent, nil)
+}
+
+func GetServiceAccountKeysHandler(w http.ResponseWriter, r *http.Request) {
+	if err := canManageServiceAccounts(r, false); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	id := mux.Vars(r)[xwcommon.ID]
+	if xshared.GetServiceAccount(id) == nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusNotFound, "ServiceAccount does not exist")
+		return
+	}
+	keys := []*xshared.ServiceAccountKey{}
+	for _, key := range xshared.GetServiceAccountKeys(id) {
+		keys = append(keys, key.WithoutHash())
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Created > keys[j].Created
+	})
+	res, err := xhttp.ReturnJsonResponse(keys, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
+func IssueServiceAccountKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if err := canManageServiceAccounts(r, true); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	id := mux.Vars(r)[xwcommon.ID]
+	if xshared.GetServiceAccount(id) == nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusNotFound, "ServiceAccount does not exist")
+		return
+	}
+
+	xw, ok := w.(*xwhttp.XResponseWriter)
+	if !ok {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, "Unable to extract body")
+		return
+	}
+	key := xshared.ServiceAccountKey{}
+	if err := json.Unmarshal([]byte(xw.Body()), &key); err != nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validateServiceAccountKeyPermissions(r, key.Permissions); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	if key.ExpiresAt > 0 && key.ExpiresAt <= time.Now().UnixMilli() {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, "expiresAt must be in the future")
+		return
+	}
+	key.AccountID = id
+	key.CreatedBy = GetUserNameOrUnknown(r)
+
+	apiKey, err := xshared.IssueServiceAccountKey(&key)
+	if err != nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	issued := IssuedServiceAccountKey{
+		ServiceAccountKey: key.WithoutHash(),
+		ApiKey:            apiKey,
+	}
+	res, err := xhttp.ReturnJsonResponse(issued, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteResponseBytes(w, res, http.StatusCreated, xhttp.ContextTypeHeader(r))
+}
+
+func RevokeServiceAccountKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if err := canManageServiceAccounts(r, true); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	vars := mux.Vars(r)
+	key := xshared.GetServiceAccountKey(vars[cServiceAccountKeyId])
+	if key == nil || key.AccountID != vars[xwcommon.ID] {
+		xhttp.WriteAdminErrorResponse(w, http.StatusNotFound, "ServiceAccountKey does not exist")
+		return
+	}
+	if !key.IsRevoked() {
+		key.RevokedAt = time.Now().UnixMilli()
+		key.RevokedBy = GetUserNameOrUnknown(r)
+		if err := xshared.SetServiceAccountKey(key); err != nil {
+			xhttp.WriteAdminErrorResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusNoContent, nil)
+}
+
+// validateServiceAccountKeyPermissions accepts known permissions the current user holds
+func validateServiceAccountKeyPermissions(r *http.Request, permissions []string) error {
+	if len(permissions) == 0 {
+		return xcommon.NewXconfError(http.StatusBadRequest, "At least one permission is required")
+	}
+	userPermissions := GetPermissionsFunc(r)
+	for _, permission := range permissions {
+		if !IsKnownPermission(permission) {
+			return xcommon.NewXconfError(http.StatusBadRequest, "Unknown permission "+permission)
+		}
+		if xcommon.SatOn && len(xhttp.GetCapabilitiesFromContext(r)) == 0 && !util.Contains(userPermissions, permission) && !holdsWildcardOf(userPermissions, permission) {
+			return xcommon.NewXconfError(http.StatusForbidden, "Permission "+permission+" can't be granted by the current user")
+		}
+	}
+	return nil
+}
+
+func holdsWildcardOf(permissions []string, permission string) bool {
+	if i := strings.LastIndex(permission, "-"); i > 0 {
+		return util.Contains(permissions, permission[:i+1]+"*")
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	xcommon "xconfadmin/common"
+	xshared "xconfadmin/shared"
+	"xconfadmin/testutil"
+
+	"gotest.tools/assert"
+)
+
+func createTestServiceAccount(t *testing.T, name string) *xshared.ServiceAccount {
+	r := testutil.NewRequest(http.MethodPost, "/xconfAdminService/serviceaccount", `{"name":"`+name+`"}`, WRITE_COMMON)
+	rr := testutil.Serve(CreateServiceAccountHandler, r)
+	assert.Equal(t, rr.Code, http.StatusCreated, rr.Body.String())
+	account := &xshared.ServiceAccount{}
+	assert.NilError(t, json.Unmarshal(rr.Body.Bytes(), account))
+	assert.Equal(t, account.CreatedBy, testutil.TestUser)
+	return account
+}
+
+func issueTestServiceAccountKey(account *xshared.ServiceAccount, body string, permissions ...string) (*IssuedServiceAccountKey, int) {
+	r := testutil.NewRequest(http.MethodPost, "/xconfAdminService/serviceaccount/"+account.ID+"/keys", body, permissions...)
+	r = testutil.WithVars(r, map[string]string{"id": account.ID})
+	rr := testutil.Serve(IssueServiceAccountKeyHandler, r)
+	if rr.Code != http.StatusCreated {
+		return nil, rr.Code
+	}
+	issued := &IssuedServiceAccountKey{}
+	json.Unmarshal(rr.Body.Bytes(), issued)
+	return issued, rr.Code
+}
+
+func TestServiceAccountKeyPermissionsAreScoped(t *testing.T) {
+	testutil.SetupTestDB()
+	// api keys are checked even when SAT is off
+	xcommon.SatOn = false
+	defer func() { xcommon.SatOn = true }()
+
+	account := createTestServiceAccount(t, "deployer")
+	issued, status := issueTestServiceAccountKey(account, `{"name":"ci","permissions":["write-firmware-stb"]}`, WRITE_COMMON, WRITE_FIRMWARE_ALL)
+	assert.Equal(t, status, http.StatusCreated)
+	assert.Equal(t, issued.Hash, "")
+
+	r, err := testutil.NewApiKeyRequest(http.MethodPost, "/xconfAdminService/firmwareconfig?applicationType=stb", "", issued.ApiKey)
+	assert.NilError(t, err)
+	assert.Equal(t, GetUserNameOrUnknown(r), "serviceaccount:deployer")
+	_, err = CanWrite(r, FIRMWARE_ENTITY)
+	assert.NilError(t, err)
+	_, err = CanRead(r, FIRMWARE_ENTITY)
+	assert.Equal(t, getErrorStatus(err), http.StatusForbidden)
+	_, err = CanWrite(r, DCM_ENTITY)
+	assert.Equal(t, getErrorStatus(err), http.StatusForbidden)
+
+	r, err = testutil.NewApiKeyRequest(http.MethodPost, "/xconfAdminService/firmwareconfig?applicationType=rdkcloud", "", issued.ApiKey)
+	assert.NilError(t, err)
+	_, err = CanWrite(r, FIRMWARE_ENTITY)
+	assert.Equal(t, getErrorStatus(err), http.StatusForbidden)
+
+	// the dev profile permissions are not granted to api keys
+	assert.DeepEqual(t, GetPermissionsFunc(r), []string{WRITE_FIRMWARE_STB})
+
+	// api keys can't manage service accounts, not even with the tools permissions
+	keyRequest, err := testutil.NewApiKeyRequest(http.MethodGet, "/xconfAdminService/serviceaccount", "", issued.ApiKey)
+	assert.NilError(t, err)
+	assert.Equal(t, testutil.Serve(GetServiceAccountsHandler, keyRequest).Code, http.StatusForbidden)
+}
+
+func TestServiceAccountKeyCanOnlyGrantHeldPermissions(t *testing.T) {
+	testutil.SetupTestDB()
+	account := createTestServiceAccount(t, "deployer")
+
+	_, status := issueTestServiceAccountKey(account, `{"permissions":["write-firmware-stb"]}`, WRITE_COMMON, WRITE_FIRMWARE_RDKCLOUD)
+	assert.Equal(t, status, http.StatusForbidden)
+	_, status = issueTestServiceAccountKey(account, `{"permissions":["write-firmware-rdkcloud"]}`, WRITE_COMMON, WRITE_FIRMWARE_RDKCLOUD)
+	assert.Equal(t, status, http.StatusCreated)
+	_, status = issueTestServiceAccountKey(account, `{"permissions":["write-firmware-unknown"]}`, WRITE_COMMON, WRITE_FIRMWARE_ALL)
+	assert.Equal(t, status, http.StatusBadRequest)
+	_, status = issueTestServiceAccountKey(account, `{"permissions":[]}`, WRITE_COMMON)
+	assert.Equal(t, status, http.StatusBadRequest)
+	// issuing keys needs the tools write permission, which is the common write permission
+	_, status = issueTestServiceAccountKey(account, `{"permissions":["write-firmware-stb"]}`, WRITE_FIRMWARE_ALL)
+	assert.Equal(t, status, http.StatusForbidden)
+}
+
+func TestServiceAccountKeyValidation(t *testing.T) {
+	testutil.SetupTestDB()
+	account := createTestServiceAccount(t, "deployer")
+	issued, _ := issueTestServiceAccountKey(account, `{"permissions":["read-firmware-*"]}`, WRITE_COMMON, READ_FIRMWARE_ALL)
+
+	_, _, err := xshared.ValidateApiKey(issued.ApiKey)
+	assert.NilError(t, err)
+	assert.Assert(t, xshared.GetServiceAccountKey(issued.ID).LastUsed > 0)
+
+	_, _, err = xshared.ValidateApiKey(issued.ApiKey + "x")
+	assert.Equal(t, err, xshared.ErrInvalidApiKey)
+	_, _, err = xshared.ValidateApiKey("xak." + issued.ID)
+	assert.Equal(t, err, xshared.ErrInvalidApiKey)
+
+	expiresAt := time.Now().Add(-time.Minute).UnixMilli()
+	_, status := issueTestServiceAccountKey(account, fmt.Sprintf(`{"permissions":["read-firmware-*"],"expiresAt":%d}`, expiresAt), WRITE_COMMON, READ_FIRMWARE_ALL)
+	assert.Equal(t, status, http.StatusBadRequest)
+	key := xshared.GetServiceAccountKey(issued.ID)
+	key.ExpiresAt = expiresAt
+	assert.NilError(t, xshared.SetServiceAccountKey(key))
+	_, _, err = xshared.ValidateApiKey(issued.ApiKey)
+	assert.ErrorContains(t, err, "expired")
+
+	issued, _ = issueTestServiceAccountKey(account, `{"permissions":["read-firmware-*"]}`, WRITE_COMMON, READ_FIRMWARE_ALL)
+	r := testutil.NewRequest(http.MethodDelete, "/xconfAdminService/serviceaccount/"+account.ID+"/keys/"+issued.ID, "", WRITE_COMMON)
+	r = testutil.WithVars(r, map[string]string{"id": account.ID, "keyId": issued.ID})
+	assert.Equal(t, testutil.Serve(RevokeServiceAccountKeyHandler, r).Code, http.StatusNoContent)
+	_, err = testutil.NewApiKeyRequest(http.MethodGet, "/xconfAdminService/firmwareconfig", "", issued.ApiKey)
+	assert.ErrorContains(t, err, "revoked")
+
+	issued, _ = issueTestServiceAccountKey(account, `{"permissions":["read-firmware-*"]}`, WRITE_COMMON, READ_FIRMWARE_ALL)
+	account.Disabled = true
+	assert.NilError(t, xshared.SetServiceAccount(account))
+	_, _, err = xshared.ValidateApiKey(issued.ApiKey)
+	assert.ErrorContains(t, err, "disabled")
+}
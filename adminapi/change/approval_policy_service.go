@@ -0,0 +1,84 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package change
+
+import (
+	"fmt"
+	"net/http"
+
+	"xconfadmin/adminapi/auth"
+	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
+	xchange "xconfadmin/shared/change"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// recordApproval checks the current user against the approval policy of the entity type
+// and returns the approvals of the change including the user's one
+func recordApproval(r *http.Request, changeId string, entityType string, applicationType string, author string, approvals []xchange.ChangeApproval) ([]xchange.ChangeApproval, *xchange.ApprovalPolicy, error) {
+	if err := validateApprovable(changeId); err != nil {
+		return nil, nil, err
+	}
+	policy, err := getReviewPolicy(r, entityType, applicationType)
+	if err != nil {
+		return nil, nil, err
+	}
+	approver := auth.GetUserNameOrUnknown(r)
+	if approver == xhttp.UNKNOWN_USER {
+		// an anonymous approver can not be told apart from an anonymous author
+		author = ""
+	}
+	approvals, err = policy.AddApproval(approvals, author, approver)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the review stays pending until the approvals meet the quorum
+	if policy.IsQuorumMet(approvals) {
+		if err := xchange.SetChangeReviewStatus(changeId, xchange.REVIEW_APPROVED, approver, ""); err != nil {
+			log.Errorf("unable to save review status of change %s: %s", changeId, err.Error())
+		}
+	}
+	log.Infof("%s change approved by %s, %d of %d approvals", entityType, approver, len(approvals), policy.RequiredApprovals)
+	return approvals, policy, nil
+}
+
+// getReviewPolicy returns the approval policy of the entity type if the current user may approve or reject its changes
+func getReviewPolicy(r *http.Request, entityType string, applicationType string) (*xchange.ApprovalPolicy, error) {
+	policy := xchange.GetApprovalPolicy(entityType, applicationType)
+	if policy.ApproverPermission != "" && !auth.HasPermission(r, policy.ApproverPermission) {
+		return nil, xcommon.NewXconfError(http.StatusForbidden, fmt.Sprintf("%s permission is required to review changes of %s", policy.ApproverPermission, entityType))
+	}
+	return policy, nil
+}
+
+// recordTelemetryChangeApproval returns the approvals of the telemetry profile change and true if they meet the quorum,
+// otherwise the approvals are stored until the quorum is met
+func recordTelemetryChangeApproval(r *http.Request, entityType string, changeId string, applicationType string, author string) ([]xchange.ChangeApproval, bool, error) {
+	approvals, policy, err := recordApproval(r, changeId, entityType, applicationType, author, xchange.GetChangeApprovals(changeId))
+	if err != nil {
+		return nil, false, err
+	}
+	if policy.IsQuorumMet(approvals) {
+		return approvals, true, nil
+	}
+	if err := xchange.SetChangeApprovals(changeId, approvals); err != nil {
+		return nil, false, err
+	}
+	return approvals, false, nil
+}
@@ -0,0 +1,100 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package change
+
+import (
+	"net/http"
+	"testing"
+
+	"xconfadmin/adminapi/auth"
+	xhttp "xconfadmin/http"
+	xchange "xconfadmin/shared/change"
+	"xconfadmin/testutil"
+	xwchange "xconfwebconfig/shared/change"
+
+	"gotest.tools/assert"
+)
+
+func TestDefaultApprovalPolicyAllowsSelfApproval(t *testing.T) {
+	testutil.SetupTestDB()
+	store := newMemoryProfileStore()
+	engine := &telemetryChangeEngine{store: store}
+	store.addChange("change-1", xwchange.Create, "profile-1", 1)
+
+	approved, err := engine.approve(newApproverRequest("author"), "change-1")
+	assert.NilError(t, err)
+	assert.Assert(t, approved != nil)
+	assert.Assert(t, store.ProfileExists("profile-1"))
+}
+
+func TestUnknownApproverIsNotTheAuthor(t *testing.T) {
+	testutil.SetupTestDB()
+	setApprovalPolicy(t, 1, false)
+	store := newMemoryProfileStore()
+	engine := &telemetryChangeEngine{store: store}
+	store.addChange("change-1", xwchange.Create, "profile-1", 1)
+	store.changes["change-1"].Author = xhttp.UNKNOWN_USER
+
+	approved, err := engine.approve(newApproverRequest(""), "change-1")
+	assert.NilError(t, err)
+	assert.Assert(t, approved != nil)
+}
+
+func TestApprovedChangeKeepsApprovals(t *testing.T) {
+	testutil.SetupTestDB()
+	setApprovalPolicy(t, 2, false)
+	store := newMemoryProfileStore()
+	engine := &telemetryChangeEngine{store: store}
+	store.addChange("change-1", xwchange.Create, "profile-1", 1)
+
+	_, err := engine.approve(newApproverRequest("approver-1"), "change-1")
+	assert.NilError(t, err)
+	// the review stays pending until the quorum is met
+	assert.Assert(t, xchange.GetChangeReview("change-1") == nil)
+	approved, err := engine.approve(newApproverRequest("approver-2"), "change-1")
+	assert.NilError(t, err)
+	assert.Assert(t, approved != nil)
+	assert.Equal(t, xchange.GetChangeReview("change-1").Status, xchange.REVIEW_APPROVED)
+
+	approvals := xchange.GetChangeApprovals("change-1")
+	assert.Equal(t, len(approvals), 2)
+	assert.Equal(t, approvals[0].User, "approver-1")
+	assert.Equal(t, approvals[1].User, "approver-2")
+
+	// the approvals go away with the reverted change
+	assert.NilError(t, engine.revert(newApproverRequest("approver-1"), "change-1"))
+	assert.Equal(t, len(xchange.GetChangeApprovals("change-1")), 0)
+}
+
+func TestApprovalPolicyAndSettingsRequireApprovalAdmin(t *testing.T) {
+	testutil.SetupTestDB()
+	policyBody := `{"entityType":"` + xchange.TelemetryTwoProfile + `","applicationType":"stb","requiredApprovals":2}`
+	settingBody := `{"entityType":"` + xchange.TelemetryTwoProfile + `","applicationType":"stb","requiresApproval":true}`
+	for _, permission := range []string{auth.WRITE_TOOLS, auth.WRITE_CHANGES_ALL} {
+		r := testutil.NewRequest(http.MethodPut, "/xconfAdminService/change/approvalPolicies", policyBody, permission)
+		assert.Equal(t, testutil.Serve(SetApprovalPolicyHandler, r).Code, http.StatusForbidden, permission)
+		r = testutil.NewRequest(http.MethodPut, "/xconfAdminService/change/approvalSettings", settingBody, permission)
+		assert.Equal(t, testutil.Serve(SetApprovalSettingHandler, r).Code, http.StatusForbidden, permission)
+	}
+
+	r := testutil.NewRequest(http.MethodPut, "/xconfAdminService/change/approvalPolicies", policyBody, auth.WRITE_APPROVAL_POLICIES)
+	assert.Equal(t, testutil.Serve(SetApprovalPolicyHandler, r).Code, http.StatusOK)
+	assert.Equal(t, xchange.GetApprovalPolicy(xchange.TelemetryTwoProfile, "stb").RequiredApprovals, 2)
+	r = testutil.NewRequest(http.MethodPut, "/xconfAdminService/change/approvalSettings", settingBody, auth.WRITE_APPROVAL_POLICIES)
+	assert.Assert(t, testutil.Serve(SetApprovalSettingHandler, r).Code != http.StatusForbidden)
+}
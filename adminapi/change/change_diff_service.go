@@ -0,0 +1,114 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package change
+
+import (
+	"encoding/json"
+	"net/http"
+
+	xcommon "xconfadmin/common"
+	xchange "xconfadmin/shared/change"
+	xutil "xconfadmin/util"
+	xwchange "xconfwebconfig/shared/change"
+	"xconfwebconfig/shared/logupload"
+)
+
+// ChangeDiff is the difference between the old and the new entity of a pending or approved change
+type ChangeDiff struct {
+	ID              string                     `json:"id"`
+	EntityID        string                     `json:"entityId"`
+	EntityType      string                     `json:"entityType"`
+	ApplicationType string                     `json:"applicationType"`
+	Operation       xwchange.ChangeOperation   `json:"operation"`
+	Approved        bool                       `json:"approved"`
+	Patch           []xutil.JsonPatchOperation `json:"patch"`
+	Summary         []string                   `json:"summary"`
+}
+
+// GetChangeDiff looks the id up in every change table and diffs the entities of the change
+func GetChangeDiff(id string) (*ChangeDiff, error) {
+	if change := xchange.GetOneChange(id); change != nil {
+		return newTelemetryProfileChangeDiff(change, false)
+	}
+	if approvedChange := xchange.GetOneApprovedChange(id); approvedChange != nil {
+		return newTelemetryProfileChangeDiff((*xwchange.Change)(approvedChange), true)
+	}
+	if change := xchange.GetOneTelemetryTwoChange(id); change != nil {
+		return newTelemetryTwoProfileChangeDiff(change, false)
+	}
+	if approvedChange := xchange.GetOneApprovedTelemetryTwoChange(id); approvedChange != nil {
+		return newTelemetryTwoProfileChangeDiff((*xwchange.TelemetryTwoChange)(approvedChange), true)
+	}
+	if change := xchange.GetOneEntityChange(id); change != nil {
+		return newEntityChangeDiff(change, false)
+	}
+	if approvedChange := xchange.GetOneApprovedEntityChange(id); approvedChange != nil {
+		return newEntityChangeDiff((*xchange.EntityChange)(approvedChange), true)
+	}
+	return nil, xcommon.NewXconfError(http.StatusNotFound, "Change with "+id+" id does not exist")
+}
+
+func newChangeDiff(id string, entityId string, entityType string, applicationType string, operation xwchange.ChangeOperation, approved bool, oldEntity interface{}, newEntity interface{}) (*ChangeDiff, error) {
+	diff, err := xutil.DiffJson(oldEntity, newEntity)
+	if err != nil {
+		return nil, xcommon.NewXconfError(http.StatusInternalServerError, err.Error())
+	}
+	return &ChangeDiff{
+		ID:              id,
+		EntityID:        entityId,
+		EntityType:      entityType,
+		ApplicationType: applicationType,
+		Operation:       operation,
+		Approved:        approved,
+		Patch:           diff.Patch,
+		Summary:         diff.Summary,
+	}, nil
+}
+
+func newTelemetryProfileChangeDiff(change *xwchange.Change, approved bool) (*ChangeDiff, error) {
+	var oldEntity, newEntity *logupload.PermanentTelemetryProfile
+	if change.Operation != xwchange.Create && !change.OldEntity.IsEmpty() {
+		oldEntity = &change.OldEntity
+	}
+	if change.Operation != xwchange.Delete && !change.NewEntity.IsEmpty() {
+		newEntity = &change.NewEntity
+	}
+	return newChangeDiff(change.ID, change.EntityID, string(change.EntityType), change.ApplicationType, change.Operation, approved, oldEntity, newEntity)
+}
+
+func newTelemetryTwoProfileChangeDiff(change *xwchange.TelemetryTwoChange, approved bool) (*ChangeDiff, error) {
+	var oldEntity, newEntity *logupload.TelemetryTwoProfile
+	if change.Operation != xwchange.Create {
+		oldEntity = change.OldEntity
+	}
+	if change.Operation != xwchange.Delete {
+		newEntity = change.NewEntity
+	}
+	return newChangeDiff(change.ID, change.EntityID, change.EntityType, change.ApplicationType, change.Operation, approved, oldEntity, newEntity)
+}
+
+func newEntityChangeDiff(change *xchange.EntityChange, approved bool) (*ChangeDiff, error) {
+	var oldEntity, newEntity interface{}
+	if len(change.OldEntity) > 0 {
+		oldEntity = json.RawMessage(change.OldEntity)
+	}
+	if len(change.NewEntity) > 0 {
+		newEntity = json.RawMessage(change.NewEntity)
+	}
+	return newChangeDiff(change.ID, change.EntityID, change.EntityType, change.ApplicationType, change.Operation, approved, oldEntity, newEntity)
+}
@@ -0,0 +1,152 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package change
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	xcommon "xconfadmin/common"
+	xshared "xconfadmin/shared"
+	xchange "xconfadmin/shared/change"
+	"xconfwebconfig/db"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// user recorded in the audit log for the changes canceled by the sweeper
+const PENDING_CHANGE_SWEEPER = "pendingChangeSweeper"
+
+// audited entity types of the pending changes, the telemetry ones are the names of their change routes
+const (
+	AUDIT_TELEMETRY_ONE_CHANGES = "Telemetry1-Changes"
+	AUDIT_TELEMETRY_TWO_CHANGES = "Telemetry2-Changes"
+	AUDIT_ENTITY_CHANGES        = "Entity-Changes"
+)
+
+// StartPendingChangeSweeper cancels the expired pending changes in the background
+func StartPendingChangeSweeper(interval time.Duration) {
+	log.Infof("pending change sweeper is running every %v", interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			if canceled := CancelExpiredChanges(now); canceled > 0 {
+				log.Infof("pending change sweeper canceled %d expired changes", canceled)
+			}
+		}
+	}()
+}
+
+// CancelExpiredChanges deletes the pending changes which outlived the TTL of their table
+// and returns the number of canceled changes, the scheduled changes are kept until they are applied.
+// Every change is claimed first, so with several admin instances only one of them cancels and audits it
+func CancelExpiredChanges(now time.Time) int {
+	owner, _ := os.Hostname()
+	scheduled := map[string]bool{}
+	for _, scheduledChange := range xchange.GetScheduledChanges() {
+		scheduled[scheduledChange.ID] = true
+	}
+	canceled := 0
+	for _, change := range xchange.GetChangeList() {
+		if scheduled[change.ID] || !xchange.IsExpired(db.TABLE_XCONF_CHANGE, change.Updated, now) {
+			continue
+		}
+		if !claimExpiredChange(change.ID, owner) {
+			continue
+		}
+		if err := telemetryOneChanges.deleteChange(change.ID); err != nil {
+			log.Errorf("unable to cancel expired change %s: %s", change.ID, err.Error())
+			continue
+		}
+		deleteExpiredChangeReview(change.ID)
+		auditExpiredChange(AUDIT_TELEMETRY_ONE_CHANGES, db.TABLE_XCONF_CHANGE, change.ID, change.ApplicationType, change)
+		canceled++
+	}
+	for _, change := range xchange.GetAllTelemetryTwoChangeList() {
+		if scheduled[change.ID] || !xchange.IsExpired(db.TABLE_XCONF_TELEMETRY_TWO_CHANGE, change.Updated, now) {
+			continue
+		}
+		if !claimExpiredChange(change.ID, owner) {
+			continue
+		}
+		if err := telemetryTwoChanges.deleteChange(change.ID); err != nil {
+			log.Errorf("unable to cancel expired change %s: %s", change.ID, err.Error())
+			continue
+		}
+		deleteExpiredChangeReview(change.ID)
+		auditExpiredChange(AUDIT_TELEMETRY_TWO_CHANGES, db.TABLE_XCONF_TELEMETRY_TWO_CHANGE, change.ID, change.ApplicationType, change)
+		canceled++
+	}
+	for _, change := range xchange.GetEntityChangeList() {
+		if scheduled[change.ID] || !xchange.IsExpired(xcommon.TABLE_ENTITY_CHANGES, change.Updated, now) {
+			continue
+		}
+		if !claimExpiredChange(change.ID, owner) {
+			continue
+		}
+		if err := xchange.DeleteOneEntityChange(change.ID); err != nil {
+			log.Errorf("unable to cancel expired change %s: %s", change.ID, err.Error())
+			continue
+		}
+		deleteExpiredChangeReview(change.ID)
+		auditExpiredChange(AUDIT_ENTITY_CHANGES, xcommon.TABLE_ENTITY_CHANGES, change.ID, change.ApplicationType, change)
+		canceled++
+	}
+	return canceled
+}
+
+func claimExpiredChange(changeId string, owner string) bool {
+	claimed, err := xchange.Claim("expired_"+changeId, owner)
+	if err != nil {
+		log.Errorf("unable to claim expired change %s: %s", changeId, err.Error())
+		return false
+	}
+	return claimed
+}
+
+// deleteExpiredChangeReview deletes the approvals, the comments and the review status of the expired change
+func deleteExpiredChangeReview(changeId string) {
+	if err := xchange.DeleteChangeApprovals(changeId); err != nil {
+		log.Errorf("unable to delete approvals of expired change %s: %s", changeId, err.Error())
+	}
+	if err := xchange.DeleteChangeComments(changeId); err != nil {
+		log.Errorf("unable to delete comments of expired change %s: %s", changeId, err.Error())
+	}
+	if err := xchange.DeleteChangeReview(changeId); err != nil {
+		log.Errorf("unable to delete review of expired change %s: %s", changeId, err.Error())
+	}
+}
+
+func auditExpiredChange(entityType string, tableName string, changeId string, applicationType string, change interface{}) {
+	entry := xshared.AuditEntry{
+		User:            PENDING_CHANGE_SWEEPER,
+		Operation:       xshared.AUDIT_EXPIRE,
+		EntityType:      entityType,
+		EntityID:        changeId,
+		TableName:       tableName,
+		ApplicationType: applicationType,
+	}
+	if before, err := json.Marshal(change); err == nil {
+		entry.Before = before
+	}
+	if err := xshared.SaveAuditEntry(&entry); err != nil {
+		log.Errorf("unable to save audit entry for expired change %s: %s", changeId, err.Error())
+	}
+}
@@ -0,0 +1,70 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package change
+
+import (
+	"testing"
+	"time"
+
+	xcommon "xconfadmin/common"
+	xshared "xconfadmin/shared"
+	xchange "xconfadmin/shared/change"
+	"xconfadmin/testutil"
+
+	"gotest.tools/assert"
+)
+
+func TestExpiredEntityChangeIsAuditedAsEntityChange(t *testing.T) {
+	testutil.SetupTestDB()
+	xcommon.PendingChangeTtlHours = map[string]int{xcommon.TABLE_ENTITY_CHANGES: 1}
+	defer func() {
+		xcommon.PendingChangeTtlHours = map[string]int{}
+	}()
+	change := &xchange.EntityChange{EntityID: "rule-1", EntityType: xchange.FIRMWARE_RULE, ApplicationType: "stb", Author: "author"}
+	assert.NilError(t, xchange.SetOneEntityChange(change))
+
+	assert.Equal(t, CancelExpiredChanges(time.Now()), 0)
+	assert.Equal(t, CancelExpiredChanges(time.Now().Add(2*time.Hour)), 1)
+	assert.Assert(t, xchange.GetOneEntityChange(change.ID) == nil)
+
+	entries := xshared.GetAuditEntries(&xshared.AuditFilter{EntityID: change.ID})
+	assert.Equal(t, len(entries), 1)
+	assert.Equal(t, entries[0].EntityType, AUDIT_ENTITY_CHANGES)
+	assert.Equal(t, entries[0].TableName, xcommon.TABLE_ENTITY_CHANGES)
+	assert.Equal(t, entries[0].Operation, xshared.AUDIT_EXPIRE)
+	assert.Equal(t, entries[0].User, PENDING_CHANGE_SWEEPER)
+}
+
+func TestExpiredChangeReviewIsDeleted(t *testing.T) {
+	testutil.SetupTestDB()
+	xcommon.PendingChangeTtlHours = map[string]int{xcommon.TABLE_ENTITY_CHANGES: 1}
+	defer func() {
+		xcommon.PendingChangeTtlHours = map[string]int{}
+	}()
+	change := &xchange.EntityChange{EntityID: "rule-1", EntityType: xchange.FIRMWARE_RULE, ApplicationType: "stb", Author: "author"}
+	assert.NilError(t, xchange.SetOneEntityChange(change))
+	assert.NilError(t, xchange.SetChangeApprovals(change.ID, []xchange.ChangeApproval{{User: "approver-1"}}))
+	_, err := xchange.AddChangeComment(change.ID, "reviewer", "looks good")
+	assert.NilError(t, err)
+	assert.Assert(t, xchange.GetChangeReview(change.ID) != nil)
+
+	assert.Equal(t, CancelExpiredChanges(time.Now().Add(2*time.Hour)), 1)
+	assert.Equal(t, len(xchange.GetChangeApprovals(change.ID)), 0)
+	assert.Equal(t, len(xchange.GetChangeComments(change.ID)), 0)
+	assert.Assert(t, xchange.GetChangeReview(change.ID) == nil)
+}
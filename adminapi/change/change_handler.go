@@ -21,7 +21,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"sort"
 	"strconv"
 
 	xcommon "xconfadmin/common"
@@ -54,12 +53,10 @@ func GetProfileChangesHandler(w http.ResponseWriter, r *http.Request) {
 
 	searchContext := make(map[string]string)
 	searchContext[xwcommon.APPLICATION_TYPE] = applicationType
-	changes := FindByContextForChanges(searchContext)
-	sort.Slice(changes, func(i, j int) bool {
-		return changes[j].Updated < changes[i].Updated
-	})
+	changes := findChangeItems(searchContext)
+	sortChangeItems(changes, true)
 
-	res, err := xhttp.ReturnJsonResponse(changes, r)
+	res, err := xhttp.ReturnJsonResponse(changeItemValues(changes), r)
 	if err != nil {
 		xhttp.AdminError(w, err)
 		return
@@ -80,13 +77,37 @@ func ApproveChangeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = Approve(r, changeId)
+	effectiveAt, err := getEffectiveAt(r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	if effectiveAt > 0 {
+		writeScheduledApproval(w, r, changeId, effectiveAt)
+		return
+	}
+
+	var applied bool
+	if xchange.GetOneEntityChange(changeId) != nil {
+		var approvedChange *xchange.ApprovedEntityChange
+		approvedChange, err = ApproveEntityChange(r, changeId)
+		applied = approvedChange != nil
+	} else {
+		var approvedChange *xwchange.ApprovedChange
+		approvedChange, err = Approve(r, changeId)
+		applied = approvedChange != nil
+	}
 	if err != nil {
 		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	status := http.StatusOK
+	if !applied {
+		// the approval is recorded, the change waits for the quorum of its approval policy
+		status = http.StatusAccepted
+	}
 	headerMap := createHeadersMap(applicationType)
-	xwhttp.WriteXconfResponseWithHeaders(w, headerMap, http.StatusOK, nil)
+	xwhttp.WriteXconfResponseWithHeaders(w, headerMap, status, nil)
 }
 
 func GetApprovedHandler(w http.ResponseWriter, r *http.Request) {
@@ -115,7 +136,11 @@ func RevertChangeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = Revert(r, approveId)
+	if xchange.GetOneApprovedEntityChange(approveId) != nil {
+		err = RevertEntityChange(r, approveId)
+	} else {
+		err = Revert(r, approveId)
+	}
 	if err != nil {
 		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
@@ -137,8 +162,12 @@ func CancelChangeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = CancelChange(r, changeId)
-	if err != nil {
+	if xchange.GetOneEntityChange(changeId) != nil {
+		if err := CancelEntityChange(r, changeId); err != nil {
+			xhttp.AdminError(w, err)
+			return
+		}
+	} else if err := CancelChange(r, changeId); err != nil {
 		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
@@ -164,6 +193,9 @@ func createHeadersMap(applicationType string) map[string]string {
 			approvedChangeList = append(approvedChangeList, approvedChange)
 		}
 	}
+	entityContext := map[string]string{xwcommon.APPLICATION_TYPE: applicationType}
+	lenChangeList += len(FindByContextForEntityChanges(entityContext))
+	lenApprovedChangeList += len(FindByContextForApprovedEntityChanges(entityContext))
 	if changeList == nil {
 		headerMap[PENDING_CHANGE_SIZE] = "0"
 	} else {
@@ -209,12 +241,11 @@ func GetGroupedChangesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	changeList := xchange.GetChangeList()
-	sort.Slice(changeList, func(i, j int) bool {
-		return changeList[i].Updated < changeList[j].Updated
-	})
-	changesPerPage := ChangesGeneratePage(changeList, pageNumber, pageSize)
-	changeMap := GroupChanges(changesPerPage)
+	searchContext := map[string]string{xwcommon.APPLICATION_TYPE: applicationType}
+	changeList := mergeChanges(xchange.GetChangeList(), FindByContextForEntityChanges(searchContext))
+	sortChangeItems(changeList, false)
+	changesPerPage := changeItemsGeneratePage(changeList, pageNumber, pageSize)
+	changeMap := groupChangeItems(changesPerPage)
 	response, err := util.JSONMarshal(changeMap)
 	if err != nil {
 		log.Error(fmt.Sprintf("json.Marshal changeMap error: %v", err))
@@ -255,13 +286,12 @@ func GetGroupedApprovedChangesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	changeList := xchange.GetApprovedChangeList()
-	sort.Slice(changeList, func(i, j int) bool {
-		return changeList[j].Updated < changeList[i].Updated
-	})
-	changesPerPage := ApprovedChangesGeneratePage(changeList, pageNumber, pageSize)
-	changeMap := GroupApprovedChanges(changesPerPage)
-	ApprovedChangesMap := make(map[string]map[string][]*xwchange.ApprovedChange, 1)
+	searchContext := map[string]string{xwcommon.APPLICATION_TYPE: applicationType}
+	changeList := mergeApprovedChanges(xchange.GetApprovedChangeList(), FindByContextForApprovedEntityChanges(searchContext))
+	sortChangeItems(changeList, true)
+	changesPerPage := changeItemsGeneratePage(changeList, pageNumber, pageSize)
+	changeMap := groupChangeItems(changesPerPage)
+	ApprovedChangesMap := make(map[string]map[string][]interface{}, 1)
 	ApprovedChangesMap["changesPerPage"] = changeMap
 	response, err := util.JSONMarshal(ApprovedChangesMap)
 	if err != nil {
@@ -271,32 +301,6 @@ func GetGroupedApprovedChangesHandler(w http.ResponseWriter, r *http.Request) {
 	xwhttp.WriteXconfResponseWithHeaders(w, headerMap, http.StatusOK, response)
 }
 
-func ChangesGeneratePage(list []*xwchange.Change, page int, pageSize int) (result []*xwchange.Change) {
-	leng := len(list)
-	startIndex := page*pageSize - pageSize
-	if page < 1 || startIndex > leng || pageSize < 1 {
-		return result
-	}
-	lastIndex := leng
-	if page*pageSize < len(list) {
-		lastIndex = page * pageSize
-	}
-	return list[startIndex:lastIndex]
-}
-
-func ApprovedChangesGeneratePage(list []*xwchange.ApprovedChange, page int, pageSize int) (result []*xwchange.ApprovedChange) {
-	leng := len(list)
-	startIndex := page*pageSize - pageSize
-	if page < 1 || startIndex > leng || pageSize < 1 {
-		return result
-	}
-	lastIndex := leng
-	if page*pageSize < len(list) {
-		lastIndex = page * pageSize
-	}
-	return list[startIndex:lastIndex]
-}
-
 func GetChangedEntityIdsHandler(w http.ResponseWriter, r *http.Request) {
 	entityIds := GetChangedEntityIds()
 	response, err := util.JSONMarshal(entityIds)
@@ -325,11 +329,17 @@ func ApproveChangesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	errorMessages, err := ApproveChanges(r, &changeIds)
+	changeIds, entityChangeIds := splitChangeIds(changeIds, func(id string) bool {
+		return xchange.GetOneEntityChange(id) != nil
+	})
+	errorMessages, err := ApproveChanges(r, changeIds)
 	if err != nil {
 		xhttp.AdminError(w, err)
 		return
 	}
+	for id, message := range ApproveEntityChanges(r, entityChangeIds) {
+		errorMessages[id] = message
+	}
 	response, err := util.JSONMarshal(errorMessages)
 	if err != nil {
 		log.Error(fmt.Sprintf("json.Marshal ApprovedChangesMap error: %v", err))
@@ -356,11 +366,17 @@ func RevertChangesHandler(w http.ResponseWriter, r *http.Request) {
 		xwhttp.WriteXconfResponse(w, http.StatusBadRequest, []byte(response))
 		return
 	}
-	errorMessages, err := RevertChanges(r, &changeIds)
+	changeIds, entityChangeIds := splitChangeIds(changeIds, func(id string) bool {
+		return xchange.GetOneApprovedEntityChange(id) != nil
+	})
+	errorMessages, err := RevertChanges(r, changeIds)
 	if err != nil {
 		xhttp.AdminError(w, err)
 		return
 	}
+	for id, message := range RevertEntityChanges(r, entityChangeIds) {
+		errorMessages[id] = message
+	}
 	response, err := util.JSONMarshal(errorMessages)
 	if err != nil {
 		log.Error(fmt.Sprintf("json.Marshal ApprovedChangesMap error: %v", err))
@@ -411,16 +427,14 @@ func GetApprovedFilteredHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	searchContext[xwcommon.APPLICATION_TYPE] = applicationType
 
-	approvedChangeList := FindByContextForApprovedChanges(r, searchContext)
-	sort.Slice(approvedChangeList, func(i, j int) bool {
-		return approvedChangeList[j].Updated < approvedChangeList[i].Updated
-	})
-	changesPerPage := ApprovedChangesGeneratePage(approvedChangeList, pageNumber, pageSize)
-	response, err := util.JSONMarshal(changesPerPage)
+	approvedChangeList := findApprovedChangeItems(r, searchContext)
+	sortChangeItems(approvedChangeList, true)
+	changesPerPage := changeItemsGeneratePage(approvedChangeList, pageNumber, pageSize)
+	response, err := util.JSONMarshal(changeItemValues(changesPerPage))
 	if err != nil {
 		log.Error(fmt.Sprintf("json.Marshal ApprovedChangesMap error: %v", err))
 	}
-	changeList := FindByContextForChanges(searchContext)
+	changeList := findChangeItems(searchContext)
 	headerMap := createHeadersWithEntitySize(len(changeList), len(approvedChangeList))
 	xwhttp.WriteXconfResponseWithHeaders(w, headerMap, http.StatusOK, response)
 }
@@ -467,16 +481,14 @@ func GetChangesFilteredHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	searchContext[xwcommon.APPLICATION_TYPE] = applicationType
 
-	changeList := FindByContextForChanges(searchContext)
-	sort.Slice(changeList, func(i, j int) bool {
-		return changeList[j].Updated < changeList[i].Updated
-	})
-	changesPerPage := ChangesGeneratePage(changeList, pageNumber, pageSize)
-	response, err := util.JSONMarshal(changesPerPage)
+	changeList := findChangeItems(searchContext)
+	sortChangeItems(changeList, true)
+	changesPerPage := changeItemsGeneratePage(changeList, pageNumber, pageSize)
+	response, err := util.JSONMarshal(changeItemValues(changesPerPage))
 	if err != nil {
 		log.Error(fmt.Sprintf("json.Marshal changeMap error: %v", err))
 	}
-	approvedChangeList := FindByContextForApprovedChanges(r, searchContext)
+	approvedChangeList := findApprovedChangeItems(r, searchContext)
 	headerMap := createHeadersWithEntitySize(len(changeList), len(approvedChangeList))
 	xwhttp.WriteXconfResponseWithHeaders(w, headerMap, http.StatusOK, response)
 }
@@ -487,3 +499,29 @@ func createHeadersWithEntitySize(pendingChangesSize int, approvedChangesSize int
 	headerMap[APPROVED_CHANGE_SIZE] = strconv.Itoa(approvedChangesSize)
 	return headerMap
 }
+
+// GetChangeDiffHandler returns the JSON Patch and the field level summary of a pending or approved change
+func GetChangeDiffHandler(w http.ResponseWriter, r *http.Request) {
+	if _, err := auth.CanRead(r, auth.CHANGE_ENTITY); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	changeId, found := mux.Vars(r)[xcommon.CHANGE_ID]
+	if !found || changeId == "" {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("%v is invalid", xcommon.CHANGE_ID))
+		return
+	}
+	diff, err := GetChangeDiff(changeId)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	res, err := xhttp.ReturnJsonResponse(diff, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
@@ -0,0 +1,137 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package change
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"xconfadmin/adminapi/auth"
+	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
+	xwhttp "xconfwebconfig/http"
+
+	"github.com/gorilla/mux"
+)
+
+// ChangeReviewRequest is the body of the comment and reject endpoints
+type ChangeReviewRequest struct {
+	Text   string `json:"text,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// GetChangeThreadHandler returns the review status and the comments of a change
+func GetChangeThreadHandler(w http.ResponseWriter, r *http.Request) {
+	applicationType, err := auth.CanRead(r, auth.CHANGE_ENTITY)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	changeId, found := mux.Vars(r)[xcommon.CHANGE_ID]
+	if !found || changeId == "" {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("%v is invalid", xcommon.CHANGE_ID))
+		return
+	}
+
+	thread, err := GetChangeThread(changeId, applicationType)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	res, err := xhttp.ReturnJsonResponse(thread, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
+func AddChangeCommentHandler(w http.ResponseWriter, r *http.Request) {
+	applicationType, err := auth.CanWrite(r, auth.CHANGE_ENTITY)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	changeId, found := mux.Vars(r)[xcommon.CHANGE_ID]
+	if !found || changeId == "" {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("%v is invalid", xcommon.CHANGE_ID))
+		return
+	}
+	reviewRequest, err := getChangeReviewRequest(w)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	comment, err := CommentChange(r, changeId, applicationType, reviewRequest.Text)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	res, err := xhttp.ReturnJsonResponse(comment, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusCreated, res)
+}
+
+// RejectChangeHandler rejects a pending change, the reason is mandatory
+func RejectChangeHandler(w http.ResponseWriter, r *http.Request) {
+	applicationType, err := auth.CanWrite(r, auth.CHANGE_ENTITY)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	changeId, found := mux.Vars(r)[xcommon.CHANGE_ID]
+	if !found || changeId == "" {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("%v is invalid", xcommon.CHANGE_ID))
+		return
+	}
+	reviewRequest, err := getChangeReviewRequest(w)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	review, err := RejectChange(r, changeId, applicationType, reviewRequest.Reason)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	res, err := xhttp.ReturnJsonResponse(review, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
+func getChangeReviewRequest(w http.ResponseWriter) (*ChangeReviewRequest, error) {
+	// r.Body is already drained in the middleware
+	xw, ok := w.(*xwhttp.XResponseWriter)
+	if !ok {
+		return nil, xcommon.NewXconfError(http.StatusBadRequest, "Unable to extract body")
+	}
+	reviewRequest := ChangeReviewRequest{}
+	if err := json.Unmarshal([]byte(xw.Body()), &reviewRequest); err != nil {
+		return nil, xcommon.NewXconfError(http.StatusBadRequest, err.Error())
+	}
+	return &reviewRequest, nil
+}
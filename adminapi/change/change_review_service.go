@@ -0,0 +1,212 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package change
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"xconfadmin/adminapi/auth"
+	xcommon "xconfadmin/common"
+	xshared "xconfadmin/shared"
+	xchange "xconfadmin/shared/change"
+	"xconfwebconfig/db"
+	"xconfwebconfig/shared"
+	xwchange "xconfwebconfig/shared/change"
+	"xconfwebconfig/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ChangeThread is the review discussion of a change
+type ChangeThread struct {
+	ChangeID string                   `json:"changeId"`
+	Review   *xchange.ChangeReview    `json:"review,omitempty"`
+	Comments []*xchange.ChangeComment `json:"comments"`
+}
+
+// reviewedChange holds the fields of a pending or approved change the review needs
+type reviewedChange struct {
+	ID              string
+	EntityType      string
+	ApplicationType string
+	Author          string
+	Pending         bool
+}
+
+// findReviewedChange looks the id up in the telemetry profile and entity change tables, a change
+// of another application type than the current one is not found
+func findReviewedChange(id string, applicationType string) (*reviewedChange, error) {
+	change, err := findAnyReviewedChange(id)
+	if err != nil {
+		return nil, err
+	}
+	if !xshared.ApplicationTypeEquals(applicationType, change.ApplicationType) && !xshared.ApplicationTypeEquals(applicationType, shared.ALL) {
+		return nil, xcommon.NewXconfError(http.StatusNotFound, "Change with "+id+" id does not exist")
+	}
+	return change, nil
+}
+
+func findAnyReviewedChange(id string) (*reviewedChange, error) {
+	if change := xchange.GetOneChange(id); change != nil {
+		return &reviewedChange{id, string(xwchange.TelemetryProfile), change.ApplicationType, change.Author, true}, nil
+	}
+	if change := xchange.GetOneTelemetryTwoChange(id); change != nil {
+		return &reviewedChange{id, xchange.TelemetryTwoProfile, change.ApplicationType, change.Author, true}, nil
+	}
+	if change := xchange.GetOneEntityChange(id); change != nil {
+		return &reviewedChange{id, change.EntityType, change.ApplicationType, change.Author, true}, nil
+	}
+	if change := xchange.GetOneApprovedChange(id); change != nil {
+		return &reviewedChange{id, string(xwchange.TelemetryProfile), change.ApplicationType, change.Author, false}, nil
+	}
+	if change := xchange.GetOneApprovedTelemetryTwoChange(id); change != nil {
+		return &reviewedChange{id, xchange.TelemetryTwoProfile, change.ApplicationType, change.Author, false}, nil
+	}
+	if change := xchange.GetOneApprovedEntityChange(id); change != nil {
+		return &reviewedChange{id, change.EntityType, change.ApplicationType, change.Author, false}, nil
+	}
+	return nil, xcommon.NewXconfError(http.StatusNotFound, "Change with "+id+" id does not exist")
+}
+
+// pendingChange adds the time until expiry and the review discussion summary to a telemetry profile change
+// in the change listings
+type pendingChange struct {
+	*xwchange.Change
+	ExpiresIn    *int64 `json:"expiresIn,omitempty"`
+	CommentCount int    `json:"commentCount"`
+	ReviewStatus string `json:"reviewStatus,omitempty"`
+}
+
+type pendingTelemetryTwoChange struct {
+	*xwchange.TelemetryTwoChange
+	ExpiresIn    *int64 `json:"expiresIn,omitempty"`
+	CommentCount int    `json:"commentCount"`
+	ReviewStatus string `json:"reviewStatus,omitempty"`
+}
+
+type pendingEntityChange struct {
+	*xchange.EntityChange
+	ExpiresIn    *int64 `json:"expiresIn,omitempty"`
+	CommentCount int    `json:"commentCount"`
+	ReviewStatus string `json:"reviewStatus,omitempty"`
+}
+
+func getReviewStatus(reviews map[string]*xchange.ChangeReview, changeId string) string {
+	if review, ok := reviews[changeId]; ok {
+		return review.Status
+	}
+	return ""
+}
+
+func newPendingChange(change *xwchange.Change, now time.Time, reviews map[string]*xchange.ChangeReview, commentCounts map[string]int) *pendingChange {
+	return &pendingChange{
+		Change:       change,
+		ExpiresIn:    xchange.GetExpiresIn(db.TABLE_XCONF_CHANGE, change.Updated, now),
+		CommentCount: commentCounts[change.ID],
+		ReviewStatus: getReviewStatus(reviews, change.ID),
+	}
+}
+
+func newPendingTelemetryTwoChange(change *xwchange.TelemetryTwoChange, now time.Time, reviews map[string]*xchange.ChangeReview, commentCounts map[string]int) *pendingTelemetryTwoChange {
+	return &pendingTelemetryTwoChange{
+		TelemetryTwoChange: change,
+		ExpiresIn:          xchange.GetExpiresIn(db.TABLE_XCONF_TELEMETRY_TWO_CHANGE, change.Updated, now),
+		CommentCount:       commentCounts[change.ID],
+		ReviewStatus:       getReviewStatus(reviews, change.ID),
+	}
+}
+
+func newPendingEntityChange(change *xchange.EntityChange, now time.Time, reviews map[string]*xchange.ChangeReview, commentCounts map[string]int) *pendingEntityChange {
+	return &pendingEntityChange{
+		EntityChange: change,
+		ExpiresIn:    xchange.GetExpiresIn(xcommon.TABLE_ENTITY_CHANGES, change.Updated, now),
+		CommentCount: commentCounts[change.ID],
+		ReviewStatus: getReviewStatus(reviews, change.ID),
+	}
+}
+
+func withPendingTelemetryTwoChanges(changes []*xwchange.TelemetryTwoChange) []*pendingTelemetryTwoChange {
+	now := time.Now()
+	reviews := xchange.GetChangeReviews()
+	commentCounts := xchange.GetChangeCommentCounts()
+	result := make([]*pendingTelemetryTwoChange, 0, len(changes))
+	for _, change := range changes {
+		result = append(result, newPendingTelemetryTwoChange(change, now, reviews, commentCounts))
+	}
+	return result
+}
+
+// validateApprovable rejects the approval of a rejected or an already scheduled change
+func validateApprovable(changeId string) error {
+	if review := xchange.GetChangeReview(changeId); review != nil && review.Status == xchange.REVIEW_REJECTED {
+		return xcommon.NewXconfError(http.StatusConflict, fmt.Sprintf("Change %s is rejected by %s: %s", changeId, review.User, review.Reason))
+	}
+	return validateNotScheduled(changeId)
+}
+
+func GetChangeThread(changeId string, applicationType string) (*ChangeThread, error) {
+	if _, err := findReviewedChange(changeId, applicationType); err != nil {
+		return nil, err
+	}
+	return &ChangeThread{
+		ChangeID: changeId,
+		Review:   xchange.GetChangeReview(changeId),
+		Comments: xchange.GetChangeComments(changeId),
+	}, nil
+}
+
+// CommentChange adds the comment of the current user to a pending or approved change
+func CommentChange(r *http.Request, changeId string, applicationType string, text string) (*xchange.ChangeComment, error) {
+	if _, err := findReviewedChange(changeId, applicationType); err != nil {
+		return nil, err
+	}
+	return xchange.AddChangeComment(changeId, auth.GetUserNameOrUnknown(r), text)
+}
+
+// RejectChange marks the pending change as rejected, unlike a cancel the change stays listed
+// with the reason until it is canceled or expires
+func RejectChange(r *http.Request, changeId string, applicationType string, reason string) (*xchange.ChangeReview, error) {
+	if util.IsBlank(reason) {
+		return nil, xcommon.NewXconfError(http.StatusBadRequest, "Reason is required to reject a change")
+	}
+	change, err := findReviewedChange(changeId, applicationType)
+	if err != nil {
+		return nil, err
+	}
+	if !change.Pending {
+		return nil, xcommon.NewXconfError(http.StatusConflict, "Change "+changeId+" is already approved")
+	}
+	if err := validateApprovable(changeId); err != nil {
+		return nil, err
+	}
+	if _, err := getReviewPolicy(r, change.EntityType, change.ApplicationType); err != nil {
+		return nil, err
+	}
+
+	userName := auth.GetUserNameOrUnknown(r)
+	if _, err := xchange.AddChangeComment(changeId, userName, reason); err != nil {
+		return nil, err
+	}
+	if err := xchange.SetChangeReviewStatus(changeId, xchange.REVIEW_REJECTED, userName, reason); err != nil {
+		return nil, err
+	}
+	log.Infof("%s change %s rejected by %s", change.EntityType, changeId, userName)
+	return xchange.GetChangeReview(changeId), nil
+}
@@ -0,0 +1,103 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package change
+
+import (
+	"net/http"
+	"testing"
+
+	"xconfadmin/adminapi/auth"
+	xcommon "xconfadmin/common"
+	xchange "xconfadmin/shared/change"
+	"xconfadmin/testutil"
+	xwchange "xconfwebconfig/shared/change"
+
+	"gotest.tools/assert"
+)
+
+func newReviewedEntityChange(t *testing.T, applicationType string) string {
+	change := &xchange.EntityChange{EntityID: "entity-1", EntityType: testEntityChangeType, ApplicationType: applicationType, Operation: xchange.Create, Author: "author"}
+	assert.NilError(t, xchange.SetOneEntityChange(change))
+	return change.ID
+}
+
+func newReviewRequest(method string, changeId string, applicationType string, body string) *http.Request {
+	r := testutil.NewRequest(method, "/xconfAdminService/change/"+changeId+"/review?applicationType="+applicationType, body, auth.READ_CHANGES_ALL, auth.WRITE_CHANGES_ALL)
+	return testutil.WithVars(r, map[string]string{xcommon.CHANGE_ID: changeId})
+}
+
+func TestPendingChangesCountTheirComments(t *testing.T) {
+	testutil.SetupTestDB()
+	for _, changeId := range []string{"change-1", "change-1", "change-2"} {
+		_, err := xchange.AddChangeComment(changeId, "reviewer", "looks good")
+		assert.NilError(t, err)
+	}
+
+	items := mergeChanges([]*xwchange.Change{{ID: "change-1"}, {ID: "change-2"}, {ID: "change-3"}}, nil)
+	counts := map[string]int{}
+	for _, item := range items {
+		pending := item.change.(*pendingChange)
+		counts[pending.ID] = pending.CommentCount
+	}
+	assert.DeepEqual(t, counts, map[string]int{"change-1": 2, "change-2": 1, "change-3": 0})
+}
+
+func TestRejectChangeNeedsReason(t *testing.T) {
+	testutil.SetupTestDB()
+	changeId := newReviewedEntityChange(t, "stb")
+
+	r := newReviewRequest(http.MethodPost, changeId, "stb", `{"reason":"  "}`)
+	assert.Equal(t, testutil.Serve(RejectChangeHandler, r).Code, http.StatusBadRequest)
+	assert.Assert(t, xchange.GetChangeReview(changeId) == nil)
+
+	r = newReviewRequest(http.MethodPost, changeId, "stb", `{"reason":"wrong model"}`)
+	assert.Equal(t, testutil.Serve(RejectChangeHandler, r).Code, http.StatusOK)
+	review := xchange.GetChangeReview(changeId)
+	assert.Equal(t, review.Status, xchange.REVIEW_REJECTED)
+	assert.Equal(t, review.Reason, "wrong model")
+	assert.Equal(t, len(xchange.GetChangeComments(changeId)), 1)
+}
+
+func TestRejectApprovedChangeIsConflict(t *testing.T) {
+	testutil.SetupTestDB()
+	approvedChange := &xchange.ApprovedEntityChange{ID: "change-1", EntityID: "entity-1", EntityType: testEntityChangeType, ApplicationType: "stb", Operation: xchange.Create, Author: "author"}
+	assert.NilError(t, xchange.SetOneApprovedEntityChange(approvedChange))
+
+	r := newReviewRequest(http.MethodPost, "change-1", "stb", `{"reason":"too late"}`)
+	assert.Equal(t, testutil.Serve(RejectChangeHandler, r).Code, http.StatusConflict)
+	assert.Assert(t, xchange.GetChangeReview("change-1") == nil)
+}
+
+func TestReviewOfAnotherApplicationTypeIsNotFound(t *testing.T) {
+	testutil.SetupTestDB()
+	changeId := newReviewedEntityChange(t, "stb")
+
+	r := newReviewRequest(http.MethodGet, changeId, "rdkcloud", "")
+	assert.Equal(t, testutil.Serve(GetChangeThreadHandler, r).Code, http.StatusNotFound)
+	r = newReviewRequest(http.MethodPost, changeId, "rdkcloud", `{"text":"looks good"}`)
+	assert.Equal(t, testutil.Serve(AddChangeCommentHandler, r).Code, http.StatusNotFound)
+	r = newReviewRequest(http.MethodPost, changeId, "rdkcloud", `{"reason":"wrong model"}`)
+	assert.Equal(t, testutil.Serve(RejectChangeHandler, r).Code, http.StatusNotFound)
+	assert.Equal(t, len(xchange.GetChangeComments(changeId)), 0)
+	assert.Assert(t, xchange.GetChangeReview(changeId) == nil)
+
+	r = newReviewRequest(http.MethodPost, changeId, "stb", `{"text":"looks good"}`)
+	assert.Equal(t, testutil.Serve(AddChangeCommentHandler, r).Code, http.StatusCreated)
+	r = newReviewRequest(http.MethodGet, changeId, "stb", "")
+	assert.Equal(t, testutil.Serve(GetChangeThreadHandler, r).Code, http.StatusOK)
+}
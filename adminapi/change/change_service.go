@@ -19,20 +19,15 @@ package change
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"sort"
-	"strings"
 
 	basecommon "xconfadmin/common"
 	xcommon "xconfadmin/common"
-	xwcommon "xconfwebconfig/common"
-	"xconfwebconfig/shared"
 
 	"xconfadmin/adminapi/auth"
 	xshared "xconfadmin/shared"
 	xchange "xconfadmin/shared/change"
-	xutil "xconfadmin/util"
 	xwshared "xconfwebconfig/shared"
 	xwchange "xconfwebconfig/shared/change"
 	"xconfwebconfig/shared/logupload"
@@ -59,16 +54,6 @@ func GetApprovedAll(r *http.Request) ([]*xwchange.ApprovedChange, error) {
 	return approvedChanges, nil
 }
 
-func Delete(changeId string) (*xwchange.Change, error) {
-	err := beforeDelete(changeId)
-	if err != nil {
-		return nil, err
-	}
-	change := xchange.GetOneChange(changeId)
-	xchange.DeleteOneChange(changeId)
-	return change, nil
-}
-
 func beforeSavingChange(r *http.Request, change *xwchange.Change) error {
 	if change != nil && change.ApplicationType == "" {
 		application, err := auth.CanWrite(r, auth.CHANGE_ENTITY)
@@ -144,387 +129,192 @@ func validateAllChanges(change *xwchange.Change) error {
 	return nil
 }
 
-func beforeDelete(id string) error {
-	if id == "" {
-		return basecommon.NewXconfError(http.StatusBadRequest, "Id is blank")
-	}
-	change := xchange.GetOneChange(id)
-	if change == nil {
-		return basecommon.NewXconfError(http.StatusNotFound, " Change with "+id+" id does not exist")
-	}
-	return nil
-}
-
 func CreateApprovedChange(r *http.Request, change *xwchange.Change) (*xwchange.ApprovedChange, error) {
 	err := beforeSavingApprovedChange(r, change)
 	if err != nil {
 		return nil, err
 	}
 	approvedChange := xwchange.ApprovedChange(*change)
-	xchange.SetOneApprovedChange(&approvedChange)
+	if err := xchange.SetOneApprovedChange(&approvedChange); err != nil {
+		return nil, err
+	}
 	jsonBytes, _ := json.Marshal(change)
 	log.Info("ApprovedChange saved: {}", string(jsonBytes))
 	return &approvedChange, nil
 }
 
-func updateDeleteEntity(r *http.Request, change *xwchange.Change) (*xwchange.ApprovedChange, error) {
-	entityToChange := logupload.GetOnePermanentTelemetryProfile(change.EntityID) //*PermanentTelemetryProfile
-	if entityToChange != nil {
-		change.ApprovedUser = auth.GetUserNameOrUnknown(r)
-		if xwchange.Delete == change.Operation {
-			_, err := Delete(change.OldEntity.ID)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			newEntity := change.NewEntity
-			_, err := UpdatePermanentTelemetryProfile(&newEntity)
-			if err != nil {
-				return nil, err
-			}
-		}
-		approvedChange, err := CreateApprovedChange(r, change)
-		if err != nil {
-			return nil, err
-		}
-		_, err = Delete(change.ID)
-		if err != nil {
-			return nil, err
-		}
-		return approvedChange, nil
-	} else {
-		jsonBytes, _ := json.Marshal(entityToChange)
-		return nil, basecommon.NewXconfError(http.StatusConflict, "Change could not be approved, "+change.OldEntity.Name+" have been already changed: "+string(jsonBytes))
+func GetChangedEntityIds() *[]string {
+	ids := []string{}
+	changeList := xchange.GetChangeList()
+	for _, change := range changeList {
+		ids = append(ids, change.EntityID)
 	}
+	for _, change := range xchange.GetEntityChangeList() {
+		ids = append(ids, change.EntityID)
+	}
+	return &ids
 }
 
-func Revert(r *http.Request, approvedId string) error {
-	if approvedId == "" {
-		return basecommon.NewXconfError(http.StatusBadRequest, "Id is blank")
-	}
-	approvedChange := xchange.GetOneApprovedChange(approvedId)
-	if approvedChange == nil {
-		return basecommon.NewXconfError(http.StatusNotFound, "ApprovedChange with "+approvedId+" id does not exist")
+func GetChangesByEntityId(entityId string) []*xwchange.Change {
+	result := []*xwchange.Change{}
+	changes := xchange.GetChangeList()
+	for _, change := range changes {
+		if change.EntityID == entityId {
+			result = append(result, change)
+		}
 	}
-	if xwchange.Delete == approvedChange.Operation {
-		revertDelete(r, approvedId, approvedChange)
-	} else {
-		revertCreateOrUpdateChange(r, approvedId, approvedChange.EntityID, approvedChange)
+	return result
+}
+
+// Approve applies the change once the quorum of its approval policy is met,
+// nil is returned while the change waits for more approvals
+func Approve(r *http.Request, id string) (*xwchange.ApprovedChange, error) {
+	approvedChange, err := telemetryOneChanges.approve(r, id)
+	if err != nil || approvedChange == nil {
+		return nil, err
 	}
-	userName := auth.GetUserNameOrUnknown(r)
-	log.Info("Change has been reverted by {}: {}", userName, approvedChange)
-	return nil
+	return approvedChange.(*xwchange.ApprovedChange), nil
 }
 
-func revertDelete(r *http.Request, id string, approvedChange *xwchange.ApprovedChange) *xwchange.ApprovedChange {
-	CreatePermanentTelemetryProfile(r, &approvedChange.OldEntity)
-	xchange.DeleteOneApprovedChange(id)
-	return approvedChange
+func ApproveChanges(r *http.Request, changeIds []string) (map[string]string, error) {
+	return telemetryOneChanges.approveAll(r, changeIds)
 }
 
-func revertCreateOrUpdateChange(r *http.Request, changeId string, entityId string, approvedChange *xwchange.ApprovedChange) *xwchange.ApprovedChange {
-	entityToRevert := logupload.GetOnePermanentTelemetryProfile(entityId)
+func Revert(r *http.Request, approvedId string) error {
+	return telemetryOneChanges.revert(r, approvedId)
+}
 
-	if xwchange.Create == approvedChange.Operation {
-		DeletePermanentTelemetryProfile(r, entityToRevert.ID)
-	} else {
-		UpdatePermanentTelemetryProfile(&approvedChange.OldEntity)
-	}
-	xchange.DeleteOneApprovedChange(changeId)
-	return approvedChange
+func RevertChanges(r *http.Request, changeIds []string) (map[string]string, error) {
+	return telemetryOneChanges.revertAll(r, changeIds)
 }
 
 func CancelChange(r *http.Request, changeId string) error {
-	canceledChange, err := Delete(changeId)
-	if err != nil {
-		return err
-	}
-	userName := auth.GetUserNameOrUnknown(r)
-	log.Info("Change has been canceled by {}: {}", userName, canceledChange)
-	return nil
+	return telemetryOneChanges.cancel(r, changeId)
 }
 
-func GroupChanges(changes []*xwchange.Change) map[string][]*xwchange.Change {
-	groupedChanges := make(map[string][]*xwchange.Change)
-	for _, change := range changes {
-		groupChange(change, groupedChanges)
+func FindByContextForChanges(searchContext map[string]string) []*xwchange.Change {
+	changesFound := []*xwchange.Change{}
+	for _, change := range telemetryOneChanges.find(searchContext) {
+		changesFound = append(changesFound, change.Change.(*xwchange.Change))
 	}
-	return groupedChanges
+	return changesFound
 }
 
-func groupChange(change *xwchange.Change, groupedChanges map[string][]*xwchange.Change) {
-	if _, ok := groupedChanges[change.EntityID]; ok && groupedChanges[change.EntityID] != nil {
-		groupedChanges[change.EntityID] = append(groupedChanges[change.EntityID], change)
-	} else {
-		var changeList []*xwchange.Change
-		groupedChanges[change.EntityID] = append(changeList, change)
+func FindByContextForApprovedChanges(searchContext map[string]string) []*xwchange.ApprovedChange {
+	changesFound := []*xwchange.ApprovedChange{}
+	for _, change := range telemetryOneChanges.findApproved(searchContext, xcommon.PROFILE_NAME) {
+		changesFound = append(changesFound, change.Change.(*xwchange.ApprovedChange))
 	}
+	return changesFound
 }
 
-func GroupApprovedChanges(changes []*xwchange.ApprovedChange) map[string][]*xwchange.ApprovedChange {
-	groupedChanges := make(map[string][]*xwchange.ApprovedChange)
-	for _, change := range changes {
-		groupApprovedChange(change, groupedChanges)
+// permanentTelemetryProfileStore plugs the telemetry profile changes into the change engine
+type permanentTelemetryProfileStore struct{}
+
+func newTelemetryOneChange(change *xwchange.Change) *telemetryChange {
+	result := &telemetryChange{
+		ID:              change.ID,
+		EntityID:        change.EntityID,
+		ApplicationType: change.ApplicationType,
+		Author:          change.Author,
+		Operation:       change.Operation,
+		Updated:         change.Updated,
+		Change:          change,
+	}
+	if !change.NewEntity.IsEmpty() {
+		result.NewEntity = &change.NewEntity
+		result.EntityName = change.NewEntity.Name
+	}
+	if !change.OldEntity.IsEmpty() {
+		result.OldEntity = &change.OldEntity
+		if result.EntityName == "" {
+			result.EntityName = change.OldEntity.Name
+		}
 	}
-	return groupedChanges
+	return result
 }
 
-func groupApprovedChange(change *xwchange.ApprovedChange, groupedChanges map[string][]*xwchange.ApprovedChange) {
-	if _, ok := groupedChanges[change.EntityID]; ok && groupedChanges[change.EntityID] != nil {
-		groupedChanges[change.EntityID] = append(groupedChanges[change.EntityID], change)
-	} else {
-		var changeList []*xwchange.ApprovedChange
-		groupedChanges[change.EntityID] = append(changeList, change)
-	}
+func (permanentTelemetryProfileStore) EntityType() string {
+	return string(xwchange.TelemetryProfile)
 }
 
-func GetChangedEntityIds() *[]string {
-	ids := []string{}
-	changeList := xchange.GetChangeList()
-	for _, change := range changeList {
-		ids = append(ids, change.EntityID)
+func (permanentTelemetryProfileStore) GetChange(id string) *telemetryChange {
+	if change := xchange.GetOneChange(id); change != nil {
+		return newTelemetryOneChange(change)
 	}
-	return &ids
+	return nil
 }
 
-func GetChangesByEntityIds(changeIds *[]string) ([]*xwchange.Change, error) {
-	changes := []*xwchange.Change{}
-	for _, id := range *changeIds {
-		if id == "" {
-			return nil, basecommon.NewXconfError(http.StatusBadRequest, "Id is blank")
-		}
-		change := xchange.GetOneChange(id)
-		if change == nil {
-			return nil, basecommon.NewXconfError(http.StatusNotFound, "Factory with "+id+" id does not exist")
-		}
-		changes = append(changes, change)
+func (permanentTelemetryProfileStore) GetChanges() []*telemetryChange {
+	changes := []*telemetryChange{}
+	for _, change := range xchange.GetChangeList() {
+		changes = append(changes, newTelemetryOneChange(change))
 	}
-	sort.Slice(changes, func(i, j int) bool {
-		return changes[i].Updated < changes[j].Updated
-	})
-	return changes, nil
+	return changes
 }
 
-func GetChangesByEntityId(entityId string) []*xwchange.Change {
-	result := []*xwchange.Change{}
-	changes := xchange.GetChangeList()
-	for _, change := range changes {
-		if change.EntityID == entityId {
-			result = append(result, change)
-		}
-	}
-	return result
+func (permanentTelemetryProfileStore) DeleteChange(id string) error {
+	return xchange.DeleteOneChange(id)
 }
 
-func Approve(r *http.Request, id string) (*xwchange.ApprovedChange, error) {
-	change := xchange.GetOneChange(id)
-	if change == nil {
-		return nil, basecommon.NewXconfError(http.StatusNotFound, "Change with "+id+" id does not exist")
-	}
-
-	var err error
-	var approvedChange *xwchange.ApprovedChange
-	switch {
-	case xwchange.Create == change.Operation:
-		_, err = CreatePermanentTelemetryProfile(r, &change.NewEntity)
-	case xwchange.Update == change.Operation:
-		_, err = UpdatePermanentTelemetryProfile(&change.NewEntity)
-	case xwchange.Delete == change.Operation:
-		_, err = DeletePermanentTelemetryProfile(r, change.OldEntity.ID)
-	}
-	if err != nil {
-		return nil, err
-	} else {
-		approvedChange, err = SaveToApprovedAndCleanUpChange(r, change)
-		if err != nil {
-			return nil, err
-		}
-	}
+func (permanentTelemetryProfileStore) SaveApprovedChange(r *http.Request, change *telemetryChange, approvedUser string) (interface{}, error) {
+	pendingChange := change.Change.(*xwchange.Change)
+	pendingChange.ApprovedUser = approvedUser
+	return CreateApprovedChange(r, pendingChange)
+}
 
-	changesByProfileId := GetChangesByEntityId(change.EntityID)
-	err = CancelApprovedChangesByEntityId(r, getChangeIds(changesByProfileId), []string{})
-	if err != nil {
-		return nil, err
+func (permanentTelemetryProfileStore) GetApprovedChange(id string) *telemetryChange {
+	if approvedChange := xchange.GetOneApprovedChange(id); approvedChange != nil {
+		return newApprovedTelemetryOneChange(approvedChange)
 	}
-
-	return approvedChange, nil
+	return nil
 }
 
-func getChangeIds(changes []*xwchange.Change) []string {
-	changeIds := []string{}
-	for _, change := range changes {
-		changeIds = append(changeIds, change.EntityID)
+func (permanentTelemetryProfileStore) GetApprovedChanges() []*telemetryChange {
+	changes := []*telemetryChange{}
+	for _, approvedChange := range xchange.GetApprovedChangeList() {
+		changes = append(changes, newApprovedTelemetryOneChange(approvedChange))
 	}
-	return changeIds
+	return changes
 }
 
-func ApproveChanges(r *http.Request, changeIds *[]string) (map[string]string, error) {
-	changesToApprove, err := GetChangesByEntityIds(changeIds)
-	if err != nil {
-		return nil, err
-	}
-	errorMessages := make(map[string]string)
-	mergedUpdateChangesByEntityId := make(map[string]*logupload.PermanentTelemetryProfile)
-	entityToByCancelChange := []string{}
-	for _, change := range changesToApprove {
-		var err error
-		switch {
-		case xwchange.Create == change.Operation:
-			_, err = CreatePermanentTelemetryProfile(r, &change.NewEntity)
-		case xwchange.Update == change.Operation:
-			mergeResult := ApplyUpdateChange(mergedUpdateChangesByEntityId[change.EntityID], change)
-			mergedUpdateChangesByEntityId[mergeResult.ID] = mergeResult
-			_, err = UpdatePermanentTelemetryProfile(mergeResult)
-		case xwchange.Delete == change.Operation:
-			_, err = DeletePermanentTelemetryProfile(r, change.OldEntity.ID)
-		}
-		if err != nil {
-			logAndCollectChangeException(change, err, errorMessages)
-		} else {
-			_, err = SaveToApprovedAndCleanUpChange(r, change)
-			if err != nil {
-				logAndCollectChangeException(change, err, errorMessages)
-			} else {
-				entityToByCancelChange = append(entityToByCancelChange, change.EntityID)
-			}
-		}
-	}
-	keys := make([]string, 0, len(errorMessages))
-	for k := range errorMessages {
-		keys = append(keys, k)
-	}
-	CancelApprovedChangesByEntityId(r, entityToByCancelChange, keys)
-	return errorMessages, nil
+func newApprovedTelemetryOneChange(approvedChange *xwchange.ApprovedChange) *telemetryChange {
+	result := newTelemetryOneChange((*xwchange.Change)(approvedChange))
+	result.Change = approvedChange
+	return result
 }
 
-func SaveToApprovedAndCleanUpChange(r *http.Request, change *xwchange.Change) (*xwchange.ApprovedChange, error) {
-	userName := auth.GetUserNameOrUnknown(r)
-	change.ApprovedUser = userName
-	approvedChange, err := CreateApprovedChange(r, change)
-	if err != nil {
-		return approvedChange, err
-	}
-	Delete(change.ID)
-	log.Info("Change approved by {}: {}", userName, approvedChange)
-	return approvedChange, nil
+func (permanentTelemetryProfileStore) DeleteApprovedChange(id string) error {
+	return xchange.DeleteOneApprovedChange(id)
 }
 
-func CancelApprovedChangesByEntityId(r *http.Request, entityIdsToByCancelChanges []string, changeIdsToBeExcluded []string) error {
-	for _, entityId := range entityIdsToByCancelChanges {
-		changes := GetChangesByEntityId(entityId)
-		for _, changeByEntityId := range changes {
-			if !xutil.StringSliceContains(changeIdsToBeExcluded, changeByEntityId.ID) {
-				_, err := Delete(changeByEntityId.ID)
-				if err != nil {
-					return err
-				}
-				userName := auth.GetUserNameOrUnknown(r)
-				log.Info("Automatically canceled change by {}: {}", userName, changeByEntityId)
-			}
-		}
-	}
-	return nil
+func (permanentTelemetryProfileStore) ProfileExists(id string) bool {
+	return logupload.GetOnePermanentTelemetryProfile(id) != nil
 }
 
-func logAndCollectChangeException(change *xwchange.Change, err error, errorMessages map[string]string) {
-	errMsg := fmt.Sprintf("ApprovingException:  %v", err)
-	log.Error(errMsg)
-	errorMessages[change.ID] = errMsg
+func (permanentTelemetryProfileStore) CreateProfile(r *http.Request, profile interface{}) error {
+	_, err := CreatePermanentTelemetryProfile(r, profile.(*logupload.PermanentTelemetryProfile))
+	return err
 }
 
-func RevertChanges(r *http.Request, changeIds *[]string) (map[string]string, error) {
-	changesToRevert := []*xwchange.ApprovedChange{}
-	for _, changeId := range *changeIds {
-		approvedChange := xchange.GetOneApprovedChange(changeId)
-		if approvedChange == nil {
-			return nil, basecommon.NewXconfError(http.StatusNotFound, "ApprovedChange with "+changeId+" id does not exist")
-		}
-		changesToRevert = append(changesToRevert, approvedChange)
-	}
-	sort.Slice(changesToRevert, func(i, j int) bool {
-		return changesToRevert[i].Updated < changesToRevert[j].Updated
-	})
-	errorMessages := make(map[string]string)
-	for _, approvedChange := range changesToRevert {
-		err := Revert(r, approvedChange.ID)
-		if err != nil {
-			log.Error("RevertingException: ", err.Error())
-			errorMessages[approvedChange.ID] = err.Error()
-		}
-	}
-	return errorMessages, nil
+func (permanentTelemetryProfileStore) UpdateProfile(r *http.Request, profile interface{}) error {
+	_, err := UpdatePermanentTelemetryProfile(profile.(*logupload.PermanentTelemetryProfile))
+	return err
 }
 
-func FindByContextForChanges(searchContext map[string]string) []*xwchange.Change {
-	changes := xchange.GetChangeList()
-	changesFound := []*xwchange.Change{}
-	for _, change := range changes {
-		if applicationType, ok := xutil.FindEntryInContext(searchContext, xwcommon.APPLICATION_TYPE, false); ok {
-			if applicationType != "" && applicationType != shared.ALL {
-				if change.ApplicationType != applicationType {
-					continue
-				}
-			}
-		}
-		if author, ok := xutil.FindEntryInContext(searchContext, xcommon.AUTHOR, false); ok {
-			if author != "" {
-				if !strings.Contains(change.Author, author) {
-					continue
-				}
-			}
-		}
-		if profileName, ok := xutil.FindEntryInContext(searchContext, xcommon.ENTITY, false); ok {
-			if profileName != "" {
-				entity := &change.NewEntity
-				if entity == nil {
-					entity = &change.OldEntity
-					if entity == nil {
-						continue
-					}
-				}
-				if !strings.Contains(entity.Name, profileName) {
-					continue
-				}
-			}
-		}
-		changesFound = append(changesFound, change)
-	}
-	return changesFound
+func (permanentTelemetryProfileStore) DeleteProfile(r *http.Request, id string) error {
+	_, err := DeletePermanentTelemetryProfile(r, id)
+	return err
 }
 
-func FindByContextForApprovedChanges(r *http.Request, searchContext map[string]string) []*xwchange.ApprovedChange {
-	approvedChanges := xchange.GetApprovedChangeList()
-	changesFound := []*xwchange.ApprovedChange{}
-	for _, change := range approvedChanges {
-		if applicationType, ok := xutil.FindEntryInContext(searchContext, xwcommon.APPLICATION_TYPE, false); ok {
-			if applicationType != "" && applicationType != xwshared.ALL {
-				if change.ApplicationType != applicationType {
-					continue
-				}
-			}
-		}
-		if author, ok := xutil.FindEntryInContext(searchContext, xcommon.AUTHOR, false); ok {
-			if author != "" {
-				if !strings.Contains(change.Author, author) {
-					continue
-				}
-			}
-		}
-		if profileName, ok := xutil.FindEntryInContext(searchContext, xcommon.PROFILE_NAME, false); ok {
-			if profileName != "" {
-				entity := &change.NewEntity
-				if entity == nil {
-					entity = &change.OldEntity
-					if entity == nil {
-						continue
-					}
-				}
-				if !strings.Contains(entity.Name, profileName) {
-					continue
-				}
-			}
+func (permanentTelemetryProfileStore) MergeUpdate(mergeResult interface{}, change *telemetryChange) (interface{}, error) {
+	pendingChange := change.Change.(*xwchange.Change)
+	if mergeResult == nil {
+		profile, err := pendingChange.NewEntity.Clone()
+		if err != nil {
+			return nil, err
 		}
-		changesFound = append(changesFound, change)
+		return profile, nil
 	}
-	return changesFound
+	return ApplyUpdateChange(mergeResult.(*logupload.PermanentTelemetryProfile), pendingChange), nil
 }
@@ -0,0 +1,191 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package change
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"xconfadmin/adminapi/auth"
+	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
+	xshared "xconfadmin/shared"
+	xchange "xconfadmin/shared/change"
+	xwhttp "xconfwebconfig/http"
+
+	"github.com/gorilla/mux"
+)
+
+// GetApprovalSettingsHandler returns the approval mode of every entity type for the current application type
+func GetApprovalSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	applicationType, err := auth.CanRead(r, auth.CHANGE_ENTITY)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	stored := make(map[string]*xchange.ApprovalSetting)
+	for _, setting := range xchange.GetApprovalSettings() {
+		if xshared.ApplicationTypeEquals(applicationType, setting.ApplicationType) {
+			stored[setting.EntityType] = setting
+		}
+	}
+	settings := []*xchange.ApprovalSetting{}
+	for _, entityType := range xchange.GetEntityChangeTypes() {
+		setting, ok := stored[entityType]
+		if !ok {
+			setting = &xchange.ApprovalSetting{
+				EntityType:      entityType,
+				ApplicationType: applicationType,
+			}
+		}
+		settings = append(settings, setting)
+	}
+
+	res, err := xhttp.ReturnJsonResponse(settings, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
+func SetApprovalSettingHandler(w http.ResponseWriter, r *http.Request) {
+	if !auth.HasApprovalAdminPermission(r) {
+		xhttp.WriteAdminErrorResponse(w, http.StatusForbidden, "No permission to modify approval settings")
+		return
+	}
+
+	// r.Body is already drained in the middleware
+	xw, ok := w.(*xwhttp.XResponseWriter)
+	if !ok {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, "Unable to extract body")
+		return
+	}
+	setting := xchange.ApprovalSetting{}
+	if err := json.Unmarshal([]byte(xw.Body()), &setting); err != nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if setting.ApplicationType == "" {
+		setting.ApplicationType = xshared.GetApplicationFromCookies(r)
+	}
+	if err := xchange.SetApprovalSetting(&setting, auth.GetUserNameOrUnknown(r)); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	res, err := xhttp.ReturnJsonResponse(setting, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
+// GetApprovalPoliciesHandler returns the approval policy of every entity type for the current application type
+func GetApprovalPoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	applicationType, err := auth.CanRead(r, auth.CHANGE_ENTITY)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	stored := make(map[string]*xchange.ApprovalPolicy)
+	for _, policy := range xchange.GetApprovalPolicies() {
+		if xshared.ApplicationTypeEquals(applicationType, policy.ApplicationType) {
+			stored[policy.EntityType] = policy
+		}
+	}
+	policies := []*xchange.ApprovalPolicy{}
+	for _, entityType := range xchange.GetApprovalPolicyTypes() {
+		policy, ok := stored[entityType]
+		if !ok {
+			policy = xchange.NewDefaultApprovalPolicy(entityType, applicationType)
+		}
+		policies = append(policies, policy)
+	}
+
+	res, err := xhttp.ReturnJsonResponse(policies, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
+func SetApprovalPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	if !auth.HasApprovalAdminPermission(r) {
+		xhttp.WriteAdminErrorResponse(w, http.StatusForbidden, "No permission to modify approval policies")
+		return
+	}
+
+	// r.Body is already drained in the middleware
+	xw, ok := w.(*xwhttp.XResponseWriter)
+	if !ok {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, "Unable to extract body")
+		return
+	}
+	policy := xchange.ApprovalPolicy{}
+	if err := json.Unmarshal([]byte(xw.Body()), &policy); err != nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if policy.ApplicationType == "" {
+		policy.ApplicationType = xshared.GetApplicationFromCookies(r)
+	}
+	if policy.ApproverPermission != "" && !auth.IsKnownPermission(policy.ApproverPermission) {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, "Unknown permission: "+policy.ApproverPermission)
+		return
+	}
+	if err := xchange.SetApprovalPolicy(&policy, auth.GetUserNameOrUnknown(r)); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	res, err := xhttp.ReturnJsonResponse(policy, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
+// GetChangeApprovalsHandler returns the approvals recorded for a change which waits for the quorum
+// or the approvals which met the quorum of an approved change
+func GetChangeApprovalsHandler(w http.ResponseWriter, r *http.Request) {
+	if _, err := auth.CanRead(r, auth.CHANGE_ENTITY); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	changeId := mux.Vars(r)[xcommon.CHANGE_ID]
+	approvals := xchange.GetChangeApprovals(changeId)
+	if change := xchange.GetOneEntityChange(changeId); change != nil {
+		approvals = append([]xchange.ChangeApproval{}, change.Approvals...)
+	} else if approvedChange := xchange.GetOneApprovedEntityChange(changeId); approvedChange != nil {
+		approvals = append([]xchange.ChangeApproval{}, approvedChange.Approvals...)
+	}
+
+	res, err := xhttp.ReturnJsonResponse(approvals, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
@@ -0,0 +1,369 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package change
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"xconfadmin/adminapi/auth"
+	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
+	xchange "xconfadmin/shared/change"
+	xutil "xconfadmin/util"
+	xwcommon "xconfwebconfig/common"
+	xwshared "xconfwebconfig/shared"
+	xwchange "xconfwebconfig/shared/change"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// changeItem is a telemetry profile change or an entity change in a merged change list
+type changeItem struct {
+	updated  int64
+	entityId string
+	change   interface{}
+}
+
+func getEntityChangeApplier(r *http.Request, entityType string, applicationType string) (xchange.EntityChangeApplier, error) {
+	applier := xchange.GetEntityChangeApplier(entityType)
+	if applier == nil {
+		return nil, xcommon.NewXconfError(http.StatusBadRequest, entityType+" does not support approval")
+	}
+	if err := auth.ValidateWrite(r, applicationType, applier.PermissionEntity()); err != nil {
+		return nil, err
+	}
+	return applier, nil
+}
+
+// ApproveEntityChange writes the new entity of the change through the validators of its type,
+// nil is returned while the change waits for more approvals
+func ApproveEntityChange(r *http.Request, id string) (*xchange.ApprovedEntityChange, error) {
+	change := xchange.GetOneEntityChange(id)
+	if change == nil {
+		return nil, xcommon.NewXconfError(http.StatusNotFound, "Change with "+id+" id does not exist")
+	}
+	applier, err := getEntityChangeApplier(r, change.EntityType, change.ApplicationType)
+	if err != nil {
+		return nil, err
+	}
+	approvals, policy, err := recordApproval(r, change.ID, change.EntityType, change.ApplicationType, change.Author, change.Approvals)
+	if err != nil {
+		return nil, err
+	}
+	change.Approvals = approvals
+	if !policy.IsQuorumMet(approvals) {
+		return nil, xchange.SetOneEntityChange(change)
+	}
+	return applyEntityChange(r, applier, change)
+}
+
+// applyEntityChange writes the entity of an approved change, the approval policy is checked by the caller
+func applyEntityChange(r *http.Request, applier xchange.EntityChangeApplier, change *xchange.EntityChange) (*xchange.ApprovedEntityChange, error) {
+	var err error
+	switch change.Operation {
+	case xchange.Create:
+		err = applier.Create(change.NewEntity, change.ApplicationType)
+	case xchange.Update:
+		err = applier.Update(change.NewEntity, change.ApplicationType)
+	case xchange.Delete:
+		err = applier.Delete(change.EntityID, change.ApplicationType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	userName := auth.GetUserNameOrUnknown(r)
+	change.ApprovedUser = userName
+	approvedChange := xchange.ApprovedEntityChange(*change)
+	if err := xchange.SetOneApprovedEntityChange(&approvedChange); err != nil {
+		return nil, err
+	}
+	xchange.DeleteOneEntityChange(change.ID)
+	log.Infof("%s change of %s %s approved by %s", change.Operation, change.EntityType, change.EntityID, userName)
+
+	// the other changes of the entity were made against the previous version
+	for _, staleChange := range xchange.GetEntityChangesByEntityId(change.EntityType, change.EntityID) {
+		xchange.DeleteOneEntityChange(staleChange.ID)
+		log.Infof("Automatically canceled change by %s: %s", userName, staleChange.ID)
+	}
+	return &approvedChange, nil
+}
+
+// RevertEntityChange restores the old entity of an approved change
+func RevertEntityChange(r *http.Request, approvedId string) error {
+	if approvedId == "" {
+		return xcommon.NewXconfError(http.StatusBadRequest, "Id is blank")
+	}
+	approvedChange := xchange.GetOneApprovedEntityChange(approvedId)
+	if approvedChange == nil {
+		return xcommon.NewXconfError(http.StatusNotFound, "ApprovedChange with "+approvedId+" id does not exist")
+	}
+	applier, err := getEntityChangeApplier(r, approvedChange.EntityType, approvedChange.ApplicationType)
+	if err != nil {
+		return err
+	}
+
+	switch approvedChange.Operation {
+	case xchange.Create:
+		err = applier.Delete(approvedChange.EntityID, approvedChange.ApplicationType)
+	case xchange.Update:
+		err = applier.Update(approvedChange.OldEntity, approvedChange.ApplicationType)
+	case xchange.Delete:
+		err = applier.Create(approvedChange.OldEntity, approvedChange.ApplicationType)
+	}
+	if err != nil {
+		return err
+	}
+	xchange.DeleteOneApprovedEntityChange(approvedId)
+	log.Infof("%s change of %s %s reverted by %s", approvedChange.Operation, approvedChange.EntityType, approvedChange.EntityID, auth.GetUserNameOrUnknown(r))
+	return nil
+}
+
+// CancelEntityChange deletes the pending change and its schedule
+func CancelEntityChange(r *http.Request, changeId string) error {
+	change := xchange.GetOneEntityChange(changeId)
+	if change == nil {
+		return xcommon.NewXconfError(http.StatusNotFound, " Change with "+changeId+" id does not exist")
+	}
+	// the author can withdraw the change, anyone else needs to be able to write the entity
+	if userName := auth.GetUserNameOrUnknown(r); userName == xhttp.UNKNOWN_USER || userName != change.Author {
+		if _, err := getEntityChangeApplier(r, change.EntityType, change.ApplicationType); err != nil {
+			return err
+		}
+	}
+	if err := xchange.DeleteOneEntityChange(changeId); err != nil {
+		return err
+	}
+	xchange.DeleteScheduledChange(changeId)
+	log.Infof("%s change of %s %s canceled by %s", change.Operation, change.EntityType, change.EntityID, auth.GetUserNameOrUnknown(r))
+	return nil
+}
+
+// ApproveEntityChanges approves the changes in the order they were made and returns the error by change id
+func ApproveEntityChanges(r *http.Request, changeIds []string) map[string]string {
+	changes := []*xchange.EntityChange{}
+	for _, id := range changeIds {
+		if change := xchange.GetOneEntityChange(id); change != nil {
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Updated < changes[j].Updated
+	})
+	errorMessages := make(map[string]string)
+	for _, change := range changes {
+		// approving an earlier change of the same entity cancels this one
+		if xchange.GetOneEntityChange(change.ID) == nil {
+			continue
+		}
+		if _, err := ApproveEntityChange(r, change.ID); err != nil {
+			log.Error("ApprovingException: ", err.Error())
+			errorMessages[change.ID] = err.Error()
+		}
+	}
+	return errorMessages
+}
+
+// RevertEntityChanges reverts the approved changes, latest first, and returns the error by change id
+func RevertEntityChanges(r *http.Request, approvedIds []string) map[string]string {
+	changes := []*xchange.ApprovedEntityChange{}
+	for _, id := range approvedIds {
+		if approvedChange := xchange.GetOneApprovedEntityChange(id); approvedChange != nil {
+			changes = append(changes, approvedChange)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[j].Updated < changes[i].Updated
+	})
+	errorMessages := make(map[string]string)
+	for _, approvedChange := range changes {
+		if err := RevertEntityChange(r, approvedChange.ID); err != nil {
+			log.Error("RevertingException: ", err.Error())
+			errorMessages[approvedChange.ID] = err.Error()
+		}
+	}
+	return errorMessages
+}
+
+func entityChangeMatches(searchContext map[string]string, change *xchange.EntityChange, nameKey string) bool {
+	if applicationType, ok := xutil.FindEntryInContext(searchContext, xwcommon.APPLICATION_TYPE, false); ok {
+		if applicationType != "" && applicationType != xwshared.ALL && change.ApplicationType != applicationType {
+			return false
+		}
+	}
+	if entityType, ok := xutil.FindEntryInContext(searchContext, xcommon.ENTITY_TYPE_UPPER, false); ok {
+		if entityType != "" && !strings.EqualFold(change.EntityType, entityType) {
+			return false
+		}
+	}
+	if author, ok := xutil.FindEntryInContext(searchContext, xcommon.AUTHOR, false); ok {
+		if author != "" && !strings.Contains(change.Author, author) {
+			return false
+		}
+	}
+	if name, ok := xutil.FindEntryInContext(searchContext, nameKey, false); ok {
+		if name != "" && !strings.Contains(change.GetEntityName(), name) {
+			return false
+		}
+	}
+	return true
+}
+
+func FindByContextForEntityChanges(searchContext map[string]string) []*xchange.EntityChange {
+	changesFound := []*xchange.EntityChange{}
+	for _, change := range xchange.GetEntityChangeList() {
+		if entityChangeMatches(searchContext, change, xcommon.ENTITY) {
+			changesFound = append(changesFound, change)
+		}
+	}
+	return changesFound
+}
+
+func FindByContextForApprovedEntityChanges(searchContext map[string]string) []*xchange.ApprovedEntityChange {
+	changesFound := []*xchange.ApprovedEntityChange{}
+	for _, change := range xchange.GetApprovedEntityChangeList() {
+		if entityChangeMatches(searchContext, (*xchange.EntityChange)(change), xcommon.PROFILE_NAME) {
+			changesFound = append(changesFound, change)
+		}
+	}
+	return changesFound
+}
+
+// isTelemetryProfileSearch returns false if the search context selects another entity type
+func isTelemetryProfileSearch(searchContext map[string]string) bool {
+	entityType, ok := xutil.FindEntryInContext(searchContext, xcommon.ENTITY_TYPE_UPPER, false)
+	return !ok || entityType == "" || strings.EqualFold(entityType, string(xwchange.TelemetryProfile))
+}
+
+// mergeChanges merges the pending changes, adding the time until each change expires and its review summary
+func mergeChanges(changes []*xwchange.Change, entityChanges []*xchange.EntityChange) []changeItem {
+	now := time.Now()
+	reviews := xchange.GetChangeReviews()
+	commentCounts := xchange.GetChangeCommentCounts()
+	items := []changeItem{}
+	for _, change := range changes {
+		items = append(items, changeItem{updated: change.Updated, entityId: change.EntityID, change: newPendingChange(change, now, reviews, commentCounts)})
+	}
+	for _, change := range entityChanges {
+		items = append(items, changeItem{updated: change.Updated, entityId: change.EntityID, change: newPendingEntityChange(change, now, reviews, commentCounts)})
+	}
+	return items
+}
+
+func mergeApprovedChanges(changes []*xwchange.ApprovedChange, entityChanges []*xchange.ApprovedEntityChange) []changeItem {
+	items := []changeItem{}
+	for _, change := range changes {
+		items = append(items, changeItem{updated: change.Updated, entityId: change.EntityID, change: change})
+	}
+	for _, change := range entityChanges {
+		items = append(items, changeItem{updated: change.Updated, entityId: change.EntityID, change: change})
+	}
+	return items
+}
+
+// pendingTelemetryTwoChangeItems lists the pending telemetry 2.0 profile changes with their expiry and review summary
+func pendingTelemetryTwoChangeItems(changes []*xwchange.TelemetryTwoChange) []changeItem {
+	now := time.Now()
+	reviews := xchange.GetChangeReviews()
+	commentCounts := xchange.GetChangeCommentCounts()
+	items := make([]changeItem, 0, len(changes))
+	for _, change := range changes {
+		items = append(items, changeItem{updated: change.Updated, entityId: change.EntityID, change: newPendingTelemetryTwoChange(change, now, reviews, commentCounts)})
+	}
+	return items
+}
+
+func approvedTelemetryTwoChangeItems(changes []*xwchange.ApprovedTelemetryTwoChange) []changeItem {
+	items := make([]changeItem, 0, len(changes))
+	for _, change := range changes {
+		items = append(items, changeItem{updated: change.Updated, entityId: change.EntityID, change: change})
+	}
+	return items
+}
+
+func sortChangeItems(items []changeItem, newestFirst bool) {
+	sort.SliceStable(items, func(i, j int) bool {
+		if newestFirst {
+			return items[j].updated < items[i].updated
+		}
+		return items[i].updated < items[j].updated
+	})
+}
+
+func changeItemsGeneratePage(items []changeItem, page int, pageSize int) []changeItem {
+	leng := len(items)
+	startIndex := page*pageSize - pageSize
+	if page < 1 || startIndex > leng || pageSize < 1 {
+		return []changeItem{}
+	}
+	lastIndex := leng
+	if page*pageSize < leng {
+		lastIndex = page * pageSize
+	}
+	return items[startIndex:lastIndex]
+}
+
+func changeItemValues(items []changeItem) []interface{} {
+	values := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		values = append(values, item.change)
+	}
+	return values
+}
+
+func groupChangeItems(items []changeItem) map[string][]interface{} {
+	groupedChanges := make(map[string][]interface{})
+	for _, item := range items {
+		groupedChanges[item.entityId] = append(groupedChanges[item.entityId], item.change)
+	}
+	return groupedChanges
+}
+
+// findChangeItems returns the matching telemetry profile changes and entity changes
+func findChangeItems(searchContext map[string]string) []changeItem {
+	changes := []*xwchange.Change{}
+	if isTelemetryProfileSearch(searchContext) {
+		changes = FindByContextForChanges(searchContext)
+	}
+	return mergeChanges(changes, FindByContextForEntityChanges(searchContext))
+}
+
+// findApprovedChangeItems returns the matching approved telemetry profile changes and entity changes
+func findApprovedChangeItems(r *http.Request, searchContext map[string]string) []changeItem {
+	changes := []*xwchange.ApprovedChange{}
+	if isTelemetryProfileSearch(searchContext) {
+		changes = FindByContextForApprovedChanges(searchContext)
+	}
+	return mergeApprovedChanges(changes, FindByContextForApprovedEntityChanges(searchContext))
+}
+
+// splitChangeIds separates the ids of entity changes from the ids of telemetry profile changes
+func splitChangeIds(ids []string, isEntityChange func(id string) bool) ([]string, []string) {
+	changeIds, entityChangeIds := []string{}, []string{}
+	for _, id := range ids {
+		if isEntityChange(id) {
+			entityChangeIds = append(entityChangeIds, id)
+		} else {
+			changeIds = append(changeIds, id)
+		}
+	}
+	return changeIds, entityChangeIds
+}
@@ -0,0 +1,83 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package change
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"xconfadmin/adminapi/auth"
+	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
+	xchange "xconfadmin/shared/change"
+	"xconfadmin/testutil"
+
+	"gotest.tools/assert"
+)
+
+const testEntityChangeType = "TEST_ENTITY"
+
+type noopEntityChangeApplier struct{}
+
+func (a noopEntityChangeApplier) PermissionEntity() string {
+	return auth.FIRMWARE_ENTITY
+}
+
+func (a noopEntityChangeApplier) GetEntity(id string) interface{} {
+	return nil
+}
+
+func (a noopEntityChangeApplier) Create(entity json.RawMessage, applicationType string) error {
+	return nil
+}
+
+func (a noopEntityChangeApplier) Update(entity json.RawMessage, applicationType string) error {
+	return nil
+}
+
+func (a noopEntityChangeApplier) Delete(id string, applicationType string) error {
+	return nil
+}
+
+func TestCancelEntityChangeNeedsAuthorOrEntityWrite(t *testing.T) {
+	testutil.SetupTestDB()
+	xchange.RegisterEntityChangeApplier(testEntityChangeType, noopEntityChangeApplier{})
+	newChange := func() string {
+		change := &xchange.EntityChange{EntityID: "entity-1", EntityType: testEntityChangeType, ApplicationType: "stb", Operation: xchange.Create, Author: "author"}
+		assert.NilError(t, xchange.SetOneEntityChange(change))
+		return change.ID
+	}
+	newRequest := func(user string, permissions ...string) *http.Request {
+		r := testutil.NewRequest(http.MethodGet, "/xconfAdminService/change/cancel?applicationType=stb", "", permissions...)
+		r.Header.Set(xhttp.AUTH_SUBJECT, user)
+		return r
+	}
+
+	changeId := newChange()
+	err := CancelEntityChange(newRequest("other", auth.WRITE_CHANGES_STB), changeId)
+	assert.Equal(t, xcommon.GetXconfErrorStatusCode(err), http.StatusForbidden)
+	assert.Assert(t, xchange.GetOneEntityChange(changeId) != nil)
+
+	assert.NilError(t, CancelEntityChange(newRequest("author", auth.WRITE_CHANGES_STB), changeId))
+	assert.Assert(t, xchange.GetOneEntityChange(changeId) == nil)
+
+	changeId = newChange()
+	assert.NilError(t, CancelEntityChange(newRequest("other", auth.WRITE_CHANGES_STB, auth.WRITE_FIRMWARE_STB), changeId))
+	assert.Assert(t, xchange.GetOneEntityChange(changeId) == nil)
+}
@@ -22,7 +22,9 @@ import (
 	"net/http"
 
 	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
 	xshared "xconfadmin/shared"
+	"xconfwebconfig/db"
 	xwhttp "xconfwebconfig/http"
 
 	"xconfadmin/adminapi/auth"
@@ -40,6 +42,7 @@ import (
 func GetTelemetryProfilesByContext(searchContext map[string]string) []*logupload.PermanentTelemetryProfile {
 	filteredProfiles := []*logupload.PermanentTelemetryProfile{}
 	profiles := logupload.GetPermanentTelemetryProfileList()
+	honoredByOwner := auth.NewOwnerFilter(searchContext, xshared.OWNED_TELEMETRY_PROFILE)
 	for _, profile := range profiles {
 		if applicationType, ok := xutil.FindEntryInContext(searchContext, xwcommon.APPLICATION_TYPE, false); ok {
 			if profile.ApplicationType != applicationType {
@@ -51,6 +54,9 @@ func GetTelemetryProfilesByContext(searchContext map[string]string) []*logupload
 				continue
 			}
 		}
+		if !honoredByOwner(profile.ID) {
+			continue
+		}
 		filteredProfiles = append(filteredProfiles, profile)
 	}
 	return filteredProfiles
@@ -78,6 +84,29 @@ func CreatePermanentTelemetryProfile(r *http.Request, profile *logupload.Permane
 	return SavePermanentTelemetryProfile(r, profile)
 }
 
+// CreatePermanentTelemetryProfileAS creates the profile in its own applicationType, the caller checks
+// the write permission for it as the applicationType of the request may differ
+func CreatePermanentTelemetryProfileAS(profile *logupload.PermanentTelemetryProfile, dryRun *xhttp.DryRun) (*logupload.PermanentTelemetryProfile, error) {
+	if err := xshared.ValidateApplicationType(profile.ApplicationType); err != nil {
+		return nil, err
+	}
+	normalizeOnSaveAfterApproving(profile)
+	if err := beforeCreating(profile); err != nil {
+		return nil, err
+	}
+	if err := beforeSavingPermanentTelemetryProfile(profile); err != nil {
+		return nil, err
+	}
+	if dryRun != nil {
+		dryRun.Record(xhttp.DRY_RUN_CREATE, db.TABLE_PERMANENT_TELEMETRY, profile.ID, profile)
+		return profile, nil
+	}
+	if err := xlogupload.SetOnePermanentTelemetryProfile(profile.ID, profile); err != nil {
+		return nil, xcommon.NewXconfError(http.StatusInternalServerError, err.Error())
+	}
+	return profile, nil
+}
+
 func SavePermanentTelemetryProfile(r *http.Request, entity *logupload.PermanentTelemetryProfile) (*logupload.PermanentTelemetryProfile, error) {
 	if err := auth.ValidateWrite(r, entity.ApplicationType, auth.TELEMETRY_ENTITY); err != nil {
 		return nil, err
@@ -171,6 +200,7 @@ func DeletePermanentTelemetryProfile(r *http.Request, id string) (*logupload.Per
 		return nil, err
 	}
 	xlogupload.DeletePermanentTelemetryProfile(id)
+	auth.RemoveOwnership(xshared.OWNED_TELEMETRY_PROFILE, id)
 	return profile, nil
 }
 
@@ -240,6 +270,9 @@ func WriteCreateChange(r *http.Request, profile *logupload.PermanentTelemetryPro
 	if err := beforeCreating(profile); err != nil {
 		return nil, err
 	}
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_TELEMETRY_PROFILE, profile.ID); err != nil {
+		return nil, err
+	}
 	if err := beforeSavingPermanentTelemetryProfile(profile); err != nil {
 		return nil, err
 	}
@@ -261,6 +294,7 @@ func WriteCreateChange(r *http.Request, profile *logupload.PermanentTelemetryPro
 	if err := xchange.CreateOneChange(change); err != nil {
 		return nil, xcommon.NewXconfError(http.StatusInternalServerError, err.Error())
 	}
+	auth.AssignOwnership(r, xshared.OWNED_TELEMETRY_PROFILE, profile.ID)
 
 	return change, nil
 }
@@ -272,6 +306,9 @@ func WriteUpdateChangeOrSave(r *http.Request, newProfile *logupload.PermanentTel
 	if err := beforeUpdating(newProfile); err != nil {
 		return nil, err
 	}
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_TELEMETRY_PROFILE, newProfile.ID); err != nil {
+		return nil, err
+	}
 	if err := beforeSavingPermanentTelemetryProfile(newProfile); err != nil {
 		return nil, err
 	}
@@ -297,6 +334,7 @@ func WriteUpdateChangeOrSave(r *http.Request, newProfile *logupload.PermanentTel
 			return nil, xcommon.NewXconfError(http.StatusInternalServerError, err.Error())
 		}
 	}
+	auth.UpdateOwnership(r, xshared.OWNED_TELEMETRY_PROFILE, newProfile.ID)
 
 	return change, nil
 }
@@ -310,6 +348,9 @@ func WriteDeleteChange(r *http.Request, profileId string) (*core_change.Change,
 	if err != nil {
 		return nil, err
 	}
+	if err := auth.ValidateOwnership(r, xshared.OWNED_TELEMETRY_PROFILE, profileId); err != nil {
+		return nil, err
+	}
 	application, err := auth.CanWrite(r, auth.CHANGE_ENTITY)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,105 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package change
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"xconfadmin/adminapi/auth"
+	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
+	xwhttp "xconfwebconfig/http"
+	"xconfwebconfig/util"
+
+	"github.com/gorilla/mux"
+)
+
+// getEffectiveAt returns the effectiveAt query parameter in epoch millis, 0 if it is not set.
+// Both epoch millis and RFC 3339 times are accepted
+func getEffectiveAt(r *http.Request) (int64, error) {
+	value := r.URL.Query().Get(xcommon.EFFECTIVE_AT)
+	if value == "" {
+		return 0, nil
+	}
+	if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return millis, nil
+	}
+	effectiveAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, xcommon.NewXconfError(http.StatusBadRequest, fmt.Sprintf("%s must be epoch millis or an RFC 3339 time", xcommon.EFFECTIVE_AT))
+	}
+	return util.GetTimestamp(effectiveAt.UTC()), nil
+}
+
+// writeScheduledApproval approves the change to be applied at effectiveAt,
+// the response is the schedule or empty if the approval policy needs more approvals
+func writeScheduledApproval(w http.ResponseWriter, r *http.Request, changeId string, effectiveAt int64) {
+	scheduledChange, err := ScheduleApproval(r, changeId, effectiveAt)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	if scheduledChange == nil {
+		xwhttp.WriteXconfResponse(w, http.StatusAccepted, nil)
+		return
+	}
+	res, err := xhttp.ReturnJsonResponse(scheduledChange, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteResponseBytes(w, res, http.StatusAccepted, xhttp.ContextTypeHeader(r))
+}
+
+func GetScheduledChangesHandler(w http.ResponseWriter, r *http.Request) {
+	applicationType, err := auth.CanRead(r, auth.CHANGE_ENTITY)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	res, err := xhttp.ReturnJsonResponse(GetScheduledChanges(applicationType), r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteResponseBytes(w, res, http.StatusOK, xhttp.ContextTypeHeader(r))
+}
+
+func CancelScheduledChangeHandler(w http.ResponseWriter, r *http.Request) {
+	applicationType, err := auth.CanWrite(r, auth.CHANGE_ENTITY)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	changeId, found := mux.Vars(r)[xcommon.CHANGE_ID]
+	if !found || changeId == "" {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("%v is invalid", xcommon.CHANGE_ID))
+		return
+	}
+
+	if err := CancelScheduledChange(r, changeId, applicationType); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusNoContent, nil)
+}
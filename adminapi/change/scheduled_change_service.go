@@ -0,0 +1,258 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package change
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"xconfadmin/adminapi/auth"
+	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
+	xshared "xconfadmin/shared"
+	xchange "xconfadmin/shared/change"
+	"xconfwebconfig/db"
+	xwchange "xconfwebconfig/shared/change"
+	"xconfwebconfig/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// validateNotScheduled rejects the approval of a change which is already scheduled,
+// the schedule must be canceled first to apply the change now
+func validateNotScheduled(changeId string) error {
+	if scheduledChange := xchange.GetScheduledChange(changeId); scheduledChange != nil {
+		return xcommon.NewXconfError(http.StatusConflict, fmt.Sprintf("Change %s is scheduled for %s", changeId, time.Unix(0, scheduledChange.EffectiveAt*int64(time.Millisecond)).UTC().Format(time.RFC3339)))
+	}
+	return nil
+}
+
+// ScheduleApproval approves the change and schedules it for effectiveAt (epoch millis).
+// It returns nil if the approval is recorded but the quorum of the approval policy is not met yet
+func ScheduleApproval(r *http.Request, changeId string, effectiveAt int64) (*xchange.ScheduledChange, error) {
+	if effectiveAt <= util.GetTimestamp(time.Now().UTC()) {
+		return nil, xcommon.NewXconfError(http.StatusBadRequest, fmt.Sprintf("%s must be in the future", xcommon.EFFECTIVE_AT))
+	}
+
+	scheduledChange := &xchange.ScheduledChange{
+		ID:           changeId,
+		EffectiveAt:  effectiveAt,
+		ScheduledBy:  auth.GetUserNameOrUnknown(r),
+		Permissions:  auth.GetPermissionsFunc(r),
+		Capabilities: xhttp.GetCapabilitiesFromContext(r),
+	}
+	if key := xhttp.GetApiKeyFromContext(r); key != nil {
+		scheduledChange.ApiKeyID = key.ID
+	}
+	if change := xchange.GetOneEntityChange(changeId); change != nil {
+		if _, err := getEntityChangeApplier(r, change.EntityType, change.ApplicationType); err != nil {
+			return nil, err
+		}
+		approvals, policy, err := recordApproval(r, change.ID, change.EntityType, change.ApplicationType, change.Author, change.Approvals)
+		if err != nil {
+			return nil, err
+		}
+		change.Approvals = approvals
+		if err := xchange.SetOneEntityChange(change); err != nil {
+			return nil, err
+		}
+		if !policy.IsQuorumMet(approvals) {
+			return nil, nil
+		}
+		scheduledChange.ChangeTable = xcommon.TABLE_ENTITY_CHANGES
+		scheduledChange.EntityID = change.EntityID
+		scheduledChange.EntityType = change.EntityType
+		scheduledChange.ApplicationType = change.ApplicationType
+	} else if change := xchange.GetOneChange(changeId); change != nil {
+		approvals, quorumMet, err := recordTelemetryChangeApproval(r, string(xwchange.TelemetryProfile), change.ID, change.ApplicationType, change.Author)
+		if err != nil || !quorumMet {
+			return nil, err
+		}
+		if err := xchange.SetChangeApprovals(change.ID, approvals); err != nil {
+			return nil, err
+		}
+		scheduledChange.ChangeTable = db.TABLE_XCONF_CHANGE
+		scheduledChange.EntityID = change.EntityID
+		scheduledChange.EntityType = string(xwchange.TelemetryProfile)
+		scheduledChange.ApplicationType = change.ApplicationType
+	} else if change := xchange.GetOneTelemetryTwoChange(changeId); change != nil {
+		approvals, quorumMet, err := recordTelemetryChangeApproval(r, xchange.TelemetryTwoProfile, change.ID, change.ApplicationType, change.Author)
+		if err != nil || !quorumMet {
+			return nil, err
+		}
+		if err := xchange.SetChangeApprovals(change.ID, approvals); err != nil {
+			return nil, err
+		}
+		scheduledChange.ChangeTable = db.TABLE_XCONF_TELEMETRY_TWO_CHANGE
+		scheduledChange.EntityID = change.EntityID
+		scheduledChange.EntityType = xchange.TelemetryTwoProfile
+		scheduledChange.ApplicationType = change.ApplicationType
+	} else {
+		return nil, xcommon.NewXconfError(http.StatusNotFound, "Change with "+changeId+" id does not exist")
+	}
+
+	if err := xchange.SetScheduledChange(scheduledChange); err != nil {
+		return nil, err
+	}
+	log.Infof("%s change %s scheduled by %s for %d", scheduledChange.EntityType, changeId, scheduledChange.ScheduledBy, effectiveAt)
+	return scheduledChange, nil
+}
+
+// GetScheduledChanges returns the scheduled changes of the application type
+func GetScheduledChanges(applicationType string) []*xchange.ScheduledChange {
+	result := []*xchange.ScheduledChange{}
+	for _, scheduledChange := range xchange.GetScheduledChanges() {
+		if xshared.ApplicationTypeEquals(applicationType, scheduledChange.ApplicationType) {
+			result = append(result, scheduledChange)
+		}
+	}
+	return result
+}
+
+// CancelScheduledChange removes the schedule, the change stays pending with its approvals
+func CancelScheduledChange(r *http.Request, changeId string, applicationType string) error {
+	scheduledChange := xchange.GetScheduledChange(changeId)
+	if scheduledChange == nil || !xshared.ApplicationTypeEquals(applicationType, scheduledChange.ApplicationType) {
+		return xcommon.NewXconfError(http.StatusNotFound, "Scheduled change with "+changeId+" id does not exist")
+	}
+	if err := xchange.DeleteScheduledChange(changeId); err != nil {
+		return err
+	}
+	log.Infof("scheduled change %s canceled by %s", changeId, auth.GetUserNameOrUnknown(r))
+	return nil
+}
+
+// StartScheduledChangeScheduler applies the due scheduled changes in the background
+func StartScheduledChangeScheduler(interval time.Duration) {
+	log.Infof("scheduled change scheduler is running every %v", interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			if applied := ApplyDueChanges(now); applied > 0 {
+				log.Infof("scheduled change scheduler applied %d changes", applied)
+			}
+		}
+	}()
+}
+
+// ApplyDueChanges applies the scheduled changes which are due and returns the number of applied changes.
+// Every change is claimed first, so with several admin instances only one of them applies it
+func ApplyDueChanges(now time.Time) int {
+	if window := xcommon.GetActiveReadonlyWindow(now); window != nil {
+		log.Debugf("scheduled changes are postponed by freeze window %s", window.String())
+		return 0
+	}
+	owner, _ := os.Hostname()
+	applied := 0
+	for _, scheduledChange := range xchange.GetScheduledChanges() {
+		if !scheduledChange.IsDue(now) {
+			break
+		}
+		claimed, err := xchange.ClaimScheduledChange(scheduledChange, owner)
+		if err != nil {
+			log.Errorf("unable to claim scheduled change %s: %s", scheduledChange.ID, err.Error())
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		status := http.StatusOK
+		if err := applyScheduledChange(scheduledChange); err != nil {
+			log.Errorf("unable to apply scheduled change %s: %s", scheduledChange.ID, err.Error())
+			status = xcommon.GetXconfErrorStatusCode(err)
+		} else {
+			applied++
+		}
+		xchange.DeleteScheduledChange(scheduledChange.ID)
+		auditScheduledChange(scheduledChange, status)
+	}
+	return applied
+}
+
+// newScheduledChangeRequest builds the request of the user who scheduled the change. The api key of a service account
+// is looked up again, so a key revoked or an account disabled since then can't apply the change any more
+func newScheduledChangeRequest(scheduledChange *xchange.ScheduledChange, now time.Time) (*http.Request, error) {
+	if scheduledChange.ApiKeyID == "" {
+		return xhttp.NewBackgroundRequest(scheduledChange.ScheduledBy, scheduledChange.ApplicationType, scheduledChange.Permissions, scheduledChange.Capabilities), nil
+	}
+	account, key, err := xshared.GetUsableServiceAccountKey(scheduledChange.ApiKeyID, now)
+	if err != nil {
+		return nil, xcommon.NewXconfError(http.StatusForbidden, fmt.Sprintf("%s can no longer apply the change: %s", scheduledChange.ScheduledBy, err.Error()))
+	}
+	return xhttp.NewServiceAccountBackgroundRequest(account, key, scheduledChange.ApplicationType), nil
+}
+
+// applyScheduledChange applies the change on behalf of the user who scheduled it,
+// the user must still be allowed to approve it by the current approval policy
+func applyScheduledChange(scheduledChange *xchange.ScheduledChange) error {
+	r, err := newScheduledChangeRequest(scheduledChange, time.Now())
+	if err != nil {
+		return err
+	}
+	if _, err := getReviewPolicy(r, scheduledChange.EntityType, scheduledChange.ApplicationType); err != nil {
+		return err
+	}
+	notPending := xcommon.NewXconfError(http.StatusNotFound, "Change with "+scheduledChange.ID+" id is no longer pending")
+	switch scheduledChange.ChangeTable {
+	case db.TABLE_XCONF_CHANGE, db.TABLE_XCONF_TELEMETRY_TWO_CHANGE:
+		engine := telemetryOneChanges
+		if scheduledChange.ChangeTable == db.TABLE_XCONF_TELEMETRY_TWO_CHANGE {
+			engine = telemetryTwoChanges
+		}
+		change := engine.store.GetChange(scheduledChange.ID)
+		if change == nil {
+			return notPending
+		}
+		_, err := engine.apply(r, change, xchange.GetChangeApprovals(change.ID))
+		return err
+	case xcommon.TABLE_ENTITY_CHANGES:
+		change := xchange.GetOneEntityChange(scheduledChange.ID)
+		if change == nil {
+			return notPending
+		}
+		applier, err := getEntityChangeApplier(r, change.EntityType, change.ApplicationType)
+		if err != nil {
+			return err
+		}
+		_, err = applyEntityChange(r, applier, change)
+		return err
+	}
+	return xcommon.NewXconfError(http.StatusBadRequest, "Unknown change table "+scheduledChange.ChangeTable)
+}
+
+func auditScheduledChange(scheduledChange *xchange.ScheduledChange, status int) {
+	entry := xshared.AuditEntry{
+		User:            scheduledChange.ScheduledBy,
+		Operation:       xshared.AUDIT_APPROVE,
+		Status:          status,
+		EntityType:      "ScheduledChanges",
+		EntityID:        scheduledChange.ID,
+		TableName:       scheduledChange.ChangeTable,
+		ApplicationType: scheduledChange.ApplicationType,
+	}
+	if before, err := json.Marshal(scheduledChange); err == nil {
+		entry.Before = before
+	}
+	if err := xshared.SaveAuditEntry(&entry); err != nil {
+		log.Errorf("unable to save audit entry for scheduled change %s: %s", scheduledChange.ID, err.Error())
+	}
+}
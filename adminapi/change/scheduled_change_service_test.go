@@ -0,0 +1,77 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package change
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"xconfadmin/adminapi/auth"
+	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
+	xshared "xconfadmin/shared"
+	xchange "xconfadmin/shared/change"
+	"xconfadmin/testutil"
+
+	"gotest.tools/assert"
+)
+
+func TestClaimIsGrantedOnce(t *testing.T) {
+	testutil.SetupTestDB()
+	claimed, err := xchange.Claim("claim-1", "owner-1")
+	assert.NilError(t, err)
+	assert.Assert(t, claimed)
+	claimed, err = xchange.Claim("claim-1", "owner-2")
+	assert.NilError(t, err)
+	assert.Assert(t, !claimed)
+}
+
+func TestScheduledChangeResolvesApiKeyPermissions(t *testing.T) {
+	testutil.SetupTestDB()
+	xchange.RegisterEntityChangeApplier(testEntityChangeType, noopEntityChangeApplier{})
+	account := &xshared.ServiceAccount{Name: "deployer"}
+	assert.NilError(t, xshared.SetServiceAccount(account))
+	key := &xshared.ServiceAccountKey{AccountID: account.ID, Permissions: []string{auth.WRITE_CHANGES_STB, auth.WRITE_FIRMWARE_STB}}
+	_, err := xshared.IssueServiceAccountKey(key)
+	assert.NilError(t, err)
+
+	change := &xchange.EntityChange{EntityID: "entity-1", EntityType: testEntityChangeType, ApplicationType: "stb", Operation: xchange.Create, Author: "author"}
+	assert.NilError(t, xchange.SetOneEntityChange(change))
+	scheduledChange := &xchange.ScheduledChange{
+		ID:              change.ID,
+		ChangeTable:     xcommon.TABLE_ENTITY_CHANGES,
+		EntityID:        change.EntityID,
+		EntityType:      change.EntityType,
+		ApplicationType: change.ApplicationType,
+		ScheduledBy:     xhttp.ServiceAccountSubjectPrefix + account.Name,
+		ApiKeyID:        key.ID,
+		// the snapshot is ignored for an api key
+		Permissions: []string{auth.WRITE_CHANGES_ALL, auth.WRITE_FIRMWARE_ALL},
+	}
+
+	r, err := newScheduledChangeRequest(scheduledChange, time.Now())
+	assert.NilError(t, err)
+	assert.DeepEqual(t, auth.GetPermissionsFunc(r), key.Permissions)
+
+	key.RevokedAt = time.Now().UnixMilli()
+	assert.NilError(t, xshared.SetServiceAccountKey(key))
+	err = applyScheduledChange(scheduledChange)
+	assert.Equal(t, xcommon.GetXconfErrorStatusCode(err), http.StatusForbidden)
+	assert.Assert(t, xchange.GetOneEntityChange(change.ID) != nil)
+}
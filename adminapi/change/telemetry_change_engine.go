@@ -0,0 +1,354 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package change
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"xconfadmin/adminapi/auth"
+	xcommon "xconfadmin/common"
+	xchange "xconfadmin/shared/change"
+	xutil "xconfadmin/util"
+	xwcommon "xconfwebconfig/common"
+	xwshared "xconfwebconfig/shared"
+	xwchange "xconfwebconfig/shared/change"
+	xwutil "xconfwebconfig/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// telemetryChange is a pending or approved change of a telemetry profile as seen by the change engine,
+// Change holds the stored change returned to the handlers
+type telemetryChange struct {
+	ID              string
+	EntityID        string
+	ApplicationType string
+	Author          string
+	EntityName      string
+	Operation       xwchange.ChangeOperation
+	Updated         int64
+	OldEntity       interface{}
+	NewEntity       interface{}
+	Change          interface{}
+}
+
+// telemetryProfileStore plugs the changes and the profiles of one telemetry profile type into the change engine,
+// a nil profile is passed as a nil interface
+type telemetryProfileStore interface {
+	// EntityType returns the entity type of the approval policy
+	EntityType() string
+	GetChange(id string) *telemetryChange
+	GetChanges() []*telemetryChange
+	DeleteChange(id string) error
+	// SaveApprovedChange stores the change as approved by the user and returns the approved change
+	SaveApprovedChange(r *http.Request, change *telemetryChange, approvedUser string) (interface{}, error)
+	GetApprovedChange(id string) *telemetryChange
+	GetApprovedChanges() []*telemetryChange
+	DeleteApprovedChange(id string) error
+	ProfileExists(id string) bool
+	CreateProfile(r *http.Request, profile interface{}) error
+	UpdateProfile(r *http.Request, profile interface{}) error
+	DeleteProfile(r *http.Request, id string) error
+	// MergeUpdate applies the update change on top of the earlier updates of the profile approved in the same batch,
+	// a nil merge result starts from a copy of the new profile
+	MergeUpdate(mergeResult interface{}, change *telemetryChange) (interface{}, error)
+}
+
+// telemetryChangeEngine approves, reverts, cancels and searches the changes of one telemetry profile type
+type telemetryChangeEngine struct {
+	store telemetryProfileStore
+	// skipUnknownIds skips the unknown ids of a batch, otherwise the batch fails on the first one
+	skipUnknownIds bool
+	// approvalErrorPrefix prefixes the errors returned by approveAll
+	approvalErrorPrefix string
+}
+
+var (
+	telemetryOneChanges = &telemetryChangeEngine{store: permanentTelemetryProfileStore{}, approvalErrorPrefix: "ApprovingException:  "}
+	telemetryTwoChanges = &telemetryChangeEngine{store: telemetryTwoProfileStore{}, skipUnknownIds: true}
+)
+
+// approve records the approval of the user and applies the change once the quorum of the approval policy is met,
+// nil is returned while the change waits for more approvals
+func (e *telemetryChangeEngine) approve(r *http.Request, id string) (interface{}, error) {
+	change := e.store.GetChange(id)
+	if change == nil {
+		return nil, xcommon.NewXconfError(http.StatusNotFound, "Change with "+id+" id does not exist")
+	}
+	approvals, quorumMet, err := recordTelemetryChangeApproval(r, e.store.EntityType(), change.ID, change.ApplicationType, change.Author)
+	if err != nil || !quorumMet {
+		return nil, err
+	}
+	return e.apply(r, change, approvals)
+}
+
+// apply writes the profile of an approved change and cancels the other changes of the profile,
+// the approval policy is checked by the caller
+func (e *telemetryChangeEngine) apply(r *http.Request, change *telemetryChange, approvals []xchange.ChangeApproval) (interface{}, error) {
+	if err := e.writeProfile(r, change, change.NewEntity); err != nil {
+		return nil, err
+	}
+	approvedChange, err := e.saveApproved(r, change, approvals)
+	if err != nil {
+		return nil, err
+	}
+	e.cancelChangesOfProfiles(r, []string{change.EntityID}, []string{})
+	return approvedChange, nil
+}
+
+// approveAll approves the changes in the order they were made, the updates of the same profile are merged
+// so a later change does not overwrite the fields changed by an earlier one. The error is returned by change id
+func (e *telemetryChangeEngine) approveAll(r *http.Request, ids []string) (map[string]string, error) {
+	changes := []*telemetryChange{}
+	for _, id := range ids {
+		if id == "" && !e.skipUnknownIds {
+			return nil, xcommon.NewXconfError(http.StatusBadRequest, "Id is blank")
+		}
+		change := e.store.GetChange(id)
+		if change == nil {
+			if e.skipUnknownIds {
+				continue
+			}
+			return nil, xcommon.NewXconfError(http.StatusNotFound, "Change with "+id+" id does not exist")
+		}
+		changes = append(changes, change)
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Updated < changes[j].Updated
+	})
+
+	errorMessages := make(map[string]string)
+	mergedProfiles := make(map[string]interface{})
+	approvedProfileIds := []string{}
+	// the changes which failed or wait for more approvals are not canceled by the approved changes of their profile
+	excludedIds := []string{}
+	for _, change := range changes {
+		approvals, quorumMet, err := recordTelemetryChangeApproval(r, e.store.EntityType(), change.ID, change.ApplicationType, change.Author)
+		if err != nil {
+			excludedIds = append(excludedIds, e.collectApprovalError(errorMessages, change.ID, err))
+			continue
+		}
+		if !quorumMet {
+			excludedIds = append(excludedIds, change.ID)
+			continue
+		}
+		newProfile := change.NewEntity
+		if change.Operation == xwchange.Update {
+			if newProfile, err = e.store.MergeUpdate(mergedProfiles[change.EntityID], change); err != nil {
+				excludedIds = append(excludedIds, e.collectApprovalError(errorMessages, change.ID, err))
+				continue
+			}
+		}
+		if err := e.writeProfile(r, change, newProfile); err != nil {
+			excludedIds = append(excludedIds, e.collectApprovalError(errorMessages, change.ID, err))
+			continue
+		}
+		if change.Operation == xwchange.Update {
+			mergedProfiles[change.EntityID] = newProfile
+		}
+		if _, err := e.saveApproved(r, change, approvals); err != nil {
+			excludedIds = append(excludedIds, e.collectApprovalError(errorMessages, change.ID, err))
+			continue
+		}
+		approvedProfileIds = append(approvedProfileIds, change.EntityID)
+	}
+	e.cancelChangesOfProfiles(r, approvedProfileIds, excludedIds)
+	return errorMessages, nil
+}
+
+// collectApprovalError logs the error and stores it by change id, the change id is returned
+func (e *telemetryChangeEngine) collectApprovalError(errorMessages map[string]string, changeId string, err error) string {
+	errMsg := fmt.Sprintf("%s%v", e.approvalErrorPrefix, err)
+	log.Errorf("ApprovingException: %v", err)
+	errorMessages[changeId] = errMsg
+	return changeId
+}
+
+// writeProfile writes the profile of the change, an update or a delete fails if the profile was deleted meanwhile
+func (e *telemetryChangeEngine) writeProfile(r *http.Request, change *telemetryChange, newProfile interface{}) error {
+	switch change.Operation {
+	case xwchange.Create:
+		return e.store.CreateProfile(r, newProfile)
+	case xwchange.Update, xwchange.Delete:
+		if !e.store.ProfileExists(change.EntityID) {
+			return xcommon.NewXconfError(http.StatusConflict, fmt.Sprintf("Change could not be approved, %s %s has been already deleted", e.store.EntityType(), change.EntityID))
+		}
+		if change.Operation == xwchange.Update {
+			return e.store.UpdateProfile(r, newProfile)
+		}
+		return e.store.DeleteProfile(r, change.EntityID)
+	}
+	return xcommon.NewXconfError(http.StatusBadRequest, "Operation is empty")
+}
+
+// saveApproved stores the approved change and the approvals which met the quorum, the approved change
+// keeps the id of the change so its approvals are found by the same id
+func (e *telemetryChangeEngine) saveApproved(r *http.Request, change *telemetryChange, approvals []xchange.ChangeApproval) (interface{}, error) {
+	userName := auth.GetUserNameOrUnknown(r)
+	approvedChange, err := e.store.SaveApprovedChange(r, change, userName)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.store.DeleteChange(change.ID); err != nil {
+		log.Errorf("unable to delete approved change %s: %s", change.ID, err.Error())
+	}
+	xchange.DeleteScheduledChange(change.ID)
+	if err := xchange.SetChangeApprovals(change.ID, approvals); err != nil {
+		log.Errorf("unable to save approvals of change %s: %s", change.ID, err.Error())
+	}
+	log.Infof("%s change of %s %s approved by %s", change.Operation, e.store.EntityType(), change.EntityID, userName)
+	return approvedChange, nil
+}
+
+// cancelChangesOfProfiles cancels the pending changes of the profiles except the excluded ones,
+// they were made against the previous version of the profile
+func (e *telemetryChangeEngine) cancelChangesOfProfiles(r *http.Request, profileIds []string, excludedChangeIds []string) {
+	userName := auth.GetUserNameOrUnknown(r)
+	for _, change := range e.store.GetChanges() {
+		if !xwutil.Contains(profileIds, change.EntityID) || xwutil.Contains(excludedChangeIds, change.ID) {
+			continue
+		}
+		if err := e.deleteChange(change.ID); err != nil {
+			log.Errorf("unable to cancel change %s: %s", change.ID, err.Error())
+			continue
+		}
+		log.Infof("Automatically canceled change by %s: %s", userName, change.ID)
+	}
+}
+
+// revert restores the profile as it was before the approved change
+func (e *telemetryChangeEngine) revert(r *http.Request, approvedId string) error {
+	if approvedId == "" {
+		return xcommon.NewXconfError(http.StatusBadRequest, "Id is blank")
+	}
+	approvedChange := e.store.GetApprovedChange(approvedId)
+	if approvedChange == nil {
+		return xcommon.NewXconfError(http.StatusNotFound, "ApprovedChange with "+approvedId+" id does not exist")
+	}
+	if approvedChange.Operation != xwchange.Create && approvedChange.OldEntity == nil {
+		return xcommon.NewXconfError(http.StatusInternalServerError, "Old entity is empty for ApprovedChange with "+approvedId+" id")
+	}
+	profileExists := e.store.ProfileExists(approvedChange.EntityID)
+	if approvedChange.Operation != xwchange.Delete && !profileExists {
+		return xcommon.NewXconfError(http.StatusConflict, fmt.Sprintf("Change could not be reverted, %s %s has been already deleted", e.store.EntityType(), approvedChange.EntityID))
+	}
+
+	var err error
+	switch approvedChange.Operation {
+	case xwchange.Create:
+		err = e.store.DeleteProfile(r, approvedChange.EntityID)
+	case xwchange.Update:
+		err = e.store.UpdateProfile(r, approvedChange.OldEntity)
+	case xwchange.Delete:
+		err = e.store.CreateProfile(r, approvedChange.OldEntity)
+	}
+	if err != nil {
+		return err
+	}
+	if err := e.store.DeleteApprovedChange(approvedId); err != nil {
+		return err
+	}
+	xchange.DeleteChangeApprovals(approvedId)
+	log.Infof("%s change of %s %s reverted by %s", approvedChange.Operation, e.store.EntityType(), approvedChange.EntityID, auth.GetUserNameOrUnknown(r))
+	return nil
+}
+
+// revertAll reverts the approved changes in the order they were approved and returns the error by change id
+func (e *telemetryChangeEngine) revertAll(r *http.Request, approvedIds []string) (map[string]string, error) {
+	changes := []*telemetryChange{}
+	for _, id := range approvedIds {
+		approvedChange := e.store.GetApprovedChange(id)
+		if approvedChange == nil {
+			if e.skipUnknownIds {
+				continue
+			}
+			return nil, xcommon.NewXconfError(http.StatusNotFound, "ApprovedChange with "+id+" id does not exist")
+		}
+		changes = append(changes, approvedChange)
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Updated < changes[j].Updated
+	})
+	errorMessages := make(map[string]string)
+	for _, approvedChange := range changes {
+		if err := e.revert(r, approvedChange.ID); err != nil {
+			log.Error("RevertingException: ", err.Error())
+			errorMessages[approvedChange.ID] = err.Error()
+		}
+	}
+	return errorMessages, nil
+}
+
+// cancel deletes the pending change together with its approvals and schedule
+func (e *telemetryChangeEngine) cancel(r *http.Request, changeId string) error {
+	if err := e.deleteChange(changeId); err != nil {
+		return err
+	}
+	log.Infof("Change has been canceled by %s: %s", auth.GetUserNameOrUnknown(r), changeId)
+	return nil
+}
+
+func (e *telemetryChangeEngine) deleteChange(changeId string) error {
+	if changeId == "" {
+		return xcommon.NewXconfError(http.StatusBadRequest, "Id is blank")
+	}
+	if e.store.GetChange(changeId) == nil {
+		return xcommon.NewXconfError(http.StatusNotFound, "Change with "+changeId+" id does not exist")
+	}
+	if err := e.store.DeleteChange(changeId); err != nil {
+		return xcommon.NewXconfError(http.StatusInternalServerError, err.Error())
+	}
+	xchange.DeleteChangeApprovals(changeId)
+	xchange.DeleteScheduledChange(changeId)
+	return nil
+}
+
+// find returns the pending changes matching the application type, the author and the profile name of the search context
+func (e *telemetryChangeEngine) find(searchContext map[string]string) []*telemetryChange {
+	return filterTelemetryChanges(e.store.GetChanges(), searchContext, xcommon.ENTITY)
+}
+
+// findApproved returns the approved changes matching the search context, the profile name is looked up by nameKey
+func (e *telemetryChangeEngine) findApproved(searchContext map[string]string, nameKey string) []*telemetryChange {
+	return filterTelemetryChanges(e.store.GetApprovedChanges(), searchContext, nameKey)
+}
+
+func filterTelemetryChanges(changes []*telemetryChange, searchContext map[string]string, nameKey string) []*telemetryChange {
+	changesFound := []*telemetryChange{}
+	for _, change := range changes {
+		if applicationType, ok := xutil.FindEntryInContext(searchContext, xwcommon.APPLICATION_TYPE, false); ok {
+			if applicationType != "" && applicationType != xwshared.ALL && change.ApplicationType != applicationType {
+				continue
+			}
+		}
+		if author, ok := xutil.FindEntryInContext(searchContext, xcommon.AUTHOR, false); ok {
+			if !xutil.ContainsIgnoreCase(change.Author, author) {
+				continue
+			}
+		}
+		if name, ok := xutil.FindEntryInContext(searchContext, nameKey, false); ok {
+			if !xutil.ContainsIgnoreCase(change.EntityName, name) {
+				continue
+			}
+		}
+		changesFound = append(changesFound, change)
+	}
+	return changesFound
+}
@@ -0,0 +1,262 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package change
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	xhttp "xconfadmin/http"
+	xchange "xconfadmin/shared/change"
+	"xconfadmin/testutil"
+	xwchange "xconfwebconfig/shared/change"
+
+	"gotest.tools/assert"
+)
+
+// memoryProfileStore keeps the changes and the profiles, a profile is its name, in maps
+// and records the profile writes in order
+type memoryProfileStore struct {
+	changes  map[string]*telemetryChange
+	approved map[string]*telemetryChange
+	profiles map[string]interface{}
+	writes   []string
+}
+
+func newMemoryProfileStore() *memoryProfileStore {
+	return &memoryProfileStore{
+		changes:  map[string]*telemetryChange{},
+		approved: map[string]*telemetryChange{},
+		profiles: map[string]interface{}{},
+	}
+}
+
+func (s *memoryProfileStore) EntityType() string {
+	return xchange.TelemetryTwoProfile
+}
+
+func (s *memoryProfileStore) GetChange(id string) *telemetryChange {
+	return s.changes[id]
+}
+
+func (s *memoryProfileStore) GetChanges() []*telemetryChange {
+	result := []*telemetryChange{}
+	for _, change := range s.changes {
+		result = append(result, change)
+	}
+	return result
+}
+
+func (s *memoryProfileStore) DeleteChange(id string) error {
+	delete(s.changes, id)
+	return nil
+}
+
+func (s *memoryProfileStore) SaveApprovedChange(r *http.Request, change *telemetryChange, approvedUser string) (interface{}, error) {
+	s.approved[change.ID] = change
+	return change, nil
+}
+
+func (s *memoryProfileStore) GetApprovedChange(id string) *telemetryChange {
+	return s.approved[id]
+}
+
+func (s *memoryProfileStore) GetApprovedChanges() []*telemetryChange {
+	result := []*telemetryChange{}
+	for _, change := range s.approved {
+		result = append(result, change)
+	}
+	return result
+}
+
+func (s *memoryProfileStore) DeleteApprovedChange(id string) error {
+	delete(s.approved, id)
+	return nil
+}
+
+func (s *memoryProfileStore) ProfileExists(id string) bool {
+	_, ok := s.profiles[id]
+	return ok
+}
+
+func (s *memoryProfileStore) CreateProfile(r *http.Request, profile interface{}) error {
+	s.profiles[profile.(string)] = profile
+	s.writes = append(s.writes, "create "+profile.(string))
+	return nil
+}
+
+func (s *memoryProfileStore) UpdateProfile(r *http.Request, profile interface{}) error {
+	s.profiles[profile.(string)] = profile
+	s.writes = append(s.writes, "update "+profile.(string))
+	return nil
+}
+
+func (s *memoryProfileStore) DeleteProfile(r *http.Request, id string) error {
+	delete(s.profiles, id)
+	s.writes = append(s.writes, "delete "+id)
+	return nil
+}
+
+func (s *memoryProfileStore) MergeUpdate(mergeResult interface{}, change *telemetryChange) (interface{}, error) {
+	return change.NewEntity, nil
+}
+
+func (s *memoryProfileStore) addChange(id string, operation xwchange.ChangeOperation, profile string, updated int64) {
+	change := &telemetryChange{
+		ID:              id,
+		EntityID:        profile,
+		ApplicationType: "stb",
+		Author:          "author",
+		Operation:       operation,
+		Updated:         updated,
+		NewEntity:       profile,
+	}
+	if operation != xwchange.Create {
+		change.OldEntity = profile
+	}
+	s.changes[id] = change
+}
+
+func newApproverRequest(user string) *http.Request {
+	r := testutil.NewRequest(http.MethodPost, "/xconfAdminService/change/approve", "")
+	r.Header.Set(xhttp.AUTH_SUBJECT, user)
+	return r
+}
+
+func setApprovalPolicy(t *testing.T, requiredApprovals int, allowSelfApproval bool) {
+	policy := xchange.NewDefaultApprovalPolicy(xchange.TelemetryTwoProfile, "stb")
+	policy.RequiredApprovals = requiredApprovals
+	policy.AllowSelfApproval = allowSelfApproval
+	assert.NilError(t, xchange.SetApprovalPolicy(policy, testutil.TestUser))
+}
+
+func TestTelemetryChangeEngineApproveWaitsForQuorum(t *testing.T) {
+	testutil.SetupTestDB()
+	setApprovalPolicy(t, 2, false)
+	store := newMemoryProfileStore()
+	engine := &telemetryChangeEngine{store: store}
+	store.addChange("change-1", xwchange.Create, "profile-1", 1)
+
+	approved, err := engine.approve(newApproverRequest("approver-1"), "change-1")
+	assert.NilError(t, err)
+	assert.Assert(t, approved == nil)
+	assert.Assert(t, !store.ProfileExists("profile-1"))
+
+	approved, err = engine.approve(newApproverRequest("approver-2"), "change-1")
+	assert.NilError(t, err)
+	assert.Assert(t, approved != nil)
+	assert.Assert(t, store.ProfileExists("profile-1"))
+	assert.Assert(t, store.GetChange("change-1") == nil)
+
+	_, err = engine.approve(newApproverRequest("approver-2"), "change-1")
+	assert.ErrorContains(t, err, "Change with change-1 id does not exist")
+}
+
+func TestTelemetryChangeEngineApproveAllKeepsChangesWaitingForQuorum(t *testing.T) {
+	testutil.SetupTestDB()
+	setApprovalPolicy(t, 2, false)
+	store := newMemoryProfileStore()
+	engine := &telemetryChangeEngine{store: store}
+	store.profiles["profile-1"] = "profile-1"
+	store.addChange("change-1", xwchange.Update, "profile-1", 1)
+	store.addChange("change-2", xwchange.Update, "profile-1", 2)
+	store.addChange("change-3", xwchange.Update, "profile-1", 3)
+
+	// the first approver approves the first change alone, then the three together
+	_, err := engine.approve(newApproverRequest("approver-1"), "change-1")
+	assert.NilError(t, err)
+	errorMessages, err := engine.approveAll(newApproverRequest("approver-2"), []string{"change-1", "change-2"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(errorMessages), 0)
+
+	// change-1 is applied, change-2 waits for a second approval and is not canceled, change-3 is canceled
+	assert.Assert(t, store.GetApprovedChange("change-1") != nil)
+	assert.Assert(t, store.GetChange("change-2") != nil)
+	assert.Assert(t, store.GetChange("change-3") == nil)
+}
+
+func TestTelemetryChangeEngineApproveAllErrors(t *testing.T) {
+	testutil.SetupTestDB()
+	setApprovalPolicy(t, 1, false)
+	store := newMemoryProfileStore()
+	engine := &telemetryChangeEngine{store: store, approvalErrorPrefix: "ApprovingException:  "}
+	store.addChange("change-1", xwchange.Update, "deleted-profile", 1)
+	store.addChange("change-2", xwchange.Create, "profile-2", 2)
+
+	_, err := engine.approveAll(newApproverRequest("approver-1"), []string{"change-1", "unknown"})
+	assert.ErrorContains(t, err, "Change with unknown id does not exist")
+	assert.Assert(t, store.GetChange("change-1") != nil)
+
+	errorMessages, err := engine.approveAll(newApproverRequest("approver-1"), []string{"change-1", "change-2"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(errorMessages), 1)
+	assert.Assert(t, strings.HasPrefix(errorMessages["change-1"], "ApprovingException:  "), errorMessages["change-1"])
+	assert.Assert(t, store.ProfileExists("profile-2"))
+
+	// the author may not approve the change
+	errorMessages, err = engine.approveAll(newApproverRequest("author"), []string{"change-1"})
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(errorMessages["change-1"], "author is the author of the change"), errorMessages["change-1"])
+
+	// an engine which skips the unknown ids ignores them
+	engine.skipUnknownIds = true
+	errorMessages, err = engine.approveAll(newApproverRequest("approver-2"), []string{"unknown"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(errorMessages), 0)
+}
+
+func TestTelemetryChangeEngineRevertAllInApprovalOrder(t *testing.T) {
+	testutil.SetupTestDB()
+	store := newMemoryProfileStore()
+	engine := &telemetryChangeEngine{store: store}
+	store.profiles["profile-1"] = "profile-1"
+	store.approved["approved-2"] = &telemetryChange{ID: "approved-2", EntityID: "profile-2", Operation: xwchange.Delete, OldEntity: "profile-2", Updated: 2}
+	store.approved["approved-1"] = &telemetryChange{ID: "approved-1", EntityID: "profile-1", Operation: xwchange.Update, OldEntity: "profile-1", Updated: 1}
+	store.approved["approved-3"] = &telemetryChange{ID: "approved-3", EntityID: "profile-3", Operation: xwchange.Create, NewEntity: "profile-3", Updated: 3}
+
+	_, err := engine.revertAll(newApproverRequest("approver-1"), []string{"approved-1", "unknown"})
+	assert.ErrorContains(t, err, "ApprovedChange with unknown id does not exist")
+	assert.Equal(t, len(store.writes), 0)
+
+	errorMessages, err := engine.revertAll(newApproverRequest("approver-1"), []string{"approved-3", "approved-2", "approved-1"})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, store.writes, []string{"update profile-1", "create profile-2"})
+	// the profile created by approved-3 does not exist anymore
+	assert.Equal(t, len(errorMessages), 1)
+	assert.Assert(t, strings.Contains(errorMessages["approved-3"], "has been already deleted"), errorMessages["approved-3"])
+	assert.Assert(t, store.GetApprovedChange("approved-1") == nil)
+}
+
+func TestTelemetryChangeEngineCancel(t *testing.T) {
+	testutil.SetupTestDB()
+	setApprovalPolicy(t, 2, false)
+	store := newMemoryProfileStore()
+	engine := &telemetryChangeEngine{store: store}
+	store.addChange("change-1", xwchange.Create, "profile-1", 1)
+	_, err := engine.approve(newApproverRequest("approver-1"), "change-1")
+	assert.NilError(t, err)
+	assert.Equal(t, len(xchange.GetChangeApprovals("change-1")), 1)
+
+	assert.NilError(t, engine.cancel(newApproverRequest("approver-1"), "change-1"))
+	assert.Assert(t, store.GetChange("change-1") == nil)
+	assert.Equal(t, len(xchange.GetChangeApprovals("change-1")), 0)
+
+	assert.ErrorContains(t, engine.cancel(newApproverRequest("approver-1"), "change-1"), "Change with change-1 id does not exist")
+	assert.ErrorContains(t, engine.cancel(newApproverRequest("approver-1"), ""), "Id is blank")
+}
@@ -31,6 +31,7 @@ import (
 	xwcommon "xconfwebconfig/common"
 
 	xcommon "xconfadmin/common"
+	xshared "xconfadmin/shared"
 	xlogupload "xconfadmin/shared/logupload"
 	xwlogupload "xconfwebconfig/shared/logupload"
 
@@ -204,11 +205,16 @@ func CreateTelemetryProfileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_TELEMETRY_PROFILE, permTelemetryProfile.ID); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
 	savedProfile, err := CreatePermanentTelemetryProfile(r, permTelemetryProfile)
 	if err != nil {
 		xhttp.AdminError(w, err)
 		return
 	}
+	auth.AssignOwnership(r, xshared.OWNED_TELEMETRY_PROFILE, savedProfile.ID)
 
 	res, err := xhttp.ReturnJsonResponse(savedProfile, r)
 	if err != nil {
@@ -273,11 +279,16 @@ func UpdateTelemetryProfileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_TELEMETRY_PROFILE, permTelemetryProfile.ID); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
 	updatedProfile, err := UpdatePermanentTelemetryProfile(permTelemetryProfile)
 	if err != nil {
 		xhttp.AdminError(w, err)
 		return
 	}
+	auth.UpdateOwnership(r, xshared.OWNED_TELEMETRY_PROFILE, updatedProfile.ID)
 
 	res, err := xhttp.ReturnJsonResponse(updatedProfile, r)
 	if err != nil {
@@ -340,6 +351,10 @@ func DeleteTelemetryProfileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := auth.ValidateOwnership(r, xshared.OWNED_TELEMETRY_PROFILE, id); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
 	_, err = DeletePermanentTelemetryProfile(r, id)
 	if err != nil {
 		xhttp.AdminError(w, err)
@@ -549,6 +564,10 @@ func AddTelemetryProfileEntryHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.AdminError(w, xcommon.NewXconfError(http.StatusNotFound, fmt.Sprintf("Entity with id: %s does not exist", id)))
 		return
 	}
+	if err := auth.ValidateOwnership(r, xshared.OWNED_TELEMETRY_PROFILE, id); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
 	updatedTelemetryEntries := profile.TelemetryProfile
 	for _, telemetryElement := range telemetryElements {
 		updatedTelemetryEntries, err = AddPermanentTelemetryProfileElement(&telemetryElement, updatedTelemetryEntries)
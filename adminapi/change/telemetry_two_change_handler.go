@@ -36,7 +36,6 @@ import (
 	xwhttp "xconfwebconfig/http"
 
 	"github.com/gorilla/mux"
-	log "github.com/sirupsen/logrus"
 )
 
 func GetTwoProfileChangesHandler(w http.ResponseWriter, r *http.Request) {
@@ -54,7 +53,7 @@ func GetTwoProfileChangesHandler(w http.ResponseWriter, r *http.Request) {
 		return changes[j].Updated < changes[i].Updated
 	})
 
-	res, err := xhttp.ReturnJsonResponse(changes, r)
+	res, err := xhttp.ReturnJsonResponse(withPendingTelemetryTwoChanges(changes), r)
 	if err != nil {
 		xhttp.AdminError(w, err)
 		return
@@ -101,11 +100,26 @@ func ApproveTwoChangeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	effectiveAt, err := getEffectiveAt(r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	if effectiveAt > 0 {
+		writeScheduledApproval(w, r, changeId, effectiveAt)
+		return
+	}
+
 	approvedChange, err := ApproveTelemetryTwoChange(r, changeId)
 	if err != nil {
 		xhttp.AdminError(w, err)
 		return
 	}
+	if approvedChange == nil {
+		// the approval is recorded, the change waits for the quorum of its approval policy
+		xwhttp.WriteXconfResponse(w, http.StatusAccepted, nil)
+		return
+	}
 
 	res, err := xhttp.ReturnJsonResponse(approvedChange, r)
 	if err != nil {
@@ -160,18 +174,11 @@ func RevertTwoChangeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respEntity := RevertTelemetryTwoChange(r, approveId)
-	if respEntity.Error != nil {
-		xwhttp.WriteXconfResponse(w, respEntity.Status, []byte(respEntity.Error.Error()))
-		return
-	}
-
-	res, err := xhttp.ReturnJsonResponse(respEntity.Data, r)
-	if err != nil {
+	if err := RevertTelemetryTwoChange(r, approveId); err != nil {
 		xhttp.AdminError(w, err)
 		return
 	}
-	xwhttp.WriteResponseBytes(w, res, respEntity.Status, xhttp.ContextTypeHeader(r))
+	xwhttp.WriteXconfResponse(w, http.StatusOK, nil)
 }
 
 func RevertTwoChangesHandler(w http.ResponseWriter, r *http.Request) {
@@ -219,14 +226,11 @@ func CancelTwoChangeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := DeleteTelemetryTwoChange(changeId); err != nil {
+	if err := CancelTelemetryTwoChange(r, changeId); err != nil {
 		xhttp.AdminError(w, err)
 		return
 	}
 
-	userName := auth.GetUserNameOrUnknown(r)
-	log.Info(fmt.Sprintf("Change has been canceled by %s: %s", userName, changeId))
-
 	xwhttp.WriteResponseBytes(w, []byte{}, http.StatusOK, xhttp.ContextTypeHeader(r))
 }
 
@@ -244,15 +248,11 @@ func GetGroupedTwoChangesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	changes := xchange.GetAllTelemetryTwoChangeList()
-	changesPerPage := GeneratePageTelemetryTwoChanges(changes, pageNumber, pageSize)
-	if err != nil {
-		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, err.Error())
-		return
-	}
+	changes := pendingTelemetryTwoChangeItems(xchange.GetAllTelemetryTwoChangeList())
+	sortChangeItems(changes, true)
+	changesPerPage := changeItemsGeneratePage(changes, pageNumber, pageSize)
 
-	groupedChanges := GroupTelemetryTwoChanges(changesPerPage)
-	res, err := xhttp.ReturnJsonResponse(groupedChanges, r)
+	res, err := xhttp.ReturnJsonResponse(groupChangeItems(changesPerPage), r)
 	if err != nil {
 		xhttp.AdminError(w, err)
 		return
@@ -275,15 +275,11 @@ func GetGroupedApprovedTwoChangesHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	changes := xchange.GetAllApprovedTelemetryTwoChangeList()
-	changesPerPage := GeneratePageApprovedTelemetryTwoChanges(changes, pageNumber, pageSize)
-	if err != nil {
-		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, err.Error())
-		return
-	}
+	changes := approvedTelemetryTwoChangeItems(xchange.GetAllApprovedTelemetryTwoChangeList())
+	sortChangeItems(changes, true)
+	changesPerPage := changeItemsGeneratePage(changes, pageNumber, pageSize)
 
-	groupedChanges := GroupApprovedTelemetryTwoChanges(changesPerPage)
-	res, err := xhttp.ReturnJsonResponse(groupedChanges, r)
+	res, err := xhttp.ReturnJsonResponse(groupChangeItems(changesPerPage), r)
 	if err != nil {
 		xhttp.AdminError(w, err)
 		return
@@ -330,11 +326,12 @@ func GetApprovedTwoChangesFilteredHandler(w http.ResponseWriter, r *http.Request
 	xutil.AddQueryParamsToContextMap(r, contextMap)
 	contextMap[xwcommon.APPLICATION_TYPE] = applicationType
 
-	approvedChanges := GetApprovedTelemetryTwoChangesByContext(contextMap)
-	approvedChangesPerPage := GeneratePageApprovedTelemetryTwoChanges(approvedChanges, pageNumber, pageSize)
+	approvedChanges := approvedTelemetryTwoChangeItems(GetApprovedTelemetryTwoChangesByContext(contextMap))
+	sortChangeItems(approvedChanges, true)
+	approvedChangesPerPage := changeItemsGeneratePage(approvedChanges, pageNumber, pageSize)
 	changes := GetTelemetryTwoChangesByContext(contextMap)
 
-	res, err := xhttp.ReturnJsonResponse(approvedChangesPerPage, r)
+	res, err := xhttp.ReturnJsonResponse(changeItemValues(approvedChangesPerPage), r)
 	if err != nil {
 		xhttp.AdminError(w, err)
 		return
@@ -381,11 +378,12 @@ func GetTwoChangesFilteredHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	contextMap[xwcommon.APPLICATION_TYPE] = applicationType
 
-	changes := GetTelemetryTwoChangesByContext(contextMap)
-	changesPerPage := GeneratePageTelemetryTwoChanges(changes, pageNumber, pageSize)
+	changes := pendingTelemetryTwoChangeItems(GetTelemetryTwoChangesByContext(contextMap))
+	sortChangeItems(changes, true)
+	changesPerPage := changeItemsGeneratePage(changes, pageNumber, pageSize)
 	approvedChanges := GetApprovedTelemetryTwoChangesByContext(contextMap)
 
-	res, err := xhttp.ReturnJsonResponse(changesPerPage, r)
+	res, err := xhttp.ReturnJsonResponse(changeItemValues(changesPerPage), r)
 	if err != nil {
 		xhttp.AdminError(w, err)
 		return
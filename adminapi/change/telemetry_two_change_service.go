@@ -18,23 +18,15 @@
 package change
 
 import (
-	"fmt"
 	"net/http"
-	"sort"
-
-	xwcommon "xconfwebconfig/common"
 
 	"xconfadmin/adminapi/auth"
 	xcommon "xconfadmin/common"
 	xchange "xconfadmin/shared/change"
-	xutil "xconfadmin/util"
-	xwhttp "xconfwebconfig/http"
 	xwchange "xconfwebconfig/shared/change"
 	"xconfwebconfig/shared/logupload"
-	xwutil "xconfwebconfig/util"
 
 	"github.com/google/uuid"
-	log "github.com/sirupsen/logrus"
 )
 
 func GetTelemetryTwoChangeEntityIds() []string {
@@ -46,163 +38,35 @@ func GetTelemetryTwoChangeEntityIds() []string {
 	return ids
 }
 
-func GetTelemetryTwoChangesByEntityId(entityId string) []*xwchange.TelemetryTwoChange {
-	result := []*xwchange.TelemetryTwoChange{}
-	changes := xchange.GetAllTelemetryTwoChangeList()
-	for _, change := range changes {
-		if change.EntityID == entityId {
-			result = append(result, change)
-		}
-	}
-	return result
-}
-
-func GetTelemetryTwoChangesByIds(changeIds []string) []*xwchange.TelemetryTwoChange {
-	result := []*xwchange.TelemetryTwoChange{}
-	for _, changeId := range changeIds {
-		change := xchange.GetOneTelemetryTwoChange(changeId)
-		if change != nil {
-			result = append(result, change)
-		}
-	}
-
-	sort.Slice(result, func(i, j int) bool {
-		return result[i].Updated < result[j].Updated
-	})
-
-	return result
-}
-
 func GetTelemetryTwoChangesByContext(searchContext map[string]string) []*xwchange.TelemetryTwoChange {
 	filteredChanges := []*xwchange.TelemetryTwoChange{}
-	changes := xchange.GetAllTelemetryTwoChangeList()
-	for _, change := range changes {
-		if applicationType, ok := xutil.FindEntryInContext(searchContext, xwcommon.APPLICATION_TYPE, false); ok {
-			if change.ApplicationType != applicationType {
-				continue
-			}
-		}
-		if author, ok := xutil.FindEntryInContext(searchContext, xcommon.AUTHOR, false); ok {
-			if !xutil.ContainsIgnoreCase(change.Author, author) {
-				continue
-			}
-		}
-		if name, ok := xutil.FindEntryInContext(searchContext, xcommon.ENTITY, false); ok {
-			var profileName string
-			if change.NewEntity != nil {
-				profileName = change.NewEntity.Name
-			} else if change.OldEntity != nil {
-				profileName = change.OldEntity.Name
-			}
-			if !xutil.ContainsIgnoreCase(profileName, name) {
-				continue
-			}
-		}
-		filteredChanges = append(filteredChanges, change)
+	for _, change := range telemetryTwoChanges.find(searchContext) {
+		filteredChanges = append(filteredChanges, change.Change.(*xwchange.TelemetryTwoChange))
 	}
 	return filteredChanges
 }
 
 func GetApprovedTelemetryTwoChangesByContext(searchContext map[string]string) []*xwchange.ApprovedTelemetryTwoChange {
 	filteredChanges := []*xwchange.ApprovedTelemetryTwoChange{}
-	changes := xchange.GetAllApprovedTelemetryTwoChangeList()
-	for _, change := range changes {
-		if applicationType, ok := xutil.FindEntryInContext(searchContext, xwcommon.APPLICATION_TYPE, false); ok {
-			if change.ApplicationType != applicationType {
-				continue
-			}
-		}
-		if author, ok := xutil.FindEntryInContext(searchContext, xcommon.AUTHOR, false); ok {
-			if !xutil.ContainsIgnoreCase(change.Author, author) {
-				continue
-			}
-		}
-		if name, ok := xutil.FindEntryInContext(searchContext, xcommon.ENTITY, false); ok {
-			var profileName string
-			if change.NewEntity != nil {
-				profileName = change.NewEntity.Name
-			} else if change.OldEntity != nil {
-				profileName = change.OldEntity.Name
-			}
-			if !xutil.ContainsIgnoreCase(profileName, name) {
-				continue
-			}
-		}
-		filteredChanges = append(filteredChanges, change)
+	for _, change := range telemetryTwoChanges.findApproved(searchContext, xcommon.ENTITY) {
+		filteredChanges = append(filteredChanges, change.Change.(*xwchange.ApprovedTelemetryTwoChange))
 	}
 	return filteredChanges
 }
 
+// ApproveTelemetryTwoChange applies the change once the quorum of its approval policy is met,
+// nil is returned while the change waits for more approvals
 func ApproveTelemetryTwoChange(r *http.Request, changeId string) (*xwchange.ApprovedTelemetryTwoChange, error) {
-	change := xchange.GetOneTelemetryTwoChange(changeId)
-	if change == nil {
-		return nil, xcommon.NewXconfError(http.StatusNotFound, fmt.Sprintf("Entity with id  %s does not exist", changeId))
-	}
-
-	if change.Operation == xchange.Create {
-		if _, err := CreateTelemetryTwoProfile(r, change.NewEntity); err != nil {
-			return nil, err
-		}
-
-		approvedChange, err := SaveToApprovedApprovedTelemetryTwoChange(r, change)
-		if err != nil {
-			return nil, err
-		}
-
-		if err := DeleteTelemetryTwoChange(changeId); err != nil {
-			return nil, err
-		}
-
-		return approvedChange, nil
-	}
-
-	approvedChange, err := updateDeleteEntityTelemetryTwoChange(r, change)
-	if err != nil {
+	approvedChange, err := telemetryTwoChanges.approve(r, changeId)
+	if err != nil || approvedChange == nil {
 		return nil, err
 	}
-
-	return approvedChange, nil
+	return approvedChange.(*xwchange.ApprovedTelemetryTwoChange), nil
 }
 
+// ApproveTelemetryTwoChanges skips the unknown change ids
 func ApproveTelemetryTwoChanges(r *http.Request, changeIds []string) map[string]string {
-	errorMessages := make(map[string]string)
-	mergedUpdateChangesByEntityId := make(map[string]*logupload.TelemetryTwoProfile)
-	entityToByCancelChange := []string{}
-	changesToApprove := GetTelemetryTwoChangesByIds(changeIds)
-	for _, change := range changesToApprove {
-		var err error
-		switch {
-		case xchange.Create == change.Operation:
-			_, err = CreateTelemetryTwoProfile(r, change.NewEntity)
-		case xchange.Update == change.Operation:
-			var mergeResult *logupload.TelemetryTwoProfile
-			mergeResult, err = applyUpdateTelemetryTwoChange(mergedUpdateChangesByEntityId[change.EntityID], change)
-			if err == nil {
-				mergedUpdateChangesByEntityId[mergeResult.ID] = mergeResult
-				_, err = UpdateTelemetryTwoProfile(r, mergeResult)
-			}
-		case xchange.Delete == change.Operation:
-			err = DeleteTelemetryTwoProfile(r, change.OldEntity.ID)
-		}
-		if err == nil {
-			entityToByCancelChange = append(entityToByCancelChange, change.EntityID)
-			saveToApprovedAndCleanUpTelemetryTwoChange(r, change)
-		} else {
-			errorMessages[change.ID] = err.Error()
-		}
-	}
-
-	ids := make([]string, 0, len(errorMessages))
-	for k := range errorMessages {
-		ids = append(ids, k)
-	}
-	if err := cancelApprovedTelemetryTwoChangesByEntityId(r, entityToByCancelChange, ids); err != nil {
-		log.Errorf("Failed to cancel approved changes (%v): %v", entityToByCancelChange, err)
-	}
-	if len(errorMessages) > 0 {
-		log.Errorf("Approving Error: %v", errorMessages)
-	}
-
+	errorMessages, _ := telemetryTwoChanges.approveAll(r, changeIds)
 	return errorMessages
 }
 
@@ -220,128 +84,20 @@ func SaveToApprovedApprovedTelemetryTwoChange(r *http.Request, change *xwchange.
 	return approvedChange, nil
 }
 
-func DeleteTelemetryTwoChange(changeId string) error {
-	if err := beforeDeleteTelemetryTwoChange(changeId); err != nil {
-		return err
-	}
-	if err := xchange.DeleteOneTelemetryTwoChange(changeId); err != nil {
-		return xcommon.NewXconfError(http.StatusInternalServerError, err.Error())
-	}
-	return nil
-}
-
-func DeleteApprovedTelemetryTwoChange(changeId string) error {
-	if err := beforeDeleteApprovedTelemetryTwoChange(changeId); err != nil {
-		return err
-	}
-	if err := xchange.DeleteOneApprovedTelemetryTwoChange(changeId); err != nil {
-		return err
-	}
-	return nil
+func CancelTelemetryTwoChange(r *http.Request, changeId string) error {
+	return telemetryTwoChanges.cancel(r, changeId)
 }
 
-func RevertTelemetryTwoChange(r *http.Request, approvedId string) *xwhttp.ResponseEntity {
-	approvedChange := xchange.GetOneApprovedTelemetryTwoChange(approvedId)
-	if approvedChange == nil {
-		return xwhttp.NewResponseEntity(http.StatusNotFound, fmt.Errorf("ApprovedTelemetryTwoChange with %s id does not exist", approvedId), nil)
-	}
-
-	if approvedChange.Operation == xchange.Delete {
-		revertDeleteApprovedTelemetryTwoChange(r, approvedChange)
-	} else {
-		revertCreateOrUpdateApprovedTelemetryTwoChange(r, approvedChange)
-	}
-
-	userName := auth.GetUserNameOrUnknown(r)
-	log.Infof("Change has been reverted by %s: %v", userName, approvedChange)
-	return xwhttp.NewResponseEntity(http.StatusOK, nil, nil)
+func RevertTelemetryTwoChange(r *http.Request, approvedId string) error {
+	return telemetryTwoChanges.revert(r, approvedId)
 }
 
+// RevertTelemetryTwoChanges skips the unknown approved change ids
 func RevertTelemetryTwoChanges(r *http.Request, approvedIds []string) map[string]string {
-	errorMessages := make(map[string]string)
-	changesToRevert := make([]xwchange.ApprovedTelemetryTwoChange, 0, len(approvedIds))
-	for _, approvedId := range approvedIds {
-		approvedChange := xchange.GetOneApprovedTelemetryTwoChange(approvedId)
-		if approvedChange != nil {
-			changesToRevert = append(changesToRevert, *approvedChange)
-		}
-	}
-	sort.Slice(changesToRevert, func(i, j int) bool {
-		return changesToRevert[i].Updated < changesToRevert[j].Updated
-	})
-
-	for _, approvedChange := range changesToRevert {
-		res := RevertTelemetryTwoChange(r, approvedChange.ID)
-		if res.Status != http.StatusOK {
-			errorMessages[approvedChange.ID] = res.Error.Error()
-		}
-	}
-
-	if len(errorMessages) > 0 {
-		log.Errorf("Reverting Error: %v", errorMessages)
-	}
-
+	errorMessages, _ := telemetryTwoChanges.revertAll(r, approvedIds)
 	return errorMessages
 }
 
-func GeneratePageTelemetryTwoChanges(list []*xwchange.TelemetryTwoChange, page int, pageSize int) (result []*xwchange.TelemetryTwoChange) {
-	sort.Slice(list, func(i, j int) bool {
-		return list[j].Updated < list[i].Updated
-	})
-
-	length := len(list)
-	startIndex := page*pageSize - pageSize
-	if page < 1 || startIndex > length || pageSize < 1 {
-		return result
-	}
-	lastIndex := length
-	if page*pageSize < length {
-		lastIndex = page * pageSize
-	}
-	return list[startIndex:lastIndex]
-}
-
-func GeneratePageApprovedTelemetryTwoChanges(list []*xwchange.ApprovedTelemetryTwoChange, page int, pageSize int) (result []*xwchange.ApprovedTelemetryTwoChange) {
-	sort.Slice(list, func(i, j int) bool {
-		return list[j].Updated < list[i].Updated
-	})
-
-	length := len(list)
-	startIndex := page*pageSize - pageSize
-	if page < 1 || startIndex > length || pageSize < 1 {
-		return result
-	}
-	lastIndex := length
-	if page*pageSize < length {
-		lastIndex = page * pageSize
-	}
-	return list[startIndex:lastIndex]
-}
-
-func GroupTelemetryTwoChanges(changes []*xwchange.TelemetryTwoChange) map[string][]xwchange.TelemetryTwoChange {
-	groupedChanges := make(map[string][]xwchange.TelemetryTwoChange)
-	for _, change := range changes {
-		if _, found := groupedChanges[change.EntityID]; !found {
-			groupedChanges[change.EntityID] = make([]xwchange.TelemetryTwoChange, 0, 1)
-		}
-		groupedChanges[change.EntityID] = append(groupedChanges[change.EntityID], *change)
-	}
-
-	return groupedChanges
-}
-
-func GroupApprovedTelemetryTwoChanges(changes []*xwchange.ApprovedTelemetryTwoChange) map[string][]xwchange.ApprovedTelemetryTwoChange {
-	groupedChanges := make(map[string][]xwchange.ApprovedTelemetryTwoChange)
-	for _, change := range changes {
-		if _, found := groupedChanges[change.EntityID]; !found {
-			groupedChanges[change.EntityID] = make([]xwchange.ApprovedTelemetryTwoChange, 0, 1)
-		}
-		groupedChanges[change.EntityID] = append(groupedChanges[change.EntityID], *change)
-	}
-
-	return groupedChanges
-}
-
 func beforeSavingTelemetryTwoChange(r *http.Request, change *xwchange.TelemetryTwoChange) error {
 	if change.ID == "" {
 		change.ID = uuid.New().String()
@@ -379,59 +135,6 @@ func beforeSavingApprovedTelemetryTwoChange(r *http.Request, approvedChange *xwc
 	return nil
 }
 
-func beforeDeleteTelemetryTwoChange(id string) error {
-	if id == "" {
-		xcommon.NewXconfError(http.StatusBadRequest, "Id is blank")
-	}
-	if change := xchange.GetOneTelemetryTwoChange(id); change == nil {
-		xcommon.NewXconfError(http.StatusNotFound, fmt.Sprintf("TelemetryTwoChange with %s id does not exist", id))
-	}
-	return nil
-}
-
-func beforeDeleteApprovedTelemetryTwoChange(id string) error {
-	if id == "" {
-		xcommon.NewXconfError(http.StatusBadRequest, "Id is blank")
-	}
-	if change := xchange.GetOneApprovedTelemetryTwoChange(id); change == nil {
-		xcommon.NewXconfError(http.StatusNotFound, fmt.Sprintf("ApprovedTelemetryTwoChange with %s id does not exist", id))
-	}
-	return nil
-}
-
-func revertDeleteApprovedTelemetryTwoChange(r *http.Request, approvedChange *xwchange.ApprovedTelemetryTwoChange) error {
-	if approvedChange.OldEntity == nil {
-		return xcommon.NewXconfError(http.StatusInternalServerError, fmt.Sprintf("OldEntity is empty for ApprovedTelemetryTwoChange with id %s", approvedChange.ID))
-	}
-	if _, err := CreateTelemetryTwoProfile(r, approvedChange.OldEntity); err != nil {
-		return err
-	}
-
-	if err := DeleteApprovedTelemetryTwoChange(approvedChange.ID); err != nil {
-		return err
-	}
-	return nil
-}
-
-func revertCreateOrUpdateApprovedTelemetryTwoChange(r *http.Request, approvedChange *xwchange.ApprovedTelemetryTwoChange) error {
-	entityToRevert := logupload.GetOneTelemetryTwoProfile(approvedChange.EntityID)
-	if entityToRevert == nil {
-		return xcommon.NewXconfError(http.StatusNotFound, fmt.Sprintf("TelemetryTwoProfile with id %s does not exist", approvedChange.EntityID))
-	}
-
-	if approvedChange.Operation == xchange.Create {
-		if err := DeleteTelemetryTwoProfile(r, entityToRevert.ID); err != nil {
-			return err
-		}
-	} else {
-		if _, err := UpdateTelemetryTwoProfile(r, entityToRevert); err != nil {
-			return err
-		}
-	}
-
-	return DeleteApprovedTelemetryTwoChange(approvedChange.ID)
-}
-
 func buildToCreateTelemetryTwoChange(newEntity *logupload.TelemetryTwoProfile, applicationType string, userName string) *xwchange.TelemetryTwoChange {
 	change := xchange.NewEmptyTelemetryTwoChange()
 	change.ID = uuid.New().String()
@@ -469,34 +172,6 @@ func buildToDeleteTelemetryTwoChange(oldEntity *logupload.TelemetryTwoProfile, a
 	return change
 }
 
-func updateDeleteEntityTelemetryTwoChange(r *http.Request, change *xwchange.TelemetryTwoChange) (*xwchange.ApprovedTelemetryTwoChange, error) {
-	currentEntity := change.OldEntity
-	entityToChange := logupload.GetOneTelemetryTwoProfile(change.EntityID)
-	if entityToChange != nil {
-		if change.Operation == xchange.Delete {
-			if err := DeleteTelemetryTwoProfile(r, currentEntity.ID); err != nil {
-				return nil, err
-			}
-		} else {
-			if _, err := UpdateTelemetryTwoProfile(r, change.NewEntity); err != nil {
-				return nil, err
-			}
-		}
-		change.ApprovedUser = auth.GetUserNameOrUnknown(r)
-		approvedChange, err := SaveToApprovedApprovedTelemetryTwoChange(r, change)
-		if err != nil {
-			return nil, err
-		}
-		if err := xchange.DeleteOneTelemetryTwoChange(change.ID); err != nil {
-			return nil, err
-		}
-		return approvedChange, nil
-	} else {
-		msg := fmt.Sprintf("Change could not be approved, TelemetryTwoProfile have been already changed: TelemetryTwoChange %s - EntityID %s", change.ID, change.EntityID)
-		return nil, xcommon.NewXconfError(http.StatusConflict, msg)
-	}
-}
-
 func applyUpdateTelemetryTwoChange(mergeResult *logupload.TelemetryTwoProfile, change *xwchange.TelemetryTwoChange) (*logupload.TelemetryTwoProfile, error) {
 	if mergeResult == nil {
 		var err error
@@ -517,32 +192,112 @@ func applyUpdateTelemetryTwoChange(mergeResult *logupload.TelemetryTwoProfile, c
 	return mergeResult, nil
 }
 
-func saveToApprovedAndCleanUpTelemetryTwoChange(r *http.Request, change *xwchange.TelemetryTwoChange) error {
-	approvedChange, err := SaveToApprovedApprovedTelemetryTwoChange(r, change)
-	if err != nil {
-		return err
+// telemetryTwoProfileStore plugs the telemetry 2.0 profile changes into the change engine
+type telemetryTwoProfileStore struct{}
+
+func newTelemetryTwoChange(change *xwchange.TelemetryTwoChange) *telemetryChange {
+	result := &telemetryChange{
+		ID:              change.ID,
+		EntityID:        change.EntityID,
+		ApplicationType: change.ApplicationType,
+		Author:          change.Author,
+		Operation:       change.Operation,
+		Updated:         change.Updated,
+		Change:          change,
+	}
+	if change.NewEntity != nil {
+		result.NewEntity = change.NewEntity
+		result.EntityName = change.NewEntity.Name
+	}
+	if change.OldEntity != nil {
+		result.OldEntity = change.OldEntity
+		if result.EntityName == "" {
+			result.EntityName = change.OldEntity.Name
+		}
 	}
+	return result
+}
+
+func newApprovedTelemetryTwoChange(approvedChange *xwchange.ApprovedTelemetryTwoChange) *telemetryChange {
+	result := newTelemetryTwoChange((*xwchange.TelemetryTwoChange)(approvedChange))
+	result.Change = approvedChange
+	return result
+}
 
-	if err := DeleteTelemetryTwoChange(change.ID); err != nil {
-		return err
+func (telemetryTwoProfileStore) EntityType() string {
+	return xchange.TelemetryTwoProfile
+}
+
+func (telemetryTwoProfileStore) GetChange(id string) *telemetryChange {
+	if change := xchange.GetOneTelemetryTwoChange(id); change != nil {
+		return newTelemetryTwoChange(change)
 	}
-	userName := auth.GetUserNameOrUnknown(r)
-	log.Infof("Change approved by %s: %v", userName, approvedChange)
 	return nil
 }
 
-func cancelApprovedTelemetryTwoChangesByEntityId(r *http.Request, entityIdsToByCancelChanges []string, changeIdsToBeExcluded []string) error {
-	for _, entityId := range entityIdsToByCancelChanges {
-		changes := GetTelemetryTwoChangesByEntityId(entityId)
-		for _, change := range changes {
-			if !xwutil.Contains(changeIdsToBeExcluded, change.ID) {
-				if err := DeleteTelemetryTwoChange(change.ID); err != nil {
-					return err
-				}
-				userName := auth.GetUserNameOrUnknown(r)
-				log.Infof("Automatically canceled change by %s: %v", userName, change)
-			}
-		}
+func (telemetryTwoProfileStore) GetChanges() []*telemetryChange {
+	changes := []*telemetryChange{}
+	for _, change := range xchange.GetAllTelemetryTwoChangeList() {
+		changes = append(changes, newTelemetryTwoChange(change))
+	}
+	return changes
+}
+
+func (telemetryTwoProfileStore) DeleteChange(id string) error {
+	return xchange.DeleteOneTelemetryTwoChange(id)
+}
+
+func (telemetryTwoProfileStore) SaveApprovedChange(r *http.Request, change *telemetryChange, approvedUser string) (interface{}, error) {
+	pendingChange := change.Change.(*xwchange.TelemetryTwoChange)
+	pendingChange.ApprovedUser = approvedUser
+	return SaveToApprovedApprovedTelemetryTwoChange(r, pendingChange)
+}
+
+func (telemetryTwoProfileStore) GetApprovedChange(id string) *telemetryChange {
+	if approvedChange := xchange.GetOneApprovedTelemetryTwoChange(id); approvedChange != nil {
+		return newApprovedTelemetryTwoChange(approvedChange)
 	}
 	return nil
 }
+
+func (telemetryTwoProfileStore) GetApprovedChanges() []*telemetryChange {
+	changes := []*telemetryChange{}
+	for _, approvedChange := range xchange.GetAllApprovedTelemetryTwoChangeList() {
+		changes = append(changes, newApprovedTelemetryTwoChange(approvedChange))
+	}
+	return changes
+}
+
+func (telemetryTwoProfileStore) DeleteApprovedChange(id string) error {
+	return xchange.DeleteOneApprovedTelemetryTwoChange(id)
+}
+
+func (telemetryTwoProfileStore) ProfileExists(id string) bool {
+	return logupload.GetOneTelemetryTwoProfile(id) != nil
+}
+
+func (telemetryTwoProfileStore) CreateProfile(r *http.Request, profile interface{}) error {
+	_, err := CreateTelemetryTwoProfile(r, profile.(*logupload.TelemetryTwoProfile))
+	return err
+}
+
+func (telemetryTwoProfileStore) UpdateProfile(r *http.Request, profile interface{}) error {
+	_, err := UpdateTelemetryTwoProfile(r, profile.(*logupload.TelemetryTwoProfile))
+	return err
+}
+
+func (telemetryTwoProfileStore) DeleteProfile(r *http.Request, id string) error {
+	return DeleteTelemetryTwoProfile(r, id)
+}
+
+func (telemetryTwoProfileStore) MergeUpdate(mergeResult interface{}, change *telemetryChange) (interface{}, error) {
+	var merged *logupload.TelemetryTwoProfile
+	if mergeResult != nil {
+		merged = mergeResult.(*logupload.TelemetryTwoProfile)
+	}
+	result, err := applyUpdateTelemetryTwoChange(merged, change.Change.(*xwchange.TelemetryTwoChange))
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
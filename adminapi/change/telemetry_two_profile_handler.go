@@ -111,11 +111,16 @@ func CreateTelemetryTwoProfileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_TELEMETRY_TWO_PROFILE, telemetryTwoProfile.ID); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
 	createdProfile, err := CreateTelemetryTwoProfile(r, telemetryTwoProfile)
 	if err != nil {
 		xhttp.AdminError(w, err)
 		return
 	}
+	auth.AssignOwnership(r, xshared.OWNED_TELEMETRY_TWO_PROFILE, createdProfile.ID)
 
 	res, err := xhttp.ReturnJsonResponse(createdProfile, r)
 	if err != nil {
@@ -169,11 +174,16 @@ func UpdateTelemetryTwoProfileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_TELEMETRY_TWO_PROFILE, telemetryTwoProfile.ID); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
 	updatedProfile, err := UpdateTelemetryTwoProfile(r, telemetryTwoProfile)
 	if err != nil {
 		xhttp.AdminError(w, err)
 		return
 	}
+	auth.UpdateOwnership(r, xshared.OWNED_TELEMETRY_TWO_PROFILE, updatedProfile.ID)
 
 	res, err := xhttp.ReturnJsonResponse(updatedProfile, r)
 	if err != nil {
@@ -225,6 +235,10 @@ func DeleteTelemetryTwoProfileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := auth.ValidateOwnership(r, xshared.OWNED_TELEMETRY_TWO_PROFILE, id); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
 	err := DeleteTelemetryTwoProfile(r, id)
 	if err != nil {
 		xhttp.AdminError(w, err)
@@ -66,6 +66,9 @@ func WriteCreateChangeTelemetryTwoProfile(r *http.Request, profile *xwlogupload.
 	if err := beforeCreatingTelemetryTwoProfile(profile, applicationType); err != nil {
 		return nil, err
 	}
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_TELEMETRY_TWO_PROFILE, profile.ID); err != nil {
+		return nil, err
+	}
 
 	if err := beforeSavingTelemetryTwoProfile(profile); err != nil {
 		return nil, err
@@ -82,6 +85,7 @@ func WriteCreateChangeTelemetryTwoProfile(r *http.Request, profile *xwlogupload.
 	if err := xchange.CreateOneTelemetryTwoChange(change); err != nil {
 		return nil, err
 	}
+	auth.AssignOwnership(r, xshared.OWNED_TELEMETRY_TWO_PROFILE, profile.ID)
 	return change, nil
 }
 
@@ -93,6 +97,9 @@ func WriteUpdateChangeOrSaveTelemetryTwoProfile(r *http.Request, newProfile *xwl
 	if err := beforeUpdatingTelemetryTwoProfile(newProfile, applicationType); err != nil {
 		return nil, err
 	}
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_TELEMETRY_TWO_PROFILE, newProfile.ID); err != nil {
+		return nil, err
+	}
 	if err := beforeSavingTelemetryTwoProfile(newProfile); err != nil {
 		return nil, err
 	}
@@ -117,6 +124,7 @@ func WriteUpdateChangeOrSaveTelemetryTwoProfile(r *http.Request, newProfile *xwl
 		}
 
 	}
+	auth.UpdateOwnership(r, xshared.OWNED_TELEMETRY_TWO_PROFILE, newProfile.ID)
 	return change, nil
 }
 
@@ -129,6 +137,9 @@ func WriteDeleteChangeTelemetryTwoProfile(r *http.Request, id string) (*xwchange
 	if err != nil {
 		return nil, err
 	}
+	if err := auth.ValidateOwnership(r, xshared.OWNED_TELEMETRY_TWO_PROFILE, id); err != nil {
+		return nil, err
+	}
 	if _, err := auth.CanWrite(r, auth.CHANGE_ENTITY); err != nil {
 		return nil, err
 	}
@@ -143,6 +154,7 @@ func WriteDeleteChangeTelemetryTwoProfile(r *http.Request, id string) (*xwchange
 func GetTelemetryTwoProfilesByContext(searchContext map[string]string) []*xwlogupload.TelemetryTwoProfile {
 	filteredProfiles := []*xwlogupload.TelemetryTwoProfile{}
 	profiles := xlogupload.GetAllTelemetryTwoProfileList()
+	honoredByOwner := auth.NewOwnerFilter(searchContext, xshared.OWNED_TELEMETRY_TWO_PROFILE)
 	for _, profile := range profiles {
 		if applicationType, ok := xutil.FindEntryInContext(searchContext, xwcommon.APPLICATION_TYPE, false); ok {
 			if profile.ApplicationType != applicationType {
@@ -154,6 +166,9 @@ func GetTelemetryTwoProfilesByContext(searchContext map[string]string) []*xwlogu
 				continue
 			}
 		}
+		if !honoredByOwner(profile.ID) {
+			continue
+		}
 		filteredProfiles = append(filteredProfiles, profile)
 	}
 	return filteredProfiles
@@ -221,6 +236,7 @@ func DeleteTelemetryTwoProfile(r *http.Request, id string) error {
 	if err := xlogupload.DeleteTelemetryTwoProfile(id); err != nil {
 		return err
 	}
+	auth.RemoveOwnership(xshared.OWNED_TELEMETRY_TWO_PROFILE, id)
 	return nil
 }
 
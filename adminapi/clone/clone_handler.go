@@ -0,0 +1,63 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package clone
+
+import (
+	"encoding/json"
+	"net/http"
+
+	xhttp "xconfadmin/http"
+	xwhttp "xconfwebconfig/http"
+)
+
+// CloneEntitiesHandler copies the entities and their dependencies into the target applicationType,
+// it returns 409 with the report and creates nothing if an entity can't be copied
+func CloneEntitiesHandler(w http.ResponseWriter, r *http.Request) {
+	xw, ok := w.(*xwhttp.XResponseWriter)
+	if !ok {
+		xhttp.WriteAdminErrorResponse(w, http.StatusInternalServerError, "responsewriter cast error")
+		return
+	}
+	var request CloneRequest
+	if err := json.Unmarshal([]byte(xw.Body()), &request); err != nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, "Unable to extract clone request from json file:"+err.Error())
+		return
+	}
+
+	dryRun := xhttp.NewDryRun(r)
+	result, err := CloneEntities(r, &request, dryRun)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	if dryRun != nil {
+		dryRun.Result = result
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
+	res, err := xhttp.ReturnJsonResponse(result, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	status := http.StatusCreated
+	if result.HasErrors() {
+		status = http.StatusConflict
+	}
+	xwhttp.WriteXconfResponse(w, status, res)
+}
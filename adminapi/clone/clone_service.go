@@ -0,0 +1,842 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package clone
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"xconfadmin/adminapi/auth"
+	"xconfadmin/adminapi/change"
+	"xconfadmin/adminapi/dcm"
+	"xconfadmin/adminapi/queries"
+	"xconfadmin/adminapi/reference"
+	"xconfadmin/adminapi/telemetry"
+	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
+	xshared "xconfadmin/shared"
+	xchange "xconfadmin/shared/change"
+	xlogupload "xconfadmin/shared/logupload"
+	xrfc "xconfadmin/shared/rfc"
+	xwcommon "xconfwebconfig/common"
+	ds "xconfwebconfig/db"
+	xwhttp "xconfwebconfig/http"
+	coreef "xconfwebconfig/shared/estbfirmware"
+	corefw "xconfwebconfig/shared/firmware"
+	"xconfwebconfig/shared/logupload"
+	"xconfwebconfig/shared/rfc"
+	"xconfwebconfig/util"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// entity types of the copied dependencies which are not listed by the references
+const (
+	UPLOAD_REPOSITORY   = "uploadRepository"
+	DEVICE_SETTINGS     = "deviceSettings"
+	LOG_UPLOAD_SETTINGS = "logUploadSettings"
+	VOD_SETTINGS        = "vodSettings"
+)
+
+// statuses of the cloned entities
+const (
+	CLONE_CREATED     = "CREATED"
+	CLONE_REUSED      = "REUSED"
+	CLONE_SHARED      = "SHARED"
+	CLONE_CONFLICT    = "CONFLICT"
+	CLONE_FAILED      = "FAILED"
+	CLONE_ROLLED_BACK = "ROLLED_BACK"
+	// a dry run could not validate the copy, it is validated when the clone is applied
+	CLONE_NOT_VALIDATED = "NOT_VALIDATED"
+)
+
+// permission entities needed to clone an entity type, the feature rules copy features which are dcm entities
+var clonePermissions = map[string][]string{
+	reference.FIRMWARE_RULE:  {auth.FIRMWARE_ENTITY},
+	reference.FEATURE_RULE:   {auth.FIRMWARE_ENTITY, auth.DCM_ENTITY},
+	reference.DCM_RULE:       {auth.DCM_ENTITY},
+	reference.TELEMETRY_RULE: {auth.TELEMETRY_ENTITY},
+}
+
+// entity types which can be cloned, the others are copied as their dependencies
+var CloneableEntityTypes = []string{
+	reference.FIRMWARE_RULE,
+	reference.FEATURE_RULE,
+	reference.DCM_RULE,
+	reference.TELEMETRY_RULE,
+}
+
+// entity types which are not scoped to an application type, the copies keep referencing them
+var sharedEntityTypes = []string{
+	reference.MODEL,
+	reference.ENVIRONMENT,
+	reference.NAMESPACED_LIST,
+}
+
+type CloneEntity struct {
+	EntityType string `json:"entityType"`
+	ID         string `json:"id"`
+}
+
+// CloneRequest lists the entities to copy into the target applicationType, NameSuffix is appended to the names
+// of the copied firmware configs and telemetry profiles as their names are unique across the application types
+type CloneRequest struct {
+	TargetApplicationType string        `json:"targetApplicationType"`
+	NameSuffix            string        `json:"nameSuffix,omitempty"`
+	Entities              []CloneEntity `json:"entities"`
+}
+
+// ClonedEntity is an entity of the source applicationType and its counterpart in the target one
+type ClonedEntity struct {
+	EntityType string `json:"entityType"`
+	SourceID   string `json:"sourceId"`
+	TargetID   string `json:"targetId,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Status     string `json:"status"`
+	Message    string `json:"message,omitempty"`
+
+	// audited when the entity is created
+	auditEntityType string
+	tableName       string
+	entity          interface{}
+}
+
+type CloneResult struct {
+	SourceApplicationType string          `json:"sourceApplicationType"`
+	TargetApplicationType string          `json:"targetApplicationType"`
+	Entities              []*ClonedEntity `json:"entities"`
+}
+
+// HasErrors returns true if an entity could not be copied
+func (r *CloneResult) HasErrors() bool {
+	for _, entity := range r.Entities {
+		if entity.Status == CLONE_CONFLICT || entity.Status == CLONE_FAILED || entity.Status == CLONE_ROLLED_BACK {
+			return true
+		}
+	}
+	return false
+}
+
+// cloner copies the entities of one request, with a dry run it records the creates instead of saving them
+type cloner struct {
+	r          *http.Request
+	source     string
+	target     string
+	nameSuffix string
+	dryRun     *xhttp.DryRun
+	result     *CloneResult
+	entities   map[string]*ClonedEntity
+	// set when the current root references a dependency copied by a dry run, which the validators can't find
+	copiedDependency bool
+	rollbacks        []func()
+}
+
+// CloneEntities copies the entities of the request's applicationType and their dependencies into the target applicationType.
+// The copies are validated by a dry run first and nothing is created if an entity can't be copied,
+// the entities created before a failure are deleted again
+func CloneEntities(r *http.Request, request *CloneRequest, dryRun *xhttp.DryRun) (*CloneResult, error) {
+	source, err := validateCloneRequest(r, request)
+	if err != nil {
+		return nil, err
+	}
+	preview := dryRun
+	if preview == nil {
+		preview = &xhttp.DryRun{DryRun: true, Changes: []xhttp.DryRunChange{}}
+	}
+	result := newCloner(r, source, request, preview).cloneAll(request.Entities)
+	if dryRun != nil || result.HasErrors() {
+		return result, nil
+	}
+
+	c := newCloner(r, source, request, nil)
+	result = c.cloneAll(request.Entities)
+	if result.HasErrors() {
+		c.rollback()
+		return result, nil
+	}
+	c.audit()
+	return result, nil
+}
+
+// validateCloneRequest checks the request and the permissions, it returns the source applicationType
+func validateCloneRequest(r *http.Request, request *CloneRequest) (string, error) {
+	if request == nil || len(request.Entities) == 0 {
+		return "", xcommon.NewXconfError(http.StatusBadRequest, "Entities to clone should be specified")
+	}
+	if util.IsBlank(request.TargetApplicationType) {
+		return "", xcommon.NewXconfError(http.StatusBadRequest, "TargetApplicationType is empty")
+	}
+	if err := xshared.ValidateApplicationType(request.TargetApplicationType); err != nil {
+		return "", err
+	}
+	source := ""
+	for i, entity := range request.Entities {
+		entityType, ok := normalizeCloneableEntityType(entity.EntityType)
+		if !ok {
+			return "", xcommon.NewXconfError(http.StatusBadRequest, fmt.Sprintf("EntityType must be one of %s", strings.Join(CloneableEntityTypes, ", ")))
+		}
+		if util.IsBlank(entity.ID) {
+			return "", xcommon.NewXconfError(http.StatusBadRequest, "Id of the "+entityType+" to clone is empty")
+		}
+		request.Entities[i].EntityType = entityType
+		for _, permission := range clonePermissions[entityType] {
+			applicationType, err := auth.CanRead(r, permission)
+			if err != nil {
+				return "", err
+			}
+			if err := auth.CanWriteApplicationType(r, permission, request.TargetApplicationType); err != nil {
+				return "", err
+			}
+			source = applicationType
+		}
+	}
+	if xshared.ApplicationTypeEquals(source, request.TargetApplicationType) {
+		return "", xcommon.NewXconfError(http.StatusBadRequest, "TargetApplicationType must differ from the current ApplicationType "+source)
+	}
+	for _, entity := range request.Entities {
+		if applicationType, ok := getCloneableApplicationType(entity.EntityType, entity.ID); ok && !xshared.ApplicationTypeEquals(applicationType, source) {
+			return "", xcommon.NewXconfError(http.StatusBadRequest, fmt.Sprintf("%s %s belongs to ApplicationType %s, all the entities to clone must belong to %s", entity.EntityType, entity.ID, applicationType, source))
+		}
+	}
+	return source, nil
+}
+
+// getCloneableApplicationType returns the application type of an entity to clone, false if it does not exist
+func getCloneableApplicationType(entityType string, id string) (string, bool) {
+	switch entityType {
+	case reference.FIRMWARE_RULE:
+		if rule, err := corefw.GetFirmwareRuleOneDB(id); err == nil && rule != nil {
+			return rule.ApplicationType, true
+		}
+	case reference.FEATURE_RULE:
+		if rule := queries.GetOne(id); rule != nil {
+			return rule.ApplicationType, true
+		}
+	case reference.DCM_RULE:
+		if rule := dcm.GetDcmFormula(id); rule != nil {
+			return rule.ApplicationType, true
+		}
+	case reference.TELEMETRY_RULE:
+		if rule := xlogupload.GetOneTelemetryRule(id); rule != nil {
+			return rule.ApplicationType, true
+		}
+	}
+	return "", false
+}
+
+func normalizeCloneableEntityType(entityType string) (string, bool) {
+	for _, t := range CloneableEntityTypes {
+		if strings.EqualFold(t, entityType) {
+			return t, true
+		}
+	}
+	return "", false
+}
+
+func newCloner(r *http.Request, source string, request *CloneRequest, dryRun *xhttp.DryRun) *cloner {
+	return &cloner{
+		r:          r,
+		source:     source,
+		target:     request.TargetApplicationType,
+		nameSuffix: request.NameSuffix,
+		dryRun:     dryRun,
+		result: &CloneResult{
+			SourceApplicationType: source,
+			TargetApplicationType: request.TargetApplicationType,
+			Entities:              []*ClonedEntity{},
+		},
+		entities: map[string]*ClonedEntity{},
+	}
+}
+
+func (c *cloner) cloneAll(entities []CloneEntity) *CloneResult {
+	for _, entity := range entities {
+		if c.get(entity.EntityType, entity.ID) != nil {
+			continue
+		}
+		c.copiedDependency = false
+		switch entity.EntityType {
+		case reference.FIRMWARE_RULE:
+			c.cloneFirmwareRule(entity.ID)
+		case reference.FEATURE_RULE:
+			c.cloneFeatureRule(entity.ID)
+		case reference.DCM_RULE:
+			c.cloneDcmRule(entity.ID)
+		case reference.TELEMETRY_RULE:
+			c.cloneTelemetryRule(entity.ID)
+		}
+	}
+	return c.result
+}
+
+// add appends the entity to the result and indexes it by type and source id,
+// get finds it there so an entity referenced by several roots is copied only once
+func (c *cloner) add(entity *ClonedEntity) *ClonedEntity {
+	c.entities[entity.EntityType+"/"+entity.SourceID] = entity
+	c.result.Entities = append(c.result.Entities, entity)
+	return entity
+}
+
+func (c *cloner) get(entityType string, sourceId string) *ClonedEntity {
+	return c.entities[entityType+"/"+sourceId]
+}
+
+// fail reports the entity as not copied, an error with status 409 is a conflict with the target applicationType
+func (c *cloner) fail(entity *ClonedEntity, err error) *ClonedEntity {
+	entity.Status = CLONE_FAILED
+	if xcommon.GetXconfErrorStatusCode(err) == http.StatusConflict {
+		entity.Status = CLONE_CONFLICT
+	}
+	entity.Message = err.Error()
+	if c.get(entity.EntityType, entity.SourceID) == nil {
+		c.add(entity)
+	}
+	return entity
+}
+
+func responseError(respEntity *xwhttp.ResponseEntity) error {
+	if respEntity.Error == nil {
+		return nil
+	}
+	return xcommon.NewXconfError(respEntity.Status, respEntity.Error.Error())
+}
+
+// created reports the copy, rollback deletes it if a later entity can't be created
+func (c *cloner) created(entity *ClonedEntity, auditEntityType string, tableName string, copied interface{}, rollback func()) *ClonedEntity {
+	entity.Status = CLONE_CREATED
+	entity.auditEntityType = auditEntityType
+	entity.tableName = tableName
+	entity.entity = copied
+	if c.dryRun == nil && rollback != nil {
+		c.rollbacks = append(c.rollbacks, rollback)
+	}
+	if c.get(entity.EntityType, entity.SourceID) == nil {
+		c.add(entity)
+	}
+	return entity
+}
+
+// notValidated reports a root the dry run could not validate, the validators look its dependencies up
+// in the target applicationType and the dry run did not create them
+func (c *cloner) notValidated(entity *ClonedEntity) {
+	entity.Status = CLONE_NOT_VALIDATED
+	entity.Message = "Not validated by the dry run, it references dependencies which don't exist in the target applicationType yet"
+	c.add(entity)
+}
+
+// isApprovalRequired reports the root as failed if its changes must be approved in the target applicationType
+func (c *cloner) isApprovalRequired(entity *ClonedEntity, changeEntityType string) bool {
+	if !xchange.IsApprovalRequired(changeEntityType, c.target) {
+		return false
+	}
+	c.fail(entity, xcommon.NewXconfError(http.StatusConflict, fmt.Sprintf("Changes of %s require approval in ApplicationType %s", entity.EntityType, c.target)))
+	return true
+}
+
+// hasFailedDependency reports the root as failed if one of its dependencies could not be copied
+func (c *cloner) hasFailedDependency(entity *ClonedEntity, dependencies []*ClonedEntity) bool {
+	for _, dependency := range dependencies {
+		if dependency != nil && (dependency.Status == CLONE_FAILED || dependency.Status == CLONE_CONFLICT) {
+			c.fail(entity, xcommon.NewXconfError(http.StatusBadRequest, fmt.Sprintf("%s %s could not be cloned", dependency.EntityType, dependency.SourceID)))
+			return true
+		}
+	}
+	return false
+}
+
+// shareReferences reports the models, environments and namespaced lists the entity references as shared
+func (c *cloner) shareReferences(entityType string, id string) {
+	references, err := reference.GetEntityReferences(entityType, id, c.source)
+	if err != nil {
+		log.Warnf("unable to get the references of %s %s: %s", entityType, id, err.Error())
+		return
+	}
+	for _, ref := range references.Outbound {
+		if util.Contains(sharedEntityTypes, ref.EntityType) && c.get(ref.EntityType, ref.EntityID) == nil {
+			c.add(&ClonedEntity{EntityType: ref.EntityType, SourceID: ref.EntityID, TargetID: ref.EntityID, Name: ref.EntityName, Status: CLONE_SHARED})
+		}
+	}
+}
+
+func (c *cloner) notFound(entityType string, id string) *ClonedEntity {
+	entity := &ClonedEntity{EntityType: entityType, SourceID: id}
+	return c.fail(entity, xcommon.NewXconfError(http.StatusNotFound, fmt.Sprintf("%s with id: %s does not exist in ApplicationType %s", entityType, id, c.source)))
+}
+
+func (c *cloner) cloneFirmwareRule(id string) {
+	rule, err := corefw.GetFirmwareRuleOneDB(id)
+	if err != nil || !xshared.ApplicationTypeEquals(rule.ApplicationType, c.source) {
+		c.notFound(reference.FIRMWARE_RULE, id)
+		return
+	}
+	entity := &ClonedEntity{EntityType: reference.FIRMWARE_RULE, SourceID: id, Name: rule.Name}
+	changeEntityType := xchange.FIRMWARE_RULE
+	if rule.Type == corefw.ENV_MODEL_RULE {
+		changeEntityType = xchange.PERCENTAGE_BEAN
+	}
+	if c.isApprovalRequired(entity, changeEntityType) {
+		return
+	}
+	copied, err := rule.Clone()
+	if err != nil {
+		c.fail(entity, err)
+		return
+	}
+
+	dependencies := []*ClonedEntity{c.shareFirmwareRuleTemplate(rule.Type)}
+	c.shareReferences(reference.FIRMWARE_RULE, id)
+	if action := copied.ApplicableAction; action != nil {
+		var dependency *ClonedEntity
+		if action.ConfigId != "" {
+			dependency, action.ConfigId = c.cloneFirmwareConfig(action.ConfigId)
+			dependencies = append(dependencies, dependency)
+		}
+		for i := range action.ConfigEntries {
+			dependency, action.ConfigEntries[i].ConfigId = c.cloneFirmwareConfig(action.ConfigEntries[i].ConfigId)
+			dependencies = append(dependencies, dependency)
+		}
+		if action.IntermediateVersion != "" {
+			dependency, action.IntermediateVersion = c.cloneFirmwareConfig(action.IntermediateVersion)
+			dependencies = append(dependencies, dependency)
+		}
+		for _, version := range action.FirmwareVersions {
+			dependencies = append(dependencies, c.cloneFirmwareVersion(version))
+		}
+	}
+	if c.hasFailedDependency(entity, dependencies) {
+		return
+	}
+
+	copied.ID = uuid.New().String()
+	copied.ApplicationType = c.target
+	entity.TargetID = copied.ID
+	if c.dryRun != nil && c.copiedDependency {
+		c.notValidated(entity)
+		return
+	}
+	if err := queries.CreateFirmwareRule(copied, c.target, c.dryRun); err != nil {
+		c.fail(entity, err)
+		return
+	}
+	if c.dryRun == nil {
+		auth.AssignOwnership(c.r, xshared.OWNED_FIRMWARE_RULE, copied.ID)
+	}
+	c.created(entity, "Firmware-Rules", ds.TABLE_FIRMWARE_RULE, copied, func() {
+		if err := corefw.DeleteOneFirmwareRule(copied.ID); err != nil {
+			log.Errorf("unable to roll back cloned firmware rule %s: %s", copied.ID, err.Error())
+		}
+		auth.RemoveOwnership(xshared.OWNED_FIRMWARE_RULE, copied.ID)
+	})
+}
+
+// shareFirmwareRuleTemplate reports the template of the rule as shared, the copy references the same template
+func (c *cloner) shareFirmwareRuleTemplate(ruleType string) *ClonedEntity {
+	if entity := c.get(reference.FIRMWARE_RULE_TEMPLATE, ruleType); entity != nil {
+		return entity
+	}
+	if _, err := corefw.GetFirmwareRuleTemplateOneDB(ruleType); err != nil {
+		return c.notFound(reference.FIRMWARE_RULE_TEMPLATE, ruleType)
+	}
+	return c.add(&ClonedEntity{EntityType: reference.FIRMWARE_RULE_TEMPLATE, SourceID: ruleType, TargetID: ruleType, Name: ruleType, Status: CLONE_SHARED})
+}
+
+// cloneFirmwareVersion copies the config of the version unless the target applicationType has a config of it
+func (c *cloner) cloneFirmwareVersion(version string) *ClonedEntity {
+	if queries.GetFirmwareConfigId(version, c.target) != "" {
+		return nil
+	}
+	configId := queries.GetFirmwareConfigId(version, c.source)
+	if configId == "" {
+		return c.notFound(reference.FIRMWARE_CONFIG, version)
+	}
+	entity, _ := c.cloneFirmwareConfig(configId)
+	return entity
+}
+
+// cloneFirmwareConfig returns the cloned entity and the id the copied rule must reference,
+// an existing target config of the same firmware version is reused
+func (c *cloner) cloneFirmwareConfig(id string) (*ClonedEntity, string) {
+	if entity := c.get(reference.FIRMWARE_CONFIG, id); entity != nil {
+		c.copiedDependency = c.copiedDependency || entity.Status == CLONE_CREATED
+		return entity, entity.TargetID
+	}
+	config, err := coreef.GetFirmwareConfigOneDB(id)
+	if err != nil || config == nil || !xshared.ApplicationTypeEquals(config.ApplicationType, c.source) {
+		return c.notFound(reference.FIRMWARE_CONFIG, id), id
+	}
+	entity := &ClonedEntity{EntityType: reference.FIRMWARE_CONFIG, SourceID: id, Name: config.Description}
+	if targetId := queries.GetFirmwareConfigId(config.FirmwareVersion, c.target); targetId != "" {
+		entity.TargetID = targetId
+		entity.Status = CLONE_REUSED
+		return c.add(entity), targetId
+	}
+
+	c.shareReferences(reference.FIRMWARE_CONFIG, id)
+	copied, err := config.Clone()
+	if err != nil {
+		return c.fail(entity, err), id
+	}
+	copied.ID = uuid.New().String()
+	copied.ApplicationType = c.target
+	copied.Description += c.nameSuffix
+	entity.TargetID = copied.ID
+	entity.Name = copied.Description
+	if err := responseError(queries.CreateFirmwareConfigAS(copied, c.target, true, c.dryRun)); err != nil {
+		return c.fail(entity, err), id
+	}
+	c.copiedDependency = true
+	return c.created(entity, "Firmware-Configs", ds.TABLE_FIRMWARE_CONFIG, copied, func() {
+		// the copy is only referenced by the copied rules, which are deleted first, and the usage check
+		// could still see them in the rule list cache
+		if err := coreef.DeleteOneFirmwareConfig(copied.ID); err != nil {
+			log.Errorf("unable to roll back cloned firmware config %s: %s", copied.ID, err.Error())
+		}
+	}), copied.ID
+}
+
+func (c *cloner) cloneFeatureRule(id string) {
+	rule := queries.GetOne(id)
+	if rule == nil || !xshared.ApplicationTypeEquals(rule.ApplicationType, c.source) {
+		c.notFound(reference.FEATURE_RULE, id)
+		return
+	}
+	entity := &ClonedEntity{EntityType: reference.FEATURE_RULE, SourceID: id, Name: rule.Name}
+	if c.isApprovalRequired(entity, xchange.FEATURE_RULE) {
+		return
+	}
+	copied, err := rule.Clone()
+	if err != nil {
+		c.fail(entity, err)
+		return
+	}
+
+	c.shareReferences(reference.FEATURE_RULE, id)
+	dependencies := []*ClonedEntity{}
+	for i, featureId := range copied.FeatureIds {
+		var dependency *ClonedEntity
+		dependency, copied.FeatureIds[i] = c.cloneFeature(featureId)
+		dependencies = append(dependencies, dependency)
+	}
+	if c.hasFailedDependency(entity, dependencies) {
+		return
+	}
+
+	copied.Id = uuid.New().String()
+	copied.ApplicationType = c.target
+	copied.Priority = queries.GetFeatureRulesSize(c.target) + 1
+	entity.TargetID = copied.Id
+	if c.dryRun != nil && c.copiedDependency {
+		c.notValidated(entity)
+		return
+	}
+	if err := queries.CreateFeatureRule(copied, c.target, c.dryRun); err != nil {
+		c.fail(entity, err)
+		return
+	}
+	if c.dryRun == nil {
+		auth.AssignOwnership(c.r, xshared.OWNED_FEATURE_RULE, copied.Id)
+	}
+	c.created(entity, "RFC-FeatureRules", ds.TABLE_FEATURE_CONTROL_RULE, copied, func() {
+		if err := queries.DeleteFeatureRule(copied, nil); err != nil {
+			log.Errorf("unable to roll back cloned feature rule %s: %s", copied.Id, err.Error())
+		}
+	})
+}
+
+// cloneFeature reuses the target feature with the same feature name or copies the source one
+func (c *cloner) cloneFeature(id string) (*ClonedEntity, string) {
+	if entity := c.get(reference.FEATURE, id); entity != nil {
+		c.copiedDependency = c.copiedDependency || entity.Status == CLONE_CREATED
+		return entity, entity.TargetID
+	}
+	feature := rfc.GetOneFeature(id)
+	if feature == nil || !xshared.ApplicationTypeEquals(feature.ApplicationType, c.source) {
+		return c.notFound(reference.FEATURE, id), id
+	}
+	entity := &ClonedEntity{EntityType: reference.FEATURE, SourceID: id, Name: feature.FeatureName}
+	targetFeatures := xrfc.GetFilteredFeatureList(map[string]string{xwcommon.APPLICATION_TYPE: c.target})
+	for _, targetFeature := range targetFeatures {
+		if targetFeature.FeatureName == feature.FeatureName {
+			entity.TargetID = targetFeature.ID
+			entity.Status = CLONE_REUSED
+			return c.add(entity), targetFeature.ID
+		}
+	}
+
+	c.shareReferences(reference.FEATURE, id)
+	copied, err := feature.Clone()
+	if err != nil {
+		return c.fail(entity, err), id
+	}
+	copied.ID = uuid.New().String()
+	copied.ApplicationType = c.target
+	entity.TargetID = copied.ID
+	if isValid, errorMsg := xrfc.IsValidFeature(copied); !isValid {
+		return c.fail(entity, xcommon.NewXconfError(http.StatusBadRequest, errorMsg)), id
+	}
+	if xrfc.DoesFeatureNameExistForAnotherId(copied) {
+		return c.fail(entity, xcommon.NewXconfError(http.StatusConflict, "Feature with such featureInstance already exists: "+copied.FeatureName)), id
+	}
+	if c.dryRun != nil {
+		c.dryRun.Record(xhttp.DRY_RUN_CREATE, ds.TABLE_XCONF_FEATURE, copied.ID, copied)
+	} else if _, err := queries.PostFeatureEntity(copied.CreateFeatureEntity(), c.target); err != nil {
+		return c.fail(entity, err), id
+	}
+	c.copiedDependency = true
+	return c.created(entity, "RFC-Feature", ds.TABLE_XCONF_FEATURE, copied, func() {
+		queries.DeleteFeatureById(copied.ID)
+	}), copied.ID
+}
+
+func (c *cloner) cloneDcmRule(id string) {
+	rule := dcm.GetDcmFormula(id)
+	if rule == nil || !xshared.ApplicationTypeEquals(rule.ApplicationType, c.source) {
+		c.notFound(reference.DCM_RULE, id)
+		return
+	}
+	entity := &ClonedEntity{EntityType: reference.DCM_RULE, SourceID: id, Name: rule.Name}
+	if c.isApprovalRequired(entity, xchange.DCM_FORMULA) {
+		return
+	}
+	copied, err := rule.Clone()
+	if err != nil {
+		c.fail(entity, err)
+		return
+	}
+	c.shareReferences(reference.DCM_RULE, id)
+	copied.ID = uuid.New().String()
+	copied.ApplicationType = c.target
+	copied.Priority = len(dcm.GetDcmRulesByApplicationType(c.target)) + 1
+	entity.TargetID = copied.ID
+
+	// the settings share the id of their formula
+	formulaWithSettings := &logupload.FormulaWithSettings{Formula: copied}
+	settings := []*ClonedEntity{}
+	if deviceSettings := logupload.GetOneDeviceSettings(id); deviceSettings != nil {
+		if formulaWithSettings.DeviceSettings, err = deviceSettings.Clone(); err != nil {
+			c.fail(entity, err)
+			return
+		}
+		formulaWithSettings.DeviceSettings.ID = copied.ID
+		formulaWithSettings.DeviceSettings.ApplicationType = c.target
+		settings = append(settings, &ClonedEntity{EntityType: DEVICE_SETTINGS, SourceID: id, TargetID: copied.ID, Name: deviceSettings.Name,
+			auditEntityType: "DCM-DeviceSettings", tableName: ds.TABLE_DEVICE_SETTINGS, entity: formulaWithSettings.DeviceSettings})
+	}
+	if logUploadSettings := logupload.GetOneLogUploadSettings(id); logUploadSettings != nil {
+		if formulaWithSettings.LogUpLoadSettings, err = logUploadSettings.Clone(); err != nil {
+			c.fail(entity, err)
+			return
+		}
+		formulaWithSettings.LogUpLoadSettings.ID = copied.ID
+		formulaWithSettings.LogUpLoadSettings.ApplicationType = c.target
+		var repository *ClonedEntity
+		repository, formulaWithSettings.LogUpLoadSettings.UploadRepositoryID = c.cloneUploadRepository(logUploadSettings.UploadRepositoryID)
+		if c.hasFailedDependency(entity, []*ClonedEntity{repository}) {
+			return
+		}
+		settings = append(settings, &ClonedEntity{EntityType: LOG_UPLOAD_SETTINGS, SourceID: id, TargetID: copied.ID, Name: logUploadSettings.Name,
+			auditEntityType: "DCM-LogUploadSettings", tableName: ds.TABLE_LOG_UPLOAD_SETTINGS, entity: formulaWithSettings.LogUpLoadSettings})
+	}
+	if vodSettings := logupload.GetOneVodSettings(id); vodSettings != nil {
+		if formulaWithSettings.VodSettings, err = vodSettings.Clone(); err != nil {
+			c.fail(entity, err)
+			return
+		}
+		formulaWithSettings.VodSettings.ID = copied.ID
+		formulaWithSettings.VodSettings.ApplicationType = c.target
+		settings = append(settings, &ClonedEntity{EntityType: VOD_SETTINGS, SourceID: id, TargetID: copied.ID, Name: vodSettings.Name,
+			auditEntityType: "DCM-VODSettings", tableName: ds.TABLE_VOD_SETTINGS, entity: formulaWithSettings.VodSettings})
+	}
+
+	// the log upload settings don't look up their repository, so the formula is validated even if the repository is copied
+	if err := responseError(dcm.CreateFormulaWithSettings(formulaWithSettings, c.target, c.dryRun)); err != nil {
+		c.fail(entity, err)
+		return
+	}
+	if c.dryRun == nil {
+		auth.AssignOwnership(c.r, xshared.OWNED_DCM_FORMULA, copied.ID)
+	}
+	c.created(entity, "DCM-Formulas", ds.TABLE_DCM_RULE, copied, func() {
+		// deleting the formula deletes its settings
+		if err := dcm.DeleteOneDcmFormula(copied.ID, c.target, nil); err != nil {
+			log.Errorf("unable to roll back cloned dcm formula %s: %s", copied.ID, err.Error())
+		}
+		auth.RemoveOwnership(xshared.OWNED_DCM_FORMULA, copied.ID)
+	})
+	for _, setting := range settings {
+		setting.Status = CLONE_CREATED
+		c.add(setting)
+	}
+}
+
+// cloneUploadRepository matches the repositories by name, the log upload settings keep pointing at the target one
+func (c *cloner) cloneUploadRepository(id string) (*ClonedEntity, string) {
+	if entity := c.get(UPLOAD_REPOSITORY, id); entity != nil {
+		return entity, entity.TargetID
+	}
+	repository := dcm.GetOneLogRepoSettings(id)
+	if repository == nil || !xshared.ApplicationTypeEquals(repository.ApplicationType, c.source) {
+		return c.notFound(UPLOAD_REPOSITORY, id), id
+	}
+	entity := &ClonedEntity{EntityType: UPLOAD_REPOSITORY, SourceID: id, Name: repository.Name}
+	for _, targetRepository := range dcm.GetLogRepoSettingsAll() {
+		if xshared.ApplicationTypeEquals(targetRepository.ApplicationType, c.target) && targetRepository.Name == repository.Name {
+			entity.TargetID = targetRepository.ID
+			entity.Status = CLONE_REUSED
+			return c.add(entity), targetRepository.ID
+		}
+	}
+
+	copied, err := repository.Clone()
+	if err != nil {
+		return c.fail(entity, err), id
+	}
+	copied.ID = uuid.New().String()
+	copied.ApplicationType = c.target
+	entity.TargetID = copied.ID
+	if err := responseError(dcm.CreateLogRepoSettings(copied, c.target, c.dryRun)); err != nil {
+		return c.fail(entity, err), id
+	}
+	return c.created(entity, "DCM-UploadRepository", ds.TABLE_UPLOAD_REPOSITORY, copied, func() {
+		if err := dcm.DeleteOneLogRepoSettings(copied.ID); err != nil {
+			log.Errorf("unable to roll back cloned upload repository %s: %s", copied.ID, err.Error())
+		}
+	}), copied.ID
+}
+
+func (c *cloner) cloneTelemetryRule(id string) {
+	rule := xlogupload.GetOneTelemetryRule(id)
+	if rule == nil || !xshared.ApplicationTypeEquals(rule.ApplicationType, c.source) {
+		c.notFound(reference.TELEMETRY_RULE, id)
+		return
+	}
+	entity := &ClonedEntity{EntityType: reference.TELEMETRY_RULE, SourceID: id, Name: rule.Name}
+	copied, err := rule.Clone()
+	if err != nil {
+		c.fail(entity, err)
+		return
+	}
+	c.shareReferences(reference.TELEMETRY_RULE, id)
+	var profile *ClonedEntity
+	profile, copied.BoundTelemetryID = c.cloneTelemetryProfile(rule.BoundTelemetryID)
+	if c.hasFailedDependency(entity, []*ClonedEntity{profile}) {
+		return
+	}
+
+	copied.ID = uuid.New().String()
+	copied.ApplicationType = c.target
+	entity.TargetID = copied.ID
+	if c.dryRun != nil && c.copiedDependency {
+		c.notValidated(entity)
+		return
+	}
+	if err := responseError(telemetry.CreateTelemetryRule(copied, c.target, c.dryRun)); err != nil {
+		c.fail(entity, err)
+		return
+	}
+	c.created(entity, "Telemetry1-Rules", ds.TABLE_TELEMETRY_RULES, copied, func() {
+		if err := telemetry.DeleteOneTelemetryRule(copied.ID); err != nil {
+			log.Errorf("unable to roll back cloned telemetry rule %s: %s", copied.ID, err.Error())
+		}
+	})
+}
+
+// cloneTelemetryProfile reuses the target profile of the same name, profile names are unique so a copy gets the suffix
+func (c *cloner) cloneTelemetryProfile(id string) (*ClonedEntity, string) {
+	if entity := c.get(reference.TELEMETRY_PROFILE, id); entity != nil {
+		c.copiedDependency = c.copiedDependency || entity.Status == CLONE_CREATED
+		return entity, entity.TargetID
+	}
+	profile := logupload.GetOnePermanentTelemetryProfile(id)
+	if profile == nil || !xshared.ApplicationTypeEquals(profile.ApplicationType, c.source) {
+		return c.notFound(reference.TELEMETRY_PROFILE, id), id
+	}
+	name := profile.Name + c.nameSuffix
+	entity := &ClonedEntity{EntityType: reference.TELEMETRY_PROFILE, SourceID: id, Name: name}
+	for _, targetProfile := range xlogupload.GetPermanentTelemetryProfileListByApplicationType(c.target) {
+		if targetProfile.Name == name {
+			entity.TargetID = targetProfile.ID
+			entity.Status = CLONE_REUSED
+			return c.add(entity), targetProfile.ID
+		}
+	}
+
+	copied, err := profile.Clone()
+	if err != nil {
+		return c.fail(entity, err), id
+	}
+	copied.ID = uuid.New().String()
+	copied.ApplicationType = c.target
+	copied.Name = name
+	for i := range copied.TelemetryProfile {
+		copied.TelemetryProfile[i].ID = uuid.New().String()
+	}
+	entity.TargetID = copied.ID
+	if _, err := change.CreatePermanentTelemetryProfileAS(copied, c.dryRun); err != nil {
+		return c.fail(entity, err), id
+	}
+	if c.dryRun == nil {
+		auth.AssignOwnership(c.r, xshared.OWNED_TELEMETRY_PROFILE, copied.ID)
+	}
+	c.copiedDependency = true
+	return c.created(entity, "Telemetry1-Profiles", ds.TABLE_PERMANENT_TELEMETRY, copied, func() {
+		xlogupload.DeletePermanentTelemetryProfile(copied.ID)
+		auth.RemoveOwnership(xshared.OWNED_TELEMETRY_PROFILE, copied.ID)
+	}), copied.ID
+}
+
+// rollback deletes the created entities in reverse order, so the rules are deleted before their dependencies
+func (c *cloner) rollback() {
+	for i := len(c.rollbacks) - 1; i >= 0; i-- {
+		c.rollbacks[i]()
+	}
+	for _, entity := range c.result.Entities {
+		if entity.Status == CLONE_CREATED {
+			entity.Status = CLONE_ROLLED_BACK
+		}
+	}
+}
+
+// audit writes an audit entry for every created entity
+func (c *cloner) audit() {
+	for _, entity := range c.result.Entities {
+		if entity.Status != CLONE_CREATED || entity.auditEntityType == "" {
+			continue
+		}
+		entry := xshared.AuditEntry{
+			User:            auth.GetUserNameOrUnknown(c.r),
+			Operation:       xshared.AUDIT_CREATE,
+			Method:          c.r.Method,
+			Path:            c.r.URL.Path,
+			Status:          http.StatusCreated,
+			EntityType:      entity.auditEntityType,
+			EntityID:        entity.TargetID,
+			TableName:       entity.tableName,
+			ApplicationType: c.target,
+		}
+		if bytes, err := json.Marshal(entity.entity); err == nil {
+			entry.After = bytes
+		}
+		if err := xshared.SaveAuditEntry(&entry); err != nil {
+			log.Errorf("unable to save audit entry for cloned %s %s: %s", entity.EntityType, entity.TargetID, err.Error())
+		}
+	}
+}
@@ -0,0 +1,171 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package clone
+
+import (
+	"net/http"
+	"testing"
+
+	"xconfadmin/adminapi/auth"
+	"xconfadmin/adminapi/reference"
+	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
+	xshared "xconfadmin/shared"
+	"xconfadmin/testutil"
+	ds "xconfwebconfig/db"
+	re "xconfwebconfig/rulesengine"
+	"xconfwebconfig/shared"
+	coreef "xconfwebconfig/shared/estbfirmware"
+	corefw "xconfwebconfig/shared/firmware"
+
+	"gotest.tools/assert"
+)
+
+const testCloneRuleType = "CLONE_TEST_RULE"
+
+func newTestCloneRule(t *testing.T, name string, applicationType string) *corefw.FirmwareRule {
+	config := coreef.NewEmptyFirmwareConfig()
+	config.ID = name + "-config"
+	config.Description = name + " config"
+	config.FirmwareVersion = name + "_VERSION"
+	config.FirmwareFilename = "fw.bin"
+	config.ApplicationType = applicationType
+	config.SupportedModelIds = []string{"MODEL1"}
+	assert.NilError(t, ds.GetCachedSimpleDao().SetOne(ds.TABLE_FIRMWARE_CONFIG, config.ID, config))
+
+	rule := corefw.NewEmptyFirmwareRule()
+	rule.ID = name
+	rule.Name = name
+	rule.Type = testCloneRuleType
+	rule.ApplicationType = applicationType
+	rule.Rule = *re.NewEmptyRule()
+	rule.Rule.Condition = re.NewCondition(coreef.RuleFactoryMODEL, re.StandardOperationIs, re.NewFixedArg("MODEL1"))
+	rule.ApplicableAction = corefw.NewApplicableActionAndType(corefw.RuleActionClass, corefw.RULE, config.ID)
+	assert.NilError(t, corefw.CreateFirmwareRuleOneDB(rule))
+	return rule
+}
+
+func setupCloneTest(t *testing.T) {
+	testutil.SetupTestDB()
+	assert.NilError(t, ds.GetCachedSimpleDao().SetOne(ds.TABLE_MODEL, "MODEL1", shared.NewModel("MODEL1", "test model")))
+	template := corefw.NewEmptyFirmwareRuleTemplate()
+	template.ID = testCloneRuleType
+	template.Rule = *re.NewEmptyRule()
+	template.Rule.Condition = re.NewCondition(coreef.RuleFactoryMODEL, re.StandardOperationIs, re.NewFixedArg(""))
+	template.ApplicableAction = corefw.NewTemplateApplicableActionAndType(corefw.RuleActionClass, corefw.RULE_TEMPLATE, "")
+	assert.NilError(t, corefw.CreateFirmwareRuleTemplateOneDB(template))
+}
+
+func newCloneTestRequest(ids ...string) (*http.Request, *CloneRequest) {
+	r := testutil.NewRequest(http.MethodPost, "/xconfAdminService/clone?applicationType=stb", "", auth.READ_FIRMWARE_ALL, auth.WRITE_FIRMWARE_ALL)
+	request := &CloneRequest{TargetApplicationType: "rdkcloud", NameSuffix: "-rdkcloud"}
+	for _, id := range ids {
+		request.Entities = append(request.Entities, CloneEntity{EntityType: reference.FIRMWARE_RULE, ID: id})
+	}
+	return r, request
+}
+
+func getClonedEntity(result *CloneResult, entityType string, sourceId string) *ClonedEntity {
+	for _, entity := range result.Entities {
+		if entity.EntityType == entityType && entity.SourceID == sourceId {
+			return entity
+		}
+	}
+	return nil
+}
+
+func TestCloneDryRunCreatesNothing(t *testing.T) {
+	setupCloneTest(t)
+	newTestCloneRule(t, "rule-1", "stb")
+	r, request := newCloneTestRequest("rule-1")
+
+	result, err := CloneEntities(r, request, &xhttp.DryRun{DryRun: true, Changes: []xhttp.DryRunChange{}})
+	assert.NilError(t, err)
+	assert.Assert(t, !result.HasErrors())
+	config := getClonedEntity(result, reference.FIRMWARE_CONFIG, "rule-1-config")
+	assert.Equal(t, config.Status, CLONE_CREATED)
+	// the rule references the config the dry run did not create
+	rule := getClonedEntity(result, reference.FIRMWARE_RULE, "rule-1")
+	assert.Equal(t, rule.Status, CLONE_NOT_VALIDATED)
+
+	_, err = corefw.GetFirmwareRuleOneDB(rule.TargetID)
+	assert.Assert(t, err != nil)
+	_, err = coreef.GetFirmwareConfigOneDB(config.TargetID)
+	assert.Assert(t, err != nil)
+}
+
+func TestCloneCreatesTheRuleAndItsConfig(t *testing.T) {
+	setupCloneTest(t)
+	newTestCloneRule(t, "rule-1", "stb")
+	r, request := newCloneTestRequest("rule-1")
+
+	result, err := CloneEntities(r, request, nil)
+	assert.NilError(t, err)
+	assert.Assert(t, !result.HasErrors())
+	rule := getClonedEntity(result, reference.FIRMWARE_RULE, "rule-1")
+	assert.Equal(t, rule.Status, CLONE_CREATED)
+	config := getClonedEntity(result, reference.FIRMWARE_CONFIG, "rule-1-config")
+	assert.Equal(t, config.Status, CLONE_CREATED)
+	assert.Equal(t, getClonedEntity(result, reference.MODEL, "MODEL1").Status, CLONE_SHARED)
+
+	copiedRule, err := corefw.GetFirmwareRuleOneDB(rule.TargetID)
+	assert.NilError(t, err)
+	assert.Equal(t, copiedRule.ApplicationType, "rdkcloud")
+	assert.Equal(t, copiedRule.ApplicableAction.ConfigId, config.TargetID)
+	copiedConfig, err := coreef.GetFirmwareConfigOneDB(config.TargetID)
+	assert.NilError(t, err)
+	assert.Equal(t, copiedConfig.ApplicationType, "rdkcloud")
+	assert.Equal(t, copiedConfig.Description, "rule-1 config-rdkcloud")
+}
+
+func TestCloneRollbackDeletesTheCreatedEntities(t *testing.T) {
+	setupCloneTest(t)
+	newTestCloneRule(t, "rule-1", "stb")
+	r, request := newCloneTestRequest("rule-1")
+
+	c := newCloner(r, "stb", request, nil)
+	result := c.cloneAll(request.Entities)
+	rule := getClonedEntity(result, reference.FIRMWARE_RULE, "rule-1")
+	config := getClonedEntity(result, reference.FIRMWARE_CONFIG, "rule-1-config")
+	assert.Equal(t, rule.Status, CLONE_CREATED)
+	c.rollback()
+
+	assert.Equal(t, rule.Status, CLONE_ROLLED_BACK)
+	assert.Equal(t, config.Status, CLONE_ROLLED_BACK)
+	// the cache invalidation is asynchronous, so the database is checked
+	_, err := ds.GetSimpleDao().GetOne(ds.TABLE_FIRMWARE_RULE, rule.TargetID)
+	assert.Assert(t, err != nil)
+	_, err = ds.GetSimpleDao().GetOne(ds.TABLE_FIRMWARE_CONFIG, config.TargetID)
+	assert.Assert(t, err != nil)
+	// the source entities are kept
+	_, err = corefw.GetFirmwareRuleOneDB("rule-1")
+	assert.NilError(t, err)
+}
+
+func TestCloneEntitiesMustBelongToTheSourceApplicationType(t *testing.T) {
+	setupCloneTest(t)
+	newTestCloneRule(t, "rule-1", "stb")
+	newTestCloneRule(t, "rule-2", "rdkcloud")
+	r, request := newCloneTestRequest("rule-1", "rule-2")
+	assert.NilError(t, xshared.SetApplicationType(&xshared.ApplicationType{ID: "gateway"}))
+	request.TargetApplicationType = "gateway"
+
+	_, err := CloneEntities(r, request, nil)
+	assert.Equal(t, xcommon.GetXconfErrorStatusCode(err), http.StatusBadRequest)
+	assert.ErrorContains(t, err, "firmwareRule rule-2 belongs to ApplicationType rdkcloud, all the entities to clone must belong to stb")
+}
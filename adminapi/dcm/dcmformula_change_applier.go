@@ -0,0 +1,102 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package dcm
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"xconfadmin/adminapi/auth"
+	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
+	xshared "xconfadmin/shared"
+	xchange "xconfadmin/shared/change"
+	xwhttp "xconfwebconfig/http"
+	xwchange "xconfwebconfig/shared/change"
+	"xconfwebconfig/shared/logupload"
+)
+
+// writePendingChange stores the change for approval and returns it with 202 Accepted, validate runs the
+// validation of the direct write in a dry run first, so an invalid change is not left waiting for approval
+func writePendingChange(w http.ResponseWriter, r *http.Request, applicationType string, operation xwchange.ChangeOperation, entityId string, newEntity interface{}, validate func(dryRun *xhttp.DryRun) error) {
+	if validate != nil {
+		if err := validate(xhttp.NewValidationDryRun()); err != nil {
+			xhttp.AdminError(w, err)
+			return
+		}
+	}
+	change, err := xchange.CreatePendingEntityChange(xchange.DCM_FORMULA, applicationType, operation, entityId, newEntity, auth.GetUserNameOrUnknown(r))
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	res, err := xhttp.ReturnJsonResponse(change, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusAccepted, res)
+}
+
+func responseEntityError(respEntity *xwhttp.ResponseEntity) error {
+	if respEntity.Error == nil {
+		return nil
+	}
+	return xcommon.NewXconfError(respEntity.Status, respEntity.Error.Error())
+}
+
+type dcmFormulaChangeApplier struct{}
+
+func NewDcmFormulaChangeApplier() xchange.EntityChangeApplier {
+	return &dcmFormulaChangeApplier{}
+}
+
+func (a *dcmFormulaChangeApplier) PermissionEntity() string {
+	return auth.DCM_ENTITY
+}
+
+func (a *dcmFormulaChangeApplier) GetEntity(id string) interface{} {
+	if dcmRule := logupload.GetOneDCMGenericRule(id); dcmRule != nil {
+		return dcmRule
+	}
+	return nil
+}
+
+func (a *dcmFormulaChangeApplier) Create(entity json.RawMessage, applicationType string) error {
+	dcmRule := logupload.DCMGenericRule{}
+	if err := json.Unmarshal(entity, &dcmRule); err != nil {
+		return xcommon.NewXconfError(http.StatusBadRequest, err.Error())
+	}
+	return responseEntityError(CreateDcmRule(&dcmRule, applicationType, nil))
+}
+
+func (a *dcmFormulaChangeApplier) Update(entity json.RawMessage, applicationType string) error {
+	dcmRule := logupload.DCMGenericRule{}
+	if err := json.Unmarshal(entity, &dcmRule); err != nil {
+		return xcommon.NewXconfError(http.StatusBadRequest, err.Error())
+	}
+	return responseEntityError(UpdateDcmRule(&dcmRule, applicationType, nil))
+}
+
+func (a *dcmFormulaChangeApplier) Delete(id string, applicationType string) error {
+	if err := responseEntityError(DeleteDcmFormulabyId(id, applicationType, nil)); err != nil {
+		return err
+	}
+	auth.RemoveOwnership(xshared.OWNED_DCM_FORMULA, id)
+	return nil
+}
@@ -24,6 +24,7 @@ import (
 	"sort"
 	"strconv"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 
 	xutil "xconfadmin/util"
@@ -36,6 +37,8 @@ import (
 
 	"xconfadmin/adminapi/auth"
 	xhttp "xconfadmin/http"
+	xshared "xconfadmin/shared"
+	xchange "xconfadmin/shared/change"
 	xwhttp "xconfwebconfig/http"
 )
 
@@ -198,11 +201,30 @@ func DeleteDcmFormulaByIdHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respEntity := DeleteDcmFormulabyId(id, appType)
+	if err := auth.ValidateOwnership(r, xshared.OWNED_DCM_FORMULA, id); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	dryRun := xhttp.NewDryRun(r)
+	if xchange.IsApprovalRequired(xchange.DCM_FORMULA, appType) {
+		if dryRun == nil {
+			writePendingChange(w, r, appType, xchange.Delete, id, nil, func(dryRun *xhttp.DryRun) error {
+				return responseEntityError(DeleteDcmFormulabyId(id, appType, dryRun))
+			})
+			return
+		}
+		dryRun.ApprovalRequired = true
+	}
+	respEntity := DeleteDcmFormulabyId(id, appType, dryRun)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	if dryRun != nil {
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
+	auth.RemoveOwnership(xshared.OWNED_DCM_FORMULA, id)
 	xwhttp.WriteXconfResponse(w, respEntity.Status, nil)
 }
 
@@ -227,11 +249,33 @@ func CreateDcmFormulaHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respEntity := CreateDcmRule(&newdfrule, appType)
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_DCM_FORMULA, newdfrule.ID); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	dryRun := xhttp.NewDryRun(r)
+	if xchange.IsApprovalRequired(xchange.DCM_FORMULA, appType) {
+		if newdfrule.ID == "" {
+			newdfrule.ID = uuid.New().String()
+		}
+		if dryRun == nil {
+			writePendingChange(w, r, appType, xchange.Create, newdfrule.ID, newdfrule, func(dryRun *xhttp.DryRun) error {
+				return responseEntityError(CreateDcmRule(&newdfrule, appType, dryRun))
+			})
+			return
+		}
+		dryRun.ApprovalRequired = true
+	}
+	respEntity := CreateDcmRule(&newdfrule, appType, dryRun)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	if dryRun != nil {
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
+	auth.AssignOwnership(r, xshared.OWNED_DCM_FORMULA, newdfrule.ID)
 
 	res, err := xhttp.ReturnJsonResponse(respEntity.Data, r)
 	if err != nil {
@@ -262,11 +306,30 @@ func UpdateDcmFormulaHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respEntity := UpdateDcmRule(&newdfrule, appType)
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_DCM_FORMULA, newdfrule.ID); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	dryRun := xhttp.NewDryRun(r)
+	if xchange.IsApprovalRequired(xchange.DCM_FORMULA, appType) {
+		if dryRun == nil {
+			writePendingChange(w, r, appType, xchange.Update, newdfrule.ID, newdfrule, func(dryRun *xhttp.DryRun) error {
+				return responseEntityError(UpdateDcmRule(&newdfrule, appType, dryRun))
+			})
+			return
+		}
+		dryRun.ApprovalRequired = true
+	}
+	respEntity := UpdateDcmRule(&newdfrule, appType, dryRun)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	if dryRun != nil {
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
+	auth.UpdateOwnership(r, xshared.OWNED_DCM_FORMULA, newdfrule.ID)
 
 	res, err := xhttp.ReturnJsonResponse(respEntity.Data, r)
 	if err != nil {
@@ -438,11 +501,28 @@ func DcmFormulaChangePriorityHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, "ApplicationType doesn't match")
 		return
 	}
-	reorganizedFormulas, err := UpdateItemAndRepriortize(formulaToUpdate, formulaToUpdate.Priority, newPriority)
+	if err := auth.ValidateOwnership(r, xshared.OWNED_DCM_FORMULA, id); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	dryRun := xhttp.NewDryRun(r)
+	if dryRun != nil {
+		copied := *formulaToUpdate
+		formulaToUpdate = &copied
+	}
+	reorganizedFormulas, err := UpdateItemAndRepriortize(formulaToUpdate, formulaToUpdate.Priority, newPriority, dryRun)
 	if err != nil {
 		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("unable to re-organize priorities: %s", err))
 		return
 	}
+	if dryRun != nil {
+		for _, entry := range reorganizedFormulas {
+			dryRun.Record(xhttp.DRY_RUN_UPDATE, db.TABLE_DCM_RULE, entry.ID, entry)
+		}
+		dryRun.Result = reorganizedFormulas
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 
 	for _, entry := range reorganizedFormulas {
 		if err = db.GetCachedSimpleDao().SetOne(db.TABLE_DCM_RULE, entry.ID, entry); err != nil {
@@ -464,6 +544,10 @@ func ImportDcmFormulaWithOverwriteHandler(w http.ResponseWriter, r *http.Request
 		xhttp.AdminError(w, err)
 		return
 	}
+	if err := xchange.ValidateDirectWrite(xchange.DCM_FORMULA, appType); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
 
 	overwriteStr, ok := mux.Vars(r)[xcommon.OVERWRITE]
 	if !ok {
@@ -488,11 +572,27 @@ func ImportDcmFormulaWithOverwriteHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	respEntity := importFormula(&formulaWithSettings, overwrite, appType)
+	if formulaWithSettings.Formula != nil {
+		if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_DCM_FORMULA, formulaWithSettings.Formula.ID); err != nil {
+			xhttp.AdminError(w, err)
+			return
+		}
+	}
+	dryRun := xhttp.NewDryRun(r)
+	respEntity := importFormula(&formulaWithSettings, overwrite, appType, dryRun)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	if dryRun != nil {
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
+	if formulaWithSettings.Formula != nil && overwrite {
+		auth.UpdateOwnership(r, xshared.OWNED_DCM_FORMULA, formulaWithSettings.Formula.ID)
+	} else if formulaWithSettings.Formula != nil {
+		auth.AssignOwnership(r, xshared.OWNED_DCM_FORMULA, formulaWithSettings.Formula.ID)
+	}
 	res, err := xhttp.ReturnJsonResponse(respEntity.Data, r)
 	if err != nil {
 		xhttp.AdminError(w, err)
@@ -507,6 +607,10 @@ func ImportDcmFormulasHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.AdminError(w, err)
 		return
 	}
+	if err := xchange.ValidateDirectWrite(xchange.DCM_FORMULA, appType); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
 
 	xw, ok := w.(*xwhttp.XResponseWriter)
 	if !ok {
@@ -525,16 +629,26 @@ func ImportDcmFormulasHandler(w http.ResponseWriter, r *http.Request) {
 		return formulaWithSettingsList[i].Formula.Priority < formulaWithSettingsList[j].Formula.Priority
 	})
 
+	dryRun := xhttp.NewDryRun(r)
 	failedToImport := []string{}
 	successfulImportIds := []string{}
 
 	for _, formulaWithSettings := range formulaWithSettingsList {
 		formula := formulaWithSettings.Formula
-		respEntity := importFormula(&formulaWithSettings, false, appType)
+		if formula != nil {
+			if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_DCM_FORMULA, formula.ID); err != nil {
+				failedToImport = append(failedToImport, err.Error())
+				continue
+			}
+		}
+		respEntity := importFormula(&formulaWithSettings, false, appType, dryRun)
 		if respEntity.Error != nil {
 			failedToImport = append(failedToImport, respEntity.Error.Error())
 		} else {
 			successfulImportIds = append(successfulImportIds, formula.ID)
+			if dryRun == nil {
+				auth.AssignOwnership(r, xshared.OWNED_DCM_FORMULA, formula.ID)
+			}
 		}
 	}
 
@@ -542,6 +656,11 @@ func ImportDcmFormulasHandler(w http.ResponseWriter, r *http.Request) {
 		"success": successfulImportIds,
 		"failure": failedToImport,
 	}
+	if dryRun != nil {
+		dryRun.Result = result
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 
 	res, err := xhttp.ReturnJsonResponse(result, r)
 	if err != nil {
@@ -557,6 +676,10 @@ func PostDcmFormulaListHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.AdminError(w, err)
 		return
 	}
+	if err := xchange.ValidateDirectWrite(xchange.DCM_FORMULA, appType); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
 
 	xw, ok := w.(*xwhttp.XResponseWriter)
 	if !ok {
@@ -572,7 +695,23 @@ func PostDcmFormulaListHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result := importFormulas(formulaWithSettingsList, appType, false)
+	denied := map[string]xhttp.EntityMessage{}
+	formulaWithSettingsList = filterOwnedFormulas(r, formulaWithSettingsList, denied)
+	dryRun := xhttp.NewDryRun(r)
+	result := importFormulas(formulaWithSettingsList, appType, false, dryRun)
+	for id, entityMessage := range result {
+		if entityMessage.Status == xcommon.ENTITY_STATUS_SUCCESS && dryRun == nil {
+			auth.AssignOwnership(r, xshared.OWNED_DCM_FORMULA, id)
+		}
+	}
+	for id, entityMessage := range denied {
+		result[id] = entityMessage
+	}
+	if dryRun != nil {
+		dryRun.Result = result
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 
 	res, err := xhttp.ReturnJsonResponse(result, r)
 	if err != nil {
@@ -588,6 +727,10 @@ func PutDcmFormulaListHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.AdminError(w, err)
 		return
 	}
+	if err := xchange.ValidateDirectWrite(xchange.DCM_FORMULA, appType); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
 
 	xw, ok := w.(*xwhttp.XResponseWriter)
 	if !ok {
@@ -603,7 +746,23 @@ func PutDcmFormulaListHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result := importFormulas(formulaWithSettingsList, appType, true)
+	denied := map[string]xhttp.EntityMessage{}
+	formulaWithSettingsList = filterOwnedFormulas(r, formulaWithSettingsList, denied)
+	dryRun := xhttp.NewDryRun(r)
+	result := importFormulas(formulaWithSettingsList, appType, true, dryRun)
+	for id, entityMessage := range result {
+		if entityMessage.Status == xcommon.ENTITY_STATUS_SUCCESS && dryRun == nil {
+			auth.UpdateOwnership(r, xshared.OWNED_DCM_FORMULA, id)
+		}
+	}
+	for id, entityMessage := range denied {
+		result[id] = entityMessage
+	}
+	if dryRun != nil {
+		dryRun.Result = result
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 
 	res, err := xhttp.ReturnJsonResponse(result, r)
 	if err != nil {
@@ -29,9 +29,11 @@ import (
 
 	ru "xconfwebconfig/rulesengine"
 
+	"xconfadmin/adminapi/auth"
 	queries "xconfadmin/adminapi/queries"
 	xcommon "xconfadmin/common"
 	xhttp "xconfadmin/http"
+	xshared "xconfadmin/shared"
 	xutil "xconfadmin/util"
 	xwcommon "xconfwebconfig/common"
 	ds "xconfwebconfig/db"
@@ -71,7 +73,7 @@ func validateUsageForDcmFormula(id string, appType string) (string, error) {
 	return "", nil
 }
 
-func DeleteDcmFormulabyId(id string, appType string) *xwhttp.ResponseEntity {
+func DeleteDcmFormulabyId(id string, appType string, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
 	usage, err := validateUsageForDcmFormula(id, appType)
 	if err != nil {
 		return xwhttp.NewResponseEntity(http.StatusNotFound, err, nil)
@@ -81,7 +83,7 @@ func DeleteDcmFormulabyId(id string, appType string) *xwhttp.ResponseEntity {
 		return xwhttp.NewResponseEntity(http.StatusNotFound, errors.New(usage), nil)
 	}
 
-	err = DeleteOneDcmFormula(id, appType)
+	err = DeleteOneDcmFormula(id, appType, dryRun)
 	if err != nil {
 		return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
 	}
@@ -89,7 +91,20 @@ func DeleteDcmFormulabyId(id string, appType string) *xwhttp.ResponseEntity {
 	return xwhttp.NewResponseEntity(http.StatusNoContent, nil, nil)
 }
 
-func DeleteOneDcmFormula(id string, appType string) error {
+func DeleteOneDcmFormula(id string, appType string, dryRun *xhttp.DryRun) error {
+	if dryRun != nil {
+		dryRun.Record(xhttp.DRY_RUN_DELETE, ds.TABLE_DCM_RULE, id, GetDcmFormula(id))
+		if devicesettings := logupload.GetOneDeviceSettings(id); devicesettings != nil {
+			dryRun.Record(xhttp.DRY_RUN_DELETE, ds.TABLE_DEVICE_SETTINGS, id, devicesettings)
+		}
+		if loguploadsettings := logupload.GetOneLogUploadSettings(id); loguploadsettings != nil {
+			dryRun.Record(xhttp.DRY_RUN_DELETE, ds.TABLE_LOG_UPLOAD_SETTINGS, id, loguploadsettings)
+		}
+		if vodsettings := logupload.GetOneVodSettings(id); vodsettings != nil {
+			dryRun.Record(xhttp.DRY_RUN_DELETE, ds.TABLE_VOD_SETTINGS, id, vodsettings)
+		}
+		return packPriorities(appType, id, dryRun)
+	}
 	err := ds.GetCachedSimpleDao().DeleteOne(ds.TABLE_DCM_RULE, id)
 	if err != nil {
 		return err
@@ -116,18 +131,22 @@ func DeleteOneDcmFormula(id string, appType string) error {
 		}
 	}
 
-	return packPriorities(appType)
+	return packPriorities(appType, id, nil)
 }
 
-func packPriorities(appType string) error {
+// packPriorities closes the gap left by the deleted formula, a dry run still finds it in the DB so it is skipped
+func packPriorities(appType string, deletedId string, dryRun *xhttp.DryRun) error {
 	changedRules := []*logupload.DCMGenericRule{}
-	dfrules := GetDcmRulesByApplicationType(appType)
+	dfrules := getDcmRulesToReorganize(appType, dryRun)
 	// sort by ascending priority
 	sort.Slice(dfrules, func(i, j int) bool {
 		return dfrules[i].Priority < dfrules[j].Priority
 	})
 	priority := 1
 	for _, item := range dfrules {
+		if item.ID == deletedId {
+			continue
+		}
 		if item.Priority != priority {
 			item.Priority = priority
 			changedRules = append(changedRules, item)
@@ -136,6 +155,10 @@ func packPriorities(appType string) error {
 	}
 	// Now save all updated priorities
 	for _, dcmrule := range changedRules {
+		if dryRun != nil {
+			dryRun.Record(xhttp.DRY_RUN_UPDATE, ds.TABLE_DCM_RULE, dcmrule.ID, dcmrule)
+			continue
+		}
 		if err := ds.GetCachedSimpleDao().SetOne(ds.TABLE_DCM_RULE, dcmrule.ID, dcmrule); err != nil {
 			return err
 		}
@@ -143,6 +166,47 @@ func packPriorities(appType string) error {
 	return nil
 }
 
+// getDcmRulesToReorganize returns the formulas of the application type, copied for a dry run
+// so their priorities can change without altering the cached formulas. A dry run also sees
+// the formulas recorded by the earlier entries of the same batch
+func getDcmRulesToReorganize(appType string, dryRun *xhttp.DryRun) []*logupload.DCMGenericRule {
+	dfrules := GetDcmRulesByApplicationType(appType)
+	if dryRun == nil {
+		return dfrules
+	}
+	pending := dryRun.Pending(ds.TABLE_DCM_RULE)
+	copies := make([]*logupload.DCMGenericRule, 0, len(dfrules)+len(pending))
+	for _, dfrule := range dfrules {
+		if _, ok := pending[dfrule.ID]; ok {
+			continue
+		}
+		copied := *dfrule
+		copies = append(copies, &copied)
+	}
+	for _, entity := range pending {
+		if dfrule, ok := entity.(*logupload.DCMGenericRule); ok && dfrule.ApplicationType == appType {
+			copied := *dfrule
+			copies = append(copies, &copied)
+		}
+	}
+	return copies
+}
+
+// saveDcmRules stores the formulas whose priority changed, a dry run only records them
+func saveDcmRules(dfrules []*logupload.DCMGenericRule, dryRun *xhttp.DryRun) error {
+	for _, entry := range dfrules {
+		entry.Updated = xwutil.GetTimestamp(time.Now().UTC())
+		if dryRun != nil {
+			dryRun.RecordSave(ds.TABLE_DCM_RULE, entry.ID, entry, logupload.GetOneDCMGenericRule(entry.ID) != nil)
+			continue
+		}
+		if err := ds.GetCachedSimpleDao().SetOne(ds.TABLE_DCM_RULE, entry.ID, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func dcmRuleValidate(dfrule *logupload.DCMGenericRule) *xwhttp.ResponseEntity {
 	if dfrule == nil {
 		return xwhttp.NewResponseEntity(http.StatusBadRequest, errors.New("DCM formula Rule should be specified"), nil)
@@ -248,8 +312,8 @@ func reorganizePriorities(dfrules []*logupload.DCMGenericRule, oldpriority int,
 	return getAlteredSubList(dfrules, oldpriority, newpriority)
 }
 
-func AddnewItemAndRepriortize(newdfrule *logupload.DCMGenericRule) []*logupload.DCMGenericRule {
-	dfrules := GetDcmRulesByApplicationType(newdfrule.ApplicationType)
+func AddnewItemAndRepriortize(newdfrule *logupload.DCMGenericRule, dryRun *xhttp.DryRun) []*logupload.DCMGenericRule {
+	dfrules := getDcmRulesToReorganize(newdfrule.ApplicationType, dryRun)
 	// sort by ascending priority
 	sort.Slice(dfrules, func(i, j int) bool {
 		return dfrules[i].Priority < dfrules[j].Priority
@@ -260,8 +324,8 @@ func AddnewItemAndRepriortize(newdfrule *logupload.DCMGenericRule) []*logupload.
 	return reorganizePriorities(dfrules, oldpriority, newpriority)
 }
 
-func CreateDcmRule(dfrule *logupload.DCMGenericRule, appType string) *xwhttp.ResponseEntity {
-	if existingRule := logupload.GetOneDCMGenericRule(dfrule.ID); existingRule != nil {
+func CreateDcmRule(dfrule *logupload.DCMGenericRule, appType string, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
+	if existingRule := logupload.GetOneDCMGenericRule(dfrule.ID); existingRule != nil || dryRun.Saved(ds.TABLE_DCM_RULE, dfrule.ID) {
 		return xwhttp.NewResponseEntity(http.StatusConflict, fmt.Errorf("Entity with id %s already exists", dfrule.ID), nil)
 	}
 	if dfrule.ApplicationType != appType {
@@ -271,12 +335,8 @@ func CreateDcmRule(dfrule *logupload.DCMGenericRule, appType string) *xwhttp.Res
 		return respEntity
 	}
 
-	list := AddnewItemAndRepriortize(dfrule)
-	for _, entry := range list {
-		entry.Updated = xwutil.GetTimestamp(time.Now().UTC())
-		if err := ds.GetCachedSimpleDao().SetOne(ds.TABLE_DCM_RULE, entry.ID, entry); err != nil {
-			return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
-		}
+	if err := saveDcmRules(AddnewItemAndRepriortize(dfrule, dryRun), dryRun); err != nil {
+		return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
 	}
 	return xwhttp.NewResponseEntity(http.StatusCreated, nil, dfrule)
 }
@@ -292,8 +352,8 @@ func GetDcmRulesByApplicationType(applicationType string) []*logupload.DCMGeneri
 	return list
 }
 
-func UpdateItemAndRepriortize(newdfrule *logupload.DCMGenericRule, oldPriority, newPriority int) (result []*logupload.DCMGenericRule, err error) {
-	dfrules := GetDcmRulesByApplicationType(newdfrule.ApplicationType)
+func UpdateItemAndRepriortize(newdfrule *logupload.DCMGenericRule, oldPriority, newPriority int, dryRun *xhttp.DryRun) (result []*logupload.DCMGenericRule, err error) {
+	dfrules := getDcmRulesToReorganize(newdfrule.ApplicationType, dryRun)
 	// sort by ascending priority
 	sort.Slice(dfrules, func(i, j int) bool {
 		return dfrules[i].Priority < dfrules[j].Priority
@@ -313,7 +373,7 @@ func UpdateItemAndRepriortize(newdfrule *logupload.DCMGenericRule, oldPriority,
 	return result, nil
 }
 
-func UpdateDcmRule(dfrule *logupload.DCMGenericRule, appType string) *xwhttp.ResponseEntity {
+func UpdateDcmRule(dfrule *logupload.DCMGenericRule, appType string, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
 	if xwutil.IsBlank(dfrule.ID) {
 		return xwhttp.NewResponseEntity(http.StatusBadRequest, errors.New("ID is empty"), nil)
 	}
@@ -333,20 +393,16 @@ func UpdateDcmRule(dfrule *logupload.DCMGenericRule, appType string) *xwhttp.Res
 	}
 
 	if dfrule.Priority == existingRule.Priority {
-		dfrule.Updated = xwutil.GetTimestamp(time.Now().UTC())
-		if err := ds.GetCachedSimpleDao().SetOne(ds.TABLE_DCM_RULE, dfrule.ID, dfrule); err != nil {
+		if err := saveDcmRules([]*logupload.DCMGenericRule{dfrule}, dryRun); err != nil {
 			return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
 		}
 	} else {
-		list, err := UpdateItemAndRepriortize(dfrule, existingRule.Priority, dfrule.Priority)
+		list, err := UpdateItemAndRepriortize(dfrule, existingRule.Priority, dfrule.Priority, dryRun)
 		if err != nil {
 			return xwhttp.NewResponseEntity(http.StatusBadRequest, err, nil)
 		}
-		for _, entry := range list {
-			entry.Updated = xwutil.GetTimestamp(time.Now().UTC())
-			if err = ds.GetCachedSimpleDao().SetOne(ds.TABLE_DCM_RULE, entry.ID, entry); err != nil {
-				return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
-			}
+		if err = saveDcmRules(list, dryRun); err != nil {
+			return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
 		}
 	}
 
@@ -389,6 +445,7 @@ func DcmFormulaRuleGeneratePageWithContext(dfrules []*logupload.DCMGenericRule,
 
 func DcmFormulaFilterByContext(searchContext map[string]string) []*logupload.DCMGenericRule {
 	dcmFormulaRules := logupload.GetDCMGenericRuleList()
+	honoredByOwner := auth.NewOwnerFilter(searchContext, xshared.OWNED_DCM_FORMULA)
 	dcmFormulaRuleList := []*logupload.DCMGenericRule{}
 	for _, dcmRule := range dcmFormulaRules {
 		if dcmRule == nil {
@@ -442,12 +499,20 @@ func DcmFormulaFilterByContext(searchContext map[string]string) []*logupload.DCM
 				continue
 			}
 		}
+		if !honoredByOwner(dcmRule.ID) {
+			continue
+		}
 		dcmFormulaRuleList = append(dcmFormulaRuleList, dcmRule)
 	}
 	return dcmFormulaRuleList
 }
 
-func importFormula(formulaWithSettings *logupload.FormulaWithSettings, overwrite bool, appType string) *xwhttp.ResponseEntity {
+// CreateFormulaWithSettings creates the formula and its settings, the settings share the formula's id
+func CreateFormulaWithSettings(formulaWithSettings *logupload.FormulaWithSettings, appType string, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
+	return importFormula(formulaWithSettings, false, appType, dryRun)
+}
+
+func importFormula(formulaWithSettings *logupload.FormulaWithSettings, overwrite bool, appType string, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
 	formula := formulaWithSettings.Formula
 	deviceSettings := formulaWithSettings.DeviceSettings
 	logUploadSettings := formulaWithSettings.LogUpLoadSettings
@@ -499,40 +564,40 @@ func importFormula(formulaWithSettings *logupload.FormulaWithSettings, overwrite
 	}
 
 	if overwrite {
-		if respEntity := UpdateDcmRule(formula, appType); respEntity.Error != nil {
+		if respEntity := UpdateDcmRule(formula, appType, dryRun); respEntity.Error != nil {
 			return respEntity
 		}
 		if deviceSettings != nil {
-			if respEntity := UpdateDeviceSettings(deviceSettings, appType); respEntity.Error != nil {
+			if respEntity := UpdateDeviceSettings(deviceSettings, appType, dryRun); respEntity.Error != nil {
 				return respEntity
 			}
 		}
 		if logUploadSettings != nil {
-			if respEntity := UpdateLogUploadSettings(logUploadSettings, appType); respEntity.Error != nil {
+			if respEntity := UpdateLogUploadSettings(logUploadSettings, appType, dryRun); respEntity.Error != nil {
 				return respEntity
 			}
 		}
 		if vodSettings != nil {
-			if respEntity := UpdateVodSettings(vodSettings, appType); respEntity.Error != nil {
+			if respEntity := UpdateVodSettings(vodSettings, appType, dryRun); respEntity.Error != nil {
 				return respEntity
 			}
 		}
 	} else {
-		if respEntity := CreateDcmRule(formula, appType); respEntity.Error != nil {
+		if respEntity := CreateDcmRule(formula, appType, dryRun); respEntity.Error != nil {
 			return respEntity
 		}
 		if deviceSettings != nil {
-			if respEntity := CreateDeviceSettings(deviceSettings, appType); respEntity.Error != nil {
+			if respEntity := CreateDeviceSettings(deviceSettings, appType, dryRun); respEntity.Error != nil {
 				return respEntity
 			}
 		}
 		if logUploadSettings != nil {
-			if respEntity := CreateLogUploadSettings(logUploadSettings, appType); respEntity.Error != nil {
+			if respEntity := CreateLogUploadSettings(logUploadSettings, appType, dryRun); respEntity.Error != nil {
 				return respEntity
 			}
 		}
 		if vodSettings != nil {
-			if respEntity := CreateVodSettings(vodSettings, appType); respEntity.Error != nil {
+			if respEntity := CreateVodSettings(vodSettings, appType, dryRun); respEntity.Error != nil {
 				return respEntity
 			}
 		}
@@ -541,7 +606,27 @@ func importFormula(formulaWithSettings *logupload.FormulaWithSettings, overwrite
 	return xwhttp.NewResponseEntity(http.StatusOK, nil, formulaWithSettings)
 }
 
-func importFormulas(formulaWithSettingsList []*logupload.FormulaWithSettings, appType string, overwrite bool) map[string]xhttp.EntityMessage {
+// filterOwnedFormulas returns the formulas the user may modify, the others are reported as failures in entitiesMap
+func filterOwnedFormulas(r *http.Request, formulaWithSettingsList []*logupload.FormulaWithSettings, entitiesMap map[string]xhttp.EntityMessage) []*logupload.FormulaWithSettings {
+	allowed := []*logupload.FormulaWithSettings{}
+	for _, formulaWithSettings := range formulaWithSettingsList {
+		if formulaWithSettings.Formula == nil {
+			allowed = append(allowed, formulaWithSettings)
+			continue
+		}
+		if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_DCM_FORMULA, formulaWithSettings.Formula.ID); err != nil {
+			entitiesMap[formulaWithSettings.Formula.ID] = xhttp.EntityMessage{
+				Status:  xcommon.ENTITY_STATUS_FAILURE,
+				Message: err.Error(),
+			}
+			continue
+		}
+		allowed = append(allowed, formulaWithSettings)
+	}
+	return allowed
+}
+
+func importFormulas(formulaWithSettingsList []*logupload.FormulaWithSettings, appType string, overwrite bool, dryRun *xhttp.DryRun) map[string]xhttp.EntityMessage {
 	entitiesMap := map[string]xhttp.EntityMessage{}
 
 	sort.Slice(formulaWithSettingsList, func(i, j int) bool {
@@ -550,7 +635,7 @@ func importFormulas(formulaWithSettingsList []*logupload.FormulaWithSettings, ap
 
 	for _, formulaWithSettings := range formulaWithSettingsList {
 		formula := formulaWithSettings.Formula
-		respEntity := importFormula(formulaWithSettings, overwrite, appType)
+		respEntity := importFormula(formulaWithSettings, overwrite, appType, dryRun)
 		if respEntity.Error != nil {
 			entityMessage := xhttp.EntityMessage{
 				Status:  xcommon.ENTITY_STATUS_FAILURE,
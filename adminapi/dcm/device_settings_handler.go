@@ -201,7 +201,7 @@ func CreateDeviceSettingsHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	respEntity := CreateDeviceSettings(&newds, applicationType)
+	respEntity := CreateDeviceSettings(&newds, applicationType, nil)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
@@ -235,7 +235,7 @@ func UpdateDeviceSettingsHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	respEntity := UpdateDeviceSettings(&newdsrule, applicationType)
+	respEntity := UpdateDeviceSettings(&newdsrule, applicationType, nil)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
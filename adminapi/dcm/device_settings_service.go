@@ -32,6 +32,7 @@ import (
 	"github.com/google/uuid"
 
 	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
 	xutil "xconfadmin/util"
 	xwcommon "xconfwebconfig/common"
 	"xconfwebconfig/db"
@@ -166,7 +167,7 @@ func DeviceSettingsValidate(ds *logupload.DeviceSettings) *xwhttp.ResponseEntity
 	return xwhttp.NewResponseEntity(http.StatusCreated, nil, nil)
 }
 
-func CreateDeviceSettings(dset *logupload.DeviceSettings, app string) *xwhttp.ResponseEntity {
+func CreateDeviceSettings(dset *logupload.DeviceSettings, app string, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
 	if existingSettings := logupload.GetOneDeviceSettings(dset.ID); existingSettings != nil {
 		return xwhttp.NewResponseEntity(http.StatusConflict, fmt.Errorf("Entity with id %s already exists", dset.ID), nil)
 	}
@@ -180,14 +181,16 @@ func CreateDeviceSettings(dset *logupload.DeviceSettings, app string) *xwhttp.Re
 	}
 
 	dset.Updated = util.GetTimestamp(time.Now().UTC())
-	if err := db.GetCachedSimpleDao().SetOne(db.TABLE_DEVICE_SETTINGS, dset.ID, dset); err != nil {
+	if dryRun != nil {
+		dryRun.Record(xhttp.DRY_RUN_CREATE, db.TABLE_DEVICE_SETTINGS, dset.ID, dset)
+	} else if err := db.GetCachedSimpleDao().SetOne(db.TABLE_DEVICE_SETTINGS, dset.ID, dset); err != nil {
 		return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
 	}
 
 	return xwhttp.NewResponseEntity(http.StatusCreated, nil, dset)
 }
 
-func UpdateDeviceSettings(dset *logupload.DeviceSettings, app string) *xwhttp.ResponseEntity {
+func UpdateDeviceSettings(dset *logupload.DeviceSettings, app string, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
 	if util.IsBlank(dset.ID) {
 		return xwhttp.NewResponseEntity(http.StatusBadRequest, errors.New("ID is empty"), nil)
 	}
@@ -206,7 +209,9 @@ func UpdateDeviceSettings(dset *logupload.DeviceSettings, app string) *xwhttp.Re
 	}
 
 	dset.Updated = util.GetTimestamp(time.Now().UTC())
-	if err := db.GetCachedSimpleDao().SetOne(db.TABLE_DEVICE_SETTINGS, dset.ID, dset); err != nil {
+	if dryRun != nil {
+		dryRun.Record(xhttp.DRY_RUN_UPDATE, db.TABLE_DEVICE_SETTINGS, dset.ID, dset)
+	} else if err := db.GetCachedSimpleDao().SetOne(db.TABLE_DEVICE_SETTINGS, dset.ID, dset); err != nil {
 		return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
 	}
 	return xwhttp.NewResponseEntity(http.StatusOK, nil, dset)
@@ -197,7 +197,7 @@ func CreateLogRepoSettingsHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	respEntity := CreateLogRepoSettings(&newlr, applicationType)
+	respEntity := CreateLogRepoSettings(&newlr, applicationType, nil)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
@@ -306,7 +306,7 @@ func PostLogRepoSettingsEntitiesHandler(w http.ResponseWriter, r *http.Request)
 	}
 	entitiesMap := map[string]xhttp.EntityMessage{}
 	for _, entity := range entities {
-		respEntity := CreateLogRepoSettings(&entity, applicationType)
+		respEntity := CreateLogRepoSettings(&entity, applicationType, nil)
 		if respEntity.Error != nil {
 			entitiesMap[entity.ID] = xhttp.EntityMessage{
 				Status:  xcommon.ENTITY_STATUS_FAILURE,
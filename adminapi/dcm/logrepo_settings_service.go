@@ -33,6 +33,7 @@ import (
 
 	"github.com/google/uuid"
 
+	xhttp "xconfadmin/http"
 	xwhttp "xconfwebconfig/http"
 
 	"xconfadmin/common"
@@ -184,7 +185,7 @@ func LogRepoSettingsValidate(lr *logupload.UploadRepository) *xwhttp.ResponseEnt
 	return xwhttp.NewResponseEntity(http.StatusCreated, nil, nil)
 }
 
-func CreateLogRepoSettings(lr *logupload.UploadRepository, app string) *xwhttp.ResponseEntity {
+func CreateLogRepoSettings(lr *logupload.UploadRepository, app string, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
 	_, err := db.GetCachedSimpleDao().GetOne(db.TABLE_UPLOAD_REPOSITORY, lr.ID)
 	if err == nil {
 		return xwhttp.NewResponseEntity(http.StatusConflict, errors.New(fmt.Sprintf("Entity with id %s already exists", lr.ID)), nil)
@@ -198,7 +199,9 @@ func CreateLogRepoSettings(lr *logupload.UploadRepository, app string) *xwhttp.R
 		return respEntity
 	}
 	lr.Updated = util.GetTimestamp(time.Now().UTC())
-	if err = db.GetCachedSimpleDao().SetOne(db.TABLE_UPLOAD_REPOSITORY, lr.ID, lr); err != nil {
+	if dryRun != nil {
+		dryRun.Record(xhttp.DRY_RUN_CREATE, db.TABLE_UPLOAD_REPOSITORY, lr.ID, lr)
+	} else if err = db.GetCachedSimpleDao().SetOne(db.TABLE_UPLOAD_REPOSITORY, lr.ID, lr); err != nil {
 		return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
 	}
 	return xwhttp.NewResponseEntity(http.StatusCreated, nil, lr)
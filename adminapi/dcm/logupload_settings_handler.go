@@ -175,7 +175,7 @@ func CreateLogUploadSettingsHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	respEntity := CreateLogUploadSettings(&newlu, applicationType)
+	respEntity := CreateLogUploadSettings(&newlu, applicationType, nil)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
@@ -209,7 +209,7 @@ func UpdateLogUploadSettingsHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	respEntity := UpdateLogUploadSettings(&newlurule, applicationType)
+	respEntity := UpdateLogUploadSettings(&newlurule, applicationType, nil)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
@@ -27,6 +27,7 @@ import (
 	"time"
 
 	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
 	xwcommon "xconfwebconfig/common"
 	"xconfwebconfig/shared/logupload"
 	"xconfwebconfig/util"
@@ -153,7 +154,7 @@ func LogUploadSettingsValidate(lu *logupload.LogUploadSettings) *xwhttp.Response
 	return xwhttp.NewResponseEntity(http.StatusCreated, nil, nil)
 }
 
-func CreateLogUploadSettings(lu *logupload.LogUploadSettings, app string) *xwhttp.ResponseEntity {
+func CreateLogUploadSettings(lu *logupload.LogUploadSettings, app string, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
 	if existingSettings := logupload.GetOneLogUploadSettings(lu.ID); existingSettings != nil {
 		return xwhttp.NewResponseEntity(http.StatusConflict, errors.New(fmt.Sprintf("Entity with id %s already exists", lu.ID)), nil)
 	}
@@ -167,14 +168,16 @@ func CreateLogUploadSettings(lu *logupload.LogUploadSettings, app string) *xwhtt
 	}
 
 	lu.Updated = util.GetTimestamp(time.Now().UTC())
-	if err := ds.GetCachedSimpleDao().SetOne(ds.TABLE_LOG_UPLOAD_SETTINGS, lu.ID, lu); err != nil {
+	if dryRun != nil {
+		dryRun.Record(xhttp.DRY_RUN_CREATE, ds.TABLE_LOG_UPLOAD_SETTINGS, lu.ID, lu)
+	} else if err := ds.GetCachedSimpleDao().SetOne(ds.TABLE_LOG_UPLOAD_SETTINGS, lu.ID, lu); err != nil {
 		return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
 	}
 
 	return xwhttp.NewResponseEntity(http.StatusCreated, nil, lu)
 }
 
-func UpdateLogUploadSettings(lu *logupload.LogUploadSettings, app string) *xwhttp.ResponseEntity {
+func UpdateLogUploadSettings(lu *logupload.LogUploadSettings, app string, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
 	if util.IsBlank(lu.ID) {
 		return xwhttp.NewResponseEntity(http.StatusBadRequest, errors.New("ID is empty"), nil)
 	}
@@ -193,7 +196,9 @@ func UpdateLogUploadSettings(lu *logupload.LogUploadSettings, app string) *xwhtt
 	}
 
 	lu.Updated = util.GetTimestamp(time.Now().UTC())
-	if err := ds.GetCachedSimpleDao().SetOne(ds.TABLE_LOG_UPLOAD_SETTINGS, lu.ID, lu); err != nil {
+	if dryRun != nil {
+		dryRun.Record(xhttp.DRY_RUN_UPDATE, ds.TABLE_LOG_UPLOAD_SETTINGS, lu.ID, lu)
+	} else if err := ds.GetCachedSimpleDao().SetOne(ds.TABLE_LOG_UPLOAD_SETTINGS, lu.ID, lu); err != nil {
 		return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
 	}
 
@@ -161,7 +161,7 @@ func CreateVodSettingsHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	respEntity := CreateVodSettings(&newvs, applicationType)
+	respEntity := CreateVodSettings(&newvs, applicationType, nil)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
@@ -195,7 +195,7 @@ func UpdateVodSettingsHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	respEntity := UpdateVodSettings(&newvsrule, applicationType)
+	respEntity := UpdateVodSettings(&newvsrule, applicationType, nil)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
@@ -28,6 +28,7 @@ import (
 	"time"
 
 	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
 	xutil "xconfadmin/util"
 	xwcommon "xconfwebconfig/common"
 	"xconfwebconfig/db"
@@ -164,7 +165,7 @@ func VodSettingsValidate(vs *logupload.VodSettings) *xwhttp.ResponseEntity {
 	return xwhttp.NewResponseEntity(http.StatusCreated, nil, nil)
 }
 
-func CreateVodSettings(vs *logupload.VodSettings, app string) *xwhttp.ResponseEntity {
+func CreateVodSettings(vs *logupload.VodSettings, app string, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
 	if existingSettings := logupload.GetOneVodSettings(vs.ID); existingSettings != nil {
 		return xwhttp.NewResponseEntity(http.StatusConflict, errors.New(fmt.Sprintf("Entity with id %s already exists", vs.ID)), nil)
 	}
@@ -179,14 +180,16 @@ func CreateVodSettings(vs *logupload.VodSettings, app string) *xwhttp.ResponseEn
 	}
 
 	vs.Updated = xwutil.GetTimestamp(time.Now().UTC())
-	if err := db.GetCachedSimpleDao().SetOne(db.TABLE_VOD_SETTINGS, vs.ID, vs); err != nil {
+	if dryRun != nil {
+		dryRun.Record(xhttp.DRY_RUN_CREATE, db.TABLE_VOD_SETTINGS, vs.ID, vs)
+	} else if err := db.GetCachedSimpleDao().SetOne(db.TABLE_VOD_SETTINGS, vs.ID, vs); err != nil {
 		return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
 	}
 
 	return xwhttp.NewResponseEntity(http.StatusCreated, nil, vs)
 }
 
-func UpdateVodSettings(vs *logupload.VodSettings, app string) *xwhttp.ResponseEntity {
+func UpdateVodSettings(vs *logupload.VodSettings, app string, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
 	if xwutil.IsBlank(vs.ID) {
 		return xwhttp.NewResponseEntity(http.StatusBadRequest, errors.New("ID is empty"), nil)
 	}
@@ -202,7 +205,9 @@ func UpdateVodSettings(vs *logupload.VodSettings, app string) *xwhttp.ResponseEn
 	}
 
 	vs.Updated = xwutil.GetTimestamp(time.Now().UTC())
-	if err := db.GetCachedSimpleDao().SetOne(db.TABLE_VOD_SETTINGS, vs.ID, vs); err != nil {
+	if dryRun != nil {
+		dryRun.Record(xhttp.DRY_RUN_UPDATE, db.TABLE_VOD_SETTINGS, vs.ID, vs)
+	} else if err := db.GetCachedSimpleDao().SetOne(db.TABLE_VOD_SETTINGS, vs.ID, vs); err != nil {
 		return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
 	}
 
@@ -0,0 +1,52 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package adminapi
+
+import (
+	"net/http"
+
+	xhttp "xconfadmin/http"
+	"xconfwebconfig/util"
+
+	"github.com/gorilla/mux"
+)
+
+// routes whose every write handler honors the dryRun param
+var dryRunRoutes = []string{
+	"Clone",
+	"DCM-Formulas",
+	"Firmware-Configs",
+	"Firmware-PercentFilter",
+	"Firmware-Rules",
+	"Firmware-Templates",
+	"NameSpaced-Lists",
+	"RFC-FeatureRules",
+	"Settings-Rules",
+}
+
+// DryRunMiddleware rejects a dry run of a write which would otherwise be saved
+func DryRunMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := mux.CurrentRoute(r)
+		if route == nil || !xhttp.IsDryRun(r) || getAuditOperation(r.Method, route) == "" || util.Contains(dryRunRoutes, route.GetName()) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, xhttp.DRY_RUN+" is not supported by "+r.Method+" "+r.URL.Path)
+	})
+}
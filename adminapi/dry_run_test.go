@@ -0,0 +1,98 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package adminapi
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	xhttp "xconfadmin/http"
+	xshared "xconfadmin/shared"
+	"xconfadmin/testutil"
+	xwhttp "xconfwebconfig/http"
+
+	"github.com/gorilla/mux"
+	"gotest.tools/assert"
+)
+
+func newDryRunTestRouter() *mux.Router {
+	// the handler honors a dry run like the services do, unless the query asks it not to
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if dryRun := xhttp.NewDryRun(r); dryRun != nil && r.URL.Query().Get("ignored") == "" {
+			xhttp.WriteDryRunResponse(w, r, dryRun)
+			return
+		}
+		xwhttp.WriteXconfResponse(w, http.StatusOK, []byte(`{"id":"dry-run-test"}`))
+	}
+	r := mux.NewRouter()
+	r.Use(DryRunMiddleware)
+	r.Use(AuditMiddleware)
+	r.HandleFunc("/xconfAdminService/firmwareconfig", handler).Methods("POST", "GET").Name("Firmware-Configs")
+	r.HandleFunc("/xconfAdminService/firmwareconfig/filtered", handler).Methods("POST").Name("Firmware-Configs")
+	r.HandleFunc("/xconfAdminService/telemetry/profile", handler).Methods("POST", "GET").Name("Telemetry-Profiles")
+	return r
+}
+
+func serveDryRunTest(router *mux.Router, method string, url string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	xw := xwhttp.NewXResponseWriter(rr)
+	r := httptest.NewRequest(method, url, strings.NewReader(`{"id":"dry-run-test"}`))
+	body, _ := ioutil.ReadAll(r.Body)
+	xw.SetBody(string(body))
+	router.ServeHTTP(xw, r)
+	return rr
+}
+
+func countDryRunTestAudits() int {
+	return len(xshared.GetAuditEntries(&xshared.AuditFilter{EntityID: "dry-run-test"}))
+}
+
+func TestDryRunMiddlewareRejectsUnsupportedWrites(t *testing.T) {
+	testutil.SetupTestDB()
+	router := newDryRunTestRouter()
+
+	rr := serveDryRunTest(router, http.MethodPost, "/xconfAdminService/telemetry/profile?dryRun=true")
+	assert.Equal(t, rr.Code, http.StatusBadRequest)
+	assert.Assert(t, strings.Contains(rr.Body.String(), "dryRun is not supported by POST /xconfAdminService/telemetry/profile"), rr.Body.String())
+
+	// reads, writes without a dry run and the routes which honor it pass
+	assert.Equal(t, serveDryRunTest(router, http.MethodGet, "/xconfAdminService/telemetry/profile?dryRun=true").Code, http.StatusOK)
+	assert.Equal(t, serveDryRunTest(router, http.MethodPost, "/xconfAdminService/telemetry/profile?dryRun=false").Code, http.StatusOK)
+	assert.Equal(t, serveDryRunTest(router, http.MethodPost, "/xconfAdminService/firmwareconfig/filtered?dryRun=true").Code, http.StatusOK)
+	rr = serveDryRunTest(router, http.MethodPost, "/xconfAdminService/firmwareconfig?dryRun=true")
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Equal(t, rr.Header().Get(xhttp.DRY_RUN_HEADER), "true")
+}
+
+func TestAuditSkipsOnlyHonoredDryRun(t *testing.T) {
+	testutil.SetupTestDB()
+	router := newDryRunTestRouter()
+
+	assert.Equal(t, serveDryRunTest(router, http.MethodPost, "/xconfAdminService/firmwareconfig?dryRun=true").Code, http.StatusOK)
+	assert.Equal(t, countDryRunTestAudits(), 0)
+
+	// the handler saved the entity although dryRun was set, so the write is audited
+	assert.Equal(t, serveDryRunTest(router, http.MethodPost, "/xconfAdminService/firmwareconfig?dryRun=true&ignored=true").Code, http.StatusOK)
+	assert.Equal(t, countDryRunTestAudits(), 1)
+
+	assert.Equal(t, serveDryRunTest(router, http.MethodPost, "/xconfAdminService/firmwareconfig").Code, http.StatusOK)
+	assert.Equal(t, countDryRunTestAudits(), 2)
+}
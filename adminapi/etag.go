@@ -0,0 +1,301 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package adminapi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
+	xshared "xconfadmin/shared"
+	xchange "xconfadmin/shared/change"
+	xwhttp "xconfwebconfig/http"
+	"xconfwebconfig/util"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ETAG_HEADER     = "ETag"
+	IF_MATCH_HEADER = "If-Match"
+)
+
+const (
+	entityWriteLockAttempts = 20
+	entityWriteLockDelay    = 50 * time.Millisecond
+)
+
+// The routes below have no read by id, their entities get the ETag from the response of a write:
+// ApprovalPolicies, ApprovalSettings, AppSettings, Ownership-Groups and ScheduledChanges
+
+// ETagMiddleware returns the version of the entity on GET by id and on writes, and rejects updates and deletes
+// of an entity whose version does not match the If-Match header. Updates and deletes with If-Match hold a write
+// lock on their entities from the version check until the handler is done, so concurrent writers can't both pass
+// the check. The lock covers the entities of the request, not the other entities the handler writes with them
+func ETagMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := mux.CurrentRoute(r)
+		xw, ok := w.(*xwhttp.XResponseWriter)
+		if route == nil || !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		tableName := auditedTables[route.GetName()]
+		operation := getAuditOperation(r.Method, route)
+		entityId := getRouteEntityId(r)
+		batch := entityId == "" && strings.HasPrefix(strings.TrimSpace(xw.Body()), "[")
+
+		switch {
+		case tableName == "":
+			next.ServeHTTP(w, r)
+		case r.Method == http.MethodGet && operation == "":
+			if etag := getEntityETag(tableName, entityId); etag != "" {
+				w.Header().Set(ETAG_HEADER, etag)
+			}
+			next.ServeHTTP(w, r)
+		case operation == xshared.AUDIT_CREATE && !batch:
+			serveWithETag(xw, r, next, tableName, getJsonId(xw.Body()))
+		case operation != xshared.AUDIT_UPDATE && operation != xshared.AUDIT_DELETE:
+			next.ServeHTTP(w, r)
+		default:
+			entityIds := []string{entityId}
+			if batch {
+				entityIds = getBatchEntityIds(xw.Body())
+			} else if entityId == "" {
+				entityIds[0] = getJsonId(xw.Body())
+			}
+			ifMatch := r.Header.Get(IF_MATCH_HEADER)
+			if ifMatch != "" {
+				unlock, err := lockEntityWrites(tableName, entityIds)
+				if err != nil {
+					xhttp.AdminError(w, err)
+					return
+				}
+				defer unlock()
+			}
+
+			switch {
+			case batch && ifMatch != "":
+				serveWithVersionConflicts(xw, r, next, tableName)
+			case batch:
+				next.ServeHTTP(w, r)
+			case ifMatch != "" && !matchesETag(ifMatch, getEntityETag(tableName, entityIds[0])):
+				xhttp.WriteAdminErrorResponse(w, http.StatusPreconditionFailed, fmt.Sprintf("Entity %s has been modified, reload it and retry", entityIds[0]))
+			default:
+				serveWithETag(xw, r, next, tableName, entityIds[0])
+			}
+		}
+	})
+}
+
+// lockEntityWrites claims the write lock of every entity and returns the func releasing them,
+// a lock held by another writer is waited for a while before the write is rejected. A database which
+// can't store the lock conditionally writes without it, the version check alone still rejects most stale writes
+func lockEntityWrites(tableName string, entityIds []string) (func(), error) {
+	if !xchange.SupportsClaims() {
+		return func() {}, nil
+	}
+	owner, _ := os.Hostname()
+	locked := []string{}
+	unlock := func() {
+		for _, lockId := range locked {
+			if err := xchange.ReleaseRow(xcommon.TABLE_ENTITY_WRITE_LOCKS, lockId); err != nil {
+				log.Errorf("error releasing the write lock %s: %s", lockId, err.Error())
+			}
+		}
+	}
+	for _, entityId := range entityIds {
+		if entityId == "" || util.Contains(locked, tableName+"_"+entityId) {
+			continue
+		}
+		lockId := tableName + "_" + entityId
+		claimed := false
+		for attempt := 0; attempt < entityWriteLockAttempts && !claimed; attempt++ {
+			if attempt > 0 {
+				time.Sleep(entityWriteLockDelay)
+			}
+			var err error
+			if claimed, err = xchange.ClaimRow(xcommon.TABLE_ENTITY_WRITE_LOCKS, lockId, owner, xchange.EntityWriteLockTtlSeconds); err != nil {
+				unlock()
+				return nil, xcommon.NewXconfError(http.StatusInternalServerError, fmt.Sprintf("Unable to lock entity %s for writing: %s", entityId, err.Error()))
+			}
+		}
+		if !claimed {
+			unlock()
+			return nil, xcommon.NewXconfError(http.StatusConflict, fmt.Sprintf("Entity %s is being modified by another request, retry", entityId))
+		}
+		locked = append(locked, lockId)
+	}
+	return unlock, nil
+}
+
+func getBatchEntityIds(body string) []string {
+	entities := []json.RawMessage{}
+	if err := json.Unmarshal([]byte(body), &entities); err != nil {
+		return nil
+	}
+	entityIds := make([]string, 0, len(entities))
+	for _, entity := range entities {
+		entityIds = append(entityIds, getJsonId(string(entity)))
+	}
+	return entityIds
+}
+
+// serveWithETag adds the version of the entity after the write to the response
+func serveWithETag(xw *xwhttp.XResponseWriter, r *http.Request, next http.Handler, tableName string, entityId string) {
+	buffer := &bufferedResponseWriter{header: xw.Header()}
+	bw := xwhttp.NewXResponseWriter(buffer, xw.StartTime(), xw.Token(), xw.Audit())
+	bw.SetBody(xw.Body())
+	next.ServeHTTP(bw, r)
+
+	status := bw.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	response := buffer.body.Bytes()
+	if status < http.StatusBadRequest {
+		if entityId == "" {
+			entityId = getJsonId(string(response))
+		}
+		if etag := getEntityETag(tableName, entityId); etag != "" {
+			xw.Header().Set(ETAG_HEADER, etag)
+		}
+	}
+	xw.Header().Del("Content-Length")
+	xw.WriteHeader(status)
+	xw.Write(response)
+}
+
+func getRouteEntityId(r *http.Request) string {
+	vars := mux.Vars(r)
+	for _, key := range auditedEntityVars {
+		if id, ok := vars[key]; ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// getEntityETag returns the content hash of the stored entity, empty if the entity does not exist
+func getEntityETag(tableName string, id string) string {
+	snapshot := getAuditSnapshot(tableName, id)
+	if snapshot == nil {
+		return ""
+	}
+	return fmt.Sprintf(`"%x"`, sha256.Sum256(snapshot))
+}
+
+// matchesETag checks the ETag against the comma separated If-Match value, * matches any existing entity
+func matchesETag(ifMatch string, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	for _, value := range strings.Split(ifMatch, ",") {
+		value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+		if value == "*" || value == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// serveWithVersionConflicts removes the entities whose version is not listed in If-Match from a batch update
+// and adds them to the per entity result of the handler
+func serveWithVersionConflicts(xw *xwhttp.XResponseWriter, r *http.Request, next http.Handler, tableName string) {
+	entities := []json.RawMessage{}
+	if err := json.Unmarshal([]byte(xw.Body()), &entities); err != nil {
+		next.ServeHTTP(xw, r)
+		return
+	}
+	ifMatch := r.Header.Get(IF_MATCH_HEADER)
+	accepted := []json.RawMessage{}
+	conflicts := map[string]xhttp.EntityMessage{}
+	for _, entity := range entities {
+		id := getJsonId(string(entity))
+		if etag := getEntityETag(tableName, id); etag != "" && !matchesETag(ifMatch, etag) {
+			conflicts[id] = xhttp.EntityMessage{
+				Status:  xcommon.ENTITY_STATUS_FAILURE,
+				Message: "Version conflict, entity " + id + " has been modified",
+			}
+			continue
+		}
+		accepted = append(accepted, entity)
+	}
+	if len(conflicts) == 0 {
+		next.ServeHTTP(xw, r)
+		return
+	}
+	acceptedBytes, err := json.Marshal(accepted)
+	if err != nil {
+		next.ServeHTTP(xw, r)
+		return
+	}
+
+	buffer := &bufferedResponseWriter{header: xw.Header()}
+	bw := xwhttp.NewXResponseWriter(buffer, xw.StartTime(), xw.Token(), xw.Audit())
+	bw.SetBody(string(acceptedBytes))
+	next.ServeHTTP(bw, r)
+
+	status := bw.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	response := buffer.body.Bytes()
+	if status == http.StatusOK {
+		results := map[string]xhttp.EntityMessage{}
+		if err := json.Unmarshal(response, &results); err == nil {
+			for id, conflict := range conflicts {
+				results[id] = conflict
+			}
+			if merged, err := json.Marshal(results); err == nil {
+				response = merged
+			}
+		}
+	}
+	log.Infof("version conflicts in batch update of %s: %d", tableName, len(conflicts))
+	xw.Header().Del("Content-Length")
+	xw.WriteHeader(status)
+	xw.Write(response)
+}
+
+// bufferedResponseWriter keeps the response of a handler so the middleware can amend it
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
@@ -0,0 +1,180 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package adminapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
+	xchange "xconfadmin/shared/change"
+	"xconfadmin/testutil"
+	ds "xconfwebconfig/db"
+	xwhttp "xconfwebconfig/http"
+	"xconfwebconfig/shared"
+
+	"github.com/gorilla/mux"
+	"gotest.tools/assert"
+)
+
+func newETagTestRouter(t *testing.T) *mux.Router {
+	save := func(r *http.Request, model *shared.Model) {
+		// a conditional write happens while the middleware holds the write lock of the entity
+		if r.Header.Get(IF_MATCH_HEADER) != "" && xchange.SupportsClaims() {
+			claimed, err := xchange.ClaimRow(xcommon.TABLE_ENTITY_WRITE_LOCKS, ds.TABLE_MODEL+"_"+model.ID, "test", 1)
+			assert.NilError(t, err)
+			assert.Assert(t, !claimed, "%s is written without the write lock", model.ID)
+		}
+		assert.NilError(t, ds.GetCachedSimpleDao().SetOne(ds.TABLE_MODEL, model.ID, model))
+	}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		xw := w.(*xwhttp.XResponseWriter)
+		model := &shared.Model{}
+		json.Unmarshal([]byte(xw.Body()), model)
+		save(r, model)
+		xwhttp.WriteXconfResponse(w, http.StatusOK, []byte(xw.Body()))
+	}
+	batchHandler := func(w http.ResponseWriter, r *http.Request) {
+		xw := w.(*xwhttp.XResponseWriter)
+		models := []*shared.Model{}
+		json.Unmarshal([]byte(xw.Body()), &models)
+		results := map[string]xhttp.EntityMessage{}
+		for _, model := range models {
+			save(r, model)
+			results[model.ID] = xhttp.EntityMessage{Status: xcommon.ENTITY_STATUS_SUCCESS, Message: model.ID}
+		}
+		response, _ := json.Marshal(results)
+		xwhttp.WriteXconfResponse(w, http.StatusOK, response)
+	}
+	getHandler := func(w http.ResponseWriter, r *http.Request) {
+		xwhttp.WriteXconfResponse(w, http.StatusOK, []byte("{}"))
+	}
+	r := mux.NewRouter()
+	r.Use(ETagMiddleware)
+	r.HandleFunc("/xconfAdminService/model", handler).Methods("POST", "PUT").Name("Models")
+	r.HandleFunc("/xconfAdminService/model/entities", batchHandler).Methods("PUT").Name("Models")
+	r.HandleFunc("/xconfAdminService/model/{id}", getHandler).Methods("GET").Name("Models")
+	return r
+}
+
+func serveETagTest(router *mux.Router, method string, url string, body string, ifMatch string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	xw := xwhttp.NewXResponseWriter(rr)
+	r := httptest.NewRequest(method, url, strings.NewReader(body))
+	if ifMatch != "" {
+		r.Header.Set(IF_MATCH_HEADER, ifMatch)
+	}
+	b, _ := ioutil.ReadAll(r.Body)
+	xw.SetBody(string(b))
+	router.ServeHTTP(xw, r)
+	return rr
+}
+
+func TestETagIsReturnedByReadsAndWrites(t *testing.T) {
+	testutil.SetupTestDB()
+	router := newETagTestRouter(t)
+
+	rr := serveETagTest(router, http.MethodPost, "/xconfAdminService/model", `{"id":"MODEL1","description":"first"}`, "")
+	assert.Equal(t, rr.Code, http.StatusOK)
+	created := rr.Header().Get(ETAG_HEADER)
+	assert.Assert(t, created != "")
+
+	rr = serveETagTest(router, http.MethodGet, "/xconfAdminService/model/MODEL1", "", "")
+	assert.Equal(t, rr.Header().Get(ETAG_HEADER), created)
+
+	rr = serveETagTest(router, http.MethodPut, "/xconfAdminService/model", `{"id":"MODEL1","description":"second"}`, created)
+	assert.Equal(t, rr.Code, http.StatusOK)
+	updated := rr.Header().Get(ETAG_HEADER)
+	assert.Assert(t, updated != "" && updated != created)
+
+	// the first version is stale now
+	rr = serveETagTest(router, http.MethodPut, "/xconfAdminService/model", `{"id":"MODEL1","description":"third"}`, created)
+	assert.Equal(t, rr.Code, http.StatusPreconditionFailed)
+	rr = serveETagTest(router, http.MethodPut, "/xconfAdminService/model", `{"id":"MODEL1","description":"third"}`, "*")
+	assert.Equal(t, rr.Code, http.StatusOK)
+	rr = serveETagTest(router, http.MethodPut, "/xconfAdminService/model", `{"id":"MODEL2","description":"missing"}`, "*")
+	assert.Equal(t, rr.Code, http.StatusPreconditionFailed)
+}
+
+func TestETagWriteWaitsForTheWriteLock(t *testing.T) {
+	testutil.SetupTestDB()
+	router := newETagTestRouter(t)
+	assert.Equal(t, serveETagTest(router, http.MethodPost, "/xconfAdminService/model", `{"id":"MODEL1"}`, "").Code, http.StatusOK)
+
+	lockId := ds.TABLE_MODEL + "_MODEL1"
+	claimed, err := xchange.ClaimRow(xcommon.TABLE_ENTITY_WRITE_LOCKS, lockId, "other", xchange.EntityWriteLockTtlSeconds)
+	assert.NilError(t, err)
+	assert.Assert(t, claimed)
+	rr := serveETagTest(router, http.MethodPut, "/xconfAdminService/model", `{"id":"MODEL1","description":"locked"}`, "*")
+	assert.Equal(t, rr.Code, http.StatusConflict)
+	assert.Assert(t, strings.Contains(rr.Body.String(), "Entity MODEL1 is being modified by another request"), rr.Body.String())
+	// a write without If-Match does not wait for the lock
+	rr = serveETagTest(router, http.MethodPut, "/xconfAdminService/model", `{"id":"MODEL1","description":"unconditional"}`, "")
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	assert.NilError(t, xchange.ReleaseRow(xcommon.TABLE_ENTITY_WRITE_LOCKS, lockId))
+	rr = serveETagTest(router, http.MethodPut, "/xconfAdminService/model", `{"id":"MODEL1","description":"unlocked"}`, "*")
+	assert.Equal(t, rr.Code, http.StatusOK)
+	// the middleware released its lock
+	claimed, err = xchange.ClaimRow(xcommon.TABLE_ENTITY_WRITE_LOCKS, lockId, "other", xchange.EntityWriteLockTtlSeconds)
+	assert.NilError(t, err)
+	assert.Assert(t, claimed)
+}
+
+func TestETagBatchUpdateReportsVersionConflicts(t *testing.T) {
+	testutil.SetupTestDB()
+	router := newETagTestRouter(t)
+	etag1 := serveETagTest(router, http.MethodPost, "/xconfAdminService/model", `{"id":"MODEL1"}`, "").Header().Get(ETAG_HEADER)
+	serveETagTest(router, http.MethodPost, "/xconfAdminService/model", `{"id":"MODEL2"}`, "")
+	serveETagTest(router, http.MethodPut, "/xconfAdminService/model", `{"id":"MODEL2","description":"changed"}`, "")
+
+	rr := serveETagTest(router, http.MethodPut, "/xconfAdminService/model/entities", `[{"id":"MODEL1","description":"batch"},{"id":"MODEL2","description":"batch"}]`, etag1)
+	assert.Equal(t, rr.Code, http.StatusOK)
+	results := map[string]xhttp.EntityMessage{}
+	assert.NilError(t, json.Unmarshal(rr.Body.Bytes(), &results))
+	assert.Equal(t, results["MODEL1"].Status, xcommon.ENTITY_STATUS_SUCCESS)
+	assert.Equal(t, results["MODEL2"].Status, xcommon.ENTITY_STATUS_FAILURE)
+	assert.Equal(t, results["MODEL2"].Message, "Version conflict, entity MODEL2 has been modified")
+
+	model, err := ds.GetCachedSimpleDao().GetOne(ds.TABLE_MODEL, "MODEL2")
+	assert.NilError(t, err)
+	assert.Equal(t, model.(*shared.Model).Description, "changed")
+}
+
+// nonConditionalDatabaseClient hides the conditional writes of the memory database
+type nonConditionalDatabaseClient struct {
+	ds.DatabaseClient
+}
+
+func TestETagWriteWithoutConditionalDatabase(t *testing.T) {
+	client := testutil.SetupTestDB()
+	ds.SetDatabaseClient(nonConditionalDatabaseClient{client})
+	defer ds.SetDatabaseClient(client)
+	router := newETagTestRouter(t)
+
+	etag := serveETagTest(router, http.MethodPost, "/xconfAdminService/model", `{"id":"MODEL1"}`, "").Header().Get(ETAG_HEADER)
+	rr := serveETagTest(router, http.MethodPut, "/xconfAdminService/model", `{"id":"MODEL1","description":"changed"}`, etag)
+	assert.Equal(t, rr.Code, http.StatusOK)
+	rr = serveETagTest(router, http.MethodPut, "/xconfAdminService/model", `{"id":"MODEL1","description":"stale"}`, etag)
+	assert.Equal(t, rr.Code, http.StatusPreconditionFailed)
+}
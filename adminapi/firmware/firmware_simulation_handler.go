@@ -0,0 +1,189 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package firmware
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"xconfadmin/adminapi/auth"
+	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
+	xcorefw "xconfadmin/shared/firmware"
+	xwcommon "xconfwebconfig/common"
+	xwhttp "xconfwebconfig/http"
+
+	"github.com/gorilla/mux"
+)
+
+var simulationResultCsvHeader = []string{
+	"matchedRuleId",
+	"matchedRuleName",
+	"matchedRuleType",
+	"resultFirmwareVersion",
+	"blocked",
+	"blockingFilter",
+	"description",
+	"error",
+}
+
+// PostFirmwareSimulationHandler evaluates a JSON or CSV list of device contexts, a batch up to the sync limit
+// is evaluated in the request, a larger one runs in the background and is returned with 202
+func PostFirmwareSimulationHandler(w http.ResponseWriter, r *http.Request) {
+	applicationType, err := auth.CanRead(r, auth.FIRMWARE_ENTITY)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xw, ok := w.(*xwhttp.XResponseWriter)
+	if !ok {
+		xhttp.AdminError(w, xcommon.NewXconfError(http.StatusInternalServerError, "responsewriter cast error"))
+		return
+	}
+	contexts, err := parseDeviceContexts(xw.Body())
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	job, err := newFirmwareSimulationJob(applicationType, len(contexts), auth.GetUserNameOrUnknown(r))
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if len(contexts) <= xcommon.FirmwareSimulationSyncLimit {
+		runFirmwareSimulation(job, contexts)
+	} else {
+		status = http.StatusAccepted
+	}
+	res, err := xhttp.ReturnJsonResponse(job, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	if status == http.StatusAccepted {
+		go runFirmwareSimulation(job, contexts)
+	}
+	xwhttp.WriteXconfResponse(w, status, res)
+}
+
+func GetFirmwareSimulationsHandler(w http.ResponseWriter, r *http.Request) {
+	applicationType, err := auth.CanRead(r, auth.FIRMWARE_ENTITY)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	jobs := xcorefw.GetFirmwareSimulationJobs(applicationType)
+	now := time.Now()
+	for _, job := range jobs {
+		failStaleFirmwareSimulation(job, now)
+	}
+	res, err := xhttp.ReturnJsonResponse(jobs, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
+// GetFirmwareSimulationByIdHandler returns the progress and the summary of the job
+func GetFirmwareSimulationByIdHandler(w http.ResponseWriter, r *http.Request) {
+	job, err := getFirmwareSimulationJob(r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	res, err := xhttp.ReturnJsonResponse(job, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
+// GetFirmwareSimulationResultsHandler downloads the device results of a finished job, as CSV with format=csv
+func GetFirmwareSimulationResultsHandler(w http.ResponseWriter, r *http.Request) {
+	job, err := getFirmwareSimulationJob(r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	if job.Status == xcorefw.FIRMWARE_SIMULATION_RUNNING {
+		xhttp.WriteAdminErrorResponse(w, http.StatusConflict, fmt.Sprintf("Firmware simulation is running, %d of %d devices processed", job.Processed, job.Total))
+		return
+	}
+	results := xcorefw.GetFirmwareSimulationResults(job.ID)
+	fileName := "firmware_simulation_" + job.ID
+
+	if r.URL.Query().Get("format") != "csv" {
+		res, err := xhttp.ReturnJsonResponse(results, r)
+		if err != nil {
+			xhttp.AdminError(w, err)
+			return
+		}
+		xwhttp.WriteXconfResponseWithHeaders(w, xhttp.CreateContentDispositionHeader(fileName), http.StatusOK, res)
+		return
+	}
+
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	writer.Write(append(append([]string{"index"}, simulationContextColumns...), simulationResultCsvHeader...))
+	for _, result := range results {
+		record := []string{strconv.Itoa(result.Index)}
+		for _, column := range simulationContextColumns {
+			record = append(record, result.Context[column])
+		}
+		record = append(record,
+			result.MatchedRuleID,
+			result.MatchedRuleName,
+			result.MatchedRuleType,
+			result.FirmwareVersion,
+			strconv.FormatBool(result.Blocked),
+			result.BlockingFilter,
+			result.Description,
+			result.Error,
+		)
+		writer.Write(record)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.csv", fileName))
+	xwhttp.WriteResponseBytes(w, buffer.Bytes(), http.StatusOK, "text/csv")
+}
+
+func getFirmwareSimulationJob(r *http.Request) (*xcorefw.FirmwareSimulationJob, error) {
+	applicationType, err := auth.CanRead(r, auth.FIRMWARE_ENTITY)
+	if err != nil {
+		return nil, err
+	}
+	job := xcorefw.GetFirmwareSimulationJob(mux.Vars(r)[xwcommon.ID])
+	if job == nil || job.ApplicationType != applicationType {
+		return nil, xcommon.NewXconfError(http.StatusNotFound, "Firmware simulation does not exist")
+	}
+	failStaleFirmwareSimulation(job, time.Now())
+	return job, nil
+}
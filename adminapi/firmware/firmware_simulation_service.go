@@ -0,0 +1,286 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package firmware
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	xcommon "xconfadmin/common"
+	xshared "xconfadmin/shared"
+	xcorefw "xconfadmin/shared/firmware"
+	xwcommon "xconfwebconfig/common"
+	ef "xconfwebconfig/dataapi/estbfirmware"
+	coreef "xconfwebconfig/shared/estbfirmware"
+	corefw "xconfwebconfig/shared/firmware"
+	"xconfwebconfig/util"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// the job state is saved after this many devices so the progress can be followed
+const firmwareSimulationProgressInterval = 500
+
+// a running job saves its state at least every heartbeat, one which was not saved for firmwareSimulationStaleAfter
+// is not run by any admin instance anymore
+const (
+	firmwareSimulationHeartbeat   = 30 * time.Second
+	firmwareSimulationStaleAfter  = 10 * time.Minute
+	firmwareSimulationInterrupted = "Firmware simulation was interrupted by a restart of the admin service, run it again"
+)
+
+// short column names of the uploaded device contexts, other names are taken as context keys
+var simulationContextAliases = map[string]string{
+	"mac":     xwcommon.ESTB_MAC,
+	"estbmac": xwcommon.ESTB_MAC,
+	"ip":      xwcommon.IP_ADDRESS,
+}
+
+// simulationContextColumns are the context keys written to the result file
+var simulationContextColumns = []string{
+	xwcommon.ESTB_MAC,
+	xwcommon.MODEL,
+	xwcommon.ENV,
+	xwcommon.FIRMWARE_VERSION,
+	xwcommon.IP_ADDRESS,
+	xwcommon.PARTNER_ID,
+	xwcommon.TIME,
+}
+
+// parseDeviceContexts reads a JSON array of device contexts or a CSV file with a header row
+func parseDeviceContexts(body string) ([]map[string]string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, xcommon.NewXconfError(http.StatusBadRequest, "Device list is empty")
+	}
+	var contexts []map[string]string
+	var err error
+	if strings.HasPrefix(body, "[") {
+		contexts, err = parseJsonDeviceContexts(body)
+	} else {
+		contexts, err = parseCsvDeviceContexts(body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(contexts) == 0 {
+		return nil, xcommon.NewXconfError(http.StatusBadRequest, "Device list is empty")
+	}
+	if len(contexts) > xcommon.FirmwareSimulationMaxDevices {
+		return nil, xcommon.NewXconfError(http.StatusBadRequest, fmt.Sprintf("Device list has %d devices, the limit is %d", len(contexts), xcommon.FirmwareSimulationMaxDevices))
+	}
+	return contexts, nil
+}
+
+func parseJsonDeviceContexts(body string) ([]map[string]string, error) {
+	devices := []map[string]interface{}{}
+	if err := json.Unmarshal([]byte(body), &devices); err != nil {
+		return nil, xcommon.NewXconfError(http.StatusBadRequest, "Unable to parse the device list: "+err.Error())
+	}
+	contexts := make([]map[string]string, 0, len(devices))
+	for _, device := range devices {
+		context := map[string]string{}
+		for k, v := range device {
+			if v != nil {
+				putSimulationContextValue(context, k, fmt.Sprintf("%v", v))
+			}
+		}
+		contexts = append(contexts, context)
+	}
+	return contexts, nil
+}
+
+func parseCsvDeviceContexts(body string) ([]map[string]string, error) {
+	reader := csv.NewReader(strings.NewReader(body))
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, xcommon.NewXconfError(http.StatusBadRequest, "Unable to parse the device list: "+err.Error())
+	}
+	contexts := []map[string]string{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, xcommon.NewXconfError(http.StatusBadRequest, "Unable to parse the device list: "+err.Error())
+		}
+		context := map[string]string{}
+		for i, name := range header {
+			putSimulationContextValue(context, name, record[i])
+		}
+		contexts = append(contexts, context)
+	}
+	return contexts, nil
+}
+
+func putSimulationContextValue(context map[string]string, name string, value string) {
+	name = strings.TrimSpace(name)
+	value = strings.TrimSpace(value)
+	if name == "" || value == "" {
+		return
+	}
+	if key, ok := simulationContextAliases[strings.ToLower(name)]; ok {
+		name = key
+	}
+	context[name] = value
+}
+
+// newFirmwareSimulationJob saves a running job for the device contexts
+func newFirmwareSimulationJob(applicationType string, total int, userName string) (*xcorefw.FirmwareSimulationJob, error) {
+	owner, _ := os.Hostname()
+	job := &xcorefw.FirmwareSimulationJob{
+		ID:              uuid.New().String(),
+		ApplicationType: applicationType,
+		Status:          xcorefw.FIRMWARE_SIMULATION_RUNNING,
+		Total:           total,
+		Summary:         xcorefw.NewFirmwareSimulationSummary(),
+		Owner:           owner,
+		CreatedBy:       userName,
+		Created:         util.GetTimestamp(time.Now().UTC()),
+	}
+	if err := xcorefw.SetFirmwareSimulationJob(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// runFirmwareSimulation evaluates every device context, saves its result and completes the job with the summary
+func runFirmwareSimulation(job *xcorefw.FirmwareSimulationJob, contexts []map[string]string) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("firmware simulation %s failed: %v", job.ID, r)
+			completeFirmwareSimulation(job, xcorefw.FIRMWARE_SIMULATION_FAILED, fmt.Sprintf("%v", r))
+		}
+	}()
+
+	ruleBase := ef.NewEstbFirmwareRuleBaseDefault()
+	saved := time.Now()
+	for i, context := range contexts {
+		result := evaluateSimulationDevice(ruleBase, job, i, context)
+		job.Summary.Add(result)
+		if err := xcorefw.SetFirmwareSimulationResult(result); err != nil {
+			log.Errorf("unable to save result %d of firmware simulation %s: %s", i, job.ID, err.Error())
+			completeFirmwareSimulation(job, xcorefw.FIRMWARE_SIMULATION_FAILED, "Unable to save the device results")
+			return
+		}
+		job.Processed = i + 1
+		if (job.Processed%firmwareSimulationProgressInterval == 0 || time.Since(saved) >= firmwareSimulationHeartbeat) && job.Processed < job.Total {
+			if err := xcorefw.SetFirmwareSimulationJob(job); err != nil {
+				log.Warnf("unable to save progress of firmware simulation %s: %s", job.ID, err.Error())
+			}
+			saved = time.Now()
+		}
+	}
+	completeFirmwareSimulation(job, xcorefw.FIRMWARE_SIMULATION_COMPLETED, "")
+}
+
+func completeFirmwareSimulation(job *xcorefw.FirmwareSimulationJob, status string, message string) {
+	job.Status = status
+	job.Message = message
+	if err := xcorefw.SetFirmwareSimulationJob(job); err != nil {
+		log.Errorf("unable to save firmware simulation %s: %s", job.ID, err.Error())
+		return
+	}
+	log.Infof("firmware simulation %s of %d devices %s", job.ID, job.Total, status)
+}
+
+// FailInterruptedFirmwareSimulations fails the running jobs left by an earlier run of this admin instance
+// and the ones of any instance which stopped saving their state, it is called when the service starts
+func FailInterruptedFirmwareSimulations(now time.Time) int {
+	owner, _ := os.Hostname()
+	failed := 0
+	for _, job := range xcorefw.GetAllFirmwareSimulationJobs() {
+		if job.Status == xcorefw.FIRMWARE_SIMULATION_RUNNING && (job.Owner == owner || isStaleFirmwareSimulation(job, now)) {
+			completeFirmwareSimulation(job, xcorefw.FIRMWARE_SIMULATION_FAILED, firmwareSimulationInterrupted)
+			failed++
+		}
+	}
+	return failed
+}
+
+// failStaleFirmwareSimulation fails a running job which stopped saving its state, so it is not shown as running forever
+func failStaleFirmwareSimulation(job *xcorefw.FirmwareSimulationJob, now time.Time) {
+	if job.Status == xcorefw.FIRMWARE_SIMULATION_RUNNING && isStaleFirmwareSimulation(job, now) {
+		completeFirmwareSimulation(job, xcorefw.FIRMWARE_SIMULATION_FAILED, firmwareSimulationInterrupted)
+	}
+}
+
+func isStaleFirmwareSimulation(job *xcorefw.FirmwareSimulationJob, now time.Time) bool {
+	return util.GetTimestamp(now.UTC())-job.Updated > firmwareSimulationStaleAfter.Milliseconds()
+}
+
+// evaluateSimulationDevice runs the device context through the firmware rules the same way as the test page,
+// a blocked device gets no firmware version
+func evaluateSimulationDevice(ruleBase *ef.EstbFirmwareRuleBase, job *xcorefw.FirmwareSimulationJob, index int, context map[string]string) *xcorefw.FirmwareSimulationResult {
+	result := &xcorefw.FirmwareSimulationResult{
+		JobID:   job.ID,
+		Index:   index,
+		Context: context,
+	}
+	if err := xshared.NormalizeCommonContext(context, xwcommon.ESTB_MAC, xwcommon.ECM_MAC); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if err := validateTestPageContext(context); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	// the rule base may add evaluation keys to the context, the result keeps the device values only
+	result.Context = make(map[string]string, len(context))
+	for k, v := range context {
+		result.Context[k] = v
+	}
+
+	eval, err := ruleBase.Eval(context, coreef.GetContextConverted(context), job.ApplicationType, log.Fields{})
+	if err != nil {
+		result.Error = fmt.Sprintf("Rule Evaluation Error: %v", err)
+		return result
+	}
+	result.Description = eval.Description
+	if eval.MatchedRule != nil {
+		result.MatchedRuleID = eval.MatchedRule.ID
+		result.MatchedRuleName = eval.MatchedRule.Name
+		result.MatchedRuleType = eval.MatchedRule.Type
+	}
+	if eval.Blocked {
+		result.Blocked = true
+		result.BlockingFilter = getBlockingFilterName(eval)
+	} else if eval.FirmwareConfig != nil {
+		result.FirmwareVersion = eval.FirmwareConfig.GetFirmwareVersion()
+	}
+	return result
+}
+
+// getBlockingFilterName returns the name of the blocking filter rule, otherwise the reason of the block
+func getBlockingFilterName(eval *ef.EvaluationResult) string {
+	if n := len(eval.AppliedFilters); n > 0 {
+		if rule, ok := eval.AppliedFilters[n-1].(*corefw.FirmwareRule); ok && rule.ApplicableAction != nil && rule.ApplicableAction.ActionType == corefw.BLOCKING_FILTER_TEMPLATE {
+			return rule.Name
+		}
+	}
+	return eval.Description
+}
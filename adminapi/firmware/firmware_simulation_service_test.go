@@ -0,0 +1,94 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package firmware
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	xcommon "xconfadmin/common"
+	xcorefw "xconfadmin/shared/firmware"
+	"xconfadmin/testutil"
+
+	"gotest.tools/assert"
+)
+
+func saveTestFirmwareSimulation(t *testing.T, id string, status string, owner string) {
+	job := &xcorefw.FirmwareSimulationJob{
+		ID:              id,
+		ApplicationType: "stb",
+		Status:          status,
+		Total:           1000,
+		Summary:         xcorefw.NewFirmwareSimulationSummary(),
+		Owner:           owner,
+	}
+	assert.NilError(t, xcorefw.SetFirmwareSimulationJob(job))
+}
+
+func TestFailInterruptedFirmwareSimulations(t *testing.T) {
+	testutil.SetupTestDB()
+	hostname, _ := os.Hostname()
+	saveTestFirmwareSimulation(t, "own", xcorefw.FIRMWARE_SIMULATION_RUNNING, hostname)
+	saveTestFirmwareSimulation(t, "other", xcorefw.FIRMWARE_SIMULATION_RUNNING, "other-instance")
+	saveTestFirmwareSimulation(t, "completed", xcorefw.FIRMWARE_SIMULATION_COMPLETED, hostname)
+
+	// the job of the earlier run of this instance is failed right away, the one of another instance is still saving its state
+	assert.Equal(t, FailInterruptedFirmwareSimulations(time.Now()), 1)
+	own := xcorefw.GetFirmwareSimulationJob("own")
+	assert.Equal(t, own.Status, xcorefw.FIRMWARE_SIMULATION_FAILED)
+	assert.Equal(t, own.Message, firmwareSimulationInterrupted)
+	assert.Equal(t, xcorefw.GetFirmwareSimulationJob("other").Status, xcorefw.FIRMWARE_SIMULATION_RUNNING)
+	assert.Equal(t, xcorefw.GetFirmwareSimulationJob("completed").Status, xcorefw.FIRMWARE_SIMULATION_COMPLETED)
+
+	assert.Equal(t, FailInterruptedFirmwareSimulations(time.Now().Add(firmwareSimulationStaleAfter+time.Minute)), 1)
+	assert.Equal(t, xcorefw.GetFirmwareSimulationJob("other").Status, xcorefw.FIRMWARE_SIMULATION_FAILED)
+	assert.Equal(t, xcorefw.GetFirmwareSimulationJob("completed").Status, xcorefw.FIRMWARE_SIMULATION_COMPLETED)
+}
+
+func TestStaleFirmwareSimulationIsFailedWhenRead(t *testing.T) {
+	testutil.SetupTestDB()
+	saveTestFirmwareSimulation(t, "other", xcorefw.FIRMWARE_SIMULATION_RUNNING, "other-instance")
+
+	job := xcorefw.GetFirmwareSimulationJob("other")
+	failStaleFirmwareSimulation(job, time.Now())
+	assert.Equal(t, job.Status, xcorefw.FIRMWARE_SIMULATION_RUNNING)
+
+	failStaleFirmwareSimulation(job, time.Now().Add(firmwareSimulationStaleAfter+time.Minute))
+	assert.Equal(t, job.Status, xcorefw.FIRMWARE_SIMULATION_FAILED)
+	assert.Equal(t, xcorefw.GetFirmwareSimulationJob("other").Status, xcorefw.FIRMWARE_SIMULATION_FAILED)
+}
+
+func TestRunFirmwareSimulationCompletesTheJob(t *testing.T) {
+	testutil.SetupTestDB()
+	xcommon.FirmwareSimulationMaxDevices = 10
+	contexts, err := parseDeviceContexts("mac,model\nAA:BB:CC:DD:EE:01,MODEL1\nAA:BB:CC:DD:EE:02,MODEL2")
+	assert.NilError(t, err)
+	job, err := newFirmwareSimulationJob("stb", len(contexts), testutil.TestUser)
+	assert.NilError(t, err)
+	hostname, _ := os.Hostname()
+	assert.Equal(t, job.Owner, hostname)
+
+	runFirmwareSimulation(job, contexts)
+	job = xcorefw.GetFirmwareSimulationJob(job.ID)
+	assert.Equal(t, job.Status, xcorefw.FIRMWARE_SIMULATION_COMPLETED)
+	assert.Equal(t, job.Processed, 2)
+	assert.Equal(t, len(xcorefw.GetFirmwareSimulationResults(job.ID)), 2)
+	// a completed job of this instance is left as it is on a restart
+	assert.Equal(t, FailInterruptedFirmwareSimulations(time.Now()), 0)
+}
@@ -54,46 +54,35 @@ func writeErrorResponse(w http.ResponseWriter, r *http.Request, errorMsg string,
 	xwhttp.WriteXconfResponse(w, status, response)
 }
 
-func GetFirmwareTestPageHandler(w http.ResponseWriter, r *http.Request) {
-	// Extract the search parameters from query params
-	context := make(map[string]string)
-	xutil.AddQueryParamsToContextMap(r, context)
-
-	if err := xshared.NormalizeCommonContext(context, xwcommon.ESTB_MAC, xwcommon.ECM_MAC); err != nil {
-		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, err.Error())
-		return
-	}
+// If input has any of these search-paramters, validate their values
+var testPageValidators = map[string]ValueValidator{
+	xwcommon.ENV: func(id string) bool {
+		return id != "" && xwshared.GetOneEnvironment(id) != nil
+	},
+	xwcommon.MODEL: func(id string) bool {
+		return id != "" && xwshared.GetOneModel(id) != nil
+	},
+	xwcommon.IP_ADDRESS: func(val string) bool {
+		return xwshared.NewIpAddress(val) != nil
+	},
+	xwcommon.ESTB_MAC: func(val string) bool {
+		ok, _ := util.MACAddressValidator(val)
+		return ok
+	},
+}
 
-	// If input has any of these search-paramters, validate their values
-	searchValidators := map[string]ValueValidator{
-		xwcommon.ENV: func(id string) bool {
-			return id != "" && xwshared.GetOneEnvironment(id) != nil
-		},
-		xwcommon.MODEL: func(id string) bool {
-			return id != "" && xwshared.GetOneModel(id) != nil
-		},
-		xwcommon.IP_ADDRESS: func(val string) bool {
-			return xwshared.NewIpAddress(val) != nil
-		},
-		xwcommon.ESTB_MAC: func(val string) bool {
-			ok, _ := util.MACAddressValidator(val)
-			return ok
-		},
-	}
+// validateTestPageContext checks the values of the device context and sets the default time and ip address
+func validateTestPageContext(context map[string]string) error {
 	for k, v := range context {
-		if validator, ok := searchValidators[k]; ok {
+		if validator, ok := testPageValidators[k]; ok {
 			if validator != nil && !validator(v) {
-				errMsg := "Invalid Value '" + v + "' for " + k
-				log.Error(errMsg)
-				writeErrorResponse(w, r, errMsg, http.StatusBadRequest, "IllegalArgumentException")
-				return
+				return fmt.Errorf("Invalid Value '%s' for %s", v, k)
 			}
 		}
 	}
 
 	if _, ok := context[xwcommon.ESTB_MAC]; !ok {
-		writeErrorResponse(w, r, xwcommon.ESTB_MAC+" cannot be empty", http.StatusBadRequest, "IllegalArgumentException")
-		return
+		return fmt.Errorf("%s cannot be empty", xwcommon.ESTB_MAC)
 	}
 	if _, ok := context[xwcommon.TIME]; !ok {
 		context[xwcommon.TIME] = util.UtcCurrentTimestamp().String()
@@ -102,6 +91,24 @@ func GetFirmwareTestPageHandler(w http.ResponseWriter, r *http.Request) {
 	if _, ok := context[xwcommon.IP_ADDRESS]; !ok {
 		context[xwcommon.IP_ADDRESS] = "1.1.1.1"
 	}
+	return nil
+}
+
+func GetFirmwareTestPageHandler(w http.ResponseWriter, r *http.Request) {
+	// Extract the search parameters from query params
+	context := make(map[string]string)
+	xutil.AddQueryParamsToContextMap(r, context)
+
+	if err := xshared.NormalizeCommonContext(context, xwcommon.ESTB_MAC, xwcommon.ECM_MAC); err != nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := validateTestPageContext(context); err != nil {
+		log.Error(err.Error())
+		writeErrorResponse(w, r, err.Error(), http.StatusBadRequest, "IllegalArgumentException")
+		return
+	}
 
 	// Construct ruleBase
 	ruleBase := ef.NewEstbFirmwareRuleBaseDefault()
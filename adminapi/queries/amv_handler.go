@@ -202,11 +202,15 @@ func CreateAmvHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	lifecycleWarnings := GetAmvLifecycleWarnings(&newAmv, applicationType)
+	versionWarnings := GetAmvVersionWarnings(&newAmv, applicationType)
 	respEntity := CreateAmv(&newAmv, applicationType)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	xhttp.AddWarningHeaders(w, lifecycleWarnings)
+	xhttp.AddWarningHeaders(w, versionWarnings)
 
 	res, err := xhttp.ReturnJsonResponse(respEntity.Data, r)
 	if err != nil {
@@ -271,11 +275,15 @@ func UpdateAmvHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	lifecycleWarnings := GetAmvLifecycleWarnings(&newAmv, applicationType)
+	versionWarnings := GetAmvVersionWarnings(&newAmv, applicationType)
 	respEntity := UpdateAmv(&newAmv, applicationType)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	xhttp.AddWarningHeaders(w, lifecycleWarnings)
+	xhttp.AddWarningHeaders(w, versionWarnings)
 	res, err := xhttp.ReturnJsonResponse(respEntity.Data, r)
 	if err != nil {
 		xhttp.AdminError(w, err)
@@ -187,7 +187,7 @@ func GetSupportedVersionforModel(modelids []string, FirmwareVersions []string, a
 			supportedFwList = append(supportedFwList, k)
 		}
 	}
-
+	sortFirmwareVersions(app, modelids, supportedFwList)
 	return supportedFwList
 }
 
@@ -262,6 +262,9 @@ func CreateAmv(amv *firmware.ActivationVersion, app string) *xwhttp.ResponseEnti
 
 	fwRule := coreef.ConvertIntoRule(amv)
 	ru.NormalizeConditions(&fwRule.Rule)
+	if _, err := validateFirmwareConfigLifecycle(fwRule, app); err != nil {
+		return xwhttp.NewResponseEntity(http.StatusConflict, err, nil)
+	}
 	if err = firmware.CreateFirmwareRuleOneDB(fwRule); err != nil {
 		return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
 	}
@@ -308,6 +311,9 @@ func UpdateAmvImport(amvToImport *firmware.ActivationVersion, amvinDB *firmware.
 	}
 	fwRule := coreef.ConvertIntoRule(amvinDB)
 	ru.NormalizeConditions(&fwRule.Rule)
+	if _, err := validateFirmwareConfigLifecycle(fwRule, amvinDB.ApplicationType); err != nil {
+		return xwhttp.NewResponseEntity(http.StatusConflict, err, nil)
+	}
 	if err := firmware.CreateFirmwareRuleOneDB(fwRule); err != nil {
 		return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
 	}
@@ -0,0 +1,127 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package queries
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	xshared "xconfadmin/shared"
+	xwcommon "xconfwebconfig/common"
+
+	"xconfadmin/adminapi/auth"
+	xhttp "xconfadmin/http"
+	xwhttp "xconfwebconfig/http"
+
+	"github.com/gorilla/mux"
+)
+
+func GetApplicationTypesHandler(w http.ResponseWriter, r *http.Request) {
+	if _, err := auth.CanRead(r, auth.COMMON_ENTITY); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	res, err := xhttp.ReturnJsonResponse(xshared.GetApplicationTypes(), r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
+func GetApplicationTypeByIdHandler(w http.ResponseWriter, r *http.Request) {
+	if _, err := auth.CanRead(r, auth.COMMON_ENTITY); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	id, found := mux.Vars(r)[xwcommon.ID]
+	if !found {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("%v is invalid", xwcommon.ID))
+		return
+	}
+	appType := xshared.GetApplicationType(strings.ToLower(id))
+	if appType == nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusNotFound, "ApplicationType "+id+" does not exist")
+		return
+	}
+	res, err := xhttp.ReturnJsonResponse(appType, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
+func CreateApplicationTypeHandler(w http.ResponseWriter, r *http.Request) {
+	writeApplicationType(w, r, CreateApplicationType)
+}
+
+func UpdateApplicationTypeHandler(w http.ResponseWriter, r *http.Request) {
+	writeApplicationType(w, r, UpdateApplicationType)
+}
+
+func writeApplicationType(w http.ResponseWriter, r *http.Request, save func(*xshared.ApplicationType) *xwhttp.ResponseEntity) {
+	if _, err := auth.CanWrite(r, auth.COMMON_ENTITY); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	// r.Body is already drained in the middleware
+	xw, ok := w.(*xwhttp.XResponseWriter)
+	if !ok {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, "Unable to extract body")
+		return
+	}
+	appType := xshared.ApplicationType{}
+	if err := json.Unmarshal([]byte(xw.Body()), &appType); err != nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respEntity := save(&appType)
+	if respEntity.Error != nil {
+		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
+		return
+	}
+	res, err := xhttp.ReturnJsonResponse(respEntity.Data, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteResponseBytes(w, res, respEntity.Status, xhttp.ContextTypeHeader(r))
+}
+
+func DeleteApplicationTypeHandler(w http.ResponseWriter, r *http.Request) {
+	if _, err := auth.CanWrite(r, auth.COMMON_ENTITY); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	id, found := mux.Vars(r)[xwcommon.ID]
+	if !found {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("%v is invalid", xwcommon.ID))
+		return
+	}
+	respEntity := DeleteApplicationType(strings.ToLower(id))
+	if respEntity.Error != nil {
+		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
+		return
+	}
+	xwhttp.WriteXconfResponse(w, respEntity.Status, nil)
+}
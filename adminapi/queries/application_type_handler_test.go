@@ -0,0 +1,86 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package queries
+
+import (
+	"net/http"
+	"testing"
+
+	"xconfadmin/adminapi/auth"
+	xcommon "xconfadmin/common"
+	xshared "xconfadmin/shared"
+	"xconfadmin/testutil"
+	coreef "xconfwebconfig/shared/estbfirmware"
+
+	"gotest.tools/assert"
+)
+
+func saveTestApplicationType(handler http.HandlerFunc, method string, body string) int {
+	r := testutil.NewRequest(method, "/xconfAdminService/applicationtype", body, auth.WRITE_COMMON)
+	return testutil.Serve(handler, r).Code
+}
+
+func TestApplicationTypeErrorStatus(t *testing.T) {
+	testutil.SetupTestDB()
+	assert.Equal(t, saveTestApplicationType(CreateApplicationTypeHandler, http.MethodPost, `{"id":"gateway"}`), http.StatusCreated)
+	assert.Equal(t, saveTestApplicationType(CreateApplicationTypeHandler, http.MethodPost, `{"id":"gateway"}`), http.StatusConflict)
+	assert.Equal(t, saveTestApplicationType(CreateApplicationTypeHandler, http.MethodPost, `{"id":"G"}`), http.StatusBadRequest)
+	// the permission suffix of another type
+	assert.Equal(t, saveTestApplicationType(CreateApplicationTypeHandler, http.MethodPost, `{"id":"xb","permissionSuffix":"stb"}`), http.StatusConflict)
+	assert.Equal(t, saveTestApplicationType(UpdateApplicationTypeHandler, http.MethodPut, `{"id":"gateway","permissionSuffix":"rdkcloud"}`), http.StatusConflict)
+	assert.Equal(t, saveTestApplicationType(UpdateApplicationTypeHandler, http.MethodPut, `{"id":"unknown"}`), http.StatusNotFound)
+}
+
+func TestBuiltinApplicationTypeIsImmutable(t *testing.T) {
+	testutil.SetupTestDB()
+	assert.Equal(t, saveTestApplicationType(UpdateApplicationTypeHandler, http.MethodPut, `{"id":"stb","permissionSuffix":"settop","defaults":{"firmwareRuleTemplates":true}}`), http.StatusConflict)
+	assert.Equal(t, saveTestApplicationType(UpdateApplicationTypeHandler, http.MethodPut, `{"id":"rdkcloud","defaults":{"globalPercentage":true}}`), http.StatusConflict)
+	assert.Equal(t, saveTestApplicationType(UpdateApplicationTypeHandler, http.MethodPut, `{"id":"rdkcloud","description":"RDK cloud devices"}`), http.StatusOK)
+	rdkcloud := xshared.GetApplicationType("rdkcloud")
+	assert.Equal(t, rdkcloud.Description, "RDK cloud devices")
+	assert.Equal(t, rdkcloud.GetPermissionSuffix(), "rdkcloud")
+	assert.Assert(t, rdkcloud.Builtin)
+
+	// a stored override of a builtin type only changes the description
+	assert.NilError(t, xshared.SetApplicationType(&xshared.ApplicationType{ID: "stb", PermissionSuffix: "settop", Description: "set-top boxes"}))
+	stb := xshared.GetApplicationType("stb")
+	assert.Equal(t, stb.GetPermissionSuffix(), "stb")
+	assert.Equal(t, stb.Description, "set-top boxes")
+}
+
+func TestApplicationTypeValidator(t *testing.T) {
+	testutil.SetupTestDB()
+	assert.Equal(t, saveTestApplicationType(CreateApplicationTypeHandler, http.MethodPost, `{"id":"validated"}`), http.StatusCreated)
+	xshared.RegisterApplicationTypeValidator("validated", func(appType *xshared.ApplicationType, entity interface{}) error {
+		if config, ok := entity.(*coreef.FirmwareConfig); ok && config.FirmwareFilename != "validated.bin" {
+			return xcommon.NewXconfError(http.StatusBadRequest, "FirmwareFilename of "+appType.ID+" must be validated.bin")
+		}
+		return nil
+	})
+	config := coreef.NewEmptyFirmwareConfig()
+	config.ApplicationType = "validated"
+	config.FirmwareFilename = "fw.bin"
+	err := beforeCreatingFirmwareConfig(config, "validated")
+	assert.Equal(t, xcommon.GetXconfErrorStatusCode(err), http.StatusBadRequest)
+	assert.ErrorContains(t, err, "FirmwareFilename of validated must be validated.bin")
+
+	config.FirmwareFilename = "validated.bin"
+	assert.NilError(t, beforeCreatingFirmwareConfig(config, "validated"))
+	// the validator does not apply to other application types
+	assert.NilError(t, xshared.ValidateApplicationTypeEntity("stb", coreef.NewEmptyFirmwareConfig()))
+}
@@ -0,0 +1,137 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package queries
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	xcommon "xconfadmin/common"
+	xshared "xconfadmin/shared"
+	xcoreef "xconfadmin/shared/estbfirmware"
+	xwhttp "xconfwebconfig/http"
+	coreef "xconfwebconfig/shared/estbfirmware"
+	corefw "xconfwebconfig/shared/firmware"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func CreateApplicationType(appType *xshared.ApplicationType) *xwhttp.ResponseEntity {
+	appType.ID = strings.ToLower(strings.TrimSpace(appType.ID))
+	if err := appType.Validate(); err != nil {
+		return xwhttp.NewResponseEntity(xcommon.GetXconfErrorStatusCode(err), err, nil)
+	}
+	if xshared.GetApplicationType(appType.ID) != nil {
+		return xwhttp.NewResponseEntity(http.StatusConflict, errors.New("ApplicationType "+appType.ID+" already exists"), nil)
+	}
+	appType.Builtin = false
+	if err := xshared.SetApplicationType(appType); err != nil {
+		return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
+	}
+	if err := SeedApplicationTypeDefaults(appType); err != nil {
+		return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
+	}
+	return xwhttp.NewResponseEntity(http.StatusCreated, nil, appType)
+}
+
+func UpdateApplicationType(appType *xshared.ApplicationType) *xwhttp.ResponseEntity {
+	appType.ID = strings.ToLower(strings.TrimSpace(appType.ID))
+	if err := appType.Validate(); err != nil {
+		return xwhttp.NewResponseEntity(xcommon.GetXconfErrorStatusCode(err), err, nil)
+	}
+	existing := xshared.GetApplicationType(appType.ID)
+	if existing == nil {
+		return xwhttp.NewResponseEntity(http.StatusNotFound, errors.New("ApplicationType "+appType.ID+" does not exist"), nil)
+	}
+	if err := appType.ValidateUpdate(existing); err != nil {
+		return xwhttp.NewResponseEntity(xcommon.GetXconfErrorStatusCode(err), err, nil)
+	}
+	appType.Builtin = existing.Builtin
+	if err := xshared.SetApplicationType(appType); err != nil {
+		return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
+	}
+	if err := SeedApplicationTypeDefaults(appType); err != nil {
+		return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
+	}
+	return xwhttp.NewResponseEntity(http.StatusOK, nil, appType)
+}
+
+func DeleteApplicationType(id string) *xwhttp.ResponseEntity {
+	appType := xshared.GetApplicationType(id)
+	if appType == nil {
+		return xwhttp.NewResponseEntity(http.StatusNotFound, errors.New("ApplicationType "+id+" does not exist"), nil)
+	}
+	if appType.Builtin {
+		return xwhttp.NewResponseEntity(http.StatusConflict, errors.New("ApplicationType "+id+" is defined in configuration and can't be deleted"), nil)
+	}
+	if rules, err := corefw.GetFirmwareRuleAllAsListByApplicationType(id); err == nil {
+		for _, list := range rules {
+			for _, rule := range list {
+				if rule.ID != GetGlobalPercentageIdByApplication(id) {
+					return xwhttp.NewResponseEntity(http.StatusConflict, fmt.Errorf("ApplicationType %s is used by FirmwareRule %s", id, rule.Name), nil)
+				}
+			}
+		}
+	}
+	if err := xshared.DeleteApplicationType(id); err != nil {
+		return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
+	}
+	return xwhttp.NewResponseEntity(http.StatusNoContent, nil, nil)
+}
+
+// SeedApplicationTypeDefaults creates the default entities of the application type if they don't exist yet
+func SeedApplicationTypeDefaults(appType *xshared.ApplicationType) error {
+	if appType.Defaults.FirmwareRuleTemplates {
+		CreateFirmwareRuleTemplates()
+	}
+	if appType.Defaults.GlobalPercentage {
+		id := GetGlobalPercentageIdByApplication(appType.ID)
+		if rule, _ := corefw.GetFirmwareRuleOneDB(id); rule == nil {
+			globalPercentage := coreef.NewGlobalPercentage()
+			globalPercentage.ApplicationType = appType.ID
+			if respEntity := UpdatePercentFilterGlobal(appType.ID, globalPercentage, nil); respEntity.Error != nil {
+				return fmt.Errorf("unable to create GlobalPercentage for %s: %s", appType.ID, respEntity.Error.Error())
+			}
+			log.Infof("created GlobalPercentage %s", id)
+		}
+	}
+	if appType.Defaults.RoundRobinFilter {
+		id := xcoreef.GetRoundRobinIdByApplication(appType.ID)
+		if filter, _ := coreef.GetDownloadLocationRoundRobinFilterValOneDB(id); filter == nil {
+			filter = coreef.NewEmptyDownloadLocationRoundRobinFilterValue()
+			filter.ID = id
+			filter.ApplicationType = appType.ID
+			if err := coreef.CreateDownloadLocationRoundRobinFilterValOneDB(filter); err != nil {
+				return fmt.Errorf("unable to create DownloadLocationRoundRobinFilter for %s: %s", appType.ID, err.Error())
+			}
+			log.Infof("created DownloadLocationRoundRobinFilter %s", id)
+		}
+	}
+	return nil
+}
+
+// SeedAllApplicationTypeDefaults seeds the default entities of every registered application type
+func SeedAllApplicationTypeDefaults() {
+	for _, appType := range xshared.GetApplicationTypes() {
+		if err := SeedApplicationTypeDefaults(appType); err != nil {
+			log.Error(err.Error())
+		}
+	}
+}
@@ -0,0 +1,153 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package queries
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"xconfadmin/adminapi/auth"
+	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
+	xshared "xconfadmin/shared"
+	xwcommon "xconfwebconfig/common"
+	xwhttp "xconfwebconfig/http"
+)
+
+const (
+	cAuditUser            = "user"
+	cAuditEntityType      = "entityType"
+	cAuditEntityId        = "entityId"
+	cAuditFrom            = "from"
+	cAuditTo              = "to"
+	cAuditDefaultPageSize = 50
+)
+
+var auditCsvHeader = []string{"timestamp", "user", "sourceIp", "requestId", "operation", "method", "path", "status",
+	"entityType", "entityId", "tableName", "applicationType", "before", "after"}
+
+// getAuditFilter reads the filter from query params, from and to are epoch millis or RFC3339 times
+func getAuditFilter(r *http.Request) (*xshared.AuditFilter, error) {
+	query := r.URL.Query()
+	filter := xshared.AuditFilter{
+		User:       query.Get(cAuditUser),
+		EntityType: query.Get(cAuditEntityType),
+		EntityID:   query.Get(cAuditEntityId),
+		TableName:  query.Get(xwcommon.TABLE_NAME),
+	}
+	var err error
+	if filter.From, err = xcommon.ParseReadonlyTime(query.Get(cAuditFrom)); err != nil {
+		return nil, xcommon.NewXconfError(http.StatusBadRequest, "Invalid value for from")
+	}
+	if filter.To, err = xcommon.ParseReadonlyTime(query.Get(cAuditTo)); err != nil {
+		return nil, xcommon.NewXconfError(http.StatusBadRequest, "Invalid value for to")
+	}
+	if filter.From > 0 && filter.To > 0 && filter.From > filter.To {
+		return nil, xcommon.NewXconfError(http.StatusBadRequest, "from must not be after to")
+	}
+	return &filter, nil
+}
+
+func GetAuditEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	if _, err := auth.CanRead(r, auth.TOOL_ENTITY); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	filter, err := getAuditFilter(r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	pageNumber, pageSize := 1, cAuditDefaultPageSize
+	if value := r.URL.Query().Get(xcommon.PAGE_NUMBER); value != "" {
+		if pageNumber, err = strconv.Atoi(value); err != nil || pageNumber < 1 {
+			xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, "Invalid value for pageNumber")
+			return
+		}
+	}
+	if value := r.URL.Query().Get(xcommon.PAGE_SIZE); value != "" {
+		if pageSize, err = strconv.Atoi(value); err != nil || pageSize < 1 {
+			xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, "Invalid value for pageSize")
+			return
+		}
+	}
+
+	entries := xshared.GetAuditEntries(filter)
+	page := []*xshared.AuditEntry{}
+	if startIndex := (pageNumber - 1) * pageSize; startIndex < len(entries) {
+		lastIndex := startIndex + pageSize
+		if lastIndex > len(entries) {
+			lastIndex = len(entries)
+		}
+		page = entries[startIndex:lastIndex]
+	}
+
+	res, err := xhttp.ReturnJsonResponse(page, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	headers := xhttp.CreateNumberOfItemsHttpHeaders(len(entries))
+	xwhttp.WriteXconfResponseWithHeaders(w, headers, http.StatusOK, res)
+}
+
+func ExportAuditEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	if _, err := auth.CanRead(r, auth.TOOL_ENTITY); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	filter, err := getAuditFilter(r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	writer.Write(auditCsvHeader)
+	for _, entry := range xshared.GetAuditEntries(filter) {
+		writer.Write([]string{
+			time.UnixMilli(entry.Timestamp).UTC().Format(time.RFC3339),
+			entry.User,
+			entry.SourceIp,
+			entry.RequestId,
+			entry.Operation,
+			entry.Method,
+			entry.Path,
+			strconv.Itoa(entry.Status),
+			entry.EntityType,
+			entry.EntityID,
+			entry.TableName,
+			entry.ApplicationType,
+			string(entry.Before),
+			string(entry.After),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=audit_%s.csv", time.Now().UTC().Format("20060102")))
+	xwhttp.WriteResponseBytes(w, buffer.Bytes(), http.StatusOK, "text/csv")
+}
@@ -0,0 +1,196 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package queries
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"xconfadmin/adminapi/auth"
+	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
+	xshared "xconfadmin/shared"
+	xchange "xconfadmin/shared/change"
+	"xconfwebconfig/db"
+	xwhttp "xconfwebconfig/http"
+	xwchange "xconfwebconfig/shared/change"
+	coreef "xconfwebconfig/shared/estbfirmware"
+	corefw "xconfwebconfig/shared/firmware"
+	"xconfwebconfig/shared/rfc"
+)
+
+// writePendingChange stores the change for approval and returns it with 202 Accepted, validate runs the
+// validation of the direct write in a dry run first, so an invalid change is not left waiting for approval
+func writePendingChange(w http.ResponseWriter, r *http.Request, entityType string, applicationType string, operation xwchange.ChangeOperation, entityId string, newEntity interface{}, validate func(dryRun *xhttp.DryRun) error) {
+	if validate != nil {
+		if err := validate(xhttp.NewValidationDryRun()); err != nil {
+			xhttp.AdminError(w, err)
+			return
+		}
+	}
+	change, err := xchange.CreatePendingEntityChange(entityType, applicationType, operation, entityId, newEntity, auth.GetUserNameOrUnknown(r))
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	res, err := xhttp.ReturnJsonResponse(change, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusAccepted, res)
+}
+
+func responseEntityError(respEntity *xwhttp.ResponseEntity) error {
+	if respEntity.Error == nil {
+		return nil
+	}
+	return xcommon.NewXconfError(respEntity.Status, respEntity.Error.Error())
+}
+
+type firmwareRuleChangeApplier struct{}
+
+func NewFirmwareRuleChangeApplier() xchange.EntityChangeApplier {
+	return &firmwareRuleChangeApplier{}
+}
+
+func (a *firmwareRuleChangeApplier) PermissionEntity() string {
+	return auth.FIRMWARE_ENTITY
+}
+
+func (a *firmwareRuleChangeApplier) GetEntity(id string) interface{} {
+	firmwareRule, err := corefw.GetFirmwareRuleOneDB(id)
+	if err != nil || firmwareRule == nil {
+		return nil
+	}
+	return firmwareRule
+}
+
+func (a *firmwareRuleChangeApplier) Create(entity json.RawMessage, applicationType string) error {
+	firmwareRule := corefw.NewEmptyFirmwareRule()
+	if err := json.Unmarshal(entity, firmwareRule); err != nil {
+		return xcommon.NewXconfError(http.StatusBadRequest, err.Error())
+	}
+	return createFirmwareRule(*firmwareRule, applicationType, true, nil)
+}
+
+func (a *firmwareRuleChangeApplier) Update(entity json.RawMessage, applicationType string) error {
+	firmwareRule := corefw.NewEmptyFirmwareRule()
+	if err := json.Unmarshal(entity, firmwareRule); err != nil {
+		return xcommon.NewXconfError(http.StatusBadRequest, err.Error())
+	}
+	return updateFirmwareRule(*firmwareRule, applicationType, true, nil)
+}
+
+func (a *firmwareRuleChangeApplier) Delete(id string, applicationType string) error {
+	entityOnDb, err := corefw.GetFirmwareRuleOneDB(id)
+	if err != nil {
+		return xcommon.NewXconfError(http.StatusNotFound, "firmwareRule does not exist for "+id)
+	}
+	if entityOnDb.ApplicationType != applicationType {
+		return xcommon.NewXconfError(http.StatusConflict, fmt.Sprintf("ApplicationType mismatch: %v on db. %v provided", entityOnDb.ApplicationType, applicationType))
+	}
+	if err := db.GetCachedSimpleDao().DeleteOne(db.TABLE_FIRMWARE_RULE, id); err != nil {
+		return err
+	}
+	auth.RemoveOwnership(xshared.OWNED_FIRMWARE_RULE, id)
+	return nil
+}
+
+type percentageBeanChangeApplier struct{}
+
+func NewPercentageBeanChangeApplier() xchange.EntityChangeApplier {
+	return &percentageBeanChangeApplier{}
+}
+
+func (a *percentageBeanChangeApplier) PermissionEntity() string {
+	return auth.FIRMWARE_ENTITY
+}
+
+func (a *percentageBeanChangeApplier) GetEntity(id string) interface{} {
+	bean, err := GetOnePercentageBeanFromDB(id)
+	if err != nil || bean == nil {
+		return nil
+	}
+	return bean
+}
+
+func (a *percentageBeanChangeApplier) Create(entity json.RawMessage, applicationType string) error {
+	bean := coreef.NewPercentageBean()
+	if err := json.Unmarshal(entity, bean); err != nil {
+		return xcommon.NewXconfError(http.StatusBadRequest, err.Error())
+	}
+	return responseEntityError(CreatePercentageBean(bean, applicationType, nil))
+}
+
+func (a *percentageBeanChangeApplier) Update(entity json.RawMessage, applicationType string) error {
+	bean := coreef.NewPercentageBean()
+	if err := json.Unmarshal(entity, bean); err != nil {
+		return xcommon.NewXconfError(http.StatusBadRequest, err.Error())
+	}
+	return responseEntityError(UpdatePercentageBean(bean, applicationType, nil))
+}
+
+func (a *percentageBeanChangeApplier) Delete(id string, applicationType string) error {
+	return responseEntityError(DeletePercentageBean(id, applicationType, nil))
+}
+
+type featureRuleChangeApplier struct{}
+
+func NewFeatureRuleChangeApplier() xchange.EntityChangeApplier {
+	return &featureRuleChangeApplier{}
+}
+
+func (a *featureRuleChangeApplier) PermissionEntity() string {
+	return auth.FIRMWARE_ENTITY
+}
+
+func (a *featureRuleChangeApplier) GetEntity(id string) interface{} {
+	if featureRule := GetOne(id); featureRule != nil {
+		return featureRule
+	}
+	return nil
+}
+
+func (a *featureRuleChangeApplier) Create(entity json.RawMessage, applicationType string) error {
+	featureRule := rfc.FeatureRule{}
+	if err := json.Unmarshal(entity, &featureRule); err != nil {
+		return xcommon.NewXconfError(http.StatusBadRequest, err.Error())
+	}
+	return CreateFeatureRule(&featureRule, applicationType, nil)
+}
+
+func (a *featureRuleChangeApplier) Update(entity json.RawMessage, applicationType string) error {
+	featureRule := rfc.FeatureRule{}
+	if err := json.Unmarshal(entity, &featureRule); err != nil {
+		return xcommon.NewXconfError(http.StatusBadRequest, err.Error())
+	}
+	return UpdateFeatureRule(&featureRule, applicationType, nil)
+}
+
+func (a *featureRuleChangeApplier) Delete(id string, applicationType string) error {
+	featureRuleToDelete := GetOne(id)
+	if featureRuleToDelete == nil {
+		return xcommon.NewXconfError(http.StatusNotFound, "Entity with id: "+id+" does not exist")
+	}
+	if featureRuleToDelete.ApplicationType != applicationType {
+		return xcommon.NewXconfError(http.StatusConflict, "ApplicationType mismatch: "+featureRuleToDelete.ApplicationType+" on db. "+applicationType+" provided")
+	}
+	return DeleteFeatureRule(featureRuleToDelete, nil)
+}
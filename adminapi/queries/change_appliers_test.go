@@ -0,0 +1,67 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package queries
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"xconfadmin/adminapi/auth"
+	xchange "xconfadmin/shared/change"
+	"xconfadmin/testutil"
+	re "xconfwebconfig/rulesengine"
+	coreef "xconfwebconfig/shared/estbfirmware"
+
+	"gotest.tools/assert"
+)
+
+func requireTestApproval(t *testing.T, entityType string, applier xchange.EntityChangeApplier) {
+	xchange.RegisterEntityChangeApplier(entityType, applier)
+	setting := &xchange.ApprovalSetting{EntityType: entityType, ApplicationType: "stb", RequiresApproval: true}
+	assert.NilError(t, xchange.SetApprovalSetting(setting, testutil.TestUser))
+}
+
+func TestInvalidFirmwareRuleIsNotStoredForApproval(t *testing.T) {
+	testutil.SetupTestDB()
+	requireTestApproval(t, xchange.FIRMWARE_RULE, NewFirmwareRuleChangeApplier())
+	createTestAnalysisTemplate(t, "MODEL_TEMPLATE", 1)
+
+	// a rule without a name fails like the direct write
+	rule := newTestAnalysisRule("rule-1", "MODEL_TEMPLATE", re.NewCondition(coreef.RuleFactoryMODEL, re.StandardOperationIs, re.NewFixedArg("X1")))
+	rule.Name = ""
+	body, _ := json.Marshal(rule)
+	r := testutil.NewRequest(http.MethodPost, "/xconfAdminService/firmwarerule?applicationType=stb", string(body), auth.WRITE_FIRMWARE_ALL)
+	assert.Equal(t, testutil.Serve(PostFirmwareRuleHandler, r).Code, http.StatusBadRequest)
+	assert.Equal(t, len(xchange.GetEntityChangeList()), 0)
+
+	rule.Name = "rule-1"
+	body, _ = json.Marshal(rule)
+	r = testutil.NewRequest(http.MethodPost, "/xconfAdminService/firmwarerule?applicationType=stb", string(body), auth.WRITE_FIRMWARE_ALL)
+	assert.Equal(t, testutil.Serve(PostFirmwareRuleHandler, r).Code, http.StatusAccepted)
+	assert.Equal(t, len(xchange.GetEntityChangeList()), 1)
+}
+
+func TestInvalidPercentageBeanIsNotStoredForApproval(t *testing.T) {
+	testutil.SetupTestDB()
+	requireTestApproval(t, xchange.PERCENTAGE_BEAN, NewPercentageBeanChangeApplier())
+
+	r := testutil.NewRequest(http.MethodPost, "/xconfAdminService/percentagebean?applicationType=stb", `{"name":"","model":"X1"}`, auth.WRITE_FIRMWARE_ALL)
+	assert.Equal(t, testutil.Serve(CreatePercentageBeanHandler, r).Code, http.StatusBadRequest)
+	assert.Equal(t, len(xchange.GetEntityChangeList()), 0)
+}
@@ -356,6 +356,7 @@ func GetAppSettings(w http.ResponseWriter, r *http.Request) {
 		xhttp.AdminError(w, err)
 		return
 	}
+	settings[xcommon.READONLY_MODE_STATUS] = xcommon.GetReadonlyWindowsStatus(time.Now())
 	response, _ := util.JSONMarshal(settings)
 	xwhttp.WriteXconfResponse(w, http.StatusOK, response)
 }
@@ -380,11 +381,19 @@ func UpdateAppSettings(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// computed status returned by GetAppSettings is not saved
+	delete(settings, xcommon.READONLY_MODE_STATUS)
 	for k, v := range settings {
 		if !xcommon.IsValidAppSetting(k) {
 			xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid AppSetting: %s", k))
 			return
 		}
+		if err := xcommon.ValidateReadonlyAppSetting(k, v); err != nil {
+			xhttp.AdminError(w, err)
+			return
+		}
+	}
+	for k, v := range settings {
 		if _, err := xcommon.SetAppSetting(k, v); err != nil {
 			xhttp.WriteAdminErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("Unable to save AppSetting for %s: %s", k, err.Error()))
 			return
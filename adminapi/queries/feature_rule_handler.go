@@ -25,15 +25,15 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
 
 	xshared "xconfadmin/shared"
+	xchange "xconfadmin/shared/change"
 	xwhttp "xconfwebconfig/http"
 
-	xrfc "xconfadmin/shared/rfc"
 	"xconfwebconfig/common"
-	ds "xconfwebconfig/db"
 	"xconfwebconfig/shared/rfc"
 	"xconfwebconfig/util"
 
@@ -275,11 +275,33 @@ func CreateFeatureRuleHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = CreateFeatureRule(&featureRule, applicationType)
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_FEATURE_RULE, featureRule.Id); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	dryRun := xhttp.NewDryRun(r)
+	if xchange.IsApprovalRequired(xchange.FEATURE_RULE, applicationType) {
+		if util.IsBlank(featureRule.Id) {
+			featureRule.Id = uuid.New().String()
+		}
+		if dryRun == nil {
+			writePendingChange(w, r, xchange.FEATURE_RULE, applicationType, xchange.Create, featureRule.Id, featureRule, func(dryRun *xhttp.DryRun) error {
+				return CreateFeatureRule(&featureRule, applicationType, dryRun)
+			})
+			return
+		}
+		dryRun.ApprovalRequired = true
+	}
+	err = CreateFeatureRule(&featureRule, applicationType, dryRun)
 	if err != nil {
 		xhttp.AdminError(w, err)
 		return
 	}
+	if dryRun != nil {
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
+	auth.AssignOwnership(r, xshared.OWNED_FEATURE_RULE, featureRule.Id)
 	response, err := util.JSONMarshal(featureRule)
 	if err != nil {
 		log.Error(fmt.Sprintf("json.Marshal featureRuleNew error: %v", err))
@@ -307,11 +329,30 @@ func UpdateFeatureRuleHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = UpdateFeatureRule(&featureRule, applicationType)
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_FEATURE_RULE, featureRule.Id); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	dryRun := xhttp.NewDryRun(r)
+	if xchange.IsApprovalRequired(xchange.FEATURE_RULE, applicationType) {
+		if dryRun == nil {
+			writePendingChange(w, r, xchange.FEATURE_RULE, applicationType, xchange.Update, featureRule.Id, featureRule, func(dryRun *xhttp.DryRun) error {
+				return UpdateFeatureRule(&featureRule, applicationType, dryRun)
+			})
+			return
+		}
+		dryRun.ApprovalRequired = true
+	}
+	err = UpdateFeatureRule(&featureRule, applicationType, dryRun)
 	if err != nil {
 		xhttp.AdminError(w, err)
 		return
 	}
+	if dryRun != nil {
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
+	auth.UpdateOwnership(r, xshared.OWNED_FEATURE_RULE, featureRule.Id)
 	response, err := util.JSONMarshal(featureRule)
 	if err != nil {
 		log.Error(fmt.Sprintf("json.Marshal featureRuleNew error: %v", err))
@@ -325,6 +366,10 @@ func ImportAllFeatureRulesHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.AdminError(w, err)
 		return
 	}
+	if err := xchange.ValidateDirectWrite(xchange.FEATURE_RULE, applicationType); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
 
 	xw, ok := w.(*xwhttp.XResponseWriter)
 	if !ok {
@@ -342,7 +387,26 @@ func ImportAllFeatureRulesHandler(w http.ResponseWriter, r *http.Request) {
 		return featureRules[i].Priority < featureRules[j].Priority
 	})
 
-	importResult := ImportOrUpdateAllFeatureRule(featureRules, applicationType)
+	allowed := []rfc.FeatureRule{}
+	denied := []string{}
+	for _, featureRule := range featureRules {
+		if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_FEATURE_RULE, featureRule.Id); err != nil {
+			denied = append(denied, featureRule.Id)
+			continue
+		}
+		allowed = append(allowed, featureRule)
+	}
+	dryRun := xhttp.NewDryRun(r)
+	importResult := ImportOrUpdateAllFeatureRule(allowed, applicationType, dryRun)
+	importResult[NOT_IMPORTED] = append(importResult[NOT_IMPORTED], denied...)
+	if dryRun != nil {
+		dryRun.Result = importResult
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
+	for _, id := range importResult[IMPORTED] {
+		auth.UpdateOwnership(r, xshared.OWNED_FEATURE_RULE, id)
+	}
 	response, err := util.JSONMarshal(importResult)
 	if err != nil {
 		log.Error(fmt.Sprintf("json.Marshal featureRuleNew error: %v", err))
@@ -366,17 +430,29 @@ func DeleteOneFeatureRuleHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.AdminError(w, err)
 		return
 	}
+	if err := auth.ValidateOwnership(r, xshared.OWNED_FEATURE_RULE, id); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
 
-	xrfc.DeleteFeatureRule(id)
-
-	allFeatureRules := rfc.GetFeatureRuleList()
-	altered := PackFeaturePriorities(allFeatureRules, featureRuleToDelete)
-	for _, item := range altered {
-		if err := ds.GetCachedSimpleDao().SetOne(ds.TABLE_FEATURE_CONTROL_RULE, item.Id, item); err != nil {
-			response := "FeatureRule saving failed while updating priorities "
-			xhttp.WriteAdminErrorResponse(w, http.StatusNotFound, response)
+	dryRun := xhttp.NewDryRun(r)
+	if xchange.IsApprovalRequired(xchange.FEATURE_RULE, featureRuleToDelete.ApplicationType) {
+		if dryRun == nil {
+			writePendingChange(w, r, xchange.FEATURE_RULE, featureRuleToDelete.ApplicationType, xchange.Delete, id, nil, func(dryRun *xhttp.DryRun) error {
+				return DeleteFeatureRule(featureRuleToDelete, dryRun)
+			})
 			return
 		}
+		dryRun.ApprovalRequired = true
+	}
+
+	if err := DeleteFeatureRule(featureRuleToDelete, dryRun); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	if dryRun != nil {
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
 	}
 	xwhttp.WriteXconfResponse(w, http.StatusNoContent, []byte(""))
 }
@@ -427,12 +503,22 @@ func ChangeFeatureRulePrioritiesHandler(w http.ResponseWriter, r *http.Request)
 		xwhttp.WriteXconfResponse(w, http.StatusBadRequest, []byte("newPriority must be a number"))
 		return
 	}
+	if err := auth.ValidateOwnership(r, xshared.OWNED_FEATURE_RULE, id); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
 
-	featureRules, err := ChangeFeatureRulePriorities(id, newPriorityInt, applicationType)
+	dryRun := xhttp.NewDryRun(r)
+	featureRules, err := ChangeFeatureRulePriorities(id, newPriorityInt, applicationType, dryRun)
 	if err != nil {
 		xwhttp.WriteXconfResponse(w, http.StatusBadRequest, []byte(err.Error()))
 		return
 	}
+	if dryRun != nil {
+		dryRun.Result = featureRules
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	response, err := util.JSONMarshal(featureRules)
 	if err != nil {
 		log.Error(fmt.Sprintf("json.Marshal featureRules error: %v", err))
@@ -477,6 +563,10 @@ func UpdateFeatureRulesHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.AdminError(w, err)
 		return
 	}
+	if err := xchange.ValidateDirectWrite(xchange.FEATURE_RULE, applicationType); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
 
 	xw, ok := w.(*xwhttp.XResponseWriter)
 	if !ok {
@@ -494,11 +584,18 @@ func UpdateFeatureRulesHandler(w http.ResponseWriter, r *http.Request) {
 		return entities[i].Priority < entities[j].Priority
 	})
 
+	dryRun := xhttp.NewDryRun(r)
 	entitiesMap := map[string]xhttp.EntityMessage{}
 	for _, entity := range entities {
 		entity := entity
-		err := UpdateFeatureRule(&entity, applicationType)
+		err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_FEATURE_RULE, entity.Id)
+		if err == nil {
+			err = UpdateFeatureRule(&entity, applicationType, dryRun)
+		}
 		if err == nil {
+			if dryRun == nil {
+				auth.UpdateOwnership(r, xshared.OWNED_FEATURE_RULE, entity.Id)
+			}
 			entityMessage := xhttp.EntityMessage{
 				Status:  xcommon.ENTITY_STATUS_SUCCESS,
 				Message: entity.Id,
@@ -512,6 +609,11 @@ func UpdateFeatureRulesHandler(w http.ResponseWriter, r *http.Request) {
 			entitiesMap[entity.Id] = entityMessage
 		}
 	}
+	if dryRun != nil {
+		dryRun.Result = entitiesMap
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	response, _ := util.JSONMarshal(entitiesMap)
 	xwhttp.WriteXconfResponse(w, http.StatusOK, response)
 }
@@ -522,6 +624,10 @@ func CreateFeatureRulesHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.AdminError(w, err)
 		return
 	}
+	if err := xchange.ValidateDirectWrite(xchange.FEATURE_RULE, applicationType); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
 
 	xw, ok := w.(*xwhttp.XResponseWriter)
 	if !ok {
@@ -539,11 +645,18 @@ func CreateFeatureRulesHandler(w http.ResponseWriter, r *http.Request) {
 		return entities[i].Priority < entities[j].Priority
 	})
 
+	dryRun := xhttp.NewDryRun(r)
 	entitiesMap := map[string]xhttp.EntityMessage{}
 	for _, entity := range entities {
 		entity := entity
-		err := CreateFeatureRule(&entity, applicationType)
+		err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_FEATURE_RULE, entity.Id)
+		if err == nil {
+			err = CreateFeatureRule(&entity, applicationType, dryRun)
+		}
 		if err == nil {
+			if dryRun == nil {
+				auth.AssignOwnership(r, xshared.OWNED_FEATURE_RULE, entity.Id)
+			}
 			entityMessage := xhttp.EntityMessage{
 				Status:  xcommon.ENTITY_STATUS_SUCCESS,
 				Message: entity.Id,
@@ -557,6 +670,11 @@ func CreateFeatureRulesHandler(w http.ResponseWriter, r *http.Request) {
 			entitiesMap[entity.Id] = entityMessage
 		}
 	}
+	if dryRun != nil {
+		dryRun.Result = entitiesMap
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	response, _ := util.JSONMarshal(entitiesMap)
 	xwhttp.WriteXconfResponse(w, http.StatusOK, response)
 }
@@ -28,12 +28,15 @@ import (
 	"xconfwebconfig/dataapi/featurecontrol"
 	ru "xconfwebconfig/rulesengine"
 
+	"xconfadmin/adminapi/auth"
 	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
 	xshared "xconfadmin/shared"
 
 	xrfc "xconfadmin/shared/rfc"
 	"xconfadmin/util"
 	"xconfwebconfig/common"
+	"xconfwebconfig/db"
 	"xconfwebconfig/rulesengine"
 	"xconfwebconfig/shared"
 	"xconfwebconfig/shared/rfc"
@@ -74,6 +77,7 @@ func FindFeatureRuleByContext(searchContext map[string]string) []*rfc.FeatureRul
 		return featureRules[i].Id < featureRules[j].Id
 	})
 	featureRuleList := []*rfc.FeatureRule{}
+	honoredByOwner := auth.NewOwnerFilter(searchContext, xshared.OWNED_FEATURE_RULE)
 	for _, featureRule := range featureRules {
 		if featureRule == nil {
 			continue
@@ -142,13 +146,16 @@ func FindFeatureRuleByContext(searchContext map[string]string) []*rfc.FeatureRul
 				continue
 			}
 		}
+		if !honoredByOwner(featureRule.Id) {
+			continue
+		}
 		featureRuleList = append(featureRuleList, featureRule)
 	}
 	return featureRuleList
 }
 
-func CreateFeatureRule(featureRule *rfc.FeatureRule, applicationType string) error {
-	err := beforeCreating(featureRule)
+func CreateFeatureRule(featureRule *rfc.FeatureRule, applicationType string, dryRun *xhttp.DryRun) error {
+	err := beforeCreating(featureRule, dryRun)
 	if err != nil {
 		return err
 	}
@@ -157,10 +164,72 @@ func CreateFeatureRule(featureRule *rfc.FeatureRule, applicationType string) err
 		return err
 	}
 	contextMap := map[string]string{common.APPLICATION_TYPE: featureRule.ApplicationType}
-	featureRules := addNewFeatureRuleAndReorganize(featureRule, FindFeatureRuleByContext(contextMap))
+	featureRules := addNewFeatureRuleAndReorganize(featureRule, getFeatureRulesToReorganize(contextMap, dryRun))
+	saveFeatureRules(featureRules, dryRun)
+	return nil
+}
+
+// getFeatureRulesToReorganize returns the rules matching the context, copied for a dry run
+// so their priorities can change without altering the cached rules, and merged with the rules
+// the dry run saved earlier so the entries of a batch are reorganized together
+func getFeatureRulesToReorganize(contextMap map[string]string, dryRun *xhttp.DryRun) []*rfc.FeatureRule {
+	featureRules := FindFeatureRuleByContext(contextMap)
+	if dryRun == nil {
+		return featureRules
+	}
+	pending := dryRun.Pending(db.TABLE_FEATURE_CONTROL_RULE)
+	merged := []*rfc.FeatureRule{}
+	for _, featureRule := range featureRules {
+		if _, ok := pending[featureRule.Id]; !ok {
+			merged = append(merged, featureRule)
+		}
+	}
+	for _, entity := range pending {
+		if featureRule, ok := entity.(*rfc.FeatureRule); ok && featureRule.ApplicationType == contextMap[common.APPLICATION_TYPE] {
+			merged = append(merged, featureRule)
+		}
+	}
+	return copyFeatureRules(merged)
+}
+
+func copyFeatureRules(featureRules []*rfc.FeatureRule) []*rfc.FeatureRule {
+	copies := make([]*rfc.FeatureRule, 0, len(featureRules))
+	for _, featureRule := range featureRules {
+		copied := *featureRule
+		copies = append(copies, &copied)
+	}
+	return copies
+}
+
+// saveFeatureRules stores the rules, a dry run only records them
+func saveFeatureRules(featureRules []*rfc.FeatureRule, dryRun *xhttp.DryRun) {
 	for _, featureRule := range featureRules {
+		if dryRun != nil {
+			dryRun.RecordSave(db.TABLE_FEATURE_CONTROL_RULE, featureRule.Id, featureRule, GetOne(featureRule.Id) != nil)
+			continue
+		}
 		xrfc.SetFeatureRule(featureRule.Id, featureRule)
 	}
+}
+
+// DeleteFeatureRule deletes the rule and packs the priorities of the remaining rules
+func DeleteFeatureRule(featureRuleToDelete *rfc.FeatureRule, dryRun *xhttp.DryRun) error {
+	if dryRun != nil {
+		dryRun.Record(xhttp.DRY_RUN_DELETE, db.TABLE_FEATURE_CONTROL_RULE, featureRuleToDelete.Id, featureRuleToDelete)
+		for _, item := range PackFeaturePriorities(copyFeatureRules(rfc.GetFeatureRuleList()), featureRuleToDelete) {
+			dryRun.Record(xhttp.DRY_RUN_UPDATE, db.TABLE_FEATURE_CONTROL_RULE, item.Id, item)
+		}
+		return nil
+	}
+	xrfc.DeleteFeatureRule(featureRuleToDelete.Id)
+	auth.RemoveOwnership(xshared.OWNED_FEATURE_RULE, featureRuleToDelete.Id)
+
+	altered := PackFeaturePriorities(rfc.GetFeatureRuleList(), featureRuleToDelete)
+	for _, item := range altered {
+		if err := xrfc.SetFeatureRule(item.Id, item); err != nil {
+			return xcommon.NewXconfError(http.StatusNotFound, "FeatureRule saving failed while updating priorities ")
+		}
+	}
 	return nil
 }
 
@@ -202,13 +271,13 @@ func getAlteredFeatureRuleSubList(itemsList []*rfc.FeatureRule, oldPriority int,
 	return itemsList[start:end]
 }
 
-func beforeCreating(entity *rfc.FeatureRule) error {
+func beforeCreating(entity *rfc.FeatureRule, dryRun *xhttp.DryRun) error {
 	id := entity.Id
 	if id == "" {
 		entity.Id = uuid.New().String()
 	} else {
 		featureRule := GetOne(id)
-		if featureRule != nil {
+		if featureRule != nil || dryRun.Saved(db.TABLE_FEATURE_CONTROL_RULE, id) {
 			return xcommon.NewXconfError(http.StatusConflict, "\"FeatureRule with id: "+id+" already exists\"")
 		}
 	}
@@ -233,6 +302,9 @@ func beforeSaving(featureRule *rfc.FeatureRule, applicationType string) error {
 	if err != nil {
 		return err
 	}
+	if err := xshared.ValidateApplicationTypeEntity(featureRule.ApplicationType, featureRule); err != nil {
+		return err
+	}
 	err = validateAllFeatureRule(featureRule)
 	if err != nil {
 		return err
@@ -365,7 +437,7 @@ func validateAllFeatureRule(ruleToCheck *rfc.FeatureRule) error {
 	return nil
 }
 
-func UpdateFeatureRule(featureRule *rfc.FeatureRule, applicationType string) error {
+func UpdateFeatureRule(featureRule *rfc.FeatureRule, applicationType string, dryRun *xhttp.DryRun) error {
 	if featureRule.Id == "" {
 		return xcommon.NewXconfError(http.StatusBadRequest, "FeatureRule id is empty")
 	}
@@ -382,10 +454,8 @@ func UpdateFeatureRule(featureRule *rfc.FeatureRule, applicationType string) err
 	}
 
 	contextMap := map[string]string{common.APPLICATION_TYPE: featureRule.ApplicationType}
-	featureRules := updateFeatureRuleByPriorityAndReorganize(featureRule, FindFeatureRuleByContext(contextMap), featureRuleToUpdate.Priority)
-	for _, featureRule := range featureRules {
-		xrfc.SetFeatureRule(featureRule.Id, featureRule)
-	}
+	featureRules := updateFeatureRuleByPriorityAndReorganize(featureRule, getFeatureRulesToReorganize(contextMap, dryRun), featureRuleToUpdate.Priority)
+	saveFeatureRules(featureRules, dryRun)
 	return nil
 }
 
@@ -406,19 +476,20 @@ func updateFeatureRuleByPriorityAndReorganize(newItem *rfc.FeatureRule, itemsLis
 	return reorganizeFeatureRulePriorities(itemsList, priority, newItem.Priority)
 }
 
-func ImportOrUpdateAllFeatureRule(featureRuleList []rfc.FeatureRule, applicationType string) map[string][]string {
+func ImportOrUpdateAllFeatureRule(featureRuleList []rfc.FeatureRule, applicationType string, dryRun *xhttp.DryRun) map[string][]string {
 	importResult := make(map[string][]string, 2)
 	imported := []string{}
 	notImported := []string{}
 	var err error
 	for _, featureRule := range featureRuleList {
+		featureRule := featureRule
 		if featureRule.Id != "" {
-			err = CreateFeatureRule(&featureRule, applicationType)
+			err = CreateFeatureRule(&featureRule, applicationType, dryRun)
 		} else {
 			if featureRuleDB := GetOne(featureRule.Id); featureRuleDB != nil {
-				err = UpdateFeatureRule(&featureRule, applicationType)
+				err = UpdateFeatureRule(&featureRule, applicationType, dryRun)
 			} else {
-				err = CreateFeatureRule(&featureRule, applicationType)
+				err = CreateFeatureRule(&featureRule, applicationType, dryRun)
 			}
 		}
 		if err == nil {
@@ -438,13 +509,16 @@ func ImportOrUpdateAllFeatureRule(featureRuleList []rfc.FeatureRule, application
 	return importResult
 }
 
-func ChangeFeatureRulePriorities(featureRuleId string, newPriority int, applicationType string) ([]*rfc.FeatureRule, error) {
+func ChangeFeatureRulePriorities(featureRuleId string, newPriority int, applicationType string, dryRun *xhttp.DryRun) ([]*rfc.FeatureRule, error) {
 	featureRuleToUpdate := GetOne(featureRuleId)
 	if featureRuleToUpdate == nil {
 		return nil, xcommon.NewXconfError(http.StatusNotFound, "FeatureRule with id: "+featureRuleId+" does not exist")
 	}
 	oldPriority := featureRuleToUpdate.Priority
 	featureRuleList := rfc.GetFeatureRuleList()
+	if dryRun != nil {
+		featureRuleList = copyFeatureRules(featureRuleList)
+	}
 	featureRuleListForApplicationType := []*rfc.FeatureRule{}
 	if applicationType != "" {
 		for _, featureRule := range featureRuleList {
@@ -456,8 +530,9 @@ func ChangeFeatureRulePriorities(featureRuleId string, newPriority int, applicat
 		featureRuleListForApplicationType = featureRuleList
 	}
 	reorganizedFeatureRules := UpdateFeatureRulePriorities(featureRuleListForApplicationType, oldPriority, newPriority)
-	for _, featureRule := range reorganizedFeatureRules {
-		xrfc.SetFeatureRule(featureRule.Id, featureRule)
+	saveFeatureRules(reorganizedFeatureRules, dryRun)
+	if dryRun != nil {
+		return reorganizedFeatureRules, nil
 	}
 	log.Info("Priority of FeatureRule " + featureRuleId + " has been changed, oldPriority=" + strconv.Itoa(oldPriority) + ", newPriority=" + strconv.Itoa(newPriority))
 	return reorganizedFeatureRules, nil
@@ -27,6 +27,7 @@ import (
 	"strings"
 
 	xcommon "xconfwebconfig/common"
+	"xconfwebconfig/db"
 	"xconfwebconfig/util"
 
 	"xconfadmin/common"
@@ -66,7 +67,8 @@ func PostFirmwareConfigHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	status := http.StatusCreated
-	respEntity := CreateFirmwareConfigAS(firmwareConfig, applicationType, true)
+	dryRun := xhttp.NewDryRun(r)
+	respEntity := CreateFirmwareConfigAS(firmwareConfig, applicationType, true, dryRun)
 	data := respEntity.Data
 	status = respEntity.Status
 	err = respEntity.Error
@@ -75,6 +77,10 @@ func PostFirmwareConfigHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.WriteAdminErrorResponse(w, status, err.Error())
 		return
 	}
+	if dryRun != nil {
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 
 	res, err := xhttp.ReturnJsonResponse(data, r)
 	if err != nil {
@@ -107,7 +113,8 @@ func PutFirmwareConfigHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	status := http.StatusOK
-	respEntity := UpdateFirmwareConfigAS(firmwareConfig, appType, true)
+	dryRun := xhttp.NewDryRun(r)
+	respEntity := UpdateFirmwareConfigAS(firmwareConfig, appType, true, dryRun)
 	data := respEntity.Data
 	status = respEntity.Status
 	err = respEntity.Error
@@ -115,6 +122,10 @@ func PutFirmwareConfigHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.WriteAdminErrorResponse(w, status, err.Error())
 		return
 	}
+	if dryRun != nil {
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	res, err := xhttp.ReturnJsonResponse(data, r)
 	if err != nil {
 		xhttp.AdminError(w, err)
@@ -185,6 +196,7 @@ func PutPostFirmwareConfigEntitiesHandler(w http.ResponseWriter, r *http.Request
 		descMap[item.Description] = append(descMap[item.Description], item)
 	}
 
+	dryRun := xhttp.NewDryRun(r)
 	entitiesMap := map[string]xhttp.EntityMessage{}
 	for i, entity := range entities {
 		_, err := estbfirmware.GetFirmwareConfigOneDB(entity.ID)
@@ -195,7 +207,7 @@ func PutPostFirmwareConfigEntitiesHandler(w http.ResponseWriter, r *http.Request
 			}
 			continue
 		}
-		if !isPut && err == nil {
+		if !isPut && (err == nil || dryRun.Saved(db.TABLE_FIRMWARE_CONFIG, entity.ID)) {
 			entitiesMap[entity.ID] = xhttp.EntityMessage{
 				Status:  common.ENTITY_STATUS_FAILURE,
 				Message: "FirmwareConfig with " + entity.ID + " already present",
@@ -227,9 +239,9 @@ func PutPostFirmwareConfigEntitiesHandler(w http.ResponseWriter, r *http.Request
 		var err2 *xwhttp.ResponseEntity
 		entity := entity
 		if isPut {
-			err2 = UpdateFirmwareConfigAS(&entity, appType, false)
+			err2 = UpdateFirmwareConfigAS(&entity, appType, false, dryRun)
 		} else {
-			err2 = CreateFirmwareConfigAS(&entity, appType, false)
+			err2 = CreateFirmwareConfigAS(&entity, appType, false, dryRun)
 		}
 
 		if err2.Error != nil {
@@ -246,6 +258,11 @@ func PutPostFirmwareConfigEntitiesHandler(w http.ResponseWriter, r *http.Request
 			Message: entity.ID,
 		}
 	}
+	if dryRun != nil {
+		dryRun.Result = entitiesMap
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	response, err := xhttp.ReturnJsonResponse(entitiesMap, r)
 	if err != nil {
 		xhttp.AdminError(w, err)
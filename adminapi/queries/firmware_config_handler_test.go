@@ -0,0 +1,116 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package queries
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"xconfadmin/adminapi/auth"
+	"xconfadmin/common"
+	xhttp "xconfadmin/http"
+	"xconfadmin/testutil"
+	"xconfwebconfig/shared"
+	"xconfwebconfig/shared/estbfirmware"
+
+	"gotest.tools/assert"
+)
+
+func postTestFirmwareConfig(t *testing.T, applicationType string, permissions ...string) (*estbfirmware.FirmwareConfig, int) {
+	body := `{"applicationType":"` + applicationType + `","description":"config-` + applicationType + `","firmwareVersion":"FW_` + applicationType + `_1.0","firmwareFilename":"fw.bin","supportedModelIds":["MODEL1"]}`
+	r := testutil.NewRequest(http.MethodPost, "/xconfAdminService/firmwareconfig?applicationType="+applicationType, body, permissions...)
+	rr := testutil.Serve(PostFirmwareConfigHandler, r)
+	if rr.Code != http.StatusCreated {
+		return nil, rr.Code
+	}
+	config := &estbfirmware.FirmwareConfig{}
+	assert.NilError(t, json.Unmarshal(rr.Body.Bytes(), config))
+	return config, rr.Code
+}
+
+func TestFirmwareConfigWriteIsScopedToApplicationType(t *testing.T) {
+	testutil.SetupTestDB()
+	createTestModel(t, "MODEL1")
+
+	config, status := postTestFirmwareConfig(t, "rdkcloud", auth.WRITE_FIRMWARE_RDKCLOUD)
+	assert.Equal(t, status, http.StatusCreated)
+	assert.Equal(t, config.ApplicationType, "rdkcloud")
+
+	_, status = postTestFirmwareConfig(t, "stb", auth.WRITE_FIRMWARE_RDKCLOUD)
+	assert.Equal(t, status, http.StatusForbidden)
+
+	_, status = postTestFirmwareConfig(t, "stb", auth.WRITE_FIRMWARE_STB)
+	assert.Equal(t, status, http.StatusCreated)
+
+	// the read permission does not allow writes
+	_, status = postTestFirmwareConfig(t, "rdkcloud", auth.READ_FIRMWARE_RDKCLOUD)
+	assert.Equal(t, status, http.StatusForbidden)
+}
+
+func TestFirmwareConfigReadIsScopedToApplicationType(t *testing.T) {
+	testutil.SetupTestDB()
+	createTestModel(t, "MODEL1")
+	config, _ := postTestFirmwareConfig(t, "rdkcloud", auth.WRITE_FIRMWARE_ALL)
+
+	get := func(applicationType string, permissions ...string) int {
+		r := testutil.NewRequest(http.MethodGet, "/xconfAdminService/firmwareconfig/"+config.ID+"?applicationType="+applicationType, "", permissions...)
+		r = testutil.WithVars(r, map[string]string{"id": config.ID})
+		return testutil.Serve(GetFirmwareConfigByIdHandler, r).Code
+	}
+	assert.Equal(t, get("rdkcloud", auth.READ_FIRMWARE_RDKCLOUD), http.StatusOK)
+	assert.Equal(t, get("rdkcloud", auth.READ_FIRMWARE_ALL), http.StatusOK)
+	assert.Equal(t, get("rdkcloud", auth.READ_FIRMWARE_STB), http.StatusForbidden)
+	assert.Equal(t, get("stb", auth.READ_FIRMWARE_RDKCLOUD), http.StatusForbidden)
+	// the entity of another application type is not returned to a user of both
+	assert.Equal(t, get("stb", auth.READ_FIRMWARE_STB, auth.READ_FIRMWARE_RDKCLOUD), http.StatusConflict)
+	// dcm permissions do not cover firmware entities
+	assert.Equal(t, get("rdkcloud", auth.READ_DCM_ALL), http.StatusForbidden)
+}
+
+func createTestModel(t *testing.T, id string) {
+	respEntity := CreateModel(shared.NewModel(id, "test model"))
+	assert.NilError(t, respEntity.Error)
+}
+
+func TestFirmwareConfigEntitiesDryRunChecksEntriesOfTheBatch(t *testing.T) {
+	testutil.SetupTestDB()
+	createTestModel(t, "MODEL1")
+	_, status := postTestFirmwareConfig(t, "stb", auth.WRITE_FIRMWARE_STB)
+	assert.Equal(t, status, http.StatusCreated)
+	entry := func(description string) string {
+		return `{"id":"dry-run-config","applicationType":"stb","description":"` + description + `","firmwareVersion":"FW_` + description + `","firmwareFilename":"fw.bin","supportedModelIds":["MODEL1"]}`
+	}
+	body := "[" + entry("first") + "," + entry("second") + "]"
+	r := testutil.NewRequest(http.MethodPost, "/xconfAdminService/firmwareconfig/entities?applicationType=stb&dryRun=true", body, auth.WRITE_FIRMWARE_STB)
+	rr := testutil.Serve(PostFirmwareConfigEntitiesHandler, r)
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	result := struct {
+		Result  map[string]xhttp.EntityMessage `json:"result"`
+		Changes []xhttp.DryRunChange           `json:"changes"`
+	}{}
+	assert.NilError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	// the second entry conflicts with the first one as if that was saved
+	assert.Equal(t, len(result.Changes), 1)
+	assert.Equal(t, result.Result["dry-run-config"].Status, common.ENTITY_STATUS_FAILURE)
+	assert.Equal(t, result.Result["dry-run-config"].Message, "FirmwareConfig with dry-run-config already present")
+
+	_, err := estbfirmware.GetFirmwareConfigOneDB("dry-run-config")
+	assert.Assert(t, err != nil)
+}
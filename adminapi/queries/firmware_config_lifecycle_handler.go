@@ -0,0 +1,132 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package queries
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"xconfadmin/adminapi/auth"
+	xhttp "xconfadmin/http"
+	xcoreef "xconfadmin/shared/estbfirmware"
+	"xconfwebconfig/common"
+	xwhttp "xconfwebconfig/http"
+
+	"github.com/gorilla/mux"
+)
+
+// firmwareConfigStateRequest is the body of a firmware config lifecycle change
+type firmwareConfigStateRequest struct {
+	State  string `json:"state"`
+	Reason string `json:"reason"`
+}
+
+func getFirmwareConfigId(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id, found := mux.Vars(r)[common.ID]
+	if !found || id == "" {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Required ID parameter '%s' is not present", common.ID))
+		return "", false
+	}
+	return id, true
+}
+
+func writeFirmwareConfigLifecycle(w http.ResponseWriter, r *http.Request, entity interface{}) {
+	res, err := xhttp.ReturnJsonResponse(entity, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteResponseBytes(w, res, http.StatusOK, xhttp.ContextTypeHeader(r))
+}
+
+func GetFirmwareConfigLifecyclesHandler(w http.ResponseWriter, r *http.Request) {
+	applicationType, err := auth.CanRead(r, auth.FIRMWARE_ENTITY)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	lifecycles := []*xcoreef.FirmwareConfigLifecycle{}
+	for _, lifecycle := range xcoreef.GetFirmwareConfigLifecycles(applicationType) {
+		lifecycles = append(lifecycles, lifecycle)
+	}
+	sort.Slice(lifecycles, func(i, j int) bool {
+		return lifecycles[i].ID < lifecycles[j].ID
+	})
+	writeFirmwareConfigLifecycle(w, r, lifecycles)
+}
+
+func GetFirmwareConfigLifecycleReportHandler(w http.ResponseWriter, r *http.Request) {
+	applicationType, err := auth.CanRead(r, auth.FIRMWARE_ENTITY)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	writeFirmwareConfigLifecycle(w, r, GetNonActiveFirmwareConfigReferences(applicationType))
+}
+
+func GetFirmwareConfigLifecycleHandler(w http.ResponseWriter, r *http.Request) {
+	applicationType, err := auth.CanRead(r, auth.FIRMWARE_ENTITY)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	id, ok := getFirmwareConfigId(w, r)
+	if !ok {
+		return
+	}
+
+	lifecycle, err := GetFirmwareConfigLifecycle(id, applicationType)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	writeFirmwareConfigLifecycle(w, r, lifecycle)
+}
+
+func PutFirmwareConfigLifecycleHandler(w http.ResponseWriter, r *http.Request) {
+	applicationType, err := auth.CanWrite(r, auth.FIRMWARE_ENTITY)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	id, ok := getFirmwareConfigId(w, r)
+	if !ok {
+		return
+	}
+
+	xw, ok := w.(*xwhttp.XResponseWriter)
+	if !ok {
+		xhttp.WriteAdminErrorResponse(w, http.StatusInternalServerError, "responsewriter cast error")
+		return
+	}
+	request := firmwareConfigStateRequest{}
+	if err := json.Unmarshal([]byte(xw.Body()), &request); err != nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, "Unable to extract lifecycle state from json file:"+err.Error())
+		return
+	}
+
+	lifecycle, err := ChangeFirmwareConfigState(r, id, applicationType, request.State, request.Reason)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	writeFirmwareConfigLifecycle(w, r, lifecycle)
+}
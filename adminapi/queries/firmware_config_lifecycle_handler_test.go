@@ -0,0 +1,126 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package queries
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"xconfadmin/adminapi/auth"
+	xcoreef "xconfadmin/shared/estbfirmware"
+	"xconfadmin/testutil"
+	corefw "xconfwebconfig/shared/firmware"
+
+	"gotest.tools/assert"
+)
+
+func putTestFirmwareConfigState(id string, applicationType string, body string, permissions ...string) (*xcoreef.FirmwareConfigLifecycle, int) {
+	r := testutil.NewRequest(http.MethodPut, "/xconfAdminService/firmwareconfig/"+id+"/lifecycle?applicationType="+applicationType, body, permissions...)
+	r = testutil.WithVars(r, map[string]string{"id": id})
+	rr := testutil.Serve(PutFirmwareConfigLifecycleHandler, r)
+	if rr.Code != http.StatusOK {
+		return nil, rr.Code
+	}
+	lifecycle := &xcoreef.FirmwareConfigLifecycle{}
+	json.Unmarshal(rr.Body.Bytes(), lifecycle)
+	return lifecycle, rr.Code
+}
+
+func newTestConfigRule(name string, applicationType string, configId string) *corefw.FirmwareRule {
+	rule := corefw.NewEmptyFirmwareRule()
+	rule.Name = name
+	rule.Type = corefw.MAC_RULE
+	rule.ApplicationType = applicationType
+	rule.ApplicableAction = corefw.NewApplicableActionAndType(corefw.RuleActionClass, corefw.RULE, configId)
+	return rule
+}
+
+func TestFirmwareConfigLifecycleIsScopedToApplicationType(t *testing.T) {
+	testutil.SetupTestDB()
+	createTestModel(t, "MODEL1")
+	config, _ := postTestFirmwareConfig(t, "rdkcloud", auth.WRITE_FIRMWARE_ALL)
+	body := `{"state":"deprecated","reason":"replaced by 2.0"}`
+
+	_, status := putTestFirmwareConfigState(config.ID, "rdkcloud", body, auth.WRITE_FIRMWARE_STB)
+	assert.Equal(t, status, http.StatusForbidden)
+	_, status = putTestFirmwareConfigState(config.ID, "rdkcloud", body, auth.READ_FIRMWARE_RDKCLOUD)
+	assert.Equal(t, status, http.StatusForbidden)
+	// the config is not found through another application type
+	_, status = putTestFirmwareConfigState(config.ID, "stb", body, auth.WRITE_FIRMWARE_ALL)
+	assert.Equal(t, status, http.StatusNotFound)
+
+	lifecycle, status := putTestFirmwareConfigState(config.ID, "rdkcloud", body, auth.WRITE_FIRMWARE_RDKCLOUD)
+	assert.Equal(t, status, http.StatusOK)
+	assert.Equal(t, lifecycle.State, xcoreef.FIRMWARE_CONFIG_DEPRECATED)
+	assert.Equal(t, lifecycle.ApplicationType, "rdkcloud")
+	assert.Equal(t, len(lifecycle.History), 1)
+	assert.Equal(t, lifecycle.History[0].ChangedBy, testutil.TestUser)
+
+	_, status = putTestFirmwareConfigState(config.ID, "rdkcloud", `{"state":"retired"}`, auth.WRITE_FIRMWARE_RDKCLOUD)
+	assert.Equal(t, status, http.StatusBadRequest)
+
+	list := func(applicationType string, permissions ...string) []*xcoreef.FirmwareConfigLifecycle {
+		r := testutil.NewRequest(http.MethodGet, "/xconfAdminService/firmwareconfig/lifecycle?applicationType="+applicationType, "", permissions...)
+		rr := testutil.Serve(GetFirmwareConfigLifecyclesHandler, r)
+		assert.Equal(t, rr.Code, http.StatusOK)
+		lifecycles := []*xcoreef.FirmwareConfigLifecycle{}
+		assert.NilError(t, json.Unmarshal(rr.Body.Bytes(), &lifecycles))
+		return lifecycles
+	}
+	assert.Equal(t, len(list("rdkcloud", auth.READ_FIRMWARE_RDKCLOUD)), 1)
+	assert.Equal(t, len(list("stb", auth.READ_FIRMWARE_STB)), 0)
+}
+
+func TestFirmwareConfigLifecycleReferenceChecks(t *testing.T) {
+	testutil.SetupTestDB()
+	createTestModel(t, "MODEL1")
+	config, _ := postTestFirmwareConfig(t, "rdkcloud", auth.WRITE_FIRMWARE_ALL)
+	existingRule := newTestConfigRule("existing", "rdkcloud", config.ID)
+	assert.NilError(t, corefw.CreateFirmwareRuleOneDB(existingRule))
+
+	_, status := putTestFirmwareConfigState(config.ID, "rdkcloud", `{"state":"deprecated","reason":"replaced by 2.0"}`, auth.WRITE_FIRMWARE_RDKCLOUD)
+	assert.Equal(t, status, http.StatusOK)
+	warnings, err := validateFirmwareConfigLifecycle(newTestConfigRule("new", "rdkcloud", config.ID), "rdkcloud")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, warnings, []string{"FirmwareConfig FW_rdkcloud_1.0 is deprecated: replaced by 2.0"})
+
+	_, status = putTestFirmwareConfigState(config.ID, "rdkcloud", `{"state":"retired","reason":"end of life"}`, auth.WRITE_FIRMWARE_RDKCLOUD)
+	assert.Equal(t, status, http.StatusOK)
+	_, err = validateFirmwareConfigLifecycle(newTestConfigRule("new", "rdkcloud", config.ID), "rdkcloud")
+	assert.ErrorContains(t, err, "FirmwareConfig FW_rdkcloud_1.0 is retired and cannot be assigned: end of life")
+	// the rule which already pointed at the config can still be saved
+	warnings, err = validateFirmwareConfigLifecycle(existingRule, "rdkcloud")
+	assert.NilError(t, err)
+	assert.Equal(t, len(warnings), 0)
+
+	report := func(applicationType string, permissions ...string) []*FirmwareConfigReference {
+		r := testutil.NewRequest(http.MethodGet, "/xconfAdminService/firmwareconfig/lifecycle/report?applicationType="+applicationType, "", permissions...)
+		rr := testutil.Serve(GetFirmwareConfigLifecycleReportHandler, r)
+		assert.Equal(t, rr.Code, http.StatusOK)
+		references := []*FirmwareConfigReference{}
+		assert.NilError(t, json.Unmarshal(rr.Body.Bytes(), &references))
+		return references
+	}
+	references := report("rdkcloud", auth.READ_FIRMWARE_RDKCLOUD)
+	assert.Equal(t, len(references), 1)
+	assert.Equal(t, references[0].EntityID, existingRule.ID)
+	assert.Equal(t, references[0].EntityType, CONFIG_REFERENCE_FIRMWARE_RULE)
+	assert.Equal(t, references[0].State, xcoreef.FIRMWARE_CONFIG_RETIRED)
+	assert.Equal(t, len(report("stb", auth.READ_FIRMWARE_STB)), 0)
+}
@@ -0,0 +1,250 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package queries
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"xconfadmin/adminapi/auth"
+	xcommon "xconfadmin/common"
+	xcoreef "xconfadmin/shared/estbfirmware"
+	coreef "xconfwebconfig/shared/estbfirmware"
+	corefw "xconfwebconfig/shared/firmware"
+	"xconfwebconfig/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// entity types of FirmwareConfigReference
+const (
+	CONFIG_REFERENCE_FIRMWARE_RULE      = "FIRMWARE_RULE"
+	CONFIG_REFERENCE_PERCENTAGE_BEAN    = "PERCENTAGE_BEAN"
+	CONFIG_REFERENCE_ACTIVATION_VERSION = "ACTIVATION_VERSION"
+)
+
+// FirmwareConfigReference is a rule, percentage bean or AMV pointing at a firmware config which is not active
+type FirmwareConfigReference struct {
+	EntityType      string `json:"entityType"`
+	EntityID        string `json:"entityId"`
+	EntityName      string `json:"entityName"`
+	ConfigID        string `json:"configId"`
+	FirmwareVersion string `json:"firmwareVersion"`
+	State           string `json:"state"`
+	Reason          string `json:"reason,omitempty"`
+}
+
+// firmwareConfigIndex holds the firmware configs of an application type by id and by firmware version
+type firmwareConfigIndex struct {
+	configs   map[string]*coreef.FirmwareConfig
+	byVersion map[string][]string
+}
+
+func newFirmwareConfigIndex(applicationType string) *firmwareConfigIndex {
+	index := &firmwareConfigIndex{
+		configs:   map[string]*coreef.FirmwareConfig{},
+		byVersion: map[string][]string{},
+	}
+	for _, config := range GetFirmwareConfigsAS(applicationType) {
+		index.configs[config.ID] = config
+		index.byVersion[config.FirmwareVersion] = append(index.byVersion[config.FirmwareVersion], config.ID)
+	}
+	return index
+}
+
+func (i *firmwareConfigIndex) getFirmwareVersion(configId string) string {
+	if config, ok := i.configs[configId]; ok {
+		return config.FirmwareVersion
+	}
+	return configId
+}
+
+// getReferencedConfigIds returns the ids of the configs the rule assigns, the firmware versions
+// of an AMV are resolved to the configs with these versions
+func (i *firmwareConfigIndex) getReferencedConfigIds(rule *corefw.FirmwareRule) []string {
+	ids := util.Set{}
+	action := rule.ApplicableAction
+	if action == nil {
+		return ids.ToSlice()
+	}
+	if action.ConfigId != "" {
+		ids.Add(action.ConfigId)
+	}
+	if action.IntermediateVersion != "" {
+		ids.Add(action.IntermediateVersion)
+	}
+	for _, entry := range action.ConfigEntries {
+		if entry.ConfigId != "" {
+			ids.Add(entry.ConfigId)
+		}
+	}
+	if rule.Type == coreef.ACTIVATION_VERSION {
+		for _, version := range action.GetFirmwareVersions() {
+			ids.Add(i.byVersion[version]...)
+		}
+	}
+	return ids.ToSlice()
+}
+
+func getFirmwareConfigReferenceType(rule *corefw.FirmwareRule) string {
+	switch rule.Type {
+	case corefw.ENV_MODEL_RULE:
+		return CONFIG_REFERENCE_PERCENTAGE_BEAN
+	case coreef.ACTIVATION_VERSION:
+		return CONFIG_REFERENCE_ACTIVATION_VERSION
+	}
+	return CONFIG_REFERENCE_FIRMWARE_RULE
+}
+
+// validateFirmwareConfigLifecycle rejects a rule which newly references a retired or recalled firmware config
+// and returns warnings for the deprecated configs it newly references
+func validateFirmwareConfigLifecycle(rule *corefw.FirmwareRule, applicationType string) ([]string, error) {
+	warnings := []string{}
+	lifecycles := xcoreef.GetFirmwareConfigLifecycles(applicationType)
+	if len(lifecycles) == 0 || rule.ApplicableAction == nil {
+		return warnings, nil
+	}
+	index := newFirmwareConfigIndex(applicationType)
+	previous := util.Set{}
+	if existing, err := corefw.GetFirmwareRuleOneDB(rule.ID); err == nil {
+		previous.Add(index.getReferencedConfigIds(existing)...)
+	}
+
+	configIds := index.getReferencedConfigIds(rule)
+	sort.Strings(configIds)
+	for _, configId := range configIds {
+		lifecycle, ok := lifecycles[configId]
+		if !ok || lifecycle.State == xcoreef.FIRMWARE_CONFIG_ACTIVE || previous.Contains(configId) {
+			continue
+		}
+		if !lifecycle.IsAssignable() {
+			return nil, xcommon.NewXconfError(http.StatusConflict, fmt.Sprintf("FirmwareConfig %s is %s and cannot be assigned: %s", index.getFirmwareVersion(configId), strings.ToLower(lifecycle.State), lifecycle.Reason))
+		}
+		warnings = append(warnings, fmt.Sprintf("FirmwareConfig %s is deprecated: %s", index.getFirmwareVersion(configId), lifecycle.Reason))
+	}
+	return warnings, nil
+}
+
+// GetFirmwareConfigLifecycleWarnings returns the warnings for the deprecated configs the rule newly references,
+// it must be called before the rule is saved
+func GetFirmwareConfigLifecycleWarnings(rule *corefw.FirmwareRule, applicationType string) []string {
+	warnings, err := validateFirmwareConfigLifecycle(rule, applicationType)
+	if err != nil {
+		return []string{}
+	}
+	return warnings
+}
+
+// GetPercentageBeanLifecycleWarnings returns the warnings for the deprecated configs the percentage bean newly references
+func GetPercentageBeanLifecycleWarnings(bean *coreef.PercentageBean, applicationType string) []string {
+	for _, distribution := range bean.Distributions {
+		if distribution == nil {
+			return []string{}
+		}
+	}
+	return GetFirmwareConfigLifecycleWarnings(coreef.ConvertPercentageBeanToFirmwareRule(*bean), applicationType)
+}
+
+// GetAmvLifecycleWarnings returns the warnings for the deprecated configs the AMV newly references
+func GetAmvLifecycleWarnings(amv *corefw.ActivationVersion, applicationType string) []string {
+	return GetFirmwareConfigLifecycleWarnings(coreef.ConvertIntoRule(amv), applicationType)
+}
+
+// GetNonActiveFirmwareConfigReferences returns every rule, percentage bean and AMV of the application type
+// which still points at a deprecated, retired or recalled firmware config
+func GetNonActiveFirmwareConfigReferences(applicationType string) []*FirmwareConfigReference {
+	result := []*FirmwareConfigReference{}
+	lifecycles := xcoreef.GetFirmwareConfigLifecycles(applicationType)
+	if len(lifecycles) == 0 {
+		return result
+	}
+	rules, err := corefw.GetFirmwareRulesByApplicationType(applicationType)
+	if err != nil {
+		log.Warnf("no firmware rules of %s to check: %s", applicationType, err.Error())
+		return result
+	}
+	index := newFirmwareConfigIndex(applicationType)
+	for _, rule := range rules {
+		for _, configId := range index.getReferencedConfigIds(rule) {
+			lifecycle, ok := lifecycles[configId]
+			if !ok || lifecycle.State == xcoreef.FIRMWARE_CONFIG_ACTIVE {
+				continue
+			}
+			result = append(result, &FirmwareConfigReference{
+				EntityType:      getFirmwareConfigReferenceType(rule),
+				EntityID:        rule.ID,
+				EntityName:      rule.Name,
+				ConfigID:        configId,
+				FirmwareVersion: index.getFirmwareVersion(configId),
+				State:           lifecycle.State,
+				Reason:          lifecycle.Reason,
+			})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].EntityType != result[j].EntityType {
+			return result[i].EntityType < result[j].EntityType
+		}
+		if result[i].EntityName != result[j].EntityName {
+			return strings.ToLower(result[i].EntityName) < strings.ToLower(result[j].EntityName)
+		}
+		return result[i].FirmwareVersion < result[j].FirmwareVersion
+	})
+	return result
+}
+
+// GetFirmwareConfigLifecycle returns the lifecycle of the firmware config, a config without one is active
+func GetFirmwareConfigLifecycle(id string, applicationType string) (*xcoreef.FirmwareConfigLifecycle, error) {
+	config := GetFirmwareConfigByIdAS(id)
+	if config == nil || config.ApplicationType != applicationType {
+		return nil, xcommon.NewXconfError(http.StatusNotFound, "Entity with id: "+id+" does not exist")
+	}
+	if lifecycle := xcoreef.GetFirmwareConfigLifecycle(id); lifecycle != nil {
+		return lifecycle, nil
+	}
+	return &xcoreef.FirmwareConfigLifecycle{
+		ID:              id,
+		ApplicationType: applicationType,
+		State:           xcoreef.FIRMWARE_CONFIG_ACTIVE,
+		History:         []*xcoreef.FirmwareConfigStateChange{},
+	}, nil
+}
+
+// ChangeFirmwareConfigState moves the firmware config to the lifecycle state, a reason is required unless it becomes active
+func ChangeFirmwareConfigState(r *http.Request, id string, applicationType string, state string, reason string) (*xcoreef.FirmwareConfigLifecycle, error) {
+	state = strings.ToUpper(strings.TrimSpace(state))
+	if !util.Contains(xcoreef.FirmwareConfigStates, state) {
+		return nil, xcommon.NewXconfError(http.StatusBadRequest, fmt.Sprintf("State must be one of %s", strings.Join(xcoreef.FirmwareConfigStates, ", ")))
+	}
+	reason = strings.TrimSpace(reason)
+	if state != xcoreef.FIRMWARE_CONFIG_ACTIVE && reason == "" {
+		return nil, xcommon.NewXconfError(http.StatusBadRequest, "Reason is required")
+	}
+	lifecycle, err := GetFirmwareConfigLifecycle(id, applicationType)
+	if err != nil {
+		return nil, err
+	}
+	lifecycle.ChangeState(state, reason, auth.GetUserNameOrUnknown(r))
+	if err := xcoreef.SetFirmwareConfigLifecycle(lifecycle); err != nil {
+		return nil, err
+	}
+	log.Infof("firmware config %s is %s: %s", id, state, reason)
+	return lifecycle, nil
+}
@@ -25,12 +25,14 @@ import (
 	"time"
 
 	xshared "xconfadmin/shared"
+	xcoreef "xconfadmin/shared/estbfirmware"
 	xutil "xconfadmin/util"
 
 	xwcommon "xconfwebconfig/common"
 	ru "xconfwebconfig/rulesengine"
 
 	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
 	ds "xconfwebconfig/db"
 	xwhttp "xconfwebconfig/http"
 	"xconfwebconfig/shared"
@@ -274,14 +276,17 @@ func filterFirmwareConfigsByContext(entries []*coreef.FirmwareConfig, searchCont
 }
 
 func beforeCreatingFirmwareConfig(entity *coreef.FirmwareConfig, writeApplication string) error {
+	if util.IsBlank(entity.ApplicationType) {
+		entity.ApplicationType = writeApplication
+	} else if entity.ApplicationType != writeApplication {
+		return xcommon.NewXconfError(http.StatusConflict, "ApplicationType conflict")
+	}
+	if err := xshared.ValidateApplicationTypeEntity(entity.ApplicationType, entity); err != nil {
+		return err
+	}
 	if util.IsBlank(entity.ID) {
 		entity.ID = uuid.New().String()
 	} else {
-		if util.IsBlank(entity.ApplicationType) {
-			entity.ApplicationType = writeApplication
-		} else if entity.ApplicationType != writeApplication {
-			return xcommon.NewXconfError(http.StatusConflict, "ApplicationType conflict")
-		}
 		entity.Updated = util.GetTimestamp(time.Now().UTC())
 		existingEntity, _ := coreef.GetFirmwareConfigOneDB(entity.ID)
 
@@ -292,7 +297,7 @@ func beforeCreatingFirmwareConfig(entity *coreef.FirmwareConfig, writeApplicatio
 	return nil
 }
 
-func CreateFirmwareConfigAS(config *coreef.FirmwareConfig, appType string, validateName bool) *xwhttp.ResponseEntity {
+func CreateFirmwareConfigAS(config *coreef.FirmwareConfig, appType string, validateName bool, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
 	for i, id := range config.SupportedModelIds {
 		config.SupportedModelIds[i] = strings.ToUpper(id)
 	}
@@ -310,6 +315,10 @@ func CreateFirmwareConfigAS(config *coreef.FirmwareConfig, appType string, valid
 	if err = beforeCreatingFirmwareConfig(config, appType); err != nil {
 		return xwhttp.NewResponseEntity(http.StatusConflict, err, nil)
 	}
+	if dryRun != nil {
+		dryRun.Record(xhttp.DRY_RUN_CREATE, ds.TABLE_FIRMWARE_CONFIG, config.ID, config)
+		return xwhttp.NewResponseEntity(http.StatusCreated, nil, config)
+	}
 
 	err = coreef.CreateFirmwareConfigOneDB(config)
 	if err != nil {
@@ -318,8 +327,8 @@ func CreateFirmwareConfigAS(config *coreef.FirmwareConfig, appType string, valid
 	return xwhttp.NewResponseEntity(http.StatusCreated, nil, config)
 }
 
-func CreateFirmwareConfig(config *coreef.FirmwareConfig, appType string) *xwhttp.ResponseEntity {
-	if err := CreateFirmwareConfigAS(config, appType, true); err != nil {
+func CreateFirmwareConfig(config *coreef.FirmwareConfig, appType string, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
+	if err := CreateFirmwareConfigAS(config, appType, true, dryRun); err != nil {
 		return err
 	}
 	resp := config.CreateFirmwareConfigResponse()
@@ -342,10 +351,10 @@ func beforeUpdatingFirmwareConfig(entity *coreef.FirmwareConfig, writeApplicatio
 	if existingEntity == nil || existingEntity.ApplicationType != entity.ApplicationType {
 		return xcommon.NewXconfError(http.StatusNotFound, "Entity with id: "+entity.ID+" does not exist in "+existingEntity.ApplicationType+" application")
 	}
-	return nil
+	return xshared.ValidateApplicationTypeEntity(entity.ApplicationType, entity)
 }
 
-func UpdateFirmwareConfigAS(config *coreef.FirmwareConfig, appType string, validateName bool) *xwhttp.ResponseEntity {
+func UpdateFirmwareConfigAS(config *coreef.FirmwareConfig, appType string, validateName bool, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
 	for i, id := range config.SupportedModelIds {
 		config.SupportedModelIds[i] = strings.ToUpper(id)
 	}
@@ -367,6 +376,10 @@ func UpdateFirmwareConfigAS(config *coreef.FirmwareConfig, appType string, valid
 	if err = beforeUpdatingFirmwareConfig(config, appType); err != nil {
 		return xwhttp.NewResponseEntity(http.StatusNotFound, err, nil)
 	}
+	if dryRun != nil {
+		dryRun.Record(xhttp.DRY_RUN_UPDATE, ds.TABLE_FIRMWARE_CONFIG, config.ID, config)
+		return xwhttp.NewResponseEntity(http.StatusOK, nil, config)
+	}
 
 	err = ds.GetCachedSimpleDao().SetOne(ds.TABLE_FIRMWARE_CONFIG, config.ID, config)
 	if err != nil {
@@ -375,8 +388,8 @@ func UpdateFirmwareConfigAS(config *coreef.FirmwareConfig, appType string, valid
 	return xwhttp.NewResponseEntity(http.StatusOK, nil, config)
 }
 
-func UpdateFirmwareConfig(config *coreef.FirmwareConfig, appType string) *xwhttp.ResponseEntity {
-	if err := UpdateFirmwareConfigAS(config, appType, true); err != nil {
+func UpdateFirmwareConfig(config *coreef.FirmwareConfig, appType string, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
+	if err := UpdateFirmwareConfigAS(config, appType, true, dryRun); err != nil {
 		return err
 	}
 	resp := config.CreateFirmwareConfigResponse()
@@ -424,15 +437,24 @@ func beforeDeletingFirmwareConfig(id string, appType string) *xwhttp.ResponseEnt
 	return xwhttp.NewResponseEntity(http.StatusOK, nil, entity)
 }
 
-func DeleteFirmwareConfig(id string, appType string) *xwhttp.ResponseEntity {
+func DeleteFirmwareConfig(id string, appType string, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
 	err := beforeDeletingFirmwareConfig(id, appType)
 	if err.Error != nil {
 		return err
 	}
+	if dryRun != nil {
+		dryRun.Record(xhttp.DRY_RUN_DELETE, ds.TABLE_FIRMWARE_CONFIG, id, err.Data)
+		return xwhttp.NewResponseEntity(http.StatusNoContent, nil, nil)
+	}
 	err2 := coreef.DeleteOneFirmwareConfig(id)
 	if err2 != nil {
 		return xwhttp.NewResponseEntity(http.StatusInternalServerError, err2, nil)
 	}
+	if xcoreef.GetFirmwareConfigLifecycle(id) != nil {
+		if err := xcoreef.DeleteFirmwareConfigLifecycle(id); err != nil {
+			log.Errorf("unable to delete lifecycle of firmware config %s: %s", id, err.Error())
+		}
+	}
 	return xwhttp.NewResponseEntity(http.StatusNoContent, nil, nil)
 }
 
@@ -491,6 +513,8 @@ func GetSortedFirmwareVersionsIfDoesExistOrNot(firmwareConfigData FirmwareConfig
 			notExistedVersions = append(notExistedVersions, firmwareVersion)
 		}
 	}
+	sortFirmwareVersions(applicationType, firmwareConfigData.ModelSet, existedVersions)
+	sortFirmwareVersions(applicationType, firmwareConfigData.ModelSet, notExistedVersions)
 	firmwareVersionMap[cFirmwareConfigExistedVersions] = existedVersions
 	firmwareVersionMap[cFirmwareConfigNotExistedVersions] = notExistedVersions
 	return firmwareVersionMap
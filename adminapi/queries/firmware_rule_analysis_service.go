@@ -0,0 +1,555 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package queries
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"xconfwebconfig/common"
+	re "xconfwebconfig/rulesengine"
+	"xconfwebconfig/shared"
+	"xconfwebconfig/shared/firmware"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// reference types of FirmwareRuleReference
+const (
+	REFERENCE_MODEL           = "MODEL"
+	REFERENCE_ENVIRONMENT     = "ENVIRONMENT"
+	REFERENCE_NAMESPACED_LIST = "NAMESPACED_LIST"
+	REFERENCE_TEMPLATE        = "TEMPLATE"
+)
+
+// a rule whose conditions expand to more alternatives than this is not compared with other rules
+const maxRuleAlternatives = 64
+
+// FirmwareRuleAnalysis reports the rules of an application type which never or only partly match
+// because other rules are evaluated first, and the rules referencing entities which do not exist
+type FirmwareRuleAnalysis struct {
+	ApplicationType   string                   `json:"applicationType"`
+	Shadowed          []FirmwareRuleOverlap    `json:"shadowed"`
+	ExactOverlaps     []FirmwareRuleOverlap    `json:"exactOverlaps"`
+	PartialOverlaps   []FirmwareRuleOverlap    `json:"partialOverlaps"`
+	InvalidReferences []FirmwareRuleReference  `json:"invalidReferences"`
+	NotAnalyzed       []FirmwareRuleDescriptor `json:"notAnalyzed"`
+}
+
+// FirmwareRuleWithWarnings is returned by the create of a rule with the warnings parameter,
+// the rule is only saved when there are no warnings
+type FirmwareRuleWithWarnings struct {
+	FirmwareRule *firmware.FirmwareRule `json:"firmwareRule"`
+	Warnings     []string               `json:"warnings"`
+}
+
+type FirmwareRuleDescriptor struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Priority int32  `json:"priority"`
+}
+
+// FirmwareRuleOverlap is a pair of rules matching the same devices, for a shadowed rule OtherRule always wins
+type FirmwareRuleOverlap struct {
+	Rule      FirmwareRuleDescriptor `json:"rule"`
+	OtherRule FirmwareRuleDescriptor `json:"otherRule"`
+	Message   string                 `json:"message"`
+}
+
+type FirmwareRuleReference struct {
+	Rule          FirmwareRuleDescriptor `json:"rule"`
+	ReferenceType string                 `json:"referenceType"`
+	Reference     string                 `json:"reference"`
+	Message       string                 `json:"message"`
+}
+
+// ruleLiteral is a condition of a rule, a value set of IS or IN is compared with the other conditions of the same argument
+type ruleLiteral struct {
+	freeArg   string
+	operation string
+	negated   bool
+	values    []string
+}
+
+// ruleListValues caches the data of the namespaced lists referenced by IN_LIST, nil for the lists
+// which are missing or hold ip addresses, ranges can't be compared as value sets
+type ruleListValues map[string][]string
+
+// ruleAlternative is a conjunction of conditions, a rule matches if any of its alternatives does
+type ruleAlternative []ruleLiteral
+
+type analyzedFirmwareRule struct {
+	rule         *firmware.FirmwareRule
+	template     *firmware.FirmwareRuleTemplate
+	conditions   int
+	alternatives []ruleAlternative
+}
+
+// AnalyzeFirmwareRules compares the active rules of the templates where the first matching rule wins.
+// When ruleId is set only the findings of this rule are returned
+func AnalyzeFirmwareRules(applicationType string, ruleId string) *FirmwareRuleAnalysis {
+	return analyzeFirmwareRules(applicationType, nil, ruleId)
+}
+
+// AnalyzeFirmwareRule returns the findings of a rule before it is saved, the rule replaces the stored one with the same id
+func AnalyzeFirmwareRule(rule *firmware.FirmwareRule, applicationType string) *FirmwareRuleAnalysis {
+	return analyzeFirmwareRules(applicationType, rule, rule.ID)
+}
+
+func analyzeFirmwareRules(applicationType string, candidate *firmware.FirmwareRule, ruleId string) *FirmwareRuleAnalysis {
+	analysis := &FirmwareRuleAnalysis{
+		ApplicationType:   applicationType,
+		Shadowed:          []FirmwareRuleOverlap{},
+		ExactOverlaps:     []FirmwareRuleOverlap{},
+		PartialOverlaps:   []FirmwareRuleOverlap{},
+		InvalidReferences: []FirmwareRuleReference{},
+		NotAnalyzed:       []FirmwareRuleDescriptor{},
+	}
+	rules, err := firmware.GetFirmwareRulesByApplicationType(applicationType)
+	if err != nil && candidate == nil {
+		log.Warnf("no firmware rules of %s to analyze: %s", applicationType, err.Error())
+		return analysis
+	}
+	if candidate != nil {
+		stored := rules
+		rules = []*firmware.FirmwareRule{candidate}
+		for _, rule := range stored {
+			if rule.ID != candidate.ID {
+				rules = append(rules, rule)
+			}
+		}
+	}
+	templates := map[string]*firmware.FirmwareRuleTemplate{}
+	for _, actionType := range []firmware.ApplicableActionType{firmware.RULE_TEMPLATE, firmware.DEFINE_PROPERTIES_TEMPLATE, firmware.BLOCKING_FILTER_TEMPLATE} {
+		list, _ := firmware.GetFirmwareRuleTemplateAllAsListByActionType(actionType)
+		for _, template := range list {
+			templates[template.ID] = template
+		}
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].ID < rules[j].ID
+	})
+
+	lists := ruleListValues{}
+	byActionType := map[firmware.ApplicableActionType][]*analyzedFirmwareRule{}
+	for _, rule := range rules {
+		focused := ruleId == "" || rule.ID == ruleId
+		template := templates[rule.Type]
+		if focused {
+			analysis.addInvalidReferences(rule, template)
+		}
+		if template == nil || !rule.Active || !isFirstMatchActionType(template) {
+			continue
+		}
+		alternatives, ok := toRuleAlternatives(&rule.Rule, lists)
+		if !ok {
+			if focused {
+				analysis.NotAnalyzed = append(analysis.NotAnalyzed, newFirmwareRuleDescriptor(rule, template))
+			}
+			continue
+		}
+		actionType := template.ApplicableAction.ActionType
+		byActionType[actionType] = append(byActionType[actionType], &analyzedFirmwareRule{
+			rule:         rule,
+			template:     template,
+			conditions:   len(re.ToConditions(&rule.Rule)),
+			alternatives: alternatives,
+		})
+	}
+
+	for _, analyzed := range byActionType {
+		for i := 0; i < len(analyzed); i++ {
+			for j := i + 1; j < len(analyzed); j++ {
+				if ruleId == "" || analyzed[i].rule.ID == ruleId || analyzed[j].rule.ID == ruleId {
+					analysis.compare(analyzed[i], analyzed[j])
+				}
+			}
+		}
+	}
+	return analysis
+}
+
+// Warnings returns the messages of all findings
+func (a *FirmwareRuleAnalysis) Warnings() []string {
+	warnings := []string{}
+	for _, list := range [][]FirmwareRuleOverlap{a.Shadowed, a.ExactOverlaps, a.PartialOverlaps} {
+		for _, overlap := range list {
+			warnings = append(warnings, overlap.Message)
+		}
+	}
+	for _, reference := range a.InvalidReferences {
+		warnings = append(warnings, reference.Message)
+	}
+	for _, rule := range a.NotAnalyzed {
+		warnings = append(warnings, fmt.Sprintf("Conditions of rule %s are too complex to compare with other rules", rule.Name))
+	}
+	return warnings
+}
+
+// isFirstMatchActionType returns true for the templates whose rules are evaluated until the first match,
+// all matching rules of DEFINE_PROPERTIES templates are applied
+func isFirstMatchActionType(template *firmware.FirmwareRuleTemplate) bool {
+	if template.ApplicableAction == nil {
+		return false
+	}
+	actionType := template.ApplicableAction.ActionType
+	return actionType == firmware.RULE_TEMPLATE || actionType == firmware.BLOCKING_FILTER_TEMPLATE
+}
+
+func newFirmwareRuleDescriptor(rule *firmware.FirmwareRule, template *firmware.FirmwareRuleTemplate) FirmwareRuleDescriptor {
+	descriptor := FirmwareRuleDescriptor{
+		ID:   rule.ID,
+		Name: rule.Name,
+		Type: rule.Type,
+	}
+	if template != nil {
+		descriptor.Priority = template.Priority
+	}
+	return descriptor
+}
+
+// evaluatedBefore returns true if rule a is evaluated before rule b, determined is false if the order is not defined:
+// templates go by priority, rules of ENV_MODEL_RULE and ACTIVATION_VERSION by the number of conditions descending
+func evaluatedBefore(a *analyzedFirmwareRule, b *analyzedFirmwareRule) (before bool, determined bool) {
+	if a.template.ID != b.template.ID {
+		if a.template.Priority == b.template.Priority {
+			return false, false
+		}
+		return a.template.Priority < b.template.Priority, true
+	}
+	if (a.template.ID == firmware.ENV_MODEL_RULE || a.template.ID == firmware.ACTIVATION_VERSION) && a.conditions != b.conditions {
+		return a.conditions > b.conditions, true
+	}
+	return false, false
+}
+
+func (a *FirmwareRuleAnalysis) compare(first *analyzedFirmwareRule, second *analyzedFirmwareRule) {
+	firstInSecond := containsRule(second, first)
+	secondInFirst := containsRule(first, second)
+	before, determined := evaluatedBefore(first, second)
+	winner, loser := first, second
+	if !before {
+		winner, loser = second, first
+	}
+	firstRule := newFirmwareRuleDescriptor(first.rule, first.template)
+	secondRule := newFirmwareRuleDescriptor(second.rule, second.template)
+
+	switch {
+	case firstInSecond && secondInFirst:
+		message := fmt.Sprintf("Rules %s and %s match the same devices", first.rule.Name, second.rule.Name)
+		if !determined {
+			message += ", the rule which wins is not defined"
+		}
+		a.ExactOverlaps = append(a.ExactOverlaps, FirmwareRuleOverlap{Rule: firstRule, OtherRule: secondRule, Message: message})
+		if determined {
+			a.addShadowed(loser, winner)
+		}
+	case determined && ((before && secondInFirst) || (!before && firstInSecond)):
+		a.addShadowed(loser, winner)
+	case determined && (firstInSecond || secondInFirst):
+		// the narrower rule is evaluated first, which is the intended use of a more specific rule
+	case overlapsRule(first, second):
+		message := fmt.Sprintf("Rules %s and %s both match some devices", first.rule.Name, second.rule.Name)
+		if determined {
+			message += fmt.Sprintf(", %s is evaluated first", winner.rule.Name)
+		} else {
+			message += ", the rule which wins is not defined"
+		}
+		a.PartialOverlaps = append(a.PartialOverlaps, FirmwareRuleOverlap{Rule: firstRule, OtherRule: secondRule, Message: message})
+	}
+}
+
+func (a *FirmwareRuleAnalysis) addShadowed(shadowed *analyzedFirmwareRule, by *analyzedFirmwareRule) {
+	a.Shadowed = append(a.Shadowed, FirmwareRuleOverlap{
+		Rule:      newFirmwareRuleDescriptor(shadowed.rule, shadowed.template),
+		OtherRule: newFirmwareRuleDescriptor(by.rule, by.template),
+		Message:   fmt.Sprintf("Rule %s can never match, every device it matches is matched first by %s", shadowed.rule.Name, by.rule.Name),
+	})
+}
+
+func (a *FirmwareRuleAnalysis) addInvalidReferences(rule *firmware.FirmwareRule, template *firmware.FirmwareRuleTemplate) {
+	descriptor := newFirmwareRuleDescriptor(rule, template)
+	add := func(referenceType string, reference string) {
+		a.InvalidReferences = append(a.InvalidReferences, FirmwareRuleReference{
+			Rule:          descriptor,
+			ReferenceType: referenceType,
+			Reference:     reference,
+			Message:       fmt.Sprintf("Rule %s references %s %s which does not exist", rule.Name, strings.ToLower(strings.ReplaceAll(referenceType, "_", " ")), reference),
+		})
+	}
+	if template == nil {
+		add(REFERENCE_TEMPLATE, rule.Type)
+	}
+	for _, condition := range re.ToConditions(&rule.Rule) {
+		if condition.GetFreeArg() == nil {
+			continue
+		}
+		literal := newRuleLiteral(condition, false)
+		switch {
+		case literal.operation == re.StandardOperationInList:
+			for _, listId := range literal.values {
+				if _, err := shared.GetGenericNamedListOneDB(listId); err != nil {
+					add(REFERENCE_NAMESPACED_LIST, listId)
+				}
+			}
+		case !literal.isValueSet():
+		case literal.freeArg == common.MODEL:
+			for _, model := range literal.values {
+				if shared.GetOneModel(strings.ToUpper(model)) == nil {
+					add(REFERENCE_MODEL, model)
+				}
+			}
+		case literal.freeArg == common.ENV:
+			for _, env := range literal.values {
+				if shared.GetOneEnvironment(strings.ToUpper(env)) == nil {
+					add(REFERENCE_ENVIRONMENT, env)
+				}
+			}
+		}
+	}
+}
+
+// toRuleAlternatives expands the rule into alternatives, compound parts are combined from left to right
+// like the rule processor does. A negated compound rule is not expanded
+func toRuleAlternatives(rule *re.Rule, lists ruleListValues) ([]ruleAlternative, bool) {
+	if rule.Condition != nil {
+		if rule.Condition.GetFreeArg() == nil {
+			return nil, false
+		}
+		return []ruleAlternative{{lists.resolve(newRuleLiteral(rule.Condition, rule.Negated))}}, true
+	}
+	if rule.Negated || len(rule.CompoundParts) == 0 {
+		return nil, false
+	}
+	var result []ruleAlternative
+	for i := range rule.CompoundParts {
+		part := &rule.CompoundParts[i]
+		alternatives, ok := toRuleAlternatives(part, lists)
+		if !ok {
+			return nil, false
+		}
+		switch {
+		case i == 0:
+			result = alternatives
+		case part.Relation == re.RelationOr:
+			result = append(result, alternatives...)
+		default:
+			product := []ruleAlternative{}
+			for _, left := range result {
+				for _, right := range alternatives {
+					combined := append(append(ruleAlternative{}, left...), right...)
+					product = append(product, combined)
+				}
+			}
+			result = product
+		}
+		if len(result) > maxRuleAlternatives {
+			return nil, false
+		}
+	}
+	return result, true
+}
+
+func newRuleLiteral(condition *re.Condition, negated bool) ruleLiteral {
+	literal := ruleLiteral{
+		freeArg:   condition.GetFreeArg().GetName(),
+		operation: condition.GetOperation(),
+		negated:   negated,
+		values:    []string{},
+	}
+	switch value := condition.GetFixedArg().GetValue().(type) {
+	case string:
+		literal.values = []string{value}
+	case []string:
+		literal.values = append(literal.values, value...)
+		sort.Strings(literal.values)
+	case float64:
+		literal.values = []string{fmt.Sprintf("%v", value)}
+	}
+	return literal
+}
+
+// resolve turns an IN_LIST literal into an IN of the list data, so rules on different lists
+// or on a list and single values are compared by their values. Unresolved lists compare by name
+func (lists ruleListValues) resolve(literal ruleLiteral) ruleLiteral {
+	if literal.operation != re.StandardOperationInList {
+		return literal
+	}
+	values := []string{}
+	for _, listId := range literal.values {
+		data, ok := lists[listId]
+		if !ok {
+			if list, err := shared.GetGenericNamedListOneDB(listId); err == nil && !list.IsIpList() {
+				data = list.Data
+				if list.IsMacList() {
+					data = make([]string, 0, len(list.Data))
+					for _, mac := range list.Data {
+						data = append(data, strings.ToUpper(mac))
+					}
+				}
+			}
+			lists[listId] = data
+		}
+		if data == nil {
+			return literal
+		}
+		values = append(values, data...)
+	}
+	sort.Strings(values)
+	literal.operation = re.StandardOperationIn
+	literal.values = values
+	return literal
+}
+
+func (l ruleLiteral) isValueSet() bool {
+	return l.operation == re.StandardOperationIs || l.operation == re.StandardOperationIn
+}
+
+func (l ruleLiteral) equals(other ruleLiteral) bool {
+	return l.freeArg == other.freeArg && l.operation == other.operation && l.negated == other.negated &&
+		strings.Join(l.values, "\n") == strings.Join(other.values, "\n")
+}
+
+// implies returns true if every device matching l also matches other
+func (l ruleLiteral) implies(other ruleLiteral) bool {
+	if l.freeArg != other.freeArg {
+		return false
+	}
+	if l.equals(other) {
+		return true
+	}
+	if !l.isValueSet() || !other.isValueSet() {
+		return false
+	}
+	switch {
+	case !l.negated && !other.negated:
+		return isSubset(l.values, other.values)
+	case !l.negated && other.negated:
+		return !intersects(l.values, other.values)
+	case l.negated && other.negated:
+		return isSubset(other.values, l.values)
+	}
+	return false
+}
+
+// conflicts returns true if no device can match both literals
+func (l ruleLiteral) conflicts(other ruleLiteral) bool {
+	if l.freeArg != other.freeArg {
+		return false
+	}
+	if l.negated != other.negated && l.operation == other.operation && strings.Join(l.values, "\n") == strings.Join(other.values, "\n") {
+		return true
+	}
+	if !l.isValueSet() || !other.isValueSet() {
+		return false
+	}
+	switch {
+	case !l.negated && !other.negated:
+		return !intersects(l.values, other.values)
+	case !l.negated && other.negated:
+		return isSubset(l.values, other.values)
+	case l.negated && !other.negated:
+		return isSubset(other.values, l.values)
+	}
+	return false
+}
+
+func (a ruleAlternative) implies(other ruleAlternative) bool {
+	for _, required := range other {
+		implied := false
+		for _, literal := range a {
+			if literal.implies(required) {
+				implied = true
+				break
+			}
+		}
+		if !implied {
+			return false
+		}
+	}
+	return true
+}
+
+// overlaps returns true if both alternatives restrict a common argument and a device may match both
+func (a ruleAlternative) overlaps(other ruleAlternative) bool {
+	sameArgument := false
+	for _, left := range a {
+		for _, right := range other {
+			if left.conflicts(right) {
+				return false
+			}
+			if left.freeArg == right.freeArg {
+				sameArgument = true
+			}
+		}
+	}
+	return sameArgument
+}
+
+// containsRule returns true if every device matching inner matches outer
+func containsRule(outer *analyzedFirmwareRule, inner *analyzedFirmwareRule) bool {
+	for _, alternative := range inner.alternatives {
+		contained := false
+		for _, outerAlternative := range outer.alternatives {
+			if alternative.implies(outerAlternative) {
+				contained = true
+				break
+			}
+		}
+		if !contained {
+			return false
+		}
+	}
+	return true
+}
+
+func overlapsRule(a *analyzedFirmwareRule, b *analyzedFirmwareRule) bool {
+	for _, left := range a.alternatives {
+		for _, right := range b.alternatives {
+			if left.overlaps(right) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isSubset(values []string, of []string) bool {
+	for _, value := range values {
+		if !containsString(of, value) {
+			return false
+		}
+	}
+	return true
+}
+
+func intersects(a []string, b []string) bool {
+	for _, value := range a {
+		if containsString(b, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	i := sort.SearchStrings(values, value)
+	return i < len(values) && values[i] == value
+}
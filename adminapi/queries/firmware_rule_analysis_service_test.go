@@ -0,0 +1,215 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package queries
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"xconfadmin/adminapi/auth"
+	"xconfadmin/testutil"
+	"xconfwebconfig/common"
+	ds "xconfwebconfig/db"
+	re "xconfwebconfig/rulesengine"
+	"xconfwebconfig/shared"
+	coreef "xconfwebconfig/shared/estbfirmware"
+	corefw "xconfwebconfig/shared/firmware"
+
+	"gotest.tools/assert"
+)
+
+func newTestLiteral(freeArg string, operation string, negated bool, values ...string) ruleLiteral {
+	return ruleLiteral{freeArg: freeArg, operation: operation, negated: negated, values: values}
+}
+
+func createTestAnalysisTemplate(t *testing.T, id string, priority int32) {
+	template := corefw.NewEmptyFirmwareRuleTemplate()
+	template.ID = id
+	template.Priority = priority
+	template.Rule = *re.NewEmptyRule()
+	template.Rule.Condition = re.NewCondition(coreef.RuleFactoryMODEL, re.StandardOperationIs, re.NewFixedArg(""))
+	template.ApplicableAction = corefw.NewTemplateApplicableActionAndType(corefw.RuleActionClass, corefw.RULE_TEMPLATE, "")
+	assert.NilError(t, corefw.CreateFirmwareRuleTemplateOneDB(template))
+}
+
+func createTestNamespacedList(t *testing.T, id string, typeName string, data ...string) {
+	list := &shared.GenericNamespacedList{ID: id, TypeName: typeName, Data: data}
+	assert.NilError(t, ds.GetCachedSimpleDao().SetOne(ds.TABLE_GENERIC_NS_LIST, id, list))
+}
+
+func newTestAnalysisRule(id string, templateId string, condition *re.Condition) *corefw.FirmwareRule {
+	rule := corefw.NewEmptyFirmwareRule()
+	rule.ID = id
+	rule.Name = id
+	rule.Type = templateId
+	rule.ApplicationType = "stb"
+	rule.Rule = *re.NewEmptyRule()
+	rule.Rule.Condition = condition
+	rule.ApplicableAction = corefw.NewApplicableActionAndType(corefw.RuleActionClass, corefw.RULE, "")
+	return rule
+}
+
+func createTestAnalysisRule(t *testing.T, id string, templateId string, condition *re.Condition) {
+	assert.NilError(t, corefw.CreateFirmwareRuleOneDB(newTestAnalysisRule(id, templateId, condition)))
+}
+
+func TestRuleLiteralImplies(t *testing.T) {
+	tests := []struct {
+		literal ruleLiteral
+		other   ruleLiteral
+		implies bool
+	}{
+		{newTestLiteral("model", "IS", false, "X1"), newTestLiteral("model", "IN", false, "X1", "X2"), true},
+		{newTestLiteral("model", "IN", false, "X1", "X2"), newTestLiteral("model", "IS", false, "X1"), false},
+		{newTestLiteral("model", "IS", false, "X1"), newTestLiteral("env", "IS", false, "X1"), false},
+		{newTestLiteral("model", "IS", false, "X1"), newTestLiteral("model", "IN", true, "X2", "X3"), true},
+		{newTestLiteral("model", "IS", false, "X2"), newTestLiteral("model", "IN", true, "X2", "X3"), false},
+		{newTestLiteral("model", "IN", true, "X1", "X2"), newTestLiteral("model", "IS", true, "X1"), true},
+		{newTestLiteral("model", "IS", true, "X1"), newTestLiteral("model", "IN", true, "X1", "X2"), false},
+		{newTestLiteral("model", "IS", true, "X1"), newTestLiteral("model", "IS", false, "X2"), false},
+		{newTestLiteral("model", "LIKE", false, "X.*"), newTestLiteral("model", "LIKE", false, "X.*"), true},
+		{newTestLiteral("model", "LIKE", false, "X1"), newTestLiteral("model", "IS", false, "X1"), false},
+	}
+	for i, test := range tests {
+		assert.Equal(t, test.literal.implies(test.other), test.implies, "case %d", i)
+	}
+}
+
+func TestRuleLiteralConflicts(t *testing.T) {
+	tests := []struct {
+		literal   ruleLiteral
+		other     ruleLiteral
+		conflicts bool
+	}{
+		{newTestLiteral("model", "IS", false, "X1"), newTestLiteral("model", "IS", false, "X2"), true},
+		{newTestLiteral("model", "IN", false, "X1", "X2"), newTestLiteral("model", "IS", false, "X2"), false},
+		{newTestLiteral("model", "IS", false, "X1"), newTestLiteral("env", "IS", false, "X2"), false},
+		{newTestLiteral("model", "IS", false, "X1"), newTestLiteral("model", "IN", true, "X1", "X2"), true},
+		{newTestLiteral("model", "IN", false, "X1", "X3"), newTestLiteral("model", "IN", true, "X1", "X2"), false},
+		{newTestLiteral("model", "IN", true, "X1", "X2"), newTestLiteral("model", "IS", false, "X2"), true},
+		{newTestLiteral("model", "IS", true, "X1"), newTestLiteral("model", "IS", true, "X2"), false},
+		{newTestLiteral("model", "LIKE", false, "X.*"), newTestLiteral("model", "LIKE", true, "X.*"), true},
+		{newTestLiteral("model", "LIKE", false, "X.*"), newTestLiteral("model", "IS", false, "Y1"), false},
+	}
+	for i, test := range tests {
+		assert.Equal(t, test.literal.conflicts(test.other), test.conflicts, "case %d", i)
+	}
+}
+
+func TestAnalysisComparesNamespacedListsByTheirData(t *testing.T) {
+	testutil.SetupTestDB()
+	createTestAnalysisTemplate(t, "ANALYSIS_FIRST", 1)
+	createTestAnalysisTemplate(t, "ANALYSIS_SECOND", 2)
+	createTestNamespacedList(t, "all-macs", shared.MAC_LIST, "AA:AA:AA:AA:AA:01", "AA:AA:AA:AA:AA:02")
+	createTestNamespacedList(t, "some-macs", shared.MAC_LIST, "aa:aa:aa:aa:aa:01")
+	createTestNamespacedList(t, "other-macs", shared.MAC_LIST, "AA:AA:AA:AA:AA:02", "AA:AA:AA:AA:AA:03")
+	createTestNamespacedList(t, "ips", shared.IP_LIST, "10.0.0.1")
+
+	createTestAnalysisRule(t, "all", "ANALYSIS_FIRST", re.NewCondition(coreef.RuleFactoryMAC, re.StandardOperationInList, re.NewFixedArg("all-macs")))
+	createTestAnalysisRule(t, "some", "ANALYSIS_SECOND", re.NewCondition(coreef.RuleFactoryMAC, re.StandardOperationInList, re.NewFixedArg("some-macs")))
+	createTestAnalysisRule(t, "other", "ANALYSIS_SECOND", re.NewCondition(coreef.RuleFactoryMAC, re.StandardOperationInList, re.NewFixedArg("other-macs")))
+	createTestAnalysisRule(t, "single", "ANALYSIS_SECOND", re.NewCondition(coreef.RuleFactoryMAC, re.StandardOperationIs, re.NewFixedArg("AA:AA:AA:AA:AA:02")))
+	ipFreeArg := re.NewFreeArg(re.StandardFreeArgTypeString, common.IP_ADDRESS)
+	createTestAnalysisRule(t, "ips", "ANALYSIS_FIRST", re.NewCondition(ipFreeArg, re.StandardOperationInList, re.NewFixedArg("ips")))
+	createTestAnalysisRule(t, "ip", "ANALYSIS_SECOND", re.NewCondition(ipFreeArg, re.StandardOperationIs, re.NewFixedArg("10.0.0.1")))
+
+	analysis := AnalyzeFirmwareRules("stb", "")
+	shadowed := map[string]string{}
+	for _, overlap := range analysis.Shadowed {
+		shadowed[overlap.Rule.ID] = overlap.OtherRule.ID
+	}
+	// the rules on another list or on a single mac of the list are never reached
+	assert.DeepEqual(t, shadowed, map[string]string{"some": "all", "single": "all"})
+
+	partial := map[string]bool{}
+	for _, overlap := range analysis.PartialOverlaps {
+		partial[overlap.Rule.ID+"/"+overlap.OtherRule.ID] = true
+	}
+	assert.Assert(t, partial["all/other"], analysis.Warnings())
+	assert.Assert(t, partial["other/single"] || partial["single/other"], analysis.Warnings())
+	// ip lists hold ranges, so an address may only be in the list
+	assert.Assert(t, partial["ip/ips"] || partial["ips/ip"], analysis.Warnings())
+	assert.Equal(t, len(analysis.InvalidReferences), 0)
+}
+
+func TestAnalyzeFirmwareRuleBeforeItIsSaved(t *testing.T) {
+	testutil.SetupTestDB()
+	createTestAnalysisTemplate(t, "ANALYSIS_FIRST", 1)
+	createTestAnalysisTemplate(t, "ANALYSIS_SECOND", 2)
+	createTestModel(t, "MODEL1")
+	createTestAnalysisRule(t, "model", "ANALYSIS_FIRST", re.NewCondition(coreef.RuleFactoryMODEL, re.StandardOperationIn, re.NewFixedArg([]string{"MODEL1", "MODEL2"})))
+
+	candidate := newTestAnalysisRule("candidate", "ANALYSIS_SECOND", re.NewCondition(coreef.RuleFactoryMODEL, re.StandardOperationIs, re.NewFixedArg("MODEL1")))
+	analysis := AnalyzeFirmwareRule(candidate, "stb")
+	assert.Equal(t, len(analysis.Shadowed), 1)
+	assert.Equal(t, analysis.Shadowed[0].Rule.ID, "candidate")
+	assert.Equal(t, analysis.Shadowed[0].OtherRule.ID, "model")
+	// the stored rule references MODEL2 which does not exist, only the findings of the candidate are returned
+	assert.Equal(t, len(analysis.InvalidReferences), 0)
+
+	candidate.Rule.Condition = re.NewCondition(coreef.RuleFactoryMODEL, re.StandardOperationIs, re.NewFixedArg("MODEL3"))
+	analysis = AnalyzeFirmwareRule(candidate, "stb")
+	assert.Equal(t, len(analysis.Shadowed), 0)
+	assert.Equal(t, len(analysis.InvalidReferences), 1)
+	assert.Equal(t, analysis.InvalidReferences[0].Reference, "MODEL3")
+	_, err := corefw.GetFirmwareRuleOneDB("candidate")
+	assert.Assert(t, err != nil)
+}
+
+func TestPostFirmwareRuleWarningModeDoesNotSaveRulesWithFindings(t *testing.T) {
+	testutil.SetupTestDB()
+	createTestAnalysisTemplate(t, "ANALYSIS_FIRST", 1)
+	createTestAnalysisTemplate(t, "ANALYSIS_SECOND", 2)
+	createTestModel(t, "MODEL1")
+	createTestAnalysisRule(t, "model", "ANALYSIS_FIRST", re.NewCondition(coreef.RuleFactoryMODEL, re.StandardOperationIs, re.NewFixedArg("MODEL1")))
+
+	candidate := newTestAnalysisRule("candidate", "ANALYSIS_SECOND", re.NewCondition(coreef.RuleFactoryMODEL, re.StandardOperationIs, re.NewFixedArg("MODEL1")))
+	body, _ := json.Marshal(candidate)
+	r := testutil.NewRequest(http.MethodPost, "/xconfAdminService/firmwarerule?applicationType=stb&warnings", string(body), auth.WRITE_FIRMWARE_ALL)
+	rr := testutil.Serve(PostFirmwareRuleHandler, r)
+	assert.Equal(t, rr.Code, http.StatusConflict)
+	result := FirmwareRuleWithWarnings{}
+	assert.NilError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	assert.DeepEqual(t, result.Warnings, []string{
+		"Rule candidate can never match, every device it matches is matched first by model",
+		"Rules candidate and model match the same devices",
+	})
+	_, err := corefw.GetFirmwareRuleOneDB("candidate")
+	assert.Assert(t, err != nil)
+}
+
+func TestPostFirmwareRuleWarningModeSavesRulesWithoutFindings(t *testing.T) {
+	testutil.SetupTestDB()
+	createTestAnalysisTemplate(t, "ANALYSIS_FIRST", 1)
+	createTestModel(t, "MODEL1")
+	createTestFirmwareConfig(t, "analysis-config", "ANALYSIS_VERSION")
+
+	candidate := newTestAnalysisRule("candidate", "ANALYSIS_FIRST", re.NewCondition(coreef.RuleFactoryMODEL, re.StandardOperationIs, re.NewFixedArg("MODEL1")))
+	candidate.ApplicableAction = corefw.NewApplicableActionAndType(corefw.RuleActionClass, corefw.RULE, "analysis-config")
+	body, _ := json.Marshal(candidate)
+	r := testutil.NewRequest(http.MethodPost, "/xconfAdminService/firmwarerule?applicationType=stb&warnings", string(body), auth.WRITE_FIRMWARE_ALL)
+	rr := testutil.Serve(PostFirmwareRuleHandler, r)
+	assert.Equal(t, rr.Code, http.StatusCreated, rr.Body.String())
+	result := FirmwareRuleWithWarnings{}
+	assert.NilError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	assert.Equal(t, result.FirmwareRule.ID, "candidate")
+	assert.Equal(t, len(result.Warnings), 0)
+	_, err := corefw.GetFirmwareRuleOneDB("candidate")
+	assert.NilError(t, err)
+}
@@ -36,6 +36,8 @@ import (
 	"xconfadmin/adminapi/auth"
 	xcommon "xconfadmin/common"
 	xhttp "xconfadmin/http"
+	xshared "xconfadmin/shared"
+	xchange "xconfadmin/shared/change"
 	xwhttp "xconfwebconfig/http"
 
 	xutil "xconfadmin/util"
@@ -186,6 +188,10 @@ func PostFirmwareRuleImportAllHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.AdminError(w, err)
 		return
 	}
+	if err := xchange.ValidateDirectWrite(xchange.FIRMWARE_RULE, appType); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
 
 	xw, ok := w.(*xwhttp.XResponseWriter)
 	if !ok {
@@ -201,7 +207,13 @@ func PostFirmwareRuleImportAllHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result := importOrUpdateAllFirmwareRules(firmwareRules, appType)
+	dryRun := xhttp.NewDryRun(r)
+	result := importOrUpdateAllFirmwareRules(firmwareRules, appType, dryRun)
+	if dryRun != nil {
+		dryRun.Result = result
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	response, err := xhttp.ReturnJsonResponse(result, r)
 	if err != nil {
 		xhttp.AdminError(w, err)
@@ -241,13 +253,52 @@ func PostFirmwareRuleHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
-	err = createFirmwareRule(*firmwareRule, appType, true)
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_FIRMWARE_RULE, firmwareRule.ID); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	// in the warning mode the rule is analyzed before it is saved and not saved when it has findings
+	_, warningMode := r.URL.Query()[xcommon.WARNINGS]
+	if warningMode {
+		firmwareRule.ApplicationType = appType
+		if warnings := AnalyzeFirmwareRule(firmwareRule, appType).Warnings(); len(warnings) > 0 {
+			response, err := xhttp.ReturnJsonResponse(FirmwareRuleWithWarnings{FirmwareRule: firmwareRule, Warnings: warnings}, r)
+			if err != nil {
+				xhttp.AdminError(w, err)
+				return
+			}
+			xwhttp.WriteXconfResponse(w, http.StatusConflict, response)
+			return
+		}
+	}
+	dryRun := xhttp.NewDryRun(r)
+	if xchange.IsApprovalRequired(xchange.FIRMWARE_RULE, appType) {
+		if dryRun == nil {
+			writePendingChange(w, r, xchange.FIRMWARE_RULE, appType, xchange.Create, firmwareRule.ID, firmwareRule, func(dryRun *xhttp.DryRun) error {
+				return createFirmwareRule(*firmwareRule, appType, true, dryRun)
+			})
+			return
+		}
+		dryRun.ApprovalRequired = true
+	}
+	lifecycleWarnings := GetFirmwareConfigLifecycleWarnings(firmwareRule, appType)
+	err = createFirmwareRule(*firmwareRule, appType, true, dryRun)
 	if err != nil {
 		xhttp.AdminError(w, err)
 		return
 	}
+	xhttp.AddWarningHeaders(w, lifecycleWarnings)
+	if dryRun != nil {
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
+	auth.AssignOwnership(r, xshared.OWNED_FIRMWARE_RULE, firmwareRule.ID)
 	result, _ := firmware.GetFirmwareRuleOneDB(firmwareRule.ID)
-	response, err := xhttp.ReturnJsonResponse(result, r)
+	var entity interface{} = result
+	if warningMode {
+		entity = FirmwareRuleWithWarnings{FirmwareRule: result, Warnings: lifecycleWarnings}
+	}
+	response, err := xhttp.ReturnJsonResponse(entity, r)
 	if err != nil {
 		xhttp.AdminError(w, err)
 		return
@@ -278,11 +329,32 @@ func PutFirmwareRuleHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	_, err = firmware.GetFirmwareRuleOneDB(firmwareRule.ID)
 	if err == nil {
-		err = updateFirmwareRule(firmwareRule, appType, true)
+		if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_FIRMWARE_RULE, firmwareRule.ID); err != nil {
+			xhttp.AdminError(w, err)
+			return
+		}
+		dryRun := xhttp.NewDryRun(r)
+		if xchange.IsApprovalRequired(xchange.FIRMWARE_RULE, appType) {
+			if dryRun == nil {
+				writePendingChange(w, r, xchange.FIRMWARE_RULE, appType, xchange.Update, firmwareRule.ID, firmwareRule, func(dryRun *xhttp.DryRun) error {
+					return updateFirmwareRule(firmwareRule, appType, true, dryRun)
+				})
+				return
+			}
+			dryRun.ApprovalRequired = true
+		}
+		lifecycleWarnings := GetFirmwareConfigLifecycleWarnings(&firmwareRule, appType)
+		err = updateFirmwareRule(firmwareRule, appType, true, dryRun)
 		if err != nil {
 			xhttp.AdminError(w, err)
 			return
 		}
+		xhttp.AddWarningHeaders(w, lifecycleWarnings)
+		if dryRun != nil {
+			xhttp.WriteDryRunResponse(w, r, dryRun)
+			return
+		}
+		auth.UpdateOwnership(r, xshared.OWNED_FIRMWARE_RULE, firmwareRule.ID)
 		result, _ := firmware.GetFirmwareRuleOneDB(firmwareRule.ID)
 		response, err := xhttp.ReturnJsonResponse(result, r)
 		if err != nil {
@@ -317,6 +389,23 @@ func DeleteFirmwareRuleByIdHandler(w http.ResponseWriter, r *http.Request) {
 			xhttp.WriteAdminErrorResponse(w, http.StatusConflict, errorStr)
 			return
 		}
+		if err := auth.ValidateOwnership(r, xshared.OWNED_FIRMWARE_RULE, id); err != nil {
+			xhttp.AdminError(w, err)
+			return
+		}
+		dryRun := xhttp.NewDryRun(r)
+		if xchange.IsApprovalRequired(xchange.FIRMWARE_RULE, appType) {
+			if dryRun == nil {
+				writePendingChange(w, r, xchange.FIRMWARE_RULE, appType, xchange.Delete, id, nil, nil)
+				return
+			}
+			dryRun.ApprovalRequired = true
+		}
+		if dryRun != nil {
+			dryRun.Record(xhttp.DRY_RUN_DELETE, db.TABLE_FIRMWARE_RULE, id, entityOnDb)
+			xhttp.WriteDryRunResponse(w, r, dryRun)
+			return
+		}
 		err = db.GetCachedSimpleDao().DeleteOne(db.TABLE_FIRMWARE_RULE, id)
 	}
 	if err != nil {
@@ -324,6 +413,7 @@ func DeleteFirmwareRuleByIdHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.WriteAdminErrorResponse(w, http.StatusNotFound, response)
 		return
 	}
+	auth.RemoveOwnership(xshared.OWNED_FIRMWARE_RULE, id)
 
 	xwhttp.WriteXconfResponse(w, http.StatusNoContent, []byte(""))
 }
@@ -562,6 +652,10 @@ func PostPutFirmwareRuleEntitiesHandler(w http.ResponseWriter, r *http.Request,
 		xhttp.AdminError(w, err)
 		return
 	}
+	if err := xchange.ValidateDirectWrite(xchange.FIRMWARE_RULE, appType); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
 	xw, ok := w.(*xwhttp.XResponseWriter)
 	if !ok {
 		xhttp.WriteAdminErrorResponse(w, http.StatusInternalServerError, err.Error())
@@ -593,6 +687,7 @@ func PostPutFirmwareRuleEntitiesHandler(w http.ResponseWriter, r *http.Request,
 		}
 	}
 
+	dryRun := xhttp.NewDryRun(r)
 	entitiesMap := map[string]xhttp.EntityMessage{}
 	for i, entity := range entities {
 		_, err := firmware.GetFirmwareRuleOneDB(entity.ID)
@@ -603,7 +698,7 @@ func PostPutFirmwareRuleEntitiesHandler(w http.ResponseWriter, r *http.Request,
 			}
 			continue
 		}
-		if !isPut && err == nil {
+		if !isPut && (err == nil || dryRun.Saved(db.TABLE_FIRMWARE_RULE, entity.ID)) {
 			entitiesMap[entity.ID] = xhttp.EntityMessage{
 				Status:  xcommon.ENTITY_STATUS_FAILURE,
 				Message: "FirmwareRule with " + entity.ID + " already present",
@@ -631,15 +726,22 @@ func PostPutFirmwareRuleEntitiesHandler(w http.ResponseWriter, r *http.Request,
 			}
 			continue
 		}
+		if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_FIRMWARE_RULE, entity.ID); err != nil {
+			entitiesMap[entity.ID] = xhttp.EntityMessage{
+				Status:  xcommon.ENTITY_STATUS_FAILURE,
+				Message: err.Error(),
+			}
+			continue
+		}
 
 		if isPut {
-			err = updateFirmwareRule(entity, appType, false)
+			err = updateFirmwareRule(entity, appType, false, dryRun)
 		} else {
 			entity.Active = true
 			if entity.ApplicableAction != nil {
 				entity.ApplicableAction.Active = true
 			}
-			err = createFirmwareRule(entity, appType, false)
+			err = createFirmwareRule(entity, appType, false, dryRun)
 		}
 		if err != nil {
 			entitiesMap[entity.ID] = xhttp.EntityMessage{
@@ -660,12 +762,24 @@ func PostPutFirmwareRuleEntitiesHandler(w http.ResponseWriter, r *http.Request,
 				ruleMap[mapKey] = append(ruleMap[mapKey], &entities[i])
 			}
 
+			if dryRun == nil {
+				if isPut {
+					auth.UpdateOwnership(r, xshared.OWNED_FIRMWARE_RULE, entity.ID)
+				} else {
+					auth.AssignOwnership(r, xshared.OWNED_FIRMWARE_RULE, entity.ID)
+				}
+			}
 			entitiesMap[entity.ID] = xhttp.EntityMessage{
 				Status:  xcommon.ENTITY_STATUS_SUCCESS,
 				Message: entity.ID,
 			}
 		}
 	}
+	if dryRun != nil {
+		dryRun.Result = entitiesMap
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	response, err := xhttp.ReturnJsonResponse(entitiesMap, r)
 	if err != nil {
 		xhttp.AdminError(w, err)
@@ -741,6 +855,21 @@ func GetFirmwareRuleHandler(w http.ResponseWriter, r *http.Request) {
 	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
 }
 
+// GetFirmwareRuleAnalysisHandler reports shadowed and overlapping rules and rules referencing missing entities
+func GetFirmwareRuleAnalysisHandler(w http.ResponseWriter, r *http.Request) {
+	appType, err := auth.CanRead(r, auth.FIRMWARE_ENTITY)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	res, err := xhttp.ReturnJsonResponse(AnalyzeFirmwareRules(appType, ""), r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
 // 1247 GET /xconfAdminService/ux/api/firmwarerule/{id}
 // 84 GET /xconfAdminService/ux/api/firmwarerule/{id}?export
 func GetFirmwareRuleByIdHandler(w http.ResponseWriter, r *http.Request) {
@@ -28,9 +28,12 @@ import (
 
 	ru "xconfwebconfig/rulesengine"
 
+	"xconfadmin/adminapi/auth"
 	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
 	xutil "xconfadmin/util"
 	"xconfwebconfig/common"
+	"xconfwebconfig/db"
 
 	xshared "xconfadmin/shared"
 	xwcommon "xconfwebconfig/common"
@@ -129,8 +132,9 @@ func honoredByFirmwareRule(context map[string]string, rule *corefw.FirmwareRule)
 }
 
 func filterFirmwareRulesByContext(dbrules []*corefw.FirmwareRule, firmwareContext map[string]string) (filteredRules []*corefw.FirmwareRule) {
+	honoredByOwner := auth.NewOwnerFilter(firmwareContext, xshared.OWNED_FIRMWARE_RULE)
 	for _, rule := range dbrules {
-		if honoredByFirmwareRule(firmwareContext, rule) {
+		if honoredByFirmwareRule(firmwareContext, rule) && honoredByOwner(rule.ID) {
 			filteredRules = append(filteredRules, rule)
 		}
 	}
@@ -210,16 +214,16 @@ func firmwareRuleFilterByActionType(dbrules []*corefw.FirmwareRule, actionType s
 	return filteredRules
 }
 
-func importOrUpdateAllFirmwareRules(firmwareRules []corefw.FirmwareRule, appType string) (importResult map[string][]string) {
+func importOrUpdateAllFirmwareRules(firmwareRules []corefw.FirmwareRule, appType string, dryRun *xhttp.DryRun) (importResult map[string][]string) {
 	result := make(map[string][]string)
 	result["IMPORTED"] = []string{}
 	result["NOT_IMPORTED"] = []string{}
 	for _, entity := range firmwareRules {
-		entityOnDb, err := corefw.GetFirmwareRuleOneDB(entity.ID)
+		entityOnDb, err := getFirmwareRuleOrPending(entity.ID, dryRun)
 		if err == nil {
-			err = checkRuleTypeAndUpdate(entity, entityOnDb, appType)
+			err = checkRuleTypeAndUpdate(entity, entityOnDb, appType, dryRun)
 		} else {
-			err = checkRuleTypeAndCreate(&entity, appType)
+			err = checkRuleTypeAndCreate(&entity, appType, dryRun)
 		}
 		if err == nil {
 			result["IMPORTED"] = append(result["IMPORTED"], entity.Name)
@@ -231,7 +235,12 @@ func importOrUpdateAllFirmwareRules(firmwareRules []corefw.FirmwareRule, appType
 	return result
 }
 
-func checkRuleTypeAndCreate(firmwareRule *corefw.FirmwareRule, appType string) error {
+// CreateFirmwareRule validates and creates the rule, an env model rule is created as a percentage bean
+func CreateFirmwareRule(firmwareRule *corefw.FirmwareRule, appType string, dryRun *xhttp.DryRun) error {
+	return checkRuleTypeAndCreate(firmwareRule, appType, dryRun)
+}
+
+func checkRuleTypeAndCreate(firmwareRule *corefw.FirmwareRule, appType string, dryRun *xhttp.DryRun) error {
 	if util.IsBlank(firmwareRule.ID) {
 		firmwareRule.ID = uuid.New().String()
 	}
@@ -240,22 +249,22 @@ func checkRuleTypeAndCreate(firmwareRule *corefw.FirmwareRule, appType string) e
 		if ipRuleBean == nil {
 			return xcommon.NewXconfError(http.StatusBadRequest, "Unable to convert FirmwareRule into PercentageBean")
 		}
-		val := CreatePercentageBean(ipRuleBean, appType)
+		val := CreatePercentageBean(ipRuleBean, appType, dryRun)
 		if val.Status == http.StatusCreated {
 			return nil
 		}
 		return xcommon.NewXconfError(val.Status, val.Error.Error())
 	}
-	return createFirmwareRule(*firmwareRule, appType, true)
+	return createFirmwareRule(*firmwareRule, appType, true, dryRun)
 }
 
-func checkRuleTypeAndUpdate(firmwareRule corefw.FirmwareRule, entityOnDb *corefw.FirmwareRule, appType string) error {
+func checkRuleTypeAndUpdate(firmwareRule corefw.FirmwareRule, entityOnDb *corefw.FirmwareRule, appType string, dryRun *xhttp.DryRun) error {
 	if firmwareRule.Type == corefw.ENV_MODEL_RULE {
 		ipRuleBean := coreef.ConvertFirmwareRuleToPercentageBean(&firmwareRule)
 		if ipRuleBean == nil {
 			return xcommon.NewXconfError(http.StatusBadRequest, "Unable to convert FirmwareRule into PercentageBean")
 		}
-		val := UpdatePercentageBean(ipRuleBean, appType)
+		val := UpdatePercentageBean(ipRuleBean, appType, dryRun)
 		if val.Status == http.StatusOK {
 			return nil
 		}
@@ -264,37 +273,37 @@ func checkRuleTypeAndUpdate(firmwareRule corefw.FirmwareRule, entityOnDb *corefw
 	if entityOnDb.ApplicationType != firmwareRule.ApplicationType {
 		return xcommon.NewXconfError(http.StatusConflict, "ApplicationType cannot be changed. Existing:"+entityOnDb.ApplicationType+" New: "+firmwareRule.ApplicationType)
 	}
-	return updateFirmwareRule(firmwareRule, appType, true)
+	return updateFirmwareRule(firmwareRule, appType, true, dryRun)
 }
 
-func createFirmwareRule(entity corefw.FirmwareRule, appType string, validateNameNRule bool) error {
-	if err := beforeCreatingFirmwareRule(entity); err != nil {
+func createFirmwareRule(entity corefw.FirmwareRule, appType string, validateNameNRule bool, dryRun *xhttp.DryRun) error {
+	if err := beforeCreatingFirmwareRule(entity, dryRun); err != nil {
 		return err
 	}
-	return saveFirmwareRule(entity, appType, validateNameNRule)
+	return saveFirmwareRule(entity, appType, validateNameNRule, dryRun)
 }
 
-func updateFirmwareRule(entity corefw.FirmwareRule, appType string, validateNameNRule bool) error {
-	if err := beforeUpdatingFirmwareRule(entity); err != nil {
+func updateFirmwareRule(entity corefw.FirmwareRule, appType string, validateNameNRule bool, dryRun *xhttp.DryRun) error {
+	if err := beforeUpdatingFirmwareRule(entity, dryRun); err != nil {
 		return err
 	}
-	return saveFirmwareRule(entity, appType, validateNameNRule)
+	return saveFirmwareRule(entity, appType, validateNameNRule, dryRun)
 }
 
-func beforeCreatingFirmwareRule(entity corefw.FirmwareRule) error {
-	if _, err := corefw.GetFirmwareRuleOneDB(entity.ID); err == nil {
+func beforeCreatingFirmwareRule(entity corefw.FirmwareRule, dryRun *xhttp.DryRun) error {
+	if _, err := getFirmwareRuleOrPending(entity.ID, dryRun); err == nil {
 		return xcommon.NewXconfError(http.StatusConflict, "Entity with id: "+entity.ID+" already exists")
 
 	}
 	return nil
 }
 
-func beforeUpdatingFirmwareRule(firmwarerule corefw.FirmwareRule) error {
+func beforeUpdatingFirmwareRule(firmwarerule corefw.FirmwareRule, dryRun *xhttp.DryRun) error {
 	id := firmwarerule.ID
 	if util.IsBlank(id) {
 		return xcommon.NewXconfError(http.StatusBadRequest, "FirmwareRule id is empty")
 	}
-	entityOnDb, err := corefw.GetFirmwareRuleOneDB(id)
+	entityOnDb, err := getFirmwareRuleOrPending(id, dryRun)
 	if err != nil {
 		return xcommon.NewXconfError(http.StatusBadRequest, "Entity with id: "+id+" does not exist")
 
@@ -305,10 +314,30 @@ func beforeUpdatingFirmwareRule(firmwarerule corefw.FirmwareRule) error {
 	return nil
 }
 
-func saveFirmwareRule(entity corefw.FirmwareRule, appType string, validateNameNRule bool) error {
+// getFirmwareRuleOrPending returns the stored rule or the one an earlier entry of the dry run saved
+func getFirmwareRuleOrPending(id string, dryRun *xhttp.DryRun) (*corefw.FirmwareRule, error) {
+	if pending, ok := dryRun.Pending(db.TABLE_FIRMWARE_RULE)[id]; ok {
+		if firmwareRule, ok := pending.(*corefw.FirmwareRule); ok {
+			return firmwareRule, nil
+		}
+		return nil, xcommon.NotFound
+	}
+	return corefw.GetFirmwareRuleOneDB(id)
+}
+
+// saveFirmwareRule validates and stores the rule, a dry run only records it
+func saveFirmwareRule(entity corefw.FirmwareRule, appType string, validateNameNRule bool, dryRun *xhttp.DryRun) error {
 	if err := beforeSavingFirmwareRule(entity, appType, validateNameNRule); err != nil {
 		return err
 	}
+	if _, err := validateFirmwareConfigLifecycle(&entity, appType); err != nil {
+		return err
+	}
+	if dryRun != nil {
+		_, err := corefw.GetFirmwareRuleOneDB(entity.ID)
+		dryRun.RecordSave(db.TABLE_FIRMWARE_RULE, entity.ID, &entity, err == nil)
+		return nil
+	}
 	return corefw.CreateFirmwareRuleOneDB(&entity)
 }
 
@@ -326,6 +355,9 @@ func beforeSavingFirmwareRule(entity corefw.FirmwareRule, appType string, valida
 		return xcommon.NewXconfError(http.StatusConflict, "ApplicationType conflict")
 	}
 	entity.Updated = util.GetTimestamp(time.Now().UTC())
+	if err := xshared.ValidateApplicationTypeEntity(entity.ApplicationType, &entity); err != nil {
+		return err
+	}
 	return superBeforeSavingFirmwareRule(entity, validateNameNRule)
 }
 
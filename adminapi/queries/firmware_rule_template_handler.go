@@ -191,7 +191,13 @@ func PostFirmwareRuleTemplateImportAllHandler(w http.ResponseWriter, r *http.Req
 		}
 	}
 
-	result := importOrUpdateAllFirmwareRTs(firmwareRTs, successTag, failedTag)
+	dryRun := xhttp.NewDryRun(r)
+	result := importOrUpdateAllFirmwareRTs(firmwareRTs, successTag, failedTag, dryRun)
+	if dryRun != nil {
+		dryRun.Result = result
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	response, err := xhttp.ReturnJsonResponse(result, r)
 	if err != nil {
 		xhttp.AdminError(w, err)
@@ -236,6 +242,7 @@ func PostFirmwareRuleTemplateImportHandler(w http.ResponseWriter, r *http.Reques
 		return wrappedFrts[i].Entity.ID < wrappedFrts[j].Entity.ID
 	})
 
+	dryRun := xhttp.NewDryRun(r)
 	for _, wrapped := range wrappedFrts {
 		entity := wrapped.Entity
 		if entity.ID == "" {
@@ -247,7 +254,7 @@ func PostFirmwareRuleTemplateImportHandler(w http.ResponseWriter, r *http.Reques
 				result[failedTag] = append(result[failedTag], "FirmwareRuleTemplate with id '"+entity.ID+"' does not exist")
 				continue
 			}
-			if err := updateFirmwareRT(entity, entityOnDb); err != nil {
+			if err := updateFirmwareRT(entity, entityOnDb, dryRun); err != nil {
 				result[failedTag] = append(result[failedTag], "failed to import FirmwareRuleTemplate with id ="+entity.ID+", Error = "+err.Error())
 			} else {
 				result[successTag] = append(result[successTag], entity.ID)
@@ -257,13 +264,18 @@ func PostFirmwareRuleTemplateImportHandler(w http.ResponseWriter, r *http.Reques
 				result[failedTag] = append(result[failedTag], "FirmwareRuleTemplate with id '"+entity.ID+"' already exists")
 				continue
 			}
-			if _, err := createFirmwareRT(entity); err != nil {
+			if _, err := createFirmwareRT(entity, dryRun); err != nil {
 				result[failedTag] = append(result[failedTag], "failed to import FirmwareRuleTemplate with id ="+entity.ID+", Error = "+err.Error())
 			} else {
 				result[successTag] = append(result[successTag], entity.ID)
 			}
 		}
 	}
+	if dryRun != nil {
+		dryRun.Result = result
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 
 	response, err := xhttp.ReturnJsonResponse(result, r)
 	if err != nil {
@@ -304,10 +316,15 @@ func PostFirmwareRuleTemplateHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.WriteAdminErrorResponse(w, http.StatusConflict, response)
 		return
 	}
-	if _, err = createFirmwareRT(firmwareRT); err != nil {
+	dryRun := xhttp.NewDryRun(r)
+	if _, err = createFirmwareRT(firmwareRT, dryRun); err != nil {
 		xhttp.AdminError(w, err)
 		return
 	}
+	if dryRun != nil {
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	result, _ := corefw.GetFirmwareRuleTemplateOneDB(firmwareRT.ID)
 	response, err := xhttp.ReturnJsonResponse(result, r)
 	if err != nil {
@@ -335,9 +352,10 @@ func PutFirmwareRuleTemplateHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, response)
 		return
 	}
+	dryRun := xhttp.NewDryRun(r)
 	entityOnDb, err := corefw.GetFirmwareRuleTemplateOneDB(firmwareRT.ID)
 	if err == nil {
-		err = updateFirmwareRT(firmwareRT, entityOnDb)
+		err = updateFirmwareRT(firmwareRT, entityOnDb, dryRun)
 	} else {
 		response := "firmwareRuleTemplate does not exist for " + firmwareRT.ID
 		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, response)
@@ -348,6 +366,10 @@ func PutFirmwareRuleTemplateHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.AdminError(w, err)
 		return
 	}
+	if dryRun != nil {
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	xwhttp.WriteXconfResponse(w, http.StatusOK, response)
 }
 
@@ -381,9 +403,14 @@ func DeleteFirmwareRuleTemplateByIdHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	dryRun := xhttp.NewDryRun(r)
 	templateToDelete, err := corefw.GetFirmwareRuleTemplateOneDBWithId(id)
 	if err == nil {
-		err = db.GetCachedSimpleDao().DeleteOne(db.TABLE_FIRMWARE_RULE_TEMPLATE, id)
+		if dryRun != nil {
+			dryRun.Record(xhttp.DRY_RUN_DELETE, db.TABLE_FIRMWARE_RULE_TEMPLATE, id, templateToDelete)
+		} else {
+			err = db.GetCachedSimpleDao().DeleteOne(db.TABLE_FIRMWARE_RULE_TEMPLATE, id)
+		}
 	}
 	if err != nil {
 		response := "firmwareRuletemplate does not exist for " + id
@@ -397,6 +424,13 @@ func DeleteFirmwareRuleTemplateByIdHandler(w http.ResponseWriter, r *http.Reques
 	actionContext[cFirmwareRTApplicableActionType] = actionType
 	templatesByAction := filterFirmwareRTsByContext(allFrts, actionContext)[actionType]
 
+	if dryRun != nil {
+		for _, item := range PackFrtPriorities(copyFirmwareRTs(templatesByAction), templateToDelete) {
+			dryRun.Record(xhttp.DRY_RUN_UPDATE, db.TABLE_FIRMWARE_RULE_TEMPLATE, item.ID, item)
+		}
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	alteredFrts := PackFrtPriorities(templatesByAction, templateToDelete)
 	for _, item := range alteredFrts {
 		if err := db.GetCachedSimpleDao().SetOne(db.TABLE_FIRMWARE_RULE_TEMPLATE, item.ID, item); err != nil {
@@ -496,17 +530,18 @@ func PostFirmwareRuleTemplateEntitiesHandler(w http.ResponseWriter, r *http.Requ
 		return entities[i].Priority < entities[j].Priority
 	})
 
+	dryRun := xhttp.NewDryRun(r)
 	entitiesMap := map[string]xhttp.EntityMessage{}
 	for _, entity := range entities {
 		_, err := corefw.GetFirmwareRuleTemplateOneDB(entity.ID)
-		if err == nil {
+		if err == nil || dryRun.Saved(db.TABLE_FIRMWARE_RULE_TEMPLATE, entity.ID) {
 			entitiesMap[entity.ID] = xhttp.EntityMessage{
 				Status:  xcommon.ENTITY_STATUS_FAILURE,
 				Message: "firmwareRuleTemplate " + entity.ID + " already present.",
 			}
 			continue
 		}
-		if _, err = createFirmwareRT(entity); err != nil {
+		if _, err = createFirmwareRT(entity, dryRun); err != nil {
 			entitiesMap[entity.ID] = xhttp.EntityMessage{
 				Status:  xcommon.ENTITY_STATUS_FAILURE,
 				Message: err.Error(),
@@ -518,6 +553,11 @@ func PostFirmwareRuleTemplateEntitiesHandler(w http.ResponseWriter, r *http.Requ
 			}
 		}
 	}
+	if dryRun != nil {
+		dryRun.Result = entitiesMap
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	response, err := xhttp.ReturnJsonResponse(entitiesMap, r)
 	if err != nil {
 		xhttp.AdminError(w, err)
@@ -552,6 +592,7 @@ func PutFirmwareRuleTemplateEntitiesHandler(w http.ResponseWriter, r *http.Reque
 		}
 		return entities[i].Priority < entities[j].Priority
 	})
+	dryRun := xhttp.NewDryRun(r)
 	entitiesMap := map[string]xhttp.EntityMessage{}
 	for _, entity := range entities {
 		entityOnDb, err := corefw.GetFirmwareRuleTemplateOneDB(entity.ID)
@@ -562,7 +603,7 @@ func PutFirmwareRuleTemplateEntitiesHandler(w http.ResponseWriter, r *http.Reque
 			}
 			continue
 		}
-		if err := updateFirmwareRT(entity, entityOnDb); err != nil {
+		if err := updateFirmwareRT(entity, entityOnDb, dryRun); err != nil {
 			entitiesMap[entity.ID] = xhttp.EntityMessage{
 				Status:  xcommon.ENTITY_STATUS_FAILURE,
 				Message: err.Error(),
@@ -574,6 +615,11 @@ func PutFirmwareRuleTemplateEntitiesHandler(w http.ResponseWriter, r *http.Reque
 			}
 		}
 	}
+	if dryRun != nil {
+		dryRun.Result = entitiesMap
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	response, err := xhttp.ReturnJsonResponse(entitiesMap, r)
 	if err != nil {
 		xhttp.AdminError(w, err)
@@ -791,15 +837,26 @@ func PostFirmwareRuleTemplateByIdPriorityByNewPriorityHandler(w http.ResponseWri
 		xwhttp.WriteXconfResponse(w, http.StatusOK, nil)
 		return
 	}
+	dryRun := xhttp.NewDryRun(r)
+	if dryRun != nil {
+		copied := *frt
+		frt = &copied
+		templatesOfCurrentType = copyFirmwareRTs(templatesOfCurrentType)
+	}
 	reorganizedTemplates, err := updateFirmwareRTByPriorityAndReorganize(frt, templatesOfCurrentType, newPriority)
 	if err != nil {
 		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("unable to re-organize priorities: %s", err))
 		return
 	}
-	if err = saveAllFirmwareRTs(reorganizedTemplates); err != nil {
+	if err = saveAllFirmwareRTs(reorganizedTemplates, dryRun); err != nil {
 		xhttp.WriteAdminErrorResponse(w, http.StatusInternalServerError, fmt.Sprintf("unable to re-organize priorities: %s", err))
 		return
 	}
+	if dryRun != nil {
+		dryRun.Result = reorganizedTemplates
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	res, err := xhttp.ReturnJsonResponse(reorganizedTemplates, r)
 	if err != nil {
 		xhttp.AdminError(w, err)
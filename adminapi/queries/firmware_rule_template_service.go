@@ -33,6 +33,7 @@ import (
 	"xconfwebconfig/common"
 
 	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
 	xcorefw "xconfadmin/shared/firmware"
 	"xconfadmin/util"
 	ds "xconfwebconfig/db"
@@ -290,9 +291,15 @@ func addNewFirmwareRTAndReorganize(newItem corefw.FirmwareRuleTemplate, itemsLis
 	return reorganizeFirmwareRTPriorities(itemsList, len(itemsList), int(newItem.Priority))
 }
 
-func saveAllFirmwareRTs(templateList []*corefw.FirmwareRuleTemplate) error {
+// saveAllFirmwareRTs stores the templates, a dry run only records them
+func saveAllFirmwareRTs(templateList []*corefw.FirmwareRuleTemplate, dryRun *xhttp.DryRun) error {
 	for _, template := range templateList {
 		template.Updated = xutil.GetTimestamp(time.Now().UTC())
+		if dryRun != nil {
+			_, err := corefw.GetFirmwareRuleTemplateOneDBWithId(template.ID)
+			dryRun.RecordSave(ds.TABLE_FIRMWARE_RULE_TEMPLATE, template.ID, template, err == nil)
+			continue
+		}
 		if err := ds.GetCachedSimpleDao().SetOne(ds.TABLE_FIRMWARE_RULE_TEMPLATE, template.ID, template); err != nil {
 			return err
 		}
@@ -300,12 +307,44 @@ func saveAllFirmwareRTs(templateList []*corefw.FirmwareRuleTemplate) error {
 	return nil
 }
 
-func updateFirmwareRT(frtToImport corefw.FirmwareRuleTemplate, frtOnDb *corefw.FirmwareRuleTemplate) error {
+// copyFirmwareRTs returns copies of the templates, so a dry run can reorganize the priorities without altering the cached ones
+func copyFirmwareRTs(templates []*corefw.FirmwareRuleTemplate) []*corefw.FirmwareRuleTemplate {
+	result := make([]*corefw.FirmwareRuleTemplate, 0, len(templates))
+	for _, template := range templates {
+		copied := *template
+		result = append(result, &copied)
+	}
+	return result
+}
+
+// getFirmwareRTsToReorganize returns the templates of the action type, a dry run gets copies merged with
+// the templates it saved earlier, so the entries of a batch are checked against each other
+func getFirmwareRTsToReorganize(actionType corefw.ApplicableActionType, dryRun *xhttp.DryRun) ([]*corefw.FirmwareRuleTemplate, error) {
+	templates, err := corefw.GetFirmwareRuleTemplateAllAsListDB(actionType)
+	if dryRun == nil || (err != nil && err.Error() != common.NotFound.Error()) {
+		return templates, err
+	}
+	pending := dryRun.Pending(ds.TABLE_FIRMWARE_RULE_TEMPLATE)
+	merged := []*corefw.FirmwareRuleTemplate{}
+	for _, template := range templates {
+		if _, ok := pending[template.ID]; !ok {
+			merged = append(merged, template)
+		}
+	}
+	for _, entity := range pending {
+		if template, ok := entity.(*corefw.FirmwareRuleTemplate); ok && template.ApplicableAction != nil && template.ApplicableAction.ActionType == actionType {
+			merged = append(merged, template)
+		}
+	}
+	return copyFirmwareRTs(merged), nil
+}
+
+func updateFirmwareRT(frtToImport corefw.FirmwareRuleTemplate, frtOnDb *corefw.FirmwareRuleTemplate, dryRun *xhttp.DryRun) error {
 	err := validateOneFirmwareRT(frtToImport)
 	if err != nil {
 		return err
 	}
-	similarFrtsOnDb, err := corefw.GetFirmwareRuleTemplateAllAsListDB(frtToImport.ApplicableAction.ActionType)
+	similarFrtsOnDb, err := getFirmwareRTsToReorganize(frtToImport.ApplicableAction.ActionType, dryRun)
 	if err != nil {
 		return err
 	}
@@ -318,18 +357,18 @@ func updateFirmwareRT(frtToImport corefw.FirmwareRuleTemplate, frtOnDb *corefw.F
 	if err != nil {
 		return err
 	}
-	if err = saveAllFirmwareRTs(list); err != nil {
+	if err = saveAllFirmwareRTs(list, dryRun); err != nil {
 		return err
 	}
 	return nil
 }
 
-func createFirmwareRT(template corefw.FirmwareRuleTemplate) (templ *corefw.FirmwareRuleTemplate, err error) {
+func createFirmwareRT(template corefw.FirmwareRuleTemplate, dryRun *xhttp.DryRun) (templ *corefw.FirmwareRuleTemplate, err error) {
 	err = validateOneFirmwareRT(template)
 	if err != nil {
 		return nil, err
 	}
-	templatesOfCurrentType, err := corefw.GetFirmwareRuleTemplateAllAsListDB(template.ApplicableAction.ActionType)
+	templatesOfCurrentType, err := getFirmwareRTsToReorganize(template.ApplicableAction.ActionType, dryRun)
 	if err != nil {
 		if err.Error() != common.NotFound.Error() {
 			return nil, err
@@ -339,7 +378,7 @@ func createFirmwareRT(template corefw.FirmwareRuleTemplate) (templ *corefw.Firmw
 	if err != nil {
 		return nil, err
 	}
-	if err = saveAllFirmwareRTs(addNewFirmwareRTAndReorganize(template, templatesOfCurrentType)); err != nil {
+	if err = saveAllFirmwareRTs(addNewFirmwareRTAndReorganize(template, templatesOfCurrentType), dryRun); err != nil {
 		return nil, err
 	}
 	templ = &template
@@ -347,7 +386,7 @@ func createFirmwareRT(template corefw.FirmwareRuleTemplate) (templ *corefw.Firmw
 	return templ, nil
 }
 
-func importOrUpdateAllFirmwareRTs(entities []corefw.FirmwareRuleTemplate, successTag string, failedTag string) map[string][]string {
+func importOrUpdateAllFirmwareRTs(entities []corefw.FirmwareRuleTemplate, successTag string, failedTag string, dryRun *xhttp.DryRun) map[string][]string {
 	result := make(map[string][]string)
 	result[successTag] = []string{}
 	result[failedTag] = []string{}
@@ -361,10 +400,10 @@ func importOrUpdateAllFirmwareRTs(entities []corefw.FirmwareRuleTemplate, succes
 			entity.ID = uuid.New().String()
 		}
 		entityOnDb, err := corefw.GetFirmwareRuleTemplateOneDBWithId(entity.ID)
-		if err != nil {
-			_, err = createFirmwareRT(entity)
+		if err != nil && !dryRun.Saved(ds.TABLE_FIRMWARE_RULE_TEMPLATE, entity.ID) {
+			_, err = createFirmwareRT(entity, dryRun)
 		} else {
-			err = updateFirmwareRT(entity, entityOnDb)
+			err = updateFirmwareRT(entity, entityOnDb, dryRun)
 		}
 		if err == nil {
 			result[successTag] = append(result[successTag], entity.ID)
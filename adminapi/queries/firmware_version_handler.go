@@ -0,0 +1,137 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package queries
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"xconfadmin/adminapi/auth"
+	xhttp "xconfadmin/http"
+	xcoreef "xconfadmin/shared/estbfirmware"
+	"xconfwebconfig/common"
+	xwhttp "xconfwebconfig/http"
+
+	"github.com/gorilla/mux"
+)
+
+// query parameters of the compare endpoint
+const (
+	FIRMWARE_VERSION_1     = "version1"
+	FIRMWARE_VERSION_2     = "version2"
+	FIRMWARE_VERSION_MODEL = "model"
+)
+
+func writeFirmwareVersionResponse(w http.ResponseWriter, r *http.Request, entity interface{}, status int) {
+	res, err := xhttp.ReturnJsonResponse(entity, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteResponseBytes(w, res, status, xhttp.ContextTypeHeader(r))
+}
+
+func GetFirmwareVersionPatternsHandler(w http.ResponseWriter, r *http.Request) {
+	applicationType, err := auth.CanRead(r, auth.FIRMWARE_ENTITY)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	writeFirmwareVersionResponse(w, r, GetFirmwareVersionPatternsAS(applicationType), http.StatusOK)
+}
+
+func GetFirmwareVersionPatternByIdHandler(w http.ResponseWriter, r *http.Request) {
+	applicationType, err := auth.CanRead(r, auth.FIRMWARE_ENTITY)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	pattern, err := GetFirmwareVersionPatternAS(mux.Vars(r)[common.ID], applicationType)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	writeFirmwareVersionResponse(w, r, pattern, http.StatusOK)
+}
+
+func CreateFirmwareVersionPatternHandler(w http.ResponseWriter, r *http.Request) {
+	saveFirmwareVersionPattern(w, r, true)
+}
+
+func UpdateFirmwareVersionPatternHandler(w http.ResponseWriter, r *http.Request) {
+	saveFirmwareVersionPattern(w, r, false)
+}
+
+func saveFirmwareVersionPattern(w http.ResponseWriter, r *http.Request, create bool) {
+	applicationType, err := auth.CanWrite(r, auth.FIRMWARE_ENTITY)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	// r.Body is already drained in the middleware
+	xw, ok := w.(*xwhttp.XResponseWriter)
+	if !ok {
+		xhttp.WriteAdminErrorResponse(w, http.StatusInternalServerError, "responsewriter cast error")
+		return
+	}
+	pattern := xcoreef.FirmwareVersionPattern{}
+	if err := json.Unmarshal([]byte(xw.Body()), &pattern); err != nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, "Unable to extract version pattern from json file:"+err.Error())
+		return
+	}
+
+	result, err := SaveFirmwareVersionPattern(&pattern, applicationType, create)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	status := http.StatusOK
+	if create {
+		status = http.StatusCreated
+	}
+	writeFirmwareVersionResponse(w, r, result, status)
+}
+
+func DeleteFirmwareVersionPatternHandler(w http.ResponseWriter, r *http.Request) {
+	applicationType, err := auth.CanWrite(r, auth.FIRMWARE_ENTITY)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	if err := DeleteFirmwareVersionPatternAS(mux.Vars(r)[common.ID], applicationType); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusNoContent, nil)
+}
+
+func CompareFirmwareVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	applicationType, err := auth.CanRead(r, auth.FIRMWARE_ENTITY)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	query := r.URL.Query()
+	comparison, err := CompareFirmwareVersions(applicationType, query.Get(FIRMWARE_VERSION_MODEL), query.Get(FIRMWARE_VERSION_1), query.Get(FIRMWARE_VERSION_2))
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	writeFirmwareVersionResponse(w, r, comparison, http.StatusOK)
+}
@@ -0,0 +1,255 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package queries
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	xcommon "xconfadmin/common"
+	xcoreef "xconfadmin/shared/estbfirmware"
+	"xconfwebconfig/shared"
+	coreef "xconfwebconfig/shared/estbfirmware"
+	corefw "xconfwebconfig/shared/firmware"
+	"xconfwebconfig/util"
+
+	"github.com/google/uuid"
+)
+
+// FirmwareVersionComparison is the result of the compare endpoint, Result is -1, 0 or 1
+// if Version1 is older, equal or newer than Version2
+type FirmwareVersionComparison struct {
+	Version1   string                         `json:"version1"`
+	Version2   string                         `json:"version2"`
+	Model      string                         `json:"model,omitempty"`
+	Comparable bool                           `json:"comparable"`
+	Result     int                            `json:"result"`
+	Parsed1    *xcoreef.ParsedFirmwareVersion `json:"parsed1"`
+	Parsed2    *xcoreef.ParsedFirmwareVersion `json:"parsed2"`
+}
+
+func GetFirmwareVersionPatternsAS(applicationType string) []*xcoreef.FirmwareVersionPattern {
+	patterns := xcoreef.GetFirmwareVersionPatterns(applicationType)
+	sort.Slice(patterns, func(i, j int) bool {
+		return strings.ToLower(patterns[i].Name) < strings.ToLower(patterns[j].Name)
+	})
+	return patterns
+}
+
+func GetFirmwareVersionPatternAS(id string, applicationType string) (*xcoreef.FirmwareVersionPattern, error) {
+	pattern := xcoreef.GetFirmwareVersionPattern(id)
+	if pattern == nil || pattern.ApplicationType != applicationType {
+		return nil, xcommon.NewXconfError(http.StatusNotFound, "FirmwareVersionPattern with id "+id+" does not exist")
+	}
+	return pattern, nil
+}
+
+// SaveFirmwareVersionPattern validates and saves the pattern, a model can only have one pattern
+func SaveFirmwareVersionPattern(pattern *xcoreef.FirmwareVersionPattern, applicationType string, create bool) (*xcoreef.FirmwareVersionPattern, error) {
+	if util.IsBlank(pattern.ApplicationType) {
+		pattern.ApplicationType = applicationType
+	} else if pattern.ApplicationType != applicationType {
+		return nil, xcommon.NewXconfError(http.StatusConflict, "ApplicationType conflict")
+	}
+	if create {
+		if util.IsBlank(pattern.ID) {
+			pattern.ID = uuid.New().String()
+		} else if xcoreef.GetFirmwareVersionPattern(pattern.ID) != nil {
+			return nil, xcommon.NewXconfError(http.StatusConflict, "FirmwareVersionPattern with id "+pattern.ID+" already exists")
+		}
+	} else if _, err := GetFirmwareVersionPatternAS(pattern.ID, applicationType); err != nil {
+		return nil, err
+	}
+
+	pattern.Name = strings.TrimSpace(pattern.Name)
+	modelIds := []string{}
+	for _, modelId := range pattern.ModelIds {
+		modelId = strings.ToUpper(strings.TrimSpace(modelId))
+		if modelId != "" && !util.Contains(modelIds, modelId) {
+			modelIds = append(modelIds, modelId)
+		}
+	}
+	pattern.ModelIds = modelIds
+	if err := pattern.Validate(); err != nil {
+		return nil, err
+	}
+	for _, modelId := range pattern.ModelIds {
+		if shared.GetOneModel(modelId) == nil {
+			return nil, xcommon.NewXconfError(http.StatusBadRequest, "Model "+modelId+" does not exist")
+		}
+	}
+	for _, existing := range xcoreef.GetFirmwareVersionPatterns(applicationType) {
+		if existing.ID == pattern.ID {
+			continue
+		}
+		if strings.EqualFold(existing.Name, pattern.Name) {
+			return nil, xcommon.NewXconfError(http.StatusConflict, "FirmwareVersionPattern with name "+pattern.Name+" already exists")
+		}
+		for _, modelId := range pattern.ModelIds {
+			if util.Contains(existing.ModelIds, modelId) {
+				return nil, xcommon.NewXconfError(http.StatusConflict, fmt.Sprintf("Model %s already has the version pattern %s", modelId, existing.Name))
+			}
+		}
+	}
+
+	if err := xcoreef.SetFirmwareVersionPattern(pattern); err != nil {
+		return nil, err
+	}
+	return pattern, nil
+}
+
+func DeleteFirmwareVersionPatternAS(id string, applicationType string) error {
+	if _, err := GetFirmwareVersionPatternAS(id, applicationType); err != nil {
+		return err
+	}
+	return xcoreef.DeleteFirmwareVersionPattern(id)
+}
+
+// CompareFirmwareVersions compares the versions with the pattern of the model, or in the natural order
+func CompareFirmwareVersions(applicationType string, modelId string, version1 string, version2 string) (*FirmwareVersionComparison, error) {
+	if util.IsBlank(version1) || util.IsBlank(version2) {
+		return nil, xcommon.NewXconfError(http.StatusBadRequest, "version1 and version2 are required")
+	}
+	modelIds := []string{}
+	if !util.IsBlank(modelId) {
+		modelIds = append(modelIds, strings.ToUpper(modelId))
+	}
+	comparator := xcoreef.GetFirmwareVersionComparator(applicationType)
+	comparison := &FirmwareVersionComparison{
+		Version1: version1,
+		Version2: version2,
+		Model:    strings.ToUpper(modelId),
+		Parsed1:  comparator.Parse(modelIds, version1),
+		Parsed2:  comparator.Parse(modelIds, version2),
+	}
+	if comparison.Parsed1.IsComparable(comparison.Parsed2) {
+		comparison.Comparable = true
+		comparison.Result = comparison.Parsed1.CompareTo(comparison.Parsed2)
+	}
+	return comparison, nil
+}
+
+// sortFirmwareVersions sorts the versions of the models from the oldest to the newest
+func sortFirmwareVersions(applicationType string, modelIds []string, versions []string) {
+	xcoreef.GetFirmwareVersionComparator(applicationType).Sort(modelIds, versions)
+}
+
+// getFirmwareVersionPatternWarnings returns a warning for each version which does not match the pattern
+// configured for the model
+func getFirmwareVersionPatternWarnings(comparator *xcoreef.FirmwareVersionComparator, modelId string, versions []string) []string {
+	warnings := []string{}
+	modelIds := []string{modelId}
+	if !comparator.HasPattern(modelIds) {
+		return warnings
+	}
+	for _, version := range versions {
+		if _, ok := comparator.ParseWithPattern(modelIds, version); !ok {
+			warnings = append(warnings, fmt.Sprintf("Firmware version %s does not match the version pattern of model %s", version, modelId))
+		}
+	}
+	return warnings
+}
+
+// GetPercentageBeanVersionWarnings returns the warnings for an IntermediateVersion which is newer than
+// a distribution and a LastKnownGood which is newer than every distribution
+func GetPercentageBeanVersionWarnings(bean *coreef.PercentageBean, applicationType string) []string {
+	warnings := []string{}
+	modelIds := []string{strings.ToUpper(bean.Model)}
+	comparator := xcoreef.GetFirmwareVersionComparator(applicationType)
+	distributionVersions := []string{}
+	for _, entry := range bean.Distributions {
+		if entry == nil {
+			continue
+		}
+		if config, err := coreef.GetFirmwareConfigOneDB(entry.ConfigId); err == nil {
+			distributionVersions = append(distributionVersions, config.FirmwareVersion)
+		}
+	}
+	if len(distributionVersions) == 0 {
+		return warnings
+	}
+
+	if !util.IsBlank(bean.IntermediateVersion) {
+		if config, err := coreef.GetFirmwareConfigOneDB(bean.IntermediateVersion); err == nil {
+			for _, version := range distributionVersions {
+				if result, ok := comparator.Compare(modelIds, config.FirmwareVersion, version); ok && result > 0 {
+					warnings = append(warnings, fmt.Sprintf("IntermediateVersion %s is newer than the distribution version %s", config.FirmwareVersion, version))
+				}
+			}
+		}
+	}
+
+	if !util.IsBlank(bean.LastKnownGood) {
+		if config, err := coreef.GetFirmwareConfigOneDB(bean.LastKnownGood); err == nil {
+			newer := true
+			for _, version := range distributionVersions {
+				if result, ok := comparator.Compare(modelIds, config.FirmwareVersion, version); !ok || result <= 0 {
+					newer = false
+					break
+				}
+			}
+			if newer {
+				warnings = append(warnings, fmt.Sprintf("LastKnownGood %s is newer than every distribution version", config.FirmwareVersion))
+			}
+		}
+	}
+	return warnings
+}
+
+// GetAmvVersionWarnings returns the warnings for the firmware versions of the AMV which do not match the
+// pattern of the model and for a minimum version which is newer than the LastKnownGood of an active
+// percentage bean of the model, the devices falling back to the LastKnownGood could not be activated
+func GetAmvVersionWarnings(amv *corefw.ActivationVersion, applicationType string) []string {
+	modelId := strings.ToUpper(amv.Model)
+	modelIds := []string{modelId}
+	comparator := xcoreef.GetFirmwareVersionComparator(applicationType)
+	warnings := getFirmwareVersionPatternWarnings(comparator, modelId, amv.FirmwareVersions)
+	if len(amv.FirmwareVersions) == 0 {
+		return warnings
+	}
+	versions := make([]string, len(amv.FirmwareVersions))
+	copy(versions, amv.FirmwareVersions)
+	comparator.Sort(modelIds, versions)
+
+	beans, err := GetAllPercentageBeansFromDB(applicationType, true, false)
+	if err != nil {
+		return warnings
+	}
+	for _, bean := range beans {
+		if !bean.Active || util.IsBlank(bean.LastKnownGood) || !strings.EqualFold(bean.Model, modelId) {
+			continue
+		}
+		config, err := coreef.GetFirmwareConfigOneDB(bean.LastKnownGood)
+		if err != nil {
+			continue
+		}
+		newer := true
+		for _, version := range versions {
+			if result, ok := comparator.Compare(modelIds, version, config.FirmwareVersion); !ok || result <= 0 {
+				newer = false
+				break
+			}
+		}
+		if newer {
+			warnings = append(warnings, fmt.Sprintf("Minimum version %s is newer than the LastKnownGood %s of percentage bean %s", versions[0], config.FirmwareVersion, bean.Name))
+		}
+	}
+	return warnings
+}
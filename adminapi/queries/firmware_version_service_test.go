@@ -0,0 +1,160 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package queries
+
+import (
+	"testing"
+
+	xcoreef "xconfadmin/shared/estbfirmware"
+	"xconfadmin/testutil"
+	coreef "xconfwebconfig/shared/estbfirmware"
+	corefw "xconfwebconfig/shared/firmware"
+
+	"gotest.tools/assert"
+)
+
+func createTestFirmwareConfig(t *testing.T, id string, version string) {
+	config := coreef.NewEmptyFirmwareConfig()
+	config.ID = id
+	config.Description = id
+	config.FirmwareVersion = version
+	config.FirmwareFilename = version + ".bin"
+	config.SupportedModelIds = []string{"MODEL1"}
+	config.ApplicationType = "stb"
+	assert.NilError(t, coreef.CreateFirmwareConfigOneDB(config))
+}
+
+func saveTestFirmwareVersionPattern(t *testing.T, name string, pattern string) {
+	_, err := SaveFirmwareVersionPattern(&xcoreef.FirmwareVersionPattern{Name: name, ModelIds: []string{"MODEL1"}, Pattern: pattern}, "stb", true)
+	assert.NilError(t, err)
+}
+
+func TestNaturalFirmwareVersionParser(t *testing.T) {
+	testutil.SetupTestDB()
+	tests := []struct {
+		version    string
+		family     string
+		components []string
+		suffix     string
+	}{
+		{"TG1682_3.14p9s1_PROD_sey", "TG1682", []string{"3", "14", "p", "9", "s", "1"}, "PROD_sey"},
+		{"TG1682_3.14p9s1", "TG1682", []string{"3", "14", "p", "9", "s", "1"}, ""},
+		{"tg1682_3.14", "TG1682", []string{"3", "14"}, ""},
+		{"1.2.10", "", []string{"1", "2", "10"}, ""},
+		{"2.0-beta", "", []string{"2", "0", "beta"}, ""},
+	}
+	for _, test := range tests {
+		comparison, err := CompareFirmwareVersions("stb", "", test.version, test.version)
+		assert.NilError(t, err)
+		parsed := comparison.Parsed1
+		assert.Equal(t, parsed.Parser, xcoreef.NATURAL_VERSION_PARSER, test.version)
+		assert.Equal(t, parsed.Family, test.family, test.version)
+		assert.DeepEqual(t, parsed.Components, test.components)
+		assert.Equal(t, parsed.Suffix, test.suffix, test.version)
+	}
+}
+
+func TestFirmwareVersionComparator(t *testing.T) {
+	testutil.SetupTestDB()
+	tests := []struct {
+		version1   string
+		version2   string
+		comparable bool
+		result     int
+	}{
+		// the suffix is not part of the version
+		{"TG1682_3.14p9s1", "TG1682_3.14p9s1_PROD_sey", true, 0},
+		{"TG1682_3.14p9s1_PROD_sey", "TG1682_3.14p10s1_DEV", true, -1},
+		// numeric components are compared as numbers
+		{"TG1682_3.2p1", "TG1682_3.10p1", true, -1},
+		{"TG1682_03.1", "TG1682_3.1", true, 0},
+		{"1.2.10", "1.2.9", true, 1},
+		// a version which has more components is newer
+		{"TG1682_3.14", "TG1682_3.14.1", true, -1},
+		{"tg1682_3.1", "TG1682_3.1", true, 0},
+		// versions of different families are not comparable
+		{"TG1682_3.14p9", "TG3482_3.14p9", false, 0},
+	}
+	for _, test := range tests {
+		comparison, err := CompareFirmwareVersions("stb", "", test.version1, test.version2)
+		assert.NilError(t, err)
+		assert.Equal(t, comparison.Comparable, test.comparable, test.version1+" "+test.version2)
+		assert.Equal(t, comparison.Result, test.result, test.version1+" "+test.version2)
+	}
+
+	_, err := CompareFirmwareVersions("stb", "", "1.0", "")
+	assert.ErrorContains(t, err, "version1 and version2 are required")
+}
+
+func TestFirmwareVersionPatternReplacesCachedComparator(t *testing.T) {
+	testutil.SetupTestDB()
+	createTestModel(t, "MODEL1")
+
+	comparison, err := CompareFirmwareVersions("stb", "MODEL1", "MODEL1_1.9", "MODEL1_1.10")
+	assert.NilError(t, err)
+	assert.Equal(t, comparison.Parsed1.Parser, xcoreef.NATURAL_VERSION_PARSER)
+
+	saveTestFirmwareVersionPattern(t, "model1", `^(?P<family>MODEL1)_(\d+)\.(\d+)$`)
+	comparison, err = CompareFirmwareVersions("stb", "MODEL1", "MODEL1_1.9", "MODEL1_1.10")
+	assert.NilError(t, err)
+	assert.Equal(t, comparison.Parsed1.Parser, "model1")
+	assert.DeepEqual(t, comparison.Parsed1.Components, []string{"1", "9"})
+	assert.Equal(t, comparison.Result, -1)
+
+	// the pattern only applies to its application type
+	comparison, err = CompareFirmwareVersions("rdkcloud", "MODEL1", "MODEL1_1.9", "MODEL1_1.10")
+	assert.NilError(t, err)
+	assert.Equal(t, comparison.Parsed1.Parser, xcoreef.NATURAL_VERSION_PARSER)
+}
+
+func TestFirmwareVersionWarnings(t *testing.T) {
+	testutil.SetupTestDB()
+	createTestModel(t, "MODEL1")
+	saveTestFirmwareVersionPattern(t, "model1", `^(?P<family>MODEL1)_(\d+)\.(\d+)$`)
+	createTestFirmwareConfig(t, "config-1", "MODEL1_1.0")
+	createTestFirmwareConfig(t, "config-2", "MODEL1_2.0")
+	createTestFirmwareConfig(t, "config-3", "MODEL1_3.0")
+
+	bean := coreef.NewPercentageBean()
+	bean.Model = "MODEL1"
+	bean.Distributions = []*corefw.ConfigEntry{corefw.NewConfigEntry("config-2", 0, 100)}
+	assert.Equal(t, len(GetPercentageBeanVersionWarnings(bean, "stb")), 0)
+	bean.IntermediateVersion = "config-3"
+	bean.LastKnownGood = "config-3"
+	assert.DeepEqual(t, GetPercentageBeanVersionWarnings(bean, "stb"), []string{
+		"IntermediateVersion MODEL1_3.0 is newer than the distribution version MODEL1_2.0",
+		"LastKnownGood MODEL1_3.0 is newer than every distribution version",
+	})
+
+	amv := &corefw.ActivationVersion{Model: "MODEL1", FirmwareVersions: []string{"MODEL1_2.1", "MODEL1_1.5", "1.5-beta"}}
+	assert.DeepEqual(t, GetAmvVersionWarnings(amv, "stb"), []string{"Firmware version 1.5-beta does not match the version pattern of model MODEL1"})
+	// the versions of the AMV are not reordered
+	assert.DeepEqual(t, amv.FirmwareVersions, []string{"MODEL1_2.1", "MODEL1_1.5", "1.5-beta"})
+
+	bean.ID = "bean-1"
+	bean.Name = "bean-1"
+	bean.Active = true
+	bean.IntermediateVersion = ""
+	bean.LastKnownGood = "config-1"
+	rule := coreef.ConvertPercentageBeanToFirmwareRule(*bean)
+	assert.NilError(t, corefw.CreateFirmwareRuleOneDB(rule))
+	amv.FirmwareVersions = []string{"MODEL1_2.1", "MODEL1_1.5"}
+	assert.DeepEqual(t, GetAmvVersionWarnings(amv, "stb"), []string{"Minimum version MODEL1_1.5 is newer than the LastKnownGood MODEL1_1.0 of percentage bean bean-1"})
+	amv.FirmwareVersions = []string{"MODEL1_2.1", "MODEL1_1.0"}
+	assert.Equal(t, len(GetAmvVersionWarnings(amv, "stb")), 0)
+}
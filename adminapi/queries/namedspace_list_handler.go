@@ -143,11 +143,17 @@ func CreateIpAddressGroupHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_NAMESPACED_LIST, newIpAddressGroup.Id); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
 	respEntity := CreateIpAddressGroup(&newIpAddressGroup)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	auth.AssignOwnership(r, xshared.OWNED_NAMESPACED_LIST, newIpAddressGroup.Id)
 
 	res, err := xhttp.ReturnJsonResponse(respEntity.Data, r)
 	if err != nil {
@@ -184,6 +190,11 @@ func AddDataIpAddressGroupHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_NAMESPACED_LIST, listId); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
 	respEntity := AddNamespacedListData(shared.IP_LIST, listId, &stringListWrapper)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
@@ -225,6 +236,11 @@ func RemoveDataIpAddressGroupHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_NAMESPACED_LIST, listId); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
 	respEntity := RemoveNamespacedListData(shared.IP_LIST, listId, &stringListWrapper)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
@@ -245,7 +261,12 @@ func DeleteIpAddressGroupHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respEntity := DeleteNamespacedList(shared.IP_LIST, id)
+	if err := auth.ValidateOwnership(r, xshared.OWNED_NAMESPACED_LIST, id); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	respEntity := DeleteNamespacedList(shared.IP_LIST, id, nil)
 	if respEntity.Error != nil {
 		if respEntity.Status == http.StatusNotFound {
 			respEntity.Status = http.StatusNoContent // Ignored not found
@@ -254,6 +275,7 @@ func DeleteIpAddressGroupHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	auth.RemoveOwnership(xshared.OWNED_NAMESPACED_LIST, id)
 	xwhttp.WriteXconfResponse(w, respEntity.Status, nil)
 }
 
@@ -348,11 +370,17 @@ func CreateIpAddressGroupHandlerV2(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respEntity := CreateNamespacedList(newIpList, false)
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_NAMESPACED_LIST, newIpList.ID); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	respEntity := CreateNamespacedList(newIpList, false, nil)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	auth.AssignOwnership(r, xshared.OWNED_NAMESPACED_LIST, newIpList.ID)
 
 	res, err := xhttp.ReturnJsonResponse(respEntity.Data, r)
 	if err != nil {
@@ -383,11 +411,17 @@ func UpdateIpAddressGroupHandlerV2(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respEntity := UpdateNamespacedList(newIpList, "")
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_NAMESPACED_LIST, newIpList.ID); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	respEntity := UpdateNamespacedList(newIpList, "", nil)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	auth.UpdateOwnership(r, xshared.OWNED_NAMESPACED_LIST, newIpList.ID)
 
 	res, err := xhttp.ReturnJsonResponse(respEntity.Data, r)
 	if err != nil {
@@ -410,11 +444,17 @@ func DeleteIpAddressGroupHandlerV2(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respEntity := DeleteNamespacedList(shared.IP_LIST, id)
+	if err := auth.ValidateOwnership(r, xshared.OWNED_NAMESPACED_LIST, id); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	respEntity := DeleteNamespacedList(shared.IP_LIST, id, nil)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	auth.RemoveOwnership(xshared.OWNED_NAMESPACED_LIST, id)
 	xwhttp.WriteXconfResponse(w, respEntity.Status, []byte(fmt.Sprintf("Successfully deleted %s", id)))
 }
 
@@ -505,12 +545,18 @@ func SaveMacListHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_NAMESPACED_LIST, newMacList.ID); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
 	// Create the new MacList or update an existing one
-	respEntity := CreateNamespacedList(newMacList, true)
+	respEntity := CreateNamespacedList(newMacList, true, nil)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	auth.UpdateOwnership(r, xshared.OWNED_NAMESPACED_LIST, newMacList.ID)
 
 	res, err := xhttp.ReturnJsonResponse(respEntity.Data, r)
 	if err != nil {
@@ -540,11 +586,17 @@ func CreateMacListHandlerV2(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respEntity := CreateNamespacedList(newMacList, false)
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_NAMESPACED_LIST, newMacList.ID); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	respEntity := CreateNamespacedList(newMacList, false, nil)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	auth.AssignOwnership(r, xshared.OWNED_NAMESPACED_LIST, newMacList.ID)
 
 	res, err := xhttp.ReturnJsonResponse(respEntity.Data, r)
 	if err != nil {
@@ -574,11 +626,17 @@ func UpdateMacListHandlerV2(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respEntity := UpdateNamespacedList(newMacList, "")
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_NAMESPACED_LIST, newMacList.ID); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	respEntity := UpdateNamespacedList(newMacList, "", nil)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	auth.UpdateOwnership(r, xshared.OWNED_NAMESPACED_LIST, newMacList.ID)
 
 	res, err := xhttp.ReturnJsonResponse(respEntity.Data, r)
 	if err != nil {
@@ -615,6 +673,11 @@ func AddDataMacListHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_NAMESPACED_LIST, listId); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
 	respEntity := AddNamespacedListData(shared.MAC_LIST, listId, &stringListWrapper)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
@@ -656,6 +719,11 @@ func RemoveDataMacListHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_NAMESPACED_LIST, listId); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
 	respEntity := RemoveNamespacedListData(shared.MAC_LIST, listId, &stringListWrapper)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
@@ -682,7 +750,12 @@ func DeleteMacListHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respEntity := DeleteNamespacedList(shared.MAC_LIST, id)
+	if err := auth.ValidateOwnership(r, xshared.OWNED_NAMESPACED_LIST, id); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	respEntity := DeleteNamespacedList(shared.MAC_LIST, id, nil)
 	if respEntity.Error != nil {
 		if respEntity.Status == http.StatusNotFound {
 			respEntity.Status = http.StatusNoContent // Ignored not found
@@ -691,6 +764,7 @@ func DeleteMacListHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	auth.RemoveOwnership(xshared.OWNED_NAMESPACED_LIST, id)
 	xwhttp.WriteXconfResponse(w, respEntity.Status, nil)
 }
 
@@ -735,11 +809,17 @@ func DeleteMacListHandlerV2(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respEntity := DeleteNamespacedList(shared.MAC_LIST, id)
+	if err := auth.ValidateOwnership(r, xshared.OWNED_NAMESPACED_LIST, id); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	respEntity := DeleteNamespacedList(shared.MAC_LIST, id, nil)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	auth.RemoveOwnership(xshared.OWNED_NAMESPACED_LIST, id)
 	xwhttp.WriteXconfResponse(w, respEntity.Status, []byte(fmt.Sprintf("Successfully deleted %s", id)))
 }
 
@@ -919,11 +999,22 @@ func CreateNamespacedListHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respEntity := CreateNamespacedList(newNamespacedListList, false)
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_NAMESPACED_LIST, newNamespacedListList.ID); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	dryRun := xhttp.NewDryRun(r)
+	respEntity := CreateNamespacedList(newNamespacedListList, false, dryRun)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	if dryRun != nil {
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
+	auth.AssignOwnership(r, xshared.OWNED_NAMESPACED_LIST, newNamespacedListList.ID)
 
 	res, err := xhttp.ReturnJsonResponse(respEntity.Data, r)
 	if err != nil {
@@ -954,11 +1045,22 @@ func UpdateNamespacedListHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respEntity := UpdateNamespacedList(namespacedListList, "")
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_NAMESPACED_LIST, namespacedListList.ID); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	dryRun := xhttp.NewDryRun(r)
+	respEntity := UpdateNamespacedList(namespacedListList, "", dryRun)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	if dryRun != nil {
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
+	auth.UpdateOwnership(r, xshared.OWNED_NAMESPACED_LIST, namespacedListList.ID)
 
 	res, err := xhttp.ReturnJsonResponse(respEntity.Data, r)
 	if err != nil {
@@ -996,11 +1098,24 @@ func RenameNamespacedListHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respEntity := UpdateNamespacedList(namespacedListList, id)
+	oldId := namespacedListList.ID
+	if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_NAMESPACED_LIST, oldId); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	dryRun := xhttp.NewDryRun(r)
+	respEntity := UpdateNamespacedList(namespacedListList, id, dryRun)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	if dryRun != nil {
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
+	auth.MoveOwnership(r, xshared.OWNED_NAMESPACED_LIST, oldId, namespacedListList.ID)
+	auth.UpdateOwnership(r, xshared.OWNED_NAMESPACED_LIST, namespacedListList.ID)
 
 	res, err := xhttp.ReturnJsonResponse(respEntity.Data, r)
 	if err != nil {
@@ -1023,11 +1138,22 @@ func DeleteNamespacedListHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respEntity := DeleteNamespacedList("", id)
+	if err := auth.ValidateOwnership(r, xshared.OWNED_NAMESPACED_LIST, id); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	dryRun := xhttp.NewDryRun(r)
+	respEntity := DeleteNamespacedList("", id, dryRun)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	if dryRun != nil {
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
+	auth.RemoveOwnership(xshared.OWNED_NAMESPACED_LIST, id)
 	xwhttp.WriteXconfResponse(w, respEntity.Status, nil)
 }
 
@@ -1104,11 +1230,22 @@ func PostNamespacedListEntitiesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	dryRun := xhttp.NewDryRun(r)
 	entitiesMap := map[string]xhttp.EntityMessage{}
 	for _, entity := range entities {
 		entity := entity
-		respEntity := CreateNamespacedList(&entity, false)
+		if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_NAMESPACED_LIST, entity.ID); err != nil {
+			entitiesMap[entity.ID] = xhttp.EntityMessage{
+				Status:  xcommon.ENTITY_STATUS_FAILURE,
+				Message: err.Error(),
+			}
+			continue
+		}
+		respEntity := CreateNamespacedList(&entity, false, dryRun)
 		if respEntity.Error == nil {
+			if dryRun == nil {
+				auth.AssignOwnership(r, xshared.OWNED_NAMESPACED_LIST, entity.ID)
+			}
 			entitiesMap[entity.ID] = xhttp.EntityMessage{
 				Status:  xcommon.ENTITY_STATUS_SUCCESS,
 				Message: entity.ID,
@@ -1121,6 +1258,11 @@ func PostNamespacedListEntitiesHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if dryRun != nil {
+		dryRun.Result = entitiesMap
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	response, err := xhttp.ReturnJsonResponse(entitiesMap, r)
 	if err != nil {
 		xhttp.AdminError(w, err)
@@ -1147,11 +1289,22 @@ func PutNamespacedListEntitiesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	dryRun := xhttp.NewDryRun(r)
 	entitiesMap := map[string]xhttp.EntityMessage{}
 	for _, entity := range entities {
 		entity := entity
-		respEntity := UpdateNamespacedList(&entity, "")
+		if err := auth.ValidateOwnershipForWrite(r, xshared.OWNED_NAMESPACED_LIST, entity.ID); err != nil {
+			entitiesMap[entity.ID] = xhttp.EntityMessage{
+				Status:  xcommon.ENTITY_STATUS_FAILURE,
+				Message: err.Error(),
+			}
+			continue
+		}
+		respEntity := UpdateNamespacedList(&entity, "", dryRun)
 		if respEntity.Error == nil {
+			if dryRun == nil {
+				auth.UpdateOwnership(r, xshared.OWNED_NAMESPACED_LIST, entity.ID)
+			}
 			entitiesMap[entity.ID] = xhttp.EntityMessage{
 				Status:  xcommon.ENTITY_STATUS_SUCCESS,
 				Message: entity.ID,
@@ -1164,6 +1317,11 @@ func PutNamespacedListEntitiesHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if dryRun != nil {
+		dryRun.Result = entitiesMap
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	response, err := xhttp.ReturnJsonResponse(entitiesMap, r)
 	if err != nil {
 		xhttp.AdminError(w, err)
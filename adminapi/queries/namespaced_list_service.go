@@ -28,7 +28,10 @@ import (
 	ru "xconfwebconfig/rulesengine"
 	xutil "xconfwebconfig/util"
 
+	"xconfadmin/adminapi/auth"
 	"xconfadmin/common"
+	xhttp "xconfadmin/http"
+	xshared "xconfadmin/shared"
 	xrfc "xconfadmin/shared/rfc"
 	"xconfadmin/util"
 	ds "xconfwebconfig/db"
@@ -146,6 +149,7 @@ func GetNamespacedListsByContext(searchContext map[string]string) []*shared.Gene
 	}
 
 	filteredLists := make([]*shared.GenericNamespacedList, 0, len(lists))
+	honoredByOwner := auth.NewOwnerFilter(searchContext, xshared.OWNED_NAMESPACED_LIST)
 
 	for _, list := range lists {
 		if name, ok := util.FindEntryInContext(searchContext, common.NAME_UPPER, false); ok {
@@ -169,6 +173,9 @@ func GetNamespacedListsByContext(searchContext map[string]string) []*shared.Gene
 				}
 			}
 		}
+		if !honoredByOwner(list.ID) {
+			continue
+		}
 		filteredLists = append(filteredLists, list)
 	}
 	return filteredLists
@@ -298,7 +305,7 @@ func RemoveNamespacedListData(listType string, listId string, stringListWrapper
 	return xwhttp.NewResponseEntity(http.StatusOK, nil, listToUpdate)
 }
 
-func CreateNamespacedList(namespacedList *shared.GenericNamespacedList, updateIfExists bool) *xwhttp.ResponseEntity {
+func CreateNamespacedList(namespacedList *shared.GenericNamespacedList, updateIfExists bool, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
 	err := namespacedList.Validate()
 	if err != nil {
 		return xwhttp.NewResponseEntity(http.StatusBadRequest, err, nil)
@@ -317,11 +324,16 @@ func CreateNamespacedList(namespacedList *shared.GenericNamespacedList, updateIf
 	// No need to check for existing record if update is allowed
 	if !updateIfExists {
 		existingList, _ := shared.GetGenericNamedListOneByTypeNonCached(namespacedList.ID, namespacedList.TypeName)
-		if existingList != nil {
+		if existingList != nil || dryRun.Saved(ds.TABLE_GENERIC_NS_LIST, namespacedList.ID) {
 			return xwhttp.NewResponseEntity(http.StatusConflict, fmt.Errorf("List with name %s already exists", namespacedList.ID), nil)
 		}
 	}
 
+	if dryRun != nil {
+		existingList, _ := shared.GetGenericNamedListOneByTypeNonCached(namespacedList.ID, namespacedList.TypeName)
+		dryRun.RecordSave(ds.TABLE_GENERIC_NS_LIST, namespacedList.ID, namespacedList, existingList != nil)
+		return xwhttp.NewResponseEntity(http.StatusCreated, nil, namespacedList)
+	}
 	err = shared.CreateGenericNamedListOneDB(namespacedList)
 	if err != nil {
 		return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
@@ -330,7 +342,7 @@ func CreateNamespacedList(namespacedList *shared.GenericNamespacedList, updateIf
 	return xwhttp.NewResponseEntity(http.StatusCreated, nil, namespacedList)
 }
 
-func UpdateNamespacedList(namespacedList *shared.GenericNamespacedList, newId string) *xwhttp.ResponseEntity {
+func UpdateNamespacedList(namespacedList *shared.GenericNamespacedList, newId string, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
 	err := namespacedList.Validate()
 	if err != nil {
 		return xwhttp.NewResponseEntity(http.StatusBadRequest, err, nil)
@@ -353,14 +365,20 @@ func UpdateNamespacedList(namespacedList *shared.GenericNamespacedList, newId st
 			return xwhttp.NewResponseEntity(http.StatusConflict, fmt.Errorf("\"%s %s already exists\"", namespacedList.TypeName, newId), nil)
 		}
 
-		if err = renameNamespacedListInUsedEntities(namespacedList.ID, newId); err != nil {
+		if err = renameNamespacedListInUsedEntities(namespacedList.ID, newId, dryRun); err != nil {
 			return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
 		}
 
-		if err = shared.DeleteOneGenericNamedList(namespacedList.ID); err != nil {
+		if dryRun != nil {
+			dryRun.Record(xhttp.DRY_RUN_DELETE, ds.TABLE_GENERIC_NS_LIST, namespacedList.ID, nil)
+		} else if err = shared.DeleteOneGenericNamedList(namespacedList.ID); err != nil {
 			return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
 		}
 		namespacedList.ID = newId
+		if dryRun != nil {
+			dryRun.Record(xhttp.DRY_RUN_CREATE, ds.TABLE_GENERIC_NS_LIST, namespacedList.ID, namespacedList)
+			return xwhttp.NewResponseEntity(http.StatusOK, nil, namespacedList)
+		}
 	} else {
 		existingList, err := shared.GetGenericNamedListOneByTypeNonCached(namespacedList.ID, namespacedList.TypeName)
 		if err != nil {
@@ -369,6 +387,10 @@ func UpdateNamespacedList(namespacedList *shared.GenericNamespacedList, newId st
 		if existingList == nil {
 			return xwhttp.NewResponseEntity(http.StatusConflict, fmt.Errorf("\"List with id %s doesn't exist\"", namespacedList.ID), nil)
 		}
+		if dryRun != nil {
+			dryRun.Record(xhttp.DRY_RUN_UPDATE, ds.TABLE_GENERIC_NS_LIST, namespacedList.ID, namespacedList)
+			return xwhttp.NewResponseEntity(http.StatusOK, nil, namespacedList)
+		}
 	}
 
 	err = shared.CreateGenericNamedListOneDB(namespacedList)
@@ -379,7 +401,7 @@ func UpdateNamespacedList(namespacedList *shared.GenericNamespacedList, newId st
 	return xwhttp.NewResponseEntity(http.StatusOK, nil, namespacedList)
 }
 
-func DeleteNamespacedList(typeName string, id string) *xwhttp.ResponseEntity {
+func DeleteNamespacedList(typeName string, id string, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
 	var namespacedList *shared.GenericNamespacedList
 	if typeName == "" {
 		namespacedList = GetNamespacedListById(id)
@@ -400,6 +422,10 @@ func DeleteNamespacedList(typeName string, id string) *xwhttp.ResponseEntity {
 		return xwhttp.NewResponseEntity(http.StatusConflict, errors.New(usage), nil)
 	}
 
+	if dryRun != nil {
+		dryRun.Record(xhttp.DRY_RUN_DELETE, ds.TABLE_GENERIC_NS_LIST, id, namespacedList)
+		return xwhttp.NewResponseEntity(http.StatusNoContent, nil, nil)
+	}
 	if err := shared.DeleteOneGenericNamedList(id); err == nil {
 		return xwhttp.NewResponseEntity(http.StatusNoContent, nil, nil)
 	}
@@ -446,7 +472,9 @@ func validateUsageForNamespacedList(id string) (string, error) {
 	return "", nil
 }
 
-func renameNamespacedListInUsedEntities(oldNamespacedListId string, newNamespacedListId string) error {
+// renameNamespacedListInUsedEntities points the rules and features using the list to its new id,
+// a dry run renames copies of them and only records the change
+func renameNamespacedListInUsedEntities(oldNamespacedListId string, newNamespacedListId string, dryRun *xhttp.DryRun) error {
 	for _, tableName := range ruleTables {
 		ruleList, err := ds.GetCachedSimpleDao().GetAllAsList(tableName, 0)
 		if err != nil {
@@ -454,10 +482,17 @@ func renameNamespacedListInUsedEntities(oldNamespacedListId string, newNamespace
 		}
 
 		for _, v := range ruleList {
+			if dryRun != nil {
+				if v, err = xutil.Copy(v); err != nil {
+					return err
+				}
+			}
 			if xrule, ok := v.(ru.XRule); ok {
 				rule := xrule.GetRule()
 				if ru.ChangeFixedArgToNewValue(oldNamespacedListId, newNamespacedListId, *rule, re.StandardOperationInList) {
-					if err := ds.GetCachedSimpleDao().SetOne(tableName, xrule.GetId(), v); err != nil {
+					if dryRun != nil {
+						dryRun.Record(xhttp.DRY_RUN_UPDATE, tableName, xrule.GetId(), v)
+					} else if err := ds.GetCachedSimpleDao().SetOne(tableName, xrule.GetId(), v); err != nil {
 						return err
 					}
 				}
@@ -465,13 +500,21 @@ func renameNamespacedListInUsedEntities(oldNamespacedListId string, newNamespace
 				return fmt.Errorf("Failed to assert %s as XRule type", tableName)
 			}
 		}
+	}
 
-		for _, feature := range rfc.GetFeatureList() {
-			if feature != nil && feature.Whitelisted && feature.WhitelistProperty != nil && feature.WhitelistProperty.Value == oldNamespacedListId {
-				feature.WhitelistProperty.Value = newNamespacedListId
-				if _, err := xrfc.SetOneFeature(feature); err != nil {
-					return err
-				}
+	for _, feature := range rfc.GetFeatureList() {
+		if feature != nil && feature.Whitelisted && feature.WhitelistProperty != nil && feature.WhitelistProperty.Value == oldNamespacedListId {
+			if dryRun != nil {
+				featureCopy := *feature
+				whitelistProperty := *feature.WhitelistProperty
+				whitelistProperty.Value = newNamespacedListId
+				featureCopy.WhitelistProperty = &whitelistProperty
+				dryRun.Record(xhttp.DRY_RUN_UPDATE, ds.TABLE_XCONF_FEATURE, feature.ID, &featureCopy)
+				continue
+			}
+			feature.WhitelistProperty.Value = newNamespacedListId
+			if _, err := xrfc.SetOneFeature(feature); err != nil {
+				return err
 			}
 		}
 	}
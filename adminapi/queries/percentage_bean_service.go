@@ -27,9 +27,12 @@ import (
 	"strings"
 
 	"xconfadmin/common"
+	xhttp "xconfadmin/http"
 	xshared "xconfadmin/shared"
+	xcoreef "xconfadmin/shared/estbfirmware"
 	"xconfadmin/util"
 	xcommon "xconfwebconfig/common"
+	"xconfwebconfig/db"
 	xwhttp "xconfwebconfig/http"
 	re "xconfwebconfig/rulesengine"
 	ru "xconfwebconfig/rulesengine"
@@ -181,6 +184,9 @@ func getPercentageBeanFieldValues(fieldName string, applicationType string) (map
 }
 
 func GetGlobalPercentageIdByApplication(applicationType string) string {
+	if appType := xshared.GetApplicationType(applicationType); appType != nil && appType.GlobalPercentageId != "" {
+		return appType.GlobalPercentageId
+	}
 	if xshared.ApplicationTypeEquals(applicationType, shared.STB) {
 		return firmware.GLOBAL_PERCENT
 	}
@@ -215,9 +221,9 @@ func GetStructFieldValues(fieldName string, structValue reflect.Value) []interfa
 	return resultFieldValues
 }
 
-func CreatePercentageBean(bean *coreef.PercentageBean, applicationType string) *xwhttp.ResponseEntity {
+func CreatePercentageBean(bean *coreef.PercentageBean, applicationType string, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
 	_, err := firmware.GetFirmwareRuleOneDB(bean.ID)
-	if err == nil {
+	if err == nil || dryRun.Saved(db.TABLE_FIRMWARE_RULE, bean.ID) {
 		return xwhttp.NewResponseEntity(http.StatusConflict, fmt.Errorf("Entity with id %s Already Exist", bean.ID), nil)
 	}
 
@@ -225,7 +231,7 @@ func CreatePercentageBean(bean *coreef.PercentageBean, applicationType string) *
 		return xwhttp.NewResponseEntity(http.StatusConflict, fmt.Errorf("Entity with id %s ApplicationType doesn't match", bean.ID), nil)
 	}
 
-	if err := firmware.ValidateRuleName(bean.ID, bean.Name); err != nil {
+	if err := validatePendingRuleName(bean.ID, bean.Name, dryRun); err != nil {
 		return xwhttp.NewResponseEntity(http.StatusBadRequest, err, nil)
 	}
 
@@ -237,6 +243,7 @@ func CreatePercentageBean(bean *coreef.PercentageBean, applicationType string) *
 	if err != nil {
 		return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
 	}
+	beans = withPendingPercentageBeans(beans, bean.ApplicationType, dryRun)
 
 	if err := bean.ValidateAll(beans); err != nil {
 		return xwhttp.NewResponseEntity(http.StatusConflict, err, nil)
@@ -246,7 +253,12 @@ func CreatePercentageBean(bean *coreef.PercentageBean, applicationType string) *
 
 	fRule := coreef.ConvertPercentageBeanToFirmwareRule(*bean)
 	ru.NormalizeConditions(&fRule.Rule)
-	if err := firmware.CreateFirmwareRuleOneDB(fRule); err != nil {
+	if _, err := validateFirmwareConfigLifecycle(fRule, applicationType); err != nil {
+		return xwhttp.NewResponseEntity(http.StatusConflict, err, nil)
+	}
+	if dryRun != nil {
+		dryRun.Record(xhttp.DRY_RUN_CREATE, db.TABLE_FIRMWARE_RULE, fRule.ID, fRule)
+	} else if err := firmware.CreateFirmwareRuleOneDB(fRule); err != nil {
 		return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
 	}
 
@@ -255,12 +267,12 @@ func CreatePercentageBean(bean *coreef.PercentageBean, applicationType string) *
 	return xwhttp.NewResponseEntity(http.StatusCreated, nil, newBean)
 }
 
-func UpdatePercentageBean(bean *coreef.PercentageBean, applicationType string) *xwhttp.ResponseEntity {
+func UpdatePercentageBean(bean *coreef.PercentageBean, applicationType string, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
 	if xutil.IsBlank(bean.ID) {
 		return xwhttp.NewResponseEntity(http.StatusBadRequest, errors.New("Entity id is empty"), nil)
 	}
 
-	fRule, err := firmware.GetFirmwareRuleOneDB(bean.ID)
+	fRule, err := getFirmwareRuleOrPending(bean.ID, dryRun)
 	if fRule == nil || err != nil {
 		return xwhttp.NewResponseEntity(http.StatusBadRequest, fmt.Errorf("Entity with id: %s does not exist", bean.ID), nil)
 	}
@@ -271,7 +283,7 @@ func UpdatePercentageBean(bean *coreef.PercentageBean, applicationType string) *
 		return xwhttp.NewResponseEntity(http.StatusBadRequest, fmt.Errorf("ApplicationType cannot be changed: Existing value:"+fRule.ApplicationType+" New Value:"+bean.ApplicationType), nil)
 	}
 
-	if err := firmware.ValidateRuleName(bean.ID, bean.Name); err != nil {
+	if err := validatePendingRuleName(bean.ID, bean.Name, dryRun); err != nil {
 		return xwhttp.NewResponseEntity(http.StatusBadRequest, err, nil)
 	}
 
@@ -283,6 +295,7 @@ func UpdatePercentageBean(bean *coreef.PercentageBean, applicationType string) *
 	if err != nil {
 		return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
 	}
+	beans = withPendingPercentageBeans(beans, bean.ApplicationType, dryRun)
 
 	if err := bean.ValidateAll(beans); err != nil {
 		return xwhttp.NewResponseEntity(http.StatusConflict, err, nil)
@@ -292,7 +305,12 @@ func UpdatePercentageBean(bean *coreef.PercentageBean, applicationType string) *
 
 	fRule = coreef.ConvertPercentageBeanToFirmwareRule(*bean)
 	ru.NormalizeConditions(&fRule.Rule)
-	if err := firmware.CreateFirmwareRuleOneDB(fRule); err != nil {
+	if _, err := validateFirmwareConfigLifecycle(fRule, applicationType); err != nil {
+		return xwhttp.NewResponseEntity(http.StatusConflict, err, nil)
+	}
+	if dryRun != nil {
+		dryRun.Record(xhttp.DRY_RUN_UPDATE, db.TABLE_FIRMWARE_RULE, fRule.ID, fRule)
+	} else if err := firmware.CreateFirmwareRuleOneDB(fRule); err != nil {
 		return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
 	}
 
@@ -301,7 +319,7 @@ func UpdatePercentageBean(bean *coreef.PercentageBean, applicationType string) *
 	return xwhttp.NewResponseEntity(http.StatusOK, nil, newBean)
 }
 
-func DeletePercentageBean(id string, app string) *xwhttp.ResponseEntity {
+func DeletePercentageBean(id string, app string, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
 	fRule, err := firmware.GetFirmwareRuleOneDB(id)
 	if err != nil {
 		return xwhttp.NewResponseEntity(http.StatusNotFound, fmt.Errorf("Entity with id: %s does not exist", id), nil)
@@ -309,13 +327,60 @@ func DeletePercentageBean(id string, app string) *xwhttp.ResponseEntity {
 	if fRule.ApplicationType != app {
 		return xwhttp.NewResponseEntity(http.StatusNotFound, fmt.Errorf("Entity with id: %s ApplicationType doesn't match", id), nil)
 	}
+	if dryRun != nil {
+		dryRun.Record(xhttp.DRY_RUN_DELETE, db.TABLE_FIRMWARE_RULE, fRule.ID, fRule)
+		if rollout := xcoreef.GetPercentageRollout(fRule.ID); rollout != nil {
+			dryRun.Record(xhttp.DRY_RUN_DELETE, common.TABLE_PERCENTAGE_ROLLOUTS, fRule.ID, rollout)
+		}
+		return xwhttp.NewResponseEntity(http.StatusNoContent, nil, nil)
+	}
 	if err = firmware.DeleteOneFirmwareRule(fRule.ID); err != nil {
 		return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
 	}
+	if xcoreef.GetPercentageRollout(fRule.ID) != nil {
+		if err := xcoreef.DeletePercentageRollout(fRule.ID); err != nil {
+			log.Errorf("unable to delete rollout of percentage bean %s: %s", fRule.ID, err.Error())
+		}
+	}
 
 	return xwhttp.NewResponseEntity(http.StatusNoContent, nil, nil)
 }
 
+// validatePendingRuleName checks the name against the stored rules and the rules saved earlier in the dry run
+func validatePendingRuleName(id string, name string, dryRun *xhttp.DryRun) error {
+	if err := firmware.ValidateRuleName(id, name); err != nil {
+		return err
+	}
+	for pendingId, entity := range dryRun.Pending(db.TABLE_FIRMWARE_RULE) {
+		if rule, ok := entity.(*firmware.FirmwareRule); ok && pendingId != id && rule.Name == name {
+			return errors.New("Name is already used")
+		}
+	}
+	return nil
+}
+
+// withPendingPercentageBeans replaces the stored beans with the beans saved or deleted earlier in the dry run
+func withPendingPercentageBeans(beans []*coreef.PercentageBean, applicationType string, dryRun *xhttp.DryRun) []*coreef.PercentageBean {
+	pending := dryRun.Pending(db.TABLE_FIRMWARE_RULE)
+	if len(pending) == 0 {
+		return beans
+	}
+	result := []*coreef.PercentageBean{}
+	for _, bean := range beans {
+		if _, ok := pending[bean.ID]; !ok {
+			result = append(result, bean)
+		}
+	}
+	for _, entity := range pending {
+		if rule, ok := entity.(*firmware.FirmwareRule); ok && rule.ApplicationType == applicationType && rule.Type == firmware.ENV_MODEL_RULE {
+			bean := coreef.ConvertFirmwareRuleToPercentageBean(rule)
+			replaceFieldsWithFirmwareVersion(bean)
+			result = append(result, bean)
+		}
+	}
+	return result
+}
+
 func percentageBeanGeneratePage(list []*coreef.PercentageBean, page int, pageSize int) (result []*coreef.PercentageBean) {
 	leng := len(list)
 	startIndex := page*pageSize - pageSize
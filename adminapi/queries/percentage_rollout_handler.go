@@ -0,0 +1,133 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package queries
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"xconfadmin/adminapi/auth"
+	xhttp "xconfadmin/http"
+	xcoreef "xconfadmin/shared/estbfirmware"
+	"xconfwebconfig/common"
+	xwhttp "xconfwebconfig/http"
+
+	"github.com/gorilla/mux"
+)
+
+// percentageRolloutAction changes the status of the rollout of a percentage bean
+type percentageRolloutAction func(r *http.Request, beanId string, applicationType string) (*xcoreef.PercentageRollout, error)
+
+func getPercentageBeanId(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id, found := mux.Vars(r)[common.ID]
+	if !found || id == "" {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("Required ID parameter '%s' is not present", common.ID))
+		return "", false
+	}
+	return id, true
+}
+
+func writePercentageRollout(w http.ResponseWriter, r *http.Request, rollout *xcoreef.PercentageRollout, status int) {
+	res, err := xhttp.ReturnJsonResponse(rollout, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteResponseBytes(w, res, status, xhttp.ContextTypeHeader(r))
+}
+
+func GetPercentageRolloutHandler(w http.ResponseWriter, r *http.Request) {
+	applicationType, err := auth.CanRead(r, auth.FIRMWARE_ENTITY)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	id, ok := getPercentageBeanId(w, r)
+	if !ok {
+		return
+	}
+
+	rollout, err := GetPercentageRollout(id, applicationType)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	writePercentageRollout(w, r, rollout, http.StatusOK)
+}
+
+func CreatePercentageRolloutHandler(w http.ResponseWriter, r *http.Request) {
+	applicationType, err := auth.CanWrite(r, auth.FIRMWARE_ENTITY)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	id, ok := getPercentageBeanId(w, r)
+	if !ok {
+		return
+	}
+
+	xw, ok := w.(*xwhttp.XResponseWriter)
+	if !ok {
+		xhttp.WriteAdminErrorResponse(w, http.StatusInternalServerError, "responsewriter cast error")
+		return
+	}
+	plan := xcoreef.PercentageRollout{}
+	if err := json.Unmarshal([]byte(xw.Body()), &plan); err != nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, "Unable to extract rollout from json file:"+err.Error())
+		return
+	}
+
+	rollout, err := CreatePercentageRollout(r, id, applicationType, plan.Steps)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	writePercentageRollout(w, r, rollout, http.StatusCreated)
+}
+
+func PausePercentageRolloutHandler(w http.ResponseWriter, r *http.Request) {
+	changePercentageRollout(w, r, PausePercentageRollout)
+}
+
+func ResumePercentageRolloutHandler(w http.ResponseWriter, r *http.Request) {
+	changePercentageRollout(w, r, ResumePercentageRollout)
+}
+
+func AbortPercentageRolloutHandler(w http.ResponseWriter, r *http.Request) {
+	changePercentageRollout(w, r, AbortPercentageRollout)
+}
+
+func changePercentageRollout(w http.ResponseWriter, r *http.Request, action percentageRolloutAction) {
+	applicationType, err := auth.CanWrite(r, auth.FIRMWARE_ENTITY)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	id, ok := getPercentageBeanId(w, r)
+	if !ok {
+		return
+	}
+
+	rollout, err := action(r, id, applicationType)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	writePercentageRollout(w, r, rollout, http.StatusOK)
+}
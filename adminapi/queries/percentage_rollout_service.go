@@ -0,0 +1,311 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package queries
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"xconfadmin/adminapi/auth"
+	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
+	xshared "xconfadmin/shared"
+	xchange "xconfadmin/shared/change"
+	xcoreef "xconfadmin/shared/estbfirmware"
+	"xconfwebconfig/db"
+	coreef "xconfwebconfig/shared/estbfirmware"
+	"xconfwebconfig/shared/firmware"
+	"xconfwebconfig/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// PERCENTAGE_ROLLOUT_SCHEDULER is the user of the audit entries of the steps applied without a known author
+const PERCENTAGE_ROLLOUT_SCHEDULER = "percentageRolloutScheduler"
+
+// PercentageBeanResponse is a percentage bean with the progress of its rollout plan
+type PercentageBeanResponse struct {
+	*coreef.PercentageBean
+	Rollout *xcoreef.PercentageRolloutProgress `json:"rollout,omitempty"`
+}
+
+// NewPercentageBeanResponses adds the progress of the rollout plans to the beans
+func NewPercentageBeanResponses(beans []*coreef.PercentageBean) []*PercentageBeanResponse {
+	rollouts := map[string]*xcoreef.PercentageRollout{}
+	for _, rollout := range xcoreef.GetPercentageRollouts() {
+		rollouts[rollout.ID] = rollout
+	}
+	result := make([]*PercentageBeanResponse, 0, len(beans))
+	for _, bean := range beans {
+		response := &PercentageBeanResponse{PercentageBean: bean}
+		if rollout, ok := rollouts[bean.ID]; ok {
+			response.Rollout = rollout.GetProgress()
+		}
+		result = append(result, response)
+	}
+	return result
+}
+
+// NewPercentageBeanResponse adds the progress of the rollout plan to the bean
+func NewPercentageBeanResponse(bean *coreef.PercentageBean) *PercentageBeanResponse {
+	response := &PercentageBeanResponse{PercentageBean: bean}
+	if rollout := xcoreef.GetPercentageRollout(bean.ID); rollout != nil {
+		response.Rollout = rollout.GetProgress()
+	}
+	return response
+}
+
+func copyConfigEntries(entries []*firmware.ConfigEntry) []*firmware.ConfigEntry {
+	result := make([]*firmware.ConfigEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry != nil {
+			copied := *entry
+			result = append(result, &copied)
+		}
+	}
+	return result
+}
+
+// validateRolloutApproval rejects rollouts of percentage beans which can only be changed through approval
+func validateRolloutApproval(applicationType string) error {
+	if xchange.IsApprovalRequired(xchange.PERCENTAGE_BEAN, applicationType) {
+		return xcommon.NewXconfError(http.StatusConflict, fmt.Sprintf("Changes of %s require approval for %s, they cannot be rolled out by a plan", xchange.PERCENTAGE_BEAN, applicationType))
+	}
+	return nil
+}
+
+// GetPercentageRollout returns the rollout plan of the percentage bean
+func GetPercentageRollout(beanId string, applicationType string) (*xcoreef.PercentageRollout, error) {
+	rollout := xcoreef.GetPercentageRollout(beanId)
+	if rollout == nil || rollout.ApplicationType != applicationType {
+		return nil, xcommon.NewXconfError(http.StatusNotFound, "Rollout of percentage bean "+beanId+" does not exist")
+	}
+	return rollout, nil
+}
+
+// CreatePercentageRollout attaches a rollout plan to the percentage bean. Every step is validated
+// against the bean up front, the first step is applied as soon as it is due
+func CreatePercentageRollout(r *http.Request, beanId string, applicationType string, steps []*xcoreef.PercentageRolloutStep) (*xcoreef.PercentageRollout, error) {
+	bean, err := GetOnePercentageBeanFromDB(beanId)
+	if err != nil || bean.ApplicationType != applicationType {
+		return nil, xcommon.NewXconfError(http.StatusNotFound, "Entity with id: "+beanId+" does not exist")
+	}
+	if err := validateRolloutApproval(applicationType); err != nil {
+		return nil, err
+	}
+	if existing := xcoreef.GetPercentageRollout(beanId); existing != nil && existing.IsOpen() {
+		return nil, xcommon.NewXconfError(http.StatusConflict, fmt.Sprintf("Percentage bean %s already has a %s rollout, abort it first", beanId, existing.Status))
+	}
+	if len(steps) == 0 {
+		return nil, xcommon.NewXconfError(http.StatusBadRequest, "Rollout must have at least one step")
+	}
+	for i, step := range steps {
+		if step == nil {
+			return nil, xcommon.NewXconfError(http.StatusBadRequest, fmt.Sprintf("Step %d is empty", i+1))
+		}
+		if step.DurationMinutes < 0 {
+			return nil, xcommon.NewXconfError(http.StatusBadRequest, fmt.Sprintf("Step %d: durationMinutes must not be negative", i+1))
+		}
+		step.Distributions = copyConfigEntries(step.Distributions)
+		step.AppliedAt = 0
+		stepBean := *bean
+		stepBean.Distributions = copyConfigEntries(step.Distributions)
+		respEntity := UpdatePercentageBean(&stepBean, applicationType, &xhttp.DryRun{DryRun: true})
+		if respEntity.Error != nil {
+			return nil, xcommon.NewXconfError(respEntity.Status, fmt.Sprintf("Step %d: %s", i+1, respEntity.Error.Error()))
+		}
+	}
+
+	rollout := &xcoreef.PercentageRollout{
+		ID:                beanId,
+		ApplicationType:   applicationType,
+		Status:            xcoreef.PERCENTAGE_ROLLOUT_ACTIVE,
+		Steps:             steps,
+		BeanDistributions: copyConfigEntries(bean.Distributions),
+		CreatedBy:         auth.GetUserNameOrUnknown(r),
+		Created:           util.GetTimestamp(time.Now().UTC()),
+	}
+	if err := xcoreef.SetPercentageRollout(rollout); err != nil {
+		return nil, err
+	}
+	log.Infof("rollout of percentage bean %s with %d steps created by %s", beanId, len(steps), rollout.CreatedBy)
+	return rollout, nil
+}
+
+// PausePercentageRollout stops an active rollout at its current step
+func PausePercentageRollout(r *http.Request, beanId string, applicationType string) (*xcoreef.PercentageRollout, error) {
+	return changePercentageRolloutStatus(r, beanId, applicationType, xcoreef.PERCENTAGE_ROLLOUT_PAUSED, xcoreef.PERCENTAGE_ROLLOUT_ACTIVE)
+}
+
+// ResumePercentageRollout continues a paused or failed rollout, a step which is overdue is applied right away.
+// The distributions the bean has now are taken as reviewed, a manual change which paused the rollout is kept until the next step
+func ResumePercentageRollout(r *http.Request, beanId string, applicationType string) (*xcoreef.PercentageRollout, error) {
+	if err := validateRolloutApproval(applicationType); err != nil {
+		return nil, err
+	}
+	return changePercentageRolloutStatus(r, beanId, applicationType, xcoreef.PERCENTAGE_ROLLOUT_ACTIVE, xcoreef.PERCENTAGE_ROLLOUT_PAUSED, xcoreef.PERCENTAGE_ROLLOUT_FAILED)
+}
+
+// AbortPercentageRollout ends the rollout, the percentage bean keeps the distributions of the last applied step
+func AbortPercentageRollout(r *http.Request, beanId string, applicationType string) (*xcoreef.PercentageRollout, error) {
+	return changePercentageRolloutStatus(r, beanId, applicationType, xcoreef.PERCENTAGE_ROLLOUT_ABORTED, xcoreef.PERCENTAGE_ROLLOUT_ACTIVE, xcoreef.PERCENTAGE_ROLLOUT_PAUSED, xcoreef.PERCENTAGE_ROLLOUT_FAILED)
+}
+
+func changePercentageRolloutStatus(r *http.Request, beanId string, applicationType string, status string, fromStatuses ...string) (*xcoreef.PercentageRollout, error) {
+	rollout, err := GetPercentageRollout(beanId, applicationType)
+	if err != nil {
+		return nil, err
+	}
+	if !util.Contains(fromStatuses, rollout.Status) {
+		return nil, xcommon.NewXconfError(http.StatusConflict, fmt.Sprintf("Rollout of percentage bean %s is %s", beanId, rollout.Status))
+	}
+	if status == xcoreef.PERCENTAGE_ROLLOUT_ACTIVE {
+		bean, err := GetOnePercentageBeanFromDB(beanId)
+		if err != nil {
+			return nil, xcommon.NewXconfError(http.StatusNotFound, "Entity with id: "+beanId+" does not exist")
+		}
+		rollout.BeanDistributions = copyConfigEntries(bean.Distributions)
+	}
+	rollout.Status = status
+	rollout.Message = ""
+	if err := xcoreef.SetPercentageRollout(rollout); err != nil {
+		return nil, err
+	}
+	log.Infof("rollout of percentage bean %s is %s by %s", beanId, status, auth.GetUserNameOrUnknown(r))
+	return rollout, nil
+}
+
+// StartPercentageRolloutScheduler applies the due rollout steps in the background
+func StartPercentageRolloutScheduler(interval time.Duration) {
+	log.Infof("percentage rollout scheduler is running every %v", interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			if applied := ApplyDueRolloutSteps(now); applied > 0 {
+				log.Infof("percentage rollout scheduler applied %d steps", applied)
+			}
+		}
+	}()
+}
+
+// ApplyDueRolloutSteps applies the rollout steps which are due and returns the number of applied steps.
+// Every step is claimed first, so with several admin instances only one of them applies it
+func ApplyDueRolloutSteps(now time.Time) int {
+	if window := xcommon.GetActiveReadonlyWindow(now); window != nil {
+		log.Debugf("percentage rollout steps are postponed by freeze window %s", window.String())
+		return 0
+	}
+	owner, _ := os.Hostname()
+	applied := 0
+	for _, rollout := range xcoreef.GetPercentageRollouts() {
+		if !rollout.IsDue(now) {
+			continue
+		}
+		// every saved state of a rollout is claimed once, so a resumed step can be claimed again
+		claimed, err := xchange.Claim(rollout.ID+"_rollout_"+strconv.FormatInt(rollout.Updated, 10), owner)
+		if err != nil {
+			log.Errorf("unable to claim rollout step of percentage bean %s: %s", rollout.ID, err.Error())
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		// the rollout may have been paused or aborted since it was listed
+		if rollout = xcoreef.GetPercentageRollout(rollout.ID); rollout == nil || !rollout.IsDue(now) {
+			continue
+		}
+		if modified, err := applyRolloutStep(rollout, now); err != nil {
+			log.Errorf("unable to apply rollout step %d of percentage bean %s: %s", rollout.CurrentStep+1, rollout.ID, err.Error())
+			rollout.Status = xcoreef.PERCENTAGE_ROLLOUT_FAILED
+			rollout.Message = err.Error()
+		} else if modified {
+			log.Warnf("rollout of percentage bean %s is paused, the bean was modified outside the rollout", rollout.ID)
+			rollout.Status = xcoreef.PERCENTAGE_ROLLOUT_PAUSED
+			rollout.Message = fmt.Sprintf("Percentage bean %s was modified outside the rollout, review it and resume the rollout", rollout.ID)
+		} else {
+			applied++
+		}
+		if err := xcoreef.SetPercentageRollout(rollout); err != nil {
+			log.Errorf("unable to save rollout of percentage bean %s: %s", rollout.ID, err.Error())
+		}
+	}
+	return applied
+}
+
+// applyRolloutStep sets the distributions of the next step through the validation of a percentage bean update.
+// A bean whose distributions were changed since the last step is left as it is and modified is returned
+func applyRolloutStep(rollout *xcoreef.PercentageRollout, now time.Time) (modified bool, err error) {
+	if err := validateRolloutApproval(rollout.ApplicationType); err != nil {
+		return false, err
+	}
+	step := rollout.GetNextStep()
+	before, err := GetOnePercentageBeanFromDB(rollout.ID)
+	if err != nil {
+		return false, xcommon.NewXconfError(http.StatusNotFound, "Entity with id: "+rollout.ID+" does not exist")
+	}
+	if rollout.IsBeanModified(before.Distributions) {
+		return true, nil
+	}
+	bean := *before
+	bean.Distributions = copyConfigEntries(step.Distributions)
+	respEntity := UpdatePercentageBean(&bean, rollout.ApplicationType, nil)
+	if respEntity.Error != nil {
+		return false, respEntity.Error
+	}
+	if after, ok := respEntity.Data.(*coreef.PercentageBean); ok {
+		rollout.BeanDistributions = copyConfigEntries(after.Distributions)
+	}
+
+	step.AppliedAt = util.GetTimestamp(now.UTC())
+	rollout.CurrentStep++
+	if rollout.GetNextStep() == nil {
+		rollout.Status = xcoreef.PERCENTAGE_ROLLOUT_COMPLETED
+	}
+	auditRolloutStep(rollout, before, respEntity.Data)
+	return false, nil
+}
+
+func auditRolloutStep(rollout *xcoreef.PercentageRollout, before interface{}, after interface{}) {
+	user := rollout.CreatedBy
+	if user == "" {
+		user = PERCENTAGE_ROLLOUT_SCHEDULER
+	}
+	entry := xshared.AuditEntry{
+		User:            user,
+		Operation:       xshared.AUDIT_ROLLOUT_STEP,
+		Status:          http.StatusOK,
+		EntityType:      "Firmware-PercentFilter",
+		EntityID:        rollout.ID,
+		TableName:       db.TABLE_FIRMWARE_RULE,
+		ApplicationType: rollout.ApplicationType,
+	}
+	if bytes, err := json.Marshal(before); err == nil {
+		entry.Before = bytes
+	}
+	if bytes, err := json.Marshal(after); err == nil {
+		entry.After = bytes
+	}
+	if err := xshared.SaveAuditEntry(&entry); err != nil {
+		log.Errorf("unable to save audit entry for rollout step %d of percentage bean %s: %s", rollout.CurrentStep, rollout.ID, err.Error())
+	}
+}
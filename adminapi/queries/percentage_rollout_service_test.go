@@ -0,0 +1,127 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package queries
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	xcoreef "xconfadmin/shared/estbfirmware"
+	"xconfadmin/testutil"
+	coreef "xconfwebconfig/shared/estbfirmware"
+	corefw "xconfwebconfig/shared/firmware"
+
+	"gotest.tools/assert"
+)
+
+func setupTestPercentageRollout(t *testing.T) *xcoreef.PercentageRollout {
+	testutil.SetupTestDB()
+	createTestModel(t, "MODEL1")
+	createTestFirmwareConfig(t, "config-1", "MODEL1_1.0")
+	createTestFirmwareConfig(t, "config-2", "MODEL1_2.0")
+
+	bean := coreef.NewPercentageBean()
+	bean.ID = "bean-1"
+	bean.Name = "bean-1"
+	bean.Model = "MODEL1"
+	bean.Active = true
+	bean.ApplicationType = "stb"
+	bean.FirmwareVersions = []string{"MODEL1_1.0", "MODEL1_2.0"}
+	bean.Distributions = []*corefw.ConfigEntry{corefw.NewConfigEntry("config-1", 0, 100)}
+	assert.NilError(t, corefw.CreateFirmwareRuleOneDB(coreef.ConvertPercentageBeanToFirmwareRule(*bean)))
+
+	r := testutil.NewRequest(http.MethodPost, "/xconfAdminService/percentfilter/percentageBean/bean-1/rollout?applicationType=stb", "")
+	rollout, err := CreatePercentageRollout(r, "bean-1", "stb", []*xcoreef.PercentageRolloutStep{
+		{Distributions: []*corefw.ConfigEntry{corefw.NewConfigEntry("config-1", 0, 50), corefw.NewConfigEntry("config-2", 50, 100)}, DurationMinutes: 60},
+		{Distributions: []*corefw.ConfigEntry{corefw.NewConfigEntry("config-2", 0, 100)}},
+	})
+	assert.NilError(t, err)
+	return rollout
+}
+
+func getTestBeanDistributions(t *testing.T) []*corefw.ConfigEntry {
+	bean, err := GetOnePercentageBeanFromDB("bean-1")
+	assert.NilError(t, err)
+	return bean.Distributions
+}
+
+func TestPercentageRolloutAppliesItsSteps(t *testing.T) {
+	rollout := setupTestPercentageRollout(t)
+	now := time.Now()
+
+	assert.Equal(t, ApplyDueRolloutSteps(now), 1)
+	assert.Equal(t, len(getTestBeanDistributions(t)), 2)
+	// the next step holds for the duration of the first one
+	assert.Equal(t, ApplyDueRolloutSteps(now), 0)
+	assert.Equal(t, ApplyDueRolloutSteps(now.Add(61*time.Minute)), 1)
+
+	rollout = xcoreef.GetPercentageRollout(rollout.ID)
+	assert.Equal(t, rollout.Status, xcoreef.PERCENTAGE_ROLLOUT_COMPLETED)
+	assert.Equal(t, rollout.CurrentStep, 2)
+	distributions := getTestBeanDistributions(t)
+	assert.Equal(t, len(distributions), 1)
+	assert.Equal(t, distributions[0].ConfigId, "config-2")
+}
+
+func TestPercentageRolloutPausesOnAModifiedBean(t *testing.T) {
+	rollout := setupTestPercentageRollout(t)
+	now := time.Now()
+	assert.Equal(t, ApplyDueRolloutSteps(now), 1)
+
+	// the bean is edited by hand while the rollout holds the first step
+	bean, err := GetOnePercentageBeanFromDB("bean-1")
+	assert.NilError(t, err)
+	bean.Distributions = []*corefw.ConfigEntry{corefw.NewConfigEntry("config-1", 0, 80), corefw.NewConfigEntry("config-2", 80, 100)}
+	respEntity := UpdatePercentageBean(bean, "stb", nil)
+	assert.NilError(t, respEntity.Error)
+
+	later := now.Add(61 * time.Minute)
+	assert.Equal(t, ApplyDueRolloutSteps(later), 0)
+	rollout = xcoreef.GetPercentageRollout(rollout.ID)
+	assert.Equal(t, rollout.Status, xcoreef.PERCENTAGE_ROLLOUT_PAUSED)
+	assert.Equal(t, rollout.CurrentStep, 1)
+	assert.Equal(t, rollout.Message, "Percentage bean bean-1 was modified outside the rollout, review it and resume the rollout")
+	distributions := getTestBeanDistributions(t)
+	assert.Equal(t, len(distributions), 2)
+	assert.Equal(t, distributions[0].EndPercentRange, float64(80))
+
+	// resuming takes the manual change as reviewed and continues with the next step
+	r := testutil.NewRequest(http.MethodPost, "/xconfAdminService/percentfilter/percentageBean/bean-1/rollout/resume?applicationType=stb", "")
+	_, err = ResumePercentageRollout(r, "bean-1", "stb")
+	assert.NilError(t, err)
+	assert.Equal(t, ApplyDueRolloutSteps(later), 1)
+	rollout = xcoreef.GetPercentageRollout(rollout.ID)
+	assert.Equal(t, rollout.Status, xcoreef.PERCENTAGE_ROLLOUT_COMPLETED)
+	distributions = getTestBeanDistributions(t)
+	assert.Equal(t, len(distributions), 1)
+	assert.Equal(t, distributions[0].ConfigId, "config-2")
+}
+
+func TestPercentageRolloutIsBeanModified(t *testing.T) {
+	rollout := &xcoreef.PercentageRollout{}
+	entries := []*corefw.ConfigEntry{corefw.NewConfigEntry("config-1", 0, 50)}
+	// rollouts which did not keep the distributions are not checked
+	assert.Assert(t, !rollout.IsBeanModified(entries))
+
+	rollout.BeanDistributions = []*corefw.ConfigEntry{corefw.NewConfigEntry("config-1", 0, 50)}
+	assert.Assert(t, !rollout.IsBeanModified(entries))
+	assert.Assert(t, rollout.IsBeanModified([]*corefw.ConfigEntry{corefw.NewConfigEntry("config-1", 0, 60)}))
+	assert.Assert(t, rollout.IsBeanModified([]*corefw.ConfigEntry{corefw.NewConfigEntry("config-2", 0, 50)}))
+	assert.Assert(t, rollout.IsBeanModified([]*corefw.ConfigEntry{}))
+}
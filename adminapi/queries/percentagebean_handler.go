@@ -34,6 +34,7 @@ import (
 
 	"xconfadmin/adminapi/auth"
 	xhttp "xconfadmin/http"
+	xchange "xconfadmin/shared/change"
 	xwhttp "xconfwebconfig/http"
 )
 
@@ -56,24 +57,16 @@ func GetPercentageBeanAllHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	util.AddQueryParamsToContextMap(r, contextMap)
 
-	var result interface{}
-
-	result, err = GetAllPercentageBeansFromDB(applicationType, true, false)
-
+	beans, err := GetAllPercentageBeansFromDB(applicationType, true, false)
 	if err != nil {
 		xhttp.WriteAdminErrorResponse(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	res, err := xhttp.ReturnJsonResponse(result, r)
-	if err != nil {
-		xhttp.AdminError(w, err)
-		return
-	}
 	_, ok := contextMap[xcommon.EXPORT]
 	if ok {
 		percentageBeansToExport := make(map[string]interface{})
-		percentageBeansToExport["percentageBeans"] = result
+		percentageBeansToExport["percentageBeans"] = beans
 		res, err := xhttp.ReturnJsonResponse(percentageBeansToExport, r)
 		if err != nil {
 			xhttp.AdminError(w, err)
@@ -82,6 +75,11 @@ func GetPercentageBeanAllHandler(w http.ResponseWriter, r *http.Request) {
 		headers := xhttp.CreateContentDispositionHeader(xcommon.ExportFileNames_ENV_MODEL_PERCENTAGE_BEANS + "_" + applicationType)
 		xwhttp.WriteXconfResponseWithHeaders(w, headers, http.StatusOK, res)
 	} else {
+		res, err := xhttp.ReturnJsonResponse(NewPercentageBeanResponses(beans), r)
+		if err != nil {
+			xhttp.AdminError(w, err)
+			return
+		}
 		xwhttp.WriteResponseBytes(w, res, http.StatusOK, xhttp.ContextTypeHeader(r))
 	}
 }
@@ -108,11 +106,6 @@ func GetPercentageBeanByIdHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.WriteAdminErrorResponse(w, http.StatusNotFound, "ApplicationType doesn't match")
 		return
 	}
-	res, err := xhttp.ReturnJsonResponse(bean, r)
-	if err != nil {
-		xhttp.AdminError(w, err)
-		return
-	}
 	queryParams := r.URL.Query()
 	_, ok := queryParams[xcommon.EXPORT]
 	if ok {
@@ -128,6 +121,11 @@ func GetPercentageBeanByIdHandler(w http.ResponseWriter, r *http.Request) {
 		headers := xhttp.CreateContentDispositionHeader(xcommon.ExportFileNames_ENV_MODEL_PERCENTAGE_BEAN + bean.ID + "_" + applicationType)
 		xwhttp.WriteXconfResponseWithHeaders(w, headers, http.StatusOK, exres)
 	} else {
+		res, err := xhttp.ReturnJsonResponse(NewPercentageBeanResponse(bean), r)
+		if err != nil {
+			xhttp.AdminError(w, err)
+			return
+		}
 		xwhttp.WriteResponseBytes(w, res, http.StatusOK, xhttp.ContextTypeHeader(r))
 	}
 }
@@ -210,6 +208,10 @@ func PostPercentageBeanEntitiesHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.AdminError(w, err)
 		return
 	}
+	if err := xchange.ValidateDirectWrite(xchange.PERCENTAGE_BEAN, applicationType); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
 
 	xw, ok := w.(*xwhttp.XResponseWriter)
 	if !ok {
@@ -222,10 +224,11 @@ func PostPercentageBeanEntitiesHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, response)
 		return
 	}
+	dryRun := xhttp.NewDryRun(r)
 	entitiesMap := map[string]xhttp.EntityMessage{}
 	for _, entity := range entities {
 		entity := entity
-		respEntity := CreatePercentageBean(&entity, applicationType)
+		respEntity := CreatePercentageBean(&entity, applicationType, dryRun)
 		if respEntity.Status != http.StatusCreated {
 			entitiesMap[entity.ID] = xhttp.EntityMessage{
 				Status:  xcommon.ENTITY_STATUS_FAILURE,
@@ -238,6 +241,11 @@ func PostPercentageBeanEntitiesHandler(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
+	if dryRun != nil {
+		dryRun.Result = entitiesMap
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	response, err := xhttp.ReturnJsonResponse(entitiesMap, r)
 	if err != nil {
 		xhttp.AdminError(w, err)
@@ -252,6 +260,10 @@ func PutPercentageBeanEntitiesHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.AdminError(w, err)
 		return
 	}
+	if err := xchange.ValidateDirectWrite(xchange.PERCENTAGE_BEAN, applicationType); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
 
 	xw, ok := w.(*xwhttp.XResponseWriter)
 	if !ok {
@@ -264,10 +276,11 @@ func PutPercentageBeanEntitiesHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, response)
 		return
 	}
+	dryRun := xhttp.NewDryRun(r)
 	entitiesMap := map[string]xhttp.EntityMessage{}
 	for _, entity := range entities {
 		entity := entity
-		respEntity := UpdatePercentageBean(&entity, applicationType)
+		respEntity := UpdatePercentageBean(&entity, applicationType, dryRun)
 		if respEntity.Status == http.StatusOK {
 			entitiesMap[entity.ID] = xhttp.EntityMessage{
 				Status:  xcommon.ENTITY_STATUS_SUCCESS,
@@ -280,6 +293,11 @@ func PutPercentageBeanEntitiesHandler(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 	}
+	if dryRun != nil {
+		dryRun.Result = entitiesMap
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	response, err := xhttp.ReturnJsonResponse(entitiesMap, r)
 	if err != nil {
 		xhttp.AdminError(w, err)
@@ -40,14 +40,14 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-func UpdatePercentFilterGlobal(applicationType string, globalPercentage *coreef.GlobalPercentage) *xwhttp.ResponseEntity {
+func UpdatePercentFilterGlobal(applicationType string, globalPercentage *coreef.GlobalPercentage, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
 	globalFwRule := xcoreef.ConvertGlobalPercentageIntoRule(globalPercentage, applicationType)
 	globalFwRule.ID = GetGlobalPercentageIdByApplication(applicationType)
 	ruleDb, err := firmware.GetFirmwareRuleOneDB(globalFwRule.ID)
 	if err == nil || ruleDb != nil {
-		err = updateFirmwareRule(*globalFwRule, applicationType, false)
+		err = updateFirmwareRule(*globalFwRule, applicationType, false, dryRun)
 	} else {
-		err = createFirmwareRule(*globalFwRule, applicationType, false)
+		err = createFirmwareRule(*globalFwRule, applicationType, false, dryRun)
 	}
 	if err != nil {
 		return xwhttp.NewResponseEntity(http.StatusBadRequest, err, nil)
@@ -77,11 +77,17 @@ func UpdatePercentFilterGlobalHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respEntity := UpdatePercentFilterGlobal(applicationType, globalPercentage)
+	dryRun := xhttp.NewDryRun(r)
+	respEntity := UpdatePercentFilterGlobal(applicationType, globalPercentage, dryRun)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	if dryRun != nil {
+		dryRun.Result = respEntity.Data
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 
 	res, err := xhttp.ReturnJsonResponse(respEntity.Data, r)
 	if err != nil {
@@ -26,6 +26,7 @@ import (
 	"strings"
 
 	xshared "xconfadmin/shared"
+	xchange "xconfadmin/shared/change"
 	xutil "xconfadmin/util"
 	"xconfwebconfig/shared"
 
@@ -149,11 +150,34 @@ func CreatePercentageBeanHandler(w http.ResponseWriter, r *http.Request) {
 		percentageBean.ApplicationType = applicationType
 	}
 
-	respEntity := CreatePercentageBean(percentageBean, applicationType)
+	dryRun := xhttp.NewDryRun(r)
+	if xchange.IsApprovalRequired(xchange.PERCENTAGE_BEAN, applicationType) {
+		if util.IsBlank(percentageBean.ID) {
+			percentageBean.ID = uuid.New().String()
+		}
+		if dryRun == nil {
+			writePendingChange(w, r, xchange.PERCENTAGE_BEAN, applicationType, xchange.Create, percentageBean.ID, percentageBean, func(dryRun *xhttp.DryRun) error {
+				return responseEntityError(CreatePercentageBean(percentageBean, applicationType, dryRun))
+			})
+			return
+		}
+		dryRun.ApprovalRequired = true
+	}
+
+	lifecycleWarnings := GetPercentageBeanLifecycleWarnings(percentageBean, applicationType)
+	versionWarnings := GetPercentageBeanVersionWarnings(percentageBean, applicationType)
+	respEntity := CreatePercentageBean(percentageBean, applicationType, dryRun)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	xhttp.AddWarningHeaders(w, lifecycleWarnings)
+	xhttp.AddWarningHeaders(w, versionWarnings)
+	if dryRun != nil {
+		dryRun.Result = respEntity.Data
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 
 	res, err := xhttp.ReturnJsonResponse(respEntity.Data, r)
 	if err != nil {
@@ -184,11 +208,31 @@ func UpdatePercentageBeanHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respEntity := UpdatePercentageBean(percentageBean, applicationType)
+	dryRun := xhttp.NewDryRun(r)
+	if xchange.IsApprovalRequired(xchange.PERCENTAGE_BEAN, applicationType) {
+		if dryRun == nil {
+			writePendingChange(w, r, xchange.PERCENTAGE_BEAN, applicationType, xchange.Update, percentageBean.ID, percentageBean, func(dryRun *xhttp.DryRun) error {
+				return responseEntityError(UpdatePercentageBean(percentageBean, applicationType, dryRun))
+			})
+			return
+		}
+		dryRun.ApprovalRequired = true
+	}
+
+	lifecycleWarnings := GetPercentageBeanLifecycleWarnings(percentageBean, applicationType)
+	versionWarnings := GetPercentageBeanVersionWarnings(percentageBean, applicationType)
+	respEntity := UpdatePercentageBean(percentageBean, applicationType, dryRun)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	xhttp.AddWarningHeaders(w, lifecycleWarnings)
+	xhttp.AddWarningHeaders(w, versionWarnings)
+	if dryRun != nil {
+		dryRun.Result = respEntity.Data
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 
 	res, err := xhttp.ReturnJsonResponse(respEntity.Data, r)
 	if err != nil {
@@ -217,11 +261,26 @@ func DeletePercentageBeanHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respEntity := DeletePercentageBean(id, applicationType)
+	dryRun := xhttp.NewDryRun(r)
+	if xchange.IsApprovalRequired(xchange.PERCENTAGE_BEAN, applicationType) {
+		if dryRun == nil {
+			writePendingChange(w, r, xchange.PERCENTAGE_BEAN, applicationType, xchange.Delete, id, nil, func(dryRun *xhttp.DryRun) error {
+				return responseEntityError(DeletePercentageBean(id, applicationType, dryRun))
+			})
+			return
+		}
+		dryRun.ApprovalRequired = true
+	}
+
+	respEntity := DeletePercentageBean(id, applicationType, dryRun)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	if dryRun != nil {
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	xwhttp.WriteXconfResponse(w, respEntity.Status, nil)
 }
 
@@ -669,11 +728,17 @@ func CreateFirmwareConfigHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respEntity := CreateFirmwareConfig(firmwareConfig, applicationType)
+	dryRun := xhttp.NewDryRun(r)
+	respEntity := CreateFirmwareConfig(firmwareConfig, applicationType, dryRun)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	if dryRun != nil {
+		dryRun.Result = respEntity.Data
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 
 	res, err := xhttp.ReturnJsonResponse(respEntity.Data, r)
 	if err != nil {
@@ -709,11 +774,17 @@ func UpdateFirmwareConfigHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respEntity := UpdateFirmwareConfig(firmwareConfig, applicationType)
+	dryRun := xhttp.NewDryRun(r)
+	respEntity := UpdateFirmwareConfig(firmwareConfig, applicationType, dryRun)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	if dryRun != nil {
+		dryRun.Result = respEntity.Data
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 }
 
 func DeleteFirmwareConfigHandler(w http.ResponseWriter, r *http.Request) {
@@ -734,11 +805,16 @@ func DeleteFirmwareConfigHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respEntity := DeleteFirmwareConfig(id, applicationType)
+	dryRun := xhttp.NewDryRun(r)
+	respEntity := DeleteFirmwareConfig(id, applicationType, dryRun)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
 	}
+	if dryRun != nil {
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	xwhttp.WriteXconfResponse(w, respEntity.Status, nil)
 }
 
@@ -755,7 +831,8 @@ func DeleteFirmwareConfigHandlerASFlavor(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	respEntity := DeleteFirmwareConfig(id, appType)
+	dryRun := xhttp.NewDryRun(r)
+	respEntity := DeleteFirmwareConfig(id, appType, dryRun)
 	status := respEntity.Status
 	err = respEntity.Error
 
@@ -763,6 +840,10 @@ func DeleteFirmwareConfigHandlerASFlavor(w http.ResponseWriter, r *http.Request)
 		xhttp.WriteAdminErrorResponse(w, status, err.Error())
 		return
 	}
+	if dryRun != nil {
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	xwhttp.WriteXconfResponse(w, status, nil)
 }
 
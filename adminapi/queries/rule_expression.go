@@ -0,0 +1,394 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package queries
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	xcommon "xconfadmin/common"
+
+	re "xconfwebconfig/rulesengine"
+	util "xconfwebconfig/util"
+)
+
+// A rule expression is the textual form of a rule, for example
+//
+//	model IN ["X1","X2"] AND env IS "PROD" AND NOT estbMac IN_LIST "beta-macs"
+//
+// Relations are kept in the order they are written and evaluated the same way as the compound parts
+// of the json form, parentheses group compound parts. A free arg which is not of STRING type is written
+// as name:TYPE, string fixed args are quoted, numbers are not and the fixed arg of EXISTS is left out.
+const (
+	EXPRESSION_NOT = "NOT"
+)
+
+const (
+	expressionTokenWord = iota
+	expressionTokenString
+	expressionTokenPunct
+)
+
+type expressionToken struct {
+	kind int
+	text string
+	pos  int
+}
+
+type ruleExpressionParser struct {
+	expression string
+	tokens     []expressionToken
+	pos        int
+}
+
+// FormatRuleExpression returns the rule expression of the rule, ParseRuleExpression returns the rule back
+func FormatRuleExpression(rule *re.Rule) string {
+	if rule == nil {
+		return ""
+	}
+	if len(rule.CompoundParts) > 1 && !rule.Negated {
+		return formatCompoundParts(rule.CompoundParts)
+	}
+	return formatRuleTerm(rule)
+}
+
+func formatCompoundParts(compoundParts []re.Rule) string {
+	terms := make([]string, 0, len(compoundParts))
+	for i := range compoundParts {
+		term := formatRuleTerm(&compoundParts[i])
+		if i > 0 {
+			relation := strings.ToUpper(compoundParts[i].Relation)
+			if relation == "" {
+				relation = re.RelationAnd
+			}
+			term = relation + " " + term
+		}
+		terms = append(terms, term)
+	}
+	return strings.Join(terms, " ")
+}
+
+func formatRuleTerm(rule *re.Rule) string {
+	term := ""
+	if len(rule.CompoundParts) > 0 {
+		term = "(" + formatCompoundParts(rule.CompoundParts) + ")"
+	} else if rule.Condition != nil {
+		term = formatCondition(rule.Condition)
+	}
+	if rule.Negated && term != "" {
+		return EXPRESSION_NOT + " " + term
+	}
+	return term
+}
+
+func formatCondition(condition *re.Condition) string {
+	var sb strings.Builder
+	if freeArg := condition.FreeArg; freeArg != nil {
+		sb.WriteString(formatFreeArgName(freeArg.Name))
+		if freeArg.Type != "" && freeArg.Type != re.StandardFreeArgTypeString {
+			sb.WriteString(":" + freeArg.Type)
+		}
+	} else {
+		sb.WriteString(`""`)
+	}
+	sb.WriteString(" " + condition.Operation)
+	if fixedArg := formatFixedArg(condition.Operation, condition.FixedArg); fixedArg != "" {
+		sb.WriteString(" " + fixedArg)
+	}
+	return sb.String()
+}
+
+func formatFreeArgName(name string) string {
+	if name == "" || isExpressionKeyword(name) {
+		return quoteExpressionString(name)
+	}
+	for _, r := range name {
+		if !isExpressionWordRune(r) {
+			return quoteExpressionString(name)
+		}
+	}
+	return name
+}
+
+func formatFixedArg(operation string, fixedArg *re.FixedArg) string {
+	if fixedArg == nil {
+		return ""
+	}
+	switch {
+	case fixedArg.IsCollectionValue() || (fixedArg.Collection.Value != nil && operation == re.StandardOperationIn):
+		values := make([]string, 0, len(fixedArg.Collection.Value))
+		for _, value := range fixedArg.Collection.Value {
+			values = append(values, quoteExpressionString(value))
+		}
+		return "[" + strings.Join(values, ",") + "]"
+	case fixedArg.IsStringValue():
+		return quoteExpressionString(fixedArg.Bean.Value.JLString)
+	case fixedArg.Bean.Value.JLDouble != 0 || isNumericOperation(operation):
+		return strconv.FormatFloat(fixedArg.Bean.Value.JLDouble, 'f', -1, 64)
+	case operation == re.StandardOperationExists:
+		return ""
+	}
+	// an empty bean is an empty string or a 0, which the rules engine can't tell apart
+	return `""`
+}
+
+func isNumericOperation(operation string) bool {
+	switch operation {
+	case re.StandardOperationPercent, re.StandardOperationGt, re.StandardOperationGte, re.StandardOperationLt, re.StandardOperationLte:
+		return true
+	}
+	return false
+}
+
+func quoteExpressionString(value string) string {
+	quoted, _ := util.JSONMarshal(value)
+	return strings.TrimSuffix(string(quoted), "\n")
+}
+
+func isExpressionKeyword(word string) bool {
+	return word == re.RelationAnd || word == re.RelationOr || word == EXPRESSION_NOT
+}
+
+func isExpressionWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.'
+}
+
+// ParseRuleExpression returns the rule of the rule expression, a single condition or group is returned as is,
+// several ones as the compound parts of the rule
+func ParseRuleExpression(expression string) (*re.Rule, error) {
+	tokens, err := tokenizeRuleExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, xcommon.NewXconfError(http.StatusBadRequest, "Rule expression is empty")
+	}
+	p := &ruleExpressionParser{expression: expression, tokens: tokens}
+	terms, err := p.parseTerms()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, p.errorf("unexpected %s", p.tokens[p.pos].text)
+	}
+	if len(terms) == 1 {
+		return &terms[0], nil
+	}
+	return &re.Rule{CompoundParts: terms}, nil
+}
+
+// ValidateRuleExpression parses the rule expression and validates the rule against the allowed operations
+func ValidateRuleExpression(expression string, fp func() []string) (*re.Rule, error) {
+	rule, err := ParseRuleExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateRuleStructure(rule); err != nil {
+		return nil, err
+	}
+	if err := RunGlobalValidation(*rule, fp); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func tokenizeRuleExpression(expression string) ([]expressionToken, error) {
+	tokens := []expressionToken{}
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case strings.ContainsRune("()[],:", r):
+			tokens = append(tokens, expressionToken{kind: expressionTokenPunct, text: string(r), pos: i})
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				if runes[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(runes) {
+				return nil, xcommon.NewXconfError(http.StatusBadRequest, fmt.Sprintf("Invalid rule expression at position %d: unterminated string", i+1))
+			}
+			var value string
+			if err := json.Unmarshal([]byte(string(runes[i:end+1])), &value); err != nil {
+				return nil, xcommon.NewXconfError(http.StatusBadRequest, fmt.Sprintf("Invalid rule expression at position %d: invalid string", i+1))
+			}
+			tokens = append(tokens, expressionToken{kind: expressionTokenString, text: value, pos: i})
+			i = end + 1
+		case isExpressionWordRune(r):
+			end := i
+			for end < len(runes) && isExpressionWordRune(runes[end]) {
+				end++
+			}
+			tokens = append(tokens, expressionToken{kind: expressionTokenWord, text: string(runes[i:end]), pos: i})
+			i = end
+		default:
+			return nil, xcommon.NewXconfError(http.StatusBadRequest, fmt.Sprintf("Invalid rule expression at position %d: unexpected %q", i+1, r))
+		}
+	}
+	return tokens, nil
+}
+
+func (p *ruleExpressionParser) parseTerms() ([]re.Rule, error) {
+	terms := []re.Rule{}
+	relation := ""
+	for {
+		term, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		term.Relation = relation
+		terms = append(terms, *term)
+
+		if p.acceptWord(re.RelationAnd) {
+			relation = re.RelationAnd
+		} else if p.acceptWord(re.RelationOr) {
+			relation = re.RelationOr
+		} else {
+			return terms, nil
+		}
+	}
+}
+
+func (p *ruleExpressionParser) parseTerm() (*re.Rule, error) {
+	negated := p.acceptWord(EXPRESSION_NOT)
+	rule := &re.Rule{}
+	if p.acceptPunct("(") {
+		compoundParts, err := p.parseTerms()
+		if err != nil {
+			return nil, err
+		}
+		if !p.acceptPunct(")") {
+			return nil, p.errorf("expected )")
+		}
+		rule.CompoundParts = compoundParts
+	} else {
+		condition, err := p.parseCondition()
+		if err != nil {
+			return nil, err
+		}
+		rule.Condition = condition
+	}
+	rule.Negated = negated
+	return rule, nil
+}
+
+func (p *ruleExpressionParser) parseCondition() (*re.Condition, error) {
+	token, ok := p.peek()
+	if !ok || token.kind == expressionTokenPunct || (token.kind == expressionTokenWord && isExpressionKeyword(token.text)) {
+		return nil, p.errorf("expected free arg")
+	}
+	p.pos++
+	freeArg := re.NewFreeArg(re.StandardFreeArgTypeString, token.text)
+	if p.acceptPunct(":") {
+		freeArgType, ok := p.peek()
+		if !ok || freeArgType.kind != expressionTokenWord {
+			return nil, p.errorf("expected free arg type")
+		}
+		p.pos++
+		freeArg.Type = strings.ToUpper(freeArgType.text)
+	}
+
+	operation, ok := p.peek()
+	if !ok || operation.kind != expressionTokenWord || isExpressionKeyword(operation.text) {
+		return nil, p.errorf("expected operation of %s", freeArg.Name)
+	}
+	p.pos++
+
+	fixedArg, err := p.parseFixedArg()
+	if err != nil {
+		return nil, err
+	}
+	return re.NewCondition(freeArg, strings.ToUpper(operation.text), fixedArg), nil
+}
+
+func (p *ruleExpressionParser) parseFixedArg() (*re.FixedArg, error) {
+	token, ok := p.peek()
+	if !ok {
+		return nil, nil
+	}
+	switch {
+	case token.kind == expressionTokenString:
+		p.pos++
+		return re.NewFixedArg(token.text), nil
+	case token.kind == expressionTokenPunct && token.text == "[":
+		p.pos++
+		values := []string{}
+		for !p.acceptPunct("]") {
+			if len(values) > 0 && !p.acceptPunct(",") {
+				return nil, p.errorf("expected , or ]")
+			}
+			value, ok := p.peek()
+			if !ok || value.kind != expressionTokenString {
+				return nil, p.errorf("expected quoted list item")
+			}
+			p.pos++
+			values = append(values, value.text)
+		}
+		return re.NewFixedArg(values), nil
+	case token.kind == expressionTokenWord && !isExpressionKeyword(token.text):
+		number, err := strconv.ParseFloat(token.text, 64)
+		if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+			return nil, p.errorf("%s is not a number, quote string values", token.text)
+		}
+		p.pos++
+		return re.NewFixedArg(number), nil
+	}
+	return nil, nil
+}
+
+func (p *ruleExpressionParser) peek() (expressionToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return expressionToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *ruleExpressionParser) acceptWord(word string) bool {
+	if token, ok := p.peek(); ok && token.kind == expressionTokenWord && token.text == word {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *ruleExpressionParser) acceptPunct(punct string) bool {
+	if token, ok := p.peek(); ok && token.kind == expressionTokenPunct && token.text == punct {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *ruleExpressionParser) errorf(format string, args ...interface{}) error {
+	position := len([]rune(p.expression)) + 1
+	if token, ok := p.peek(); ok {
+		position = token.pos + 1
+	}
+	return xcommon.NewXconfError(http.StatusBadRequest, fmt.Sprintf("Invalid rule expression at position %d: ", position)+fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,123 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package queries
+
+import (
+	"net/http"
+	"testing"
+
+	xcommon "xconfadmin/common"
+	"xconfadmin/testutil"
+	re "xconfwebconfig/rulesengine"
+
+	"gotest.tools/assert"
+)
+
+func TestRuleExpressionRoundTrip(t *testing.T) {
+	expressions := []string{
+		`model IN ["X1","X2"]`,
+		`estbMac IN_LIST "beta-macs"`,
+		`partnerId EXISTS`,
+		`NOT env IS "PROD"`,
+		`model IS "X1" AND env IS "PROD" OR NOT estbMac IN_LIST "beta-macs"`,
+		`model IS "X1" AND (env IS "PROD" OR NOT (env IS "QA" AND ipAddress IN_LIST "qa-ips"))`,
+		`NOT (model IS "X1" OR model IS "X2")`,
+		`"AND" IS "OR" AND "NOT" IS "x y"`,
+		`"a:b" LIKE ".*\"quoted\".*"`,
+		`eStbMac PERCENT 0`,
+		`uptime:LONG GTE 0 AND uptime:LONG LT 3600.5`,
+		`env IS ""`,
+	}
+	for _, expression := range expressions {
+		rule, err := ParseRuleExpression(expression)
+		assert.NilError(t, err, expression)
+		assert.Equal(t, FormatRuleExpression(rule), expression)
+
+		reparsed, err := ParseRuleExpression(FormatRuleExpression(rule))
+		assert.NilError(t, err, expression)
+		assert.DeepEqual(t, reparsed, rule)
+	}
+}
+
+func TestRuleExpressionParsesRule(t *testing.T) {
+	rule, err := ParseRuleExpression(`model IN ["X1","X2"] AND NOT (estbMac IN_LIST "beta-macs" OR partnerId EXISTS)`)
+	assert.NilError(t, err)
+	assert.Equal(t, len(rule.CompoundParts), 2)
+
+	in := rule.CompoundParts[0]
+	assert.Equal(t, in.Condition.FreeArg.Name, "model")
+	assert.Equal(t, in.Condition.Operation, re.StandardOperationIn)
+	assert.DeepEqual(t, in.Condition.FixedArg.Collection.Value, []string{"X1", "X2"})
+
+	group := rule.CompoundParts[1]
+	assert.Equal(t, group.Relation, re.RelationAnd)
+	assert.Assert(t, group.Negated)
+	assert.Equal(t, len(group.CompoundParts), 2)
+	assert.Equal(t, group.CompoundParts[0].Condition.Operation, re.StandardOperationInList)
+	assert.Equal(t, group.CompoundParts[0].Condition.FixedArg.Bean.Value.JLString, "beta-macs")
+	assert.Equal(t, group.CompoundParts[1].Relation, re.RelationOr)
+	assert.Equal(t, group.CompoundParts[1].Condition.Operation, re.StandardOperationExists)
+	assert.Assert(t, group.CompoundParts[1].Condition.FixedArg == nil)
+}
+
+func TestFormatRuleExpressionKeepsZeroFixedArgs(t *testing.T) {
+	percent := re.NewCondition(re.NewFreeArg(re.StandardFreeArgTypeString, "eStbMac"), re.StandardOperationPercent, re.NewFixedArg(float64(0)))
+	assert.Equal(t, FormatRuleExpression(&re.Rule{Condition: percent}), "eStbMac PERCENT 0")
+
+	gte := re.NewCondition(re.NewFreeArg(re.StandardFreeArgTypeLong, "uptime"), re.StandardOperationGte, re.NewFixedArg(float64(0)))
+	assert.Equal(t, FormatRuleExpression(&re.Rule{Condition: gte}), "uptime:LONG GTE 0")
+
+	is := re.NewCondition(re.NewFreeArg(re.StandardFreeArgTypeString, "env"), re.StandardOperationIs, re.NewFixedArg(""))
+	assert.Equal(t, FormatRuleExpression(&re.Rule{Condition: is}), `env IS ""`)
+}
+
+func TestRuleExpressionSyntaxErrors(t *testing.T) {
+	expressions := []string{
+		``,
+		`model IS X1`,
+		`model IS "X1" AND`,
+		`(model IS "X1"`,
+		`model IN ["X1" "X2"]`,
+		`model IS "X1`,
+		`AND IS "X1"`,
+	}
+	for _, expression := range expressions {
+		_, err := ParseRuleExpression(expression)
+		assert.Assert(t, err != nil, expression)
+		assert.Equal(t, xcommon.GetXconfErrorStatusCode(err), http.StatusBadRequest, expression)
+	}
+}
+
+func TestValidateRuleExpressionRejectsDisallowedOperations(t *testing.T) {
+	testutil.SetupTestDB()
+
+	_, err := ValidateRuleExpression(`model IN ["X1","X2"]`, GetAllowedOperations)
+	assert.Assert(t, err != nil)
+	assert.Equal(t, xcommon.GetXconfErrorStatusCode(err), http.StatusBadRequest)
+	assert.ErrorContains(t, err, "Operation is not valid: IN")
+
+	_, err = ValidateRuleExpression(`model IS "X1" AND NOT env IS "PROD" OR uptime:LONG GTE 10`, GetAllowedOperations)
+	assert.ErrorContains(t, err, "Operation is not valid: GTE")
+
+	_, err = ValidateRuleExpression(`model MATCHES "X1"`, GetFirmwareRuleAllowedOperations)
+	assert.ErrorContains(t, err, "Operation is not valid: MATCHES")
+
+	rule, err := ValidateRuleExpression(`model IN ["X1","X2"]`, GetFirmwareRuleAllowedOperations)
+	assert.NilError(t, err)
+	assert.Equal(t, rule.Condition.Operation, re.StandardOperationIn)
+}
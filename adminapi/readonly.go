@@ -0,0 +1,58 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package adminapi
+
+import (
+	"net/http"
+	"time"
+
+	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
+
+	"github.com/gorilla/mux"
+)
+
+// routes which stay writable in a freeze window, the app settings end a manual freeze
+var readonlyExemptRoutes = []string{
+	"AppSettings",
+}
+
+// ReadonlyWindowMiddleware rejects every write while a freeze window is active
+func ReadonlyWindowMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := mux.CurrentRoute(r)
+		if route == nil || getAuditOperation(r.Method, route) == "" || isReadonlyExemptRoute(route.GetName()) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if window := xcommon.GetActiveReadonlyWindow(time.Now()); window != nil {
+			xhttp.WriteAdminErrorResponse(w, http.StatusForbidden, "Modification not allowed in read-only mode, blocked by freeze window "+window.String())
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isReadonlyExemptRoute(name string) bool {
+	for _, exempt := range readonlyExemptRoutes {
+		if name == exempt {
+			return true
+		}
+	}
+	return false
+}
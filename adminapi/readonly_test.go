@@ -0,0 +1,98 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package adminapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	xcommon "xconfadmin/common"
+	"xconfadmin/testutil"
+
+	"github.com/gorilla/mux"
+	"gotest.tools/assert"
+)
+
+func newReadonlyTestRouter() *mux.Router {
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	r := mux.NewRouter()
+	r.Use(ReadonlyWindowMiddleware)
+	r.HandleFunc("/xconfAdminService/firmwareconfig", ok).Methods("POST", "GET").Name("Firmware-Configs")
+	r.HandleFunc("/xconfAdminService/firmwareconfig/filtered", ok).Methods("POST").Name("Firmware-Configs")
+	r.HandleFunc("/xconfAdminService/ownership/groups", ok).Methods("POST").Name("Ownership-Groups")
+	r.HandleFunc("/xconfAdminService/serviceAccount", ok).Methods("POST").Name("ServiceAccounts")
+	r.HandleFunc("/xconfAdminService/change/approvalSettings", ok).Methods("PUT").Name("ApprovalSettings")
+	r.HandleFunc("/xconfAdminService/change/approve/{changeId}", ok).Methods("GET").Name("Telemetry1-Changes")
+	r.HandleFunc("/xconfAdminService/appsettings", ok).Methods("PUT").Name("AppSettings")
+	return r
+}
+
+func serveReadonlyTest(router *mux.Router, method string, url string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(method, url, nil))
+	return rr
+}
+
+func TestReadonlyWindowMiddlewareBlocksAllWrites(t *testing.T) {
+	testutil.SetupTestDB()
+	router := newReadonlyTestRouter()
+	writes := [][]string{
+		{http.MethodPost, "/xconfAdminService/firmwareconfig"},
+		{http.MethodPost, "/xconfAdminService/ownership/groups"},
+		{http.MethodPost, "/xconfAdminService/serviceAccount"},
+		{http.MethodPut, "/xconfAdminService/change/approvalSettings"},
+		{http.MethodGet, "/xconfAdminService/change/approve/1"},
+	}
+	for _, write := range writes {
+		assert.Equal(t, serveReadonlyTest(router, write[0], write[1]).Code, http.StatusOK, write[1])
+	}
+
+	_, err := xcommon.SetAppSetting(xcommon.READONLY_MODE, true)
+	assert.NilError(t, err)
+	for _, write := range writes {
+		rr := serveReadonlyTest(router, write[0], write[1])
+		assert.Equal(t, rr.Code, http.StatusForbidden, write[1])
+		assert.Assert(t, strings.Contains(rr.Body.String(), "blocked by freeze window 'manual'"), rr.Body.String())
+	}
+	// reads and the app settings, which end the freeze, are not blocked
+	assert.Equal(t, serveReadonlyTest(router, http.MethodGet, "/xconfAdminService/firmwareconfig").Code, http.StatusOK)
+	assert.Equal(t, serveReadonlyTest(router, http.MethodPost, "/xconfAdminService/firmwareconfig/filtered").Code, http.StatusOK)
+	assert.Equal(t, serveReadonlyTest(router, http.MethodPut, "/xconfAdminService/appsettings").Code, http.StatusOK)
+}
+
+func TestReadonlyWindowMiddlewareScheduledWindow(t *testing.T) {
+	testutil.SetupTestDB()
+	router := newReadonlyTestRouter()
+	now := time.Now()
+	_, err := xcommon.SetAppSetting(xcommon.READONLY_MODE_STARTTIME, now.Add(-time.Hour).UnixMilli())
+	assert.NilError(t, err)
+	_, err = xcommon.SetAppSetting(xcommon.READONLY_MODE_ENDTIME, now.Add(time.Hour).UnixMilli())
+	assert.NilError(t, err)
+	rr := serveReadonlyTest(router, http.MethodPost, "/xconfAdminService/ownership/groups")
+	assert.Equal(t, rr.Code, http.StatusForbidden)
+	assert.Assert(t, strings.Contains(rr.Body.String(), "blocked by freeze window 'scheduled'"), rr.Body.String())
+
+	_, err = xcommon.SetAppSetting(xcommon.READONLY_MODE_ENDTIME, now.Add(-time.Minute).UnixMilli())
+	assert.NilError(t, err)
+	assert.Equal(t, serveReadonlyTest(router, http.MethodPost, "/xconfAdminService/ownership/groups").Code, http.StatusOK)
+}
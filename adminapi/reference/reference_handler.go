@@ -0,0 +1,83 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package reference
+
+import (
+	"net/http"
+
+	"xconfadmin/adminapi/auth"
+	xhttp "xconfadmin/http"
+	xshared "xconfadmin/shared"
+	xwcommon "xconfwebconfig/common"
+	xwhttp "xconfwebconfig/http"
+
+	"github.com/gorilla/mux"
+)
+
+const ENTITY_TYPE = "entityType"
+
+// permission entities needed to read the references of an entity type
+var referencePermissions = map[string]string{
+	FIRMWARE_CONFIG:        auth.FIRMWARE_ENTITY,
+	FIRMWARE_RULE:          auth.FIRMWARE_ENTITY,
+	PERCENTAGE_BEAN:        auth.FIRMWARE_ENTITY,
+	ACTIVATION_VERSION:     auth.FIRMWARE_ENTITY,
+	FIRMWARE_RULE_TEMPLATE: auth.FIRMWARE_ENTITY,
+	FEATURE_RULE:           auth.FIRMWARE_ENTITY,
+	FEATURE:                auth.DCM_ENTITY,
+	DCM_RULE:               auth.DCM_ENTITY,
+	SETTING_RULE:           auth.DCM_ENTITY,
+	TELEMETRY_PROFILE:      auth.TELEMETRY_ENTITY,
+	TELEMETRY_TWO_PROFILE:  auth.TELEMETRY_ENTITY,
+	TELEMETRY_RULE:         auth.TELEMETRY_ENTITY,
+	TELEMETRY_TWO_RULE:     auth.TELEMETRY_ENTITY,
+}
+
+// GetEntityReferencesHandler returns the inbound and outbound references of the entity within the application type
+func GetEntityReferencesHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	entityType, _ := normalizeEntityType(vars[ENTITY_TYPE])
+	permission, ok := referencePermissions[entityType]
+	if !ok {
+		permission = auth.COMMON_ENTITY
+	}
+	applicationType, err := auth.CanRead(r, permission)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	if applicationType == "" {
+		// the shared entities list the references of the application type the user works in
+		applicationType = r.URL.Query().Get(xwcommon.APPLICATION_TYPE)
+		if applicationType == "" {
+			applicationType = xshared.GetApplicationFromCookies(r)
+		}
+	}
+
+	references, err := GetEntityReferences(vars[ENTITY_TYPE], vars[xwcommon.ID], applicationType)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	res, err := xhttp.ReturnJsonResponse(references, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
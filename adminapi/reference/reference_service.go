@@ -0,0 +1,500 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package reference
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	xcommon "xconfadmin/common"
+	xshared "xconfadmin/shared"
+	ds "xconfwebconfig/db"
+	re "xconfwebconfig/rulesengine"
+	"xconfwebconfig/shared"
+	coreef "xconfwebconfig/shared/estbfirmware"
+	corefw "xconfwebconfig/shared/firmware"
+	"xconfwebconfig/shared/logupload"
+	"xconfwebconfig/shared/rfc"
+	"xconfwebconfig/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// entity types of the references, they are also the {entityType} of the endpoint
+const (
+	MODEL                  = "model"
+	ENVIRONMENT            = "environment"
+	FIRMWARE_CONFIG        = "firmwareConfig"
+	NAMESPACED_LIST        = "namespacedList"
+	FEATURE                = "feature"
+	TELEMETRY_PROFILE      = "telemetryProfile"
+	TELEMETRY_TWO_PROFILE  = "telemetryTwoProfile"
+	FIRMWARE_RULE          = "firmwareRule"
+	PERCENTAGE_BEAN        = "percentageBean"
+	ACTIVATION_VERSION     = "activationVersion"
+	FIRMWARE_RULE_TEMPLATE = "firmwareRuleTemplate"
+	DCM_RULE               = "dcmRule"
+	TELEMETRY_RULE         = "telemetryRule"
+	TELEMETRY_TWO_RULE     = "telemetryTwoRule"
+	FEATURE_RULE           = "featureRule"
+	SETTING_RULE           = "settingRule"
+)
+
+// tables of the rules whose conditions reference models, environments and namespaced lists
+var ruleTables = []string{
+	ds.TABLE_FIRMWARE_RULE,
+	ds.TABLE_FIRMWARE_RULE_TEMPLATE,
+	ds.TABLE_DCM_RULE,
+	ds.TABLE_TELEMETRY_RULES,
+	ds.TABLE_TELEMETRY_TWO_RULES,
+	ds.TABLE_FEATURE_CONTROL_RULE,
+	ds.TABLE_SETTING_RULES,
+}
+
+// tables of the entities which may reference an entity of the type, the entity types missing here are not referenced
+var referencedByTables = map[string][]string{
+	MODEL:                 append([]string{ds.TABLE_FIRMWARE_CONFIG}, ruleTables...),
+	ENVIRONMENT:           ruleTables,
+	NAMESPACED_LIST:       append([]string{ds.TABLE_XCONF_FEATURE}, ruleTables...),
+	FIRMWARE_CONFIG:       {ds.TABLE_FIRMWARE_RULE},
+	FEATURE:               {ds.TABLE_FEATURE_CONTROL_RULE},
+	TELEMETRY_PROFILE:     {ds.TABLE_TELEMETRY_RULES},
+	TELEMETRY_TWO_PROFILE: {ds.TABLE_TELEMETRY_TWO_RULES},
+}
+
+// entity types which are not scoped to an application type
+var sharedEntityTypes = []string{MODEL, ENVIRONMENT, NAMESPACED_LIST, FIRMWARE_RULE_TEMPLATE}
+
+// field of the rules holding a reference in a condition
+const RULE_FIELD = "rule"
+
+var EntityTypes = []string{
+	MODEL,
+	ENVIRONMENT,
+	FIRMWARE_CONFIG,
+	NAMESPACED_LIST,
+	FEATURE,
+	TELEMETRY_PROFILE,
+	TELEMETRY_TWO_PROFILE,
+	FIRMWARE_RULE,
+	PERCENTAGE_BEAN,
+	ACTIVATION_VERSION,
+	FIRMWARE_RULE_TEMPLATE,
+	DCM_RULE,
+	TELEMETRY_RULE,
+	TELEMETRY_TWO_RULE,
+	FEATURE_RULE,
+	SETTING_RULE,
+}
+
+// tables of the entities which reference other entities
+var referencingTables = map[string]string{
+	ds.TABLE_FIRMWARE_RULE:          FIRMWARE_RULE,
+	ds.TABLE_FIRMWARE_RULE_TEMPLATE: FIRMWARE_RULE_TEMPLATE,
+	ds.TABLE_DCM_RULE:               DCM_RULE,
+	ds.TABLE_TELEMETRY_RULES:        TELEMETRY_RULE,
+	ds.TABLE_TELEMETRY_TWO_RULES:    TELEMETRY_TWO_RULE,
+	ds.TABLE_FEATURE_CONTROL_RULE:   FEATURE_RULE,
+	ds.TABLE_SETTING_RULES:          SETTING_RULE,
+	ds.TABLE_FIRMWARE_CONFIG:        FIRMWARE_CONFIG,
+	ds.TABLE_XCONF_FEATURE:          FEATURE,
+}
+
+// EntityReference is one end of a reference, Field is the field of the referencing entity holding it
+// and Condition the rule condition when the field is the rule
+type EntityReference struct {
+	EntityType      string `json:"entityType"`
+	EntityID        string `json:"entityId"`
+	EntityName      string `json:"entityName,omitempty"`
+	ApplicationType string `json:"applicationType,omitempty"`
+	Field           string `json:"field"`
+	Condition       string `json:"condition,omitempty"`
+}
+
+// EntityReferences lists the entities referencing the entity and the entities it references
+type EntityReferences struct {
+	EntityType      string             `json:"entityType"`
+	EntityID        string             `json:"entityId"`
+	EntityName      string             `json:"entityName,omitempty"`
+	ApplicationType string             `json:"applicationType,omitempty"`
+	Inbound         []*EntityReference `json:"inbound"`
+	Outbound        []*EntityReference `json:"outbound"`
+}
+
+// referenceNode is a referencing entity with the references it holds
+type referenceNode struct {
+	tableName       string
+	entityType      string
+	id              string
+	name            string
+	applicationType string
+	outbound        []*EntityReference
+}
+
+// referenceGraph resolves the firmware versions of the firmware rules to configs of their application type
+type referenceGraph struct {
+	nodes            []*referenceNode
+	configsByVersion map[string][]*coreef.FirmwareConfig
+}
+
+// GetEntityReferences returns the inbound and outbound references of the entity within the application type,
+// an entity of another application type is not found and the references to or from one are left out
+func GetEntityReferences(entityType string, id string, applicationType string) (*EntityReferences, error) {
+	entityType, ok := normalizeEntityType(entityType)
+	if !ok {
+		return nil, xcommon.NewXconfError(http.StatusBadRequest, fmt.Sprintf("EntityType must be one of %s", strings.Join(EntityTypes, ", ")))
+	}
+	graph, err := loadReferenceGraph(getReferenceTables(entityType))
+	if err != nil {
+		return nil, err
+	}
+	result, err := graph.getEntity(entityType, id)
+	if err != nil {
+		return nil, err
+	}
+	if !isInApplicationType(result.EntityType, result.ApplicationType, applicationType) {
+		return nil, xcommon.NewXconfError(http.StatusNotFound, fmt.Sprintf("Entity with id: %s does not exist", id))
+	}
+
+	for _, node := range graph.nodes {
+		if node.tableName == getTableName(result.EntityType) && node.id == result.EntityID {
+			for _, reference := range node.outbound {
+				if targetType, ok := getApplicationType(reference.EntityType, reference.EntityID); !ok || isInApplicationType(reference.EntityType, targetType, applicationType) {
+					result.Outbound = append(result.Outbound, reference)
+				}
+			}
+		}
+		if !isInApplicationType(node.entityType, node.applicationType, applicationType) {
+			continue
+		}
+		for _, reference := range node.outbound {
+			if reference.EntityType == result.EntityType && strings.EqualFold(reference.EntityID, result.EntityID) {
+				result.Inbound = append(result.Inbound, &EntityReference{
+					EntityType:      node.entityType,
+					EntityID:        node.id,
+					EntityName:      node.name,
+					ApplicationType: node.applicationType,
+					Field:           reference.Field,
+					Condition:       reference.Condition,
+				})
+			}
+		}
+	}
+	sortReferences(result.Inbound)
+	sortReferences(result.Outbound)
+	return result, nil
+}
+
+// isInApplicationType returns true if the entity is shared or belongs to the application type
+func isInApplicationType(entityType string, entityApplicationType string, applicationType string) bool {
+	return util.Contains(sharedEntityTypes, entityType) || xshared.ApplicationTypeEquals(entityApplicationType, applicationType)
+}
+
+// getApplicationType returns the application type of a referenced entity, false if it does not exist
+func getApplicationType(entityType string, id string) (string, bool) {
+	switch entityType {
+	case FIRMWARE_CONFIG:
+		if config, err := coreef.GetFirmwareConfigOneDB(id); err == nil && config != nil {
+			return config.ApplicationType, true
+		}
+	case FEATURE:
+		if feature := rfc.GetOneFeature(id); feature != nil {
+			return feature.ApplicationType, true
+		}
+	case TELEMETRY_PROFILE:
+		if profile := logupload.GetOnePermanentTelemetryProfile(id); profile != nil {
+			return profile.ApplicationType, true
+		}
+	case TELEMETRY_TWO_PROFILE:
+		if profile := logupload.GetOneTelemetryTwoProfile(id); profile != nil {
+			return profile.ApplicationType, true
+		}
+	}
+	return "", false
+}
+
+// getReferenceTables returns the tables holding the entity and the entities which may reference it
+func getReferenceTables(entityType string) []string {
+	tableNames := append([]string{}, referencedByTables[entityType]...)
+	if tableName := getTableName(entityType); tableName != "" && !util.Contains(tableNames, tableName) {
+		tableNames = append(tableNames, tableName)
+	}
+	sort.Strings(tableNames)
+	return tableNames
+}
+
+func normalizeEntityType(entityType string) (string, bool) {
+	for _, t := range EntityTypes {
+		if strings.EqualFold(t, entityType) {
+			return t, true
+		}
+	}
+	return "", false
+}
+
+// getTableName returns the table of the referencing entities of the type
+func getTableName(entityType string) string {
+	switch entityType {
+	case PERCENTAGE_BEAN, ACTIVATION_VERSION:
+		return ds.TABLE_FIRMWARE_RULE
+	}
+	for tableName, t := range referencingTables {
+		if t == entityType {
+			return tableName
+		}
+	}
+	return ""
+}
+
+func sortReferences(references []*EntityReference) {
+	sort.SliceStable(references, func(i, j int) bool {
+		if references[i].EntityType != references[j].EntityType {
+			return references[i].EntityType < references[j].EntityType
+		}
+		if references[i].EntityName != references[j].EntityName {
+			return strings.ToLower(references[i].EntityName) < strings.ToLower(references[j].EntityName)
+		}
+		if references[i].EntityID != references[j].EntityID {
+			return references[i].EntityID < references[j].EntityID
+		}
+		return references[i].Field < references[j].Field
+	})
+}
+
+// getEntity returns the references of the entity without its inbound and outbound references
+func (g *referenceGraph) getEntity(entityType string, id string) (*EntityReferences, error) {
+	result := &EntityReferences{
+		EntityType: entityType,
+		EntityID:   id,
+		Inbound:    []*EntityReference{},
+		Outbound:   []*EntityReference{},
+	}
+	notFound := xcommon.NewXconfError(http.StatusNotFound, fmt.Sprintf("Entity with id: %s does not exist", id))
+	switch entityType {
+	case MODEL:
+		model := shared.GetOneModel(strings.ToUpper(id))
+		if model == nil {
+			return nil, notFound
+		}
+		result.EntityID = model.ID
+		result.EntityName = model.ID
+	case ENVIRONMENT:
+		environment := shared.GetOneEnvironment(strings.ToUpper(id))
+		if environment == nil {
+			return nil, notFound
+		}
+		result.EntityID = environment.ID
+		result.EntityName = environment.ID
+	case NAMESPACED_LIST:
+		namespacedList, err := shared.GetGenericNamedListOneDB(id)
+		if err != nil || namespacedList == nil {
+			return nil, notFound
+		}
+		result.EntityName = namespacedList.ID
+	case TELEMETRY_PROFILE:
+		profile := logupload.GetOnePermanentTelemetryProfile(id)
+		if profile == nil {
+			return nil, notFound
+		}
+		result.EntityName = profile.Name
+		result.ApplicationType = profile.ApplicationType
+	case TELEMETRY_TWO_PROFILE:
+		profile := logupload.GetOneTelemetryTwoProfile(id)
+		if profile == nil {
+			return nil, notFound
+		}
+		result.EntityName = profile.Name
+		result.ApplicationType = profile.ApplicationType
+	default:
+		node := g.getNode(getTableName(entityType), id)
+		if node == nil {
+			return nil, notFound
+		}
+		result.EntityType = node.entityType
+		result.EntityName = node.name
+		result.ApplicationType = node.applicationType
+	}
+	return result, nil
+}
+
+func (g *referenceGraph) getNode(tableName string, id string) *referenceNode {
+	for _, node := range g.nodes {
+		if node.tableName == tableName && node.id == id {
+			return node
+		}
+	}
+	return nil
+}
+
+// loadReferenceGraph reads the entities of the tables with their outbound references
+func loadReferenceGraph(tableNames []string) (*referenceGraph, error) {
+	graph := &referenceGraph{configsByVersion: map[string][]*coreef.FirmwareConfig{}}
+	if util.Contains(tableNames, ds.TABLE_FIRMWARE_RULE) {
+		configs, err := coreef.GetFirmwareConfigAsListDB()
+		if err != nil && err.Error() != xcommon.NotFound.Error() {
+			return nil, xcommon.NewXconfError(http.StatusInternalServerError, err.Error())
+		}
+		for _, config := range configs {
+			graph.configsByVersion[config.FirmwareVersion] = append(graph.configsByVersion[config.FirmwareVersion], config)
+		}
+	}
+
+	for _, tableName := range tableNames {
+		list, err := ds.GetCachedSimpleDao().GetAllAsList(tableName, 0)
+		if err != nil {
+			log.Warnf("no %s entities to check for references: %s", tableName, err.Error())
+			continue
+		}
+		for _, v := range list {
+			if node := graph.newReferenceNode(tableName, v); node != nil {
+				graph.nodes = append(graph.nodes, node)
+			}
+		}
+	}
+	return graph, nil
+}
+
+func (g *referenceGraph) newReferenceNode(tableName string, v interface{}) *referenceNode {
+	node := &referenceNode{tableName: tableName, entityType: referencingTables[tableName], outbound: []*EntityReference{}}
+	switch entity := v.(type) {
+	case *corefw.FirmwareRule:
+		node.id, node.name, node.applicationType = entity.ID, entity.Name, entity.ApplicationType
+		switch entity.Type {
+		case corefw.ENV_MODEL_RULE:
+			node.entityType = PERCENTAGE_BEAN
+		case coreef.ACTIVATION_VERSION:
+			node.entityType = ACTIVATION_VERSION
+		}
+		node.addConditionReferences(&entity.Rule)
+		g.addFirmwareActionReferences(node, entity)
+	case *corefw.FirmwareRuleTemplate:
+		node.id, node.name = entity.ID, entity.ID
+		node.addConditionReferences(&entity.Rule)
+	case *logupload.DCMGenericRule:
+		node.id, node.name, node.applicationType = entity.ID, entity.Name, entity.ApplicationType
+		node.addConditionReferences(&entity.Rule)
+	case *logupload.TelemetryRule:
+		node.id, node.name, node.applicationType = entity.ID, entity.Name, entity.ApplicationType
+		node.addConditionReferences(&entity.Rule)
+		if entity.BoundTelemetryID != "" {
+			node.addReference(TELEMETRY_PROFILE, entity.BoundTelemetryID, "boundTelemetryId", "")
+		}
+	case *logupload.TelemetryTwoRule:
+		node.id, node.name, node.applicationType = entity.ID, entity.Name, entity.ApplicationType
+		node.addConditionReferences(&entity.Rule)
+		for _, profileId := range entity.BoundTelemetryIDs {
+			node.addReference(TELEMETRY_TWO_PROFILE, profileId, "boundTelemetryIds", "")
+		}
+	case *rfc.FeatureRule:
+		node.id, node.name, node.applicationType = entity.Id, entity.Name, entity.ApplicationType
+		node.addConditionReferences(entity.Rule)
+		for _, featureId := range entity.FeatureIds {
+			node.addReference(FEATURE, featureId, "featureIds", "")
+		}
+	case *logupload.SettingRule:
+		node.id, node.name, node.applicationType = entity.ID, entity.Name, entity.ApplicationType
+		node.addConditionReferences(&entity.Rule)
+	case *coreef.FirmwareConfig:
+		node.id, node.name, node.applicationType = entity.ID, entity.Description, entity.ApplicationType
+		for _, modelId := range entity.SupportedModelIds {
+			node.addReference(MODEL, modelId, "supportedModelIds", "")
+		}
+	case *rfc.Feature:
+		node.id, node.name, node.applicationType = entity.ID, entity.Name, entity.ApplicationType
+		if entity.Whitelisted && entity.WhitelistProperty != nil && entity.WhitelistProperty.Value != "" {
+			node.addReference(NAMESPACED_LIST, entity.WhitelistProperty.Value, "whitelistProperty.value", "")
+		}
+	default:
+		log.Warnf("unexpected %T in %s while checking references", v, tableName)
+		return nil
+	}
+	return node
+}
+
+func (n *referenceNode) addReference(entityType string, id string, field string, condition string) {
+	n.outbound = append(n.outbound, &EntityReference{
+		EntityType: entityType,
+		EntityID:   id,
+		EntityName: id,
+		Field:      field,
+		Condition:  condition,
+	})
+}
+
+// addConditionReferences adds the models and environments the conditions match and the lists they look up
+func (n *referenceNode) addConditionReferences(rule *re.Rule) {
+	if rule == nil {
+		return
+	}
+	for _, condition := range re.ToConditions(rule) {
+		if condition == nil || condition.GetFreeArg() == nil || condition.GetFixedArg() == nil {
+			continue
+		}
+		entityType := ""
+		switch {
+		case condition.GetOperation() == re.StandardOperationInList:
+			entityType = NAMESPACED_LIST
+		case strings.EqualFold(condition.GetFreeArg().Name, coreef.RuleFactoryMODEL.GetName()):
+			entityType = MODEL
+		case strings.EqualFold(condition.GetFreeArg().Name, coreef.RuleFactoryENV.GetName()):
+			entityType = ENVIRONMENT
+		default:
+			continue
+		}
+		switch value := condition.GetFixedArg().GetValue().(type) {
+		case string:
+			n.addReference(entityType, value, RULE_FIELD, condition.String())
+		case []string:
+			for _, id := range value {
+				n.addReference(entityType, id, RULE_FIELD, condition.String())
+			}
+		}
+	}
+}
+
+// addFirmwareActionReferences adds the configs the action of the firmware rule assigns,
+// the firmware versions resolve to the configs of the rule's application type
+func (g *referenceGraph) addFirmwareActionReferences(node *referenceNode, rule *corefw.FirmwareRule) {
+	action := rule.ApplicableAction
+	if action == nil {
+		return
+	}
+	if action.ConfigId != "" {
+		node.addReference(FIRMWARE_CONFIG, action.ConfigId, "applicableAction.configId", "")
+	}
+	for _, entry := range action.ConfigEntries {
+		if entry.ConfigId != "" {
+			node.addReference(FIRMWARE_CONFIG, entry.ConfigId, "applicableAction.configEntries", "")
+		}
+	}
+	if action.IntermediateVersion != "" {
+		node.addReference(FIRMWARE_CONFIG, action.IntermediateVersion, "applicableAction.intermediateVersion", "")
+	}
+	if action.Whitelist != "" {
+		node.addReference(NAMESPACED_LIST, action.Whitelist, "applicableAction.whitelist", "")
+	}
+	for _, version := range action.GetFirmwareVersions() {
+		for _, config := range g.configsByVersion[version] {
+			if xshared.ApplicationTypeEquals(config.ApplicationType, rule.ApplicationType) {
+				node.addReference(FIRMWARE_CONFIG, config.ID, "applicableAction.firmwareVersions", "")
+			}
+		}
+	}
+}
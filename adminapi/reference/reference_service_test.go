@@ -0,0 +1,69 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package reference
+
+import (
+	"net/http"
+	"testing"
+
+	xcommon "xconfadmin/common"
+	"xconfadmin/testutil"
+	ds "xconfwebconfig/db"
+	"xconfwebconfig/shared"
+	coreef "xconfwebconfig/shared/estbfirmware"
+
+	"gotest.tools/assert"
+)
+
+func createTestConfig(t *testing.T, id string, applicationType string) {
+	config := coreef.NewEmptyFirmwareConfig()
+	config.ID = id
+	config.Description = id
+	config.FirmwareVersion = id + "_VERSION"
+	config.ApplicationType = applicationType
+	config.SupportedModelIds = []string{"MODEL1"}
+	assert.NilError(t, ds.GetCachedSimpleDao().SetOne(ds.TABLE_FIRMWARE_CONFIG, config.ID, config))
+}
+
+func TestEntityReferencesAreScopedToApplicationType(t *testing.T) {
+	testutil.SetupTestDB()
+	assert.NilError(t, ds.GetCachedSimpleDao().SetOne(ds.TABLE_MODEL, "MODEL1", shared.NewModel("MODEL1", "test model")))
+	createTestConfig(t, "stb-config", shared.STB)
+	createTestConfig(t, "rdkcloud-config", "rdkcloud")
+
+	references, err := GetEntityReferences(MODEL, "MODEL1", shared.STB)
+	assert.NilError(t, err)
+	assert.Equal(t, len(references.Inbound), 1)
+	assert.Equal(t, references.Inbound[0].EntityID, "stb-config")
+
+	references, err = GetEntityReferences(MODEL, "model1", "rdkcloud")
+	assert.NilError(t, err)
+	assert.Equal(t, len(references.Inbound), 1)
+	assert.Equal(t, references.Inbound[0].EntityID, "rdkcloud-config")
+
+	references, err = GetEntityReferences(FIRMWARE_CONFIG, "stb-config", shared.STB)
+	assert.NilError(t, err)
+	assert.Equal(t, len(references.Outbound), 1)
+	assert.Equal(t, references.Outbound[0].EntityID, "MODEL1")
+
+	// the config of another application type is not found
+	_, err = GetEntityReferences(FIRMWARE_CONFIG, "stb-config", "rdkcloud")
+	xerr, ok := err.(xcommon.XconfError)
+	assert.Assert(t, ok, err)
+	assert.Equal(t, xerr.StatusCode, http.StatusNotFound)
+}
@@ -25,14 +25,18 @@ import (
 
 	"xconfadmin/adminapi/auth"
 	"xconfadmin/adminapi/change"
+	"xconfadmin/adminapi/clone"
 	ipmacrule "xconfadmin/adminapi/configuration/ip-macrule"
 	dcm "xconfadmin/adminapi/dcm"
 	firmware "xconfadmin/adminapi/firmware"
 	queries "xconfadmin/adminapi/queries"
+	"xconfadmin/adminapi/reference"
 	"xconfadmin/adminapi/rfc/feature"
 	setting "xconfadmin/adminapi/setting"
 	telemetry "xconfadmin/adminapi/telemetry"
+	"xconfadmin/adminapi/webhook"
 	xhttp "xconfadmin/http"
+	"xconfadmin/shared/schema"
 	"xconfwebconfig/db"
 
 	"github.com/gorilla/mux"
@@ -46,10 +50,18 @@ func XconfSetup(server *xhttp.WebconfigServer, r *mux.Router) {
 	WebServerInjection(server, xc)
 	db.ConfigInjection(server.XW_XconfServer.Config)
 	auth.WebServerInjection(server)
+	initLoginTokenVerification(server)
 
 	dataapi.RegisterTables()
+	schema.RegisterTables()
+	registerEntityChangeAppliers()
 	initDB()
 	db.GetCacheManager() // Initialize cache manager
+	startPendingChangeSweeper(server)
+	startScheduledChangeScheduler(server)
+	startPercentageRolloutScheduler(server)
+	startWebhookDispatcher(server)
+	failInterruptedFirmwareSimulations(server)
 
 	routeXconfAdminserviceApis(server, r)
 }
@@ -76,9 +88,12 @@ func routeXconfAdminserviceApis(s *xhttp.WebconfigServer, r *mux.Router) {
 	authInfoPath.HandleFunc("", auth.AuthInfoHandler).Methods("GET").Name("Auth-Uncategorized")
 	paths = append(paths, authInfoPath)
 
-	basicAuthpath := r.PathPrefix("/xconfAdminService/auth/basic").Subrouter()
-	basicAuthpath.HandleFunc("", auth.BasicAuthHandler).Methods("POST").Name("Auth-Basic")
-	paths = append(paths, authInfoPath)
+	// dev login tokens are rejected once JWKS verification is on, so the basic login is not routed
+	if xhttp.IsLegacyLoginTokenEnabled() {
+		basicAuthpath := r.PathPrefix("/xconfAdminService/auth/basic").Subrouter()
+		basicAuthpath.HandleFunc("", auth.BasicAuthHandler).Methods("POST").Name("Auth-Basic")
+		paths = append(paths, authInfoPath)
+	}
 
 	// DataService bypass APIs
 	dsBypassPathPrefix := r.PathPrefix("/xconfAdminService/dataService").Subrouter()
@@ -241,6 +256,15 @@ func routeXconfAdminserviceApis(s *xhttp.WebconfigServer, r *mux.Router) {
 	environmentPath.HandleFunc("/{id}", queries.DeleteEnvironmentHandler).Methods("DELETE").Name("Environments")
 	paths = append(paths, environmentPath)
 
+	// applicationtype
+	applicationTypePath := r.PathPrefix("/xconfAdminService/applicationType").Subrouter()
+	applicationTypePath.HandleFunc("", queries.GetApplicationTypesHandler).Methods("GET").Name("ApplicationTypes")
+	applicationTypePath.HandleFunc("", queries.CreateApplicationTypeHandler).Methods("POST").Name("ApplicationTypes")
+	applicationTypePath.HandleFunc("", queries.UpdateApplicationTypeHandler).Methods("PUT").Name("ApplicationTypes")
+	applicationTypePath.HandleFunc("/{id}", queries.GetApplicationTypeByIdHandler).Methods("GET").Name("ApplicationTypes")
+	applicationTypePath.HandleFunc("/{id}", queries.DeleteApplicationTypeHandler).Methods("DELETE").Name("ApplicationTypes")
+	paths = append(paths, applicationTypePath)
+
 	// genericnamespacedlist
 	nameSpacedListPath := r.PathPrefix("/xconfAdminService/genericnamespacedlist").Subrouter()
 	nameSpacedListPath.HandleFunc("", queries.GetNamespacedListsHandler).Methods("GET").Name("NameSpaced-Lists")
@@ -267,7 +291,12 @@ func routeXconfAdminserviceApis(s *xhttp.WebconfigServer, r *mux.Router) {
 	firmwareRulePath.HandleFunc("/byTemplate/{templateId}/names", queries.GetFirmwareRuleByTemplateByTemplateIdNamesHandler).Methods("GET").Name("Firmware-Rules")
 	firmwareRulePath.HandleFunc("/export/byType", queries.GetFirmwareRuleExportByTypeHandler).Methods("GET").Name("Firmware-Rules")
 	firmwareRulePath.HandleFunc("/export/allTypes", queries.GetFirmwareRuleExportAllTypesHandler).Methods("GET").Name("Firmware-Rules")
+	firmwareRulePath.HandleFunc("/analysis", queries.GetFirmwareRuleAnalysisHandler).Methods("GET").Name("Firmware-Rules")
 	firmwareRulePath.HandleFunc("/testpage", firmware.GetFirmwareTestPageHandler).Methods("GET").Name("Firmware-Rules")
+	firmwareRulePath.HandleFunc("/testpage/batch", firmware.PostFirmwareSimulationHandler).Methods("POST").Name("Firmware-Rules")
+	firmwareRulePath.HandleFunc("/testpage/batch", firmware.GetFirmwareSimulationsHandler).Methods("GET").Name("Firmware-Rules")
+	firmwareRulePath.HandleFunc("/testpage/batch/{id}", firmware.GetFirmwareSimulationByIdHandler).Methods("GET").Name("Firmware-Rules")
+	firmwareRulePath.HandleFunc("/testpage/batch/{id}/results", firmware.GetFirmwareSimulationResultsHandler).Methods("GET").Name("Firmware-Rules")
 	firmwareRulePath.HandleFunc("", queries.GetFirmwareRuleHandler).Methods("GET").Name("Firmware-Rules")
 	firmwareRulePath.HandleFunc("", queries.PostFirmwareRuleHandler).Methods("POST").Name("Firmware-Rules")
 	firmwareRulePath.HandleFunc("", queries.PutFirmwareRuleHandler).Methods("PUT").Name("Firmware-Rules")
@@ -317,11 +346,25 @@ func routeXconfAdminserviceApis(s *xhttp.WebconfigServer, r *mux.Router) {
 	firmwareConfigPath.HandleFunc("/entities", queries.PutFirmwareConfigEntitiesHandler).Methods("PUT").Name("Firmware-Configs")
 	firmwareConfigPath.HandleFunc("/filtered", queries.PostFirmwareConfigFilteredHandler).Methods("POST").Name("Firmware-Configs")
 	firmwareConfigPath.HandleFunc("/page", queries.NotImplementedHandler).Methods("GET").Name("Firmware-Configs")
+	firmwareConfigPath.HandleFunc("/lifecycle", queries.GetFirmwareConfigLifecyclesHandler).Methods("GET").Name("Firmware-ConfigLifecycle")
+	firmwareConfigPath.HandleFunc("/lifecycle/report", queries.GetFirmwareConfigLifecycleReportHandler).Methods("GET").Name("Firmware-ConfigLifecycle")
+	firmwareConfigPath.HandleFunc("/{id}/lifecycle", queries.GetFirmwareConfigLifecycleHandler).Methods("GET").Name("Firmware-ConfigLifecycle")
+	firmwareConfigPath.HandleFunc("/{id}/lifecycle", queries.PutFirmwareConfigLifecycleHandler).Methods("PUT").Name("Firmware-ConfigLifecycle")
 	// url with var has to be placed last otherwise, it gets confused with url with defined paths
 	firmwareConfigPath.HandleFunc("/{id}", queries.DeleteFirmwareConfigByIdHandler).Methods("DELETE").Name("Firmware-Configs")
 	firmwareConfigPath.HandleFunc("/{id}", queries.GetFirmwareConfigByIdHandler).Methods("GET").Name("Firmware-Configs")
 	paths = append(paths, firmwareConfigPath)
 
+	// firmware version patterns and comparison
+	firmwareVersionPath := r.PathPrefix("/xconfAdminService/firmwareversion").Subrouter()
+	firmwareVersionPath.HandleFunc("/compare", queries.CompareFirmwareVersionsHandler).Methods("GET").Name("Firmware-Versions")
+	firmwareVersionPath.HandleFunc("/pattern", queries.GetFirmwareVersionPatternsHandler).Methods("GET").Name("Firmware-VersionPatterns")
+	firmwareVersionPath.HandleFunc("/pattern", queries.CreateFirmwareVersionPatternHandler).Methods("POST").Name("Firmware-VersionPatterns")
+	firmwareVersionPath.HandleFunc("/pattern", queries.UpdateFirmwareVersionPatternHandler).Methods("PUT").Name("Firmware-VersionPatterns")
+	firmwareVersionPath.HandleFunc("/pattern/{id}", queries.GetFirmwareVersionPatternByIdHandler).Methods("GET").Name("Firmware-VersionPatterns")
+	firmwareVersionPath.HandleFunc("/pattern/{id}", queries.DeleteFirmwareVersionPatternHandler).Methods("DELETE").Name("Firmware-VersionPatterns")
+	paths = append(paths, firmwareVersionPath)
+
 	// percentfilter/percentageBean
 	percentageBeanPath := r.PathPrefix("/xconfAdminService/percentfilter/percentageBean").Subrouter()
 	percentageBeanPath.HandleFunc("", queries.GetPercentageBeanAllHandler).Methods("GET").Name("Firmware-PercentFilter")
@@ -333,6 +376,11 @@ func routeXconfAdminserviceApis(s *xhttp.WebconfigServer, r *mux.Router) {
 	percentageBeanPath.HandleFunc("/entities", queries.PutPercentageBeanEntitiesHandler).Methods("PUT").Name("Firmware-PercentFilter")
 	percentageBeanPath.HandleFunc("/allAsRules", queries.GetAllPercentageBeanAsRule).Methods("GET").Name("Firmware-PercentFilter")
 	percentageBeanPath.HandleFunc("/asRule/{id}", queries.GetPercentageBeanAsRuleById).Methods("GET").Name("Firmware-PercentFilter")
+	percentageBeanPath.HandleFunc("/{id}/rollout", queries.GetPercentageRolloutHandler).Methods("GET").Name("Firmware-PercentageRollouts")
+	percentageBeanPath.HandleFunc("/{id}/rollout", queries.CreatePercentageRolloutHandler).Methods("POST").Name("Firmware-PercentageRollouts")
+	percentageBeanPath.HandleFunc("/{id}/rollout/pause", queries.PausePercentageRolloutHandler).Methods("POST").Name("Firmware-PercentageRollouts")
+	percentageBeanPath.HandleFunc("/{id}/rollout/resume", queries.ResumePercentageRolloutHandler).Methods("POST").Name("Firmware-PercentageRollouts")
+	percentageBeanPath.HandleFunc("/{id}/rollout/abort", queries.AbortPercentageRolloutHandler).Methods("POST").Name("Firmware-PercentageRollouts")
 	percentageBeanPath.HandleFunc("/{id}", queries.GetPercentageBeanByIdHandler).Methods("GET").Name("Firmware-PercentFilter")
 	percentageBeanPath.HandleFunc("/{id}", queries.DeletePercentageBeanByIdHandler).Methods("DELETE").Name("Firmware-PercentFilter")
 	paths = append(paths, percentageBeanPath)
@@ -643,6 +691,17 @@ func routeXconfAdminserviceApis(s *xhttp.WebconfigServer, r *mux.Router) {
 	changePath.HandleFunc("/revertChanges", change.RevertChangesHandler).Methods("POST").Name("Telemetry1-Changes")
 	changePath.HandleFunc("/approved/filtered", change.GetApprovedFilteredHandler).Methods("POST").Name("Telemetry1-Changes")
 	changePath.HandleFunc("/changes/filtered", change.GetChangesFilteredHandler).Methods("POST").Name("Telemetry1-Changes")
+	changePath.HandleFunc("/approvalSettings", change.GetApprovalSettingsHandler).Methods("GET").Name("ApprovalSettings")
+	changePath.HandleFunc("/approvalSettings", change.SetApprovalSettingHandler).Methods("PUT").Name("ApprovalSettings")
+	changePath.HandleFunc("/approvalPolicies", change.GetApprovalPoliciesHandler).Methods("GET").Name("ApprovalPolicies")
+	changePath.HandleFunc("/approvalPolicies", change.SetApprovalPolicyHandler).Methods("PUT").Name("ApprovalPolicies")
+	changePath.HandleFunc("/approvals/{changeId}", change.GetChangeApprovalsHandler).Methods("GET").Name("Telemetry1-Changes")
+	changePath.HandleFunc("/diff/{changeId}", change.GetChangeDiffHandler).Methods("GET").Name("Telemetry1-Changes")
+	changePath.HandleFunc("/scheduled", change.GetScheduledChangesHandler).Methods("GET").Name("ScheduledChanges")
+	changePath.HandleFunc("/scheduled/{changeId}", change.CancelScheduledChangeHandler).Methods("DELETE").Name("ScheduledChanges")
+	changePath.HandleFunc("/comments/{changeId}", change.GetChangeThreadHandler).Methods("GET").Name("ChangeReviews")
+	changePath.HandleFunc("/comments/{changeId}", change.AddChangeCommentHandler).Methods("POST").Name("ChangeReviews")
+	changePath.HandleFunc("/reject/{changeId}", change.RejectChangeHandler).Methods("POST").Name("ChangeReviews")
 	paths = append(paths, changePath)
 
 	// telemetry/change
@@ -669,6 +728,10 @@ func routeXconfAdminserviceApis(s *xhttp.WebconfigServer, r *mux.Router) {
 	telemetryTwoChangePath.HandleFunc("/revert/{approveId}", change.RevertTwoChangeHandler).Methods("GET").Name("Telemetry2-Changes")
 	telemetryTwoChangePath.HandleFunc("/cancel/{changeId}", change.CancelTwoChangeHandler).Methods("GET").Name("Telemetry2-Changes")
 	telemetryTwoChangePath.HandleFunc("/entityIds", change.GetTwoChangeEntityIdsHandler).Methods("GET").Name("Telemetry2-Changes")
+	telemetryTwoChangePath.HandleFunc("/diff/{changeId}", change.GetChangeDiffHandler).Methods("GET").Name("Telemetry2-Changes")
+	telemetryTwoChangePath.HandleFunc("/comments/{changeId}", change.GetChangeThreadHandler).Methods("GET").Name("ChangeReviews")
+	telemetryTwoChangePath.HandleFunc("/comments/{changeId}", change.AddChangeCommentHandler).Methods("POST").Name("ChangeReviews")
+	telemetryTwoChangePath.HandleFunc("/reject/{changeId}", change.RejectChangeHandler).Methods("POST").Name("ChangeReviews")
 	telemetryTwoChangePath.HandleFunc("/changes/grouped/byId", change.GetGroupedTwoChangesHandler).Methods("GET").Name("Telemetry2-Changes")
 	telemetryTwoChangePath.HandleFunc("/approved/grouped/byId", change.GetGroupedApprovedTwoChangesHandler).Methods("GET").Name("Telemetry2-Changes")
 	telemetryTwoChangePath.HandleFunc("/approveChanges", change.ApproveTwoChangesHandler).Methods("POST").Name("Telemetry2-Changes")
@@ -710,6 +773,54 @@ func routeXconfAdminserviceApis(s *xhttp.WebconfigServer, r *mux.Router) {
 	appsettingsPath.HandleFunc("", queries.UpdateAppSettings).Methods("PUT").Name("AppSettings")
 	paths = append(paths, appsettingsPath)
 
+	// serviceaccount
+	serviceAccountPath := r.PathPrefix("/xconfAdminService/serviceAccount").Subrouter()
+	serviceAccountPath.HandleFunc("", auth.GetServiceAccountsHandler).Methods("GET").Name("ServiceAccounts")
+	serviceAccountPath.HandleFunc("", auth.CreateServiceAccountHandler).Methods("POST").Name("ServiceAccounts")
+	serviceAccountPath.HandleFunc("", auth.UpdateServiceAccountHandler).Methods("PUT").Name("ServiceAccounts")
+	serviceAccountPath.HandleFunc("/{id}/keys", auth.GetServiceAccountKeysHandler).Methods("GET").Name("ServiceAccounts")
+	serviceAccountPath.HandleFunc("/{id}/keys", auth.IssueServiceAccountKeyHandler).Methods("POST").Name("ServiceAccounts")
+	serviceAccountPath.HandleFunc("/{id}/keys/{keyId}", auth.RevokeServiceAccountKeyHandler).Methods("DELETE").Name("ServiceAccounts")
+	serviceAccountPath.HandleFunc("/{id}", auth.GetServiceAccountByIdHandler).Methods("GET").Name("ServiceAccounts")
+	serviceAccountPath.HandleFunc("/{id}", auth.DeleteServiceAccountHandler).Methods("DELETE").Name("ServiceAccounts")
+	paths = append(paths, serviceAccountPath)
+
+	// ownership groups
+	ownershipPath := r.PathPrefix("/xconfAdminService/ownership").Subrouter()
+	ownershipPath.HandleFunc("/groups", auth.GetOwnershipGroupMappingsHandler).Methods("GET").Name("Ownership-Groups")
+	ownershipPath.HandleFunc("/groups", auth.SetOwnershipGroupMappingHandler).Methods("POST", "PUT").Name("Ownership-Groups")
+	ownershipPath.HandleFunc("/groups/{id}", auth.DeleteOwnershipGroupMappingHandler).Methods("DELETE").Name("Ownership-Groups")
+	ownershipPath.HandleFunc("/{entityType}/{id}", auth.GetEntityOwnerHandler).Methods("GET").Name("Ownership")
+	ownershipPath.HandleFunc("/{entityType}/{id}", auth.SetEntityOwnerHandler).Methods("PUT").Name("Ownership")
+	paths = append(paths, ownershipPath)
+
+	// audit
+	auditPath := r.PathPrefix("/xconfAdminService/audit").Subrouter()
+	auditPath.HandleFunc("", queries.GetAuditEntriesHandler).Methods("GET").Name("Audit")
+	auditPath.HandleFunc("/export", queries.ExportAuditEntriesHandler).Methods("GET").Name("Audit")
+	paths = append(paths, auditPath)
+
+	// references
+	referencePath := r.PathPrefix("/xconfAdminService/references").Subrouter()
+	referencePath.HandleFunc("/{entityType}/{id}", reference.GetEntityReferencesHandler).Methods("GET").Name("References")
+	paths = append(paths, referencePath)
+
+	// clone
+	clonePath := r.PathPrefix("/xconfAdminService/clone").Subrouter()
+	clonePath.HandleFunc("", clone.CloneEntitiesHandler).Methods("POST").Name("Clone")
+	paths = append(paths, clonePath)
+
+	// webhook subscriptions
+	webhookPath := r.PathPrefix("/xconfAdminService/webhook").Subrouter()
+	webhookPath.HandleFunc("", webhook.GetWebhookSubscriptionsHandler).Methods("GET").Name("Webhooks")
+	webhookPath.HandleFunc("", webhook.CreateWebhookSubscriptionHandler).Methods("POST").Name("Webhooks")
+	webhookPath.HandleFunc("", webhook.UpdateWebhookSubscriptionHandler).Methods("PUT").Name("Webhooks")
+	webhookPath.HandleFunc("/{id}/deliveries", webhook.GetWebhookDeliveriesHandler).Methods("GET").Name("Webhooks")
+	webhookPath.HandleFunc("/{id}/test", webhook.TestWebhookSubscriptionHandler).Methods("POST").Name("Webhooks")
+	webhookPath.HandleFunc("/{id}", webhook.GetWebhookSubscriptionByIdHandler).Methods("GET").Name("Webhooks")
+	webhookPath.HandleFunc("/{id}", webhook.DeleteWebhookSubscriptionHandler).Methods("DELETE").Name("Webhooks")
+	paths = append(paths, webhookPath)
+
 	// penetration data report
 	penetrationPath := r.PathPrefix("/xconfAdminService/penetrationdata").Subrouter()
 	penetrationPath.HandleFunc("/{macAddress}", queries.GetPenetrationMetricsByEstbMac).Methods("GET").Name("PenetrationData")
@@ -725,7 +836,8 @@ func routeXconfAdminserviceApis(s *xhttp.WebconfigServer, r *mux.Router) {
 		AllowCredentials: true,
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
-		AllowedHeaders:   []string{"X-Requested-With", "Origin", "Content-Type", "Accept", "Authorization", "token"},
+		AllowedHeaders:   []string{"X-Requested-With", "Origin", "Content-Type", "Accept", "Authorization", "token", "X-Api-Key", IF_MATCH_HEADER},
+		ExposedHeaders:   []string{ETAG_HEADER},
 	})
 
 	for _, p := range authPaths {
@@ -740,5 +852,10 @@ func routeXconfAdminserviceApis(s *xhttp.WebconfigServer, r *mux.Router) {
 		} else {
 			p.Use(s.XW_XconfServer.NoAuthMiddleware)
 		}
+		p.Use(ReadonlyWindowMiddleware)
+		p.Use(DryRunMiddleware)
+		p.Use(AuditMiddleware)
+		p.Use(ETagMiddleware)
+		p.Use(RuleExpressionMiddleware)
 	}
 }
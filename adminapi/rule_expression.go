@@ -0,0 +1,266 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package adminapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"xconfadmin/adminapi/queries"
+	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
+	"xconfwebconfig/db"
+	xwhttp "xconfwebconfig/http"
+	re "xconfwebconfig/rulesengine"
+	"xconfwebconfig/util"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	RULE_EXPRESSION_FORMAT = "expr"
+	ruleField              = "rule"
+)
+
+// ruleExpressionTables maps the routes which take or return rules, reads and filters included, to the table of the rules
+var ruleExpressionTables = map[string]string{
+	"DataServiceByPass":      db.TABLE_FIRMWARE_RULE,
+	"DCM-Formulas":           db.TABLE_DCM_RULE,
+	"Firmware-PercentFilter": db.TABLE_FIRMWARE_RULE,
+	"Firmware-Rules":         db.TABLE_FIRMWARE_RULE,
+	"Firmware-Templates":     db.TABLE_FIRMWARE_RULE_TEMPLATE,
+	"RFC-FeatureRules":       db.TABLE_FEATURE_CONTROL_RULE,
+	"Settings-Rules":         db.TABLE_SETTING_RULES,
+	"Telemetry1-Rules":       db.TABLE_TELEMETRY_RULES,
+	"Telemetry2-Rules":       db.TABLE_TELEMETRY_TWO_RULES,
+}
+
+// ruleExpressionOperations maps the table of a rule bearing entity to the operations allowed in its rule
+var ruleExpressionOperations = map[string]func() []string{
+	db.TABLE_FIRMWARE_RULE:          queries.GetFirmwareRuleAllowedOperations,
+	db.TABLE_FIRMWARE_RULE_TEMPLATE: queries.GetFirmwareRuleAllowedOperations,
+	db.TABLE_FEATURE_CONTROL_RULE:   queries.GetFeatureRuleAllowedOperations,
+	db.TABLE_DCM_RULE:               queries.GetFirmwareRuleAllowedOperations,
+	db.TABLE_SETTING_RULES:          queries.GetAllowedOperations,
+	db.TABLE_TELEMETRY_RULES:        queries.GetAllowedOperations,
+	db.TABLE_TELEMETRY_TWO_RULES:    queries.GetAllowedOperations,
+}
+
+// embeddedRuleTables keep the fields of the rule in the entity itself instead of a rule field
+var embeddedRuleTables = []string{
+	db.TABLE_DCM_RULE,
+	db.TABLE_TELEMETRY_RULES,
+	db.TABLE_TELEMETRY_TWO_RULES,
+}
+
+var embeddedRuleFields = []string{"compoundParts", "condition", "negated", "relation"}
+
+// RuleExpressionMiddleware lets the rule endpoints take and return rule expressions in the rule field
+// when called with format=expr
+func RuleExpressionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := mux.CurrentRoute(r)
+		xw, ok := w.(*xwhttp.XResponseWriter)
+		if route == nil || !ok || r.URL.Query().Get("format") != RULE_EXPRESSION_FORMAT {
+			next.ServeHTTP(w, r)
+			return
+		}
+		tableName := ruleExpressionTables[route.GetName()]
+		allowedOperations, ok := ruleExpressionOperations[tableName]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		embedded := util.Contains(embeddedRuleTables, tableName)
+		body := xw.Body()
+		if entity, err := decodeJsonValue([]byte(body)); err == nil {
+			if err := parseRuleExpressions(entity, embedded, allowedOperations); err != nil {
+				xhttp.WriteAdminErrorResponse(w, xcommon.GetXconfErrorStatusCode(err), err.Error())
+				return
+			}
+			if entityBytes, err := util.JSONMarshal(entity); err == nil {
+				body = string(entityBytes)
+			}
+		}
+
+		buffer := &bufferedResponseWriter{header: xw.Header()}
+		bw := xwhttp.NewXResponseWriter(buffer, xw.StartTime(), xw.Token(), xw.Audit())
+		bw.SetBody(body)
+		next.ServeHTTP(bw, r)
+
+		status := bw.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		response := buffer.body.Bytes()
+		if status < http.StatusBadRequest {
+			if entity, err := decodeJsonValue(response); err == nil && formatRuleExpressions(entity, embedded) {
+				if entityBytes, err := util.JSONMarshal(entity); err == nil {
+					response = entityBytes
+				}
+			}
+		}
+		xw.Header().Del("Content-Length")
+		xw.WriteHeader(status)
+		xw.Write(response)
+	})
+}
+
+// decodeJsonValue keeps the numbers as they are so ids and timestamps are not rounded on the way back
+func decodeJsonValue(data []byte) (interface{}, error) {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// parseRuleExpressions replaces the rule expression of the entity, or of every entity of a list, by the rule
+// it stands for. Only the rule field of the entity itself is parsed, the nested objects are kept as they are
+func parseRuleExpressions(value interface{}, embedded bool, allowedOperations func() []string) error {
+	if list, ok := value.([]interface{}); ok {
+		for _, item := range list {
+			if err := parseEntityRuleExpression(item, embedded, allowedOperations); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return parseEntityRuleExpression(value, embedded, allowedOperations)
+}
+
+func parseEntityRuleExpression(value interface{}, embedded bool, allowedOperations func() []string) error {
+	entity, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	expression, ok := entity[ruleField].(string)
+	if !ok {
+		return nil
+	}
+	rule, err := queries.ValidateRuleExpression(expression, allowedOperations)
+	if err != nil {
+		return err
+	}
+	if !embedded {
+		entity[ruleField] = rule
+		return nil
+	}
+	delete(entity, ruleField)
+	if rule.Condition != nil {
+		entity["condition"] = rule.Condition
+	} else {
+		entity["compoundParts"] = rule.CompoundParts
+	}
+	entity["negated"] = rule.Negated
+	return nil
+}
+
+// formatRuleExpressions replaces the rule of the entity, or of every entity of a list, by its rule expression,
+// false if there is none. Only the rule of the entity itself is formatted, the nested objects are kept as they are
+func formatRuleExpressions(value interface{}, embedded bool) bool {
+	if list, ok := value.([]interface{}); ok {
+		formatted := false
+		for _, item := range list {
+			formatted = formatEntityRuleExpression(item, embedded) || formatted
+		}
+		return formatted
+	}
+	return formatEntityRuleExpression(value, embedded)
+}
+
+func formatEntityRuleExpression(value interface{}, embedded bool) bool {
+	entity, ok := value.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	if !embedded {
+		ruleValue, ok := entity[ruleField].(map[string]interface{})
+		if !ok || !isJsonRule(ruleValue) {
+			return false
+		}
+		expression, ok := formatJsonRule(ruleValue)
+		if !ok {
+			return false
+		}
+		entity[ruleField] = expression
+		return true
+	}
+
+	if _, ok := entity[ruleField]; ok || !isEmbeddedJsonRule(entity) {
+		return false
+	}
+	ruleValue := map[string]interface{}{}
+	for _, field := range embeddedRuleFields {
+		if fieldValue, ok := entity[field]; ok {
+			ruleValue[field] = fieldValue
+		}
+	}
+	expression, ok := formatJsonRule(ruleValue)
+	if !ok {
+		return false
+	}
+	for _, field := range embeddedRuleFields {
+		delete(entity, field)
+	}
+	entity[ruleField] = expression
+	return true
+}
+
+func isJsonRule(value map[string]interface{}) bool {
+	if _, ok := value["negated"]; !ok {
+		return false
+	}
+	for key := range value {
+		if !util.Contains(embeddedRuleFields, key) {
+			return false
+		}
+	}
+	return true
+}
+
+// isEmbeddedJsonRule checks for an entity which has the fields of its rule next to its own fields
+func isEmbeddedJsonRule(value map[string]interface{}) bool {
+	_, hasCondition := value["condition"]
+	_, hasCompoundParts := value["compoundParts"]
+	if _, ok := value["negated"]; !ok || (!hasCondition && !hasCompoundParts) {
+		return false
+	}
+	for key := range value {
+		if !util.Contains(embeddedRuleFields, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatJsonRule(value map[string]interface{}) (string, bool) {
+	ruleBytes, err := json.Marshal(value)
+	if err != nil {
+		return "", false
+	}
+	rule := re.Rule{}
+	if err := json.Unmarshal(ruleBytes, &rule); err != nil {
+		return "", false
+	}
+	return queries.FormatRuleExpression(&rule), true
+}
@@ -0,0 +1,114 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package adminapi
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	xwhttp "xconfwebconfig/http"
+
+	"github.com/gorilla/mux"
+	"gotest.tools/assert"
+)
+
+const (
+	ruleExpressionTestRule    = `{"id":"rule-1","rule":{"negated":false,"condition":{"freeArg":{"type":"STRING","name":"model"},"operation":"IS","fixedArg":{"bean":{"value":{"java.lang.String":"X1"}}}},"compoundParts":[]}}`
+	ruleExpressionTestFormula = `[{"id":"formula-1","negated":false,"condition":{"freeArg":{"type":"STRING","name":"model"},"operation":"IS","fixedArg":{"bean":{"value":{"java.lang.String":"X1"}}}},"compoundParts":[]}]`
+)
+
+func newRuleExpressionTestRouter() *mux.Router {
+	// the handler echoes the rule it is sent, reads return a stored rule
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		body := ruleExpressionTestRule
+		if xw, ok := w.(*xwhttp.XResponseWriter); ok && r.Method != http.MethodGet {
+			body = xw.Body()
+		}
+		xwhttp.WriteXconfResponse(w, http.StatusOK, []byte(body))
+	}
+	r := mux.NewRouter()
+	r.Use(RuleExpressionMiddleware)
+	r.HandleFunc("/xconfAdminService/firmwarerule", handler).Methods("POST").Name("Firmware-Rules")
+	r.HandleFunc("/xconfAdminService/firmwarerule/{id}", handler).Methods("GET").Name("Firmware-Rules")
+	r.HandleFunc("/xconfAdminService/dcm/formula/filtered", func(w http.ResponseWriter, r *http.Request) {
+		xwhttp.WriteXconfResponse(w, http.StatusOK, []byte(ruleExpressionTestFormula))
+	}).Methods("GET").Name("DCM-Formulas")
+	r.HandleFunc("/xconfAdminService/firmwareconfig/{id}", handler).Methods("GET").Name("Firmware-Configs")
+	return r
+}
+
+func serveRuleExpressionTest(router *mux.Router, method string, url string, body string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	xw := xwhttp.NewXResponseWriter(rr)
+	r := httptest.NewRequest(method, url, strings.NewReader(body))
+	b, _ := ioutil.ReadAll(r.Body)
+	xw.SetBody(string(b))
+	router.ServeHTTP(xw, r)
+	return rr
+}
+
+func TestRuleExpressionMiddlewareFormatsReads(t *testing.T) {
+	router := newRuleExpressionTestRouter()
+
+	for _, url := range []string{"/xconfAdminService/firmwarerule/rule-1", "/xconfAdminService/dcm/formula/filtered"} {
+		rr := serveRuleExpressionTest(router, http.MethodGet, url+"?format=expr", "")
+		assert.Equal(t, rr.Code, http.StatusOK)
+		assert.Assert(t, strings.Contains(rr.Body.String(), `"rule":"model IS \"X1\""`), rr.Body.String())
+	}
+
+	// the json form is kept without format=expr and on the routes which carry no rules
+	rr := serveRuleExpressionTest(router, http.MethodGet, "/xconfAdminService/firmwarerule/rule-1", "")
+	assert.Equal(t, rr.Body.String(), ruleExpressionTestRule)
+	rr = serveRuleExpressionTest(router, http.MethodGet, "/xconfAdminService/firmwareconfig/rule-1?format=expr", "")
+	assert.Equal(t, rr.Body.String(), ruleExpressionTestRule)
+}
+
+func TestRuleExpressionMiddlewareParsesWrites(t *testing.T) {
+	router := newRuleExpressionTestRouter()
+
+	rr := serveRuleExpressionTest(router, http.MethodPost, "/xconfAdminService/firmwarerule?format=expr", `{"id":"rule-1","rule":"model IN [\"X1\",\"X2\"] AND NOT env IS \"PROD\""}`)
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Assert(t, strings.Contains(rr.Body.String(), `"rule":"model IN [\"X1\",\"X2\"] AND NOT env IS \"PROD\""`), rr.Body.String())
+
+	rr = serveRuleExpressionTest(router, http.MethodPost, "/xconfAdminService/firmwarerule?format=expr", `{"id":"rule-1","rule":"model MATCHES \"X1\""}`)
+	assert.Equal(t, rr.Code, http.StatusBadRequest)
+	assert.Assert(t, strings.Contains(rr.Body.String(), "Operation is not valid: MATCHES"), rr.Body.String())
+
+	rr = serveRuleExpressionTest(router, http.MethodPost, "/xconfAdminService/firmwarerule?format=expr", `{"id":"rule-1","rule":"model IS"}`)
+	assert.Equal(t, rr.Code, http.StatusBadRequest)
+}
+
+func TestRuleExpressionMiddlewareKeepsNestedRules(t *testing.T) {
+	router := newRuleExpressionTestRouter()
+
+	// only the rule of the entity itself is formatted, not a rule key of another field
+	body := `{"id":"rule-1","rule":"model IS \"X1\"","audit":{"before":{"rule":{"negated":false,"compoundParts":[]}}}}`
+	rr := serveRuleExpressionTest(router, http.MethodPost, "/xconfAdminService/firmwarerule?format=expr", body)
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Assert(t, strings.Contains(rr.Body.String(), `"rule":"model IS \"X1\""`), rr.Body.String())
+	assert.Assert(t, strings.Contains(rr.Body.String(), `"audit":{"before":{"rule":{"compoundParts":[],"negated":false}}}`), rr.Body.String())
+
+	// a nested rule expression is not parsed either
+	body = `{"id":"rule-1","rule":"model IS \"X1\"","audit":{"rule":"not an expression"}}`
+	rr = serveRuleExpressionTest(router, http.MethodPost, "/xconfAdminService/firmwarerule?format=expr", body)
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Assert(t, strings.Contains(rr.Body.String(), `"audit":{"rule":"not an expression"}`), rr.Body.String())
+}
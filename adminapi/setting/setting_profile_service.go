@@ -228,6 +228,9 @@ func beforeSaving(entity *xwlogupload.SettingProfiles, writeApplication string)
 	if err != nil {
 		return err
 	}
+	if err := shared.ValidateApplicationTypeEntity(entity.ApplicationType, entity); err != nil {
+		return err
+	}
 	all := GetSettingProfileList()
 	err = validateAll(entity, all)
 	if err != nil {
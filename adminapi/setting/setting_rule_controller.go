@@ -145,11 +145,16 @@ func DeleteOneSettingRulesHandler(w http.ResponseWriter, r *http.Request) {
 		xwhttp.WriteXconfResponse(w, http.StatusMethodNotAllowed, nil)
 		return
 	}
-	_, err = DeleteSettingRule(id, applicationType)
+	dryRun := xhttp.NewDryRun(r)
+	_, err = DeleteSettingRule(id, applicationType, dryRun)
 	if err != nil {
 		xwhttp.WriteXconfResponse(w, http.StatusBadRequest, []byte(err.Error()))
 		return
 	}
+	if dryRun != nil {
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	xwhttp.WriteXconfResponse(w, http.StatusNoContent, nil)
 }
 
@@ -223,11 +228,16 @@ func CreateSettingRuleHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
-	err = CreateSettingRule(r, &settingRules)
+	dryRun := xhttp.NewDryRun(r)
+	err = CreateSettingRule(r, &settingRules, dryRun)
 	if err != nil {
 		xhttp.AdminError(w, err)
 		return
 	}
+	if dryRun != nil {
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	response, err := util.JSONMarshal(settingRules)
 	if err != nil {
 		log.Error(fmt.Sprintf("json.Marshal settingRules error: %v", err))
@@ -247,10 +257,11 @@ func CreateSettingRulesPackageHandler(w http.ResponseWriter, r *http.Request) {
 		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, response)
 		return
 	}
+	dryRun := xhttp.NewDryRun(r)
 	entitiesMap := map[string]xhttp.EntityMessage{}
 	for _, entity := range entities {
 		entity := entity
-		err := CreateSettingRule(r, &entity)
+		err := CreateSettingRule(r, &entity, dryRun)
 		if err == nil {
 			entityMessage := xhttp.EntityMessage{
 				Status:  xcommon.ENTITY_STATUS_SUCCESS,
@@ -266,6 +277,11 @@ func CreateSettingRulesPackageHandler(w http.ResponseWriter, r *http.Request) {
 			break
 		}
 	}
+	if dryRun != nil {
+		dryRun.Result = entitiesMap
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	response, _ := util.JSONMarshal(entitiesMap)
 	xwhttp.WriteXconfResponse(w, http.StatusOK, response)
 }
@@ -288,11 +304,16 @@ func UpdateSettingRulesHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	err = UpdateSettingRule(r, &settingRules)
+	dryRun := xhttp.NewDryRun(r)
+	err = UpdateSettingRule(r, &settingRules, dryRun)
 	if err != nil {
 		xhttp.AdminError(w, err)
 		return
 	}
+	if dryRun != nil {
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	response, err := util.JSONMarshal(settingRules)
 	if err != nil {
 		log.Error(fmt.Sprintf("json.Marshal featureRuleNew error: %v", err))
@@ -312,10 +333,11 @@ func UpdateSettingRulesPackageHandler(w http.ResponseWriter, r *http.Request) {
 		xwhttp.WriteXconfResponse(w, http.StatusBadRequest, []byte(response))
 		return
 	}
+	dryRun := xhttp.NewDryRun(r)
 	entitiesMap := map[string]xhttp.EntityMessage{}
 	for _, entity := range entities {
 		entity := entity
-		err := UpdateSettingRule(r, &entity)
+		err := UpdateSettingRule(r, &entity, dryRun)
 		if err == nil {
 			entityMessage := xhttp.EntityMessage{
 				Status:  xcommon.ENTITY_STATUS_SUCCESS,
@@ -331,6 +353,11 @@ func UpdateSettingRulesPackageHandler(w http.ResponseWriter, r *http.Request) {
 			break
 		}
 	}
+	if dryRun != nil {
+		dryRun.Result = entitiesMap
+		xhttp.WriteDryRunResponse(w, r, dryRun)
+		return
+	}
 	response, _ := util.JSONMarshal(entitiesMap)
 	xwhttp.WriteXconfResponse(w, http.StatusOK, response)
 }
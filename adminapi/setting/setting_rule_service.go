@@ -27,6 +27,7 @@ import (
 	"xconfwebconfig/dataapi/dcm/settings"
 
 	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
 
 	"xconfadmin/adminapi/auth"
 	"xconfadmin/adminapi/queries"
@@ -57,7 +58,7 @@ func GetOneSettingRule(id string) (*logupload.SettingRule, error) {
 	return settingRule, nil
 }
 
-func DeleteSettingRule(id string, writeApplication string) (*logupload.SettingRule, error) {
+func DeleteSettingRule(id string, writeApplication string, dryRun *xhttp.DryRun) (*logupload.SettingRule, error) {
 	entity, err := GetOneSettingRule(id)
 	if err != nil {
 		return nil, err
@@ -70,6 +71,10 @@ func DeleteSettingRule(id string, writeApplication string) (*logupload.SettingRu
 		return nil, fmt.Errorf("Entity with id %s ApplicationType doesn't match", id)
 	}
 
+	if dryRun != nil {
+		dryRun.Record(xhttp.DRY_RUN_DELETE, db.TABLE_SETTING_RULES, id, entity)
+		return entity, nil
+	}
 	DeleteSettingRuleOne(id)
 	return entity, nil
 }
@@ -176,8 +181,20 @@ func validatePropertiesSettingRule(entity *logupload.SettingRule) string {
 	return ""
 }
 
-func validateAllSettingRule(ruleToCheck *logupload.SettingRule) error {
-	existingSettingRules := GetAllSettingRules()
+// validateAllSettingRule checks the rule against the stored rules and the rules saved earlier in the dry run
+func validateAllSettingRule(ruleToCheck *logupload.SettingRule, dryRun *xhttp.DryRun) error {
+	existingSettingRules := []*logupload.SettingRule{}
+	pending := dryRun.Pending(db.TABLE_SETTING_RULES)
+	for _, settingRule := range GetAllSettingRules() {
+		if _, ok := pending[settingRule.ID]; !ok {
+			existingSettingRules = append(existingSettingRules, settingRule)
+		}
+	}
+	for _, entity := range pending {
+		if settingRule, ok := entity.(*logupload.SettingRule); ok {
+			existingSettingRules = append(existingSettingRules, settingRule)
+		}
+	}
 	for _, settingRule := range existingSettingRules {
 		if settingRule.ID == ruleToCheck.ID {
 			continue
@@ -218,11 +235,13 @@ func SettingRulesGeneratePage(list []*logupload.SettingRule, page int, pageSize
 	return list[startIndex:lastIndex]
 }
 
-func beforeCreatingSettingRule(r *http.Request, entity *logupload.SettingRule) error {
+func beforeCreatingSettingRule(r *http.Request, entity *logupload.SettingRule, dryRun *xhttp.DryRun) error {
 	id := entity.ID
 
 	if id == "" {
 		entity.ID = uuid.New().String()
+	} else if dryRun.Saved(db.TABLE_SETTING_RULES, id) {
+		return xcommon.NewXconfError(http.StatusConflict, "Entity with id: "+id+" already exists")
 	} else {
 		existingEntity := GetSettingRule(id)
 		writeApplication, err := auth.CanWrite(r, auth.DCM_ENTITY)
@@ -258,7 +277,7 @@ func beforeUpdatingSettingRule(r *http.Request, entity *logupload.SettingRule) e
 	return nil
 }
 
-func beforeSavingSettingRule(r *http.Request, entity *logupload.SettingRule) error {
+func beforeSavingSettingRule(r *http.Request, entity *logupload.SettingRule, dryRun *xhttp.DryRun) error {
 	if entity != nil && entity.ApplicationType == "" {
 		application, err := auth.CanWrite(r, auth.DCM_ENTITY)
 		if err != nil {
@@ -273,34 +292,42 @@ func beforeSavingSettingRule(r *http.Request, entity *logupload.SettingRule) err
 	if err != nil {
 		return err
 	}
-	err = validateAllSettingRule(entity)
+	err = validateAllSettingRule(entity, dryRun)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func CreateSettingRule(r *http.Request, entity *logupload.SettingRule) error {
-	err := beforeCreatingSettingRule(r, entity)
+func CreateSettingRule(r *http.Request, entity *logupload.SettingRule, dryRun *xhttp.DryRun) error {
+	err := beforeCreatingSettingRule(r, entity, dryRun)
 	if err != nil {
 		return err
 	}
-	err = beforeSavingSettingRule(r, entity)
+	err = beforeSavingSettingRule(r, entity, dryRun)
 	if err != nil {
 		return err
 	}
+	if dryRun != nil {
+		dryRun.Record(xhttp.DRY_RUN_CREATE, db.TABLE_SETTING_RULES, entity.ID, entity)
+		return nil
+	}
 	return SetSettingRule(entity.ID, entity)
 }
 
-func UpdateSettingRule(r *http.Request, entity *logupload.SettingRule) error {
+func UpdateSettingRule(r *http.Request, entity *logupload.SettingRule, dryRun *xhttp.DryRun) error {
 	err := beforeUpdatingSettingRule(r, entity)
 	if err != nil {
 		return err
 	}
-	err = beforeSavingSettingRule(r, entity)
+	err = beforeSavingSettingRule(r, entity, dryRun)
 	if err != nil {
 		return err
 	}
+	if dryRun != nil {
+		dryRun.Record(xhttp.DRY_RUN_UPDATE, db.TABLE_SETTING_RULES, entity.ID, entity)
+		return nil
+	}
 	return SetSettingRule(entity.ID, entity)
 }
 
@@ -156,7 +156,7 @@ func CreateTelemetryRuleHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respEntity := CreateTelemetryRule(&newtmrule, applicationType)
+	respEntity := CreateTelemetryRule(&newtmrule, applicationType, nil)
 	if respEntity.Error != nil {
 		xhttp.WriteAdminErrorResponse(w, respEntity.Status, respEntity.Error.Error())
 		return
@@ -229,7 +229,7 @@ func PostTelemtryRuleEntitiesHandler(w http.ResponseWriter, r *http.Request) {
 	entitiesMap := map[string]xhttp.EntityMessage{}
 	for _, entity := range entities {
 		entity := entity
-		respEntity := CreateTelemetryRule(&entity, applicationType)
+		respEntity := CreateTelemetryRule(&entity, applicationType, nil)
 		if respEntity.Status != http.StatusCreated {
 			entitiesMap[entity.ID] = xhttp.EntityMessage{
 				Status:  xcommon.ENTITY_STATUS_FAILURE,
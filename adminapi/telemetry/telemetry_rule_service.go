@@ -30,6 +30,7 @@ import (
 
 	queries "xconfadmin/adminapi/queries"
 	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
 	xlogupload "xconfadmin/shared/logupload"
 	xutil "xconfadmin/util"
 	xwcommon "xconfwebconfig/common"
@@ -136,7 +137,7 @@ func telemetryRuleValidate(tmrule *xwlogupload.TelemetryRule) *xwhttp.ResponseEn
 	return xwhttp.NewResponseEntity(http.StatusCreated, nil, nil)
 }
 
-func CreateTelemetryRule(tmrule *xwlogupload.TelemetryRule, app string) *xwhttp.ResponseEntity {
+func CreateTelemetryRule(tmrule *xwlogupload.TelemetryRule, app string, dryRun *xhttp.DryRun) *xwhttp.ResponseEntity {
 	if xwutil.IsBlank(tmrule.ID) {
 		tmrule.ID = uuid.New().String()
 	} else {
@@ -158,7 +159,9 @@ func CreateTelemetryRule(tmrule *xwlogupload.TelemetryRule, app string) *xwhttp.
 	}
 
 	tmrule.Updated = xwutil.GetTimestamp(time.Now().UTC())
-	if err := db.GetCachedSimpleDao().SetOne(db.TABLE_TELEMETRY_RULES, tmrule.ID, tmrule); err != nil {
+	if dryRun != nil {
+		dryRun.Record(xhttp.DRY_RUN_CREATE, db.TABLE_TELEMETRY_RULES, tmrule.ID, tmrule)
+	} else if err := db.GetCachedSimpleDao().SetOne(db.TABLE_TELEMETRY_RULES, tmrule.ID, tmrule); err != nil {
 		return xwhttp.NewResponseEntity(http.StatusInternalServerError, err, nil)
 	}
 
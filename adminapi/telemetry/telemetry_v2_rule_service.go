@@ -252,6 +252,9 @@ func beforeSaving(entity *xwlogupload.TelemetryTwoRule, writeApplication string)
 	if err != nil {
 		return err
 	}
+	if err := xshared.ValidateApplicationTypeEntity(entity.ApplicationType, entity); err != nil {
+		return err
+	}
 	all := xwlogupload.GetTelemetryTwoRuleList()
 	err = validateAll(entity, all)
 	if err != nil {
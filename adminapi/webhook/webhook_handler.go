@@ -0,0 +1,232 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"xconfadmin/adminapi/auth"
+	xhttp "xconfadmin/http"
+	xshared "xconfadmin/shared"
+	xwcommon "xconfwebconfig/common"
+	xwhttp "xconfwebconfig/http"
+	"xconfwebconfig/util"
+
+	"github.com/gorilla/mux"
+)
+
+// WebhookSubscriptionRequest is the body of a create or update, a blank secret keeps the current one
+// and on create a random secret is generated
+type WebhookSubscriptionRequest struct {
+	xshared.WebhookSubscription
+	Secret string `json:"secret,omitempty"`
+}
+
+// CreatedWebhookSubscription is returned once when the subscription is created, Secret is not retrievable later
+type CreatedWebhookSubscription struct {
+	*xshared.WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+func GetWebhookSubscriptionsHandler(w http.ResponseWriter, r *http.Request) {
+	if _, err := auth.CanRead(r, auth.TOOL_ENTITY); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	subscriptions := xshared.GetWebhookSubscriptions()
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return strings.ToLower(subscriptions[i].Name) < strings.ToLower(subscriptions[j].Name)
+	})
+	res, err := xhttp.ReturnJsonResponse(subscriptions, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
+func GetWebhookSubscriptionByIdHandler(w http.ResponseWriter, r *http.Request) {
+	if _, err := auth.CanRead(r, auth.TOOL_ENTITY); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	subscription := xshared.GetWebhookSubscription(mux.Vars(r)[xwcommon.ID])
+	if subscription == nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusNotFound, "WebhookSubscription does not exist")
+		return
+	}
+	res, err := xhttp.ReturnJsonResponse(subscription, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
+func CreateWebhookSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	saveWebhookSubscription(w, r, true)
+}
+
+func UpdateWebhookSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	saveWebhookSubscription(w, r, false)
+}
+
+func saveWebhookSubscription(w http.ResponseWriter, r *http.Request, create bool) {
+	if _, err := auth.CanWrite(r, auth.TOOL_ENTITY); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	// r.Body is already drained in the middleware
+	xw, ok := w.(*xwhttp.XResponseWriter)
+	if !ok {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, "Unable to extract body")
+		return
+	}
+	request := WebhookSubscriptionRequest{}
+	if err := json.Unmarshal([]byte(xw.Body()), &request); err != nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	subscription := request.WebhookSubscription
+
+	status := http.StatusOK
+	if create {
+		subscription.ID = ""
+		subscription.Created = 0
+		subscription.CreatedBy = auth.GetUserNameOrUnknown(r)
+		status = http.StatusCreated
+	} else {
+		existing := xshared.GetWebhookSubscription(subscription.ID)
+		if util.IsBlank(subscription.ID) || existing == nil {
+			xhttp.WriteAdminErrorResponse(w, http.StatusNotFound, "WebhookSubscription "+subscription.ID+" does not exist")
+			return
+		}
+		subscription.Created = existing.Created
+		subscription.CreatedBy = existing.CreatedBy
+	}
+	subscription.Name = strings.TrimSpace(subscription.Name)
+	subscription.URL = strings.TrimSpace(subscription.URL)
+	if err := subscription.Validate(); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+
+	secret := request.Secret
+	if create && secret == "" {
+		var err error
+		if secret, err = generateWebhookSecret(); err != nil {
+			xhttp.WriteAdminErrorResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if err := xshared.SetWebhookSubscription(&subscription); err != nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if secret != "" {
+		if err := xshared.SetWebhookSecret(subscription.ID, secret); err != nil {
+			xhttp.WriteAdminErrorResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	var response interface{} = &subscription
+	if create {
+		response = CreatedWebhookSubscription{WebhookSubscription: &subscription, Secret: secret}
+	}
+	res, err := xhttp.ReturnJsonResponse(response, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteResponseBytes(w, res, status, xhttp.ContextTypeHeader(r))
+}
+
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func DeleteWebhookSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	if _, err := auth.CanWrite(r, auth.TOOL_ENTITY); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	id := mux.Vars(r)[xwcommon.ID]
+	if xshared.GetWebhookSubscription(id) == nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusNotFound, "WebhookSubscription does not exist")
+		return
+	}
+	if err := xshared.DeleteWebhookSubscription(id); err != nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusNoContent, nil)
+}
+
+func GetWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	if _, err := auth.CanRead(r, auth.TOOL_ENTITY); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	id := mux.Vars(r)[xwcommon.ID]
+	if xshared.GetWebhookSubscription(id) == nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusNotFound, "WebhookSubscription does not exist")
+		return
+	}
+	res, err := xhttp.ReturnJsonResponse(xshared.GetWebhookDeliveries(id), r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
+
+// TestWebhookSubscriptionHandler sends a test event to the subscription and returns the delivery,
+// a failed delivery is reported in the delivery status
+func TestWebhookSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	if _, err := auth.CanWrite(r, auth.TOOL_ENTITY); err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	subscription := xshared.GetWebhookSubscription(mux.Vars(r)[xwcommon.ID])
+	if subscription == nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusNotFound, "WebhookSubscription does not exist")
+		return
+	}
+	delivery, err := TestWebhook(subscription, auth.GetUserNameOrUnknown(r))
+	if err != nil {
+		xhttp.WriteAdminErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	res, err := xhttp.ReturnJsonResponse(delivery, r)
+	if err != nil {
+		xhttp.AdminError(w, err)
+		return
+	}
+	xwhttp.WriteXconfResponse(w, http.StatusOK, res)
+}
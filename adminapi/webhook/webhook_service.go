@@ -0,0 +1,300 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+
+	xcommon "xconfadmin/common"
+	xshared "xconfadmin/shared"
+	xchange "xconfadmin/shared/change"
+	"xconfwebconfig/util"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	WEBHOOK_SIGNATURE_HEADER = "X-Xconf-Signature"
+	WEBHOOK_EVENT_HEADER     = "X-Xconf-Event"
+	WEBHOOK_DELIVERY_HEADER  = "X-Xconf-Delivery"
+
+	// operation of the event sent by the test endpoint
+	WEBHOOK_TEST_OPERATION = "TEST"
+
+	webhookQueueSize = 1000
+	webhookWorkers   = 4
+	webhookTimeout   = 10 * time.Second
+
+	// a queued delivery which is not finished within the lease is picked up again by the retry check,
+	// e.g. after the instance sending it was restarted
+	webhookDeliveryLease = time.Minute
+)
+
+var (
+	webhookClient = &http.Client{
+		Timeout: webhookTimeout,
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{Timeout: webhookTimeout, Control: checkWebhookAddress}).DialContext,
+		},
+	}
+	eventQueue    chan *xshared.WebhookEvent
+	deliveryQueue chan *xshared.WebhookPendingDelivery
+)
+
+// StartWebhookDispatcher starts the delivery workers, subscribes them to the audited writes and starts the
+// check which retries the due deliveries, the first check recovers the deliveries left pending by a restart
+func StartWebhookDispatcher(retryInterval time.Duration) {
+	eventQueue = make(chan *xshared.WebhookEvent, webhookQueueSize)
+	deliveryQueue = make(chan *xshared.WebhookPendingDelivery, webhookQueueSize)
+	go func() {
+		for event := range eventQueue {
+			dispatchEvent(event)
+		}
+	}()
+	for i := 0; i < webhookWorkers; i++ {
+		go func() {
+			for pending := range deliveryQueue {
+				attemptDelivery(pending)
+			}
+		}()
+	}
+	xshared.AddAuditListener(PublishAuditEntry)
+	go func() {
+		RetryDueDeliveries(time.Now())
+		ticker := time.NewTicker(retryInterval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			RetryDueDeliveries(now)
+		}
+	}()
+	log.Infof("webhook dispatcher is running with %d workers, retries are checked every %v", webhookWorkers, retryInterval)
+}
+
+// PublishAuditEntry queues the event of an audited write, it does not block the write
+func PublishAuditEntry(entry *xshared.AuditEntry) {
+	select {
+	case eventQueue <- xshared.NewWebhookEvent(entry):
+	default:
+		log.Errorf("webhook event queue is full, dropped event %s", entry.ID)
+	}
+}
+
+// dispatchEvent saves a pending delivery of the event for every matching subscription and queues it
+func dispatchEvent(event *xshared.WebhookEvent) {
+	for _, subscription := range xshared.GetMatchingWebhookSubscriptions(event) {
+		pending, err := newPendingDelivery(subscription.ID, event)
+		if err != nil {
+			log.Errorf("unable to create delivery of event %s to webhook %s: %s", event.ID, subscription.ID, err.Error())
+			continue
+		}
+		enqueue(pending)
+	}
+}
+
+func newPendingDelivery(subscriptionId string, event *xshared.WebhookEvent) (*xshared.WebhookPendingDelivery, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	delivery := xshared.NewWebhookDelivery(subscriptionId, event)
+	delivery.NextAttempt = util.GetTimestamp(time.Now().UTC().Add(webhookDeliveryLease))
+	if err := xshared.SetWebhookDelivery(delivery); err != nil {
+		return nil, err
+	}
+	pending := &xshared.WebhookPendingDelivery{ID: delivery.ID, Delivery: delivery, Payload: payload}
+	if err := xshared.SetWebhookPendingDelivery(pending); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+func enqueue(pending *xshared.WebhookPendingDelivery) {
+	select {
+	case deliveryQueue <- pending:
+	default:
+		log.Warnf("webhook delivery queue is full, delivery %s is left to the retry check", pending.ID)
+	}
+}
+
+// RetryDueDeliveries queues the pending deliveries whose next attempt is due and returns their number.
+// Every attempt is claimed first, so with several admin instances only one of them sends it
+func RetryDueDeliveries(now time.Time) int {
+	owner, _ := os.Hostname()
+	nowMillis := util.GetTimestamp(now.UTC())
+	queued := 0
+	for _, pending := range xshared.GetWebhookPendingDeliveries() {
+		delivery := pending.Delivery
+		if delivery.NextAttempt > nowMillis {
+			continue
+		}
+		claimed, err := xchange.Claim(fmt.Sprintf("webhook_%s_%d", delivery.ID, delivery.NextAttempt), owner)
+		if err != nil {
+			log.Errorf("unable to claim webhook delivery %s: %s", delivery.ID, err.Error())
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		delivery.NextAttempt = util.GetTimestamp(now.UTC().Add(webhookDeliveryLease))
+		saveDelivery(delivery)
+		if err := xshared.SetWebhookPendingDelivery(pending); err != nil {
+			log.Errorf("unable to save pending webhook delivery %s: %s", delivery.ID, err.Error())
+			continue
+		}
+		enqueue(pending)
+		queued++
+	}
+	return queued
+}
+
+// attemptDelivery sends the event once, a failed attempt is retried by the retry check after a doubling
+// backoff until the attempts are used up
+func attemptDelivery(pending *xshared.WebhookPendingDelivery) {
+	delivery := pending.Delivery
+	subscription := xshared.GetWebhookSubscription(delivery.SubscriptionID)
+	if subscription == nil {
+		xshared.DeleteWebhookPendingDelivery(pending.ID)
+		return
+	}
+	responseStatus, err := send(subscription, delivery, pending.Payload)
+	delivery.Attempts++
+	delivery.ResponseStatus = responseStatus
+	delivery.NextAttempt = 0
+	if err == nil {
+		delivery.Status = xshared.WEBHOOK_DELIVERY_SUCCESS
+		delivery.Error = ""
+		saveDelivery(delivery)
+		xshared.DeleteWebhookPendingDelivery(pending.ID)
+		return
+	}
+
+	delivery.Error = err.Error()
+	if delivery.Attempts >= xcommon.WebhookMaxAttempts {
+		delivery.Status = xshared.WEBHOOK_DELIVERY_FAILED
+		saveDelivery(delivery)
+		xshared.DeleteWebhookPendingDelivery(pending.ID)
+		log.Warnf("webhook delivery %s to %s failed after %d attempts: %s", delivery.ID, subscription.URL, delivery.Attempts, delivery.Error)
+		return
+	}
+	delivery.NextAttempt = util.GetTimestamp(time.Now().UTC().Add(retryBackoff(delivery.Attempts)))
+	saveDelivery(delivery)
+	if err := xshared.SetWebhookPendingDelivery(pending); err != nil {
+		log.Errorf("unable to save pending webhook delivery %s: %s", delivery.ID, err.Error())
+	}
+}
+
+func retryBackoff(attempts int) time.Duration {
+	backoff := time.Duration(xcommon.WebhookRetryBackoffSeconds) * time.Second
+	if backoff <= 0 {
+		backoff = 10 * time.Second
+	}
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+	}
+	return backoff
+}
+
+func saveDelivery(delivery *xshared.WebhookDelivery) {
+	if err := xshared.SetWebhookDelivery(delivery); err != nil {
+		log.Errorf("unable to save webhook delivery %s: %s", delivery.ID, err.Error())
+	}
+}
+
+// checkWebhookAddress rejects the connections to internal addresses, it is checked on every dial so
+// neither a changed dns record nor a redirect can reach them
+func checkWebhookAddress(network string, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !xshared.IsWebhookAddressAllowed(ip) {
+		return fmt.Errorf("webhook address %s is not allowed", host)
+	}
+	return nil
+}
+
+// send posts the signed payload and returns the response status, any status other than 2xx is an error
+func send(subscription *xshared.WebhookSubscription, delivery *xshared.WebhookDelivery, payload []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, subscription.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WEBHOOK_EVENT_HEADER, delivery.Operation)
+	req.Header.Set(WEBHOOK_DELIVERY_HEADER, delivery.ID)
+	if secret := xshared.GetWebhookSecret(subscription.ID); secret != "" {
+		req.Header.Set(WEBHOOK_SIGNATURE_HEADER, SignPayload(secret, payload))
+	}
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignPayload returns the hex encoded HMAC-SHA256 of the payload, prefixed with sha256=
+func SignPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// TestWebhook sends a test event to the subscription once, without retries, and returns the delivery
+func TestWebhook(subscription *xshared.WebhookSubscription, userName string) (*xshared.WebhookDelivery, error) {
+	event := &xshared.WebhookEvent{
+		ID:         uuid.New().String(),
+		Timestamp:  util.GetTimestamp(time.Now().UTC()),
+		Operation:  WEBHOOK_TEST_OPERATION,
+		EntityType: "Webhooks",
+		EntityID:   subscription.ID,
+		User:       userName,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	delivery := xshared.NewWebhookDelivery(subscription.ID, event)
+	responseStatus, err := send(subscription, delivery, payload)
+	delivery.Attempts = 1
+	delivery.ResponseStatus = responseStatus
+	delivery.Status = xshared.WEBHOOK_DELIVERY_SUCCESS
+	if err != nil {
+		delivery.Status = xshared.WEBHOOK_DELIVERY_FAILED
+		delivery.Error = err.Error()
+	}
+	saveDelivery(delivery)
+	return delivery, nil
+}
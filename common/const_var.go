@@ -22,25 +22,67 @@ var ActiveAuthProfiles string
 var DefaultAuthProfiles string
 var IpMacIsConditionLimit int
 var AllowedNumberOfFeatures int
+var ConfiguredApplicationTypes []string
+var AuditRetentionDays int
+var PendingChangeTtlHours map[string]int
+var PendingChangeSweepIntervalMinutes int
+var ScheduledChangeIntervalSeconds int
+var WebhookMaxAttempts int
+var WebhookRetryBackoffSeconds int
+var WebhookDeliveryRetentionDays int
+var WebhookAllowPrivateNetworks bool
+var FirmwareSimulationMaxDevices int
+var FirmwareSimulationSyncLimit int
+var FirmwareSimulationRetentionDays int
+var PercentageRolloutIntervalSeconds int
 
 const (
 	READONLY_MODE           = "ReadonlyMode"
 	READONLY_MODE_STARTTIME = "ReadonlyModeStartTime"
 	READONLY_MODE_ENDTIME   = "ReadonlyModeEndTime"
+	READONLY_MODE_WINDOWS   = "ReadonlyModeWindows"
+	READONLY_MODE_STATUS    = "ReadonlyModeStatus"
 )
 
 // db
 const (
-	TABLE_APP_SETTINGS = "AppSettings"
+	TABLE_APP_SETTINGS                = "AppSettings"
+	TABLE_APPLICATION_TYPES           = "ApplicationType"
+	TABLE_SERVICE_ACCOUNTS            = "ServiceAccount"
+	TABLE_SERVICE_ACCOUNT_KEYS        = "ServiceAccountKey"
+	TABLE_ENTITY_OWNERS               = "EntityOwner"
+	TABLE_OWNERSHIP_GROUP_MAPPINGS    = "OwnershipGroupMapping"
+	TABLE_AUDIT_LOG                   = "AuditLog"
+	TABLE_ENTITY_CHANGES              = "EntityChange"
+	TABLE_APPROVED_ENTITY_CHANGES     = "ApprovedEntityChange"
+	TABLE_APPROVAL_SETTINGS           = "ApprovalSetting"
+	TABLE_APPROVAL_POLICIES           = "ApprovalPolicy"
+	TABLE_CHANGE_APPROVALS            = "ChangeApprovals"
+	TABLE_SCHEDULED_CHANGES           = "ScheduledChange"
+	TABLE_SCHEDULED_CHANGE_CLAIMS     = "ScheduledChangeClaim"
+	TABLE_ENTITY_WRITE_LOCKS          = "EntityWriteLock"
+	TABLE_CHANGE_COMMENTS             = "ChangeComment"
+	TABLE_CHANGE_REVIEWS              = "ChangeReview"
+	TABLE_WEBHOOK_SUBSCRIPTIONS       = "WebhookSubscription"
+	TABLE_WEBHOOK_SECRETS             = "WebhookSecret"
+	TABLE_WEBHOOK_DELIVERIES          = "WebhookDelivery"
+	TABLE_WEBHOOK_PENDING_DELIVERIES  = "WebhookPendingDelivery"
+	TABLE_FIRMWARE_SIMULATIONS        = "FirmwareSimulation"
+	TABLE_FIRMWARE_SIMULATION_RESULTS = "FirmwareSimulationResult"
+	TABLE_PERCENTAGE_ROLLOUTS         = "PercentageRollout"
+	TABLE_FIRMWARE_CONFIG_LIFECYCLES  = "FirmwareConfigLifecycle"
+	TABLE_FIRMWARE_VERSION_PATTERNS   = "FirmwareVersionPattern"
 )
 
 const (
 	APPROVE_ID             = "approveId"
 	CHANGE_ID              = "changeId"
+	EFFECTIVE_AT           = "effectiveAt"
 	PAGE_NUMBER            = "pageNumber"
 	PAGE_SIZE              = "pageSize"
 	AUTHOR                 = "AUTHOR"
 	ENTITY                 = "ENTITY"
+	ENTITY_TYPE_UPPER      = "ENTITY_TYPE"
 	PROFILE_NAME           = "profilename"
 	NAME_UPPER             = "NAME"
 	EXPORT                 = "export"
@@ -57,6 +99,7 @@ const (
 	MAC_ADDRESS            = "macAddress"
 	SCHEDULE_TIME_ZONE     = "scheduleTimezone"
 	EXPORTALL              = "exportAll"
+	WARNINGS               = "warnings"
 	TYPE_UPPER             = "TYPE"
 	DATA_UPPER             = "DATA"
 	ROW_KEY                = "rowKey"
@@ -64,12 +107,15 @@ const (
 	IP_ADDRESS_GROUP_NAME  = "ipAddressGroupName"
 	EDITABLE               = "isEditable"
 	APPLICABLE_ACTION_TYPE = "APPLICABLE_ACTION_TYPE"
+	OWNER                  = "OWNER"
+	OWNER_GROUP            = "ownerGroup"
 )
 
 var AllAppSettings = []string{
 	READONLY_MODE,
 	READONLY_MODE_STARTTIME,
 	READONLY_MODE_ENDTIME,
+	READONLY_MODE_WINDOWS,
 }
 
 const (
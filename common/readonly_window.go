@@ -0,0 +1,267 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package common
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	ds "xconfwebconfig/db"
+	"xconfwebconfig/shared"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	RECURRENCE_NONE   = ""
+	RECURRENCE_WEEKLY = "WEEKLY"
+
+	readonlyScheduledWindowName = "scheduled"
+	readonlyManualWindowName    = "manual"
+	readonlyTimeLayout          = "2006-01-02 15:04:05"
+	week                        = 7 * 24 * time.Hour
+)
+
+// ReadonlyWindow is a change-freeze window, a WEEKLY window repeats every week starting from StartTime
+type ReadonlyWindow struct {
+	Name       string `json:"name"`
+	StartTime  int64  `json:"startTime"`
+	EndTime    int64  `json:"endTime"`
+	Recurrence string `json:"recurrence,omitempty"`
+}
+
+// ReadonlyWindowOccurrence is one occurrence of a ReadonlyWindow
+type ReadonlyWindowOccurrence struct {
+	Name       string `json:"name"`
+	StartTime  int64  `json:"startTime"`
+	EndTime    int64  `json:"endTime,omitempty"`
+	Recurrence string `json:"recurrence,omitempty"`
+}
+
+func (o *ReadonlyWindowOccurrence) String() string {
+	if o.EndTime == 0 {
+		return fmt.Sprintf("'%s'", o.Name)
+	}
+	return fmt.Sprintf("'%s' (%s - %s UTC)", o.Name,
+		time.UnixMilli(o.StartTime).UTC().Format(readonlyTimeLayout),
+		time.UnixMilli(o.EndTime).UTC().Format(readonlyTimeLayout))
+}
+
+func (w *ReadonlyWindow) Validate() error {
+	if strings.TrimSpace(w.Name) == "" {
+		return NewXconfError(http.StatusBadRequest, "Readonly window name is empty")
+	}
+	if w.StartTime <= 0 || w.EndTime <= 0 {
+		return NewXconfError(http.StatusBadRequest, fmt.Sprintf("Readonly window %s must have startTime and endTime", w.Name))
+	}
+	if w.EndTime <= w.StartTime {
+		return NewXconfError(http.StatusBadRequest, fmt.Sprintf("Readonly window %s endTime must be after startTime", w.Name))
+	}
+	switch w.Recurrence {
+	case RECURRENCE_NONE:
+	case RECURRENCE_WEEKLY:
+		if time.Duration(w.EndTime-w.StartTime)*time.Millisecond >= week {
+			return NewXconfError(http.StatusBadRequest, fmt.Sprintf("Weekly readonly window %s must be shorter than a week", w.Name))
+		}
+	default:
+		return NewXconfError(http.StatusBadRequest, fmt.Sprintf("Readonly window %s has invalid recurrence %s", w.Name, w.Recurrence))
+	}
+	return nil
+}
+
+// occurrenceAt returns the occurrence active at now, or the next one if none is active
+func (w *ReadonlyWindow) occurrenceAt(now time.Time) (*ReadonlyWindowOccurrence, bool) {
+	nowMillis := now.UnixMilli()
+	start, end := w.StartTime, w.EndTime
+	if w.Recurrence == RECURRENCE_WEEKLY && nowMillis >= end {
+		weekMillis := week.Milliseconds()
+		shift := (nowMillis - start) / weekMillis * weekMillis
+		start, end = start+shift, end+shift
+		if nowMillis >= end {
+			start, end = start+weekMillis, end+weekMillis
+		}
+	}
+	occurrence := &ReadonlyWindowOccurrence{
+		Name:       w.Name,
+		StartTime:  start,
+		EndTime:    end,
+		Recurrence: w.Recurrence,
+	}
+	if nowMillis >= end {
+		return nil, false
+	}
+	return occurrence, nowMillis >= start
+}
+
+// ParseReadonlyTime accepts epoch milliseconds, RFC3339 or "yyyy-MM-dd HH:mm:ss" in UTC
+func ParseReadonlyTime(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case string:
+		v = strings.TrimSpace(v)
+		if v == "" {
+			return 0, nil
+		}
+		if millis, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return millis, nil
+		}
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t.UnixMilli(), nil
+		}
+		if t, err := time.Parse(readonlyTimeLayout, v); err == nil {
+			return t.UnixMilli(), nil
+		}
+		return 0, fmt.Errorf("invalid time %s, expected epoch millis, RFC3339 or %s", v, readonlyTimeLayout)
+	}
+	return 0, fmt.Errorf("invalid time %v", value)
+}
+
+// ParseReadonlyWindows converts the ReadonlyModeWindows app setting value into windows
+func ParseReadonlyWindows(value interface{}) ([]ReadonlyWindow, error) {
+	windows := []ReadonlyWindow{}
+	if value == nil {
+		return windows, nil
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, NewXconfError(http.StatusBadRequest, READONLY_MODE_WINDOWS+" must be a list")
+	}
+	for _, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, NewXconfError(http.StatusBadRequest, READONLY_MODE_WINDOWS+" must be a list of objects")
+		}
+		window := ReadonlyWindow{}
+		window.Name, _ = m["name"].(string)
+		window.Recurrence, _ = m["recurrence"].(string)
+		window.Recurrence = strings.ToUpper(window.Recurrence)
+		var err error
+		if window.StartTime, err = ParseReadonlyTime(m["startTime"]); err != nil {
+			return nil, NewXconfError(http.StatusBadRequest, err.Error())
+		}
+		if window.EndTime, err = ParseReadonlyTime(m["endTime"]); err != nil {
+			return nil, NewXconfError(http.StatusBadRequest, err.Error())
+		}
+		if err := window.Validate(); err != nil {
+			return nil, err
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// ValidateReadonlyAppSetting validates the value of a readonly app setting before it is saved
+func ValidateReadonlyAppSetting(key string, value interface{}) error {
+	switch key {
+	case READONLY_MODE:
+		if _, ok := value.(bool); !ok {
+			return NewXconfError(http.StatusBadRequest, READONLY_MODE+" must be boolean")
+		}
+	case READONLY_MODE_STARTTIME, READONLY_MODE_ENDTIME:
+		if _, err := ParseReadonlyTime(value); err != nil {
+			return NewXconfError(http.StatusBadRequest, key+": "+err.Error())
+		}
+	case READONLY_MODE_WINDOWS:
+		if _, err := ParseReadonlyWindows(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getAppSettingValue(key string) interface{} {
+	inst, err := ds.GetCachedSimpleDao().GetOne(TABLE_APP_SETTINGS, key)
+	if err != nil {
+		return nil
+	}
+	return inst.(*shared.AppSetting).Value
+}
+
+// GetReadonlyWindows returns the window defined by ReadonlyModeStartTime/EndTime and the ReadonlyModeWindows
+func GetReadonlyWindows() []ReadonlyWindow {
+	windows := []ReadonlyWindow{}
+
+	start, startErr := ParseReadonlyTime(getAppSettingValue(READONLY_MODE_STARTTIME))
+	end, endErr := ParseReadonlyTime(getAppSettingValue(READONLY_MODE_ENDTIME))
+	if startErr == nil && endErr == nil && start > 0 && end > start {
+		windows = append(windows, ReadonlyWindow{
+			Name:      readonlyScheduledWindowName,
+			StartTime: start,
+			EndTime:   end,
+		})
+	}
+
+	list, err := ParseReadonlyWindows(getAppSettingValue(READONLY_MODE_WINDOWS))
+	if err != nil {
+		log.Errorf("invalid %s: %s", READONLY_MODE_WINDOWS, err.Error())
+	}
+	return append(windows, list...)
+}
+
+// GetActiveReadonlyWindow returns the freeze which blocks modifications at the given time, nil if none
+func GetActiveReadonlyWindow(now time.Time) *ReadonlyWindowOccurrence {
+	if GetBooleanAppSetting(READONLY_MODE, false) {
+		return &ReadonlyWindowOccurrence{Name: readonlyManualWindowName}
+	}
+	for _, window := range GetReadonlyWindows() {
+		window := window
+		if occurrence, active := window.occurrenceAt(now); active {
+			return occurrence
+		}
+	}
+	return nil
+}
+
+// GetUpcomingReadonlyWindows returns the next occurrence of every window which is not active yet
+func GetUpcomingReadonlyWindows(now time.Time) []*ReadonlyWindowOccurrence {
+	result := []*ReadonlyWindowOccurrence{}
+	for _, window := range GetReadonlyWindows() {
+		window := window
+		if occurrence, active := window.occurrenceAt(now); occurrence != nil && !active {
+			result = append(result, occurrence)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StartTime < result[j].StartTime
+	})
+	return result
+}
+
+// ReadonlyWindowsStatus is returned with the app settings
+type ReadonlyWindowsStatus struct {
+	Active   *ReadonlyWindowOccurrence   `json:"active"`
+	Upcoming []*ReadonlyWindowOccurrence `json:"upcoming"`
+}
+
+func GetReadonlyWindowsStatus(now time.Time) *ReadonlyWindowsStatus {
+	return &ReadonlyWindowsStatus{
+		Active:   GetActiveReadonlyWindow(now),
+		Upcoming: GetUpcomingReadonlyWindows(now),
+	}
+}
@@ -0,0 +1,74 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package common
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestReadonlyWindowOccurrence(t *testing.T) {
+	start := time.Date(2024, 1, 5, 22, 0, 0, 0, time.UTC)
+	window := ReadonlyWindow{
+		Name:       "weekend",
+		StartTime:  start.UnixMilli(),
+		EndTime:    start.Add(4 * time.Hour).UnixMilli(),
+		Recurrence: RECURRENCE_WEEKLY,
+	}
+	assert.NilError(t, window.Validate())
+
+	// before the first occurrence
+	occurrence, active := window.occurrenceAt(start.Add(-time.Hour))
+	assert.Assert(t, !active)
+	assert.Equal(t, occurrence.StartTime, start.UnixMilli())
+
+	// inside an occurrence three weeks later
+	occurrence, active = window.occurrenceAt(start.Add(3*week + time.Hour))
+	assert.Assert(t, active)
+	assert.Equal(t, occurrence.StartTime, start.Add(3*week).UnixMilli())
+
+	// after an occurrence, the next week is upcoming
+	occurrence, active = window.occurrenceAt(start.Add(3*week + 5*time.Hour))
+	assert.Assert(t, !active)
+	assert.Equal(t, occurrence.StartTime, start.Add(4*week).UnixMilli())
+
+	// a one-off window is over once it ends
+	window.Recurrence = RECURRENCE_NONE
+	occurrence, active = window.occurrenceAt(start.Add(5 * time.Hour))
+	assert.Assert(t, occurrence == nil && !active)
+}
+
+func TestParseReadonlyWindows(t *testing.T) {
+	value := []interface{}{
+		map[string]interface{}{
+			"name":       "release",
+			"startTime":  "2024-01-05T22:00:00Z",
+			"endTime":    float64(time.Date(2024, 1, 6, 2, 0, 0, 0, time.UTC).UnixMilli()),
+			"recurrence": "weekly",
+		},
+	}
+	windows, err := ParseReadonlyWindows(value)
+	assert.NilError(t, err)
+	assert.Equal(t, len(windows), 1)
+	assert.Equal(t, windows[0].Recurrence, RECURRENCE_WEEKLY)
+
+	_, err = ParseReadonlyWindows([]interface{}{map[string]interface{}{"name": "bad", "startTime": "2024-01-05", "endTime": "x"}})
+	assert.Assert(t, err != nil)
+}
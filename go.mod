@@ -7,6 +7,7 @@ replace xconfwebconfig => github.com/rdkcentral/xconfwebconfig v0.0.0-2024100309
 require (
 	github.com/360EntSecGroup-Skylar/excelize v1.4.1
 	github.com/dchest/siphash v1.2.2
+	github.com/gocql/gocql v0.0.0-20210129204804-4364a4b9cfdd
 	github.com/golang-jwt/jwt/v4 v4.4.2
 	github.com/google/uuid v1.1.2
 	github.com/gorilla/mux v1.7.0
@@ -28,7 +29,6 @@ require (
 	github.com/carlescere/scheduler v0.0.0-20170109141437-ee74d2f83d82 // indirect
 	github.com/cespare/xxhash/v2 v2.1.2 // indirect
 	github.com/go-akka/configuration v0.0.0-20200606091224-a002c0330665 // indirect
-	github.com/gocql/gocql v0.0.0-20210129204804-4364a4b9cfdd // indirect
 	github.com/golang/protobuf v1.5.2 // indirect
 	github.com/golang/snappy v0.0.3 // indirect
 	github.com/google/go-cmp v0.5.8 // indirect
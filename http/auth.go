@@ -18,12 +18,17 @@
 package http
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
 	"net/http"
+	"net/url"
 	"strings"
 
+	xshared "xconfadmin/shared"
+	"xconfwebconfig/util"
+
 	"github.com/golang-jwt/jwt/v4"
 	log "github.com/sirupsen/logrus"
 )
@@ -32,12 +37,18 @@ const (
 	AUTHORIZATION = "Authorization"
 	AUTH_TOKEN    = "token"
 	AUTH_SUBJECT  = "X-Auth-Subject"
+	API_KEY       = "X-Api-Key"
 	RequestID     = "X-Request-ID"
 	UNKNOWN_USER  = "UNKNOWN_USER"
 
 	KeysBaseURL = "https://sat-sample-url.net"
+
+	// LegacyLoginTokenSecret signs login tokens in the dev profile only
+	LegacyLoginTokenSecret = "xconf"
 )
 
+var legacyLoginTokenEnabled bool
+
 type AuthCtxKey string
 
 func (c AuthCtxKey) String() string {
@@ -48,6 +59,12 @@ const (
 	CTX_KEY_TOKEN        AuthCtxKey = "Token"
 	CTX_KEY_PERMISSIONS  AuthCtxKey = "Permissions"
 	CTX_KEY_CAPABILITIES AuthCtxKey = "Capabilities"
+
+	CTX_KEY_SERVICE_ACCOUNT AuthCtxKey = "ServiceAccount"
+	CTX_KEY_API_KEY         AuthCtxKey = "ApiKey"
+
+	// subject of requests authenticated with a service account api key
+	ServiceAccountSubjectPrefix = "serviceaccount:"
 )
 
 type LoginToken struct {
@@ -64,6 +81,9 @@ type LoginToken struct {
 	PartnerId      string
 	Email          string
 	Application    []Application
+	// ownership groups and admin flag from the ownershipGroups and ownershipAdmin claims
+	OwnershipGroups []string
+	OwnershipAdmin  bool
 }
 
 type Application struct {
@@ -96,17 +116,45 @@ func NewAuthResponse(r *http.Request) *AuthResponse {
 		groups[i] = app.Role
 		permissions = append(permissions, app.Rights...)
 	}
+	ownershipGroups, ownershipAdmin := GetOwnershipGroups(r)
 	authResponse := &AuthResponse{
 		FirstName:       LoginToken.FirstName,
 		LastName:        LoginToken.LastName,
 		Username:        LoginToken.Subject,
 		Groups:          groups,
 		Permissions:     permissions,
-		OwnershipGroups: []string{},
+		OwnershipGroups: ownershipGroups,
+		OwnershipAdmin:  ownershipAdmin,
 	}
 	return authResponse
 }
 
+// GetOwnershipGroups returns the ownership groups of the user from the login token claims
+// merged with the local ownership group mapping, and whether the user is an ownership admin
+func GetOwnershipGroups(r *http.Request) ([]string, bool) {
+	groups := []string{}
+	admin := false
+	userName := r.Header.Get(AUTH_SUBJECT)
+	if token := GetLoginTokenFromContext(r); token != nil {
+		groups = append(groups, token.OwnershipGroups...)
+		admin = token.OwnershipAdmin
+		if userName == "" {
+			userName = token.Subject
+		}
+	}
+	if userName != "" {
+		if mapping := xshared.GetOwnershipGroupMapping(userName); mapping != nil {
+			for _, group := range mapping.Groups {
+				if !util.Contains(groups, group) {
+					groups = append(groups, group)
+				}
+			}
+			admin = admin || mapping.Admin
+		}
+	}
+	return groups, admin
+}
+
 func NewErasedAuthTokenCookie() *http.Cookie {
 	c := &http.Cookie{
 		Name:   AUTH_TOKEN,
@@ -154,23 +202,101 @@ func GetCapabilitiesFromContext(r *http.Request) []string {
 	return capabilities.([]string)
 }
 
+// NewBackgroundRequest builds the request of a background job acting on behalf of a user,
+// with the permissions and capabilities the user had when the job was created
+func NewBackgroundRequest(userName string, applicationType string, permissions []string, capabilities []string) *http.Request {
+	query := url.Values{}
+	query.Set("applicationType", applicationType)
+	r, _ := http.NewRequest(http.MethodPost, "/?"+query.Encode(), nil)
+	r.Header.Set(AUTH_SUBJECT, userName)
+	ctx := context.WithValue(r.Context(), CTX_KEY_PERMISSIONS, permissions)
+	if len(capabilities) > 0 {
+		ctx = context.WithValue(ctx, CTX_KEY_CAPABILITIES, capabilities)
+	}
+	return r.WithContext(ctx)
+}
+
+// NewServiceAccountBackgroundRequest builds the request of a background job acting on behalf of a service account,
+// with the current permissions of its api key
+func NewServiceAccountBackgroundRequest(account *xshared.ServiceAccount, key *xshared.ServiceAccountKey, applicationType string) *http.Request {
+	r := NewBackgroundRequest(ServiceAccountSubjectPrefix+account.Name, applicationType, key.Permissions, nil)
+	return r.WithContext(contextWithServiceAccount(r.Context(), account, key))
+}
+
+// EnableLegacyLoginToken allows HS256 login tokens signed with the built-in dev secret,
+// only meant for the dev profile when no verification key is configured
+func EnableLegacyLoginToken(enabled bool) {
+	legacyLoginTokenEnabled = enabled
+}
+
+// IsLegacyLoginTokenEnabled returns true if HS256 login tokens signed with the dev secret are accepted
+func IsLegacyLoginTokenEnabled() bool {
+	return legacyLoginTokenEnabled && !IsLoginTokenKeyConfigured()
+}
+
+// GetServiceAccountFromContext returns the service account of a request authenticated with an api key
+func GetServiceAccountFromContext(r *http.Request) *xshared.ServiceAccount {
+	account := r.Context().Value(CTX_KEY_SERVICE_ACCOUNT)
+	if account == nil {
+		return nil
+	}
+	return account.(*xshared.ServiceAccount)
+}
+
+// GetApiKeyFromContext returns the api key a request is authenticated with
+func GetApiKeyFromContext(r *http.Request) *xshared.ServiceAccountKey {
+	key := r.Context().Value(CTX_KEY_API_KEY)
+	if key == nil {
+		return nil
+	}
+	return key.(*xshared.ServiceAccountKey)
+}
+
+func IsServiceAccountRequest(r *http.Request) bool {
+	return GetServiceAccountFromContext(r) != nil
+}
+
+// ContextWithApiKey validates the api key and returns the context with the service account and the key's permissions
+func ContextWithApiKey(ctx context.Context, r *http.Request, apiKey string) (context.Context, error) {
+	account, key, err := xshared.ValidateApiKey(apiKey)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set(AUTH_SUBJECT, ServiceAccountSubjectPrefix+account.Name)
+	// Add service account & key permissions to request context
+	return contextWithServiceAccount(ctx, account, key), nil
+}
+
+func contextWithServiceAccount(ctx context.Context, account *xshared.ServiceAccount, key *xshared.ServiceAccountKey) context.Context {
+	ctx = context.WithValue(ctx, CTX_KEY_SERVICE_ACCOUNT, account)
+	ctx = context.WithValue(ctx, CTX_KEY_API_KEY, key)
+	return context.WithValue(ctx, CTX_KEY_PERMISSIONS, key.Permissions)
+}
+
 func ValidateAndGetLoginToken(authToken string) (*LoginToken, error) {
 	if authToken == "" {
 		return nil, errors.New("auth token is empty")
 	}
 
-	// first parse without validation to get the public key information
-	jwtToken, _ := jwt.Parse(authToken, nil)
-	if jwtToken == nil {
-		return nil, errors.New("error parsing auth token")
+	if IsLoginTokenKeyConfigured() {
+		claims, err := loginTokenValidator.Validate(authToken)
+		if err != nil {
+			return nil, fmt.Errorf("error validating auth token with public key: %s", err.Error())
+		}
+		return NewLoginToken(claims), nil
+	}
+
+	if !legacyLoginTokenEnabled {
+		return nil, errors.New("login token verification key is not configured")
 	}
 
-	// parse and validate
-	token, err := jwt.Parse(authToken, func(token *jwt.Token) (interface{}, error) {
-		return []byte("xconf"), nil
+	// parse and validate with dev secret
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	token, err := parser.Parse(authToken, func(token *jwt.Token) (interface{}, error) {
+		return []byte(LegacyLoginTokenSecret), nil
 	})
 	if err != nil {
-		return nil, fmt.Errorf("error parsing auth token with public key: %s", err.Error())
+		return nil, fmt.Errorf("error parsing auth token: %s", err.Error())
 	}
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok || !token.Valid {
@@ -259,6 +385,16 @@ func NewLoginToken(claims jwt.MapClaims) *LoginToken {
 	if email, ok := claims["email"].(string); ok {
 		LoginToken.Email = email
 	}
+	if ownershipGroups, ok := claims["ownershipGroups"].([]interface{}); ok {
+		for _, group := range ownershipGroups {
+			if g, ok := group.(string); ok && g != "" {
+				LoginToken.OwnershipGroups = append(LoginToken.OwnershipGroups, g)
+			}
+		}
+	}
+	if ownershipAdmin, ok := claims["ownershipAdmin"].(bool); ok {
+		LoginToken.OwnershipAdmin = ownershipAdmin
+	}
 	return LoginToken
 }
 
@@ -267,6 +403,10 @@ func getSatTokenFromRequest(r *http.Request) string {
 	return r.Header.Get(AUTHORIZATION)
 }
 
+func getApiKeyFromRequest(r *http.Request) string {
+	return r.Header.Get(API_KEY)
+}
+
 func getLoginTokenFromRequest(r *http.Request) string {
 	authToken := r.Header.Get(AUTH_TOKEN)
 	if authToken == "" {
@@ -0,0 +1,143 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	xwhttp "xconfwebconfig/http"
+)
+
+const (
+	DRY_RUN        = "dryRun"
+	DRY_RUN_HEADER = "X-Dry-Run"
+)
+
+// operations of DryRunChange
+const (
+	DRY_RUN_CREATE = "CREATE"
+	DRY_RUN_UPDATE = "UPDATE"
+	DRY_RUN_DELETE = "DELETE"
+)
+
+// DryRunChange is a write the request would have made
+type DryRunChange struct {
+	Operation string      `json:"operation"`
+	TableName string      `json:"tableName"`
+	EntityID  string      `json:"entityId"`
+	Entity    interface{} `json:"entity,omitempty"`
+}
+
+// DryRun collects the writes of a request called with dryRun=true, the services validate the entities
+// as usual and record the writes instead of saving them
+type DryRun struct {
+	DryRun           bool           `json:"dryRun"`
+	ApprovalRequired bool           `json:"approvalRequired,omitempty"`
+	Result           interface{}    `json:"result,omitempty"`
+	Changes          []DryRunChange `json:"changes"`
+}
+
+// NewDryRun returns nil unless the request asks for a dry run, so a nil DryRun means the writes are saved
+func NewDryRun(r *http.Request) *DryRun {
+	if !IsDryRun(r) {
+		return nil
+	}
+	return NewValidationDryRun()
+}
+
+// NewValidationDryRun returns a dry run which is not asked for by the request, e.g. to validate a change before
+// it is stored for approval
+func NewValidationDryRun() *DryRun {
+	return &DryRun{
+		DryRun:  true,
+		Changes: []DryRunChange{},
+	}
+}
+
+func IsDryRun(r *http.Request) bool {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get(DRY_RUN))
+	return dryRun
+}
+
+func (d *DryRun) Record(operation string, tableName string, id string, entity interface{}) {
+	d.Changes = append(d.Changes, DryRunChange{
+		Operation: operation,
+		TableName: tableName,
+		EntityID:  id,
+		Entity:    entity,
+	})
+}
+
+// Saved returns true if an earlier write of the dry run creates or updates the entity, so the entries
+// of a batch are checked against the earlier entries as if those were saved
+func (d *DryRun) Saved(tableName string, id string) bool {
+	if d == nil {
+		return false
+	}
+	for i := len(d.Changes) - 1; i >= 0; i-- {
+		if change := d.Changes[i]; change.TableName == tableName && change.EntityID == id {
+			return change.Operation != DRY_RUN_DELETE
+		}
+	}
+	return false
+}
+
+// Pending returns the latest entity the dry run writes for every id of the table, nil for a deleted entity
+func (d *DryRun) Pending(tableName string) map[string]interface{} {
+	pending := map[string]interface{}{}
+	if d == nil {
+		return pending
+	}
+	for _, change := range d.Changes {
+		if change.TableName != tableName {
+			continue
+		}
+		if change.Operation == DRY_RUN_DELETE {
+			pending[change.EntityID] = nil
+		} else {
+			pending[change.EntityID] = change.Entity
+		}
+	}
+	return pending
+}
+
+// RecordSave records the create of a new entity or the update of a stored one
+func (d *DryRun) RecordSave(tableName string, id string, entity interface{}, exists bool) {
+	operation := DRY_RUN_CREATE
+	if exists {
+		operation = DRY_RUN_UPDATE
+	}
+	d.Record(operation, tableName, id, entity)
+}
+
+// WriteDryRunResponse returns the recorded writes, a dry run which passed the validation always returns 200
+func WriteDryRunResponse(w http.ResponseWriter, r *http.Request, dryRun *DryRun) {
+	response, err := ReturnJsonResponse(dryRun, r)
+	if err != nil {
+		AdminError(w, err)
+		return
+	}
+	w.Header().Set(DRY_RUN_HEADER, "true")
+	xwhttp.WriteXconfResponse(w, http.StatusOK, response)
+}
+
+// IsDryRunHonored returns true if the handler answered with the writes of a dry run instead of saving them
+func IsDryRunHonored(w http.ResponseWriter) bool {
+	return w.Header().Get(DRY_RUN_HEADER) == "true"
+}
@@ -0,0 +1,53 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package http
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestDryRunSavedAndPending(t *testing.T) {
+	var none *DryRun
+	assert.Assert(t, !none.Saved("table", "id"))
+	assert.Equal(t, len(none.Pending("table")), 0)
+
+	dryRun := NewDryRun(httptest.NewRequest("POST", "/entities?dryRun=true", nil))
+	assert.Assert(t, dryRun != nil)
+	dryRun.RecordSave("table", "a", "a1", false)
+	dryRun.RecordSave("table", "b", "b1", true)
+	dryRun.RecordSave("other", "c", "c1", false)
+	dryRun.RecordSave("table", "a", "a2", true)
+	dryRun.Record(DRY_RUN_DELETE, "table", "b", nil)
+
+	assert.Assert(t, dryRun.Saved("table", "a"))
+	assert.Assert(t, !dryRun.Saved("table", "b"))
+	assert.Assert(t, !dryRun.Saved("table", "c"))
+	assert.Assert(t, dryRun.Saved("other", "c"))
+
+	pending := dryRun.Pending("table")
+	assert.Equal(t, len(pending), 2)
+	assert.Equal(t, pending["a"], "a2")
+	entity, ok := pending["b"]
+	assert.Assert(t, ok)
+	assert.Assert(t, entity == nil)
+
+	assert.Assert(t, NewDryRun(httptest.NewRequest("POST", "/entities?dryRun=false", nil)) == nil)
+}
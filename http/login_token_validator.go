@@ -0,0 +1,377 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultJwksRefreshInterval    = 60 * time.Minute
+	defaultJwksMinRefreshInterval = 30 * time.Second
+)
+
+var (
+	loginTokenValidator *LoginTokenValidator
+
+	// ErrNoLoginTokenKey indicates that no key matches the "kid" of the login token
+	ErrNoLoginTokenKey = errors.New("no verification key found for login token")
+)
+
+// LoginTokenValidator verifies admin login tokens against RS256/ES256 public keys
+// published as a JWKS document (file or url). Keys are cached by kid and
+// reloaded periodically or when a token signed with an unknown kid arrives.
+type LoginTokenValidator struct {
+	Client             *http.Client
+	JwksURL            string
+	JwksFile           string
+	Audience           string
+	Issuer             string
+	Leeway             time.Duration
+	RefreshInterval    time.Duration
+	MinRefreshInterval time.Duration
+
+	mutex    sync.RWMutex
+	keys     map[string]interface{}
+	loadedAt time.Time
+}
+
+// JsonWebKey is a single entry of a JWKS document
+type JsonWebKey struct {
+	Kty string   `json:"kty"`
+	Kid string   `json:"kid"`
+	Use string   `json:"use,omitempty"`
+	Alg string   `json:"alg,omitempty"`
+	N   string   `json:"n,omitempty"`
+	E   string   `json:"e,omitempty"`
+	Crv string   `json:"crv,omitempty"`
+	X   string   `json:"x,omitempty"`
+	Y   string   `json:"y,omitempty"`
+	X5c []string `json:"x5c,omitempty"`
+}
+
+type JsonWebKeySet struct {
+	Keys []JsonWebKey `json:"keys"`
+}
+
+// IsLoginTokenKeyConfigured returns true if login tokens are verified against public keys
+func IsLoginTokenKeyConfigured() bool {
+	return loginTokenValidator != nil && loginTokenValidator.IsConfigured()
+}
+
+// ValidateLoginTokenConfig checks that login token verification is fully configured
+func ValidateLoginTokenConfig() error {
+	if !IsLoginTokenKeyConfigured() {
+		return errors.New("login token verification key is not configured, set xconfwebconfig.login.jwks_file or xconfwebconfig.login.jwks_url")
+	}
+	if strings.TrimSpace(loginTokenValidator.Audience) == "" {
+		return errors.New("login token audience is not configured, set xconfwebconfig.login.audience")
+	}
+	if strings.TrimSpace(loginTokenValidator.Issuer) == "" {
+		return errors.New("login token issuer is not configured, set xconfwebconfig.login.issuer")
+	}
+	if _, err := loginTokenValidator.reloadKeys(); err != nil {
+		return fmt.Errorf("unable to load login token verification keys: %s", err.Error())
+	}
+	return nil
+}
+
+func newLoginTokenValidator(jwksFile string, jwksUrl string, audience string, issuer string, leewaySeconds int64, refreshMinutes int64) *LoginTokenValidator {
+	v := &LoginTokenValidator{
+		Client:             http.DefaultClient,
+		JwksFile:           strings.TrimSpace(jwksFile),
+		JwksURL:            strings.TrimSpace(jwksUrl),
+		Audience:           strings.TrimSpace(audience),
+		Issuer:             strings.TrimSpace(issuer),
+		Leeway:             time.Duration(leewaySeconds) * time.Second,
+		RefreshInterval:    defaultJwksRefreshInterval,
+		MinRefreshInterval: defaultJwksMinRefreshInterval,
+		keys:               make(map[string]interface{}),
+	}
+	if refreshMinutes > 0 {
+		v.RefreshInterval = time.Duration(refreshMinutes) * time.Minute
+	}
+	return v
+}
+
+func (v *LoginTokenValidator) IsConfigured() bool {
+	return v.JwksFile != "" || v.JwksURL != ""
+}
+
+// Validate verifies the signature and the exp, nbf, aud and iss claims of the token
+func (v *LoginTokenValidator) Validate(authToken string) (jwt.MapClaims, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}),
+		jwt.WithoutClaimsValidation(),
+	)
+	claims := jwt.MapClaims{}
+	token, err := parser.ParseWithClaims(authToken, claims, v.fetchKey)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("login token signature is invalid")
+	}
+	if err := v.validateClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (v *LoginTokenValidator) validateClaims(claims jwt.MapClaims) error {
+	now := time.Now().Unix()
+	leeway := int64(v.Leeway.Seconds())
+	var issues []string
+
+	if _, ok := claims["exp"]; !ok {
+		issues = append(issues, "expiration time is missing")
+	} else if !claims.VerifyExpiresAt(now-leeway, true) {
+		issues = append(issues, "token has already expired")
+	}
+	if !claims.VerifyNotBefore(now+leeway, false) {
+		issues = append(issues, "token is not yet valid")
+	}
+	if !claims.VerifyIssuedAt(now+leeway, false) {
+		issues = append(issues, "cannot use token before it has been issued")
+	}
+	if v.Audience != "" && !claims.VerifyAudience(v.Audience, true) {
+		issues = append(issues, "audience is not accepted")
+	}
+	if v.Issuer != "" && !claims.VerifyIssuer(v.Issuer, true) {
+		issues = append(issues, "issuer is not accepted")
+	}
+	if len(issues) > 0 {
+		return ErrInvalidToken{issues}
+	}
+	return nil
+}
+
+func (v *LoginTokenValidator) fetchKey(token *jwt.Token) (interface{}, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok || kid == "" {
+		return nil, ErrNoKIDParameter
+	}
+
+	v.mutex.RLock()
+	key, found := v.keys[kid]
+	expired := time.Since(v.loadedAt) > v.RefreshInterval
+	v.mutex.RUnlock()
+
+	if !found || expired {
+		keys, err := v.reloadKeys()
+		if err != nil {
+			if found {
+				log.Warnf("unable to refresh login token keys, using cached key %s: %s", kid, err.Error())
+				return checkKeyType(token, key)
+			}
+			return nil, err
+		}
+		if key, found = keys[kid]; !found {
+			return nil, ErrNoLoginTokenKey
+		}
+	}
+	return checkKeyType(token, key)
+}
+
+func checkKeyType(token *jwt.Token, key interface{}) (interface{}, error) {
+	switch key.(type) {
+	case *rsa.PublicKey:
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); ok {
+			return key, nil
+		}
+	case *ecdsa.PublicKey:
+		if _, ok := token.Method.(*jwt.SigningMethodECDSA); ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("signing method %s does not match key type", token.Method.Alg())
+}
+
+// reloadKeys replaces the cached keys with the current JWKS content, so that
+// keys removed from the set are no longer accepted
+func (v *LoginTokenValidator) reloadKeys() (map[string]interface{}, error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	if time.Since(v.loadedAt) < v.MinRefreshInterval {
+		return v.keys, nil
+	}
+
+	data, err := v.readJwks()
+	if err != nil {
+		return nil, err
+	}
+	keys, err := parseJwks(data)
+	if err != nil {
+		return nil, err
+	}
+	v.keys = keys
+	v.loadedAt = time.Now()
+	return keys, nil
+}
+
+func (v *LoginTokenValidator) readJwks() ([]byte, error) {
+	if v.JwksFile != "" {
+		data, err := ioutil.ReadFile(v.JwksFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read jwks file: %w", err)
+		}
+		return data, nil
+	}
+	if v.JwksURL == "" {
+		return nil, errors.New("jwks source is not configured")
+	}
+
+	var (
+		start  = time.Now()
+		status = "failure"
+	)
+	defer func() {
+		keyRequestSeconds.WithLabelValues(status).Observe(time.Since(start).Seconds())
+	}()
+
+	res, err := v.Client.Get(v.JwksURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve jwks: %w", err)
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+	if (res.StatusCode / 100) != 2 {
+		return nil, fmt.Errorf("attempt to fetch jwks failed with non-2xx status: %d", res.StatusCode)
+	}
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jwks body: %w", err)
+	}
+	status = "success"
+	return data, nil
+}
+
+func parseJwks(data []byte) (map[string]interface{}, error) {
+	var jwks JsonWebKeySet
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, fmt.Errorf("jwks is not valid json: %w", err)
+	}
+	keys := make(map[string]interface{})
+	for _, jwk := range jwks.Keys {
+		if jwk.Kid == "" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			log.Warnf("skipping jwk %s: %s", jwk.Kid, err.Error())
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("jwks does not contain any usable RS256/ES256 signing key")
+	}
+	return keys, nil
+}
+
+// PublicKey converts the jwk into an *rsa.PublicKey or a P-256 *ecdsa.PublicKey
+func (k *JsonWebKey) PublicKey() (interface{}, error) {
+	if k.N == "" && k.X == "" && len(k.X5c) > 0 {
+		return parseX5cPublicKey(k.X5c[0])
+	}
+	switch k.Kty {
+	case "RSA":
+		if k.Alg != "" && k.Alg != jwt.SigningMethodRS256.Alg() {
+			return nil, fmt.Errorf("unsupported alg %s", k.Alg)
+		}
+		n, err := decodeBase64UrlInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := decodeBase64UrlInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent: %w", err)
+		}
+		if !e.IsInt64() {
+			return nil, errors.New("exponent is too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" || (k.Alg != "" && k.Alg != jwt.SigningMethodES256.Alg()) {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decodeBase64UrlInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x coordinate: %w", err)
+		}
+		y, err := decodeBase64UrlInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y coordinate: %w", err)
+		}
+		curve := elliptic.P256()
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve P-256")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported kty %s", k.Kty)
+}
+
+func parseX5cPublicKey(x5c string) (interface{}, error) {
+	der, err := base64.StdEncoding.DecodeString(x5c)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x5c: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x5c certificate: %w", err)
+	}
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return key, nil
+	case *ecdsa.PublicKey:
+		if key.Curve == elliptic.P256() {
+			return key, nil
+		}
+	}
+	return nil, errors.New("unsupported x5c public key")
+}
+
+func decodeBase64UrlInt(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, errors.New("value is empty")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
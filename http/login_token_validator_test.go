@@ -0,0 +1,176 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v4"
+	"gotest.tools/assert"
+)
+
+const (
+	testAudience = "xconf-admin"
+	testIssuer   = "https://login.example.com"
+)
+
+func newTestRsaKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NilError(t, err)
+	return key
+}
+
+func writeTestJwks(t *testing.T, file string, keys map[string]*rsa.PrivateKey) {
+	jwks := JsonWebKeySet{}
+	for kid, key := range keys {
+		jwks.Keys = append(jwks.Keys, JsonWebKey{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			Alg: jwt.SigningMethodRS256.Alg(),
+			N:   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+		})
+	}
+	data, err := json.Marshal(jwks)
+	assert.NilError(t, err)
+	assert.NilError(t, ioutil.WriteFile(file, data, 0600))
+}
+
+func newTestValidator(file string) *LoginTokenValidator {
+	v := newLoginTokenValidator(file, "", testAudience, testIssuer, 0, 60)
+	v.MinRefreshInterval = 0
+	return v
+}
+
+func testClaims(expiresIn time.Duration) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"sub": "jdoe",
+		"aud": testAudience,
+		"iss": testIssuer,
+		"iat": now.Unix(),
+		"exp": now.Add(expiresIn).Unix(),
+	}
+}
+
+func signTestToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	assert.NilError(t, err)
+	return signed
+}
+
+func TestLoginTokenValidatorAcceptsSignedToken(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	key := newTestRsaKey(t)
+	writeTestJwks(t, file, map[string]*rsa.PrivateKey{"k1": key})
+	v := newTestValidator(file)
+
+	claims, err := v.Validate(signTestToken(t, jwt.SigningMethodRS256, "k1", key, testClaims(time.Hour)))
+	assert.NilError(t, err)
+	assert.Equal(t, claims["sub"], "jdoe")
+}
+
+func TestLoginTokenValidatorKeyRotation(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	oldKey := newTestRsaKey(t)
+	newKey := newTestRsaKey(t)
+	writeTestJwks(t, file, map[string]*rsa.PrivateKey{"k1": oldKey})
+	v := newTestValidator(file)
+
+	_, err := v.Validate(signTestToken(t, jwt.SigningMethodRS256, "k1", oldKey, testClaims(time.Hour)))
+	assert.NilError(t, err)
+
+	// the key set is rotated, the new kid is picked up and the removed one is no longer accepted
+	writeTestJwks(t, file, map[string]*rsa.PrivateKey{"k2": newKey})
+	_, err = v.Validate(signTestToken(t, jwt.SigningMethodRS256, "k2", newKey, testClaims(time.Hour)))
+	assert.NilError(t, err)
+	_, err = v.Validate(signTestToken(t, jwt.SigningMethodRS256, "k1", oldKey, testClaims(time.Hour)))
+	assert.ErrorContains(t, err, ErrNoLoginTokenKey.Error())
+
+	// a kid which is not in the key set is rejected
+	_, err = v.Validate(signTestToken(t, jwt.SigningMethodRS256, "unknown", newTestRsaKey(t), testClaims(time.Hour)))
+	assert.ErrorContains(t, err, ErrNoLoginTokenKey.Error())
+
+	// a known kid signed with another key is rejected
+	_, err = v.Validate(signTestToken(t, jwt.SigningMethodRS256, "k2", oldKey, testClaims(time.Hour)))
+	assert.ErrorContains(t, err, "verification error")
+}
+
+func TestLoginTokenValidatorRejectsAlgorithmConfusion(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	key := newTestRsaKey(t)
+	writeTestJwks(t, file, map[string]*rsa.PrivateKey{"k1": key})
+	v := newTestValidator(file)
+
+	// unsigned token
+	unsigned := signTestToken(t, jwt.SigningMethodNone, "k1", jwt.UnsafeAllowNoneSignatureType, testClaims(time.Hour))
+	_, err := v.Validate(unsigned)
+	assert.ErrorContains(t, err, "signing method none is invalid")
+
+	// HS256 token using the public RSA key as the HMAC secret
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.NilError(t, err)
+	secret := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})
+	_, err = v.Validate(signTestToken(t, jwt.SigningMethodHS256, "k1", secret, testClaims(time.Hour)))
+	assert.ErrorContains(t, err, "signing method HS256 is invalid")
+
+	// ES256 is accepted but does not match the RSA key of the kid
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+	_, err = v.Validate(signTestToken(t, jwt.SigningMethodES256, "k1", ecKey, testClaims(time.Hour)))
+	assert.ErrorContains(t, err, "signing method ES256 does not match key type")
+
+	// RSA algorithms other than RS256 are not accepted
+	_, err = v.Validate(signTestToken(t, jwt.SigningMethodRS512, "k1", key, testClaims(time.Hour)))
+	assert.ErrorContains(t, err, "signing method RS512 is invalid")
+}
+
+func TestLoginTokenValidatorRejectsExpiredToken(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	key := newTestRsaKey(t)
+	writeTestJwks(t, file, map[string]*rsa.PrivateKey{"k1": key})
+	v := newTestValidator(file)
+
+	_, err := v.Validate(signTestToken(t, jwt.SigningMethodRS256, "k1", key, testClaims(-time.Minute)))
+	assert.ErrorContains(t, err, "token has already expired")
+
+	// within the leeway the token is still accepted
+	v.Leeway = 2 * time.Minute
+	_, err = v.Validate(signTestToken(t, jwt.SigningMethodRS256, "k1", key, testClaims(-time.Minute)))
+	assert.NilError(t, err)
+
+	claims := testClaims(time.Hour)
+	delete(claims, "exp")
+	_, err = v.Validate(signTestToken(t, jwt.SigningMethodRS256, "k1", key, claims))
+	assert.ErrorContains(t, err, "expiration time is missing")
+}
@@ -117,6 +117,13 @@ func CreateNumberOfItemsHttpHeaders(size int) map[string]string {
 	return map[string]string{"numberOfItems": strconv.Itoa(size)}
 }
 
+// AddWarningHeaders adds every warning as a "299 Miscellaneous persistent warning" Warning header
+func AddWarningHeaders(w http.ResponseWriter, warnings []string) {
+	for _, warning := range warnings {
+		w.Header().Add("Warning", fmt.Sprintf("299 - %s", strconv.Quote(warning)))
+	}
+}
+
 func escapeXml(str string) string {
 	var buffer bytes.Buffer
 	xml.EscapeText(&buffer, []byte(str))
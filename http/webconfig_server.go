@@ -56,6 +56,15 @@ func NewWebconfigServer(sc *common.ServerConfig, testOnly bool, dc db.DatabaseCl
 		webConfServer.XW_XconfServer.SetupMocks()
 	}
 
+	loginTokenValidator = newLoginTokenValidator(
+		conf.GetString("xconfwebconfig.login.jwks_file", ""),
+		conf.GetString("xconfwebconfig.login.jwks_url", ""),
+		conf.GetString("xconfwebconfig.login.audience", ""),
+		conf.GetString("xconfwebconfig.login.issuer", ""),
+		conf.GetInt64("xconfwebconfig.login.leeway_in_secs", 30),
+		conf.GetInt64("xconfwebconfig.login.jwks_refresh_interval_in_mins", 60),
+	)
+
 	return webConfServer
 }
 
@@ -78,6 +87,14 @@ func (s *WebconfigServer) AuthValidationMiddleware(next http.Handler) http.Handl
 				// Add capabilities to request context
 				ctx = context.WithValue(ctx, CTX_KEY_CAPABILITIES, capabilities)
 			}
+		} else if apiKey := getApiKeyFromRequest(r); apiKey != "" {
+			if apiKeyCtx, err := ContextWithApiKey(ctx, r, apiKey); err != nil {
+				log.Error(err.Error())
+				http.Error(w, "invalid api key", http.StatusUnauthorized)
+				return
+			} else {
+				ctx = apiKeyCtx
+			}
 		} else if authToken := getLoginTokenFromRequest(r); authToken != "" {
 			if LoginToken, err := ValidateAndGetLoginToken(authToken); err != nil {
 				log.Error(err.Error())
@@ -0,0 +1,260 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	xcommon "xconfadmin/common"
+	"xconfwebconfig/db"
+	"xconfwebconfig/shared"
+	"xconfwebconfig/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const applicationTypeReloadInterval = time.Minute
+
+var applicationTypeIdPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,31}$`)
+
+// ApplicationTypeDefaults lists the default entities seeded for an application type
+type ApplicationTypeDefaults struct {
+	FirmwareRuleTemplates bool `json:"firmwareRuleTemplates"`
+	GlobalPercentage      bool `json:"globalPercentage"`
+	RoundRobinFilter      bool `json:"roundRobinFilter"`
+}
+
+// ApplicationType is an entry of the application type registry
+type ApplicationType struct {
+	ID                 string                  `json:"id"`
+	Updated            int64                   `json:"updated"`
+	Description        string                  `json:"description,omitempty"`
+	PermissionSuffix   string                  `json:"permissionSuffix,omitempty"`
+	GlobalPercentageId string                  `json:"globalPercentageId,omitempty"`
+	RoundRobinFilterId string                  `json:"roundRobinFilterId,omitempty"`
+	Defaults           ApplicationTypeDefaults `json:"defaults"`
+	Builtin            bool                    `json:"builtin"`
+}
+
+func NewApplicationTypeInf() interface{} {
+	return &ApplicationType{}
+}
+
+// GetPermissionSuffix returns the suffix of the scoped permissions, i.e. read-firmware-<suffix>
+func (obj *ApplicationType) GetPermissionSuffix() string {
+	if obj.PermissionSuffix != "" {
+		return obj.PermissionSuffix
+	}
+	return obj.ID
+}
+
+func (obj *ApplicationType) Validate() error {
+	if !applicationTypeIdPattern.MatchString(obj.ID) {
+		return xcommon.NewXconfError(http.StatusBadRequest, fmt.Sprintf("ApplicationType id %s is not valid, it must match %s", obj.ID, applicationTypeIdPattern.String()))
+	}
+	if obj.PermissionSuffix != "" && !applicationTypeIdPattern.MatchString(obj.PermissionSuffix) {
+		return xcommon.NewXconfError(http.StatusBadRequest, fmt.Sprintf("Permission suffix %s is not valid, it must match %s", obj.PermissionSuffix, applicationTypeIdPattern.String()))
+	}
+	for _, appType := range GetApplicationTypes() {
+		if appType.ID != obj.ID && appType.GetPermissionSuffix() == obj.GetPermissionSuffix() {
+			return xcommon.NewXconfError(http.StatusConflict, fmt.Sprintf("Permission suffix %s is already used by ApplicationType %s", obj.GetPermissionSuffix(), appType.ID))
+		}
+	}
+	return nil
+}
+
+// ValidateUpdate rejects changes of the fields of a builtin type, only its description can be changed
+func (obj *ApplicationType) ValidateUpdate(existing *ApplicationType) error {
+	if !existing.Builtin {
+		return nil
+	}
+	if obj.GetPermissionSuffix() != existing.GetPermissionSuffix() ||
+		obj.GlobalPercentageId != existing.GlobalPercentageId ||
+		obj.RoundRobinFilterId != existing.RoundRobinFilterId ||
+		obj.Defaults != existing.Defaults {
+		return xcommon.NewXconfError(http.StatusConflict, fmt.Sprintf("ApplicationType %s is builtin, only its description can be changed", obj.ID))
+	}
+	return nil
+}
+
+// ApplicationTypeValidator validates an entity before it is saved in the application type
+type ApplicationTypeValidator func(appType *ApplicationType, entity interface{}) error
+
+var (
+	applicationTypeValidatorsMutex sync.RWMutex
+	applicationTypeValidators      = map[string][]ApplicationTypeValidator{}
+)
+
+// RegisterApplicationTypeValidator adds a validation of the entities saved in the application type
+func RegisterApplicationTypeValidator(id string, validator ApplicationTypeValidator) {
+	applicationTypeValidatorsMutex.Lock()
+	defer applicationTypeValidatorsMutex.Unlock()
+	applicationTypeValidators[id] = append(applicationTypeValidators[id], validator)
+}
+
+// ValidateApplicationTypeEntity runs the validators registered for the application type on the entity
+func ValidateApplicationTypeEntity(applicationType string, entity interface{}) error {
+	applicationTypeValidatorsMutex.RLock()
+	validators := applicationTypeValidators[applicationType]
+	applicationTypeValidatorsMutex.RUnlock()
+	if len(validators) == 0 {
+		return nil
+	}
+	appType := GetApplicationType(applicationType)
+	if appType == nil {
+		return xcommon.NewXconfError(http.StatusBadRequest, fmt.Sprintf("ApplicationType %s is not valid", applicationType))
+	}
+	for _, validator := range validators {
+		if err := validator(appType, entity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type applicationTypeRegistry struct {
+	sync.RWMutex
+	types    map[string]*ApplicationType
+	loadedAt time.Time
+}
+
+var appTypeRegistry = &applicationTypeRegistry{}
+
+func builtinApplicationTypes() map[string]*ApplicationType {
+	types := map[string]*ApplicationType{}
+	types[shared.STB] = &ApplicationType{
+		ID:       shared.STB,
+		Defaults: ApplicationTypeDefaults{FirmwareRuleTemplates: true},
+		Builtin:  true,
+	}
+	types[shared.RDKCLOUD] = &ApplicationType{
+		ID:      shared.RDKCLOUD,
+		Builtin: true,
+	}
+	for _, id := range xcommon.ConfiguredApplicationTypes {
+		id = strings.ToLower(strings.TrimSpace(id))
+		if _, ok := types[id]; ok || id == "" {
+			continue
+		}
+		types[id] = &ApplicationType{
+			ID:       id,
+			Defaults: ApplicationTypeDefaults{GlobalPercentage: true, RoundRobinFilter: true},
+			Builtin:  true,
+		}
+	}
+	return types
+}
+
+// load merges the builtin and configured types with the admin-managed ones
+func (reg *applicationTypeRegistry) load() map[string]*ApplicationType {
+	types := builtinApplicationTypes()
+	list, err := db.GetSimpleDao().GetAllAsList(xcommon.TABLE_APPLICATION_TYPES, 0)
+	if err != nil {
+		log.Debugf("no ApplicationType found in %s: %v", xcommon.TABLE_APPLICATION_TYPES, err)
+	}
+	for _, inst := range list {
+		appType, ok := inst.(*ApplicationType)
+		if !ok {
+			continue
+		}
+		if builtin, ok := types[appType.ID]; ok {
+			// a stored builtin type only overrides the description
+			builtin.Description = appType.Description
+			builtin.Updated = appType.Updated
+			continue
+		}
+		types[appType.ID] = appType
+	}
+
+	reg.Lock()
+	reg.types = types
+	reg.loadedAt = time.Now()
+	reg.Unlock()
+	return types
+}
+
+func (reg *applicationTypeRegistry) get() map[string]*ApplicationType {
+	reg.RLock()
+	types := reg.types
+	expired := time.Since(reg.loadedAt) > applicationTypeReloadInterval
+	reg.RUnlock()
+	if types == nil || expired {
+		return reg.load()
+	}
+	return types
+}
+
+// ReloadApplicationTypes refreshes the registry from the configuration and db
+func ReloadApplicationTypes() {
+	appTypeRegistry.load()
+}
+
+// GetApplicationTypes returns all registered application types sorted by id
+func GetApplicationTypes() []*ApplicationType {
+	result := []*ApplicationType{}
+	for _, appType := range appTypeRegistry.get() {
+		result = append(result, appType)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+func GetApplicationType(id string) *ApplicationType {
+	if appType, ok := appTypeRegistry.get()[id]; ok {
+		return appType
+	}
+	return nil
+}
+
+func SetApplicationType(appType *ApplicationType) error {
+	appType.Updated = util.GetTimestamp(time.Now().UTC())
+	bytes, err := json.Marshal(appType)
+	if err != nil {
+		return err
+	}
+	if err := db.GetSimpleDao().SetOne(xcommon.TABLE_APPLICATION_TYPES, appType.ID, bytes); err != nil {
+		return err
+	}
+	ReloadApplicationTypes()
+	return nil
+}
+
+func DeleteApplicationType(id string) error {
+	if err := db.GetSimpleDao().DeleteOne(xcommon.TABLE_APPLICATION_TYPES, id); err != nil {
+		return err
+	}
+	ReloadApplicationTypes()
+	return nil
+}
+
+// GetApplicationTypePermissionSuffix returns the permission suffix of the registered application type
+func GetApplicationTypePermissionSuffix(applicationType string) string {
+	if appType := GetApplicationType(applicationType); appType != nil {
+		return appType.GetPermissionSuffix()
+	}
+	return ""
+}
@@ -0,0 +1,175 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	xcommon "xconfadmin/common"
+	"xconfwebconfig/db"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// audited operations
+const (
+	AUDIT_CREATE          = "CREATE"
+	AUDIT_UPDATE          = "UPDATE"
+	AUDIT_DELETE          = "DELETE"
+	AUDIT_IMPORT          = "IMPORT"
+	AUDIT_PRIORITY_CHANGE = "PRIORITY_CHANGE"
+	AUDIT_APPROVE         = "APPROVE"
+	AUDIT_REVERT          = "REVERT"
+	AUDIT_CANCEL          = "CANCEL"
+	AUDIT_EXPIRE          = "EXPIRE"
+	AUDIT_REJECT          = "REJECT"
+	AUDIT_ROLLOUT_STEP    = "ROLLOUT_STEP"
+)
+
+const auditDayLayout = "2006-01-02"
+
+// AuditListener is notified of every saved audit entry
+type AuditListener func(entry *AuditEntry)
+
+var auditListeners = []AuditListener{}
+
+// AddAuditListener registers a listener of the audited writes, listeners are added at startup
+func AddAuditListener(listener AuditListener) {
+	auditListeners = append(auditListeners, listener)
+}
+
+// AuditEntry records one admin write, entries are stored in a row per day
+type AuditEntry struct {
+	ID              string          `json:"id"`
+	Timestamp       int64           `json:"timestamp"`
+	User            string          `json:"user"`
+	SourceIp        string          `json:"sourceIp"`
+	RequestId       string          `json:"requestId"`
+	Operation       string          `json:"operation"`
+	Method          string          `json:"method"`
+	Path            string          `json:"path"`
+	Status          int             `json:"status"`
+	EntityType      string          `json:"entityType"`
+	EntityID        string          `json:"entityId,omitempty"`
+	TableName       string          `json:"tableName,omitempty"`
+	ApplicationType string          `json:"applicationType,omitempty"`
+	Before          json.RawMessage `json:"before,omitempty"`
+	After           json.RawMessage `json:"after,omitempty"`
+}
+
+// AuditFilter selects audit entries, From and To are epoch millis and 0 means unbounded
+type AuditFilter struct {
+	User       string
+	EntityType string
+	EntityID   string
+	TableName  string
+	From       int64
+	To         int64
+}
+
+func NewAuditEntryInf() interface{} {
+	return &AuditEntry{}
+}
+
+func auditDay(timestamp int64) string {
+	return time.UnixMilli(timestamp).UTC().Format(auditDayLayout)
+}
+
+func SaveAuditEntry(entry *AuditEntry) error {
+	if entry.Timestamp == 0 {
+		entry.Timestamp = time.Now().UTC().UnixMilli()
+	}
+	if entry.ID == "" {
+		// timestamp prefix keeps the entries of a day in order
+		entry.ID = fmt.Sprintf("%013d_%s", entry.Timestamp, uuid.New().String())
+	}
+	bytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := db.GetListingDao().SetOne(xcommon.TABLE_AUDIT_LOG, auditDay(entry.Timestamp), entry.ID, bytes); err != nil {
+		return err
+	}
+	for _, listener := range auditListeners {
+		listener(entry)
+	}
+	return nil
+}
+
+func (f *AuditFilter) matches(entry *AuditEntry) bool {
+	if f.From > 0 && entry.Timestamp < f.From {
+		return false
+	}
+	if f.To > 0 && entry.Timestamp > f.To {
+		return false
+	}
+	if f.User != "" && !strings.EqualFold(entry.User, f.User) {
+		return false
+	}
+	if f.EntityType != "" && !strings.EqualFold(entry.EntityType, f.EntityType) {
+		return false
+	}
+	if f.EntityID != "" && entry.EntityID != f.EntityID {
+		return false
+	}
+	if f.TableName != "" && !strings.EqualFold(entry.TableName, f.TableName) {
+		return false
+	}
+	return true
+}
+
+// GetAuditEntries returns the matching entries within the retention period, newest first
+func GetAuditEntries(filter *AuditFilter) []*AuditEntry {
+	now := time.Now().UTC()
+	from := now.AddDate(0, 0, -xcommon.AuditRetentionDays)
+	if filter.From > 0 && time.UnixMilli(filter.From).After(from) {
+		from = time.UnixMilli(filter.From).UTC()
+	}
+	to := now
+	if filter.To > 0 && time.UnixMilli(filter.To).Before(to) {
+		to = time.UnixMilli(filter.To).UTC()
+	}
+
+	result := []*AuditEntry{}
+	lastDay := to.Format(auditDayLayout)
+	for day := from; ; day = day.AddDate(0, 0, 1) {
+		rowKey := day.Format(auditDayLayout)
+		if rowKey > lastDay {
+			break
+		}
+		list, err := db.GetListingDao().GetAll(xcommon.TABLE_AUDIT_LOG, rowKey)
+		if err != nil {
+			log.Debugf("no audit entries for %s: %v", rowKey, err)
+			continue
+		}
+		for _, inst := range list {
+			if entry := inst.(*AuditEntry); filter.matches(entry) {
+				result = append(result, entry)
+			}
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Timestamp > result[j].Timestamp
+	})
+	return result
+}
@@ -0,0 +1,172 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package change
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	xcommon "xconfadmin/common"
+	"xconfwebconfig/db"
+	xwchange "xconfwebconfig/shared/change"
+	"xconfwebconfig/util"
+)
+
+// ApprovalPolicy sets who may approve the changes of an entity type for an application type,
+// without a stored policy one approval is enough and the author may approve the change
+type ApprovalPolicy struct {
+	ID                 string `json:"id"`
+	EntityType         string `json:"entityType"`
+	ApplicationType    string `json:"applicationType"`
+	AllowSelfApproval  bool   `json:"allowSelfApproval"`
+	RequiredApprovals  int    `json:"requiredApprovals"`
+	ApproverPermission string `json:"approverPermission,omitempty"`
+	Updated            int64  `json:"updated"`
+	UpdatedBy          string `json:"updatedBy,omitempty"`
+}
+
+// ChangeApproval is the approval of a change by one user
+type ChangeApproval struct {
+	User      string `json:"user"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// ChangeApprovals holds the partial approvals of a telemetry profile change
+type ChangeApprovals struct {
+	ID        string           `json:"id"`
+	Approvals []ChangeApproval `json:"approvals"`
+}
+
+func NewApprovalPolicyInf() interface{} {
+	return &ApprovalPolicy{}
+}
+
+func NewChangeApprovalsInf() interface{} {
+	return &ChangeApprovals{}
+}
+
+// GetApprovalPolicyTypes returns the entity types whose changes are approved through the change endpoints
+func GetApprovalPolicyTypes() []string {
+	return append([]string{string(xwchange.TelemetryProfile), TelemetryTwoProfile}, GetEntityChangeTypes()...)
+}
+
+func isApprovalPolicyType(entityType string) bool {
+	return util.Contains(GetApprovalPolicyTypes(), entityType)
+}
+
+func NewDefaultApprovalPolicy(entityType string, applicationType string) *ApprovalPolicy {
+	return &ApprovalPolicy{
+		ID:                approvalSettingId(entityType, applicationType),
+		EntityType:        entityType,
+		ApplicationType:   applicationType,
+		AllowSelfApproval: true,
+		RequiredApprovals: 1,
+	}
+}
+
+// GetApprovalPolicy returns the stored policy or the default one
+func GetApprovalPolicy(entityType string, applicationType string) *ApprovalPolicy {
+	inst, err := db.GetSimpleDao().GetOne(xcommon.TABLE_APPROVAL_POLICIES, approvalSettingId(entityType, applicationType))
+	if err != nil {
+		return NewDefaultApprovalPolicy(entityType, applicationType)
+	}
+	return inst.(*ApprovalPolicy)
+}
+
+func GetApprovalPolicies() []*ApprovalPolicy {
+	result := []*ApprovalPolicy{}
+	list, err := db.GetSimpleDao().GetAllAsList(xcommon.TABLE_APPROVAL_POLICIES, 0)
+	if err != nil {
+		return result
+	}
+	for _, inst := range list {
+		result = append(result, inst.(*ApprovalPolicy))
+	}
+	return result
+}
+
+// SetApprovalPolicy validates and stores the policy, the approver permission is checked by the caller
+func SetApprovalPolicy(policy *ApprovalPolicy, userName string) error {
+	if !isApprovalPolicyType(policy.EntityType) {
+		return xcommon.NewXconfError(http.StatusBadRequest, fmt.Sprintf("%s does not support approval", policy.EntityType))
+	}
+	if util.IsBlank(policy.ApplicationType) {
+		return xcommon.NewXconfError(http.StatusBadRequest, "ApplicationType is empty")
+	}
+	if policy.RequiredApprovals < 1 {
+		return xcommon.NewXconfError(http.StatusBadRequest, "RequiredApprovals must be at least 1")
+	}
+	policy.ID = approvalSettingId(policy.EntityType, policy.ApplicationType)
+	policy.Updated = util.GetTimestamp(time.Now().UTC())
+	policy.UpdatedBy = userName
+	policyBytes, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	return db.GetSimpleDao().SetOne(xcommon.TABLE_APPROVAL_POLICIES, policy.ID, policyBytes)
+}
+
+// AddApproval validates the approver against the policy and returns the approvals including the new one
+func (p *ApprovalPolicy) AddApproval(approvals []ChangeApproval, author string, approver string) ([]ChangeApproval, error) {
+	if !p.AllowSelfApproval && strings.EqualFold(author, approver) {
+		return nil, xcommon.NewXconfError(http.StatusForbidden, fmt.Sprintf("%s is the author of the change and can not approve it", approver))
+	}
+	for _, approval := range approvals {
+		if strings.EqualFold(approval.User, approver) {
+			return nil, xcommon.NewXconfError(http.StatusConflict, fmt.Sprintf("Change is already approved by %s", approver))
+		}
+	}
+	approval := ChangeApproval{
+		User:      approver,
+		Timestamp: util.GetTimestamp(time.Now().UTC()),
+	}
+	return append(approvals, approval), nil
+}
+
+// IsQuorumMet returns true if the approvals are enough to apply the change
+func (p *ApprovalPolicy) IsQuorumMet(approvals []ChangeApproval) bool {
+	return len(approvals) >= p.RequiredApprovals
+}
+
+// GetChangeApprovals returns the partial approvals of a telemetry profile change
+func GetChangeApprovals(changeId string) []ChangeApproval {
+	inst, err := db.GetSimpleDao().GetOne(xcommon.TABLE_CHANGE_APPROVALS, changeId)
+	if err != nil {
+		return []ChangeApproval{}
+	}
+	return inst.(*ChangeApprovals).Approvals
+}
+
+func SetChangeApprovals(changeId string, approvals []ChangeApproval) error {
+	changeApprovals := ChangeApprovals{
+		ID:        changeId,
+		Approvals: approvals,
+	}
+	approvalsBytes, err := json.Marshal(changeApprovals)
+	if err != nil {
+		return err
+	}
+	return db.GetSimpleDao().SetOne(xcommon.TABLE_CHANGE_APPROVALS, changeId, approvalsBytes)
+}
+
+func DeleteChangeApprovals(changeId string) error {
+	return db.GetSimpleDao().DeleteOne(xcommon.TABLE_CHANGE_APPROVALS, changeId)
+}
@@ -0,0 +1,58 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package change
+
+import (
+	"time"
+
+	xcommon "xconfadmin/common"
+)
+
+// GetPendingChangeTtl returns how long the pending changes of the table live, 0 if they don't expire
+func GetPendingChangeTtl(tableName string) time.Duration {
+	return time.Duration(xcommon.PendingChangeTtlHours[tableName]) * time.Hour
+}
+
+// HasPendingChangeTtl returns true if the pending changes of any table expire
+func HasPendingChangeTtl() bool {
+	for _, hours := range xcommon.PendingChangeTtlHours {
+		if hours > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// GetExpiresIn returns the millis left until a pending change of the table last updated at the timestamp expires,
+// nil if the changes of the table don't expire
+func GetExpiresIn(tableName string, updated int64, now time.Time) *int64 {
+	ttl := GetPendingChangeTtl(tableName)
+	if ttl <= 0 {
+		return nil
+	}
+	expiresIn := updated + ttl.Milliseconds() - now.UnixMilli()
+	if expiresIn < 0 {
+		expiresIn = 0
+	}
+	return &expiresIn
+}
+
+func IsExpired(tableName string, updated int64, now time.Time) bool {
+	expiresIn := GetExpiresIn(tableName, updated, now)
+	return expiresIn != nil && *expiresIn == 0
+}
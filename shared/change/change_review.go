@@ -0,0 +1,173 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package change
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	xcommon "xconfadmin/common"
+	"xconfwebconfig/db"
+	"xconfwebconfig/util"
+
+	"github.com/google/uuid"
+)
+
+// review statuses of a change, the latest review action sets the status
+const (
+	REVIEW_COMMENTED = "COMMENTED"
+	REVIEW_APPROVED  = "APPROVED"
+	REVIEW_REJECTED  = "REJECTED"
+)
+
+// ChangeComment is one comment of the review discussion of a change
+type ChangeComment struct {
+	ID       string `json:"id"`
+	ChangeID string `json:"changeId"`
+	Author   string `json:"author"`
+	Text     string `json:"text"`
+	Created  int64  `json:"created"`
+}
+
+// ChangeReview is the latest review status of a change, a rejected change keeps its status
+// and can't be approved any more
+type ChangeReview struct {
+	ID      string `json:"id"`
+	Status  string `json:"status"`
+	User    string `json:"user"`
+	Reason  string `json:"reason,omitempty"`
+	Updated int64  `json:"updated"`
+}
+
+func NewChangeCommentInf() interface{} {
+	return &ChangeComment{}
+}
+
+func NewChangeReviewInf() interface{} {
+	return &ChangeReview{}
+}
+
+// GetChangeComments returns the comments of the change, oldest first
+func GetChangeComments(changeId string) []*ChangeComment {
+	result := []*ChangeComment{}
+	list, err := db.GetListingDao().GetAll(xcommon.TABLE_CHANGE_COMMENTS, changeId)
+	if err != nil {
+		return result
+	}
+	for _, inst := range list {
+		result = append(result, inst.(*ChangeComment))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Created < result[j].Created
+	})
+	return result
+}
+
+// GetChangeCommentCounts returns the number of comments by change id, reading only the keys
+// of the comments table once for a whole change listing
+func GetChangeCommentCounts() map[string]int {
+	result := map[string]int{}
+	keys, err := db.GetListingDao().GetKeys(xcommon.TABLE_CHANGE_COMMENTS)
+	if err != nil {
+		return result
+	}
+	for _, key := range keys {
+		result[key.Key]++
+	}
+	return result
+}
+
+// AddChangeComment stores the comment and marks the change as commented unless it is rejected
+func AddChangeComment(changeId string, author string, text string) (*ChangeComment, error) {
+	if util.IsBlank(text) {
+		return nil, xcommon.NewXconfError(http.StatusBadRequest, "Comment text is empty")
+	}
+	created := util.GetTimestamp(time.Now().UTC())
+	comment := &ChangeComment{
+		// timestamp prefix keeps the comments of a change in order
+		ID:       fmt.Sprintf("%013d_%s", created, uuid.New().String()),
+		ChangeID: changeId,
+		Author:   author,
+		Text:     strings.TrimSpace(text),
+		Created:  created,
+	}
+	commentBytes, err := json.Marshal(comment)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.GetListingDao().SetOne(xcommon.TABLE_CHANGE_COMMENTS, changeId, comment.ID, commentBytes); err != nil {
+		return nil, err
+	}
+	if review := GetChangeReview(changeId); review == nil || review.Status != REVIEW_REJECTED {
+		if err := SetChangeReviewStatus(changeId, REVIEW_COMMENTED, author, ""); err != nil {
+			return nil, err
+		}
+	}
+	return comment, nil
+}
+
+// GetChangeReview returns the review status of the change, nil if nobody reviewed it yet
+func GetChangeReview(changeId string) *ChangeReview {
+	inst, err := db.GetSimpleDao().GetOne(xcommon.TABLE_CHANGE_REVIEWS, changeId)
+	if err != nil {
+		return nil
+	}
+	return inst.(*ChangeReview)
+}
+
+// GetChangeReviews returns the review statuses by change id
+func GetChangeReviews() map[string]*ChangeReview {
+	result := map[string]*ChangeReview{}
+	list, err := db.GetSimpleDao().GetAllAsList(xcommon.TABLE_CHANGE_REVIEWS, 0)
+	if err != nil {
+		return result
+	}
+	for _, inst := range list {
+		review := inst.(*ChangeReview)
+		result[review.ID] = review
+	}
+	return result
+}
+
+func SetChangeReviewStatus(changeId string, status string, user string, reason string) error {
+	review := ChangeReview{
+		ID:      changeId,
+		Status:  status,
+		User:    user,
+		Reason:  reason,
+		Updated: util.GetTimestamp(time.Now().UTC()),
+	}
+	reviewBytes, err := json.Marshal(review)
+	if err != nil {
+		return err
+	}
+	return db.GetSimpleDao().SetOne(xcommon.TABLE_CHANGE_REVIEWS, changeId, reviewBytes)
+}
+
+// DeleteChangeComments deletes all the comments of the change
+func DeleteChangeComments(changeId string) error {
+	return db.GetListingDao().DeleteAll(xcommon.TABLE_CHANGE_COMMENTS, changeId)
+}
+
+func DeleteChangeReview(changeId string) error {
+	return db.GetSimpleDao().DeleteOne(xcommon.TABLE_CHANGE_REVIEWS, changeId)
+}
@@ -0,0 +1,332 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package change
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	xcommon "xconfadmin/common"
+	"xconfwebconfig/db"
+	xwchange "xconfwebconfig/shared/change"
+	"xconfwebconfig/util"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// entity types which go through the generic approval workflow
+const (
+	FIRMWARE_RULE   = "FIRMWARE_RULE"
+	PERCENTAGE_BEAN = "PERCENTAGE_BEAN"
+	FEATURE_RULE    = "FEATURE_RULE"
+	DCM_FORMULA     = "DCM_FORMULA"
+)
+
+// EntityChangeApplier writes the entities of one type through the type's own validators
+type EntityChangeApplier interface {
+	// PermissionEntity returns the entity type checked by the permission service
+	PermissionEntity() string
+	// GetEntity returns the stored entity, nil if it does not exist
+	GetEntity(id string) interface{}
+	Create(entity json.RawMessage, applicationType string) error
+	Update(entity json.RawMessage, applicationType string) error
+	Delete(id string, applicationType string) error
+}
+
+var entityChangeAppliers = map[string]EntityChangeApplier{}
+
+// RegisterEntityChangeApplier enables the approval workflow for the entity type
+func RegisterEntityChangeApplier(entityType string, applier EntityChangeApplier) {
+	entityChangeAppliers[entityType] = applier
+}
+
+func GetEntityChangeApplier(entityType string) EntityChangeApplier {
+	return entityChangeAppliers[entityType]
+}
+
+func GetEntityChangeTypes() []string {
+	types := []string{}
+	for entityType := range entityChangeAppliers {
+		types = append(types, entityType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// EntityChange is a pending change of an entity type registered with RegisterEntityChangeApplier
+type EntityChange struct {
+	ID              string                   `json:"id"`
+	Updated         int64                    `json:"updated"`
+	EntityID        string                   `json:"entityId"`
+	EntityType      string                   `json:"entityType"`
+	ApplicationType string                   `json:"applicationType"`
+	NewEntity       json.RawMessage          `json:"newEntity,omitempty"`
+	OldEntity       json.RawMessage          `json:"oldEntity,omitempty"`
+	Operation       xwchange.ChangeOperation `json:"operation"`
+	Author          string                   `json:"author"`
+	ApprovedUser    string                   `json:"approvedUser,omitempty"`
+	Approvals       []ChangeApproval         `json:"approvals,omitempty"`
+}
+
+type ApprovedEntityChange EntityChange
+
+// ApprovalSetting puts an entity type of an application type into approval mode
+type ApprovalSetting struct {
+	ID               string `json:"id"`
+	EntityType       string `json:"entityType"`
+	ApplicationType  string `json:"applicationType"`
+	RequiresApproval bool   `json:"requiresApproval"`
+	Updated          int64  `json:"updated"`
+	UpdatedBy        string `json:"updatedBy,omitempty"`
+}
+
+func NewEntityChangeInf() interface{} {
+	return &EntityChange{}
+}
+
+func NewApprovedEntityChangeInf() interface{} {
+	return &ApprovedEntityChange{}
+}
+
+func NewApprovalSettingInf() interface{} {
+	return &ApprovalSetting{}
+}
+
+// GetEntityName returns the name of the new entity, or of the old entity for a delete
+func (c *EntityChange) GetEntityName() string {
+	entity := struct {
+		Name string `json:"name"`
+	}{}
+	for _, raw := range []json.RawMessage{c.NewEntity, c.OldEntity} {
+		if len(raw) > 0 && json.Unmarshal(raw, &entity) == nil && entity.Name != "" {
+			return entity.Name
+		}
+	}
+	return ""
+}
+
+func (c *ApprovedEntityChange) GetEntityName() string {
+	return (*EntityChange)(c).GetEntityName()
+}
+
+func (c *EntityChange) equalChangeData(other *EntityChange) bool {
+	return c.EntityType == other.EntityType &&
+		c.EntityID == other.EntityID &&
+		c.Operation == other.Operation &&
+		bytes.Equal(c.NewEntity, other.NewEntity)
+}
+
+func GetEntityChangeList() []*EntityChange {
+	all := []*EntityChange{}
+	list, err := db.GetSimpleDao().GetAllAsList(xcommon.TABLE_ENTITY_CHANGES, 0)
+	if err != nil {
+		log.Warn("no EntityChange found")
+		return all
+	}
+	for _, inst := range list {
+		all = append(all, inst.(*EntityChange))
+	}
+	return all
+}
+
+func GetOneEntityChange(id string) *EntityChange {
+	inst, err := db.GetSimpleDao().GetOne(xcommon.TABLE_ENTITY_CHANGES, id)
+	if err != nil {
+		return nil
+	}
+	return inst.(*EntityChange)
+}
+
+func GetEntityChangesByEntityId(entityType string, entityId string) []*EntityChange {
+	result := []*EntityChange{}
+	for _, change := range GetEntityChangeList() {
+		if change.EntityType == entityType && change.EntityID == entityId {
+			result = append(result, change)
+		}
+	}
+	return result
+}
+
+func SetOneEntityChange(change *EntityChange) error {
+	if util.IsBlank(change.ID) {
+		change.ID = uuid.New().String()
+	}
+	change.Updated = util.GetTimestamp(time.Now().UTC())
+
+	changeBytes, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	return db.GetSimpleDao().SetOne(xcommon.TABLE_ENTITY_CHANGES, change.ID, changeBytes)
+}
+
+func DeleteOneEntityChange(id string) error {
+	return db.GetSimpleDao().DeleteOne(xcommon.TABLE_ENTITY_CHANGES, id)
+}
+
+func GetApprovedEntityChangeList() []*ApprovedEntityChange {
+	all := []*ApprovedEntityChange{}
+	list, err := db.GetSimpleDao().GetAllAsList(xcommon.TABLE_APPROVED_ENTITY_CHANGES, 0)
+	if err != nil {
+		log.Warn("no ApprovedEntityChange found")
+		return all
+	}
+	for _, inst := range list {
+		all = append(all, inst.(*ApprovedEntityChange))
+	}
+	return all
+}
+
+func GetOneApprovedEntityChange(id string) *ApprovedEntityChange {
+	inst, err := db.GetSimpleDao().GetOne(xcommon.TABLE_APPROVED_ENTITY_CHANGES, id)
+	if err != nil {
+		return nil
+	}
+	return inst.(*ApprovedEntityChange)
+}
+
+func SetOneApprovedEntityChange(approvedChange *ApprovedEntityChange) error {
+	approvedChange.Updated = util.GetTimestamp(time.Now().UTC())
+
+	approvedChangeBytes, err := json.Marshal(approvedChange)
+	if err != nil {
+		return err
+	}
+	return db.GetSimpleDao().SetOne(xcommon.TABLE_APPROVED_ENTITY_CHANGES, approvedChange.ID, approvedChangeBytes)
+}
+
+func DeleteOneApprovedEntityChange(id string) error {
+	return db.GetSimpleDao().DeleteOne(xcommon.TABLE_APPROVED_ENTITY_CHANGES, id)
+}
+
+// CreatePendingEntityChange stores a change awaiting approval instead of writing the entity,
+// the old entity is taken from the DB
+func CreatePendingEntityChange(entityType string, applicationType string, operation xwchange.ChangeOperation, entityId string, newEntity interface{}, author string) (*EntityChange, error) {
+	applier := GetEntityChangeApplier(entityType)
+	if applier == nil {
+		return nil, xcommon.NewXconfError(http.StatusBadRequest, fmt.Sprintf("%s does not support approval", entityType))
+	}
+	if util.IsBlank(entityId) {
+		return nil, xcommon.NewXconfError(http.StatusBadRequest, "Entity id is empty")
+	}
+	if util.IsBlank(author) {
+		return nil, xcommon.NewXconfError(http.StatusBadRequest, "Author is empty")
+	}
+
+	change := &EntityChange{
+		EntityID:        entityId,
+		EntityType:      entityType,
+		ApplicationType: applicationType,
+		Operation:       operation,
+		Author:          author,
+	}
+	oldEntity := applier.GetEntity(entityId)
+	switch operation {
+	case Create:
+		if oldEntity != nil {
+			return nil, xcommon.NewXconfError(http.StatusConflict, fmt.Sprintf("Entity with id: %s already exists", entityId))
+		}
+	case Update, Delete:
+		if oldEntity == nil {
+			return nil, xcommon.NewXconfError(http.StatusNotFound, fmt.Sprintf("Entity with id: %s does not exist", entityId))
+		}
+		oldBytes, err := json.Marshal(oldEntity)
+		if err != nil {
+			return nil, err
+		}
+		change.OldEntity = oldBytes
+	default:
+		return nil, xcommon.NewXconfError(http.StatusBadRequest, "Operation is empty")
+	}
+	if operation != Delete {
+		if newEntity == nil {
+			return nil, xcommon.NewXconfError(http.StatusBadRequest, "New entity is empty")
+		}
+		newBytes, err := json.Marshal(newEntity)
+		if err != nil {
+			return nil, err
+		}
+		change.NewEntity = newBytes
+	}
+
+	for _, existingChange := range GetEntityChangesByEntityId(entityType, entityId) {
+		if existingChange.equalChangeData(change) {
+			return nil, xcommon.NewXconfError(http.StatusConflict, "The same change already exists")
+		}
+	}
+	if err := SetOneEntityChange(change); err != nil {
+		return nil, err
+	}
+	log.Infof("%s change of %s %s saved by %s", operation, entityType, entityId, author)
+	return change, nil
+}
+
+func approvalSettingId(entityType string, applicationType string) string {
+	return entityType + "_" + applicationType
+}
+
+// IsApprovalRequired returns true if changes of the entity type must be approved for the application type
+func IsApprovalRequired(entityType string, applicationType string) bool {
+	inst, err := db.GetSimpleDao().GetOne(xcommon.TABLE_APPROVAL_SETTINGS, approvalSettingId(entityType, applicationType))
+	if err != nil {
+		return false
+	}
+	return inst.(*ApprovalSetting).RequiresApproval
+}
+
+func GetApprovalSettings() []*ApprovalSetting {
+	result := []*ApprovalSetting{}
+	list, err := db.GetSimpleDao().GetAllAsList(xcommon.TABLE_APPROVAL_SETTINGS, 0)
+	if err != nil {
+		return result
+	}
+	for _, inst := range list {
+		result = append(result, inst.(*ApprovalSetting))
+	}
+	return result
+}
+
+func SetApprovalSetting(setting *ApprovalSetting, userName string) error {
+	if GetEntityChangeApplier(setting.EntityType) == nil {
+		return xcommon.NewXconfError(http.StatusBadRequest, fmt.Sprintf("%s does not support approval", setting.EntityType))
+	}
+	if util.IsBlank(setting.ApplicationType) {
+		return xcommon.NewXconfError(http.StatusBadRequest, "ApplicationType is empty")
+	}
+	setting.ID = approvalSettingId(setting.EntityType, setting.ApplicationType)
+	setting.Updated = util.GetTimestamp(time.Now().UTC())
+	setting.UpdatedBy = userName
+	settingBytes, err := json.Marshal(setting)
+	if err != nil {
+		return err
+	}
+	return db.GetSimpleDao().SetOne(xcommon.TABLE_APPROVAL_SETTINGS, setting.ID, settingBytes)
+}
+
+// ValidateDirectWrite rejects batch writes and imports of an entity type which requires approval
+func ValidateDirectWrite(entityType string, applicationType string) error {
+	if IsApprovalRequired(entityType, applicationType) {
+		return xcommon.NewXconfError(http.StatusConflict, fmt.Sprintf("Changes of %s require approval for %s, they must be saved one by one", entityType, applicationType))
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package change
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	xcommon "xconfadmin/common"
+	"xconfwebconfig/db"
+	"xconfwebconfig/util"
+)
+
+// ScheduledChange is an approved change which is applied at EffectiveAt on the approver's behalf.
+// The permissions of an approver authenticated with an api key are resolved again from ApiKeyID
+// when the change is applied, the ones of a login token are kept since only the token carries them
+type ScheduledChange struct {
+	ID              string   `json:"id"`
+	ChangeTable     string   `json:"changeTable"`
+	EntityID        string   `json:"entityId"`
+	EntityType      string   `json:"entityType"`
+	ApplicationType string   `json:"applicationType"`
+	EffectiveAt     int64    `json:"effectiveAt"`
+	ScheduledBy     string   `json:"scheduledBy"`
+	ApiKeyID        string   `json:"apiKeyId,omitempty"`
+	Permissions     []string `json:"permissions,omitempty"`
+	Capabilities    []string `json:"capabilities,omitempty"`
+	Updated         int64    `json:"updated"`
+}
+
+func NewScheduledChangeInf() interface{} {
+	return &ScheduledChange{}
+}
+
+// IsDue returns true if the change must be applied at the given time
+func (c *ScheduledChange) IsDue(now time.Time) bool {
+	return c.EffectiveAt <= util.GetTimestamp(now.UTC())
+}
+
+// GetScheduledChanges returns the scheduled changes, earliest first
+func GetScheduledChanges() []*ScheduledChange {
+	result := []*ScheduledChange{}
+	list, err := db.GetSimpleDao().GetAllAsList(xcommon.TABLE_SCHEDULED_CHANGES, 0)
+	if err != nil {
+		return result
+	}
+	for _, inst := range list {
+		result = append(result, inst.(*ScheduledChange))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].EffectiveAt < result[j].EffectiveAt
+	})
+	return result
+}
+
+// GetScheduledChange returns the schedule of the change, nil if the change is not scheduled
+func GetScheduledChange(changeId string) *ScheduledChange {
+	inst, err := db.GetSimpleDao().GetOne(xcommon.TABLE_SCHEDULED_CHANGES, changeId)
+	if err != nil {
+		return nil
+	}
+	return inst.(*ScheduledChange)
+}
+
+func SetScheduledChange(scheduledChange *ScheduledChange) error {
+	scheduledChange.Updated = util.GetTimestamp(time.Now().UTC())
+	scheduledChangeBytes, err := json.Marshal(scheduledChange)
+	if err != nil {
+		return err
+	}
+	return db.GetSimpleDao().SetOne(xcommon.TABLE_SCHEDULED_CHANGES, scheduledChange.ID, scheduledChangeBytes)
+}
+
+func DeleteScheduledChange(changeId string) error {
+	return db.GetSimpleDao().DeleteOne(xcommon.TABLE_SCHEDULED_CHANGES, changeId)
+}
+
+const (
+	// claims are kept long enough to outlive any retry of the same schedule
+	ScheduledChangeClaimTtlSeconds = 7 * 24 * 60 * 60
+	// a write lock left by a crashed instance expires after the longest write
+	EntityWriteLockTtlSeconds = 60
+)
+
+// NewClaimInf returns the value of a claim row, the name of the instance which owns the claim
+func NewClaimInf() interface{} {
+	return new(string)
+}
+
+// ClaimScheduledChange returns true if this admin instance is the one to apply the scheduled change
+func ClaimScheduledChange(scheduledChange *ScheduledChange, owner string) (bool, error) {
+	return Claim(scheduledChange.ID+"_"+strconv.FormatInt(scheduledChange.EffectiveAt, 10), owner)
+}
+
+// ConditionalDatabaseClient is a database client which stores a row only if it does not exist yet,
+// databases without lightweight transactions implement it to support the claims
+type ConditionalDatabaseClient interface {
+	SetXconfDataIfNotExists(tableName string, rowKey string, value []byte, ttl int) (bool, error)
+}
+
+// Claim returns true if this admin instance is the one to run the background work identified by claimId.
+// The claim is a lightweight transaction, so only one of several instances wins it. It fails closed,
+// a database which can't store the claim conditionally never grants it
+func Claim(claimId string, owner string) (bool, error) {
+	return ClaimRow(xcommon.TABLE_SCHEDULED_CHANGE_CLAIMS, claimId, owner, ScheduledChangeClaimTtlSeconds)
+}
+
+// SupportsClaims returns true if the database can store a row conditionally, which the claims need
+func SupportsClaims() bool {
+	switch db.GetDatabaseClient().(type) {
+	case *db.CassandraClient, ConditionalDatabaseClient:
+		return true
+	}
+	return false
+}
+
+// ClaimRow stores the row with a lightweight transaction and returns true if it did not exist yet,
+// the row expires after ttl seconds unless it is released before
+func ClaimRow(tableName string, rowKey string, owner string, ttl int) (bool, error) {
+	value, err := json.Marshal(owner)
+	if err != nil {
+		return false, err
+	}
+	switch client := db.GetDatabaseClient().(type) {
+	case *db.CassandraClient:
+		stmt := fmt.Sprintf(`INSERT INTO "%s"(key, column1, value) VALUES(?,?,?) IF NOT EXISTS USING TTL %d`, tableName, ttl)
+		existing := map[string]interface{}{}
+		applied, err := client.Query(stmt, rowKey, db.DefaultColumnValue, value).MapScanCAS(existing)
+		if err != nil {
+			return false, err
+		}
+		return applied, nil
+	case ConditionalDatabaseClient:
+		return client.SetXconfDataIfNotExists(tableName, rowKey, value, ttl)
+	}
+	return false, fmt.Errorf("database does not support lightweight transactions, %s can not be claimed", rowKey)
+}
+
+// ReleaseRow deletes a row claimed by ClaimRow so it can be claimed again
+func ReleaseRow(tableName string, rowKey string) error {
+	return db.GetDatabaseClient().DeleteXconfData(tableName, rowKey)
+}
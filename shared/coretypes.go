@@ -92,10 +92,7 @@ func NormalizeCommonContext(contextMap map[string]string, estbMacKey string, ecm
 }
 
 func IsValidApplicationType(at string) bool {
-	if at == shared.STB || at == shared.RDKCLOUD {
-		return true
-	}
-	return false
+	return GetApplicationType(at) != nil
 }
 
 // Validate whether the ApplicationType is valid if specified
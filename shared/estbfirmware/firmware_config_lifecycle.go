@@ -0,0 +1,120 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package estbfirmware
+
+import (
+	"encoding/json"
+	"time"
+
+	xcommon "xconfadmin/common"
+	"xconfwebconfig/db"
+	"xconfwebconfig/util"
+)
+
+// firmware config lifecycle states, a config without a lifecycle is active
+const (
+	FIRMWARE_CONFIG_ACTIVE     = "ACTIVE"
+	FIRMWARE_CONFIG_DEPRECATED = "DEPRECATED"
+	FIRMWARE_CONFIG_RETIRED    = "RETIRED"
+	FIRMWARE_CONFIG_RECALLED   = "RECALLED"
+)
+
+var FirmwareConfigStates = []string{
+	FIRMWARE_CONFIG_ACTIVE,
+	FIRMWARE_CONFIG_DEPRECATED,
+	FIRMWARE_CONFIG_RETIRED,
+	FIRMWARE_CONFIG_RECALLED,
+}
+
+// FirmwareConfigLifecycle is the state of the firmware config with the same id, History holds every state change
+type FirmwareConfigLifecycle struct {
+	ID              string                       `json:"id"`
+	ApplicationType string                       `json:"applicationType"`
+	State           string                       `json:"state"`
+	Reason          string                       `json:"reason,omitempty"`
+	Since           int64                        `json:"since"`
+	History         []*FirmwareConfigStateChange `json:"history"`
+	Updated         int64                        `json:"updated"`
+}
+
+type FirmwareConfigStateChange struct {
+	State     string `json:"state"`
+	Reason    string `json:"reason,omitempty"`
+	ChangedBy string `json:"changedBy,omitempty"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+func NewFirmwareConfigLifecycleInf() interface{} {
+	return &FirmwareConfigLifecycle{}
+}
+
+// IsAssignable returns false if new references to the config must be rejected
+func (l *FirmwareConfigLifecycle) IsAssignable() bool {
+	return l.State != FIRMWARE_CONFIG_RETIRED && l.State != FIRMWARE_CONFIG_RECALLED
+}
+
+// ChangeState moves the config to the state and records the change in the history
+func (l *FirmwareConfigLifecycle) ChangeState(state string, reason string, changedBy string) {
+	now := util.GetTimestamp(time.Now().UTC())
+	l.State = state
+	l.Reason = reason
+	l.Since = now
+	l.History = append(l.History, &FirmwareConfigStateChange{
+		State:     state,
+		Reason:    reason,
+		ChangedBy: changedBy,
+		Timestamp: now,
+	})
+}
+
+func GetFirmwareConfigLifecycle(id string) *FirmwareConfigLifecycle {
+	inst, err := db.GetSimpleDao().GetOne(xcommon.TABLE_FIRMWARE_CONFIG_LIFECYCLES, id)
+	if err != nil {
+		return nil
+	}
+	return inst.(*FirmwareConfigLifecycle)
+}
+
+// GetFirmwareConfigLifecycles returns the lifecycles of the application type by config id
+func GetFirmwareConfigLifecycles(applicationType string) map[string]*FirmwareConfigLifecycle {
+	result := map[string]*FirmwareConfigLifecycle{}
+	list, err := db.GetSimpleDao().GetAllAsList(xcommon.TABLE_FIRMWARE_CONFIG_LIFECYCLES, 0)
+	if err != nil {
+		return result
+	}
+	for _, inst := range list {
+		lifecycle := inst.(*FirmwareConfigLifecycle)
+		if lifecycle.ApplicationType == applicationType {
+			result[lifecycle.ID] = lifecycle
+		}
+	}
+	return result
+}
+
+func SetFirmwareConfigLifecycle(lifecycle *FirmwareConfigLifecycle) error {
+	lifecycle.Updated = util.GetTimestamp(time.Now().UTC())
+	bytes, err := json.Marshal(lifecycle)
+	if err != nil {
+		return err
+	}
+	return db.GetSimpleDao().SetOne(xcommon.TABLE_FIRMWARE_CONFIG_LIFECYCLES, lifecycle.ID, bytes)
+}
+
+func DeleteFirmwareConfigLifecycle(id string) error {
+	return db.GetSimpleDao().DeleteOne(xcommon.TABLE_FIRMWARE_CONFIG_LIFECYCLES, id)
+}
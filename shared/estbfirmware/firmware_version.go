@@ -0,0 +1,417 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package estbfirmware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	xcommon "xconfadmin/common"
+	"xconfwebconfig/db"
+	"xconfwebconfig/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// parser of the versions which do not match a pattern of their model
+	NATURAL_VERSION_PARSER = "NATURAL"
+
+	// named group of a FirmwareVersionPattern holding the family of the version, e.g. the model prefix,
+	// versions of different families are not comparable
+	FIRMWARE_VERSION_FAMILY_GROUP = "family"
+)
+
+// FirmwareVersionPattern extracts the comparable components of the firmware versions of its models,
+// every capture group other than family is a component
+type FirmwareVersionPattern struct {
+	ID              string   `json:"id"`
+	Name            string   `json:"name"`
+	ApplicationType string   `json:"applicationType"`
+	ModelIds        []string `json:"modelIds"`
+	Pattern         string   `json:"pattern"`
+	Updated         int64    `json:"updated"`
+}
+
+// ParsedFirmwareVersion holds the components of a version, numeric components are compared as numbers
+type ParsedFirmwareVersion struct {
+	Version    string   `json:"version"`
+	Parser     string   `json:"parser"`
+	Family     string   `json:"family,omitempty"`
+	Components []string `json:"components"`
+	Suffix     string   `json:"suffix,omitempty"`
+}
+
+// FirmwareVersionParser parses the versions it recognizes
+type FirmwareVersionParser interface {
+	Name() string
+	Parse(version string) (*ParsedFirmwareVersion, bool)
+}
+
+type naturalVersionParser struct{}
+
+type patternVersionParser struct {
+	pattern *FirmwareVersionPattern
+	regex   *regexp.Regexp
+}
+
+func NewFirmwareVersionPatternInf() interface{} {
+	return &FirmwareVersionPattern{}
+}
+
+func (obj *FirmwareVersionPattern) Validate() error {
+	if strings.TrimSpace(obj.Name) == "" {
+		return xcommon.NewXconfError(http.StatusBadRequest, "Name is empty")
+	}
+	if len(obj.ModelIds) == 0 {
+		return xcommon.NewXconfError(http.StatusBadRequest, "Models are empty")
+	}
+	regex, err := regexp.Compile(obj.Pattern)
+	if err != nil {
+		return xcommon.NewXconfError(http.StatusBadRequest, fmt.Sprintf("Pattern is invalid: %s", err.Error()))
+	}
+	for _, name := range regex.SubexpNames()[1:] {
+		if name != FIRMWARE_VERSION_FAMILY_GROUP {
+			return nil
+		}
+	}
+	return xcommon.NewXconfError(http.StatusBadRequest, "Pattern must have a capture group for the version components")
+}
+
+// NewFirmwareVersionParser returns the parser of the pattern
+func (obj *FirmwareVersionPattern) NewFirmwareVersionParser() (FirmwareVersionParser, error) {
+	regex, err := regexp.Compile(obj.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &patternVersionParser{pattern: obj, regex: regex}, nil
+}
+
+func (p *patternVersionParser) Name() string {
+	return p.pattern.Name
+}
+
+func (p *patternVersionParser) Parse(version string) (*ParsedFirmwareVersion, bool) {
+	match := p.regex.FindStringSubmatch(version)
+	if match == nil {
+		return nil, false
+	}
+	parsed := &ParsedFirmwareVersion{Version: version, Parser: p.pattern.Name, Components: []string{}}
+	for i, name := range p.regex.SubexpNames() {
+		if i == 0 {
+			continue
+		}
+		if name == FIRMWARE_VERSION_FAMILY_GROUP {
+			parsed.Family = strings.ToUpper(match[i])
+		} else {
+			parsed.Components = append(parsed.Components, match[i])
+		}
+	}
+	return parsed, true
+}
+
+func (p naturalVersionParser) Name() string {
+	return NATURAL_VERSION_PARSER
+}
+
+// Parse splits the version into runs of digits and letters, the part before the first underscore
+// is the family and the part after the next one is the suffix, e.g. TG1682_3.14p9s1_PROD_sey
+// is TG1682 [3 14 p 9 s 1] PROD_sey, the suffix is not compared
+func (p naturalVersionParser) Parse(version string) (*ParsedFirmwareVersion, bool) {
+	parsed := &ParsedFirmwareVersion{Version: version, Parser: NATURAL_VERSION_PARSER, Components: []string{}}
+	rest := version
+	if i := strings.Index(rest, "_"); i > 0 {
+		parsed.Family = strings.ToUpper(rest[:i])
+		rest = rest[i+1:]
+	}
+	if i := strings.Index(rest, "_"); i >= 0 {
+		parsed.Suffix = rest[i+1:]
+		rest = rest[:i]
+	}
+	var token strings.Builder
+	digits := false
+	for _, c := range rest {
+		isDigit := unicode.IsDigit(c)
+		if !isDigit && !unicode.IsLetter(c) {
+			if token.Len() > 0 {
+				parsed.Components = append(parsed.Components, token.String())
+				token.Reset()
+			}
+			continue
+		}
+		if token.Len() > 0 && isDigit != digits {
+			parsed.Components = append(parsed.Components, token.String())
+			token.Reset()
+		}
+		digits = isDigit
+		token.WriteRune(c)
+	}
+	if token.Len() > 0 {
+		parsed.Components = append(parsed.Components, token.String())
+	}
+	return parsed, true
+}
+
+// IsComparable returns true if both versions are parsed by the same parser and belong to the same family
+func (v *ParsedFirmwareVersion) IsComparable(other *ParsedFirmwareVersion) bool {
+	return v.Parser == other.Parser && v.Family == other.Family
+}
+
+// CompareTo compares the components, a version which has the components of the other one and more is newer
+func (v *ParsedFirmwareVersion) CompareTo(other *ParsedFirmwareVersion) int {
+	for i := 0; i < len(v.Components) && i < len(other.Components); i++ {
+		if result := compareVersionComponents(v.Components[i], other.Components[i]); result != 0 {
+			return result
+		}
+	}
+	return compareInts(len(v.Components), len(other.Components))
+}
+
+func compareVersionComponents(a string, b string) int {
+	if isNumeric(a) && isNumeric(b) {
+		a = strings.TrimLeft(a, "0")
+		b = strings.TrimLeft(b, "0")
+		if len(a) != len(b) {
+			return compareInts(len(a), len(b))
+		}
+		return strings.Compare(a, b)
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+func isNumeric(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, c := range value {
+		if !unicode.IsDigit(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func compareInts(a int, b int) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+// FirmwareVersionComparator parses versions with the patterns of their models and falls back to the natural order
+type FirmwareVersionComparator struct {
+	parsers  map[string][]FirmwareVersionParser
+	fallback FirmwareVersionParser
+}
+
+const firmwareVersionPatternReloadInterval = time.Minute
+
+type firmwareVersionComparatorRegistry struct {
+	sync.RWMutex
+	comparators map[string]*FirmwareVersionComparator
+	loadedAt    time.Time
+}
+
+var versionComparatorRegistry = &firmwareVersionComparatorRegistry{}
+
+func newFirmwareVersionComparator() *FirmwareVersionComparator {
+	return &FirmwareVersionComparator{
+		parsers:  map[string][]FirmwareVersionParser{},
+		fallback: naturalVersionParser{},
+	}
+}
+
+// load builds the comparators of all application types from one scan of the patterns
+func (reg *firmwareVersionComparatorRegistry) load() map[string]*FirmwareVersionComparator {
+	comparators := map[string]*FirmwareVersionComparator{}
+	patterns := getAllFirmwareVersionPatterns()
+	sort.Slice(patterns, func(i, j int) bool {
+		return patterns[i].Name < patterns[j].Name
+	})
+	for _, pattern := range patterns {
+		parser, err := pattern.NewFirmwareVersionParser()
+		if err != nil {
+			log.Errorf("invalid firmware version pattern %s: %s", pattern.ID, err.Error())
+			continue
+		}
+		comparator, ok := comparators[pattern.ApplicationType]
+		if !ok {
+			comparator = newFirmwareVersionComparator()
+			comparators[pattern.ApplicationType] = comparator
+		}
+		for _, modelId := range pattern.ModelIds {
+			modelId = strings.ToUpper(modelId)
+			comparator.parsers[modelId] = append(comparator.parsers[modelId], parser)
+		}
+	}
+
+	reg.Lock()
+	reg.comparators = comparators
+	reg.loadedAt = time.Now()
+	reg.Unlock()
+	return comparators
+}
+
+func (reg *firmwareVersionComparatorRegistry) get() map[string]*FirmwareVersionComparator {
+	reg.RLock()
+	comparators := reg.comparators
+	expired := time.Since(reg.loadedAt) > firmwareVersionPatternReloadInterval
+	reg.RUnlock()
+	if comparators == nil || expired {
+		return reg.load()
+	}
+	return comparators
+}
+
+// ReloadFirmwareVersionPatterns refreshes the comparators from the db
+func ReloadFirmwareVersionPatterns() {
+	versionComparatorRegistry.load()
+}
+
+// GetFirmwareVersionComparator returns the comparator of the patterns of the application type
+func GetFirmwareVersionComparator(applicationType string) *FirmwareVersionComparator {
+	if comparator, ok := versionComparatorRegistry.get()[applicationType]; ok {
+		return comparator
+	}
+	return newFirmwareVersionComparator()
+}
+
+// HasPattern returns true if a pattern is configured for one of the models
+func (c *FirmwareVersionComparator) HasPattern(modelIds []string) bool {
+	for _, modelId := range modelIds {
+		if len(c.parsers[strings.ToUpper(modelId)]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseWithPattern parses the version with the first pattern of the models which matches it
+func (c *FirmwareVersionComparator) ParseWithPattern(modelIds []string, version string) (*ParsedFirmwareVersion, bool) {
+	for _, modelId := range modelIds {
+		for _, parser := range c.parsers[strings.ToUpper(modelId)] {
+			if parsed, ok := parser.Parse(version); ok {
+				return parsed, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Parse parses the version with the patterns of the models, or the natural parser if none matches
+func (c *FirmwareVersionComparator) Parse(modelIds []string, version string) *ParsedFirmwareVersion {
+	if parsed, ok := c.ParseWithPattern(modelIds, version); ok {
+		return parsed
+	}
+	parsed, _ := c.fallback.Parse(version)
+	return parsed
+}
+
+// Compare returns -1, 0 or 1 if version a is older, equal or newer than b,
+// false if the versions are not comparable
+func (c *FirmwareVersionComparator) Compare(modelIds []string, a string, b string) (int, bool) {
+	parsedA := c.Parse(modelIds, a)
+	parsedB := c.Parse(modelIds, b)
+	if !parsedA.IsComparable(parsedB) {
+		return 0, false
+	}
+	return parsedA.CompareTo(parsedB), true
+}
+
+// Sort sorts the versions from the oldest to the newest, versions which are not comparable are grouped
+// by parser and family
+func (c *FirmwareVersionComparator) Sort(modelIds []string, versions []string) {
+	parsed := map[string]*ParsedFirmwareVersion{}
+	for _, version := range versions {
+		parsed[version] = c.Parse(modelIds, version)
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		a, b := parsed[versions[i]], parsed[versions[j]]
+		if a.Parser != b.Parser {
+			return a.Parser < b.Parser
+		}
+		if a.Family != b.Family {
+			return a.Family < b.Family
+		}
+		if result := a.CompareTo(b); result != 0 {
+			return result < 0
+		}
+		return a.Version < b.Version
+	})
+}
+
+func getAllFirmwareVersionPatterns() []*FirmwareVersionPattern {
+	result := []*FirmwareVersionPattern{}
+	list, err := db.GetSimpleDao().GetAllAsList(xcommon.TABLE_FIRMWARE_VERSION_PATTERNS, 0)
+	if err != nil {
+		return result
+	}
+	for _, inst := range list {
+		result = append(result, inst.(*FirmwareVersionPattern))
+	}
+	return result
+}
+
+func GetFirmwareVersionPatterns(applicationType string) []*FirmwareVersionPattern {
+	result := []*FirmwareVersionPattern{}
+	for _, pattern := range getAllFirmwareVersionPatterns() {
+		if pattern.ApplicationType == applicationType {
+			result = append(result, pattern)
+		}
+	}
+	return result
+}
+
+func GetFirmwareVersionPattern(id string) *FirmwareVersionPattern {
+	inst, err := db.GetSimpleDao().GetOne(xcommon.TABLE_FIRMWARE_VERSION_PATTERNS, id)
+	if err != nil {
+		return nil
+	}
+	return inst.(*FirmwareVersionPattern)
+}
+
+func SetFirmwareVersionPattern(pattern *FirmwareVersionPattern) error {
+	pattern.Updated = util.GetTimestamp(time.Now().UTC())
+	bytes, err := json.Marshal(pattern)
+	if err != nil {
+		return err
+	}
+	if err := db.GetSimpleDao().SetOne(xcommon.TABLE_FIRMWARE_VERSION_PATTERNS, pattern.ID, bytes); err != nil {
+		return err
+	}
+	ReloadFirmwareVersionPatterns()
+	return nil
+}
+
+func DeleteFirmwareVersionPattern(id string) error {
+	if err := db.GetSimpleDao().DeleteOne(xcommon.TABLE_FIRMWARE_VERSION_PATTERNS, id); err != nil {
+		return err
+	}
+	ReloadFirmwareVersionPatterns()
+	return nil
+}
@@ -0,0 +1,195 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package estbfirmware
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	xcommon "xconfadmin/common"
+	"xconfwebconfig/db"
+	"xconfwebconfig/shared/firmware"
+	"xconfwebconfig/util"
+)
+
+// percentage rollout status
+const (
+	PERCENTAGE_ROLLOUT_ACTIVE    = "ACTIVE"
+	PERCENTAGE_ROLLOUT_PAUSED    = "PAUSED"
+	PERCENTAGE_ROLLOUT_ABORTED   = "ABORTED"
+	PERCENTAGE_ROLLOUT_COMPLETED = "COMPLETED"
+	PERCENTAGE_ROLLOUT_FAILED    = "FAILED"
+)
+
+// PercentageRollout is the rollout plan of the percentage bean with the same id,
+// its steps are applied to the distributions of the bean one after another.
+// BeanDistributions are the distributions the rollout expects on the bean: those of the last applied step,
+// or those the bean had when the rollout was created or resumed
+type PercentageRollout struct {
+	ID                string                   `json:"id"`
+	ApplicationType   string                   `json:"applicationType"`
+	Status            string                   `json:"status"`
+	Steps             []*PercentageRolloutStep `json:"steps"`
+	CurrentStep       int                      `json:"currentStep"`
+	NextStepAt        int64                    `json:"nextStepAt,omitempty"`
+	Message           string                   `json:"message,omitempty"`
+	BeanDistributions []*firmware.ConfigEntry  `json:"beanDistributions"`
+	CreatedBy         string                   `json:"createdBy,omitempty"`
+	Created           int64                    `json:"created"`
+	Updated           int64                    `json:"updated"`
+}
+
+// PercentageRolloutStep holds the distributions of one step. The step starts no earlier than StartAt (epoch millis)
+// and the next step starts no earlier than DurationMinutes after this one is applied
+type PercentageRolloutStep struct {
+	Distributions   []*firmware.ConfigEntry `json:"distributions"`
+	DurationMinutes int                     `json:"durationMinutes,omitempty"`
+	StartAt         int64                   `json:"startAt,omitempty"`
+	AppliedAt       int64                   `json:"appliedAt,omitempty"`
+}
+
+// PercentageRolloutProgress is the state of a rollout shown with its percentage bean
+type PercentageRolloutProgress struct {
+	Status      string `json:"status"`
+	CurrentStep int    `json:"currentStep"`
+	StepCount   int    `json:"stepCount"`
+	NextStepAt  int64  `json:"nextStepAt,omitempty"`
+}
+
+func NewPercentageRolloutInf() interface{} {
+	return &PercentageRollout{}
+}
+
+// IsOpen returns true if the rollout can still advance
+func (p *PercentageRollout) IsOpen() bool {
+	return p.Status == PERCENTAGE_ROLLOUT_ACTIVE || p.Status == PERCENTAGE_ROLLOUT_PAUSED || p.Status == PERCENTAGE_ROLLOUT_FAILED
+}
+
+// GetNextStep returns the step to apply next, nil if all steps are applied.
+// CurrentStep is the number of applied steps
+func (p *PercentageRollout) GetNextStep() *PercentageRolloutStep {
+	if p.CurrentStep >= len(p.Steps) {
+		return nil
+	}
+	return p.Steps[p.CurrentStep]
+}
+
+// nextStepTime returns when the next step is due in epoch millis, 0 if there is no next step
+func (p *PercentageRollout) nextStepTime() int64 {
+	step := p.GetNextStep()
+	if step == nil {
+		return 0
+	}
+	dueAt := step.StartAt
+	if p.CurrentStep > 0 {
+		previous := p.Steps[p.CurrentStep-1]
+		if holdUntil := previous.AppliedAt + int64(previous.DurationMinutes)*int64(time.Minute/time.Millisecond); holdUntil > dueAt {
+			dueAt = holdUntil
+		}
+	}
+	if dueAt < p.Created {
+		dueAt = p.Created
+	}
+	return dueAt
+}
+
+// IsDue returns true if the next step of an active rollout must be applied at the given time
+func (p *PercentageRollout) IsDue(now time.Time) bool {
+	return p.Status == PERCENTAGE_ROLLOUT_ACTIVE && p.NextStepAt > 0 && p.NextStepAt <= util.GetTimestamp(now.UTC())
+}
+
+// IsBeanModified returns true if the distributions of the bean are not the ones the rollout left,
+// rollouts saved before the distributions were kept are never considered modified
+func (p *PercentageRollout) IsBeanModified(distributions []*firmware.ConfigEntry) bool {
+	if p.BeanDistributions == nil {
+		return false
+	}
+	if len(distributions) != len(p.BeanDistributions) {
+		return true
+	}
+	for i, entry := range distributions {
+		expected := p.BeanDistributions[i]
+		if entry == nil || expected == nil {
+			if entry != expected {
+				return true
+			}
+			continue
+		}
+		if *entry != *expected {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *PercentageRollout) GetProgress() *PercentageRolloutProgress {
+	return &PercentageRolloutProgress{
+		Status:      p.Status,
+		CurrentStep: p.CurrentStep,
+		StepCount:   len(p.Steps),
+		NextStepAt:  p.NextStepAt,
+	}
+}
+
+func GetPercentageRollout(id string) *PercentageRollout {
+	inst, err := db.GetSimpleDao().GetOne(xcommon.TABLE_PERCENTAGE_ROLLOUTS, id)
+	if err != nil {
+		return nil
+	}
+	return inst.(*PercentageRollout)
+}
+
+// GetPercentageRollouts returns all rollouts, the earliest due first
+func GetPercentageRollouts() []*PercentageRollout {
+	result := []*PercentageRollout{}
+	list, err := db.GetSimpleDao().GetAllAsList(xcommon.TABLE_PERCENTAGE_ROLLOUTS, 0)
+	if err != nil {
+		return result
+	}
+	for _, inst := range list {
+		result = append(result, inst.(*PercentageRollout))
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].NextStepAt < result[j].NextStepAt
+	})
+	return result
+}
+
+// SetPercentageRollout saves the rollout, the time of the next step is only kept while the rollout is active.
+// Updated grows with every save, the steps are claimed by it
+func SetPercentageRollout(rollout *PercentageRollout) error {
+	updated := util.GetTimestamp(time.Now().UTC())
+	if updated <= rollout.Updated {
+		updated = rollout.Updated + 1
+	}
+	rollout.Updated = updated
+	rollout.NextStepAt = 0
+	if rollout.Status == PERCENTAGE_ROLLOUT_ACTIVE {
+		rollout.NextStepAt = rollout.nextStepTime()
+	}
+	bytes, err := json.Marshal(rollout)
+	if err != nil {
+		return err
+	}
+	return db.GetSimpleDao().SetOne(xcommon.TABLE_PERCENTAGE_ROLLOUTS, rollout.ID, bytes)
+}
+
+func DeletePercentageRollout(id string) error {
+	return db.GetSimpleDao().DeleteOne(xcommon.TABLE_PERCENTAGE_ROLLOUTS, id)
+}
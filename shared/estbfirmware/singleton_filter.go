@@ -20,11 +20,15 @@ package estbfirmware
 import (
 	"fmt"
 	"strings"
+	xshared "xconfadmin/shared"
 	"xconfwebconfig/shared"
 	coreef "xconfwebconfig/shared/estbfirmware"
 )
 
 func GetRoundRobinIdByApplication(applicationType string) string {
+	if appType := xshared.GetApplicationType(applicationType); appType != nil && appType.RoundRobinFilterId != "" {
+		return appType.RoundRobinFilterId
+	}
 	if shared.STB == applicationType {
 		return coreef.ROUND_ROBIN_FILTER_SINGLETON_ID
 	}
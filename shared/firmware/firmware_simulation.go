@@ -0,0 +1,186 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package firmware
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	xcommon "xconfadmin/common"
+	"xconfwebconfig/db"
+	"xconfwebconfig/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// firmware simulation job status
+const (
+	FIRMWARE_SIMULATION_RUNNING   = "RUNNING"
+	FIRMWARE_SIMULATION_COMPLETED = "COMPLETED"
+	FIRMWARE_SIMULATION_FAILED    = "FAILED"
+)
+
+// FIRMWARE_SIMULATION_NONE counts the devices which get no firmware version or match no rule
+const FIRMWARE_SIMULATION_NONE = "NONE"
+
+// FirmwareSimulationJob is a batch of device contexts evaluated by the firmware rules of an application type,
+// Owner is the admin instance running it
+type FirmwareSimulationJob struct {
+	ID              string                    `json:"id"`
+	ApplicationType string                    `json:"applicationType"`
+	Status          string                    `json:"status"`
+	Total           int                       `json:"total"`
+	Processed       int                       `json:"processed"`
+	Summary         FirmwareSimulationSummary `json:"summary"`
+	Message         string                    `json:"message,omitempty"`
+	Owner           string                    `json:"owner,omitempty"`
+	CreatedBy       string                    `json:"createdBy,omitempty"`
+	Created         int64                     `json:"created"`
+	Updated         int64                     `json:"updated"`
+}
+
+// FirmwareSimulationSummary holds the number of devices per firmware version, matched rule and blocking filter
+type FirmwareSimulationSummary struct {
+	FirmwareVersions map[string]int `json:"firmwareVersions"`
+	MatchedRules     map[string]int `json:"matchedRules"`
+	BlockingFilters  map[string]int `json:"blockingFilters"`
+	Blocked          int            `json:"blocked"`
+	Errors           int            `json:"errors"`
+}
+
+// FirmwareSimulationResult is the evaluation of one device of a job
+type FirmwareSimulationResult struct {
+	JobID           string            `json:"jobId"`
+	Index           int               `json:"index"`
+	Context         map[string]string `json:"context"`
+	FirmwareVersion string            `json:"firmwareVersion,omitempty"`
+	MatchedRuleID   string            `json:"matchedRuleId,omitempty"`
+	MatchedRuleName string            `json:"matchedRuleName,omitempty"`
+	MatchedRuleType string            `json:"matchedRuleType,omitempty"`
+	Blocked         bool              `json:"blocked"`
+	BlockingFilter  string            `json:"blockingFilter,omitempty"`
+	Description     string            `json:"description,omitempty"`
+	Error           string            `json:"error,omitempty"`
+}
+
+func NewFirmwareSimulationJobInf() interface{} {
+	return &FirmwareSimulationJob{}
+}
+
+func NewFirmwareSimulationResultInf() interface{} {
+	return &FirmwareSimulationResult{}
+}
+
+func NewFirmwareSimulationSummary() FirmwareSimulationSummary {
+	return FirmwareSimulationSummary{
+		FirmwareVersions: map[string]int{},
+		MatchedRules:     map[string]int{},
+		BlockingFilters:  map[string]int{},
+	}
+}
+
+// Add counts the result of one device
+func (s *FirmwareSimulationSummary) Add(result *FirmwareSimulationResult) {
+	if result.Error != "" {
+		s.Errors++
+		return
+	}
+	s.FirmwareVersions[valueOrNone(result.FirmwareVersion)]++
+	s.MatchedRules[valueOrNone(result.MatchedRuleName)]++
+	if result.Blocked {
+		s.Blocked++
+		s.BlockingFilters[result.BlockingFilter]++
+	}
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return FIRMWARE_SIMULATION_NONE
+	}
+	return value
+}
+
+func GetFirmwareSimulationJob(id string) *FirmwareSimulationJob {
+	inst, err := db.GetSimpleDao().GetOne(xcommon.TABLE_FIRMWARE_SIMULATIONS, id)
+	if err != nil {
+		return nil
+	}
+	return inst.(*FirmwareSimulationJob)
+}
+
+// GetFirmwareSimulationJobs returns the jobs of the application type, newest first
+func GetFirmwareSimulationJobs(applicationType string) []*FirmwareSimulationJob {
+	result := []*FirmwareSimulationJob{}
+	for _, job := range GetAllFirmwareSimulationJobs() {
+		if job.ApplicationType == applicationType {
+			result = append(result, job)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Created > result[j].Created
+	})
+	return result
+}
+
+func GetAllFirmwareSimulationJobs() []*FirmwareSimulationJob {
+	result := []*FirmwareSimulationJob{}
+	list, err := db.GetSimpleDao().GetAllAsList(xcommon.TABLE_FIRMWARE_SIMULATIONS, 0)
+	if err != nil {
+		return result
+	}
+	for _, inst := range list {
+		result = append(result, inst.(*FirmwareSimulationJob))
+	}
+	return result
+}
+
+func SetFirmwareSimulationJob(job *FirmwareSimulationJob) error {
+	job.Updated = util.GetTimestamp(time.Now().UTC())
+	bytes, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return db.GetSimpleDao().SetOne(xcommon.TABLE_FIRMWARE_SIMULATIONS, job.ID, bytes)
+}
+
+// GetFirmwareSimulationResults returns the device results of the job in the order of the upload
+func GetFirmwareSimulationResults(jobId string) []*FirmwareSimulationResult {
+	result := []*FirmwareSimulationResult{}
+	list, err := db.GetListingDao().GetAll(xcommon.TABLE_FIRMWARE_SIMULATION_RESULTS, jobId)
+	if err != nil {
+		log.Warnf("no results of firmware simulation %s found", jobId)
+		return result
+	}
+	for _, inst := range list {
+		result = append(result, inst.(*FirmwareSimulationResult))
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Index < result[j].Index
+	})
+	return result
+}
+
+func SetFirmwareSimulationResult(result *FirmwareSimulationResult) error {
+	bytes, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return db.GetListingDao().SetOne(xcommon.TABLE_FIRMWARE_SIMULATION_RESULTS, result.JobID, fmt.Sprintf("%08d", result.Index), bytes)
+}
@@ -0,0 +1,178 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	xcommon "xconfadmin/common"
+	"xconfwebconfig/db"
+	"xconfwebconfig/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// entity types which carry an owning group
+const (
+	OWNED_FIRMWARE_RULE         = "FirmwareRule"
+	OWNED_FEATURE_RULE          = "FeatureRule"
+	OWNED_DCM_FORMULA           = "DcmFormula"
+	OWNED_TELEMETRY_PROFILE     = "PermanentTelemetryProfile"
+	OWNED_TELEMETRY_TWO_PROFILE = "TelemetryTwoProfile"
+	OWNED_NAMESPACED_LIST       = "NamespacedList"
+)
+
+var OwnedEntityTypes = []string{
+	OWNED_FIRMWARE_RULE,
+	OWNED_FEATURE_RULE,
+	OWNED_DCM_FORMULA,
+	OWNED_TELEMETRY_PROFILE,
+	OWNED_TELEMETRY_TWO_PROFILE,
+	OWNED_NAMESPACED_LIST,
+}
+
+// EntityOwner stores the owning group of an entity
+type EntityOwner struct {
+	ID         string `json:"id"`
+	EntityType string `json:"entityType"`
+	EntityID   string `json:"entityId"`
+	OwnerGroup string `json:"ownerGroup"`
+	Updated    int64  `json:"updated"`
+	UpdatedBy  string `json:"updatedBy,omitempty"`
+}
+
+// OwnershipGroupMapping is the local mapping of a user to ownership groups
+type OwnershipGroupMapping struct {
+	ID      string   `json:"id"`
+	Groups  []string `json:"groups"`
+	Admin   bool     `json:"admin"`
+	Updated int64    `json:"updated"`
+}
+
+func NewEntityOwnerInf() interface{} {
+	return &EntityOwner{}
+}
+
+func NewOwnershipGroupMappingInf() interface{} {
+	return &OwnershipGroupMapping{}
+}
+
+func IsOwnedEntityType(entityType string) bool {
+	return util.Contains(OwnedEntityTypes, entityType)
+}
+
+func entityOwnerId(entityType string, entityId string) string {
+	return entityType + "_" + entityId
+}
+
+// GetEntityOwner returns the owning group of the entity, empty if the entity is not owned
+func GetEntityOwner(entityType string, entityId string) string {
+	inst, err := db.GetSimpleDao().GetOne(xcommon.TABLE_ENTITY_OWNERS, entityOwnerId(entityType, entityId))
+	if err != nil {
+		return ""
+	}
+	return inst.(*EntityOwner).OwnerGroup
+}
+
+// GetEntityOwnersByType returns entityId to owning group for the entity type
+func GetEntityOwnersByType(entityType string) map[string]string {
+	result := map[string]string{}
+	list, err := db.GetSimpleDao().GetAllAsList(xcommon.TABLE_ENTITY_OWNERS, 0)
+	if err != nil {
+		return result
+	}
+	for _, inst := range list {
+		owner := inst.(*EntityOwner)
+		if owner.EntityType == entityType {
+			result[owner.EntityID] = owner.OwnerGroup
+		}
+	}
+	return result
+}
+
+func SetEntityOwner(entityType string, entityId string, ownerGroup string, userName string) error {
+	owner := EntityOwner{
+		ID:         entityOwnerId(entityType, entityId),
+		EntityType: entityType,
+		EntityID:   entityId,
+		OwnerGroup: ownerGroup,
+		Updated:    util.GetTimestamp(time.Now().UTC()),
+		UpdatedBy:  userName,
+	}
+	bytes, err := json.Marshal(owner)
+	if err != nil {
+		return err
+	}
+	return db.GetSimpleDao().SetOne(xcommon.TABLE_ENTITY_OWNERS, owner.ID, bytes)
+}
+
+func DeleteEntityOwner(entityType string, entityId string) {
+	if err := db.GetSimpleDao().DeleteOne(xcommon.TABLE_ENTITY_OWNERS, entityOwnerId(entityType, entityId)); err != nil {
+		log.Debugf("unable to delete owner of %s %s: %v", entityType, entityId, err)
+	}
+}
+
+func GetOwnershipGroupMappings() []*OwnershipGroupMapping {
+	result := []*OwnershipGroupMapping{}
+	list, err := db.GetSimpleDao().GetAllAsList(xcommon.TABLE_OWNERSHIP_GROUP_MAPPINGS, 0)
+	if err != nil {
+		return result
+	}
+	for _, inst := range list {
+		result = append(result, inst.(*OwnershipGroupMapping))
+	}
+	return result
+}
+
+func GetOwnershipGroupMapping(userName string) *OwnershipGroupMapping {
+	inst, err := db.GetSimpleDao().GetOne(xcommon.TABLE_OWNERSHIP_GROUP_MAPPINGS, userName)
+	if err != nil {
+		return nil
+	}
+	return inst.(*OwnershipGroupMapping)
+}
+
+func SetOwnershipGroupMapping(mapping *OwnershipGroupMapping) error {
+	if strings.TrimSpace(mapping.ID) == "" {
+		return xcommon.NewXconfError(http.StatusBadRequest, "User name is empty")
+	}
+	groups := []string{}
+	for _, group := range mapping.Groups {
+		if group = strings.TrimSpace(group); group != "" && !util.Contains(groups, group) {
+			groups = append(groups, group)
+		}
+	}
+	mapping.Groups = groups
+	mapping.Updated = util.GetTimestamp(time.Now().UTC())
+	bytes, err := json.Marshal(mapping)
+	if err != nil {
+		return err
+	}
+	return db.GetSimpleDao().SetOne(xcommon.TABLE_OWNERSHIP_GROUP_MAPPINGS, mapping.ID, bytes)
+}
+
+func DeleteOwnershipGroupMapping(userName string) error {
+	if GetOwnershipGroupMapping(userName) == nil {
+		return xcommon.NewXconfError(http.StatusNotFound, fmt.Sprintf("Ownership mapping for %s does not exist", userName))
+	}
+	return db.GetSimpleDao().DeleteOne(xcommon.TABLE_OWNERSHIP_GROUP_MAPPINGS, userName)
+}
@@ -0,0 +1,142 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package schema
+
+import (
+	xcommon "xconfadmin/common"
+	xshared "xconfadmin/shared"
+	xchange "xconfadmin/shared/change"
+	xcoreef "xconfadmin/shared/estbfirmware"
+	xcorefw "xconfadmin/shared/firmware"
+	"xconfwebconfig/db"
+)
+
+// RegisterTables registers the tables owned by the admin service
+func RegisterTables() {
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_APPLICATION_TYPES,
+		ConstructorFunc: xshared.NewApplicationTypeInf,
+	})
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_SERVICE_ACCOUNTS,
+		ConstructorFunc: xshared.NewServiceAccountInf,
+	})
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_SERVICE_ACCOUNT_KEYS,
+		ConstructorFunc: xshared.NewServiceAccountKeyInf,
+	})
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_ENTITY_OWNERS,
+		ConstructorFunc: xshared.NewEntityOwnerInf,
+	})
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_OWNERSHIP_GROUP_MAPPINGS,
+		ConstructorFunc: xshared.NewOwnershipGroupMappingInf,
+	})
+	// audit entries expire with the retention period
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_AUDIT_LOG,
+		ConstructorFunc: xshared.NewAuditEntryInf,
+		TTL:             xcommon.AuditRetentionDays * 24 * 60 * 60,
+	})
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_ENTITY_CHANGES,
+		ConstructorFunc: xchange.NewEntityChangeInf,
+	})
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_APPROVED_ENTITY_CHANGES,
+		ConstructorFunc: xchange.NewApprovedEntityChangeInf,
+	})
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_APPROVAL_SETTINGS,
+		ConstructorFunc: xchange.NewApprovalSettingInf,
+	})
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_APPROVAL_POLICIES,
+		ConstructorFunc: xchange.NewApprovalPolicyInf,
+	})
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_SCHEDULED_CHANGES,
+		ConstructorFunc: xchange.NewScheduledChangeInf,
+	})
+	// claims and write locks are written by xchange.ClaimRow with their TTL, the TTL here matches it
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_SCHEDULED_CHANGE_CLAIMS,
+		ConstructorFunc: xchange.NewClaimInf,
+		TTL:             xchange.ScheduledChangeClaimTtlSeconds,
+	})
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_ENTITY_WRITE_LOCKS,
+		ConstructorFunc: xchange.NewClaimInf,
+		TTL:             xchange.EntityWriteLockTtlSeconds,
+	})
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_CHANGE_COMMENTS,
+		ConstructorFunc: xchange.NewChangeCommentInf,
+	})
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_CHANGE_REVIEWS,
+		ConstructorFunc: xchange.NewChangeReviewInf,
+	})
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_CHANGE_APPROVALS,
+		ConstructorFunc: xchange.NewChangeApprovalsInf,
+	})
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_WEBHOOK_SUBSCRIPTIONS,
+		ConstructorFunc: xshared.NewWebhookSubscriptionInf,
+	})
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_WEBHOOK_SECRETS,
+		ConstructorFunc: xshared.NewWebhookSecretInf,
+	})
+	// the delivery log is kept for the retention period
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_WEBHOOK_DELIVERIES,
+		ConstructorFunc: xshared.NewWebhookDeliveryInf,
+		TTL:             xcommon.WebhookDeliveryRetentionDays * 24 * 60 * 60,
+	})
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_WEBHOOK_PENDING_DELIVERIES,
+		ConstructorFunc: xshared.NewWebhookPendingDeliveryInf,
+		TTL:             xcommon.WebhookDeliveryRetentionDays * 24 * 60 * 60,
+	})
+	// simulation jobs and their device results are kept for the retention period
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_FIRMWARE_SIMULATIONS,
+		ConstructorFunc: xcorefw.NewFirmwareSimulationJobInf,
+		TTL:             xcommon.FirmwareSimulationRetentionDays * 24 * 60 * 60,
+	})
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_FIRMWARE_SIMULATION_RESULTS,
+		ConstructorFunc: xcorefw.NewFirmwareSimulationResultInf,
+		TTL:             xcommon.FirmwareSimulationRetentionDays * 24 * 60 * 60,
+	})
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_PERCENTAGE_ROLLOUTS,
+		ConstructorFunc: xcoreef.NewPercentageRolloutInf,
+	})
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_FIRMWARE_CONFIG_LIFECYCLES,
+		ConstructorFunc: xcoreef.NewFirmwareConfigLifecycleInf,
+	})
+	db.RegisterTableConfig(&db.TableInfo{
+		TableName:       xcommon.TABLE_FIRMWARE_VERSION_PATTERNS,
+		ConstructorFunc: xcoreef.NewFirmwareVersionPatternInf,
+	})
+}
@@ -0,0 +1,276 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package shared
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	xcommon "xconfadmin/common"
+	"xconfwebconfig/db"
+	"xconfwebconfig/util"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	apiKeyPrefix = "xak"
+
+	// last used timestamp is saved at most once per interval to limit db writes
+	apiKeyLastUsedUpdateInterval = time.Minute
+)
+
+var ErrInvalidApiKey = errors.New("invalid api key")
+
+// ServiceAccount is a named non-human account used by automation
+type ServiceAccount struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	CreatedBy   string `json:"createdBy,omitempty"`
+	Created     int64  `json:"created"`
+	Updated     int64  `json:"updated"`
+	Disabled    bool   `json:"disabled"`
+}
+
+// ServiceAccountKey is an api key of a ServiceAccount, only the hash of the secret is stored
+type ServiceAccountKey struct {
+	ID          string   `json:"id"`
+	AccountID   string   `json:"accountId"`
+	Name        string   `json:"name,omitempty"`
+	Hash        string   `json:"hash,omitempty"`
+	Permissions []string `json:"permissions"`
+	CreatedBy   string   `json:"createdBy,omitempty"`
+	Created     int64    `json:"created"`
+	ExpiresAt   int64    `json:"expiresAt,omitempty"`
+	LastUsed    int64    `json:"lastUsed,omitempty"`
+	RevokedAt   int64    `json:"revokedAt,omitempty"`
+	RevokedBy   string   `json:"revokedBy,omitempty"`
+}
+
+func NewServiceAccountInf() interface{} {
+	return &ServiceAccount{}
+}
+
+func NewServiceAccountKeyInf() interface{} {
+	return &ServiceAccountKey{}
+}
+
+func (obj *ServiceAccount) Validate() error {
+	if strings.TrimSpace(obj.Name) == "" {
+		return xcommon.NewXconfError(http.StatusBadRequest, "ServiceAccount name is empty")
+	}
+	for _, account := range GetServiceAccounts() {
+		if account.ID != obj.ID && strings.EqualFold(account.Name, obj.Name) {
+			return xcommon.NewXconfError(http.StatusConflict, "ServiceAccount with name "+obj.Name+" already exists")
+		}
+	}
+	return nil
+}
+
+// WithoutHash returns a copy of the key which can be returned to the client
+func (obj *ServiceAccountKey) WithoutHash() *ServiceAccountKey {
+	key := *obj
+	key.Hash = ""
+	return &key
+}
+
+func (obj *ServiceAccountKey) IsExpired(now time.Time) bool {
+	return obj.ExpiresAt > 0 && now.UnixMilli() >= obj.ExpiresAt
+}
+
+func (obj *ServiceAccountKey) IsRevoked() bool {
+	return obj.RevokedAt > 0
+}
+
+func GetServiceAccounts() []*ServiceAccount {
+	result := []*ServiceAccount{}
+	list, err := db.GetSimpleDao().GetAllAsList(xcommon.TABLE_SERVICE_ACCOUNTS, 0)
+	if err != nil {
+		log.Warn("no ServiceAccount found")
+		return result
+	}
+	for _, inst := range list {
+		result = append(result, inst.(*ServiceAccount))
+	}
+	return result
+}
+
+func GetServiceAccount(id string) *ServiceAccount {
+	inst, err := db.GetSimpleDao().GetOne(xcommon.TABLE_SERVICE_ACCOUNTS, id)
+	if err != nil {
+		log.Warn(fmt.Sprintf("no ServiceAccount found for Id: %s", id))
+		return nil
+	}
+	return inst.(*ServiceAccount)
+}
+
+func SetServiceAccount(account *ServiceAccount) error {
+	if util.IsBlank(account.ID) {
+		account.ID = uuid.New().String()
+	}
+	now := util.GetTimestamp(time.Now().UTC())
+	if account.Created == 0 {
+		account.Created = now
+	}
+	account.Updated = now
+	bytes, err := json.Marshal(account)
+	if err != nil {
+		return err
+	}
+	return db.GetSimpleDao().SetOne(xcommon.TABLE_SERVICE_ACCOUNTS, account.ID, bytes)
+}
+
+func DeleteServiceAccount(id string) error {
+	for _, key := range GetServiceAccountKeys(id) {
+		if err := db.GetSimpleDao().DeleteOne(xcommon.TABLE_SERVICE_ACCOUNT_KEYS, key.ID); err != nil {
+			return err
+		}
+	}
+	return db.GetSimpleDao().DeleteOne(xcommon.TABLE_SERVICE_ACCOUNTS, id)
+}
+
+func GetServiceAccountKeys(accountId string) []*ServiceAccountKey {
+	result := []*ServiceAccountKey{}
+	list, err := db.GetSimpleDao().GetAllAsList(xcommon.TABLE_SERVICE_ACCOUNT_KEYS, 0)
+	if err != nil {
+		return result
+	}
+	for _, inst := range list {
+		key := inst.(*ServiceAccountKey)
+		if key.AccountID == accountId {
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+func GetServiceAccountKey(id string) *ServiceAccountKey {
+	inst, err := db.GetSimpleDao().GetOne(xcommon.TABLE_SERVICE_ACCOUNT_KEYS, id)
+	if err != nil {
+		return nil
+	}
+	return inst.(*ServiceAccountKey)
+}
+
+func SetServiceAccountKey(key *ServiceAccountKey) error {
+	bytes, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	return db.GetSimpleDao().SetOne(xcommon.TABLE_SERVICE_ACCOUNT_KEYS, key.ID, bytes)
+}
+
+// IssueServiceAccountKey creates a key and returns it with the plain api key, which is not retrievable later
+func IssueServiceAccountKey(key *ServiceAccountKey) (string, error) {
+	keyId, err := randomString(9)
+	if err != nil {
+		return "", err
+	}
+	secret, err := randomString(32)
+	if err != nil {
+		return "", err
+	}
+	key.ID = keyId
+	key.Hash = hashApiKeySecret(secret)
+	key.Created = util.GetTimestamp(time.Now().UTC())
+	key.LastUsed = 0
+	key.RevokedAt = 0
+	if err := SetServiceAccountKey(key); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s.%s.%s", apiKeyPrefix, keyId, secret), nil
+}
+
+// ValidateApiKey returns the account and key of a valid api key and records its usage
+func ValidateApiKey(apiKey string) (*ServiceAccount, *ServiceAccountKey, error) {
+	parts := strings.Split(strings.TrimSpace(apiKey), ".")
+	if len(parts) != 3 || parts[0] != apiKeyPrefix {
+		return nil, nil, ErrInvalidApiKey
+	}
+	key := GetServiceAccountKey(parts[1])
+	if key == nil {
+		return nil, nil, ErrInvalidApiKey
+	}
+	if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hashApiKeySecret(parts[2]))) != 1 {
+		return nil, nil, ErrInvalidApiKey
+	}
+	now := time.Now()
+	account, err := getUsableServiceAccount(key, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	if now.UnixMilli()-key.LastUsed > apiKeyLastUsedUpdateInterval.Milliseconds() {
+		key.LastUsed = now.UnixMilli()
+		if err := SetServiceAccountKey(key); err != nil {
+			log.Warnf("unable to update last used time of api key %s: %s", key.ID, err.Error())
+		}
+	}
+	return account, key, nil
+}
+
+// GetUsableServiceAccountKey returns the key and its account if the key can still be used,
+// an error if it was revoked, expired or its account was disabled
+func GetUsableServiceAccountKey(keyId string, now time.Time) (*ServiceAccount, *ServiceAccountKey, error) {
+	key := GetServiceAccountKey(keyId)
+	if key == nil {
+		return nil, nil, ErrInvalidApiKey
+	}
+	account, err := getUsableServiceAccount(key, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	return account, key, nil
+}
+
+func getUsableServiceAccount(key *ServiceAccountKey, now time.Time) (*ServiceAccount, error) {
+	if key.IsRevoked() {
+		return nil, errors.New("api key has been revoked")
+	}
+	if key.IsExpired(now) {
+		return nil, errors.New("api key has expired")
+	}
+	account := GetServiceAccount(key.AccountID)
+	if account == nil || account.Disabled {
+		return nil, errors.New("service account is disabled or does not exist")
+	}
+	return account, nil
+}
+
+func hashApiKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(size int) (string, error) {
+	bytes := make([]byte, size)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
@@ -0,0 +1,412 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	xcommon "xconfadmin/common"
+	"xconfwebconfig/db"
+	"xconfwebconfig/util"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// webhook delivery status
+const (
+	WEBHOOK_DELIVERY_PENDING = "PENDING"
+	WEBHOOK_DELIVERY_SUCCESS = "SUCCESS"
+	WEBHOOK_DELIVERY_FAILED  = "FAILED"
+)
+
+const webhookSubscriptionReloadInterval = time.Minute
+
+var webhookOperations = []string{
+	AUDIT_CREATE,
+	AUDIT_UPDATE,
+	AUDIT_DELETE,
+	AUDIT_IMPORT,
+	AUDIT_PRIORITY_CHANGE,
+	AUDIT_APPROVE,
+	AUDIT_REVERT,
+	AUDIT_CANCEL,
+	AUDIT_EXPIRE,
+	AUDIT_REJECT,
+	AUDIT_ROLLOUT_STEP,
+}
+
+// WebhookSubscription receives the events of the admin writes which match its filters,
+// an empty filter matches every value. Entity types are the ones of the audit log, e.g. Firmware-Rules
+type WebhookSubscription struct {
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	URL              string   `json:"url"`
+	EntityTypes      []string `json:"entityTypes"`
+	ApplicationTypes []string `json:"applicationTypes"`
+	Operations       []string `json:"operations"`
+	Disabled         bool     `json:"disabled"`
+	CreatedBy        string   `json:"createdBy,omitempty"`
+	Created          int64    `json:"created"`
+	Updated          int64    `json:"updated"`
+}
+
+// WebhookSecret is the HMAC signing secret of a subscription, it is kept apart so the subscription
+// can be returned and audited
+type WebhookSecret struct {
+	ID     string `json:"id"`
+	Secret string `json:"secret"`
+}
+
+// WebhookEvent is the payload sent to the subscriptions
+type WebhookEvent struct {
+	ID              string          `json:"id"`
+	Timestamp       int64           `json:"timestamp"`
+	Operation       string          `json:"operation"`
+	EntityType      string          `json:"entityType"`
+	EntityID        string          `json:"entityId,omitempty"`
+	ApplicationType string          `json:"applicationType,omitempty"`
+	User            string          `json:"user"`
+	Before          json.RawMessage `json:"before,omitempty"`
+	After           json.RawMessage `json:"after,omitempty"`
+}
+
+// WebhookDelivery records the attempts to send one event to a subscription
+type WebhookDelivery struct {
+	ID             string `json:"id"`
+	SubscriptionID string `json:"subscriptionId"`
+	EventID        string `json:"eventId"`
+	Operation      string `json:"operation"`
+	EntityType     string `json:"entityType"`
+	EntityID       string `json:"entityId,omitempty"`
+	Status         string `json:"status"`
+	Attempts       int    `json:"attempts"`
+	ResponseStatus int    `json:"responseStatus,omitempty"`
+	Error          string `json:"error,omitempty"`
+	NextAttempt    int64  `json:"nextAttempt,omitempty"`
+	Created        int64  `json:"created"`
+	Updated        int64  `json:"updated"`
+}
+
+// WebhookPendingDelivery keeps an unfinished delivery with its payload, so the retries survive a restart
+type WebhookPendingDelivery struct {
+	ID       string           `json:"id"`
+	Delivery *WebhookDelivery `json:"delivery"`
+	Payload  json.RawMessage  `json:"payload"`
+}
+
+func NewWebhookSubscriptionInf() interface{} {
+	return &WebhookSubscription{}
+}
+
+func NewWebhookSecretInf() interface{} {
+	return &WebhookSecret{}
+}
+
+func NewWebhookDeliveryInf() interface{} {
+	return &WebhookDelivery{}
+}
+
+func NewWebhookPendingDeliveryInf() interface{} {
+	return &WebhookPendingDelivery{}
+}
+
+// NewWebhookEvent returns the event of an audited write
+func NewWebhookEvent(entry *AuditEntry) *WebhookEvent {
+	return &WebhookEvent{
+		ID:              entry.ID,
+		Timestamp:       entry.Timestamp,
+		Operation:       entry.Operation,
+		EntityType:      entry.EntityType,
+		EntityID:        entry.EntityID,
+		ApplicationType: entry.ApplicationType,
+		User:            entry.User,
+		Before:          entry.Before,
+		After:           entry.After,
+	}
+}
+
+func (obj *WebhookSubscription) Validate() error {
+	if strings.TrimSpace(obj.Name) == "" {
+		return xcommon.NewXconfError(http.StatusBadRequest, "Webhook name is empty")
+	}
+	target, err := url.Parse(obj.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return xcommon.NewXconfError(http.StatusBadRequest, "Webhook url must be an absolute http or https url")
+	}
+	if target.User != nil {
+		return xcommon.NewXconfError(http.StatusBadRequest, "Webhook url must not have credentials")
+	}
+	ips, err := net.LookupIP(target.Hostname())
+	if err != nil || len(ips) == 0 {
+		return xcommon.NewXconfError(http.StatusBadRequest, fmt.Sprintf("Webhook host %s cannot be resolved", target.Hostname()))
+	}
+	for _, ip := range ips {
+		if !IsWebhookAddressAllowed(ip) {
+			return xcommon.NewXconfError(http.StatusBadRequest, fmt.Sprintf("Webhook host %s resolves to the internal address %s", target.Hostname(), ip.String()))
+		}
+	}
+	for _, operation := range obj.Operations {
+		if !util.Contains(webhookOperations, operation) {
+			return xcommon.NewXconfError(http.StatusBadRequest, fmt.Sprintf("Unknown operation %s, expected one of %v", operation, webhookOperations))
+		}
+	}
+	return nil
+}
+
+// Matches returns true if the event passes the filters of an enabled subscription
+func (obj *WebhookSubscription) Matches(event *WebhookEvent) bool {
+	if obj.Disabled {
+		return false
+	}
+	if len(obj.EntityTypes) > 0 && !containsIgnoreCase(obj.EntityTypes, event.EntityType) {
+		return false
+	}
+	if len(obj.Operations) > 0 && !util.Contains(obj.Operations, event.Operation) {
+		return false
+	}
+	if len(obj.ApplicationTypes) > 0 {
+		for _, applicationType := range obj.ApplicationTypes {
+			if ApplicationTypeEquals(applicationType, event.ApplicationType) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// IsWebhookAddressAllowed returns false for the loopback, private, link-local, multicast and unspecified
+// addresses unless the private networks are allowed, so the webhooks cannot reach internal services
+func IsWebhookAddressAllowed(ip net.IP) bool {
+	if xcommon.WebhookAllowPrivateNetworks {
+		return true
+	}
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+func containsIgnoreCase(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func GetWebhookSubscriptions() []*WebhookSubscription {
+	result := []*WebhookSubscription{}
+	list, err := db.GetSimpleDao().GetAllAsList(xcommon.TABLE_WEBHOOK_SUBSCRIPTIONS, 0)
+	if err != nil {
+		return result
+	}
+	for _, inst := range list {
+		result = append(result, inst.(*WebhookSubscription))
+	}
+	return result
+}
+
+type webhookSubscriptionRegistry struct {
+	sync.RWMutex
+	subscriptions []*WebhookSubscription
+	loadedAt      time.Time
+}
+
+var subscriptionRegistry = &webhookSubscriptionRegistry{}
+
+func (reg *webhookSubscriptionRegistry) load() []*WebhookSubscription {
+	subscriptions := GetWebhookSubscriptions()
+	reg.Lock()
+	reg.subscriptions = subscriptions
+	reg.loadedAt = time.Now()
+	reg.Unlock()
+	return subscriptions
+}
+
+func (reg *webhookSubscriptionRegistry) get() []*WebhookSubscription {
+	reg.RLock()
+	subscriptions := reg.subscriptions
+	expired := time.Since(reg.loadedAt) > webhookSubscriptionReloadInterval
+	reg.RUnlock()
+	if subscriptions == nil || expired {
+		return reg.load()
+	}
+	return subscriptions
+}
+
+// ReloadWebhookSubscriptions refreshes the subscriptions the events are matched against
+func ReloadWebhookSubscriptions() {
+	subscriptionRegistry.load()
+}
+
+// GetMatchingWebhookSubscriptions returns the enabled subscriptions whose filters match the event,
+// the subscriptions are reloaded from the db once per interval and on every change
+func GetMatchingWebhookSubscriptions(event *WebhookEvent) []*WebhookSubscription {
+	result := []*WebhookSubscription{}
+	for _, subscription := range subscriptionRegistry.get() {
+		if subscription.Matches(event) {
+			result = append(result, subscription)
+		}
+	}
+	return result
+}
+
+func GetWebhookSubscription(id string) *WebhookSubscription {
+	inst, err := db.GetSimpleDao().GetOne(xcommon.TABLE_WEBHOOK_SUBSCRIPTIONS, id)
+	if err != nil {
+		log.Warn(fmt.Sprintf("no WebhookSubscription found for Id: %s", id))
+		return nil
+	}
+	return inst.(*WebhookSubscription)
+}
+
+func SetWebhookSubscription(subscription *WebhookSubscription) error {
+	if util.IsBlank(subscription.ID) {
+		subscription.ID = uuid.New().String()
+	}
+	now := util.GetTimestamp(time.Now().UTC())
+	if subscription.Created == 0 {
+		subscription.Created = now
+	}
+	subscription.Updated = now
+	bytes, err := json.Marshal(subscription)
+	if err != nil {
+		return err
+	}
+	if err := db.GetSimpleDao().SetOne(xcommon.TABLE_WEBHOOK_SUBSCRIPTIONS, subscription.ID, bytes); err != nil {
+		return err
+	}
+	ReloadWebhookSubscriptions()
+	return nil
+}
+
+// DeleteWebhookSubscription deletes the subscription with its secret and delivery log
+func DeleteWebhookSubscription(id string) error {
+	if err := db.GetSimpleDao().DeleteOne(xcommon.TABLE_WEBHOOK_SECRETS, id); err != nil {
+		log.Warnf("unable to delete secret of webhook %s: %v", id, err)
+	}
+	if err := db.GetListingDao().DeleteAll(xcommon.TABLE_WEBHOOK_DELIVERIES, id); err != nil {
+		log.Warnf("unable to delete deliveries of webhook %s: %v", id, err)
+	}
+	for _, pending := range GetWebhookPendingDeliveries() {
+		if pending.Delivery.SubscriptionID == id {
+			DeleteWebhookPendingDelivery(pending.ID)
+		}
+	}
+	if err := db.GetSimpleDao().DeleteOne(xcommon.TABLE_WEBHOOK_SUBSCRIPTIONS, id); err != nil {
+		return err
+	}
+	ReloadWebhookSubscriptions()
+	return nil
+}
+
+func GetWebhookSecret(subscriptionId string) string {
+	inst, err := db.GetSimpleDao().GetOne(xcommon.TABLE_WEBHOOK_SECRETS, subscriptionId)
+	if err != nil {
+		return ""
+	}
+	return inst.(*WebhookSecret).Secret
+}
+
+func SetWebhookSecret(subscriptionId string, secret string) error {
+	bytes, err := json.Marshal(WebhookSecret{ID: subscriptionId, Secret: secret})
+	if err != nil {
+		return err
+	}
+	return db.GetSimpleDao().SetOne(xcommon.TABLE_WEBHOOK_SECRETS, subscriptionId, bytes)
+}
+
+// NewWebhookDelivery returns a pending delivery of the event to the subscription
+func NewWebhookDelivery(subscriptionId string, event *WebhookEvent) *WebhookDelivery {
+	now := util.GetTimestamp(time.Now().UTC())
+	return &WebhookDelivery{
+		// timestamp prefix keeps the deliveries of a subscription in order
+		ID:             fmt.Sprintf("%013d_%s", now, uuid.New().String()),
+		SubscriptionID: subscriptionId,
+		EventID:        event.ID,
+		Operation:      event.Operation,
+		EntityType:     event.EntityType,
+		EntityID:       event.EntityID,
+		Status:         WEBHOOK_DELIVERY_PENDING,
+		Created:        now,
+		Updated:        now,
+	}
+}
+
+// GetWebhookDeliveries returns the delivery log of the subscription, newest first
+func GetWebhookDeliveries(subscriptionId string) []*WebhookDelivery {
+	result := []*WebhookDelivery{}
+	list, err := db.GetListingDao().GetAll(xcommon.TABLE_WEBHOOK_DELIVERIES, subscriptionId)
+	if err != nil {
+		return result
+	}
+	for _, inst := range list {
+		result = append(result, inst.(*WebhookDelivery))
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].ID > result[j].ID
+	})
+	return result
+}
+
+func SetWebhookDelivery(delivery *WebhookDelivery) error {
+	delivery.Updated = util.GetTimestamp(time.Now().UTC())
+	bytes, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+	return db.GetListingDao().SetOne(xcommon.TABLE_WEBHOOK_DELIVERIES, delivery.SubscriptionID, delivery.ID, bytes)
+}
+
+// GetWebhookPendingDeliveries returns the deliveries which are waiting for an attempt
+func GetWebhookPendingDeliveries() []*WebhookPendingDelivery {
+	result := []*WebhookPendingDelivery{}
+	list, err := db.GetSimpleDao().GetAllAsList(xcommon.TABLE_WEBHOOK_PENDING_DELIVERIES, 0)
+	if err != nil {
+		return result
+	}
+	for _, inst := range list {
+		if pending := inst.(*WebhookPendingDelivery); pending.Delivery != nil {
+			result = append(result, pending)
+		}
+	}
+	return result
+}
+
+func SetWebhookPendingDelivery(pending *WebhookPendingDelivery) error {
+	bytes, err := json.Marshal(pending)
+	if err != nil {
+		return err
+	}
+	return db.GetSimpleDao().SetOne(xcommon.TABLE_WEBHOOK_PENDING_DELIVERIES, pending.ID, bytes)
+}
+
+func DeleteWebhookPendingDelivery(id string) {
+	if err := db.GetSimpleDao().DeleteOne(xcommon.TABLE_WEBHOOK_PENDING_DELIVERIES, id); err != nil {
+		log.Warnf("unable to delete pending webhook delivery %s: %v", id, err)
+	}
+}
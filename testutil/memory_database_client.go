@@ -0,0 +1,309 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package testutil
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"xconfwebconfig/db"
+
+	"github.com/gocql/gocql"
+)
+
+const singleValueColumn = "value"
+
+type memoryColumn struct {
+	key2  interface{}
+	value []byte
+}
+
+// MemoryDatabaseClient is an in-memory db.DatabaseClient for tests, rows are kept per table and row key
+// and the key2 of listing tables is ordered like a clustering column. TTLs are ignored
+type MemoryDatabaseClient struct {
+	mutex  sync.RWMutex
+	tables map[string]map[string]map[string]*memoryColumn
+}
+
+func NewMemoryDatabaseClient() *MemoryDatabaseClient {
+	return &MemoryDatabaseClient{tables: map[string]map[string]map[string]*memoryColumn{}}
+}
+
+func (c *MemoryDatabaseClient) SetUp() error {
+	return nil
+}
+
+// TearDown removes all the data
+func (c *MemoryDatabaseClient) TearDown() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.tables = map[string]map[string]map[string]*memoryColumn{}
+	return nil
+}
+
+func (c *MemoryDatabaseClient) Close() error {
+	return nil
+}
+
+func (c *MemoryDatabaseClient) Sleep() {}
+
+func (c *MemoryDatabaseClient) IsDbNotFound(err error) bool {
+	return err == gocql.ErrNotFound
+}
+
+func (c *MemoryDatabaseClient) set(tableName string, rowKey string, key2 interface{}, value []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	rows, ok := c.tables[tableName]
+	if !ok {
+		rows = map[string]map[string]*memoryColumn{}
+		c.tables[tableName] = rows
+	}
+	columns, ok := rows[rowKey]
+	if !ok {
+		columns = map[string]*memoryColumn{}
+		rows[rowKey] = columns
+	}
+	columns[fmt.Sprint(key2)] = &memoryColumn{key2: key2, value: append([]byte{}, value...)}
+}
+
+func (c *MemoryDatabaseClient) get(tableName string, rowKey string, key2 interface{}) ([]byte, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	column, ok := c.tables[tableName][rowKey][fmt.Sprint(key2)]
+	if !ok {
+		return nil, gocql.ErrNotFound
+	}
+	return append([]byte{}, column.value...), nil
+}
+
+// columns returns the columns of the row ordered by key2
+func (c *MemoryDatabaseClient) columns(tableName string, rowKey string) []*memoryColumn {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	result := []*memoryColumn{}
+	for _, column := range c.tables[tableName][rowKey] {
+		result = append(result, &memoryColumn{key2: column.key2, value: append([]byte{}, column.value...)})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return compareKey2(result[i].key2, result[j].key2) < 0
+	})
+	return result
+}
+
+func (c *MemoryDatabaseClient) rowKeys(tableName string) []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	keys := []string{}
+	for key := range c.tables[tableName] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func compareKey2(a interface{}, b interface{}) int {
+	af, aErr := strconv.ParseFloat(fmt.Sprint(a), 64)
+	bf, bErr := strconv.ParseFloat(fmt.Sprint(b), 64)
+	if aErr == nil && bErr == nil {
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		}
+		return 0
+	}
+	return bytes.Compare([]byte(fmt.Sprint(a)), []byte(fmt.Sprint(b)))
+}
+
+func (c *MemoryDatabaseClient) SetXconfData(tableName string, rowKey string, value []byte, ttl int) error {
+	c.set(tableName, rowKey, singleValueColumn, value)
+	return nil
+}
+
+// SetXconfDataIfNotExists stores the value only if the row does not exist, like a lightweight transaction
+func (c *MemoryDatabaseClient) SetXconfDataIfNotExists(tableName string, rowKey string, value []byte, ttl int) (bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if _, ok := c.tables[tableName][rowKey]; ok {
+		return false, nil
+	}
+	if _, ok := c.tables[tableName]; !ok {
+		c.tables[tableName] = map[string]map[string]*memoryColumn{}
+	}
+	c.tables[tableName][rowKey] = map[string]*memoryColumn{
+		singleValueColumn: {key2: singleValueColumn, value: append([]byte{}, value...)},
+	}
+	return true, nil
+}
+
+func (c *MemoryDatabaseClient) GetXconfData(tableName string, rowKey string) ([]byte, error) {
+	return c.get(tableName, rowKey, singleValueColumn)
+}
+
+func (c *MemoryDatabaseClient) GetAllXconfDataByKeys(tableName string, rowKeys []string) [][]byte {
+	result := [][]byte{}
+	for _, rowKey := range rowKeys {
+		if value, err := c.GetXconfData(tableName, rowKey); err == nil {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func (c *MemoryDatabaseClient) GetAllXconfKeys(tableName string) []string {
+	return c.rowKeys(tableName)
+}
+
+func (c *MemoryDatabaseClient) GetAllXconfDataAsList(tableName string, maxResults int) [][]byte {
+	result := [][]byte{}
+	for _, rowKey := range c.rowKeys(tableName) {
+		if maxResults > 0 && len(result) >= maxResults {
+			break
+		}
+		if value, err := c.GetXconfData(tableName, rowKey); err == nil {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func (c *MemoryDatabaseClient) GetAllXconfDataAsMap(tableName string, maxResults int) map[string][]byte {
+	result := map[string][]byte{}
+	for _, rowKey := range c.rowKeys(tableName) {
+		if maxResults > 0 && len(result) >= maxResults {
+			break
+		}
+		if value, err := c.GetXconfData(tableName, rowKey); err == nil {
+			result[rowKey] = value
+		}
+	}
+	return result
+}
+
+func (c *MemoryDatabaseClient) DeleteXconfData(tableName string, rowKey string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.tables[tableName], rowKey)
+	return nil
+}
+
+func (c *MemoryDatabaseClient) DeleteAllXconfData(tableName string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.tables, tableName)
+	return nil
+}
+
+func (c *MemoryDatabaseClient) GetAllXconfData(tableName string, rowKey string) [][]byte {
+	result := [][]byte{}
+	for _, column := range c.columns(tableName, rowKey) {
+		result = append(result, column.value)
+	}
+	return result
+}
+
+func (c *MemoryDatabaseClient) GetAllXconfDataTwoKeysRange(tableName string, rowKey interface{}, key2FieldName string, rangeInfo *db.RangeInfo) [][]byte {
+	result := [][]byte{}
+	for _, column := range c.columns(tableName, fmt.Sprint(rowKey)) {
+		if rangeInfo != nil {
+			if !rangeInfo.IsNilStartValue() && compareKey2(column.key2, rangeInfo.StartValue) <= 0 {
+				continue
+			}
+			if !rangeInfo.IsNilEndValue() && compareKey2(column.key2, rangeInfo.EndValue) >= 0 {
+				continue
+			}
+		}
+		result = append(result, column.value)
+	}
+	return result
+}
+
+func (c *MemoryDatabaseClient) GetAllXconfDataTwoKeysAsMap(tableName string, rowKey string, key2FieldName string, key2List []interface{}) map[interface{}][]byte {
+	result := map[interface{}][]byte{}
+	for _, key2 := range key2List {
+		if value, err := c.get(tableName, rowKey, key2); err == nil {
+			result[key2] = value
+		}
+	}
+	return result
+}
+
+func (c *MemoryDatabaseClient) SetXconfDataTwoKeys(tableName string, rowKey interface{}, key2FieldName string, key2 interface{}, value []byte, ttl int) error {
+	c.set(tableName, fmt.Sprint(rowKey), key2, value)
+	return nil
+}
+
+func (c *MemoryDatabaseClient) GetXconfDataTwoKeys(tableName string, rowKey string, key2FieldName string, key2 interface{}) ([]byte, error) {
+	return c.get(tableName, rowKey, key2)
+}
+
+func (c *MemoryDatabaseClient) DeleteXconfDataTwoKeys(tableName string, rowKey string, key2FieldName string, key2 interface{}) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.tables[tableName][rowKey], fmt.Sprint(key2))
+	return nil
+}
+
+func (c *MemoryDatabaseClient) GetAllXconfTwoKeys(tableName string, key2FieldName string) []db.TwoKeys {
+	result := []db.TwoKeys{}
+	for _, rowKey := range c.rowKeys(tableName) {
+		for _, column := range c.columns(tableName, rowKey) {
+			result = append(result, db.TwoKeys{Key: rowKey, Key2: column.key2})
+		}
+	}
+	return result
+}
+
+func (c *MemoryDatabaseClient) GetAllXconfKey2s(tableName string, rowKey string, key2FieldName string) []interface{} {
+	result := []interface{}{}
+	for _, column := range c.columns(tableName, rowKey) {
+		result = append(result, column.key2)
+	}
+	return result
+}
+
+// compressed data is kept as one value, the chunks are only a storage concern of cassandra
+func (c *MemoryDatabaseClient) SetXconfCompressedData(tableName string, rowKey string, values [][]byte, ttl int) error {
+	c.set(tableName, rowKey, singleValueColumn, bytes.Join(values, []byte{}))
+	return nil
+}
+
+func (c *MemoryDatabaseClient) GetXconfCompressedData(tableName string, rowKey string) ([]byte, error) {
+	return c.get(tableName, rowKey, singleValueColumn)
+}
+
+func (c *MemoryDatabaseClient) GetAllXconfCompressedDataAsMap(tableName string) map[string][]byte {
+	return c.GetAllXconfDataAsMap(tableName, 0)
+}
+
+func (c *MemoryDatabaseClient) GetEcmMacFromPodTable(serialNum string) (string, error) {
+	return "", gocql.ErrNotFound
+}
+
+func (c *MemoryDatabaseClient) GetPenetrationMetrics(macAddress string) (map[string]interface{}, error) {
+	return nil, gocql.ErrNotFound
+}
+
+func (c *MemoryDatabaseClient) SetPenetrationMetrics(penetrationmetrics *db.PenetrationMetrics) error {
+	return nil
+}
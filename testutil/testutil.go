@@ -0,0 +1,117 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package testutil
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	xcommon "xconfadmin/common"
+	xhttp "xconfadmin/http"
+	xshared "xconfadmin/shared"
+	xcoreef "xconfadmin/shared/estbfirmware"
+	"xconfadmin/shared/schema"
+	"xconfwebconfig/dataapi"
+	"xconfwebconfig/db"
+	xwhttp "xconfwebconfig/http"
+
+	"github.com/gorilla/mux"
+)
+
+const TestUser = "test-user"
+
+var (
+	setupOnce sync.Once
+	client    *MemoryDatabaseClient
+)
+
+var _ db.DatabaseClient = &MemoryDatabaseClient{}
+
+// SetupTestDB registers the tables on an in-memory database and turns on the permission checks,
+// every call starts from an empty database
+func SetupTestDB() *MemoryDatabaseClient {
+	setupOnce.Do(func() {
+		client = NewMemoryDatabaseClient()
+		db.SetDatabaseClient(client)
+		dataapi.RegisterTables()
+		schema.RegisterTables()
+		db.GetCacheManager()
+	})
+	client.TearDown()
+	db.GetCacheManager().RefreshAll()
+	db.GetCacheManager().ApplicationCacheInvalidateAll()
+	waitForEmptyCaches()
+	xshared.ReloadApplicationTypes()
+	xcoreef.ReloadFirmwareVersionPatterns()
+	xcommon.SatOn = true
+	return client
+}
+
+// waitForEmptyCaches waits until the cache invalidation, which is applied asynchronously, has dropped all the entries
+func waitForEmptyCaches() {
+	for _, tableInfo := range db.GetAllTableInfo() {
+		if !tableInfo.CacheData {
+			continue
+		}
+		for i := 0; i < 100; i++ {
+			keys, err := db.GetCachedSimpleDao().GetKeys(tableInfo.TableName)
+			if err != nil || len(keys) == 0 {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+// NewRequest returns a request of the test user authenticated with the given login token permissions
+func NewRequest(method string, url string, body string, permissions ...string) *http.Request {
+	r := httptest.NewRequest(method, url, strings.NewReader(body))
+	r.Header.Set(xhttp.AUTH_SUBJECT, TestUser)
+	return r.WithContext(context.WithValue(r.Context(), xhttp.CTX_KEY_PERMISSIONS, permissions))
+}
+
+// NewApiKeyRequest returns a request authenticated with the api key of a service account like the auth middleware does
+func NewApiKeyRequest(method string, url string, body string, apiKey string) (*http.Request, error) {
+	r := httptest.NewRequest(method, url, strings.NewReader(body))
+	r.Header.Set(xhttp.API_KEY, apiKey)
+	ctx, err := xhttp.ContextWithApiKey(r.Context(), r, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	return r.WithContext(ctx), nil
+}
+
+// WithVars sets the route vars of the request
+func WithVars(r *http.Request, vars map[string]string) *http.Request {
+	return mux.SetURLVars(r, vars)
+}
+
+// Serve calls the handler with the body of the request read into the response writer like the router does
+func Serve(handler http.HandlerFunc, r *http.Request) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	xw := xwhttp.NewXResponseWriter(recorder)
+	body, _ := ioutil.ReadAll(r.Body)
+	xw.SetBody(string(body))
+	handler(xw, r)
+	return recorder
+}
@@ -0,0 +1,325 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	JSON_PATCH_ADD     = "add"
+	JSON_PATCH_REMOVE  = "remove"
+	JSON_PATCH_REPLACE = "replace"
+)
+
+// JsonPatchOperation is one operation of an RFC 6902 JSON Patch
+type JsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// MarshalJSON keeps a null value of add and replace, only remove has no value
+func (o JsonPatchOperation) MarshalJSON() ([]byte, error) {
+	if o.Op == JSON_PATCH_REMOVE {
+		return json.Marshal(map[string]string{"op": o.Op, "path": o.Path})
+	}
+	return json.Marshal(map[string]interface{}{"op": o.Op, "path": o.Path, "value": o.Value})
+}
+
+// JsonDiff is the difference between two entities
+type JsonDiff struct {
+	Patch   []JsonPatchOperation `json:"patch"`
+	Summary []string             `json:"summary"`
+}
+
+// DiffJson returns the JSON Patch which turns the old entity into the new one, nil stands for no entity.
+// String fields holding a JSON object or array are parsed first, so the patch applies to the expanded document
+func DiffJson(oldEntity interface{}, newEntity interface{}) (*JsonDiff, error) {
+	oldDoc, err := toJsonDocument(oldEntity)
+	if err != nil {
+		return nil, err
+	}
+	newDoc, err := toJsonDocument(newEntity)
+	if err != nil {
+		return nil, err
+	}
+	diff := &JsonDiff{
+		Patch:   []JsonPatchOperation{},
+		Summary: []string{},
+	}
+	diff.compare("", oldDoc, newDoc)
+	return diff, nil
+}
+
+func toJsonDocument(entity interface{}) (interface{}, error) {
+	if entity == nil {
+		return nil, nil
+	}
+	bytes, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(bytes, &doc); err != nil {
+		return nil, err
+	}
+	return expandJsonStrings(doc), nil
+}
+
+// expandJsonStrings replaces the strings which hold a JSON object or array with the parsed value
+func expandJsonStrings(doc interface{}) interface{} {
+	switch value := doc.(type) {
+	case map[string]interface{}:
+		for k, v := range value {
+			value[k] = expandJsonStrings(v)
+		}
+	case []interface{}:
+		for i, v := range value {
+			value[i] = expandJsonStrings(v)
+		}
+	case string:
+		trimmed := strings.TrimSpace(value)
+		if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			var parsed interface{}
+			if err := json.Unmarshal([]byte(trimmed), &parsed); err == nil {
+				return expandJsonStrings(parsed)
+			}
+		}
+	}
+	return doc
+}
+
+func (d *JsonDiff) compare(path string, oldValue interface{}, newValue interface{}) {
+	oldMap, oldIsMap := oldValue.(map[string]interface{})
+	newMap, newIsMap := newValue.(map[string]interface{})
+	if oldIsMap && newIsMap {
+		keys := []string{}
+		for k := range oldMap {
+			keys = append(keys, k)
+		}
+		for k := range newMap {
+			if _, ok := oldMap[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			childPath := path + "/" + escapeJsonPointer(k)
+			oldChild, inOld := oldMap[k]
+			newChild, inNew := newMap[k]
+			switch {
+			case !inNew:
+				d.remove(childPath, oldChild)
+			case !inOld:
+				d.add(childPath, newChild)
+			default:
+				d.compare(childPath, oldChild, newChild)
+			}
+		}
+		return
+	}
+
+	oldList, oldIsList := oldValue.([]interface{})
+	newList, newIsList := newValue.([]interface{})
+	if oldIsList && newIsList {
+		d.compareLists(path, oldList, newList)
+		return
+	}
+
+	switch {
+	case reflect.DeepEqual(oldValue, newValue):
+	case oldValue == nil && path == "":
+		d.add(path, newValue)
+	case newValue == nil && path == "":
+		d.remove(path, oldValue)
+	default:
+		d.Patch = append(d.Patch, JsonPatchOperation{Op: JSON_PATCH_REPLACE, Path: path, Value: newValue})
+		d.Summary = append(d.Summary, fmt.Sprintf("%s changed from %s to %s", summaryPath(path), summaryValue(oldValue), summaryValue(newValue)))
+	}
+}
+
+// lists longer than this are compared by index, the matching of the items grows with the square of the length
+const maxJsonDiffListMatrix = 1000000
+
+// compareLists matches the items of the lists by their id when all of them are objects with an id, by their value
+// otherwise, so an item inserted or removed in the middle does not shift all the items after it. The items between
+// two matches which are not matched are replaced pairwise when they have no ids, then removed or added
+func (d *JsonDiff) compareLists(path string, oldList []interface{}, newList []interface{}) {
+	oldKeys, newKeys, byId := jsonListKeys(oldList, newList)
+	matches := matchJsonListKeys(oldKeys, newKeys)
+	index, i, j := 0, 0, 0
+	for _, match := range append(matches, [2]int{len(oldList), len(newList)}) {
+		if !byId {
+			for ; i < match[0] && j < match[1]; i, j = i+1, j+1 {
+				d.compare(path+"/"+strconv.Itoa(index), oldList[i], newList[j])
+				index++
+			}
+		}
+		for ; i < match[0]; i++ {
+			d.remove(path+"/"+strconv.Itoa(index), oldList[i])
+		}
+		for ; j < match[1]; j++ {
+			d.add(path+"/"+strconv.Itoa(index), newList[j])
+			index++
+		}
+		if i < len(oldList) && j < len(newList) {
+			d.compare(path+"/"+strconv.Itoa(index), oldList[i], newList[j])
+			index++
+			i, j = i+1, j+1
+		}
+	}
+}
+
+// jsonListKeys returns the keys the items are matched by, the ids if every item is an object with an id
+func jsonListKeys(oldList []interface{}, newList []interface{}) ([]string, []string, bool) {
+	oldIds, oldOk := jsonListIds(oldList)
+	newIds, newOk := jsonListIds(newList)
+	if oldOk && newOk && len(oldList) > 0 && len(newList) > 0 {
+		return oldIds, newIds, true
+	}
+	return jsonListValues(oldList), jsonListValues(newList), false
+}
+
+func jsonListIds(list []interface{}) ([]string, bool) {
+	ids := make([]string, 0, len(list))
+	for _, item := range list {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		id, ok := object["id"].(string)
+		if !ok || id == "" {
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
+
+func jsonListValues(list []interface{}) []string {
+	values := make([]string, 0, len(list))
+	for _, item := range list {
+		values = append(values, summaryValue(item))
+	}
+	return values
+}
+
+// matchJsonListKeys returns the index pairs of the longest common subsequence of the keys in ascending order,
+// lists too long to match are matched by index
+func matchJsonListKeys(oldKeys []string, newKeys []string) [][2]int {
+	matches := [][2]int{}
+	prefix := 0
+	for prefix < len(oldKeys) && prefix < len(newKeys) && oldKeys[prefix] == newKeys[prefix] {
+		matches = append(matches, [2]int{prefix, prefix})
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldKeys)-prefix && suffix < len(newKeys)-prefix && oldKeys[len(oldKeys)-1-suffix] == newKeys[len(newKeys)-1-suffix] {
+		suffix++
+	}
+	oldMiddle := oldKeys[prefix : len(oldKeys)-suffix]
+	newMiddle := newKeys[prefix : len(newKeys)-suffix]
+
+	if len(oldMiddle)*len(newMiddle) > maxJsonDiffListMatrix {
+		for i := 0; i < len(oldMiddle) && i < len(newMiddle); i++ {
+			matches = append(matches, [2]int{prefix + i, prefix + i})
+		}
+	} else if len(oldMiddle) > 0 && len(newMiddle) > 0 {
+		// lengths[i][j] is the length of the common subsequence of oldMiddle[i:] and newMiddle[j:]
+		lengths := make([][]int, len(oldMiddle)+1)
+		for i := range lengths {
+			lengths[i] = make([]int, len(newMiddle)+1)
+		}
+		for i := len(oldMiddle) - 1; i >= 0; i-- {
+			for j := len(newMiddle) - 1; j >= 0; j-- {
+				switch {
+				case oldMiddle[i] == newMiddle[j]:
+					lengths[i][j] = lengths[i+1][j+1] + 1
+				case lengths[i+1][j] >= lengths[i][j+1]:
+					lengths[i][j] = lengths[i+1][j]
+				default:
+					lengths[i][j] = lengths[i][j+1]
+				}
+			}
+		}
+		for i, j := 0, 0; i < len(oldMiddle) && j < len(newMiddle); {
+			switch {
+			case oldMiddle[i] == newMiddle[j]:
+				matches = append(matches, [2]int{prefix + i, prefix + j})
+				i, j = i+1, j+1
+			case lengths[i+1][j] >= lengths[i][j+1]:
+				i++
+			default:
+				j++
+			}
+		}
+	}
+
+	for k := suffix; k > 0; k-- {
+		matches = append(matches, [2]int{len(oldKeys) - k, len(newKeys) - k})
+	}
+	return matches
+}
+
+func (d *JsonDiff) add(path string, value interface{}) {
+	d.Patch = append(d.Patch, JsonPatchOperation{Op: JSON_PATCH_ADD, Path: path, Value: value})
+	d.Summary = append(d.Summary, fmt.Sprintf("%s added: %s", summaryPath(path), summaryValue(value)))
+}
+
+func (d *JsonDiff) remove(path string, value interface{}) {
+	d.Patch = append(d.Patch, JsonPatchOperation{Op: JSON_PATCH_REMOVE, Path: path})
+	d.Summary = append(d.Summary, fmt.Sprintf("%s removed: %s", summaryPath(path), summaryValue(value)))
+}
+
+func escapeJsonPointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// summaryPath converts a JSON pointer into the dotted form, e.g. /parameter/0/name gives parameter[0].name
+func summaryPath(path string) string {
+	if path == "" {
+		return "entity"
+	}
+	var sb strings.Builder
+	for _, token := range strings.Split(path[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		if _, err := strconv.Atoi(token); err == nil {
+			sb.WriteString("[" + token + "]")
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString(".")
+		}
+		sb.WriteString(token)
+	}
+	return sb.String()
+}
+
+func summaryValue(value interface{}) string {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(bytes)
+}
@@ -0,0 +1,206 @@
+/**
+ * Copyright 2023 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+package util
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestDiffJsonListInsertAtFrontIsSingleAdd(t *testing.T) {
+	diff, err := DiffJson(
+		map[string]interface{}{"models": []string{"A", "B", "C"}},
+		map[string]interface{}{"models": []string{"X", "A", "B", "C"}})
+	assert.NilError(t, err)
+	assert.Equal(t, len(diff.Patch), 1)
+	assert.Equal(t, diff.Patch[0].Op, JSON_PATCH_ADD)
+	assert.Equal(t, diff.Patch[0].Path, "/models/0")
+	assert.DeepEqual(t, diff.Summary, []string{`models[0] added: "X"`})
+}
+
+func TestDiffJsonListRemoveInMiddle(t *testing.T) {
+	diff, err := DiffJson([]string{"A", "B", "C", "D"}, []string{"A", "D"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(diff.Patch), 2)
+	assert.Equal(t, diff.Patch[0].Op, JSON_PATCH_REMOVE)
+	assert.Equal(t, diff.Patch[0].Path, "/1")
+	assert.Equal(t, diff.Patch[1].Op, JSON_PATCH_REMOVE)
+	assert.Equal(t, diff.Patch[1].Path, "/1")
+	assert.DeepEqual(t, diff.Summary, []string{`[1] removed: "B"`, `[1] removed: "C"`})
+}
+
+func TestDiffJsonListChangedValueIsReplaced(t *testing.T) {
+	diff, err := DiffJson([]string{"A", "B", "C"}, []string{"A", "X", "C"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(diff.Patch), 1)
+	assert.Equal(t, diff.Patch[0].Op, JSON_PATCH_REPLACE)
+	assert.Equal(t, diff.Patch[0].Path, "/1")
+}
+
+func TestDiffJsonListMatchesObjectsById(t *testing.T) {
+	oldDoc := []map[string]interface{}{
+		{"id": "1", "name": "one"},
+		{"id": "2", "name": "two"},
+	}
+	newDoc := []map[string]interface{}{
+		{"id": "0", "name": "zero"},
+		{"id": "1", "name": "one"},
+		{"id": "2", "name": "TWO"},
+	}
+	diff, err := DiffJson(oldDoc, newDoc)
+	assert.NilError(t, err)
+	assert.Equal(t, len(diff.Patch), 2)
+	assert.Equal(t, diff.Patch[0].Op, JSON_PATCH_ADD)
+	assert.Equal(t, diff.Patch[0].Path, "/0")
+	assert.Equal(t, diff.Patch[1].Op, JSON_PATCH_REPLACE)
+	assert.Equal(t, diff.Patch[1].Path, "/2/name")
+	assert.Equal(t, diff.Patch[1].Value, "TWO")
+}
+
+func TestDiffJsonListReplacedIdIsRemoveAndAdd(t *testing.T) {
+	diff, err := DiffJson(
+		[]map[string]interface{}{{"id": "1", "name": "one"}},
+		[]map[string]interface{}{{"id": "2", "name": "one"}})
+	assert.NilError(t, err)
+	assert.Equal(t, len(diff.Patch), 2)
+	assert.Equal(t, diff.Patch[0].Op, JSON_PATCH_REMOVE)
+	assert.Equal(t, diff.Patch[1].Op, JSON_PATCH_ADD)
+}
+
+func TestDiffJsonMapAndScalars(t *testing.T) {
+	diff, err := DiffJson(
+		map[string]interface{}{"a": 1, "b": "x", "c": true},
+		map[string]interface{}{"a": 2, "c": true, "d/e": nil})
+	assert.NilError(t, err)
+	assert.Equal(t, len(diff.Patch), 3)
+	assert.Equal(t, diff.Patch[0].Op, JSON_PATCH_REPLACE)
+	assert.Equal(t, diff.Patch[0].Path, "/a")
+	assert.Equal(t, diff.Patch[1].Op, JSON_PATCH_REMOVE)
+	assert.Equal(t, diff.Patch[1].Path, "/b")
+	assert.Equal(t, diff.Patch[2].Op, JSON_PATCH_ADD)
+	assert.Equal(t, diff.Patch[2].Path, "/d~1e")
+
+	diff, err = DiffJson(nil, map[string]interface{}{"a": 1})
+	assert.NilError(t, err)
+	assert.Equal(t, len(diff.Patch), 1)
+	assert.Equal(t, diff.Patch[0].Path, "")
+	assert.DeepEqual(t, diff.Summary, []string{`entity added: {"a":1}`})
+}
+
+func TestDiffJsonExpandsJsonStrings(t *testing.T) {
+	diff, err := DiffJson(
+		map[string]interface{}{"config": `{"Parameter":[{"reference":"A"},{"reference":"B"}]}`},
+		map[string]interface{}{"config": `{"Parameter":[{"reference":"A"},{"reference":"C"},{"reference":"B"}]}`})
+	assert.NilError(t, err)
+	assert.Equal(t, len(diff.Patch), 1)
+	assert.Equal(t, diff.Patch[0].Op, JSON_PATCH_ADD)
+	assert.Equal(t, diff.Patch[0].Path, "/config/Parameter/1")
+	assert.DeepEqual(t, diff.Summary, []string{`config.Parameter[1] added: {"reference":"C"}`})
+}
+
+func TestDiffJsonPatchAppliesToOldDocument(t *testing.T) {
+	cases := [][2]interface{}{
+		{[]int{1, 2, 3, 4, 5}, []int{0, 2, 3, 6, 5, 7}},
+		{[]int{1, 2, 3}, []int{3, 2, 1}},
+		{[]int{}, []int{1, 2}},
+		{[]int{1, 2}, []int{}},
+		{[]string{"A", "B", "A", "B"}, []string{"B", "A", "C", "A"}},
+		{
+			map[string]interface{}{"rules": []map[string]interface{}{{"id": "1", "v": 1}, {"id": "2", "v": 2}, {"id": "3", "v": 3}}},
+			map[string]interface{}{"rules": []map[string]interface{}{{"id": "3", "v": 3}, {"id": "4", "v": 4}, {"id": "1", "v": 10}}},
+		},
+		{
+			map[string]interface{}{"a": []interface{}{1, []int{1, 2}, "x"}},
+			map[string]interface{}{"a": []interface{}{"y", 1, []int{2, 3}}},
+		},
+	}
+	for _, c := range cases {
+		diff, err := DiffJson(c[0], c[1])
+		assert.NilError(t, err)
+		oldDoc, err := toJsonDocument(c[0])
+		assert.NilError(t, err)
+		newDoc, err := toJsonDocument(c[1])
+		assert.NilError(t, err)
+		assert.DeepEqual(t, applyJsonPatch(t, oldDoc, diff.Patch), newDoc)
+	}
+}
+
+// applyJsonPatch applies the add, remove and replace operations in order
+func applyJsonPatch(t *testing.T, doc interface{}, patch []JsonPatchOperation) interface{} {
+	for _, op := range patch {
+		doc = applyJsonPatchOperation(t, doc, jsonPointerTokens(op.Path), op)
+	}
+	bytes, err := json.Marshal(doc)
+	assert.NilError(t, err)
+	var result interface{}
+	assert.NilError(t, json.Unmarshal(bytes, &result))
+	return result
+}
+
+func jsonPointerTokens(path string) []string {
+	if path == "" {
+		return nil
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens
+}
+
+func applyJsonPatchOperation(t *testing.T, doc interface{}, tokens []string, op JsonPatchOperation) interface{} {
+	if len(tokens) == 0 {
+		if op.Op == JSON_PATCH_REMOVE {
+			return nil
+		}
+		return op.Value
+	}
+	last := len(tokens) == 1
+	switch value := doc.(type) {
+	case map[string]interface{}:
+		switch {
+		case !last:
+			value[tokens[0]] = applyJsonPatchOperation(t, value[tokens[0]], tokens[1:], op)
+		case op.Op == JSON_PATCH_REMOVE:
+			delete(value, tokens[0])
+		default:
+			value[tokens[0]] = op.Value
+		}
+		return value
+	case []interface{}:
+		index, err := strconv.Atoi(tokens[0])
+		assert.NilError(t, err)
+		switch {
+		case !last:
+			value[index] = applyJsonPatchOperation(t, value[index], tokens[1:], op)
+		case op.Op == JSON_PATCH_ADD:
+			value = append(value[:index], append([]interface{}{op.Value}, value[index:]...)...)
+		case op.Op == JSON_PATCH_REMOVE:
+			value = append(value[:index], value[index+1:]...)
+		default:
+			value[index] = op.Value
+		}
+		return value
+	}
+	t.Fatalf("cannot apply %s to %v", op.Path, doc)
+	return nil
+}